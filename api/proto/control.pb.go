@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go from control.proto. Hand-authored in this
+// checkout because protoc isn't available in this environment; regenerate
+// with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//	    api/proto/control.proto
+//
+// DO NOT EDIT by hand once protoc is available again.
+
+package proto
+
+import (
+	fmt "fmt"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+type GetRulesRequest struct {
+	Group string `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (m *GetRulesRequest) Reset()         { *m = GetRulesRequest{} }
+func (m *GetRulesRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*GetRulesRequest) ProtoMessage()    {}
+
+type RuleBundle struct {
+	Yaml      []byte `protobuf:"bytes,1,opt,name=yaml,proto3" json:"yaml,omitempty"`
+	RulesHash string `protobuf:"bytes,2,opt,name=rules_hash,json=rulesHash,proto3" json:"rules_hash,omitempty"`
+	RuleCount int32  `protobuf:"varint,3,opt,name=rule_count,json=ruleCount,proto3" json:"rule_count,omitempty"`
+}
+
+func (m *RuleBundle) Reset()         { *m = RuleBundle{} }
+func (m *RuleBundle) String() string { return fmt.Sprintf("%+v", *m) }
+func (*RuleBundle) ProtoMessage()    {}
+
+type PutRulesRequest struct {
+	Yaml    []byte `protobuf:"bytes,1,opt,name=yaml,proto3" json:"yaml,omitempty"`
+	Replace bool   `protobuf:"varint,2,opt,name=replace,proto3" json:"replace,omitempty"`
+	DryRun  bool   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+}
+
+func (m *PutRulesRequest) Reset()         { *m = PutRulesRequest{} }
+func (m *PutRulesRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*PutRulesRequest) ProtoMessage()    {}
+
+type PutRulesResponse struct {
+	Status           string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	DiffJson         string `protobuf:"bytes,2,opt,name=diff_json,json=diffJson,proto3" json:"diff_json,omitempty"`
+	RulesHash        string `protobuf:"bytes,3,opt,name=rules_hash,json=rulesHash,proto3" json:"rules_hash,omitempty"`
+	RuleCount        int32  `protobuf:"varint,4,opt,name=rule_count,json=ruleCount,proto3" json:"rule_count,omitempty"`
+	WouldBeRuleCount int32  `protobuf:"varint,5,opt,name=would_be_rule_count,json=wouldBeRuleCount,proto3" json:"would_be_rule_count,omitempty"`
+}
+
+func (m *PutRulesResponse) Reset()         { *m = PutRulesResponse{} }
+func (m *PutRulesResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (*PutRulesResponse) ProtoMessage()    {}
+
+type ReloadRulesRequest struct{}
+
+func (m *ReloadRulesRequest) Reset()         { *m = ReloadRulesRequest{} }
+func (m *ReloadRulesRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ReloadRulesRequest) ProtoMessage()    {}
+
+type ReloadRulesResponse struct {
+	Status    string `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	RulesHash string `protobuf:"bytes,2,opt,name=rules_hash,json=rulesHash,proto3" json:"rules_hash,omitempty"`
+	RuleCount int32  `protobuf:"varint,3,opt,name=rule_count,json=ruleCount,proto3" json:"rule_count,omitempty"`
+}
+
+func (m *ReloadRulesResponse) Reset()         { *m = ReloadRulesResponse{} }
+func (m *ReloadRulesResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ReloadRulesResponse) ProtoMessage()    {}
+
+type TestCommandRequest struct {
+	Command string `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+}
+
+func (m *TestCommandRequest) Reset()         { *m = TestCommandRequest{} }
+func (m *TestCommandRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*TestCommandRequest) ProtoMessage()    {}
+
+type TestDomainRequest struct {
+	Domain string `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+}
+
+func (m *TestDomainRequest) Reset()         { *m = TestDomainRequest{} }
+func (m *TestDomainRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*TestDomainRequest) ProtoMessage()    {}
+
+type TestResult struct {
+	Allowed bool   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Reason  string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	RuleId  string `protobuf:"bytes,3,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+}
+
+func (m *TestResult) Reset()         { *m = TestResult{} }
+func (m *TestResult) String() string { return fmt.Sprintf("%+v", *m) }
+func (*TestResult) ProtoMessage()    {}
+
+type GetModeRequest struct{}
+
+func (m *GetModeRequest) Reset()         { *m = GetModeRequest{} }
+func (m *GetModeRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*GetModeRequest) ProtoMessage()    {}
+
+type SetModeRequest struct {
+	AgentId string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Mode    string `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+}
+
+func (m *SetModeRequest) Reset()         { *m = SetModeRequest{} }
+func (m *SetModeRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*SetModeRequest) ProtoMessage()    {}
+
+type ModeState struct {
+	GlobalMode string            `protobuf:"bytes,1,opt,name=global_mode,json=globalMode,proto3" json:"global_mode,omitempty"`
+	AgentModes map[string]string `protobuf:"bytes,2,rep,name=agent_modes,json=agentModes,proto3" json:"agent_modes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ModeState) Reset()         { *m = ModeState{} }
+func (m *ModeState) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ModeState) ProtoMessage()    {}
+
+type StreamDecisionsRequest struct {
+	AgentId string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+}
+
+func (m *StreamDecisionsRequest) Reset()         { *m = StreamDecisionsRequest{} }
+func (m *StreamDecisionsRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*StreamDecisionsRequest) ProtoMessage()    {}
+
+type DecisionEvent struct {
+	AgentId       string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Tier          string `protobuf:"bytes,2,opt,name=tier,proto3" json:"tier,omitempty"`
+	Domain        string `protobuf:"bytes,3,opt,name=domain,proto3" json:"domain,omitempty"`
+	Method        string `protobuf:"bytes,4,opt,name=method,proto3" json:"method,omitempty"`
+	Path          string `protobuf:"bytes,5,opt,name=path,proto3" json:"path,omitempty"`
+	RuleId        string `protobuf:"bytes,6,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Mode          string `protobuf:"bytes,7,opt,name=mode,proto3" json:"mode,omitempty"`
+	Action        string `protobuf:"bytes,8,opt,name=action,proto3" json:"action,omitempty"`
+	LatencyMs     int64  `protobuf:"varint,9,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
+	ClientIp      string `protobuf:"bytes,10,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	TimestampUnix int64  `protobuf:"varint,11,opt,name=timestamp_unix,json=timestampUnix,proto3" json:"timestamp_unix,omitempty"`
+}
+
+func (m *DecisionEvent) Reset()         { *m = DecisionEvent{} }
+func (m *DecisionEvent) String() string { return fmt.Sprintf("%+v", *m) }
+func (*DecisionEvent) ProtoMessage()    {}
+
+type StreamAuditRequest struct {
+	AgentId string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+}
+
+func (m *StreamAuditRequest) Reset()         { *m = StreamAuditRequest{} }
+func (m *StreamAuditRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*StreamAuditRequest) ProtoMessage()    {}
+
+type AuditEvent struct {
+	TimestampUnix int64  `protobuf:"varint,1,opt,name=timestamp_unix,json=timestampUnix,proto3" json:"timestamp_unix,omitempty"`
+	SourceIp      string `protobuf:"bytes,2,opt,name=source_ip,json=sourceIp,proto3" json:"source_ip,omitempty"`
+	AgentId       string `protobuf:"bytes,3,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Domain        string `protobuf:"bytes,4,opt,name=domain,proto3" json:"domain,omitempty"`
+	Method        string `protobuf:"bytes,5,opt,name=method,proto3" json:"method,omitempty"`
+	Action        string `protobuf:"bytes,6,opt,name=action,proto3" json:"action,omitempty"`
+	Reason        string `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (m *AuditEvent) Reset()         { *m = AuditEvent{} }
+func (m *AuditEvent) String() string { return fmt.Sprintf("%+v", *m) }
+func (*AuditEvent) ProtoMessage()    {}
+
+var _ proto.Message = (*GetRulesRequest)(nil)
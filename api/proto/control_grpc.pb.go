@@ -0,0 +1,385 @@
+// Code generated by protoc-gen-go-grpc from control.proto. Hand-authored in
+// this checkout because protoc isn't available in this environment; see
+// control.pb.go's header for the regeneration command. DO NOT EDIT by hand
+// once protoc is available again.
+
+package proto
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// ShieldControlClient is the client API for ShieldControl.
+type ShieldControlClient interface {
+	GetRules(ctx context.Context, in *GetRulesRequest, opts ...grpc.CallOption) (*RuleBundle, error)
+	PutRules(ctx context.Context, in *PutRulesRequest, opts ...grpc.CallOption) (*PutRulesResponse, error)
+	ReloadRules(ctx context.Context, in *ReloadRulesRequest, opts ...grpc.CallOption) (*ReloadRulesResponse, error)
+	TestCommand(ctx context.Context, in *TestCommandRequest, opts ...grpc.CallOption) (*TestResult, error)
+	TestDomain(ctx context.Context, in *TestDomainRequest, opts ...grpc.CallOption) (*TestResult, error)
+	GetMode(ctx context.Context, in *GetModeRequest, opts ...grpc.CallOption) (*ModeState, error)
+	SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*ModeState, error)
+	StreamDecisions(ctx context.Context, in *StreamDecisionsRequest, opts ...grpc.CallOption) (ShieldControl_StreamDecisionsClient, error)
+	StreamAudit(ctx context.Context, in *StreamAuditRequest, opts ...grpc.CallOption) (ShieldControl_StreamAuditClient, error)
+}
+
+type shieldControlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewShieldControlClient creates a client for ShieldControl over cc.
+func NewShieldControlClient(cc grpc.ClientConnInterface) ShieldControlClient {
+	return &shieldControlClient{cc}
+}
+
+func (c *shieldControlClient) GetRules(ctx context.Context, in *GetRulesRequest, opts ...grpc.CallOption) (*RuleBundle, error) {
+	out := new(RuleBundle)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/GetRules", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) PutRules(ctx context.Context, in *PutRulesRequest, opts ...grpc.CallOption) (*PutRulesResponse, error) {
+	out := new(PutRulesResponse)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/PutRules", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) ReloadRules(ctx context.Context, in *ReloadRulesRequest, opts ...grpc.CallOption) (*ReloadRulesResponse, error) {
+	out := new(ReloadRulesResponse)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/ReloadRules", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) TestCommand(ctx context.Context, in *TestCommandRequest, opts ...grpc.CallOption) (*TestResult, error) {
+	out := new(TestResult)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/TestCommand", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) TestDomain(ctx context.Context, in *TestDomainRequest, opts ...grpc.CallOption) (*TestResult, error) {
+	out := new(TestResult)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/TestDomain", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) GetMode(ctx context.Context, in *GetModeRequest, opts ...grpc.CallOption) (*ModeState, error) {
+	out := new(ModeState)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/GetMode", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*ModeState, error) {
+	out := new(ModeState)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldControl/SetMode", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldControlClient) StreamDecisions(ctx context.Context, in *StreamDecisionsRequest, opts ...grpc.CallOption) (ShieldControl_StreamDecisionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ShieldControl_serviceDesc.Streams[0], "/plasma.shield.v1.ShieldControl/StreamDecisions", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shieldControlStreamDecisionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// ShieldControl_StreamDecisionsClient is the stream returned by StreamDecisions.
+type ShieldControl_StreamDecisionsClient interface {
+	Recv() (*DecisionEvent, error)
+	grpc.ClientStream
+}
+
+type shieldControlStreamDecisionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *shieldControlStreamDecisionsClient) Recv() (*DecisionEvent, error) {
+	m := new(DecisionEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shieldControlClient) StreamAudit(ctx context.Context, in *StreamAuditRequest, opts ...grpc.CallOption) (ShieldControl_StreamAuditClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ShieldControl_serviceDesc.Streams[1], "/plasma.shield.v1.ShieldControl/StreamAudit", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shieldControlStreamAuditClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// ShieldControl_StreamAuditClient is the stream returned by StreamAudit.
+type ShieldControl_StreamAuditClient interface {
+	Recv() (*AuditEvent, error)
+	grpc.ClientStream
+}
+
+type shieldControlStreamAuditClient struct {
+	grpc.ClientStream
+}
+
+func (x *shieldControlStreamAuditClient) Recv() (*AuditEvent, error) {
+	m := new(AuditEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ShieldControlServer is the server API for ShieldControl.
+type ShieldControlServer interface {
+	GetRules(context.Context, *GetRulesRequest) (*RuleBundle, error)
+	PutRules(context.Context, *PutRulesRequest) (*PutRulesResponse, error)
+	ReloadRules(context.Context, *ReloadRulesRequest) (*ReloadRulesResponse, error)
+	TestCommand(context.Context, *TestCommandRequest) (*TestResult, error)
+	TestDomain(context.Context, *TestDomainRequest) (*TestResult, error)
+	GetMode(context.Context, *GetModeRequest) (*ModeState, error)
+	SetMode(context.Context, *SetModeRequest) (*ModeState, error)
+	StreamDecisions(*StreamDecisionsRequest, ShieldControl_StreamDecisionsServer) error
+	StreamAudit(*StreamAuditRequest, ShieldControl_StreamAuditServer) error
+}
+
+// UnimplementedShieldControlServer can be embedded by a ShieldControlServer
+// implementation to satisfy forward compatibility if the service gains
+// methods, the same way protoc-gen-go-grpc generates it.
+type UnimplementedShieldControlServer struct{}
+
+func (UnimplementedShieldControlServer) GetRules(context.Context, *GetRulesRequest) (*RuleBundle, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRules not implemented")
+}
+func (UnimplementedShieldControlServer) PutRules(context.Context, *PutRulesRequest) (*PutRulesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PutRules not implemented")
+}
+func (UnimplementedShieldControlServer) ReloadRules(context.Context, *ReloadRulesRequest) (*ReloadRulesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReloadRules not implemented")
+}
+func (UnimplementedShieldControlServer) TestCommand(context.Context, *TestCommandRequest) (*TestResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method TestCommand not implemented")
+}
+func (UnimplementedShieldControlServer) TestDomain(context.Context, *TestDomainRequest) (*TestResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method TestDomain not implemented")
+}
+func (UnimplementedShieldControlServer) GetMode(context.Context, *GetModeRequest) (*ModeState, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetMode not implemented")
+}
+func (UnimplementedShieldControlServer) SetMode(context.Context, *SetModeRequest) (*ModeState, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetMode not implemented")
+}
+func (UnimplementedShieldControlServer) StreamDecisions(*StreamDecisionsRequest, ShieldControl_StreamDecisionsServer) error {
+	return status.Error(codes.Unimplemented, "method StreamDecisions not implemented")
+}
+func (UnimplementedShieldControlServer) StreamAudit(*StreamAuditRequest, ShieldControl_StreamAuditServer) error {
+	return status.Error(codes.Unimplemented, "method StreamAudit not implemented")
+}
+
+// RegisterShieldControlServer registers srv with s.
+func RegisterShieldControlServer(s grpc.ServiceRegistrar, srv ShieldControlServer) {
+	s.RegisterService(&_ShieldControl_serviceDesc, srv)
+}
+
+func _ShieldControl_GetRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).GetRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/GetRules"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).GetRules(ctx, req.(*GetRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_PutRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).PutRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/PutRules"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).PutRules(ctx, req.(*PutRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_ReloadRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReloadRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).ReloadRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/ReloadRules"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).ReloadRules(ctx, req.(*ReloadRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_TestCommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestCommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).TestCommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/TestCommand"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).TestCommand(ctx, req.(*TestCommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_TestDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).TestDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/TestDomain"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).TestDomain(ctx, req.(*TestDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_GetMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).GetMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/GetMode"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).GetMode(ctx, req.(*GetModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_SetMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldControlServer).SetMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldControl/SetMode"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldControlServer).SetMode(ctx, req.(*SetModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldControl_StreamDecisions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamDecisionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShieldControlServer).StreamDecisions(m, &shieldControlStreamDecisionsServer{stream})
+}
+
+// ShieldControl_StreamDecisionsServer is the server-side stream for StreamDecisions.
+type ShieldControl_StreamDecisionsServer interface {
+	Send(*DecisionEvent) error
+	grpc.ServerStream
+}
+
+type shieldControlStreamDecisionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *shieldControlStreamDecisionsServer) Send(m *DecisionEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ShieldControl_StreamAudit_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamAuditRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShieldControlServer).StreamAudit(m, &shieldControlStreamAuditServer{stream})
+}
+
+// ShieldControl_StreamAuditServer is the server-side stream for StreamAudit.
+type ShieldControl_StreamAuditServer interface {
+	Send(*AuditEvent) error
+	grpc.ServerStream
+}
+
+type shieldControlStreamAuditServer struct {
+	grpc.ServerStream
+}
+
+func (x *shieldControlStreamAuditServer) Send(m *AuditEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _ShieldControl_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "plasma.shield.v1.ShieldControl",
+	HandlerType: (*ShieldControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetRules", Handler: _ShieldControl_GetRules_Handler},
+		{MethodName: "PutRules", Handler: _ShieldControl_PutRules_Handler},
+		{MethodName: "ReloadRules", Handler: _ShieldControl_ReloadRules_Handler},
+		{MethodName: "TestCommand", Handler: _ShieldControl_TestCommand_Handler},
+		{MethodName: "TestDomain", Handler: _ShieldControl_TestDomain_Handler},
+		{MethodName: "GetMode", Handler: _ShieldControl_GetMode_Handler},
+		{MethodName: "SetMode", Handler: _ShieldControl_SetMode_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamDecisions",
+			Handler:       _ShieldControl_StreamDecisions_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamAudit",
+			Handler:       _ShieldControl_StreamAudit_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "control.proto",
+}
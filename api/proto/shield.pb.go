@@ -0,0 +1,97 @@
+// Code generated by protoc-gen-go from shield.proto. Hand-authored in this
+// checkout because protoc isn't available in this environment; regenerate
+// with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	    --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//	    api/proto/shield.proto
+//
+// DO NOT EDIT by hand once protoc is available again.
+
+package proto
+
+import (
+	fmt "fmt"
+
+	proto "github.com/golang/protobuf/proto"
+)
+
+type ExecCheckRequest struct {
+	AgentId string            `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Command string            `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	User    string            `protobuf:"bytes,3,opt,name=user,proto3" json:"user,omitempty"`
+	Cwd     string            `protobuf:"bytes,4,opt,name=cwd,proto3" json:"cwd,omitempty"`
+	Env     map[string]string `protobuf:"bytes,5,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ExecCheckRequest) Reset()         { *m = ExecCheckRequest{} }
+func (m *ExecCheckRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ExecCheckRequest) ProtoMessage()    {}
+
+type ExecCheckResponse struct {
+	Allowed bool   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Reason  string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	RuleId  string `protobuf:"bytes,3,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+}
+
+func (m *ExecCheckResponse) Reset()         { *m = ExecCheckResponse{} }
+func (m *ExecCheckResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ExecCheckResponse) ProtoMessage()    {}
+
+type ValidateGrantRequest struct {
+	Principal string `protobuf:"bytes,1,opt,name=principal,proto3" json:"principal,omitempty"`
+	Target    string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (m *ValidateGrantRequest) Reset()         { *m = ValidateGrantRequest{} }
+func (m *ValidateGrantRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ValidateGrantRequest) ProtoMessage()    {}
+
+type ValidateGrantResponse struct {
+	Valid         bool   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	GrantId       string `protobuf:"bytes,2,opt,name=grant_id,json=grantId,proto3" json:"grant_id,omitempty"`
+	Shell         bool   `protobuf:"varint,3,opt,name=shell,proto3" json:"shell,omitempty"`
+	ExpiresAtUnix int64  `protobuf:"varint,4,opt,name=expires_at_unix,json=expiresAtUnix,proto3" json:"expires_at_unix,omitempty"`
+}
+
+func (m *ValidateGrantResponse) Reset()         { *m = ValidateGrantResponse{} }
+func (m *ValidateGrantResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ValidateGrantResponse) ProtoMessage()    {}
+
+type StreamModeRequest struct {
+	AgentId string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+}
+
+func (m *StreamModeRequest) Reset()         { *m = StreamModeRequest{} }
+func (m *StreamModeRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*StreamModeRequest) ProtoMessage()    {}
+
+type ModeChange struct {
+	AgentId       string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Mode          string `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	TimestampUnix int64  `protobuf:"varint,3,opt,name=timestamp_unix,json=timestampUnix,proto3" json:"timestamp_unix,omitempty"`
+}
+
+func (m *ModeChange) Reset()         { *m = ModeChange{} }
+func (m *ModeChange) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ModeChange) ProtoMessage()    {}
+
+type ReportEventRequest struct {
+	AgentId string `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Type    string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Detail  string `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
+}
+
+func (m *ReportEventRequest) Reset()         { *m = ReportEventRequest{} }
+func (m *ReportEventRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ReportEventRequest) ProtoMessage()    {}
+
+type ReportEventResponse struct {
+	Ok bool `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+}
+
+func (m *ReportEventResponse) Reset()         { *m = ReportEventResponse{} }
+func (m *ReportEventResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (*ReportEventResponse) ProtoMessage()    {}
+
+var _ proto.Message = (*ExecCheckRequest)(nil)
@@ -0,0 +1,204 @@
+// Code generated by protoc-gen-go-grpc from shield.proto. Hand-authored in
+// this checkout because protoc isn't available in this environment; see
+// shield.pb.go's header for the regeneration command. DO NOT EDIT by hand
+// once protoc is available again.
+
+package proto
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// ShieldAgentClient is the client API for ShieldAgent.
+type ShieldAgentClient interface {
+	ExecCheck(ctx context.Context, in *ExecCheckRequest, opts ...grpc.CallOption) (*ExecCheckResponse, error)
+	ValidateGrant(ctx context.Context, in *ValidateGrantRequest, opts ...grpc.CallOption) (*ValidateGrantResponse, error)
+	StreamMode(ctx context.Context, in *StreamModeRequest, opts ...grpc.CallOption) (ShieldAgent_StreamModeClient, error)
+	ReportEvent(ctx context.Context, in *ReportEventRequest, opts ...grpc.CallOption) (*ReportEventResponse, error)
+}
+
+type shieldAgentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewShieldAgentClient creates a client for ShieldAgent over cc.
+func NewShieldAgentClient(cc grpc.ClientConnInterface) ShieldAgentClient {
+	return &shieldAgentClient{cc}
+}
+
+func (c *shieldAgentClient) ExecCheck(ctx context.Context, in *ExecCheckRequest, opts ...grpc.CallOption) (*ExecCheckResponse, error) {
+	out := new(ExecCheckResponse)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldAgent/ExecCheck", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldAgentClient) ValidateGrant(ctx context.Context, in *ValidateGrantRequest, opts ...grpc.CallOption) (*ValidateGrantResponse, error) {
+	out := new(ValidateGrantResponse)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldAgent/ValidateGrant", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shieldAgentClient) StreamMode(ctx context.Context, in *StreamModeRequest, opts ...grpc.CallOption) (ShieldAgent_StreamModeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ShieldAgent_serviceDesc.Streams[0], "/plasma.shield.v1.ShieldAgent/StreamMode", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shieldAgentStreamModeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// ShieldAgent_StreamModeClient is the stream returned by StreamMode.
+type ShieldAgent_StreamModeClient interface {
+	Recv() (*ModeChange, error)
+	grpc.ClientStream
+}
+
+type shieldAgentStreamModeClient struct {
+	grpc.ClientStream
+}
+
+func (x *shieldAgentStreamModeClient) Recv() (*ModeChange, error) {
+	m := new(ModeChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shieldAgentClient) ReportEvent(ctx context.Context, in *ReportEventRequest, opts ...grpc.CallOption) (*ReportEventResponse, error) {
+	out := new(ReportEventResponse)
+	if err := c.cc.Invoke(ctx, "/plasma.shield.v1.ShieldAgent/ReportEvent", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShieldAgentServer is the server API for ShieldAgent.
+type ShieldAgentServer interface {
+	ExecCheck(context.Context, *ExecCheckRequest) (*ExecCheckResponse, error)
+	ValidateGrant(context.Context, *ValidateGrantRequest) (*ValidateGrantResponse, error)
+	StreamMode(*StreamModeRequest, ShieldAgent_StreamModeServer) error
+	ReportEvent(context.Context, *ReportEventRequest) (*ReportEventResponse, error)
+}
+
+// UnimplementedShieldAgentServer can be embedded by a ShieldAgentServer
+// implementation to satisfy forward compatibility if the service gains
+// methods, the same way protoc-gen-go-grpc generates it.
+type UnimplementedShieldAgentServer struct{}
+
+func (UnimplementedShieldAgentServer) ExecCheck(context.Context, *ExecCheckRequest) (*ExecCheckResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExecCheck not implemented")
+}
+func (UnimplementedShieldAgentServer) ValidateGrant(context.Context, *ValidateGrantRequest) (*ValidateGrantResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ValidateGrant not implemented")
+}
+func (UnimplementedShieldAgentServer) StreamMode(*StreamModeRequest, ShieldAgent_StreamModeServer) error {
+	return status.Error(codes.Unimplemented, "method StreamMode not implemented")
+}
+func (UnimplementedShieldAgentServer) ReportEvent(context.Context, *ReportEventRequest) (*ReportEventResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReportEvent not implemented")
+}
+
+// RegisterShieldAgentServer registers srv with s.
+func RegisterShieldAgentServer(s grpc.ServiceRegistrar, srv ShieldAgentServer) {
+	s.RegisterService(&_ShieldAgent_serviceDesc, srv)
+}
+
+func _ShieldAgent_ExecCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldAgentServer).ExecCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldAgent/ExecCheck"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldAgentServer).ExecCheck(ctx, req.(*ExecCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldAgent_ValidateGrant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateGrantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldAgentServer).ValidateGrant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldAgent/ValidateGrant"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldAgentServer).ValidateGrant(ctx, req.(*ValidateGrantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShieldAgent_StreamMode_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamModeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShieldAgentServer).StreamMode(m, &shieldAgentStreamModeServer{stream})
+}
+
+// ShieldAgent_StreamModeServer is the server-side stream for StreamMode.
+type ShieldAgent_StreamModeServer interface {
+	Send(*ModeChange) error
+	grpc.ServerStream
+}
+
+type shieldAgentStreamModeServer struct {
+	grpc.ServerStream
+}
+
+func (x *shieldAgentStreamModeServer) Send(m *ModeChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ShieldAgent_ReportEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShieldAgentServer).ReportEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/plasma.shield.v1.ShieldAgent/ReportEvent"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShieldAgentServer).ReportEvent(ctx, req.(*ReportEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _ShieldAgent_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "plasma.shield.v1.ShieldAgent",
+	HandlerType: (*ShieldAgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "ExecCheck", Handler: _ShieldAgent_ExecCheck_Handler},
+		{MethodName: "ValidateGrant", Handler: _ShieldAgent_ValidateGrant_Handler},
+		{MethodName: "ReportEvent", Handler: _ShieldAgent_ReportEvent_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMode",
+			Handler:       _ShieldAgent_StreamMode_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "shield.proto",
+}
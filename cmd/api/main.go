@@ -6,22 +6,64 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
 	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
 	"github.com/Extra-Chill/plasma-shield/internal/api"
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+	"github.com/Extra-Chill/plasma-shield/internal/mitmca"
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
+	"github.com/Extra-Chill/plasma-shield/internal/tlsutil"
 )
 
 var version = "0.1.0"
 
 func main() {
-	addr := flag.String("addr", ":8443", "API listen address")
-	mgmtToken := flag.String("mgmt-token", "", "Management bearer token (required)")
-	agentToken := flag.String("agent-token", "", "Agent bearer token (required)")
+	addr := flag.String("addr", ":8443", "API listen address (\"unix://path\" for a Unix domain socket instead of TCP)")
+	unixSocketMode := flag.String("unix-socket-mode", "", "File mode for the unix:// socket in --addr, e.g. 0770 (default 0770)")
+	unixSocketUser := flag.String("unix-socket-user", "", "Owner to chown the unix:// socket to, by name (disabled if empty)")
+	unixSocketGroup := flag.String("unix-socket-group", "", "Group to chown the unix:// socket to, by name (disabled if empty)")
+	mgmtToken := flag.String("mgmt-token", "", "Management bearer token, shorthand for --mgmt-auth=static://TOKEN (required unless --mgmt-auth is set)")
+	agentToken := flag.String("agent-token", "", "Agent bearer token, shorthand for --agent-auth=static://TOKEN (required unless --agent-auth is set)")
+	mgmtAuth := flag.String("mgmt-auth", "", "Management auth backend as a scheme URL: static://TOKEN, none://, or bcryptfile:///path (overrides --mgmt-token; see api.NewAuth)")
+	agentAuth := flag.String("agent-auth", "", "Agent auth backend as a scheme URL, same schemes as --mgmt-auth (overrides --agent-token)")
+	bastionCAKey := flag.String("bastion-ca-key", "", "Bastion CA key file, for issuing host certs (disabled if empty)")
+	bastionGrantsFile := flag.String("bastion-grants-file", "", "Bastion grants file, for DELETE /bastion/grants/{id} (disabled if empty)")
+	bastionRecordingsDir := flag.String("bastion-recordings-dir", "", "Directory the bastion writes session recordings to, for GET /bastion/sessions/{id}/stream and /replay (disabled if empty)")
+	agentsFile := flag.String("agents", "", "Fleet config file, for provisioners: POST /bastion/grants (disabled if empty)")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file (enables HTTPS; required for agent mTLS)")
+	tlsKey := flag.String("tls-key", "", "TLS key file (enables HTTPS; required for agent mTLS)")
+	tlsMode := flag.String("tls-mode", "file", "How the API gets its TLS certificate: \"file\" (use --tls-cert/--tls-key), \"autocert\" (provision and renew automatically via ACME; see --tls-autocert-*), or \"selfsigned\" (generate an ephemeral in-memory cert; for local dev only)")
+	tlsDomains := flag.String("tls-domains", "", "Comma-separated hostnames for the TLS certificate (required for --tls-mode=autocert; optional for --tls-mode=selfsigned, defaults to localhost)")
+	tlsAutocertCacheDir := flag.String("tls-autocert-cache-dir", "", "Directory to persist ACME account keys and issued certificates (required if --tls-mode=autocert)")
+	tlsAutocertEmail := flag.String("tls-autocert-email", "", "Contact email for the ACME account (optional)")
+	tlsAutocertDirectoryURL := flag.String("tls-autocert-directory-url", "", "ACME directory URL (defaults to Let's Encrypt production; use Let's Encrypt's staging directory for testing)")
+	tlsAutocertRenewBefore := flag.Duration("tls-autocert-renew-before", 0, "How long before expiry to renew a certificate (0 uses autocert's own default)")
+	tlsAutocertHTTPAddr := flag.String("tls-autocert-http-addr", ":80", "Address for the ACME HTTP-01 challenge responder (only started if --tls-mode=autocert)")
+	agentCAKey := flag.String("agent-ca-key", "", "Agent mTLS CA key file, for issuing/revoking agent client certs (disabled if empty)")
+	mitmCAKey := flag.String("mitm-ca-key", "", "MITM root CA key file, same path given to the gateway's --mitm-ca-key, for GET /mitm/ca-cert (disabled if empty)")
+	agentEnrollmentsFile := flag.String("agent-enrollments-file", "", "Agent enrollment secrets file, for POST /agents/enroll-secrets (in-memory only if empty)")
+	adminToken := flag.String("admin-token", "", "Admin bearer token for /admin/* CRUD + audit API (commodore-tier client certs also work; disabled if empty and no agent CA)")
+	validateRequests := flag.Bool("validate-requests", false, "Validate POST/PUT bodies against api/openapi.yaml before handling them")
+	storageDSN := flag.String("storage-dsn", "", "Persistent storage DSN (bare path or bbolt/badger/postgres:// URL; in-memory only if empty)")
+	rulesFile := flag.String("rules-file", "", "Declarative rules file (apiVersion: plasma/v1, kind: RuleSet), reloaded on change (disabled if empty)")
+	bastionUnlockKey := flag.String("bastion-unlock-key", "", "Passphrase to create/decrypt an encrypted-at-rest bastion CA key (flag or PLASMA_UNLOCK_KEY env; required again on restart once the key is locked, via POST /unlock if omitted)")
+	approvalTimeout := flag.Duration("approval-timeout", 0, "How long a \"review\" rule match waits for a human decision via POST /approvals/{id} before falling back to a deny (default 30s)")
+	grpcAddr := flag.String("grpc-addr", "", "ShieldAgent gRPC listen address, for agents that want lower-latency checks or live mode updates instead of REST polling (disabled if empty)")
+	wsMaxMessageBytes := flag.Int("ws-max-message-bytes", 0, "Max size in bytes of a single event sent over GET /bastion/sessions/stream/ws or GET /grants/events before it's dropped (default 64KiB)")
 	flag.Parse()
 
 	// Allow env vars as fallback
@@ -31,23 +73,178 @@ func main() {
 	if *agentToken == "" {
 		*agentToken = os.Getenv("PLASMA_AGENT_TOKEN")
 	}
+	if *bastionUnlockKey == "" {
+		*bastionUnlockKey = os.Getenv("PLASMA_UNLOCK_KEY")
+	}
 
-	if *mgmtToken == "" || *agentToken == "" {
-		log.Fatal("mgmt-token and agent-token are required (flags or PLASMA_MGMT_TOKEN/PLASMA_AGENT_TOKEN env)")
+	if *mgmtAuth == "" {
+		*mgmtAuth = *mgmtToken
+	}
+	if *agentAuth == "" {
+		*agentAuth = *agentToken
+	}
+	if *mgmtAuth == "" || *agentAuth == "" {
+		log.Fatal("mgmt-auth (or mgmt-token) and agent-auth (or agent-token) are required (flags or PLASMA_MGMT_TOKEN/PLASMA_AGENT_TOKEN env)")
 	}
 
 	cfg := api.ServerConfig{
-		Addr:            *addr,
-		ManagementToken: *mgmtToken,
-		AgentToken:      *agentToken,
-		Version:         version,
+		Addr:             *addr,
+		ManagementAuth:   *mgmtAuth,
+		AgentAuth:        *agentAuth,
+		AdminToken:       *adminToken,
+		Version:          version,
+		TLSCert:          *tlsCert,
+		TLSKey:           *tlsKey,
+		UnixSocketMode:   *unixSocketMode,
+		UnixSocketUser:   *unixSocketUser,
+		UnixSocketGroup:  *unixSocketGroup,
+		ValidateRequests: *validateRequests,
+	}
+
+	server, err := api.NewServer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create API server: %v", err)
+	}
+
+	// --tls-mode=autocert provisions and renews the API's certificate via
+	// ACME instead of a file on disk, so an operator doesn't need to run a
+	// separate cert-manager sidecar in front of it; --tls-mode=selfsigned
+	// generates an ephemeral in-memory cert for local development.
+	var autocertHTTPServer *http.Server
+	switch *tlsMode {
+	case "file":
+	case "autocert":
+		domains := splitDomains(*tlsDomains)
+		tlsConfig, manager, err := tlsutil.NewAutocertTLSConfig(tlsutil.AutocertConfig{
+			Domains:      domains,
+			CacheDir:     *tlsAutocertCacheDir,
+			Email:        *tlsAutocertEmail,
+			DirectoryURL: *tlsAutocertDirectoryURL,
+			RenewBefore:  *tlsAutocertRenewBefore,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure autocert: %v", err)
+		}
+		server.SetAutocertManager(manager, tlsConfig)
+		autocertHTTPServer = &http.Server{
+			Addr:         *tlsAutocertHTTPAddr,
+			Handler:      server.AutocertHTTPHandler(),
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+	case "selfsigned":
+		tlsConfig, err := tlsutil.NewSelfSignedTLSConfig(splitDomains(*tlsDomains))
+		if err != nil {
+			log.Fatalf("Failed to generate self-signed certificate: %v", err)
+		}
+		server.SetSelfSignedTLS(tlsConfig)
+	default:
+		log.Fatalf("Unknown --tls-mode %q (want \"file\", \"autocert\", or \"selfsigned\")", *tlsMode)
+	}
+
+	if *storageDSN != "" {
+		store, err := storage.Open(*storageDSN)
+		if err != nil {
+			log.Fatalf("Failed to open storage at %q: %v", *storageDSN, err)
+		}
+		server.SetStorage(store)
+		if err := server.LoadFromStorage(); err != nil {
+			log.Fatalf("Failed to load state from storage: %v", err)
+		}
+	}
+
+	if *agentCAKey != "" {
+		ca, err := agentca.NewAgentCA(*agentCAKey)
+		if err != nil {
+			log.Fatalf("Failed to load agent CA: %v", err)
+		}
+		server.SetAgentCA(ca)
+		server.SetAgentEnrollments(agentca.NewEnrollmentStore(*agentEnrollmentsFile))
+	}
+
+	if *mitmCAKey != "" {
+		ca, err := mitmca.NewCA(*mitmCAKey)
+		if err != nil {
+			log.Fatalf("Failed to load MITM CA: %v", err)
+		}
+		server.SetMITMCA(ca)
+	}
+
+	if *bastionCAKey != "" {
+		switch {
+		case *bastionUnlockKey != "":
+			ca, err := bastion.NewLockedCertificateAuthority(*bastionCAKey, *bastionUnlockKey)
+			if err != nil {
+				log.Fatalf("Failed to unlock bastion CA: %v", err)
+			}
+			server.SetBastionCA(ca)
+		case bastion.HasLockedCAKey(*bastionCAKey):
+			server.SetLockedBastionCA(*bastionCAKey)
+			log.Println("Bastion CA key is encrypted at rest; server starting locked until POST /unlock")
+		default:
+			ca, err := bastion.NewCertificateAuthority(*bastionCAKey)
+			if err != nil {
+				log.Fatalf("Failed to load bastion CA: %v", err)
+			}
+			server.SetBastionCA(ca)
+		}
+	}
+
+	grants := bastion.NewGrantStore(*bastionGrantsFile)
+	if *bastionGrantsFile != "" {
+		server.SetBastionGrants(grants)
+	}
+
+	if *bastionRecordingsDir != "" {
+		server.SetBastionRecordingDir(*bastionRecordingsDir)
 	}
 
-	server := api.NewServer(cfg)
+	if *agentsFile != "" {
+		config, err := fleet.LoadConfig(*agentsFile)
+		if err != nil {
+			log.Fatalf("Failed to load fleet config: %v", err)
+		}
+		provisioners, err := buildProvisioners(config.Provisioners, grants)
+		if err != nil {
+			log.Fatalf("Failed to build provisioners: %v", err)
+		}
+		server.SetBastionProvisioners(provisioners)
+	}
+
+	if *approvalTimeout > 0 {
+		server.SetApprovalTimeout(*approvalTimeout)
+	}
+
+	if *wsMaxMessageBytes > 0 {
+		server.SetWSMaxMessageBytes(*wsMaxMessageBytes)
+	}
+
+	if *rulesFile != "" {
+		if err := server.SetRulesFile(*rulesFile); err != nil {
+			log.Fatalf("Failed to load rules file %q: %v", *rulesFile, err)
+		}
+	}
 
 	// Register a demo agent for testing
 	server.RegisterAgent("agent-1", "sarai", "178.156.229.129")
 
+	// --grpc-addr starts the ShieldAgent gRPC service on its own listener,
+	// independent of the REST API's http.Server, the same way
+	// --tls-autocert-http-addr runs the ACME challenge responder on its own.
+	var grpcServer *api.GRPCServer
+	var grpcListener net.Listener
+	if *grpcAddr != "" {
+		agentGRPCAuth, err := api.NewAuth(*agentAuth)
+		if err != nil {
+			log.Fatalf("Failed to build agent auth for gRPC: %v", err)
+		}
+		grpcListener, err = net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on --grpc-addr %q: %v", *grpcAddr, err)
+		}
+		grpcServer = api.NewGRPCServer(server.Handlers(), agentGRPCAuth)
+	}
+
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -58,9 +255,28 @@ func main() {
 		}
 	}()
 
+	if autocertHTTPServer != nil {
+		go func() {
+			log.Printf("ACME HTTP-01 challenge responder listening on %s", *tlsAutocertHTTPAddr)
+			if err := autocertHTTPServer.ListenAndServe(); err != http.ErrServerClosed {
+				log.Printf("ACME HTTP-01 challenge responder error: %v", err)
+			}
+		}()
+	}
+
+	if grpcServer != nil {
+		go func() {
+			log.Printf("ShieldAgent gRPC service listening on %s", *grpcAddr)
+			if err := grpcServer.Server().Serve(grpcListener); err != nil {
+				log.Printf("gRPC server error: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Plasma Shield API v%s running on %s", version, *addr)
 	log.Println("Endpoints:")
-	log.Println("  GET  /status         - Shield status")
+	log.Println("  GET  /status         - Shield status (Accept: text/plain or text/plain; version=0.0.4 for Prometheus)")
+	log.Println("  GET  /metrics        - Shield status as a Prometheus exposition")
 	log.Println("  GET  /agents         - List agents")
 	log.Println("  POST /agents/{id}/pause  - Pause agent")
 	log.Println("  POST /agents/{id}/kill   - Kill agent")
@@ -68,9 +284,36 @@ func main() {
 	log.Println("  GET  /rules          - List rules")
 	log.Println("  POST /rules          - Create rule")
 	log.Println("  DELETE /rules/{id}   - Delete rule")
-	log.Println("  GET  /logs           - View logs")
+	log.Println("  GET  /logs           - View logs (Accept: text/csv to export as CSV)")
+	log.Println("  GET  /logs/stream    - Stream new log entries (SSE)")
+	log.Println("  GET  /agents/stream  - Stream agent status changes (SSE)")
+	log.Println("  GET  /bastion/sessions          - View bastion sessions")
+	log.Println("  POST /bastion/hosts/{id}/host-cert - Issue a host cert (bastion CA required)")
+	log.Println("  DELETE /bastion/grants/{id}        - Revoke a grant's certs and delete it")
+	log.Println("  GET  /bastion/krl                  - Fetch the current KRL (bastion CA required)")
+	log.Println("  POST /bastion/grants                - Authorize a grant via a configured provisioner")
+	log.Println("  POST /agents/enroll-secrets - Mint a one-time agent enrollment secret (agent CA required)")
+	log.Println("  POST /agents/enroll         - Redeem an enrollment secret for a client cert (agent CA required)")
+	log.Println("  POST /agents/reenroll       - Renew a client cert via mTLS (agent CA required)")
+	log.Println("  GET  /agents/crl            - Fetch the current agent cert CRL (agent CA required)")
+	log.Println("  /admin/tenants, /admin/agents, /admin/rules, /admin/tokens - Admin CRUD (admin auth required)")
+	log.Println("  POST /auth/token            - Mint a scoped API token (management auth required)")
+	log.Println("  DELETE /auth/token/{token}  - Revoke a scoped API token (management auth required)")
+	log.Println("  GET  /admin/audit           - Audit log entries (admin auth required)")
+	log.Println("  GET  /admin/audit/verify    - Verify the audit log hash chain (admin auth required)")
 	log.Println("  POST /exec/check     - Check command (agent auth)")
+	log.Println("  GET  /approvals         - List pending \"review\" rule approvals (management auth)")
+	log.Println("  GET  /approvals/stream  - Stream newly created pending approvals (SSE, management auth)")
+	log.Println("  POST /approvals/{id}   - Resolve a pending approval (allow/deny, management auth)")
+	log.Println("  GET  /stats/rules/{id}  - Per-rule hit time series (management auth)")
+	log.Println("  GET  /stats/top         - Noisiest rules or most-blocked agents (management auth)")
 	log.Println("  GET  /health         - Health check (no auth)")
+	log.Println("  GET  /healthz/rules  - Rules-file reload health (no auth)")
+	log.Println("  POST /unlock         - Unlock an encrypted-at-rest bastion CA key (no auth; passphrase is the credential)")
+	log.Println("  POST /unlock/rotate  - Rotate the unlock passphrase (no auth; old passphrase is the credential)")
+	if *grpcAddr != "" {
+		log.Printf("  gRPC ShieldAgent service (ExecCheck/ValidateGrant/StreamMode/ReportEvent) on %s (agent auth)", *grpcAddr)
+	}
 
 	<-stop
 	log.Println("Shutting down...")
@@ -81,6 +324,109 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		log.Printf("Shutdown error: %v", err)
 	}
+	if autocertHTTPServer != nil {
+		autocertHTTPServer.Shutdown(ctx)
+	}
+	if grpcServer != nil {
+		grpcServer.Server().GracefulStop()
+	}
 
 	log.Println("Goodbye!")
 }
+
+// buildProvisioners constructs the concrete bastion.Provisioner for each
+// entry in a fleet config's "provisioners:" section. Construction happens
+// here, rather than in internal/bastion or internal/fleet, so neither
+// package needs to import the other.
+func buildProvisioners(configs []fleet.ProvisionerConfig, grants *bastion.GrantStore) (map[string]bastion.Provisioner, error) {
+	provisioners := make(map[string]bastion.Provisioner, len(configs))
+	for _, c := range configs {
+		maxTTL, err := parseMaxTTL(c.MaxTTL)
+		if err != nil {
+			return nil, fmt.Errorf("provisioner %q: %w", c.Name, err)
+		}
+
+		var provisioner bastion.Provisioner
+		switch c.Type {
+		case "jwk":
+			publicKey, err := readEd25519PublicKeyFile(c.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("provisioner %q: %w", c.Name, err)
+			}
+			provisioner = bastion.NewJWKProvisioner(c.Name, publicKey, grants, maxTTL)
+		case "oidc":
+			verificationKey, err := bastion.FetchJWKSKey(c.JWKSURL, "")
+			if err != nil {
+				return nil, fmt.Errorf("provisioner %q: %w", c.Name, err)
+			}
+			provisioner = bastion.NewOIDCProvisioner(c.Name, c.Issuer, verificationKey, c.AllowedPrincipals, c.AllowedTargets, grants, maxTTL)
+		case "x5c":
+			roots, err := readTrustBundleFile(c.TrustBundleFile)
+			if err != nil {
+				return nil, fmt.Errorf("provisioner %q: %w", c.Name, err)
+			}
+			provisioner = bastion.NewX5CProvisioner(c.Name, roots, c.AllowedTargets, grants, maxTTL)
+		default:
+			return nil, fmt.Errorf("provisioner %q: unknown type %q", c.Name, c.Type)
+		}
+		provisioners[c.Name] = provisioner
+	}
+	return provisioners, nil
+}
+
+// splitDomains parses a comma-separated --tls-domains value, trimming
+// whitespace around each entry and dropping empty ones (so "" parses to
+// no domains rather than one blank domain).
+func splitDomains(s string) []string {
+	var domains []string
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// parseMaxTTL parses a ProvisionerConfig's max_ttl; an empty string means
+// unbounded.
+
+func parseMaxTTL(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_ttl %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// readEd25519PublicKeyFile reads a file containing a base64-encoded
+// ed25519 public key.
+func readEd25519PublicKeyFile(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key file: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key file: expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// readTrustBundleFile reads a PEM-encoded CA bundle.
+func readTrustBundleFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read trust bundle file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("trust bundle file %q: no certificates found", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+)
+
+// defaultAgentCertTTL is the validity period for certificates issued by
+// both enroll and renew, mirroring internal/api's identically-named
+// constant for the management API's own agent CA.
+const defaultAgentCertTTL = 24 * time.Hour
+
+// agentEnrollRequest is the request body for POST /agents/enroll.
+type agentEnrollRequest struct {
+	Secret string `json:"secret"`
+	CSR    string `json:"csr"` // PEM-encoded certificate signing request
+}
+
+// agentRenewRequest is the request body for POST /agents/renew. The
+// caller's identity comes from its existing client certificate (mTLS),
+// not the request body.
+type agentRenewRequest struct {
+	CSR string `json:"csr"` // PEM-encoded certificate signing request
+}
+
+// agentCertResponse is the response for POST /agents/enroll and
+// POST /agents/renew.
+type agentCertResponse struct {
+	Certificate string    `json:"certificate"` // PEM-encoded
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+// newAgentEnrollMux builds the agent-facing mux served on --agent-enroll-addr:
+// certificate enrollment (by one-time secret), renewal (by existing mTLS
+// identity), and the CA trust bundle external verifiers need to validate
+// those certificates. Only started when agentCA is non-nil.
+func newAgentEnrollMux(agentCA *agentca.AgentCA, enrollments *agentca.EnrollmentStore, fleetMgr *fleet.Manager) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/agents/enroll", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req agentEnrollRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Secret == "" || req.CSR == "" {
+			http.Error(w, "secret and csr are required", http.StatusBadRequest)
+			return
+		}
+
+		secret, err := enrollments.Consume(req.Secret)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		cert, err := agentCA.IssueCertificate([]byte(req.CSR), secret.TenantID, secret.AgentID, secret.Tier, defaultAgentCertTTL)
+		if err != nil {
+			http.Error(w, "issue certificate: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Register (or update) the agent in the fleet so the forward
+		// proxy's ValidateAgentCert recognizes this identity; enroll is
+		// the only mTLS onboarding path, so it must also be the one that
+		// makes the agent known to the fleet.
+		fleetMgr.AddAgent(secret.TenantID, fleet.Agent{ID: secret.AgentID, Tier: secret.Tier})
+		log.Printf("Agent %s enrolled for mTLS (tenant %s)", secret.AgentID, secret.TenantID)
+
+		writeAgentJSON(w, http.StatusCreated, agentCertResponse{
+			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			ValidBefore: cert.NotAfter,
+		})
+	})
+
+	mux.HandleFunc("/agents/renew", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		identity, err := agentca.IdentityFromCertificate(r.TLS.PeerCertificates[0])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		var req agentRenewRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.CSR == "" {
+			http.Error(w, "csr is required", http.StatusBadRequest)
+			return
+		}
+
+		cert, err := agentCA.IssueCertificate([]byte(req.CSR), identity.TenantID, identity.AgentID, identity.Tier, defaultAgentCertTTL)
+		if err != nil {
+			http.Error(w, "issue certificate: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeAgentJSON(w, http.StatusCreated, agentCertResponse{
+			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			ValidBefore: cert.NotAfter,
+		})
+	})
+
+	mux.HandleFunc("/agents/trust-bundle", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		bundle, err := agentCA.TrustBundle()
+		if err != nil {
+			http.Error(w, "build trust bundle: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeAgentJSON(w, http.StatusOK, bundle)
+	})
+
+	return mux
+}
+
+// agentEnrollSecretsHandler handles POST /agents/enroll-secrets on the
+// localhost-only metrics/health mux (see main's metricsMux): operators
+// mint a one-time secret bound to an agent identity, which the agent then
+// redeems at POST /agents/enroll for a client certificate.
+func agentEnrollSecretsHandler(enrollments *agentca.EnrollmentStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req struct {
+			TenantID   string `json:"tenant_id"`
+			AgentID    string `json:"agent_id"`
+			Tier       string `json:"tier"`
+			TTLSeconds int    `json:"ttl_seconds,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.TenantID == "" || req.AgentID == "" {
+			http.Error(w, "tenant_id and agent_id are required", http.StatusBadRequest)
+			return
+		}
+
+		ttl := time.Hour
+		if req.TTLSeconds > 0 {
+			ttl = time.Duration(req.TTLSeconds) * time.Second
+		}
+
+		secret, err := enrollments.Create(req.TenantID, req.AgentID, req.Tier, ttl)
+		if err != nil {
+			http.Error(w, "create enrollment secret: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeAgentJSON(w, http.StatusCreated, struct {
+			Secret    string    `json:"secret"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}{Secret: secret.Secret, ExpiresAt: secret.ExpiresAt})
+	}
+}
+
+func writeAgentJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
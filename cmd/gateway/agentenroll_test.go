@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+)
+
+// testCSR generates a fresh ed25519 keypair and PEM-encodes a CSR for it,
+// mirroring what an enrolling agent would produce.
+func testCSR(t *testing.T) []byte {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, priv)
+	if err != nil {
+		t.Fatalf("create csr: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestAgentEnrollMux_EnrollRegistersAgentInFleet(t *testing.T) {
+	ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+	if err != nil {
+		t.Fatalf("new agent CA: %v", err)
+	}
+	enrollments := agentca.NewEnrollmentStore("")
+	fleetMgr := fleet.NewManager()
+
+	secret, err := enrollments.Create("tenant-1", "agent-1", "crew", time.Hour)
+	if err != nil {
+		t.Fatalf("create enrollment secret: %v", err)
+	}
+
+	mux := newAgentEnrollMux(ca, enrollments, fleetMgr)
+
+	body, _ := json.Marshal(agentEnrollRequest{Secret: secret.Secret, CSR: string(testCSR(t))})
+	req := httptest.NewRequest(http.MethodPost, "/agents/enroll", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp agentCertResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Certificate == "" {
+		t.Error("expected a PEM certificate in the response")
+	}
+
+	if tenantID := fleetMgr.GetTenantForAgent("agent-1"); tenantID != "tenant-1" {
+		t.Errorf("expected agent-1 registered under tenant-1, got %q", tenantID)
+	}
+
+	// The secret is single-use: enrolling again with it must fail.
+	req2 := httptest.NewRequest(http.MethodPost, "/agents/enroll", bytes.NewReader(body))
+	rec2 := httptest.NewRecorder()
+	mux.ServeHTTP(rec2, req2)
+	if rec2.Code != http.StatusForbidden {
+		t.Errorf("expected 403 reusing a consumed secret, got %d", rec2.Code)
+	}
+}
+
+func TestAgentEnrollMux_TrustBundle(t *testing.T) {
+	ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+	if err != nil {
+		t.Fatalf("new agent CA: %v", err)
+	}
+	mux := newAgentEnrollMux(ca, agentca.NewEnrollmentStore(""), fleet.NewManager())
+
+	req := httptest.NewRequest(http.MethodGet, "/agents/trust-bundle", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
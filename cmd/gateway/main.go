@@ -5,19 +5,32 @@ import (
 	"context"
 	"crypto/tls"
 	"flag"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/Extra-Chill/plasma-shield/internal/accesslog"
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
 	"github.com/Extra-Chill/plasma-shield/internal/bastion"
 	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/middleware"
+	"github.com/Extra-Chill/plasma-shield/internal/mitmca"
 	"github.com/Extra-Chill/plasma-shield/internal/mode"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
 	"github.com/Extra-Chill/plasma-shield/internal/proxy"
 	"github.com/Extra-Chill/plasma-shield/internal/rules"
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
+	"github.com/Extra-Chill/plasma-shield/internal/tlsutil"
 )
 
 func main() {
@@ -25,13 +38,40 @@ func main() {
 	outboundPort := flag.String("outbound", ":8080", "Forward proxy port (outbound agent traffic)")
 	inboundPort := flag.String("inbound", ":8443", "Reverse proxy port (inbound to agents)")
 	bastionAddr := flag.String("bastion", "", "SSH bastion address (disabled if empty)")
+	bastionRecordingsDir := flag.String("bastion-recordings-dir", "", "Directory to write bastion session recordings to, for grants with Record set (disabled if empty)")
 	dataDir := flag.String("data-dir", "/var/lib/plasma-shield", "Directory for persistent state (keys, grants)")
 	tlsCert := flag.String("tls-cert", "", "TLS certificate file for inbound HTTPS")
 	tlsKey := flag.String("tls-key", "", "TLS private key file for inbound HTTPS")
 	rulesFile := flag.String("rules", "", "Rules file (YAML)")
 	agentsFile := flag.String("agents", "/etc/plasma-shield/agents.yaml", "Agents/fleet config file")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9100", "Address for /metrics, /healthz, and /readyz (localhost only)")
+	storageDSN := flag.String("storage-dsn", "", "Persistent storage DSN (bare path or bbolt/badger/postgres:// URL; in-memory only if empty)")
+	accessLogFile := flag.String("access-log", "", "Comma-separated access log destinations (see internal/accesslog): a bare path for JSON lines, or \"file=<path>\", \"syslog=<network>://<addr>\", \"otlp=<push URL>\", \"clf=stdout\"; disabled if empty")
+	accessLogTenantDir := flag.String("access-log-tenant-dir", "", "Directory to additionally write each tenant's inbound access log to its own \"<tenant>.jsonl\" file (reverse proxy only; disabled if empty)")
+	bastionUnlockKey := flag.String("bastion-unlock-key", "", "Passphrase to create/decrypt the bastion's encrypted-at-rest host key + CA key (flag or PLASMA_UNLOCK_KEY env; if omitted once a key is locked, the gateway refuses to start)")
+	bastionCleanupInterval := flag.Duration("bastion-cleanup-interval", 5*time.Minute, "How often to prune expired bastion grants in the background (only runs if --bastion is set)")
+	startupRetryTimeout := flag.Duration("startup-retry-timeout", 0, "Retry data-dir creation, TLS cert loading, and fleet config loading until they succeed or this elapses, instead of failing fast (0 disables retrying)")
+	startupRetrySleep := flag.Duration("startup-retry-sleep", 2*time.Second, "Delay between startup retry attempts when --startup-retry-timeout is set")
+	agentCAKey := flag.String("agent-ca-key", "", "Path to the agent mTLS CA keypair; requires --tls-cert/--tls-key and switches the outbound (forward proxy) listener to client-certificate agent auth instead of source-IP validation (disabled if empty)")
+	mitmCAKey := flag.String("mitm-ca-key", "", "Path to the MITM root CA keypair; switches the outbound (forward proxy) listener from blindly relaying CONNECT tunnels to TLS-terminating them so rules can see the decrypted Host header (disabled if empty)")
+	agentEnrollmentsFile := flag.String("agent-enrollments-file", "", "Agent enrollment secrets file, for POST /agents/enroll-secrets (in-memory only if empty)")
+	agentEnrollAddr := flag.String("agent-enroll-addr", ":8444", "Address for agent certificate enrollment/renewal (only started if --agent-ca-key is set)")
+	tlsMode := flag.String("tls-mode", "file", "How the inbound (reverse proxy) listener gets its TLS certificate: \"file\" (use --tls-cert/--tls-key), \"autocert\" (provision and renew automatically via ACME; see --tls-autocert-*), or \"selfsigned\" (generate an ephemeral in-memory cert; for local dev only)")
+	tlsDomains := flag.String("tls-domains", "", "Comma-separated hostnames for the TLS certificate (required for --tls-mode=autocert; optional for --tls-mode=selfsigned, defaults to localhost)")
+	tlsAutocertCacheDir := flag.String("tls-autocert-cache-dir", "", "Directory to persist ACME account keys and issued certificates (required if --tls-mode=autocert)")
+	tlsAutocertEmail := flag.String("tls-autocert-email", "", "Contact email for the ACME account (optional)")
+	tlsAutocertDirectoryURL := flag.String("tls-autocert-directory-url", "", "ACME directory URL (defaults to Let's Encrypt production; use Let's Encrypt's staging directory for testing)")
+	tlsAutocertRenewBefore := flag.Duration("tls-autocert-renew-before", 0, "How long before expiry to renew a certificate (0 uses autocert's own default)")
+	tlsAutocertHTTPAddr := flag.String("tls-autocert-http-addr", ":80", "Address for the ACME HTTP-01 challenge responder (only started if --tls-mode=autocert)")
+	logSink := flag.String("log-sink", "", "Comma-separated structured log destinations (see internal/plasmalog): \"stdout\" (default if empty), \"file=<path>\", \"syslog=<network>://<addr>\", \"loki=<push URL>\"")
+	allowSampleRPS := flag.Float64("log-allow-sample-rps", 0, "Rate-limit \"action=allow\" log records per agent so a chatty agent can't drown out block/audit signal (0 disables sampling, logging every allow)")
+	allowSampleBurst := flag.Int("log-allow-sample-burst", 20, "Burst size for --log-allow-sample-rps")
 	flag.Parse()
 
+	if *bastionUnlockKey == "" {
+		*bastionUnlockKey = os.Getenv("PLASMA_UNLOCK_KEY")
+	}
+
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 	log.Println("Plasma Shield Gateway starting...")
 
@@ -49,33 +89,209 @@ func main() {
 	}
 
 	fleetMgr := fleet.NewManager()
+	fleetBus := fleet.NewBus(fleetMgr)
+
+	var store storage.Store
+	if *storageDSN != "" {
+		opened, err := storage.Open(*storageDSN)
+		if err != nil {
+			log.Fatalf("Failed to open storage at %q: %v", *storageDSN, err)
+		}
+		store = opened
+	}
+
+	// Rate limits are declared in the "rate_limits:" section of the same
+	// rules file, alongside filtering rules.
+	var rateLimitRules []limiter.RateLimitRule
+	if *rulesFile != "" {
+		loaded, err := limiter.LoadRateLimitsFromFile(*rulesFile)
+		if err != nil {
+			log.Printf("Warning: failed to load rate limits from %s: %v", *rulesFile, err)
+		} else {
+			rateLimitRules = loaded
+			if len(rateLimitRules) > 0 {
+				log.Printf("Loaded %d rate limit rule(s) from %s", len(rateLimitRules), *rulesFile)
+			}
+		}
+	}
+	metrics := limiter.NewMetrics()
+	fleetMgr.SetMetrics(metrics)
+	rateLimiter, err := limiter.NewLimiter(rateLimitRules, metrics)
+	if err != nil {
+		log.Fatalf("Failed to build rate limiter: %v", err)
+	}
+	breaker := limiter.NewBreaker(limiter.DefaultBreakerConfig(), metrics)
+
+	var accessLogger accesslog.Logger = accesslog.NopLogger{}
+	var accessLogClosers []io.Closer
+	if *accessLogFile != "" {
+		logger, err := accesslog.ParseSinks(*accessLogFile)
+		if err != nil {
+			log.Printf("Warning: failed to configure --access-log %q: %v", *accessLogFile, err)
+		} else {
+			accessLogger = logger
+			if closer, ok := logger.(io.Closer); ok {
+				accessLogClosers = append(accessLogClosers, closer)
+			}
+		}
+	}
+
+	// reverseAccessLogger additionally routes each tenant's inbound records
+	// to its own file when --access-log-tenant-dir is set; the forward
+	// proxy's accessLogger is unaffected since it has no tenant concept.
+	reverseAccessLogger := accessLogger
+	if *accessLogTenantDir != "" {
+		router := accesslog.NewTenantFileRouter(*accessLogTenantDir, accessLogger)
+		reverseAccessLogger = router
+		accessLogClosers = append(accessLogClosers, router)
+	}
+
+	// Structured application log (internal/plasmalog), replacing the
+	// json.Marshal+log.Println calls this and internal/bastion used to make
+	// directly. Defaults to the same destination (JSON lines on stdout) when
+	// --log-sink is unset.
+	appLogger, appLogHandler, err := plasmalog.Build(*logSink, 0, nil)
+	if err != nil {
+		log.Fatalf("Failed to configure --log-sink %q: %v", *logSink, err)
+	}
 
 	// Create handlers
 	inspector := proxy.NewInspector(rulesEngine, modeManager)
-	reverseHandler := proxy.NewReverseHandler(fleetMgr)
+	reverseHandler := proxy.NewReverseHandler(fleetMgr,
+		proxy.WithReverseLimiter(rateLimiter),
+		proxy.WithReverseBreaker(breaker),
+		proxy.WithReverseMetrics(metrics),
+		proxy.WithReverseBus(fleetBus),
+		proxy.WithReverseAccessLog(reverseAccessLogger),
+	)
 
 	// Load fleet config (agents, tokens) BEFORE creating forward handler
-	// so the agent registry is populated
-	if err := loadFleetConfig(fleetMgr, reverseHandler, *agentsFile); err != nil {
+	// so the agent registry is populated. Retried when --startup-retry-timeout
+	// is set, for orchestrators that start the gateway before its config
+	// volume is mounted; falls back to env tokens only once retries (if any)
+	// are exhausted, preserving today's fail-open behavior.
+	if err := retryUntilReady("fleet config", *startupRetryTimeout, *startupRetrySleep, func() error {
+		return loadFleetConfig(fleetMgr, store, reverseHandler, *agentsFile)
+	}); err != nil {
 		log.Printf("Warning: failed to load fleet config from %s: %v", *agentsFile, err)
 		// Also try loading tokens from environment as fallback
 		loadTokens(reverseHandler)
 	}
 
+	// Hot-reload rules and fleet config on SIGHUP or file change, so
+	// operators can push updates without dropping in-flight connections.
+	configReloader, err := startReloader(rulesEngine, fleetMgr, reverseHandler, store, *rulesFile, *agentsFile)
+	if err != nil {
+		log.Printf("Warning: config hot-reload disabled: %v", err)
+	}
+
 	// Create forward handler with agent registry for IP validation
-	forwardHandler := proxy.NewHandler(inspector, proxy.WithAgentRegistry(fleetMgr))
+	forwardHandlerOpts := []proxy.HandlerOption{
+		proxy.WithAgentRegistry(fleetMgr),
+		proxy.WithLimiter(rateLimiter),
+		proxy.WithBreaker(breaker),
+		proxy.WithMetrics(metrics),
+		proxy.WithAccessLog(accessLogger),
+		proxy.WithLogger(appLogger),
+	}
+	if *allowSampleRPS > 0 {
+		forwardHandlerOpts = append(forwardHandlerOpts, proxy.WithAllowSampleRate(*allowSampleRPS, *allowSampleBurst))
+	}
+	if *mitmCAKey != "" {
+		mitmCA, err := mitmca.NewCA(*mitmCAKey)
+		if err != nil {
+			log.Fatalf("Failed to initialize MITM CA: %v", err)
+		}
+		forwardHandlerOpts = append(forwardHandlerOpts, proxy.WithMITM(mitmCA))
+	}
+	forwardHandler := proxy.NewHandler(inspector, forwardHandlerOpts...)
 
 	// Ensure data directory exists
-	if err := os.MkdirAll(*dataDir, 0700); err != nil {
+	if err := retryUntilReady("data directory", *startupRetryTimeout, *startupRetrySleep, func() error {
+		return os.MkdirAll(*dataDir, 0700)
+	}); err != nil {
 		log.Fatalf("Failed to create data directory %s: %v", *dataDir, err)
 	}
 
+	// Pre-flight TLS cert/key load, so an orchestrator that races the
+	// gateway against a cert-manager sidecar can retry instead of the
+	// inbound listener goroutine failing fast later with log.Fatalf.
+	if *tlsMode == "file" && *tlsCert != "" && *tlsKey != "" {
+		if err := retryUntilReady("TLS certificate", *startupRetryTimeout, *startupRetrySleep, func() error {
+			_, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+			return err
+		}); err != nil {
+			log.Fatalf("Failed to load TLS certificate %s / key %s: %v", *tlsCert, *tlsKey, err)
+		}
+	}
+
+	// --tls-mode=autocert provisions and renews the inbound listener's
+	// certificate via ACME instead of a file on disk, so an operator
+	// doesn't need to run a separate cert-manager sidecar in front of it;
+	// --tls-mode=selfsigned generates an ephemeral in-memory cert for
+	// local development.
+	var autocertManager *autocert.Manager
+	var inboundTLSConfig *tls.Config
+	switch *tlsMode {
+	case "file":
+	case "autocert":
+		cfg, m, err := tlsutil.NewAutocertTLSConfig(tlsutil.AutocertConfig{
+			Domains:      splitDomains(*tlsDomains),
+			CacheDir:     *tlsAutocertCacheDir,
+			Email:        *tlsAutocertEmail,
+			DirectoryURL: *tlsAutocertDirectoryURL,
+			RenewBefore:  *tlsAutocertRenewBefore,
+		})
+		if err != nil {
+			log.Fatalf("Failed to configure autocert: %v", err)
+		}
+		autocertManager = m
+		inboundTLSConfig = cfg
+	case "selfsigned":
+		cfg, err := tlsutil.NewSelfSignedTLSConfig(splitDomains(*tlsDomains))
+		if err != nil {
+			log.Fatalf("Failed to generate self-signed certificate: %v", err)
+		}
+		inboundTLSConfig = cfg
+	default:
+		log.Fatalf("Unknown --tls-mode %q (want \"file\", \"autocert\", or \"selfsigned\")", *tlsMode)
+	}
+
+	// Agent mTLS: an agentCA turns the outbound listener's agent
+	// validation from source IP (spoofable, breaks under NAT/roaming) into
+	// a client certificate resolved by fleetMgr.ValidateAgentCert. The
+	// outbound listener still needs the same server certificate as the
+	// inbound one to terminate TLS at all.
+	var agentCA *agentca.AgentCA
+	var agentEnrollments *agentca.EnrollmentStore
+	if *agentCAKey != "" {
+		if *tlsCert == "" || *tlsKey == "" {
+			log.Fatalf("--agent-ca-key requires --tls-cert and --tls-key for the outbound listener")
+		}
+		ca, err := agentca.NewAgentCA(*agentCAKey)
+		if err != nil {
+			log.Fatalf("Failed to initialize agent CA: %v", err)
+		}
+		agentCA = ca
+		agentEnrollments = agentca.NewEnrollmentStore(*agentEnrollmentsFile)
+	}
+
 	// Initialize SSH bastion (if enabled)
 	var bastionServer *bastion.Server
+	bastionCleanupCtx, cancelBastionCleanup := context.WithCancel(context.Background())
 	if *bastionAddr != "" {
 		bastionLogStore := bastion.NewLogStore(bastion.DefaultLogLimit)
+		bastionLogStore.SetLogger(appLogger)
 		bastionLogger := bastion.NewLogger(bastionLogStore)
+		bastionLogger.SetMetrics(metrics)
 		bastionGrantStore := bastion.NewGrantStore(filepath.Join(*dataDir, "bastion_grants.json"))
+		bastionGrantStore.SetMetrics(metrics)
+		bastionGrantStore.StartCleanup(bastionCleanupCtx, *bastionCleanupInterval)
+
+		var bastionRecorder bastion.Recorder
+		if *bastionRecordingsDir != "" {
+			bastionRecorder = bastion.NewFileRecorder(*bastionRecordingsDir)
+		}
 
 		server, err := bastion.NewServer(bastion.Config{
 			Addr:        *bastionAddr,
@@ -83,6 +299,9 @@ func main() {
 			CAKeyPath:   filepath.Join(*dataDir, "bastion_ca_key"),
 			Logger:      bastionLogger,
 			GrantStore:  bastionGrantStore,
+			Recorder:    bastionRecorder,
+			UnlockKey:   *bastionUnlockKey,
+			Metrics:     metrics,
 		})
 		if err != nil {
 			log.Fatalf("Failed to initialize bastion: %v", err)
@@ -94,19 +313,44 @@ func main() {
 		log.Printf("SSH bastion listening on %s", bastionServer.Addr())
 	}
 
-	// Create servers
+	// Create servers. Wrapped in middleware.Recover so a panic inside rule
+	// evaluation or a handler bug can't crash the whole gateway process or
+	// leak the connection it was serving.
 	outboundServer := &http.Server{
 		Addr:         *outboundPort,
-		Handler:      forwardHandler,
+		Handler:      middleware.Recover("forward", metrics, middleware.WithStatusCode(http.StatusBadGateway), middleware.WithJSONError())(forwardHandler),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 60 * time.Second,
 	}
+	if agentCA != nil {
+		outboundServer.TLSConfig = &tls.Config{
+			ClientCAs:  agentCA.CertPool(),
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		}
+	}
+
+	// Agent enrollment/renewal, served separately from outboundServer so
+	// it's reachable even before an agent has a certificate to present.
+	var agentEnrollServer *http.Server
+	if agentCA != nil {
+		agentEnrollServer = &http.Server{
+			Addr:    *agentEnrollAddr,
+			Handler: middleware.Recover("agent-enroll", metrics)(newAgentEnrollMux(agentCA, agentEnrollments, fleetMgr)),
+			TLSConfig: &tls.Config{
+				ClientCAs:  agentCA.CertPool(),
+				ClientAuth: tls.VerifyClientCertIfGiven, // enroll has none yet; renew needs one
+			},
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+	}
 
 	inboundServer := &http.Server{
 		Addr:         *inboundPort,
-		Handler:      reverseHandler,
+		Handler:      middleware.Recover("reverse", metrics)(reverseHandler),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 60 * time.Second,
+		ErrorLog:     newTLSAwareErrorLog(metrics, "inbound"),
 		TLSConfig: &tls.Config{
 			MinVersion: tls.VersionTLS12,
 			CipherSuites: []uint16{
@@ -117,17 +361,97 @@ func main() {
 			},
 		},
 	}
+	if inboundTLSConfig != nil {
+		inboundServer.TLSConfig = inboundTLSConfig
+	}
+
+	// ACME HTTP-01 challenge responder. Required for autocert's default
+	// challenge type, since it proves domain control over plain HTTP on
+	// :80 rather than over the HTTPS port the certificate is actually for.
+	var autocertHTTPServer *http.Server
+	if autocertManager != nil {
+		autocertHTTPServer = &http.Server{
+			Addr:         *tlsAutocertHTTPAddr,
+			Handler:      tlsutil.HTTPChallengeHandler(autocertManager, nil),
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+	}
+
+	// Metrics/health server, bound to localhost by default since it's
+	// unauthenticated. readyReported flips true once every component above
+	// has initialized, so /readyz holds orchestrators back during startup.
+	var readyReported atomic.Bool
+	readyReported.Store(true)
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", limiter.MetricsHandler(metrics, fleetMgr.ReportFleetGauges))
+	metricsMux.Handle("/healthz", limiter.HealthzHandler())
+	metricsMux.Handle("/readyz", limiter.ReadyzHandler(readyReported.Load))
+	if agentCA != nil {
+		metricsMux.Handle("/agents/enroll-secrets", agentEnrollSecretsHandler(agentEnrollments))
+	}
+	metricsServer := &http.Server{
+		Addr:         *metricsAddr,
+		Handler:      metricsMux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
 
 	// Start servers
 	go func() {
-		log.Printf("Forward proxy (outbound) listening on %s", *outboundPort)
-		if err := outboundServer.ListenAndServe(); err != http.ErrServerClosed {
-			log.Fatalf("Forward proxy error: %v", err)
+		if agentCA != nil {
+			log.Printf("Forward proxy (outbound) listening on %s with mTLS agent auth", *outboundPort)
+			if err := outboundServer.ListenAndServeTLS(*tlsCert, *tlsKey); err != http.ErrServerClosed {
+				log.Fatalf("Forward proxy TLS error: %v", err)
+			}
+		} else {
+			log.Printf("Forward proxy (outbound) listening on %s", *outboundPort)
+			if err := outboundServer.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatalf("Forward proxy error: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		log.Printf("Metrics/health listening on %s", *metricsAddr)
+		if err := metricsServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Metrics server error: %v", err)
 		}
 	}()
 
+	if agentEnrollServer != nil {
+		go func() {
+			log.Printf("Agent enrollment/renewal listening on %s", *agentEnrollAddr)
+			if err := agentEnrollServer.ListenAndServeTLS(*tlsCert, *tlsKey); err != http.ErrServerClosed {
+				log.Fatalf("Agent enrollment server error: %v", err)
+			}
+		}()
+	}
+
+	if autocertHTTPServer != nil {
+		go func() {
+			log.Printf("ACME HTTP-01 challenge responder listening on %s", *tlsAutocertHTTPAddr)
+			if err := autocertHTTPServer.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatalf("ACME HTTP-01 challenge responder error: %v", err)
+			}
+		}()
+	}
+
 	go func() {
-		if *tlsCert != "" && *tlsKey != "" {
+		if inboundTLSConfig != nil {
+			// Certificate/key files are ignored: the TLSConfig set above
+			// already carries the autocert- or self-signed-provisioned
+			// certificate.
+			tlsSource := "self-signed"
+			if autocertManager != nil {
+				tlsSource = "autocert"
+			}
+			log.Printf("Reverse proxy (inbound) listening on %s with TLS (%s)", *inboundPort, tlsSource)
+			if err := inboundServer.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+				log.Fatalf("Reverse proxy TLS error: %v", err)
+			}
+		} else if *tlsCert != "" && *tlsKey != "" {
 			// TLS enabled for inbound
 			log.Printf("Reverse proxy (inbound) listening on %s with TLS", *inboundPort)
 			if err := inboundServer.ListenAndServeTLS(*tlsCert, *tlsKey); err != http.ErrServerClosed {
@@ -145,12 +469,24 @@ func main() {
 	}()
 
 	tlsStatus := "disabled (WARNING: insecure)"
-	if *tlsCert != "" && *tlsKey != "" {
+	switch {
+	case autocertManager != nil:
+		tlsStatus = "enabled (autocert)"
+	case inboundTLSConfig != nil:
+		tlsStatus = "enabled (self-signed)"
+	case *tlsCert != "" && *tlsKey != "":
 		tlsStatus = "enabled"
 	}
+	agentAuthStatus := "source IP"
+	if agentCA != nil {
+		agentAuthStatus = "mTLS client certificate"
+	}
 	log.Println("Plasma Shield Gateway running")
-	log.Println("  Outbound (forward proxy):", *outboundPort)
+	log.Println("  Outbound (forward proxy):", *outboundPort, "agent auth:", agentAuthStatus)
 	log.Println("  Inbound (reverse proxy):", *inboundPort, "TLS:", tlsStatus)
+	if agentEnrollServer != nil {
+		log.Println("  Agent enrollment/renewal:", *agentEnrollAddr)
+	}
 	if bastionServer != nil {
 		log.Println("  SSH bastion:", bastionServer.Addr())
 	}
@@ -166,21 +502,46 @@ func main() {
 
 	outboundServer.Shutdown(ctx)
 	inboundServer.Shutdown(ctx)
+	metricsServer.Shutdown(ctx)
+	if agentEnrollServer != nil {
+		agentEnrollServer.Shutdown(ctx)
+	}
+	if autocertHTTPServer != nil {
+		autocertHTTPServer.Shutdown(ctx)
+	}
+	if configReloader != nil {
+		configReloader.Close()
+	}
+	cancelBastionCleanup()
 	if bastionServer != nil {
 		bastionServer.Close()
 	}
+	for _, closer := range accessLogClosers {
+		closer.Close()
+	}
+	if appLogHandler != nil {
+		appLogHandler.Close()
+	}
+	if store != nil {
+		store.Close()
+	}
 	log.Println("Shutdown complete")
 }
 
-// loadFleetConfig loads fleet/agent configuration from YAML.
-func loadFleetConfig(mgr *fleet.Manager, reverseHandler *proxy.ReverseHandler, path string) error {
+// loadFleetConfig loads fleet/agent configuration from YAML. store may be
+// nil, in which case the fleet isn't persisted and only lives in mgr.
+func loadFleetConfig(mgr *fleet.Manager, store storage.Store, reverseHandler *proxy.ReverseHandler, path string) error {
 	config, err := fleet.LoadConfig(path)
 	if err != nil {
 		return err
 	}
 
-	// Apply fleet/tenant config
-	fleet.ApplyConfig(mgr, config)
+	mgr.SetConfigPath(path)
+
+	// Apply fleet/tenant config, reconciling against store if configured
+	if err := fleet.ApplyConfig(mgr, store, config); err != nil {
+		return err
+	}
 
 	// Register tokens for reverse proxy
 	for _, tc := range config.Tokens {
@@ -216,6 +577,19 @@ func loadTokens(h *proxy.ReverseHandler) {
 	}
 }
 
+// splitDomains parses a comma-separated --tls-domains value, trimming
+// whitespace around each entry and dropping empty ones (so "" parses to
+// no domains rather than one blank domain).
+func splitDomains(s string) []string {
+	var domains []string
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
 func splitFirst(s, sep string) []string {
 	idx := -1
 	for i := 0; i < len(s)-len(sep)+1; i++ {
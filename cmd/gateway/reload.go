@@ -0,0 +1,159 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+	"github.com/Extra-Chill/plasma-shield/internal/rules"
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
+)
+
+// reloadDebounce absorbs a burst of fsnotify events from a single editor
+// save (which often emits several Write/Rename events in a row) into one
+// reload, the same debounce rules.Watcher and api.RulesLoader use.
+const reloadDebounce = 100 * time.Millisecond
+
+// reloader hot-reloads the gateway's rules and fleet/token config on
+// SIGHUP or a change to either file, atomically swapping each
+// component's in-memory state behind its own RWMutex and logging what
+// changed. A reload whose file fails to parse is rejected; the
+// previously-serving config keeps running.
+type reloader struct {
+	rulesEngine    *rules.Engine
+	fleetMgr       *fleet.Manager
+	reverseHandler *proxy.ReverseHandler
+	store          storage.Store
+
+	rulesFile  string
+	agentsFile string
+
+	watcher *fsnotify.Watcher
+	sigCh   chan os.Signal
+	done    chan struct{}
+}
+
+// startReloader watches rulesFile and agentsFile (either may be empty to
+// skip it) for changes and wires SIGHUP to trigger a reload of both.
+// Close must be called on shutdown to stop the watcher goroutine.
+func startReloader(rulesEngine *rules.Engine, fleetMgr *fleet.Manager, reverseHandler *proxy.ReverseHandler, store storage.Store, rulesFile, agentsFile string) (*reloader, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if rulesFile != "" {
+		if err := fw.Add(rulesFile); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+	if agentsFile != "" {
+		if err := fw.Add(agentsFile); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+
+	r := &reloader{
+		rulesEngine:    rulesEngine,
+		fleetMgr:       fleetMgr,
+		reverseHandler: reverseHandler,
+		store:          store,
+		rulesFile:      rulesFile,
+		agentsFile:     agentsFile,
+		watcher:        fw,
+		sigCh:          make(chan os.Signal, 1),
+		done:           make(chan struct{}),
+	}
+	signal.Notify(r.sigCh, syscall.SIGHUP)
+	go r.run()
+	return r, nil
+}
+
+func (r *reloader) run() {
+	var pending *time.Timer
+	reload := func() { r.reloadAll() }
+
+	for {
+		select {
+		case event, ok := <-r.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if pending != nil {
+				pending.Stop()
+			}
+			pending = time.AfterFunc(reloadDebounce, reload)
+
+		case err, ok := <-r.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("config watcher error: %v", err)
+
+		case <-r.sigCh:
+			log.Println("received SIGHUP, reloading config")
+			r.reloadAll()
+
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// reloadAll reloads the rules file and the agents/tokens file
+// independently, each failing or succeeding on its own, so a typo in one
+// doesn't block the other from picking up a legitimate change.
+func (r *reloader) reloadAll() {
+	if r.rulesFile != "" {
+		before := r.rulesEngine.RuleCount()
+		if err := r.rulesEngine.Reload(); err != nil {
+			log.Printf("reload: rules file %s: rejected, keeping previous rules: %v", r.rulesFile, err)
+		} else if after := r.rulesEngine.RuleCount(); after != before {
+			log.Printf("reload: rules file %s: %d rule(s) now loaded (was %d)", r.rulesFile, after, before)
+		} else {
+			log.Printf("reload: rules file %s: no changes", r.rulesFile)
+		}
+	}
+
+	if r.agentsFile == "" {
+		return
+	}
+
+	config, diff, err := r.fleetMgr.Reload(r.store)
+	if err != nil {
+		log.Printf("reload: agents file %s: rejected, keeping previous fleet config: %v", r.agentsFile, err)
+		return
+	}
+	if diff.Empty() {
+		log.Printf("reload: agents file %s: no changes to tenants/agents", r.agentsFile)
+	} else {
+		log.Printf("reload: agents file %s: tenants added=%v removed=%v, agents added=%v removed=%v",
+			r.agentsFile, diff.TenantsAdded, diff.TenantsRemoved, diff.AgentsAdded, diff.AgentsRemoved)
+	}
+
+	tokens := make(map[string]string, len(config.Tokens))
+	for _, tc := range config.Tokens {
+		tokens[tc.Token] = tc.TenantID
+	}
+	added, removed := r.reverseHandler.ReplaceTokens(tokens)
+	if len(added) > 0 || len(removed) > 0 {
+		log.Printf("reload: agents file %s: tokens added for tenants=%v, %d token(s) removed", r.agentsFile, added, len(removed))
+	}
+}
+
+// Close stops the watcher goroutine and the SIGHUP handler.
+func (r *reloader) Close() error {
+	close(r.done)
+	signal.Stop(r.sigCh)
+	return r.watcher.Close()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// retryUntilReady calls fn until it succeeds, timeout elapses, or (when
+// timeout is zero) after the first attempt - the current fail-fast
+// behavior, kept as the default so --startup-retry-timeout is opt-in.
+// label identifies the step being retried in the progress log.
+func retryUntilReady(label string, timeout, sleep time.Duration, fn func() error) error {
+	if timeout <= 0 {
+		return fn()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("startup: %s ready after %d attempt(s)", label, attempt)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: not ready after %d attempt(s) over %s: %w", label, attempt, timeout, err)
+		}
+		log.Printf("startup: %s not ready (attempt %d): %v, retrying in %s", label, attempt, err, sleep)
+		time.Sleep(sleep)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+)
+
+// tlsHandshakeLogWriter wraps an http.Server's ErrorLog output to count
+// failed inbound TLS handshakes: net/http logs these itself (there's no
+// hook into crypto/tls for a failed ServerHandshake), so we recognize its
+// "TLS handshake error from ..." lines and increment the metric before
+// passing the line through to the real logger unchanged.
+type tlsHandshakeLogWriter struct {
+	metrics  *limiter.Metrics
+	listener string
+}
+
+func (w tlsHandshakeLogWriter) Write(p []byte) (int, error) {
+	if w.metrics != nil && strings.Contains(string(p), "TLS handshake error") {
+		w.metrics.IncTLSHandshakeFailure(w.listener)
+	}
+	log.Print(string(p))
+	return len(p), nil
+}
+
+// newTLSAwareErrorLog returns an *log.Logger suitable for http.Server.ErrorLog
+// that counts TLS handshake failures against metrics (if non-nil) for the
+// named listener, e.g. "inbound".
+func newTLSAwareErrorLog(metrics *limiter.Metrics, listener string) *log.Logger {
+	return log.New(tlsHandshakeLogWriter{metrics: metrics, listener: listener}, "", 0)
+}
@@ -5,14 +5,21 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -37,6 +44,15 @@ func main() {
 	// Parse flags but stop at first non-flag (subcommand)
 	flag.Parse()
 
+	// --token / PLASMA_TOKEN take priority; fall back to a credential saved
+	// by a prior `auth login` or `auth create-token`.
+	if authToken == "" {
+		if cred, err := loadCredential(); err == nil && cred != nil {
+			authToken = cred.Secret
+			maybeRenewCredential(cred)
+		}
+	}
+
 	args := flag.Args()
 	if len(args) < 1 {
 		printUsage()
@@ -70,7 +86,7 @@ func main() {
 		handleAgent(args[1:])
 	case "rules":
 		if len(args) < 2 {
-			fmt.Println("Usage: plasma-shield rules <list|add|remove> [options]")
+			fmt.Println("Usage: plasma-shield rules <list|add|remove|export|import> [options]")
 			os.Exit(1)
 		}
 		handleRules(args[1:])
@@ -78,7 +94,7 @@ func main() {
 		handleLogs(args[1:])
 	case "auth":
 		if len(args) < 2 {
-			fmt.Println("Usage: plasma-shield auth <login|logout>")
+			fmt.Println("Usage: plasma-shield auth <login|logout|create-token|list-tokens|revoke|whoami>")
 			os.Exit(1)
 		}
 		handleAuth(args[1:])
@@ -88,6 +104,20 @@ func main() {
 			os.Exit(1)
 		}
 		handleAccess(args[1:])
+	case "approvals":
+		if len(args) < 2 {
+			fmt.Println("Usage: plasma-shield approvals <list|resolve|watch> [options]")
+			os.Exit(1)
+		}
+		handleApprovals(args[1:])
+	case "stats":
+		handleStats(args[1:])
+	case "ca":
+		if len(args) < 2 {
+			fmt.Println("Usage: plasma-shield ca <init> [options]")
+			os.Exit(1)
+		}
+		handleCA(args[1:])
 	default:
 		fmt.Printf("Unknown command: %s\n", args[0])
 		printUsage()
@@ -115,11 +145,14 @@ Global Flags:
 Commands:
   status          Show shield connection status
   mode            Set global operating mode (enforce/audit/lockdown)
-  agent           Manage agents (list, pause, kill, resume)
+  agent           Manage agents (list, pause, kill, resume, issue-svid, rotate-ca, trust-bundle)
   rules           Manage blocking rules
   access          Manage SSH bastion access grants
   logs            View traffic logs
-  auth            Authentication (login, logout)
+  auth            Authentication (login, logout, create-token, list-tokens, revoke, whoami)
+  approvals       Review commands held by a "review" rule (list, resolve, watch)
+  stats           Per-rule hit time series and noisiest rules/agents
+  ca              Manage the MITM interception root CA (init)
   version         Show version
 
 Modes:
@@ -135,13 +168,32 @@ Examples:
   plasma-shield agent pause sarai             # Pause an agent
   plasma-shield agent kill sarai              # Emergency stop
   plasma-shield agent resume sarai            # Resume a paused agent
+  plasma-shield agent issue-svid sarai --tenant acme  # Issue an enrollment secret
+  plasma-shield agent rotate-ca                       # Rotate the agent CA key
+  plasma-shield agent trust-bundle -o bundle.json     # Fetch the SPIFFE trust bundle
   plasma-shield rules list
   plasma-shield rules add --pattern "rm -rf" --action block
   plasma-shield rules remove <rule-id>
+  plasma-shield rules export -o rules.yaml        # Keep rule bundles in git
+  plasma-shield rules import rules.yaml --dry-run # Preview before applying
   plasma-shield access grant --target sarai-chinwag --duration 30m
   plasma-shield access list
   plasma-shield access revoke <grant-id>
+  plasma-shield auth login --secret <token>       # Save a credential for future commands
+  plasma-shield auth create-token --desc "laptop" --policy admin --ttl 24h
+  plasma-shield auth list-tokens
+  plasma-shield auth revoke <accessor-id>
+  plasma-shield auth whoami
+  plasma-shield auth logout
+  plasma-shield approvals list
+  plasma-shield approvals resolve <approval-id> --decision allow
+  plasma-shield approvals watch                   # Prompt for each pending approval as it arrives
+  plasma-shield stats --rule <rule-id> --since 24h --step 5m
+  plasma-shield stats --rule <rule-id> --since 1h --format prometheus
+  plasma-shield stats top --by rule --since 1h --limit 20   # Noisiest rules
+  plasma-shield stats top --by agent --since 1h             # Agents with the most blocks
   plasma-shield logs --limit 50 --agent sarai
+  plasma-shield ca init -o mitm-ca.crt                # Fetch the MITM root CA for agents to trust
 
 Documentation: https://github.com/Extra-Chill/plasma-shield`)
 }
@@ -184,6 +236,68 @@ func apiRequest(method, path string, body interface{}) ([]byte, int, error) {
 	return respBody, resp.StatusCode, nil
 }
 
+// apiRequestRaw is apiRequest's counterpart for non-JSON bodies, used by
+// rules export/import: body is sent as-is under contentType instead of
+// being json.Marshal'd, and the response is returned as raw bytes instead
+// of being assumed to be JSON.
+func apiRequestRaw(method, path, contentType string, body []byte) ([]byte, int, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	url := strings.TrimSuffix(apiURL, "/") + path
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authToken)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return respBody, resp.StatusCode, nil
+}
+
+// apiRequestStream is apiRequest's streaming counterpart for long-lived
+// connections like GET /logs/stream: it returns the live response body
+// instead of buffering it. The caller must Close the returned ReadCloser.
+// There's no client-side read timeout -- ctx is the only thing that can
+// end the connection, so callers cancel it (on Ctrl-C, or an elapsed
+// --duration) instead of relying on a deadline.
+func apiRequestStream(ctx context.Context, method, path string) (io.ReadCloser, int, error) {
+	url := strings.TrimSuffix(apiURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request failed: %w", err)
+	}
+	return resp.Body, resp.StatusCode, nil
+}
+
 func outputJSON(data interface{}) {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
@@ -248,7 +362,7 @@ func handleStatus() {
 
 func handleAgent(args []string) {
 	if len(args) < 1 {
-		fmt.Println("Usage: plasma-shield agent <list|pause|kill|resume> [agent-id]")
+		fmt.Println("Usage: plasma-shield agent <list|pause|kill|resume|issue-svid|rotate-ca|trust-bundle> [agent-id]")
 		os.Exit(1)
 	}
 
@@ -406,14 +520,170 @@ func handleAgent(args []string) {
 			fmt.Printf("✓ %s\n", response.Message)
 		}
 
+	case "issue-svid":
+		if len(args) < 2 {
+			exitError("agent ID required: plasma-shield agent issue-svid <agent-id> --tenant <tenant-id> [--tier tier] [--ttl 1h]", 1)
+		}
+		agentID := args[1]
+
+		svidFlags := flag.NewFlagSet("agent issue-svid", flag.ExitOnError)
+		tenant := svidFlags.String("tenant", "", "Tenant ID this agent belongs to")
+		tier := svidFlags.String("tier", "", "Agent tier")
+		ttl := svidFlags.String("ttl", "", "Enrollment secret lifetime (e.g. 1h); empty uses the server default")
+		svidFlags.Parse(args[2:])
+
+		if *tenant == "" {
+			exitError("--tenant is required", 1)
+		}
+
+		reqBody := map[string]interface{}{
+			"tenant_id": *tenant,
+			"agent_id":  agentID,
+			"tier":      *tier,
+		}
+		if *ttl != "" {
+			d, err := time.ParseDuration(*ttl)
+			if err != nil {
+				exitError(fmt.Sprintf("invalid --ttl %q: %v", *ttl, err), 1)
+			}
+			reqBody["ttl_seconds"] = int(d.Seconds())
+		}
+
+		respBody, statusCode, err := apiRequest("POST", "/agents/enroll-secrets", reqBody)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+
+		if statusCode != http.StatusCreated {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			Secret    string    `json:"secret"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		json.Unmarshal(respBody, &response)
+
+		if jsonOut {
+			outputJSON(response)
+		} else {
+			fmt.Printf("✓ Enrollment secret issued for %s\n", agentID)
+			fmt.Printf("  Secret:  %s\n", response.Secret)
+			fmt.Printf("  Expires: %s\n", response.ExpiresAt.Format("2006-01-02 15:04:05 UTC"))
+			fmt.Println("  Redeem it with POST /agents/enroll (secret + CSR) to get a client certificate.")
+		}
+
+	case "rotate-ca":
+		respBody, statusCode, err := apiRequest("POST", "/agents/rotate-ca", nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			Certificate string    `json:"certificate"`
+			ValidBefore time.Time `json:"valid_before"`
+		}
+		json.Unmarshal(respBody, &response)
+
+		if jsonOut {
+			outputJSON(response)
+		} else {
+			fmt.Println("✓ Agent CA rotated. Existing agents must re-enroll (agent reenroll) before the old CA is removed from circulation.")
+			fmt.Printf("  New CA valid until: %s\n", response.ValidBefore.Format("2006-01-02 15:04:05 UTC"))
+		}
+
+	case "trust-bundle":
+		bundleFlags := flag.NewFlagSet("agent trust-bundle", flag.ExitOnError)
+		outFile := bundleFlags.String("o", "", "Write to this file instead of stdout")
+		bundleFlags.Parse(args[1:])
+
+		respBody, statusCode, err := apiRequest("GET", "/agents/trust-bundle", nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		if *outFile != "" {
+			if err := os.WriteFile(*outFile, respBody, 0644); err != nil {
+				exitError(fmt.Sprintf("failed to write %s: %v", *outFile, err), 1)
+			}
+			fmt.Printf("✓ Wrote trust bundle to %s\n", *outFile)
+		} else {
+			os.Stdout.Write(respBody)
+		}
+
+	default:
+		exitError(fmt.Sprintf("unknown agent action: %s (use list, pause, kill, resume, issue-svid, rotate-ca, or trust-bundle)", action), 1)
+	}
+}
+
+// handleCA manages the forward proxy's MITM interception root CA (see
+// proxy.WithMITM), distinct from the agent mTLS CA handled by
+// handleAgent's issue-svid/rotate-ca/trust-bundle actions.
+func handleCA(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: plasma-shield ca <init> [options]")
+		os.Exit(1)
+	}
+
+	action := args[0]
+
+	switch action {
+	case "init":
+		initFlags := flag.NewFlagSet("ca init", flag.ExitOnError)
+		outFile := initFlags.String("o", "", "Write to this file instead of stdout")
+		initFlags.Parse(args[1:])
+
+		respBody, statusCode, err := apiRequest("GET", "/mitm/ca-cert", nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		if *outFile != "" {
+			if err := os.WriteFile(*outFile, respBody, 0644); err != nil {
+				exitError(fmt.Sprintf("failed to write %s: %v", *outFile, err), 1)
+			}
+			fmt.Printf("✓ Wrote MITM root CA certificate to %s\n", *outFile)
+			fmt.Println("  Trust this certificate on each agent before enabling MITM interception.")
+		} else {
+			os.Stdout.Write(respBody)
+		}
+
 	default:
-		exitError(fmt.Sprintf("unknown agent action: %s (use list, pause, kill, or resume)", action), 1)
+		exitError(fmt.Sprintf("unknown ca action: %s (use init)", action), 1)
 	}
 }
 
 func handleRules(args []string) {
 	if len(args) < 1 {
-		fmt.Println("Usage: plasma-shield rules <list|add|remove> [options]")
+		fmt.Println("Usage: plasma-shield rules <list|add|remove|export|import> [options]")
 		os.Exit(1)
 	}
 
@@ -569,11 +839,114 @@ func handleRules(args []string) {
 			fmt.Printf("✓ %s\n", response.Message)
 		}
 
+	case "export":
+		exportFlags := flag.NewFlagSet("rules export", flag.ExitOnError)
+		group := exportFlags.String("group", "", "Only export this group's rules")
+		outFile := exportFlags.String("o", "", "Write to this file instead of stdout")
+		exportFlags.Parse(args[1:])
+
+		path := "/rules?format=bundle"
+		if *group != "" {
+			path += "&group=" + url.QueryEscape(*group)
+		}
+
+		respBody, statusCode, err := apiRequestRaw("GET", path, "", nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if statusCode != http.StatusOK {
+			exitError(fmt.Sprintf("API error: unexpected status %d", statusCode), 1)
+		}
+
+		if *outFile != "" {
+			if err := os.WriteFile(*outFile, respBody, 0644); err != nil {
+				exitError(fmt.Sprintf("failed to write %s: %v", *outFile, err), 1)
+			}
+			fmt.Printf("✓ Wrote rule bundle to %s\n", *outFile)
+		} else {
+			os.Stdout.Write(respBody)
+		}
+
+	case "import":
+		if len(args) < 2 {
+			exitError("bundle file required: plasma-shield rules import <file> [--dry-run] [--replace]", 1)
+		}
+		importFlags := flag.NewFlagSet("rules import", flag.ExitOnError)
+		dryRun := importFlags.Bool("dry-run", false, "Preview the diff without persisting")
+		replace := importFlags.Bool("replace", false, "Replace the entire ruleset instead of merging by rule ID")
+		importFlags.Parse(args[2:])
+
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			exitError(fmt.Sprintf("failed to read %s: %v", args[1], err), 1)
+		}
+
+		query := url.Values{}
+		if *dryRun {
+			query.Set("dry_run", "true")
+		}
+		if *replace {
+			query.Set("replace", "true")
+		}
+
+		respBody, statusCode, err := apiRequestRaw("POST", "/rules/bundle?"+query.Encode(), "application/x-yaml", data)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			Status           string          `json:"status"`
+			Diff             rulesBundleDiff `json:"diff"`
+			RuleCount        int             `json:"rule_count"`
+			WouldBeRuleCount int             `json:"would_be_rule_count"`
+			RulesHash        string          `json:"rules_hash"`
+		}
+		json.Unmarshal(respBody, &response)
+
+		if jsonOut {
+			outputJSON(response)
+		} else {
+			if *dryRun {
+				fmt.Println("Dry run (no changes persisted):")
+			} else {
+				fmt.Println("✓ Bundle imported:")
+			}
+			fmt.Printf("  added:    %d\n", len(response.Diff.Added))
+			fmt.Printf("  removed:  %d\n", len(response.Diff.Removed))
+			fmt.Printf("  modified: %d\n", len(response.Diff.Modified))
+			for _, id := range response.Diff.Added {
+				fmt.Printf("    + %s\n", id)
+			}
+			for _, id := range response.Diff.Removed {
+				fmt.Printf("    - %s\n", id)
+			}
+			for _, id := range response.Diff.Modified {
+				fmt.Printf("    ~ %s\n", id)
+			}
+		}
+
 	default:
-		exitError(fmt.Sprintf("unknown rules action: %s (use list, add, or remove)", action), 1)
+		exitError(fmt.Sprintf("unknown rules action: %s (use list, add, remove, export, or import)", action), 1)
 	}
 }
 
+// rulesBundleDiff mirrors rules.BundleDiff's JSON shape -- the added/
+// removed/modified rule IDs POST /rules/bundle reports, whether persisted
+// or previewed via --dry-run.
+type rulesBundleDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
 func handleLogs(args []string) {
 	// Parse logs flags
 	logsFlags := flag.NewFlagSet("logs", flag.ExitOnError)
@@ -582,9 +955,17 @@ func handleLogs(args []string) {
 	agentID := logsFlags.String("agent", "", "Filter by agent ID")
 	actionFilter := logsFlags.String("action", "", "Filter by action (allowed/blocked)")
 	typeFilter := logsFlags.String("type", "", "Filter by type (command/http/dns)")
+	follow := logsFlags.Bool("follow", false, "Stream new logs live (tail -f style) instead of a single page")
+	logsFlags.BoolVar(follow, "f", false, "Shorthand for --follow")
+	duration := logsFlags.String("duration", "", "With --follow, stop after this long (e.g. 5m); empty follows until Ctrl-C")
 
 	logsFlags.Parse(args)
 
+	if *follow {
+		followLogs(*agentID, *actionFilter, *typeFilter, *duration)
+		return
+	}
+
 	// Build query string
 	query := fmt.Sprintf("?limit=%d&offset=%d", *limit, *offset)
 	if *agentID != "" {
@@ -654,6 +1035,165 @@ func handleLogs(args []string) {
 	}
 }
 
+// streamedLogEntry is one `data:` event from GET /logs/stream -- the same
+// shape as the entries under handleLogs's plain GET /logs response.
+type streamedLogEntry struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	AgentID   string    `json:"agent_id"`
+	Type      string    `json:"type"`
+	Request   string    `json:"request"`
+	Action    string    `json:"action"`
+	RuleID    string    `json:"rule_id,omitempty"`
+}
+
+// followLogs opens a long-lived GET /logs/stream connection (Server-Sent
+// Events) and prints each entry as it arrives, like `tail -f`. A dropped
+// connection is retried with exponential backoff, resuming from the last
+// log ID seen via ?after= so a reconnect doesn't lose events in between.
+// Ctrl-C, or an elapsed --duration, cancels ctx and ends the loop cleanly.
+func followLogs(agentID, actionFilter, typeFilter, durationStr string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if durationStr != "" {
+		d, err := time.ParseDuration(durationStr)
+		if err != nil {
+			exitError(fmt.Sprintf("invalid --duration %q: %v", durationStr, err), 1)
+		}
+		var durationCancel context.CancelFunc
+		ctx, durationCancel = context.WithTimeout(ctx, d)
+		defer durationCancel()
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	const minBackoff = time.Second
+	const maxBackoff = 30 * time.Second
+
+	var lastID string
+	backoff := minBackoff
+	for {
+		query := url.Values{}
+		if agentID != "" {
+			query.Set("agent_id", agentID)
+		}
+		if actionFilter != "" {
+			query.Set("action", actionFilter)
+		}
+		if typeFilter != "" {
+			query.Set("type", typeFilter)
+		}
+		if lastID != "" {
+			query.Set("after", lastID)
+		}
+
+		body, statusCode, err := apiRequestStream(ctx, "GET", "/logs/stream?"+query.Encode())
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "plasma-shield: connect to %s: %v; retrying in %s\n", apiURL, err, backoff)
+			if !sleepOrDone(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff, maxBackoff)
+			continue
+		}
+
+		if statusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(body)
+			body.Close()
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: unexpected status %d (%s)", statusCode, errResp.Error), 1)
+		}
+
+		backoff = minBackoff
+		seen, streamErr := streamLogEntries(body, lastID)
+		body.Close()
+		if seen != "" {
+			lastID = seen
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+		if streamErr != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shield: stream closed: %v; reconnecting\n", streamErr)
+		}
+		if !sleepOrDone(ctx, backoff) {
+			return
+		}
+		backoff = nextBackoff(backoff, maxBackoff)
+	}
+}
+
+// streamLogEntries reads Server-Sent Events from body until it closes or
+// errors, printing each "data:" line as a parsed streamedLogEntry. It
+// returns the last entry ID seen (lastID unchanged if none were), for
+// resuming via ?after= on the next connection.
+func streamLogEntries(body io.Reader, lastID string) (string, error) {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		data := strings.TrimPrefix(line, "data: ")
+		if data == line {
+			continue // not a data line (blank, "event: ...", etc.)
+		}
+
+		var entry streamedLogEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue
+		}
+		if entry.ID != "" {
+			lastID = entry.ID
+		}
+
+		if jsonOut {
+			fmt.Println(data)
+			continue
+		}
+		actionIcon := "✓"
+		if entry.Action == "blocked" {
+			actionIcon = "✗"
+		}
+		request := entry.Request
+		if len(request) > 50 {
+			request = request[:47] + "..."
+		}
+		fmt.Printf("%s %s %-10s %-8s %s\n",
+			actionIcon, entry.Timestamp.Format("15:04:05"), entry.AgentID, entry.Action, request)
+	}
+	return lastID, scanner.Err()
+}
+
+func nextBackoff(cur, max time.Duration) time.Duration {
+	next := cur * 2
+	if next > max {
+		return max
+	}
+	return next
+}
+
+// sleepOrDone waits for d, or returns false early if ctx ends first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func handleMode(args []string) {
 	if len(args) < 1 {
 		exitError("mode required: enforce, audit, or lockdown", 1)
@@ -710,33 +1250,382 @@ func handleMode(args []string) {
 
 func handleAuth(args []string) {
 	if len(args) < 1 {
-		fmt.Println("Usage: plasma-shield auth <login|logout>")
+		fmt.Println("Usage: plasma-shield auth <login|logout|create-token|list-tokens|revoke|whoami>")
 		os.Exit(1)
 	}
 
 	action := args[0]
 	switch action {
 	case "login":
-		if jsonOut {
-			outputJSON(map[string]string{
-				"message": "Authentication not yet implemented",
-				"hint":    "Set PLASMA_TOKEN environment variable or use --token flag",
-			})
-		} else {
-			fmt.Println("Authentication not yet implemented")
-			fmt.Println("Set PLASMA_TOKEN environment variable or use --token flag")
+		loginFlags := flag.NewFlagSet("auth login", flag.ExitOnError)
+		accessor := loginFlags.String("accessor", "", "Token accessor ID (informational only)")
+		secret := loginFlags.String("secret", "", "Token secret (prompted if omitted)")
+		loginFlags.Parse(args[1:])
+
+		if *secret == "" {
+			fmt.Print("Secret: ")
+			reader := bufio.NewReader(os.Stdin)
+			line, _ := reader.ReadString('\n')
+			*secret = strings.TrimSpace(line)
+		}
+		if *secret == "" {
+			exitError("secret is required", 1)
+		}
+
+		introspect, err := introspectSecret(*secret)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if !introspect.Valid {
+			exitError("credential rejected by server", 1)
+		}
+
+		cred := &storedCredential{AccessorID: introspect.AccessorID, Secret: *secret, ExpiresAt: introspect.ExpiresAt}
+		if *accessor != "" {
+			cred.AccessorID = *accessor
+		}
+		if err := saveCredential(cred); err != nil {
+			exitError(fmt.Sprintf("failed to save credential: %v", err), 1)
+		}
+
+		if jsonOut {
+			outputJSON(introspect)
+		} else {
+			fmt.Println("✓ Logged in")
+			if introspect.Kind != "" {
+				fmt.Printf("  Kind: %s\n", introspect.Kind)
+			}
+			if introspect.ExpiresAt != nil {
+				fmt.Printf("  Expires: %s\n", introspect.ExpiresAt.Format("2006-01-02 15:04:05 UTC"))
+			}
 		}
+
 	case "logout":
+		if err := clearCredential(); err != nil {
+			exitError(fmt.Sprintf("failed to remove credential: %v", err), 1)
+		}
+		if jsonOut {
+			outputJSON(map[string]string{"message": "Logged out"})
+		} else {
+			fmt.Println("✓ Logged out")
+		}
+
+	case "create-token":
+		createFlags := flag.NewFlagSet("auth create-token", flag.ExitOnError)
+		desc := createFlags.String("desc", "", "Token description")
+		var policies stringSliceFlag
+		createFlags.Var(&policies, "policy", "Policy/scope to grant (repeatable, e.g. --policy rules:write)")
+		ttl := createFlags.String("ttl", "", "Token TTL (e.g. 24h); empty never expires")
+		renewable := createFlags.Bool("renewable", false, "Allow this token to renew its own TTL before it expires")
+		createFlags.Parse(args[1:])
+
+		if len(policies.values) == 0 {
+			exitError("at least one --policy is required (e.g. --policy admin)", 1)
+		}
+
+		reqBody := map[string]interface{}{
+			"scopes":    policies.values,
+			"name":      *desc,
+			"renewable": *renewable,
+		}
+		if *ttl != "" {
+			d, err := time.ParseDuration(*ttl)
+			if err != nil {
+				exitError(fmt.Sprintf("invalid --ttl %q: %v", *ttl, err), 1)
+			}
+			reqBody["ttl_seconds"] = int(d.Seconds())
+		}
+
+		respBody, statusCode, err := apiRequest("POST", "/auth/token", reqBody)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if statusCode != http.StatusCreated {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			AccessorID string     `json:"accessor_id"`
+			Token      string     `json:"token"`
+			Scopes     []string   `json:"scopes"`
+			ExpiresAt  *time.Time `json:"expires_at"`
+		}
+		json.Unmarshal(respBody, &response)
+
+		if err := saveCredential(&storedCredential{AccessorID: response.AccessorID, Secret: response.Token, ExpiresAt: response.ExpiresAt}); err != nil {
+			exitError(fmt.Sprintf("token was created but failed to save locally: %v", err), 1)
+		}
+
+		if jsonOut {
+			outputJSON(response)
+		} else {
+			fmt.Println("✓ Token created (secret shown once, also saved locally):")
+			fmt.Printf("  Accessor: %s\n", response.AccessorID)
+			fmt.Printf("  Secret:   %s\n", response.Token)
+			fmt.Printf("  Policies: %s\n", strings.Join(response.Scopes, ", "))
+			if response.ExpiresAt != nil {
+				fmt.Printf("  Expires:  %s\n", response.ExpiresAt.Format("2006-01-02 15:04:05 UTC"))
+			}
+		}
+
+	case "list-tokens":
+		respBody, statusCode, err := apiRequest("GET", "/auth/tokens", nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			Tokens []struct {
+				AccessorID string     `json:"accessor_id"`
+				Scopes     []string   `json:"scopes"`
+				Name       string     `json:"name,omitempty"`
+				CreatedAt  time.Time  `json:"created_at"`
+				ExpiresAt  *time.Time `json:"expires_at"`
+			} `json:"tokens"`
+			Total int `json:"total"`
+		}
+		if err := json.Unmarshal(respBody, &response); err != nil {
+			exitError(fmt.Sprintf("failed to parse response: %v", err), 1)
+		}
+
+		if jsonOut {
+			outputJSON(response)
+		} else {
+			if response.Total == 0 {
+				fmt.Println("No tokens issued")
+			} else {
+				fmt.Printf("Tokens (%d total):\n", response.Total)
+				fmt.Println("─────────────────────────────────────────────────────────────")
+				for _, t := range response.Tokens {
+					remaining := "never"
+					if t.ExpiresAt != nil {
+						remaining = time.Until(*t.ExpiresAt).Round(time.Second).String()
+					}
+					fmt.Printf("%-20s %-30s (expires in %s)\n", t.AccessorID, strings.Join(t.Scopes, ","), remaining)
+				}
+			}
+		}
+
+	case "revoke":
+		if len(args) < 2 {
+			exitError("accessor required: plasma-shield auth revoke <accessor>", 1)
+		}
+		accessor := args[1]
+
+		respBody, statusCode, err := apiRequest("DELETE", "/auth/token/"+accessor, nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &response)
+
+		if jsonOut {
+			outputJSON(response)
+		} else {
+			fmt.Printf("✓ %s\n", response.Message)
+		}
+
+	case "whoami":
+		introspect, err := introspectSecret(authToken)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+
 		if jsonOut {
-			outputJSON(map[string]string{
-				"message": "Logged out (clear PLASMA_TOKEN to complete)",
-			})
+			outputJSON(introspect)
+		} else if !introspect.Valid {
+			fmt.Println("Not authenticated")
 		} else {
-			fmt.Println("Logged out (clear PLASMA_TOKEN to complete)")
+			fmt.Printf("Kind:     %s\n", introspect.Kind)
+			if introspect.AccessorID != "" {
+				fmt.Printf("Accessor: %s\n", introspect.AccessorID)
+			}
+			if len(introspect.Scopes) > 0 {
+				fmt.Printf("Policies: %s\n", strings.Join(introspect.Scopes, ", "))
+			}
+			if introspect.ExpiresAt != nil {
+				fmt.Printf("Expires:  %s\n", introspect.ExpiresAt.Format("2006-01-02 15:04:05 UTC"))
+			}
 		}
+
 	default:
-		exitError(fmt.Sprintf("unknown auth action: %s", action), 1)
+		exitError(fmt.Sprintf("unknown auth action: %s (use login, logout, create-token, list-tokens, revoke, or whoami)", action), 1)
+	}
+}
+
+// introspectResult mirrors api.IntrospectResponse's JSON shape.
+type introspectResult struct {
+	Valid      bool       `json:"valid"`
+	AccessorID string     `json:"accessor_id,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	Kind       string     `json:"kind,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// introspectSecret validates secret against GET /auth/introspect, without
+// disturbing the global authToken used for every other command.
+func introspectSecret(secret string) (*introspectResult, error) {
+	prevToken := authToken
+	authToken = secret
+	respBody, statusCode, err := apiRequest("GET", "/auth/introspect", nil)
+	authToken = prevToken
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error: unexpected status %d", statusCode)
 	}
+
+	var result introspectResult
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &result, nil
+}
+
+// stringSliceFlag is a flag.Value that appends each --flag occurrence
+// instead of overwriting the previous one, for repeatable flags like
+// --policy.
+type stringSliceFlag struct {
+	values []string
+}
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(s.values, ",")
+}
+
+func (s *stringSliceFlag) Set(v string) error {
+	s.values = append(s.values, v)
+	return nil
+}
+
+// storedCredential is the shape persisted to credentialsPath by `auth
+// login` and `auth create-token`, and read back as the --token/PLASMA_TOKEN
+// fallback.
+type storedCredential struct {
+	AccessorID string     `json:"accessor_id,omitempty"`
+	Secret     string     `json:"secret"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// credentialsPath returns $XDG_CONFIG_HOME/plasma-shield/credentials.json
+// (or the OS equivalent, via os.UserConfigDir).
+func credentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve config directory: %w", err)
+	}
+	return filepath.Join(dir, "plasma-shield", "credentials.json"), nil
+}
+
+// loadCredential reads the saved credential, returning (nil, nil) if none
+// has been saved yet.
+func loadCredential() (*storedCredential, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cred storedCredential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &cred, nil
+}
+
+// saveCredential persists cred with 0600 permissions, creating the parent
+// directory if needed.
+func saveCredential(cred *storedCredential) error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// clearCredential removes the saved credential, if any.
+func clearCredential() error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// renewalWindow is how far ahead of expiry maybeRenewCredential tries to
+// renew a stored credential, so a long-running session of commands doesn't
+// get cut off mid-use.
+const renewalWindow = 5 * time.Minute
+
+// maybeRenewCredential renews cred against POST /auth/token/renew when it's
+// within renewalWindow of expiring, updating the saved credential file on
+// success. Renewal is opportunistic: a token that isn't Renewable, or any
+// request failure, is silently ignored, since the current command should
+// still run with whatever TTL remains.
+func maybeRenewCredential(cred *storedCredential) {
+	if cred.ExpiresAt == nil {
+		return
+	}
+	remaining := time.Until(*cred.ExpiresAt)
+	if remaining <= 0 || remaining > renewalWindow {
+		return
+	}
+
+	respBody, statusCode, err := apiRequest("POST", "/auth/token/renew", nil)
+	if err != nil || statusCode != http.StatusOK {
+		return
+	}
+
+	var response struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return
+	}
+
+	cred.ExpiresAt = &response.ExpiresAt
+	saveCredential(cred)
 }
 
 func handleAccess(args []string) {
@@ -900,3 +1789,439 @@ func handleAccess(args []string) {
 		exitError(fmt.Sprintf("unknown access action: %s (use grant, list, or revoke)", action), 1)
 	}
 }
+
+// approvalEntry mirrors api.Approval's JSON shape.
+type approvalEntry struct {
+	ID          string    `json:"id"`
+	AgentID     string    `json:"agent_id"`
+	Command     string    `json:"command"`
+	RuleID      string    `json:"rule_id,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	Resolved    bool      `json:"resolved"`
+	Decision    string    `json:"decision,omitempty"`
+	ResolvedBy  string    `json:"resolved_by,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+func handleApprovals(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: plasma-shield approvals <list|resolve|watch> [options]")
+		os.Exit(1)
+	}
+
+	action := args[0]
+
+	switch action {
+	case "list":
+		respBody, statusCode, err := apiRequest("GET", "/approvals", nil)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		var response struct {
+			Approvals []approvalEntry `json:"approvals"`
+			Total     int             `json:"total"`
+		}
+		if err := json.Unmarshal(respBody, &response); err != nil {
+			exitError(fmt.Sprintf("failed to parse response: %v", err), 1)
+		}
+
+		if jsonOut {
+			outputJSON(response)
+		} else if response.Total == 0 {
+			fmt.Println("No pending approvals")
+		} else {
+			fmt.Printf("Pending approvals (%d total):\n", response.Total)
+			fmt.Println("─────────────────────────────────────────────────────────────")
+			for _, a := range response.Approvals {
+				fmt.Printf("%-24s %-10s rule=%-14s %s\n", a.ID, a.AgentID, a.RuleID, a.Command)
+			}
+		}
+
+	case "resolve":
+		resolveFlags := flag.NewFlagSet("approvals resolve", flag.ExitOnError)
+		decision := resolveFlags.String("decision", "", "allow or deny (required)")
+		reason := resolveFlags.String("reason", "", "Reason recorded alongside the decision")
+		resolveFlags.Parse(args[1:])
+
+		if len(resolveFlags.Args()) < 1 {
+			exitError("approval ID required: plasma-shield approvals resolve <id> --decision allow|deny", 1)
+		}
+		id := resolveFlags.Args()[0]
+
+		if *decision != "allow" && *decision != "deny" {
+			exitError(`--decision must be "allow" or "deny"`, 1)
+		}
+
+		respBody, statusCode, err := resolveApproval(id, *decision, *reason)
+		if err != nil {
+			exitError(err.Error(), 1)
+		}
+		if statusCode != http.StatusOK {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+		}
+
+		if jsonOut {
+			outputJSON(json.RawMessage(respBody))
+		} else {
+			fmt.Printf("✓ %s: %s\n", id, *decision)
+		}
+
+	case "watch":
+		watchApprovals()
+
+	default:
+		exitError(fmt.Sprintf("unknown approvals action: %s (use list, resolve, or watch)", action), 1)
+	}
+}
+
+// resolveApproval POSTs a decision to /approvals/{id}, shared by
+// `approvals resolve` and the interactive prompt in `approvals watch`.
+func resolveApproval(id, decision, reason string) ([]byte, int, error) {
+	reqBody := map[string]interface{}{
+		"decision": decision,
+		"reason":   reason,
+	}
+	return apiRequest("POST", "/approvals/"+id, reqBody)
+}
+
+// watchApprovals streams GET /approvals/stream and prompts for a decision
+// on each newly created pending approval. Reconnects with backoff on a
+// dropped connection, same as followLogs.
+func watchApprovals() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	const minBackoff = time.Second
+	const maxBackoff = 30 * time.Second
+
+	backoff := minBackoff
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		body, statusCode, err := apiRequestStream(ctx, "GET", "/approvals/stream")
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "plasma-shield: connect to %s: %v; retrying in %s\n", apiURL, err, backoff)
+			if !sleepOrDone(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff, maxBackoff)
+			continue
+		}
+
+		if statusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(body)
+			body.Close()
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(respBody, &errResp)
+			exitError(fmt.Sprintf("API error: unexpected status %d (%s)", statusCode, errResp.Error), 1)
+		}
+
+		backoff = minBackoff
+		streamErr := watchApprovalStream(ctx, body, reader)
+		body.Close()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if streamErr != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shield: stream closed: %v; reconnecting\n", streamErr)
+		}
+		if !sleepOrDone(ctx, backoff) {
+			return
+		}
+		backoff = nextBackoff(backoff, maxBackoff)
+	}
+}
+
+// watchApprovalStream reads Server-Sent Events from body until it closes
+// or errors, printing each pending approval and prompting for a decision.
+// The prompt is deliberately a plain allow/deny/skip line read, not the
+// richer grant-extending or rule-adding shortcuts a terminal UI might
+// offer, since POST /approvals/{id} only supports a flat allow/deny
+// decision today.
+func watchApprovalStream(ctx context.Context, body io.Reader, reader *bufio.Reader) error {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Text()
+		data := strings.TrimPrefix(line, "data: ")
+		if data == line {
+			continue // not a data line (blank, "event: ...", etc.)
+		}
+
+		var approval approvalEntry
+		if err := json.Unmarshal([]byte(data), &approval); err != nil {
+			continue
+		}
+
+		fmt.Printf("\nApproval requested %s\n", approval.ID)
+		fmt.Printf("  agent:   %s\n", approval.AgentID)
+		fmt.Printf("  rule:    %s\n", approval.RuleID)
+		fmt.Printf("  command: %s\n", approval.Command)
+		fmt.Print("Decision [allow/deny/skip]: ")
+
+		line, _ = reader.ReadString('\n')
+		switch strings.TrimSpace(line) {
+		case "allow":
+			if _, _, err := resolveApproval(approval.ID, "allow", "approved via watch"); err != nil {
+				fmt.Fprintf(os.Stderr, "plasma-shield: failed to resolve %s: %v\n", approval.ID, err)
+			}
+		case "deny":
+			if _, _, err := resolveApproval(approval.ID, "deny", "denied via watch"); err != nil {
+				fmt.Fprintf(os.Stderr, "plasma-shield: failed to resolve %s: %v\n", approval.ID, err)
+			}
+		default:
+			fmt.Println("Skipped")
+		}
+	}
+	return scanner.Err()
+}
+
+// ruleStatsPoint mirrors api.RuleStatsPoint's JSON shape.
+type ruleStatsPoint struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Allowed      uint64    `json:"allowed"`
+	Blocked      uint64    `json:"blocked"`
+	UniqueAgents int       `json:"unique_agents"`
+}
+
+// ruleStatsResult mirrors api.RuleStatsResponse's JSON shape.
+type ruleStatsResult struct {
+	RuleID string           `json:"rule_id"`
+	Start  time.Time        `json:"start"`
+	End    time.Time        `json:"end"`
+	Step   string           `json:"step"`
+	Points []ruleStatsPoint `json:"points"`
+	Totals struct {
+		Allowed      uint64 `json:"allowed"`
+		Blocked      uint64 `json:"blocked"`
+		UniqueAgents int    `json:"unique_agents"`
+	} `json:"totals"`
+}
+
+// topStatsResult mirrors api.TopStatsResponse's JSON shape.
+type topStatsResult struct {
+	By    string    `json:"by"`
+	Since time.Time `json:"since"`
+	Rules []struct {
+		RuleID  string `json:"rule_id"`
+		Allowed uint64 `json:"allowed"`
+		Blocked uint64 `json:"blocked"`
+		Total   uint64 `json:"total"`
+	} `json:"rules,omitempty"`
+	Agents []struct {
+		AgentID string `json:"agent_id"`
+		Allowed uint64 `json:"allowed"`
+		Blocked uint64 `json:"blocked"`
+	} `json:"agents,omitempty"`
+}
+
+func handleStats(args []string) {
+	if len(args) >= 1 && args[0] == "top" {
+		handleStatsTop(args[1:])
+		return
+	}
+
+	statsFlags := flag.NewFlagSet("stats", flag.ExitOnError)
+	ruleID := statsFlags.String("rule", "", "Rule ID to query (required)")
+	since := statsFlags.String("since", "1h", "How far back to query (e.g. 1h, 24h)")
+	step := statsFlags.String("step", "5m", "Time series bucket width (e.g. 5m)")
+	format := statsFlags.String("format", "", `Output format: "" (table) or "prometheus" (OpenMetrics exposition)`)
+	statsFlags.Parse(args)
+
+	if *ruleID == "" {
+		exitError("--rule is required: plasma-shield stats --rule <rule-id> [--since 24h] [--step 5m]", 1)
+	}
+
+	sinceDur, err := time.ParseDuration(*since)
+	if err != nil {
+		exitError(fmt.Sprintf("invalid --since %q: %v", *since, err), 1)
+	}
+
+	end := time.Now().UTC()
+	start := end.Add(-sinceDur)
+
+	query := url.Values{}
+	query.Set("start", start.Format(time.RFC3339))
+	query.Set("end", end.Format(time.RFC3339))
+	query.Set("step", *step)
+
+	respBody, statusCode, err := apiRequest("GET", "/stats/rules/"+*ruleID+"?"+query.Encode(), nil)
+	if err != nil {
+		exitError(err.Error(), 1)
+	}
+	if statusCode != http.StatusOK {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(respBody, &errResp)
+		exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+	}
+
+	var resp ruleStatsResult
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		exitError(fmt.Sprintf("failed to parse response: %v", err), 1)
+	}
+
+	switch *format {
+	case "prometheus":
+		printRuleStatsPrometheus(resp)
+	case "", "table":
+		if jsonOut {
+			outputJSON(resp)
+		} else {
+			printRuleStatsTable(resp)
+		}
+	default:
+		exitError(fmt.Sprintf(`unknown --format %q (use "" or "prometheus")`, *format), 1)
+	}
+}
+
+// sparkBlocks renders a compact ascii time series: one character per
+// value, scaled from 0 to the series' max onto these eighth-height blocks.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+func sparkline(values []uint64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	var max uint64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	runes := make([]rune, len(values))
+	for i, v := range values {
+		if max == 0 {
+			runes[i] = sparkBlocks[0]
+			continue
+		}
+		idx := int(float64(v) / float64(max) * float64(len(sparkBlocks)-1))
+		runes[i] = sparkBlocks[idx]
+	}
+	return string(runes)
+}
+
+func printRuleStatsTable(resp ruleStatsResult) {
+	fmt.Printf("Rule %s: %s to %s (step %s)\n",
+		resp.RuleID, resp.Start.Format("2006-01-02 15:04:05"), resp.End.Format("2006-01-02 15:04:05"), resp.Step)
+
+	if len(resp.Points) == 0 {
+		fmt.Println("No data in range")
+	} else {
+		hits := make([]uint64, len(resp.Points))
+		for i, p := range resp.Points {
+			hits[i] = p.Allowed + p.Blocked
+		}
+		fmt.Printf("Hits: %s\n", sparkline(hits))
+	}
+
+	fmt.Printf("Totals: allowed=%d blocked=%d unique_agents=%d\n",
+		resp.Totals.Allowed, resp.Totals.Blocked, resp.Totals.UniqueAgents)
+}
+
+// printRuleStatsPrometheus writes resp as OpenMetrics exposition text, one
+// sample per point, so it can be redirected to a file a node_exporter
+// textfile collector picks up (or pasted into a Pushgateway push) instead
+// of polling GET /stats/rules/{id} as JSON.
+func printRuleStatsPrometheus(resp ruleStatsResult) {
+	fmt.Println("# HELP plasma_rule_hits_total Exec check decisions for a rule, by action.")
+	fmt.Println("# TYPE plasma_rule_hits_total counter")
+	for _, p := range resp.Points {
+		ts := p.Timestamp.UnixMilli()
+		fmt.Printf("plasma_rule_hits_total{rule_id=%q,action=\"allow\"} %d %d\n", resp.RuleID, p.Allowed, ts)
+		fmt.Printf("plasma_rule_hits_total{rule_id=%q,action=\"block\"} %d %d\n", resp.RuleID, p.Blocked, ts)
+	}
+	fmt.Println("# HELP plasma_rule_unique_agents Distinct agents that hit a rule within a step.")
+	fmt.Println("# TYPE plasma_rule_unique_agents gauge")
+	for _, p := range resp.Points {
+		fmt.Printf("plasma_rule_unique_agents{rule_id=%q} %d %d\n", resp.RuleID, p.UniqueAgents, p.Timestamp.UnixMilli())
+	}
+	fmt.Println("# EOF")
+}
+
+func handleStatsTop(args []string) {
+	topFlags := flag.NewFlagSet("stats top", flag.ExitOnError)
+	by := topFlags.String("by", "rule", `What to rank: "rule" or "agent"`)
+	since := topFlags.String("since", "1h", "How far back to query (e.g. 1h, 24h)")
+	limit := topFlags.Int("limit", 20, "Max rows to show")
+	topFlags.Parse(args)
+
+	if *by != "rule" && *by != "agent" {
+		exitError(`--by must be "rule" or "agent"`, 1)
+	}
+
+	query := url.Values{}
+	query.Set("by", *by)
+	query.Set("since", *since)
+	query.Set("limit", strconv.Itoa(*limit))
+
+	respBody, statusCode, err := apiRequest("GET", "/stats/top?"+query.Encode(), nil)
+	if err != nil {
+		exitError(err.Error(), 1)
+	}
+	if statusCode != http.StatusOK {
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(respBody, &errResp)
+		exitError(fmt.Sprintf("API error: %s", errResp.Error), 1)
+	}
+
+	var resp topStatsResult
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		exitError(fmt.Sprintf("failed to parse response: %v", err), 1)
+	}
+
+	if jsonOut {
+		outputJSON(resp)
+		return
+	}
+
+	if *by == "rule" {
+		if len(resp.Rules) == 0 {
+			fmt.Println("No rule hits in range")
+			return
+		}
+		fmt.Printf("Noisiest rules since %s:\n", *since)
+		for _, r := range resp.Rules {
+			fmt.Printf("%-24s allowed=%-6d blocked=%-6d total=%d\n", r.RuleID, r.Allowed, r.Blocked, r.Total)
+		}
+	} else {
+		if len(resp.Agents) == 0 {
+			fmt.Println("No blocked commands in range")
+			return
+		}
+		fmt.Printf("Agents with the most blocks since %s:\n", *since)
+		for _, a := range resp.Agents {
+			fmt.Printf("%-16s allowed=%-6d blocked=%d\n", a.AgentID, a.Allowed, a.Blocked)
+		}
+	}
+}
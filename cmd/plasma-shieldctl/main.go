@@ -0,0 +1,301 @@
+// Plasma ShieldCtl
+//
+// Reference CLI for internal/control's ShieldControl gRPC service: live
+// rule/mode management and decision/audit streaming, the gRPC counterpart
+// to the plasma-shield CLI's HTTP calls against cmd/proxy's REST API.
+// Install on your personal machine, not on agent VPSes.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	controlpb "github.com/Extra-Chill/plasma-shield/api/proto"
+)
+
+var version = "0.1.0"
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(0)
+	}
+
+	switch os.Args[1] {
+	case "version", "--version", "-v":
+		fmt.Printf("plasma-shieldctl v%s\n", version)
+	case "rules":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: plasma-shieldctl rules <get|put|reload> [options]")
+			os.Exit(1)
+		}
+		handleRules(os.Args[2:])
+	case "test":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: plasma-shieldctl test <command|domain> <value>")
+			os.Exit(1)
+		}
+		handleTest(os.Args[2:])
+	case "mode":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: plasma-shieldctl mode <get|set> [options]")
+			os.Exit(1)
+		}
+		handleMode(os.Args[2:])
+	case "stream":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: plasma-shieldctl stream <decisions|audit> [--agent <id>]")
+			os.Exit(1)
+		}
+		handleStream(os.Args[2:])
+	default:
+		fmt.Printf("Unknown command: %s\n", os.Args[1])
+		printUsage()
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println(`Plasma ShieldCtl
+
+Usage: plasma-shieldctl <command> [options]
+
+Commands:
+  rules get [--group <name>]           Print the active ruleset as a bundle
+  rules put <file> [--replace] [--dry-run]
+                                        Import a rule bundle YAML file
+  rules reload                         Reload -rules from disk
+  test command <value>                 Check a command against the ruleset
+  test domain <value>                  Check a domain against the ruleset
+  mode get                             Print global and per-agent modes
+  mode set [--agent <id>] <mode>       Set global or per-agent mode
+  stream decisions [--agent <id>]      Tail live decision events
+  stream audit [--agent <id>]          Tail live audit events
+  version                              Show version
+
+Environment:
+  PLASMA_CONTROL_ADDR   ShieldControl gRPC address (default 127.0.0.1:9200)
+  PLASMA_TOKEN          ACL bearer token, sent as gRPC "authorization" metadata`)
+}
+
+// dial connects to PLASMA_CONTROL_ADDR and returns a client wrapping the
+// connection, attaching PLASMA_TOKEN (if set) as outgoing "authorization"
+// metadata on every call the way tailLogs attaches it as an Authorization
+// header.
+func dial() (controlpb.ShieldControlClient, context.Context, func()) {
+	addr := getEnvOrDefault("PLASMA_CONTROL_ADDR", "127.0.0.1:9200")
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "plasma-shieldctl: connect to %s: %v\n", addr, err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	if token := os.Getenv("PLASMA_TOKEN"); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	return controlpb.NewShieldControlClient(conn), ctx, func() { conn.Close() }
+}
+
+func getEnvOrDefault(key, defaultVal string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultVal
+}
+
+func handleRules(args []string) {
+	client, ctx, closeConn := dial()
+	defer closeConn()
+
+	switch args[0] {
+	case "get":
+		group := ""
+		for i := 1; i < len(args)-1; i++ {
+			if args[i] == "--group" {
+				group = args[i+1]
+			}
+		}
+		resp, err := client.GetRules(ctx, &controlpb.GetRulesRequest{Group: group})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: get rules: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("# rules_hash=%s rule_count=%d\n", resp.RulesHash, resp.RuleCount)
+		os.Stdout.Write(resp.Yaml)
+
+	case "put":
+		if len(args) < 2 {
+			fmt.Println("Usage: plasma-shieldctl rules put <file> [--replace] [--dry-run]")
+			os.Exit(1)
+		}
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: read %s: %v\n", args[1], err)
+			os.Exit(1)
+		}
+		req := &controlpb.PutRulesRequest{Yaml: data}
+		for _, arg := range args[2:] {
+			switch arg {
+			case "--replace":
+				req.Replace = true
+			case "--dry-run":
+				req.DryRun = true
+			}
+		}
+		resp, err := client.PutRules(ctx, req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: put rules: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("status=%s rules_hash=%s rule_count=%d would_be_rule_count=%d\n",
+			resp.Status, resp.RulesHash, resp.RuleCount, resp.WouldBeRuleCount)
+		fmt.Println(resp.DiffJson)
+
+	case "reload":
+		resp, err := client.ReloadRules(ctx, &controlpb.ReloadRulesRequest{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: reload rules: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("status=%s rules_hash=%s rule_count=%d\n", resp.Status, resp.RulesHash, resp.RuleCount)
+
+	default:
+		fmt.Printf("Unknown rules command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func handleTest(args []string) {
+	if len(args) < 2 {
+		fmt.Println("Usage: plasma-shieldctl test <command|domain> <value>")
+		os.Exit(1)
+	}
+
+	client, ctx, closeConn := dial()
+	defer closeConn()
+
+	var result *controlpb.TestResult
+	var err error
+	switch args[0] {
+	case "command":
+		result, err = client.TestCommand(ctx, &controlpb.TestCommandRequest{Command: args[1]})
+	case "domain":
+		result, err = client.TestDomain(ctx, &controlpb.TestDomainRequest{Domain: args[1]})
+	default:
+		fmt.Printf("Unknown test command: %s\n", args[0])
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "plasma-shieldctl: test: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("allowed=%v rule_id=%s reason=%s\n", result.Allowed, result.RuleId, result.Reason)
+}
+
+func handleMode(args []string) {
+	client, ctx, closeConn := dial()
+	defer closeConn()
+
+	switch args[0] {
+	case "get":
+		state, err := client.GetMode(ctx, &controlpb.GetModeRequest{})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: get mode: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("global_mode=%s\n", state.GlobalMode)
+		for agentID, m := range state.AgentModes {
+			fmt.Printf("agent=%s mode=%s\n", agentID, m)
+		}
+
+	case "set":
+		var agentID, newMode string
+		rest := args[1:]
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == "--agent" && i+1 < len(rest) {
+				agentID = rest[i+1]
+				i++
+				continue
+			}
+			newMode = rest[i]
+		}
+		if newMode == "" {
+			fmt.Println("Usage: plasma-shieldctl mode set [--agent <id>] <enforce|audit|lockdown>")
+			os.Exit(1)
+		}
+		state, err := client.SetMode(ctx, &controlpb.SetModeRequest{AgentId: agentID, Mode: newMode})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: set mode: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("status=ok global_mode=%s\n", state.GlobalMode)
+
+	default:
+		fmt.Printf("Unknown mode command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func handleStream(args []string) {
+	agentID := ""
+	for i := 1; i < len(args)-1; i++ {
+		if args[i] == "--agent" {
+			agentID = args[i+1]
+		}
+	}
+
+	client, ctx, closeConn := dial()
+	defer closeConn()
+
+	switch args[0] {
+	case "decisions":
+		stream, err := client.StreamDecisions(ctx, &controlpb.StreamDecisionsRequest{AgentId: agentID})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: stream decisions: %v\n", err)
+			os.Exit(1)
+		}
+		for {
+			ev, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "plasma-shieldctl: stream closed: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[%d] agent=%s tier=%s domain=%s method=%s path=%s rule=%s mode=%s action=%s latency_ms=%d client_ip=%s\n",
+				ev.TimestampUnix, ev.AgentId, ev.Tier, ev.Domain, ev.Method, ev.Path, ev.RuleId, ev.Mode, ev.Action, ev.LatencyMs, ev.ClientIp)
+		}
+
+	case "audit":
+		stream, err := client.StreamAudit(ctx, &controlpb.StreamAuditRequest{AgentId: agentID})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plasma-shieldctl: stream audit: %v\n", err)
+			os.Exit(1)
+		}
+		for {
+			ev, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "plasma-shieldctl: stream closed: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("[%d] source_ip=%s agent=%s domain=%s method=%s action=%s reason=%s\n",
+				ev.TimestampUnix, ev.SourceIp, ev.AgentId, ev.Domain, ev.Method, ev.Action, ev.Reason)
+		}
+
+	default:
+		fmt.Printf("Unknown stream command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
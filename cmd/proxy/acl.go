@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Extra-Chill/plasma-shield/internal/acl"
+	"github.com/Extra-Chill/plasma-shield/internal/api"
+)
+
+// extractToken extracts the caller's ACL bearer token from the
+// Authorization header (Bearer scheme), falling back to X-Plasma-Token for
+// callers that can't set Authorization (e.g. some browser fetch configs).
+func extractToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		const prefix = "Bearer "
+		if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+			return auth[len(prefix):]
+		}
+	}
+	return r.Header.Get("X-Plasma-Token")
+}
+
+// checkACL authorizes the caller for resource/op against resolver. A nil
+// resolver means no -acl file was configured, so every call is allowed --
+// the proxy's historical wide-open behavior. On denial it writes the HTTP
+// error response and a structured audit log entry (so operators can see
+// who tried to flip modes) and returns false; the caller must not write
+// anything further to w.
+//
+// Used by handlers that can't go through api.Mount (e.g. /logs/stream's
+// SSE response); JSON handlers mounted via api.Mount use authorizeACL
+// instead, which returns the denial as a typed error for Wrap to render.
+func checkACL(resolver *acl.Resolver, w http.ResponseWriter, r *http.Request, resource string, op acl.AccessLevel) bool {
+	if resolver == nil {
+		return true
+	}
+
+	token := extractToken(r)
+	if err := resolver.Authorize(token, resource, op); err != nil {
+		status := http.StatusForbidden
+		if _, ok := err.(*acl.NotFoundError); ok {
+			status = http.StatusUnauthorized
+		}
+		log.Printf("acl deny: remote=%s method=%s path=%s resource=%s op=%s reason=%v",
+			r.RemoteAddr, r.Method, r.URL.Path, resource, op, err)
+		http.Error(w, err.Error(), status)
+		return false
+	}
+	return true
+}
+
+// authorizeACL is checkACL's typed-error counterpart, for handlers mounted
+// via api.Mount. A nil resolver means no -acl file was configured, so every
+// call is allowed -- the proxy's historical wide-open behavior. On denial
+// it logs a structured audit entry (so operators can see who tried to flip
+// modes) and returns a *api.ForbiddenError, or an *api.CodeWithPayloadError
+// carrying 401 if the token itself wasn't recognized.
+func authorizeACL(resolver *acl.Resolver, r *http.Request, resource string, op acl.AccessLevel) error {
+	if resolver == nil {
+		return nil
+	}
+
+	token := extractToken(r)
+	err := resolver.Authorize(token, resource, op)
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("acl deny: remote=%s method=%s path=%s resource=%s op=%s reason=%v",
+		r.RemoteAddr, r.Method, r.URL.Path, resource, op, err)
+
+	if _, ok := err.(*acl.NotFoundError); ok {
+		return &api.CodeWithPayloadError{
+			Code:    http.StatusUnauthorized,
+			Payload: api.ErrorResponse{Error: err.Error(), Code: http.StatusUnauthorized},
+		}
+	}
+	return &api.ForbiddenError{Message: err.Error()}
+}
+
+// aclTokensHandler handles GET/POST/DELETE /acl/tokens, an admin-only CRUD
+// endpoint over the resolver's token config. Every call (including GET)
+// requires admin access to the "acl" resource, since the list response
+// includes live token values. resolver is nil when -acl wasn't set, in
+// which case the endpoint has nothing to manage.
+func aclTokensHandler(resolver *acl.Resolver) api.Handler {
+	return func(r *http.Request) (interface{}, error) {
+		if resolver == nil {
+			return nil, &api.CodeWithPayloadError{
+				Code:    http.StatusServiceUnavailable,
+				Payload: api.ErrorResponse{Error: "ACL not configured; pass -acl to enable", Code: http.StatusServiceUnavailable},
+			}
+		}
+		if err := authorizeACL(resolver, r, "acl", acl.Admin); err != nil {
+			return nil, err
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			return map[string]interface{}{"tokens": resolver.Tokens()}, nil
+
+		case http.MethodPost:
+			var tc acl.TokenConfig
+			if err := json.NewDecoder(r.Body).Decode(&tc); err != nil {
+				return nil, &api.BadRequestError{Message: "Invalid JSON"}
+			}
+			if tc.Token == "" {
+				return nil, &api.BadRequestError{Message: "token required"}
+			}
+			if err := resolver.AddToken(tc); err != nil {
+				return nil, fmt.Errorf("save token: %w", err)
+			}
+			log.Printf("acl: token %q (%s) added/updated by %s", tc.Name, acl.Redact(tc.Token), r.RemoteAddr)
+			return map[string]string{"status": "ok", "name": tc.Name}, nil
+
+		case http.MethodDelete:
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				return nil, &api.BadRequestError{Message: "token query parameter required"}
+			}
+			removed, err := resolver.RemoveToken(token)
+			if err != nil {
+				return nil, fmt.Errorf("remove token: %w", err)
+			}
+			if !removed {
+				return nil, &api.NotFoundError{Resource: "token"}
+			}
+			log.Printf("acl: token %s removed by %s", acl.Redact(token), r.RemoteAddr)
+			return map[string]string{"status": "ok"}, nil
+
+		default:
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET", "POST", "DELETE"}}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/acl"
+	"github.com/Extra-Chill/plasma-shield/internal/api/filter"
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+)
+
+// parseLogFilter parses the optional ?filter= expression shared by GET
+// /logs and GET /logs/stream (see internal/api/filter). It writes a 400 and
+// returns ok=false on a malformed expression.
+func parseLogFilter(w http.ResponseWriter, r *http.Request) (expr *filter.Expr, ok bool) {
+	raw := r.URL.Query().Get("filter")
+	if raw == "" {
+		return nil, true
+	}
+	expr, err := filter.Parse(raw)
+	if err != nil {
+		http.Error(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return expr, true
+}
+
+// parseLogFilterExpr is parseLogFilter's error-returning counterpart, for
+// GET /logs now that it's mounted through internal/api's typed Handler
+// contract (see mountJSON in main.go) instead of writing the 400 itself.
+func parseLogFilterExpr(r *http.Request) (*filter.Expr, error) {
+	raw := r.URL.Query().Get("filter")
+	if raw == "" {
+		return nil, nil
+	}
+	return filter.Parse(raw)
+}
+
+// logsStreamHandler handles GET /logs/stream, pushing each new
+// proxy.LogEntry added to logStore as it's published. The default format is
+// Server-Sent Events; ?format=ndjson switches to newline-delimited JSON for
+// clients that would rather not speak SSE. The same ?filter= expression
+// accepted by GET /logs restricts which entries are delivered. An optional
+// ?since= (RFC3339) replays buffered entries published after it before the
+// live tail begins, so a reconnecting client can catch up.
+func logsStreamHandler(logStore *LogStore, resolver *acl.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkACL(resolver, w, r, "logs", acl.Read) {
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		expr, ok := parseLogFilter(w, r)
+		if !ok {
+			return
+		}
+
+		query := r.URL.Query()
+		ndjson := strings.EqualFold(query.Get("format"), "ndjson")
+
+		var since time.Time
+		if raw := query.Get("since"); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			since = parsed
+		}
+
+		sub := logStore.Subscribe()
+		defer sub.Close()
+
+		if ndjson {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		} else {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+		}
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		var lastDropped uint64
+		writeEntry := func(entry proxy.LogEntry) bool {
+			if expr != nil && !expr.Match(entry) {
+				return true
+			}
+			if dropped := sub.Dropped(); dropped != lastDropped {
+				lastDropped = dropped
+				if !writeDropped(w, ndjson, dropped) {
+					return false
+				}
+			}
+			return writeLogEntry(w, ndjson, entry)
+		}
+
+		for _, entry := range logStore.Since(since) {
+			if !writeEntry(entry) {
+				return
+			}
+		}
+		flusher.Flush()
+
+		for {
+			select {
+			case rec := <-sub.Events():
+				entry, ok := rec.Payload.(proxy.LogEntry)
+				if !ok {
+					continue
+				}
+				if !writeEntry(entry) {
+					return
+				}
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
+
+// writeLogEntry writes entry as either an SSE "data: ...\n\n" event or a
+// single ndjson line, depending on ndjson. It reports false if the write
+// failed, so the caller can stop streaming.
+func writeLogEntry(w http.ResponseWriter, ndjson bool, entry proxy.LogEntry) bool {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return true
+	}
+	if ndjson {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+	} else {
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	return err == nil
+}
+
+// writeDropped reports a new dropped-entry count to a lagging subscriber,
+// as an SSE "dropped" named event or an ndjson line.
+func writeDropped(w http.ResponseWriter, ndjson bool, dropped uint64) bool {
+	payload := struct {
+		Dropped uint64 `json:"dropped"`
+	}{dropped}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return true
+	}
+	if ndjson {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+	} else {
+		_, err = fmt.Fprintf(w, "event: dropped\ndata: %s\n\n", data)
+	}
+	return err == nil
+}
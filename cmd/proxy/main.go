@@ -10,45 +10,101 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/acl"
+	"github.com/Extra-Chill/plasma-shield/internal/api"
+	"github.com/Extra-Chill/plasma-shield/internal/control"
 	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+	"github.com/Extra-Chill/plasma-shield/internal/logs"
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/logsink"
+	"github.com/Extra-Chill/plasma-shield/internal/metrics"
 	"github.com/Extra-Chill/plasma-shield/internal/mode"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
 	"github.com/Extra-Chill/plasma-shield/internal/proxy"
 	"github.com/Extra-Chill/plasma-shield/internal/rules"
 	"github.com/Extra-Chill/plasma-shield/internal/web"
 )
 
+// logBrokerCapacity bounds how many LogEntry values LogStore's broker keeps
+// for ?since= replay on GET /logs/stream, same idea as internal/api's
+// logBrokerCapacity.
+const logBrokerCapacity = 1000
+
 var version = "0.1.0"
 
-// LogStore stores recent traffic logs in memory
+// LogStore stores recent traffic logs in memory and fans new entries out to
+// GET /logs/stream subscribers via an internal/logs.Broker. A subscriber
+// that falls behind has entries dropped for it rather than blocking Add;
+// see logs.Subscription.Dropped. It caps at maxSize and is lost on
+// restart -- SetSinks wires it to a durable internal/logsink.MultiSink for
+// callers that need the audit trail to survive one.
 type LogStore struct {
 	mu      sync.RWMutex
 	entries []proxy.LogEntry
 	maxSize int
+	broker  *logs.Broker
+	sinks   *logsink.MultiSink
 }
 
 func NewLogStore(maxSize int) *LogStore {
 	return &LogStore{
 		entries: make([]proxy.LogEntry, 0),
 		maxSize: maxSize,
+		broker:  logs.NewBroker(logBrokerCapacity),
 	}
 }
 
+// SetSinks wires s to a durable logsink.MultiSink; every entry Add sees
+// from then on is also written there, alongside the in-memory ring.
+func (s *LogStore) SetSinks(sinks *logsink.MultiSink) {
+	s.mu.Lock()
+	s.sinks = sinks
+	s.mu.Unlock()
+}
+
 func (s *LogStore) Add(entry proxy.LogEntry) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.entries = append(s.entries, entry)
 	if len(s.entries) > s.maxSize {
 		s.entries = s.entries[len(s.entries)-s.maxSize:]
 	}
+	sinks := s.sinks
+	s.mu.Unlock()
+
+	s.broker.Publish(entry, entry.Timestamp)
+
+	if sinks != nil {
+		sinks.Write(context.Background(), []proxy.LogEntry{entry})
+	}
+}
+
+// Subscribe registers a new live GET /logs/stream subscriber.
+func (s *LogStore) Subscribe() *logs.Subscription {
+	return s.broker.Subscribe()
+}
+
+// Since returns buffered entries published strictly after ts, oldest first,
+// for a reconnecting stream client to replay.
+func (s *LogStore) Since(ts time.Time) []proxy.LogEntry {
+	records := s.broker.Since(ts)
+	out := make([]proxy.LogEntry, 0, len(records))
+	for _, rec := range records {
+		if entry, ok := rec.Payload.(proxy.LogEntry); ok {
+			out = append(out, entry)
+		}
+	}
+	return out
 }
 
 func (s *LogStore) Get(limit int) []proxy.LogEntry {
@@ -65,23 +121,82 @@ func (s *LogStore) Get(limit int) []proxy.LogEntry {
 	return result
 }
 
+// mountJSON registers h at path and "/v1"+path on mux, composing
+// internal/api's typed-handler contract (CORS, gzip, request ID, structured
+// logging, error translation -- see api.Mount) with m's existing
+// plasma_http_request_duration_seconds instrumentation, labeled by path
+// the same way the handlers it replaces were.
+func mountJSON(mux *http.ServeMux, m *metrics.Metrics, path string, h api.Handler) {
+	wrapped := api.RequestID(api.CORS(api.Gzip(http.HandlerFunc(m.InstrumentHandler(path, api.Wrap(h))))))
+	mux.Handle(path, wrapped)
+	mux.Handle("/v1"+path, wrapped)
+}
+
+// mountRaw registers a raw http.HandlerFunc at path and "/v1"+path,
+// instrumented like mountJSON but without Gzip (which would buffer and
+// break streaming responses like /logs/stream) or Wrap's JSON error
+// translation, for endpoints that manage their own response format.
+func mountRaw(mux *http.ServeMux, m *metrics.Metrics, path string, h http.HandlerFunc) {
+	wrapped := api.RequestID(api.CORS(http.HandlerFunc(m.InstrumentHandler(path, h))))
+	mux.Handle(path, wrapped)
+	mux.Handle("/v1"+path, wrapped)
+}
+
 func main() {
 	// Parse command line flags
 	proxyAddr := flag.String("proxy-addr", ":8080", "Address for the proxy server")
 	apiAddr := flag.String("api-addr", "127.0.0.1:9000", "Address for the management API and web UI (localhost only)")
 	rulesFile := flag.String("rules", "", "Path to rules YAML file")
+	aclFile := flag.String("acl", "", "Path to ACL token/policy YAML file (hot-reloaded); empty leaves the management API wide open, the historical default")
+	sinksFile := flag.String("sinks", "", "Path to log sink YAML config file (file/syslog/webhook/S3 destinations); empty disables durable sinks and keeps only the in-memory log ring")
+	logSink := flag.String("log-sink", "", "Comma-separated structured log destinations (see internal/plasmalog): \"stdout\" (default if empty), \"file=<path>\", \"syslog=<network>://<addr>\", \"loki=<push URL>\"")
+	allowSampleRPS := flag.Float64("log-allow-sample-rps", 0, "Rate-limit \"action=allow\" log records per agent so a chatty agent can't drown out block/audit signal (0 disables sampling, logging every allow)")
+	allowSampleBurst := flag.Int("log-allow-sample-burst", 20, "Burst size for --log-allow-sample-rps")
+	adminAddr := flag.String("admin-addr", "127.0.0.1:9100", "Address for /metrics, /healthz, and /readyz, separate from --api-addr so scrapers don't share its ACL/rate limits (localhost only)")
+	controlGRPCAddr := flag.String("control-grpc-addr", "", "Address for the ShieldControl gRPC service (live rule/mode management and decision/audit streaming for plasma-shieldctl); empty disables it")
 	flag.Parse()
 
 	fmt.Printf("Plasma Shield Proxy v%s\n", version)
 
 	// Initialize components
+	m := metrics.New()
 	modeManager := mode.NewManager()
+	modeManager.SetMetrics(m)
 	fleetManager := fleet.NewManager()
 	logStore := NewLogStore(1000)
 	log.Printf("Default mode: %s", modeManager.GlobalMode())
 
+	// Structured application log (internal/plasmalog), replacing the
+	// json.Marshal+log.Println calls Handler.logRequestFull and
+	// ExecCheckHandler.ServeHTTP used to make directly. Defaults to JSON
+	// lines on stdout when --log-sink is unset. A BroadcastSink is always
+	// added alongside the configured destinations so ShieldControl's
+	// StreamDecisions RPC has something to tail even when --control-grpc-addr
+	// is set after the fact; an unsubscribed broker costs nothing.
+	logSinks, err := plasmalog.ParseSinks(*logSink)
+	if err != nil {
+		log.Fatalf("Failed to configure --log-sink %q: %v", *logSink, err)
+	}
+	decisionSink := plasmalog.NewBroadcastSink()
+	logSinks["broadcast"] = decisionSink
+	appLogger, appLogHandler := plasmalog.New(logSinks, 0, nil)
+
+	// Wire durable log sinks, if configured. The in-memory logStore ring
+	// stays as the tail cache for GET /logs and GET /logs/stream either way.
+	var sinks *logsink.MultiSink
+	if *sinksFile != "" {
+		s, err := logsink.Load(*sinksFile)
+		if err != nil {
+			log.Fatalf("Failed to load log sinks: %v", err)
+		}
+		sinks = s
+		logStore.SetSinks(sinks)
+		log.Printf("Loaded log sinks from %s (%d sink(s))", *sinksFile, len(sinks.Stats()))
+	}
+
 	// Initialize rule engine
 	engine := rules.NewEngine()
+	engine.SetMetrics(m)
 	if *rulesFile != "" {
 		if err := engine.LoadRules(*rulesFile); err != nil {
 			log.Fatalf("Failed to load rules: %v", err)
@@ -89,10 +204,79 @@ func main() {
 		log.Printf("Loaded rules from %s", *rulesFile)
 	}
 
+	// Rate limits and circuit breaking, same "rate_limits:" section of
+	// -rules and internal/limiter used by cmd/gateway. Kept on its own
+	// limiter.Metrics registry (surfaced at /metrics/limiter) rather than
+	// folded into m (internal/metrics), since the two packages hand-roll
+	// unrelated counter sets.
+	var rateLimitRules []limiter.RateLimitRule
+	if *rulesFile != "" {
+		loaded, err := limiter.LoadRateLimitsFromFile(*rulesFile)
+		if err != nil {
+			log.Printf("Warning: failed to load rate limits from %s: %v", *rulesFile, err)
+		} else {
+			rateLimitRules = loaded
+			if len(rateLimitRules) > 0 {
+				log.Printf("Loaded %d rate limit rule(s) from %s", len(rateLimitRules), *rulesFile)
+			}
+		}
+	}
+	limiterMetrics := limiter.NewMetrics()
+	rateLimiter, err := limiter.NewLimiter(rateLimitRules, limiterMetrics)
+	if err != nil {
+		log.Fatalf("Failed to build rate limiter: %v", err)
+	}
+	breaker := limiter.NewBreaker(limiter.DefaultBreakerConfig(), limiterMetrics)
+
+	// Load the ACL resolver, if configured
+	var resolver *acl.Resolver
+	var aclWatcher *acl.Watcher
+	if *aclFile != "" {
+		r, err := acl.LoadResolver(*aclFile)
+		if err != nil {
+			log.Fatalf("Failed to load ACL config: %v", err)
+		}
+		resolver = r
+		w, err := acl.WatchResolver(resolver)
+		if err != nil {
+			log.Fatalf("Failed to watch ACL config: %v", err)
+		}
+		aclWatcher = w
+		log.Printf("Loaded ACL config from %s (%d token(s))", *aclFile, len(resolver.Tokens()))
+	}
+
+	// ShieldControl gRPC service: live rule/mode management and
+	// decision/audit streaming for plasma-shieldctl, backed by the same
+	// engine and modeManager the REST /rules and /mode endpoints use.
+	var controlServer *control.Server
+	var controlListener net.Listener
+	if *controlGRPCAddr != "" {
+		controlServer = control.NewServer(engine, modeManager, decisionSink, logStore, resolver, m)
+		l, err := net.Listen("tcp", *controlGRPCAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on --control-grpc-addr %s: %v", *controlGRPCAddr, err)
+		}
+		controlListener = l
+	}
+
 	// Create inspector and handlers
 	inspector := proxy.NewInspector(engine, modeManager)
-	proxyHandler := proxy.NewHandler(inspector)
-	execCheckHandler := proxy.NewExecCheckHandler(inspector)
+	inspector.SetMetrics(m)
+	handlerOpts := []proxy.HandlerOption{
+		proxy.WithLogger(appLogger),
+		proxy.WithLimiter(rateLimiter),
+		proxy.WithBreaker(breaker),
+		proxy.WithMetrics(limiterMetrics),
+	}
+	if *allowSampleRPS > 0 {
+		handlerOpts = append(handlerOpts, proxy.WithAllowSampleRate(*allowSampleRPS, *allowSampleBurst))
+	}
+	proxyHandler := proxy.NewHandler(inspector, handlerOpts...)
+	execCheckHandler := proxy.NewExecCheckHandler(inspector,
+		proxy.WithExecLogger(appLogger),
+		proxy.WithExecLimiter(rateLimiter),
+		proxy.WithExecMetrics(limiterMetrics),
+	)
 
 	// Create proxy server
 	proxyServer := &http.Server{
@@ -103,124 +287,143 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
-	// Create API server with management endpoints
+	// Create API server with management endpoints. Every endpoint below is
+	// mounted at both its historical path and "/v1"+path (see mountJSON/
+	// mountRaw and internal/api.Mount), so existing callers keep working
+	// while new ones get a stable versioned surface.
 	apiMux := http.NewServeMux()
 
 	// Health check
-	apiMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	mountRaw(apiMux, m, "/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
-	// Exec check (for agents)
-	apiMux.Handle("/exec/check", execCheckHandler)
+	// Metrics. Deliberately not instrumented by mountRaw/mountJSON --
+	// observing the metrics endpoint's own latency in its own series would
+	// be circular -- but still CORS'd and versioned like every other
+	// endpoint.
+	metricsHandler := api.CORS(api.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.RefreshFleetGauges(fleetManager)
+		m.Handler()(w, r)
+	})))
+	apiMux.Handle("/metrics", metricsHandler)
+	apiMux.Handle("/v1/metrics", metricsHandler)
+
+	// Rate limit/circuit breaker state, on its own path since it's a
+	// separate limiter.Metrics registry from m (internal/metrics). Same
+	// "deliberately not instrumented" reasoning as /metrics above.
+	limiterMetricsHandler := api.CORS(api.RequestID(limiter.MetricsHandler(limiterMetrics, nil)))
+	apiMux.Handle("/metrics/limiter", limiterMetricsHandler)
+	apiMux.Handle("/v1/metrics/limiter", limiterMetricsHandler)
+
+	// Exec check (for agents). Kept as a raw http.Handler: it's already a
+	// self-contained, agent-facing contract and doesn't go through ACL.
+	mountRaw(apiMux, m, "/exec/check", execCheckHandler.ServeHTTP)
 
 	// Mode management
-	apiMux.HandleFunc("/mode", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
+	mountJSON(apiMux, m, "/mode", func(r *http.Request) (interface{}, error) {
 		switch r.Method {
 		case http.MethodGet:
-			resp := map[string]interface{}{
-				"global_mode":  string(modeManager.GlobalMode()),
-				"agent_modes":  modeManager.AllAgentModes(),
+			if err := authorizeACL(resolver, r, "mode", acl.Read); err != nil {
+				return nil, err
 			}
-			json.NewEncoder(w).Encode(resp)
+			return map[string]interface{}{
+				"global_mode": string(modeManager.GlobalMode()),
+				"agent_modes": modeManager.AllAgentModes(),
+			}, nil
 
 		case http.MethodPut, http.MethodPost:
+			if err := authorizeACL(resolver, r, "mode", acl.Write); err != nil {
+				return nil, err
+			}
 			var req struct {
 				Mode string `json:"mode"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid JSON", http.StatusBadRequest)
-				return
+				return nil, &api.BadRequestError{Message: "Invalid JSON"}
 			}
 			switch mode.Mode(req.Mode) {
 			case mode.Enforce, mode.Audit, mode.Lockdown:
 				modeManager.SetGlobalMode(mode.Mode(req.Mode))
 				log.Printf("Global mode changed to: %s", req.Mode)
-				json.NewEncoder(w).Encode(map[string]string{"status": "ok", "mode": req.Mode})
+				return map[string]string{"status": "ok", "mode": req.Mode}, nil
 			default:
-				http.Error(w, "Invalid mode. Use: enforce, audit, lockdown", http.StatusBadRequest)
+				return nil, &api.BadRequestError{Message: "Invalid mode. Use: enforce, audit, lockdown"}
 			}
 
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET", "PUT", "POST"}}
 		}
 	})
 
 	// Per-agent mode management
-	apiMux.HandleFunc("/agent/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Parse path: /agent/{id}/mode
-		path := strings.TrimPrefix(r.URL.Path, "/agent/")
+	mountJSON(apiMux, m, "/agent/", func(r *http.Request) (interface{}, error) {
+		// Parse path: /agent/{id}/mode (or its /v1 counterpart)
+		path := strings.TrimPrefix(r.URL.Path, "/v1")
+		path = strings.TrimPrefix(path, "/agent/")
 		parts := strings.Split(path, "/")
 		if len(parts) < 2 || parts[1] != "mode" {
-			http.Error(w, "Invalid path. Use: /agent/{id}/mode", http.StatusBadRequest)
-			return
+			return nil, &api.BadRequestError{Message: "Invalid path. Use: /agent/{id}/mode"}
 		}
 		agentID := parts[0]
+		resource := "agent:" + agentID
 
 		switch r.Method {
 		case http.MethodGet:
+			if err := authorizeACL(resolver, r, resource, acl.Read); err != nil {
+				return nil, err
+			}
 			agentMode := modeManager.AgentMode(agentID)
-			json.NewEncoder(w).Encode(map[string]string{
+			return map[string]string{
 				"agent": agentID,
 				"mode":  string(agentMode),
-			})
+			}, nil
 
 		case http.MethodPut:
+			if err := authorizeACL(resolver, r, resource, acl.Write); err != nil {
+				return nil, err
+			}
 			var req struct {
 				Mode string `json:"mode"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid JSON", http.StatusBadRequest)
-				return
+				return nil, &api.BadRequestError{Message: "Invalid JSON"}
 			}
 			switch mode.Mode(req.Mode) {
 			case mode.Enforce, mode.Audit, mode.Lockdown:
 				modeManager.SetAgentMode(agentID, mode.Mode(req.Mode))
 				log.Printf("Agent %s mode changed to: %s", agentID, req.Mode)
-				json.NewEncoder(w).Encode(map[string]string{"status": "ok", "agent": agentID, "mode": req.Mode})
+				return map[string]string{"status": "ok", "agent": agentID, "mode": req.Mode}, nil
 			default:
-				http.Error(w, "Invalid mode", http.StatusBadRequest)
+				return nil, &api.BadRequestError{Message: "Invalid mode"}
 			}
 
 		case http.MethodDelete:
+			if err := authorizeACL(resolver, r, resource, acl.Write); err != nil {
+				return nil, err
+			}
 			modeManager.ClearAgentMode(agentID)
 			log.Printf("Agent %s mode cleared", agentID)
-			json.NewEncoder(w).Encode(map[string]string{"status": "ok", "agent": agentID, "message": "mode cleared"})
+			return map[string]string{"status": "ok", "agent": agentID, "message": "mode cleared"}, nil
 
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET", "PUT", "DELETE"}}
 		}
 	})
 
 	// Traffic logs
-	apiMux.HandleFunc("/logs", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-
+	mountJSON(apiMux, m, "/logs", func(r *http.Request) (interface{}, error) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET"}}
+		}
+		if err := authorizeACL(resolver, r, "logs", acl.Read); err != nil {
+			return nil, err
+		}
+
+		expr, err := parseLogFilterExpr(r)
+		if err != nil {
+			return nil, &api.BadRequestError{Message: "invalid filter: " + err.Error()}
 		}
 
 		limit := 50
@@ -230,154 +433,171 @@ func main() {
 			}
 		}
 
-		logs := logStore.Get(limit)
-		json.NewEncoder(w).Encode(logs)
+		entries := logStore.Get(limit)
+		if expr != nil {
+			filtered := make([]proxy.LogEntry, 0, len(entries))
+			for _, entry := range entries {
+				if expr.Match(entry) {
+					filtered = append(filtered, entry)
+				}
+			}
+			entries = filtered
+		}
+		return entries, nil
 	})
 
-	// Rules management
-	apiMux.HandleFunc("/rules", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+	// Streaming log tail. Kept raw: SSE/ndjson streaming doesn't fit the
+	// single-JSON-response Handler contract (see internal/api.MountRaw).
+	mountRaw(apiMux, m, "/logs/stream", logsStreamHandler(logStore, resolver))
 
-		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
+	// Rules management. Kept raw (not mountJSON) because conditional GET
+	// (If-None-Match -> 304) and the ?index=/?wait= blocking query need
+	// direct control over the response headers and status that the typed
+	// Handler contract doesn't expose.
+	mountRaw(apiMux, m, "/rules", rulesHandler(engine, resolver))
 
-		// Get rules from the engine
-		// For now, return the rules file path info
-		resp := map[string]interface{}{
-			"rules_path": engine.RulesPath(),
-			"rule_count": engine.RuleCount(),
-			"rules":      []interface{}{}, // TODO: expose rules from engine
-		}
-		json.NewEncoder(w).Encode(resp)
-	})
+	// Re-reads -rules from disk, validates it, and atomically swaps it into
+	// engine; the old ruleset keeps serving if the new one is invalid.
+	mountRaw(apiMux, m, "/rules/reload", rulesReloadHandler(engine, resolver))
 
-	// Fleet management
-	apiMux.HandleFunc("/fleet/mode", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	// Atomically imports a rule bundle (see rules.Bundle and `rules
+	// export`/`rules import`): merges by rule ID by default, or discards
+	// the active ruleset with ?replace=true; ?dry_run=true reports the
+	// would-be diff without persisting. Kept raw for the same reason as
+	// /rules and /rules/reload above.
+	mountRaw(apiMux, m, "/rules/bundle", rulesBundleHandler(engine, resolver))
 
+	// Fleet management
+	mountJSON(apiMux, m, "/fleet/mode", func(r *http.Request) (interface{}, error) {
 		tenantID := r.URL.Query().Get("tenant")
 		if tenantID == "" {
 			tenantID = "default"
 		}
+		resource := "tenant:" + tenantID
 
 		switch r.Method {
 		case http.MethodGet:
+			if err := authorizeACL(resolver, r, resource, acl.Read); err != nil {
+				return nil, err
+			}
 			mode := fleetManager.GetMode(tenantID)
-			json.NewEncoder(w).Encode(map[string]string{
+			return map[string]string{
 				"tenant": tenantID,
 				"mode":   string(mode),
-			})
+			}, nil
 
 		case http.MethodPut:
+			if err := authorizeACL(resolver, r, resource, acl.Write); err != nil {
+				return nil, err
+			}
 			var req struct {
 				Mode string `json:"mode"`
 			}
 			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-				http.Error(w, "Invalid JSON", http.StatusBadRequest)
-				return
+				return nil, &api.BadRequestError{Message: "Invalid JSON"}
 			}
 			switch fleet.Mode(req.Mode) {
 			case fleet.Isolated, fleet.Fleet:
 				fleetManager.SetMode(tenantID, fleet.Mode(req.Mode))
 				log.Printf("Tenant %s fleet mode changed to: %s", tenantID, req.Mode)
-				json.NewEncoder(w).Encode(map[string]string{
+				return map[string]string{
 					"status": "ok",
 					"tenant": tenantID,
 					"mode":   req.Mode,
-				})
+				}, nil
 			default:
-				http.Error(w, "Invalid mode. Use: isolated, fleet", http.StatusBadRequest)
+				return nil, &api.BadRequestError{Message: "Invalid mode. Use: isolated, fleet"}
 			}
 
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET", "PUT"}}
 		}
 	})
 
-	apiMux.HandleFunc("/fleet/agents", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
+	mountJSON(apiMux, m, "/fleet/agents", func(r *http.Request) (interface{}, error) {
 		tenantID := r.URL.Query().Get("tenant")
 		if tenantID == "" {
 			tenantID = "default"
 		}
+		resource := "tenant:" + tenantID
 
 		switch r.Method {
 		case http.MethodGet:
+			if err := authorizeACL(resolver, r, resource, acl.Read); err != nil {
+				return nil, err
+			}
 			// Get agents - respects fleet mode (returns empty in isolated mode)
 			agents := fleetManager.GetAgents(tenantID)
 			mode := fleetManager.GetMode(tenantID)
-			json.NewEncoder(w).Encode(map[string]interface{}{
+			return map[string]interface{}{
 				"tenant": tenantID,
 				"mode":   string(mode),
 				"agents": agents,
-			})
+			}, nil
 
 		case http.MethodPost:
+			if err := authorizeACL(resolver, r, resource, acl.Write); err != nil {
+				return nil, err
+			}
 			var agent fleet.Agent
 			if err := json.NewDecoder(r.Body).Decode(&agent); err != nil {
-				http.Error(w, "Invalid JSON", http.StatusBadRequest)
-				return
+				return nil, &api.BadRequestError{Message: "Invalid JSON"}
 			}
 			if agent.ID == "" {
-				http.Error(w, "Agent ID required", http.StatusBadRequest)
-				return
+				return nil, &api.BadRequestError{Message: "Agent ID required"}
 			}
 			fleetManager.AddAgent(tenantID, agent)
 			log.Printf("Agent %s added to tenant %s", agent.ID, tenantID)
-			json.NewEncoder(w).Encode(map[string]string{
+			return map[string]string{
 				"status": "ok",
 				"tenant": tenantID,
 				"agent":  agent.ID,
-			})
+			}, nil
 
 		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET", "POST"}}
 		}
 	})
 
-	apiMux.HandleFunc("/fleet/can-communicate", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-
+	mountJSON(apiMux, m, "/fleet/can-communicate", func(r *http.Request) (interface{}, error) {
 		if r.Method != http.MethodGet {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET"}}
 		}
 
 		from := r.URL.Query().Get("from")
 		to := r.URL.Query().Get("to")
 
 		if from == "" || to == "" {
-			http.Error(w, "from and to parameters required", http.StatusBadRequest)
-			return
+			return nil, &api.BadRequestError{Message: "from and to parameters required"}
+		}
+		if err := authorizeACL(resolver, r, "agent:"+from, acl.Read); err != nil {
+			return nil, err
 		}
 
 		canComm := fleetManager.CanCommunicate(from, to)
-		json.NewEncoder(w).Encode(map[string]interface{}{
+		return map[string]interface{}{
 			"from":            from,
 			"to":              to,
 			"can_communicate": canComm,
-		})
+		}, nil
+	})
+
+	// ACL token administration
+	mountJSON(apiMux, m, "/acl/tokens", aclTokensHandler(resolver))
+
+	// Log sink status: queue depth, drop count, bytes sent, and last error
+	// per configured destination (see internal/logsink.MultiSink.Stats).
+	mountJSON(apiMux, m, "/sinks", func(r *http.Request) (interface{}, error) {
+		if r.Method != http.MethodGet {
+			return nil, &api.MethodNotAllowedError{Allow: []string{"GET"}}
+		}
+		if err := authorizeACL(resolver, r, "sinks", acl.Read); err != nil {
+			return nil, err
+		}
+		if sinks == nil {
+			return map[string]interface{}{"sinks": []logsink.SinkStats{}}, nil
+		}
+		return map[string]interface{}{"sinks": sinks.Stats()}, nil
 	})
 
 	// Serve web UI at root
@@ -391,6 +611,28 @@ func main() {
 		IdleTimeout:  30 * time.Second,
 	}
 
+	// Admin server: /metrics (internal/metrics, distinct from the
+	// limiter.Metrics series at /metrics/limiter above), /healthz, /readyz.
+	// Split from apiAddr so a scraper doesn't need an ACL token and can't be
+	// rate-limited by the management API's own limiter. Mirrors
+	// cmd/gateway's metricsAddr server.
+	var readyReported atomic.Bool
+	readyReported.Store(true)
+
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.RefreshFleetGauges(fleetManager)
+		m.Handler()(w, r)
+	}))
+	adminMux.Handle("/healthz", limiter.HealthzHandler())
+	adminMux.Handle("/readyz", limiter.ReadyzHandler(readyReported.Load))
+	adminServer := &http.Server{
+		Addr:         *adminAddr,
+		Handler:      adminMux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
 	// Start servers
 	go func() {
 		log.Printf("Starting proxy server on %s", *proxyAddr)
@@ -408,6 +650,22 @@ func main() {
 		}
 	}()
 
+	go func() {
+		log.Printf("Admin (metrics/health) listening on %s", *adminAddr)
+		if err := adminServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatalf("Admin server error: %v", err)
+		}
+	}()
+
+	if controlServer != nil {
+		go func() {
+			log.Printf("ShieldControl gRPC service listening on %s", *controlGRPCAddr)
+			if err := controlServer.Server().Serve(controlListener); err != nil {
+				log.Printf("ShieldControl gRPC server error: %v", err)
+			}
+		}()
+	}
+
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -420,6 +678,18 @@ func main() {
 
 	proxyServer.Shutdown(ctx)
 	apiServer.Shutdown(ctx)
+	if controlServer != nil {
+		controlServer.Server().GracefulStop()
+	}
+	if aclWatcher != nil {
+		aclWatcher.Close()
+	}
+	if sinks != nil {
+		sinks.Close()
+	}
+	if appLogHandler != nil {
+		appLogHandler.Close()
+	}
 
 	log.Println("Shutdown complete")
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Extra-Chill/plasma-shield/internal/acl"
+	"github.com/Extra-Chill/plasma-shield/internal/rules"
+)
+
+// rulesBundleHandler handles POST /rules/bundle: it parses the request body
+// as a rule bundle (see rules.Bundle), merges it into the active ruleset by
+// rule ID (or, with ?replace=true, discards the active ruleset and uses the
+// bundle wholesale), and atomically swaps the result in via
+// rules.Engine.LoadRuleSet -- the same validation rulesReloadHandler uses,
+// so a bundle with an unknown action or an unregexable pattern never
+// disturbs the currently-serving ruleset. With ?dry_run=true the candidate
+// ruleset is computed and diffed but never installed.
+func rulesBundleHandler(engine *rules.Engine, resolver *acl.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkACL(resolver, w, r, "rules", acl.Write) {
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		bundle, err := rules.ParseBundle(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		overlay := bundle.Flatten()
+
+		current := &rules.RuleSet{Rules: engine.Snapshot()}
+		candidate := overlay
+		if r.URL.Query().Get("replace") != "true" {
+			candidate = rules.MergeRuleSets(current, overlay)
+		}
+		diff := rules.DiffRuleSets(current, candidate)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Query().Get("dry_run") == "true" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":              "dry_run",
+				"diff":                diff,
+				"would_be_rule_count": len(candidate.Rules),
+			})
+			return
+		}
+
+		if err := engine.LoadRuleSet(candidate); err != nil {
+			resp := map[string]interface{}{"error": err.Error()}
+			var verr *rules.ValidationError
+			if errors.As(err, &verr) {
+				resp["rule_index"] = verr.Index
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     "ok",
+			"diff":       diff,
+			"rules_hash": engine.Hash(),
+			"rule_count": engine.RuleCount(),
+		})
+	}
+}
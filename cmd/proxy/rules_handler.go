@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/acl"
+	"github.com/Extra-Chill/plasma-shield/internal/rules"
+)
+
+// defaultRulesWait is the blocking-query timeout GET /rules uses when
+// ?index= is set without an explicit ?wait=, matching Consul's default.
+const defaultRulesWait = 30 * time.Second
+
+// rulesHandler handles GET /rules: it returns the full compiled ruleset
+// alongside a content hash (rules_hash, also echoed as the ETag header), so
+// callers can cheaply detect that nothing changed via If-None-Match -> 304,
+// or long-poll for the next change via ?index=<hash>&wait=<duration> (a
+// Consul-style blocking query, see rules.Engine.WaitForChange) instead of
+// tight-polling.
+func rulesHandler(engine *rules.Engine, resolver *acl.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkACL(resolver, w, r, "rules", acl.Read) {
+			return
+		}
+
+		if r.URL.Query().Get("format") == "bundle" {
+			writeRuleBundle(w, engine, r.URL.Query().Get("group"))
+			return
+		}
+
+		hash := engine.Hash()
+		if index := r.URL.Query().Get("index"); index != "" {
+			wait := defaultRulesWait
+			if raw := r.URL.Query().Get("wait"); raw != "" {
+				d, err := time.ParseDuration(raw)
+				if err != nil {
+					http.Error(w, "invalid wait: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+				wait = d
+			}
+			hash, _ = engine.WaitForChange(r.Context(), index, wait)
+		}
+
+		if inm := r.Header.Get("If-None-Match"); inm != "" && inm == hash {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", hash)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rules_path": engine.RulesPath(),
+			"rule_count": engine.RuleCount(),
+			"rules":      engine.Snapshot(),
+			"rules_hash": hash,
+		})
+	}
+}
+
+// rulesReloadHandler handles POST /rules/reload: it re-reads the file
+// engine was started with (the -rules flag), validates it, and atomically
+// swaps it in via rules.Engine.LoadRules. On a validation failure the old
+// ruleset keeps serving and the response is a 400 naming the offending
+// rule's index.
+func rulesReloadHandler(engine *rules.Engine, resolver *acl.Resolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !checkACL(resolver, w, r, "rules", acl.Write) {
+			return
+		}
+
+		path := engine.RulesPath()
+		if path == "" {
+			http.Error(w, "no rules file configured; start the proxy with -rules", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := engine.LoadRules(path); err != nil {
+			resp := map[string]interface{}{"error": err.Error()}
+			var verr *rules.ValidationError
+			if errors.As(err, &verr) {
+				resp["rule_index"] = verr.Index
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     "ok",
+			"rules_hash": engine.Hash(),
+			"rule_count": engine.RuleCount(),
+		})
+	}
+}
+
+// writeRuleBundle writes the active ruleset as a rules.Bundle (see
+// rules.BundleFromRuleSet), the response GET /rules?format=bundle and
+// `rules export` rely on. An empty group exports every group; a non-empty
+// one exports only that group's rules, as `rules export --group NAME`.
+func writeRuleBundle(w http.ResponseWriter, engine *rules.Engine, group string) {
+	rs := &rules.RuleSet{Rules: engine.Snapshot()}
+	bundle := rules.BundleFromRuleSet(rs, group)
+
+	data, err := bundle.ToYAML()
+	if err != nil {
+		http.Error(w, "failed to marshal rule bundle: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.Write(data)
+}
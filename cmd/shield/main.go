@@ -5,8 +5,15 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
 )
 
 var version = "0.1.0"
@@ -37,6 +44,12 @@ func main() {
 		handleRules(os.Args[2:])
 	case "logs":
 		handleLogs(os.Args[2:])
+	case "bastion":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: shield bastion replay <session-id> [--dir <recording-dir>]")
+			os.Exit(1)
+		}
+		handleBastion(os.Args[2:])
 	case "auth":
 		if len(os.Args) < 3 {
 			fmt.Println("Usage: shield auth <login|logout>")
@@ -60,6 +73,7 @@ Commands:
   agent           Manage agents (list, pause, kill)
   rules           Manage blocking rules
   logs            View traffic logs
+  bastion         Bastion session recordings (replay)
   auth            Authentication (login, logout)
   version         Show version
 
@@ -68,7 +82,8 @@ Examples:
   shield agent list
   shield agent pause my-agent
   shield rules add --pattern "rm -rf"
-  shield logs --tail --agent my-agent`)
+  shield logs --tail --agent my-agent
+  shield bastion replay abc123-456`)
 }
 
 func handleAgent(args []string) {
@@ -79,8 +94,187 @@ func handleRules(args []string) {
 	fmt.Println("Rules management not yet implemented")
 }
 
+// handleLogs implements `shield logs --tail`, following GET /logs/stream
+// live. Plain `shield logs` (no --tail) isn't implemented yet.
 func handleLogs(args []string) {
-	fmt.Println("Log viewing not yet implemented")
+	var tail bool
+	var agentID, action, logType, since, filterExpr string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--tail":
+			tail = true
+		case "--agent":
+			i++
+			if i < len(args) {
+				agentID = args[i]
+			}
+		case "--action":
+			i++
+			if i < len(args) {
+				action = args[i]
+			}
+		case "--type":
+			i++
+			if i < len(args) {
+				logType = args[i]
+			}
+		case "--since":
+			i++
+			if i < len(args) {
+				since = args[i]
+			}
+		case "--filter":
+			i++
+			if i < len(args) {
+				filterExpr = args[i]
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "shield: unknown logs flag %q\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	if !tail {
+		fmt.Println("Log viewing not yet implemented; pass --tail to stream live logs")
+		return
+	}
+
+	tailLogs(agentID, action, logType, since, filterExpr)
+}
+
+// tailLogs streams GET /logs/stream and pretty-prints each entry as it
+// arrives, following the same API URL and auth token conventions as the
+// plasma-shield CLI.
+func tailLogs(agentID, action, logType, since, filterExpr string) {
+	apiURL := getEnvOrDefault("PLASMA_API_URL", "http://localhost:8443")
+	token := os.Getenv("PLASMA_TOKEN")
+
+	query := url.Values{}
+	if agentID != "" {
+		query.Set("agent_id", agentID)
+	}
+	if action != "" {
+		query.Set("action", action)
+	}
+	if logType != "" {
+		query.Set("type", logType)
+	}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if filterExpr != "" {
+		query.Set("filter", filterExpr)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, apiURL+"/logs/stream?"+query.Encode(), nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shield: build request: %v\n", err)
+		os.Exit(1)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shield: connect to %s: %v\n", apiURL, err)
+		os.Exit(1)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "shield: %s: unexpected status %s\n", apiURL, resp.Status)
+		os.Exit(1)
+	}
+
+	event := "message"
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			if event == "dropped" {
+				fmt.Fprintf(os.Stderr, "shield: server dropped events for this connection: %s\n", data)
+			} else {
+				fmt.Println(data)
+			}
+			event = "message"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "shield: stream closed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// getEnvOrDefault returns the value of the named environment variable, or
+// defaultVal if it's unset or empty.
+func getEnvOrDefault(key, defaultVal string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultVal
+}
+
+func handleBastion(args []string) {
+	switch args[0] {
+	case "replay":
+		handleBastionReplay(args[1:])
+	default:
+		fmt.Printf("Unknown bastion command: %s\n", args[0])
+		os.Exit(1)
+	}
+}
+
+// handleBastionReplay pretty-prints a bastion session recording written by
+// bastion.FileRecorder: the session's meta.json (principal, grant, target,
+// timing), followed by each frame with its offset and direction.
+func handleBastionReplay(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: shield bastion replay <session-id> [--dir <recording-dir>]")
+		os.Exit(1)
+	}
+	sessionID := args[0]
+	recordingDir := "."
+	for i := 1; i < len(args)-1; i++ {
+		if args[i] == "--dir" {
+			recordingDir = args[i+1]
+		}
+	}
+
+	if meta, err := bastion.ReadMeta(recordingDir, sessionID); err == nil {
+		fmt.Printf("session %s: principal=%s grant=%s target=%s %s -> %s\n",
+			meta.SessionID, meta.Principal, meta.GrantID, meta.Target,
+			meta.StartedAt.Format("15:04:05"), meta.StoppedAt.Format("15:04:05"))
+	} else {
+		fmt.Fprintf(os.Stderr, "shield: read meta for session %s: %v\n", sessionID, err)
+	}
+
+	file, err := bastion.OpenStream(recordingDir, sessionID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shield: open recording for session %s: %v\n", sessionID, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	for {
+		offsetMS, frameDir, payload, err := bastion.ReadFrame(file)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shield: read frame: %v\n", err)
+			os.Exit(1)
+		}
+		direction := "client"
+		if frameDir == bastion.FrameServer {
+			direction = "server"
+		}
+		fmt.Printf("[%6dms] %-6s %s", offsetMS, direction, payload)
+	}
 }
 
 func handleAuth(args []string) {
@@ -0,0 +1,58 @@
+// Package accesslog provides structured access logging for the shield's
+// proxy and reverse-proxy handlers, modeled on Traefik's accesslog
+// middleware: a single Record type describes a completed request, and
+// pluggable Logger implementations decide where it ends up.
+package accesslog
+
+import "time"
+
+// Decision is the outcome the shield reached for a request.
+type Decision string
+
+const (
+	DecisionForward Decision = "forward"
+	DecisionBlock   Decision = "block"
+	DecisionInspect Decision = "inspect" // would have blocked, but audit mode
+)
+
+// Record describes a single completed request/response cycle.
+type Record struct {
+	Time       time.Time
+	Tenant     string
+	Agent      string
+	Captain    string
+	Method     string
+	Domain     string
+	Path       string
+	SNI        string // TLS SNI for a CONNECT tunnel; see Handler.handleConnect
+	Decision   Decision
+	RuleID     string
+	StatusCode int
+	ReqBytes   int64
+	RespBytes  int64
+	Latency    time.Duration
+}
+
+// Logger writes completed access log Records to some destination.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Log(Record)
+}
+
+// NopLogger discards every record. It is the default when no Logger is
+// configured via WithAccessLog, so callers never need a nil check.
+type NopLogger struct{}
+
+// Log implements Logger.
+func (NopLogger) Log(Record) {}
+
+// MultiLogger fans a Record out to several Loggers, e.g. a JSON file plus
+// an OTLP exporter.
+type MultiLogger []Logger
+
+// Log implements Logger.
+func (m MultiLogger) Log(rec Record) {
+	for _, l := range m {
+		l.Log(rec)
+	}
+}
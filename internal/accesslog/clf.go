@@ -0,0 +1,46 @@
+package accesslog
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CLFLogger writes access records in the Apache Combined Log Format, for
+// operators piping the shield's logs into tooling that already expects
+// CLF (fail2ban, GoAccess, ...).
+type CLFLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewCLFLogger returns a CLFLogger writing to w.
+func NewCLFLogger(w io.Writer) *CLFLogger {
+	return &CLFLogger{w: w}
+}
+
+// Log implements Logger, formatting the record as:
+//
+//	agent - tenant [time] "METHOD path" status respBytes "captain" "ruleID"
+//
+// The first three fields map onto CLF's remotehost/identity/user; the
+// trailing two fields are the Combined-format "referer"/"user-agent"
+// slots, repurposed here to carry captain and matched rule ID since the
+// shield has no browser referer/UA to report.
+func (c *CLFLogger) Log(rec Record) {
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s\" %d %d \"%s\" \"%s\"\n",
+		rec.Agent,
+		rec.Tenant,
+		rec.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		rec.Method,
+		rec.Path,
+		rec.StatusCode,
+		rec.RespBytes,
+		rec.Captain,
+		rec.RuleID,
+	)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	io.WriteString(c.w, line)
+}
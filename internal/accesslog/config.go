@@ -0,0 +1,85 @@
+package accesslog
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ParseSinks builds a Logger from spec, the comma-separated value of the
+// --access-log flag, mirroring plasmalog.ParseSinks' "kind=value" syntax:
+// "file=<path>", "syslog=<network>://<addr>", "otlp=<push URL>",
+// "clf=stdout"/"clf=stderr". A spec with no "=" is treated as a bare file
+// path, preserving --access-log's original path-only behavior. An empty
+// spec returns NopLogger, so the flag can be safely omitted.
+func ParseSinks(spec string) (Logger, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return NopLogger{}, nil
+	}
+	if !strings.Contains(spec, "=") {
+		return NewFileLogger(spec)
+	}
+
+	var loggers MultiLogger
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, value, _ := strings.Cut(entry, "=")
+		kind = strings.TrimSpace(kind)
+		value = strings.TrimSpace(value)
+
+		logger, err := buildSink(kind, value)
+		if err != nil {
+			return nil, fmt.Errorf("accesslog: sink %q: %w", entry, err)
+		}
+		loggers = append(loggers, logger)
+	}
+	if len(loggers) == 1 {
+		return loggers[0], nil
+	}
+	return loggers, nil
+}
+
+func buildSink(kind, value string) (Logger, error) {
+	switch kind {
+	case "file":
+		if value == "" {
+			return nil, fmt.Errorf("file sink requires a path, e.g. file=/var/log/plasma-shield-access.jsonl")
+		}
+		return NewFileLogger(value)
+
+	case "syslog":
+		if value == "" {
+			return nil, fmt.Errorf("syslog sink requires a URL, e.g. syslog=udp://collector:514")
+		}
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog URL %q: %w", value, err)
+		}
+		return NewSyslogLogger(u.Scheme, u.Host)
+
+	case "otlp":
+		if value == "" {
+			return nil, fmt.Errorf("otlp sink requires a push URL, e.g. otlp=http://collector:4318/v1/logs")
+		}
+		return NewOTLPExporter(value, 0, 0), nil
+
+	case "clf":
+		switch value {
+		case "", "stdout":
+			return NewCLFLogger(os.Stdout), nil
+		case "stderr":
+			return NewCLFLogger(os.Stderr), nil
+		default:
+			return nil, fmt.Errorf("clf sink only supports stdout/stderr, got %q", value)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", kind)
+	}
+}
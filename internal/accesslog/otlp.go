@@ -0,0 +1,150 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLPExporter batches Records and ships them as OTLP/HTTP log records to
+// a collector endpoint (e.g. an otel-collector sidecar). It keeps the
+// dependency surface small by speaking the OTLP/HTTP+JSON wire format
+// directly rather than pulling in the full OpenTelemetry SDK.
+type OTLPExporter struct {
+	endpoint   string
+	client     *http.Client
+	batchSize  int
+	flushEvery time.Duration
+
+	mu      sync.Mutex
+	pending []Record
+	done    chan struct{}
+}
+
+// NewOTLPExporter creates an exporter posting batches to endpoint (the
+// collector's OTLP/HTTP logs path, e.g. "http://localhost:4318/v1/logs").
+func NewOTLPExporter(endpoint string, batchSize int, flushEvery time.Duration) *OTLPExporter {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if flushEvery <= 0 {
+		flushEvery = 5 * time.Second
+	}
+	e := &OTLPExporter{
+		endpoint:   endpoint,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		batchSize:  batchSize,
+		flushEvery: flushEvery,
+		done:       make(chan struct{}),
+	}
+	go e.flushLoop()
+	return e
+}
+
+// Log implements Logger, buffering the record until the batch fills or the
+// flush interval elapses.
+func (e *OTLPExporter) Log(rec Record) {
+	e.mu.Lock()
+	e.pending = append(e.pending, rec)
+	full := len(e.pending) >= e.batchSize
+	e.mu.Unlock()
+
+	if full {
+		e.flush()
+	}
+}
+
+func (e *OTLPExporter) flushLoop() {
+	ticker := time.NewTicker(e.flushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flush()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// otlpLogRecord is a minimal OTLP/HTTP+JSON log record, carrying the
+// Record's fields as structured attributes.
+type otlpLogRecord struct {
+	TimeUnixNano string                 `json:"timeUnixNano"`
+	Body         map[string]interface{} `json:"body"`
+}
+
+func (e *OTLPExporter) flush() {
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	records := make([]otlpLogRecord, 0, len(batch))
+	for _, rec := range batch {
+		records = append(records, otlpLogRecord{
+			TimeUnixNano: strconv.FormatInt(rec.Time.UnixNano(), 10),
+			Body: map[string]interface{}{
+				"tenant":      rec.Tenant,
+				"agent":       rec.Agent,
+				"captain":     rec.Captain,
+				"method":      rec.Method,
+				"domain":      rec.Domain,
+				"path":        rec.Path,
+				"decision":    rec.Decision,
+				"rule_id":     rec.RuleID,
+				"status_code": rec.StatusCode,
+				"req_bytes":   rec.ReqBytes,
+				"resp_bytes":  rec.RespBytes,
+				"latency_ms":  rec.Latency.Milliseconds(),
+			},
+		})
+	}
+
+	payload := map[string]interface{}{
+		"resourceLogs": []map[string]interface{}{
+			{"scopeLogs": []map[string]interface{}{
+				{"logRecords": records},
+			}},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("accesslog: failed to marshal OTLP batch: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("accesslog: failed to build OTLP request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Printf("accesslog: OTLP export failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// Close stops the flush loop and sends any remaining buffered records.
+func (e *OTLPExporter) Close() error {
+	close(e.done)
+	e.flush()
+	return nil
+}
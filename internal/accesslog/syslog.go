@@ -0,0 +1,53 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"sync"
+)
+
+// SyslogLogger writes each Record to a syslog collector via the standard
+// library's log/syslog client, carrying the record's JSON encoding as the
+// message body. Mirrors plasmalog.SyslogSink, applied to the per-request
+// access log instead of the application log.
+type SyslogLogger struct {
+	mu sync.Mutex
+	w  *syslog.Writer
+}
+
+// NewSyslogLogger dials a syslog collector at raddr over network ("udp" or
+// "tcp"; empty uses the local syslog daemon) and returns a SyslogLogger
+// ready to Log, tagged with the plasma-shield process name.
+func NewSyslogLogger(network, raddr string) (*SyslogLogger, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, "plasma-shield")
+	if err != nil {
+		return nil, fmt.Errorf("accesslog: dial syslog %s (%s): %w", raddr, network, err)
+	}
+	return &SyslogLogger{w: w}, nil
+}
+
+// Log implements Logger, sending rec as a single syslog message at INFO
+// severity, or WARNING if it was blocked.
+func (s *SyslogLogger) Log(rec Record) {
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec.Decision == DecisionBlock {
+		s.w.Warning(string(body))
+		return
+	}
+	s.w.Info(string(body))
+}
+
+// Close closes the underlying syslog connection.
+func (s *SyslogLogger) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Close()
+}
@@ -0,0 +1,83 @@
+package accesslog
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// TenantRouter fans Records out to a per-tenant Logger, so e.g.
+// ReverseHandler requests land in a tenant-scoped log instead of one
+// shared one. Loggers are created lazily via newLogger on first use of a
+// given tenant and cached afterward.
+type TenantRouter struct {
+	mu        sync.Mutex
+	newLogger func(tenant string) (Logger, error)
+	loggers   map[string]Logger
+	fallback  Logger
+}
+
+// NewTenantRouter returns a TenantRouter that creates a Logger for a
+// tenant via newLogger the first time that tenant is seen. Records with no
+// Tenant set, or for which newLogger errors, go to fallback instead
+// (NopLogger if fallback is nil).
+func NewTenantRouter(newLogger func(tenant string) (Logger, error), fallback Logger) *TenantRouter {
+	if fallback == nil {
+		fallback = NopLogger{}
+	}
+	return &TenantRouter{
+		newLogger: newLogger,
+		loggers:   make(map[string]Logger),
+		fallback:  fallback,
+	}
+}
+
+// Log implements Logger.
+func (t *TenantRouter) Log(rec Record) {
+	if rec.Tenant == "" {
+		t.fallback.Log(rec)
+		return
+	}
+
+	t.mu.Lock()
+	logger, ok := t.loggers[rec.Tenant]
+	if !ok {
+		created, err := t.newLogger(rec.Tenant)
+		if err != nil {
+			t.mu.Unlock()
+			t.fallback.Log(rec)
+			return
+		}
+		logger = created
+		t.loggers[rec.Tenant] = logger
+	}
+	t.mu.Unlock()
+
+	logger.Log(rec)
+}
+
+// Close closes every per-tenant Logger that implements io.Closer, e.g. the
+// *FileLogger instances NewTenantFileRouter creates.
+func (t *TenantRouter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var firstErr error
+	for _, logger := range t.loggers {
+		if closer, ok := logger.(interface{ Close() error }); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// NewTenantFileRouter returns a TenantRouter that writes each tenant's
+// records to its own JSON-lines file "<dir>/<tenant>.jsonl", falling back
+// to fallback for records with no Tenant (e.g. the forward proxy's, which
+// has no tenant concept).
+func NewTenantFileRouter(dir string, fallback Logger) *TenantRouter {
+	return NewTenantRouter(func(tenant string) (Logger, error) {
+		return NewFileLogger(filepath.Join(dir, tenant+".jsonl"))
+	}, fallback)
+}
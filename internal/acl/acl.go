@@ -0,0 +1,116 @@
+// Package acl provides a token-based access control resolver for the proxy's
+// management API, modeled on Consul's agent tokens: each bearer token maps
+// to a policy document listing the access level (read, write, or admin) it
+// holds over a set of resources (mode, agent:<id>, tenant:<id>, rules,
+// logs). An anonymous policy applies to callers who present no token at
+// all, so deployments that haven't configured tokens yet keep working.
+package acl
+
+import "fmt"
+
+// AccessLevel is a grant a policy holds over a resource. Levels are ordered
+// read < write < admin: a policy granted write may also perform read, and
+// admin may perform both.
+type AccessLevel string
+
+const (
+	Read  AccessLevel = "read"
+	Write AccessLevel = "write"
+	Admin AccessLevel = "admin"
+)
+
+// accessRank orders AccessLevel for the >= comparison Authorize performs.
+var accessRank = map[AccessLevel]int{
+	Read:  1,
+	Write: 2,
+	Admin: 3,
+}
+
+// allows reports whether a policy granted level satisfies a request that
+// requires need, per the read < write < admin hierarchy.
+func (level AccessLevel) allows(need AccessLevel) bool {
+	return accessRank[level] >= accessRank[need]
+}
+
+// Policy grants Access over Resource. Resource is matched exactly, by
+// "<prefix>:*" wildcard (e.g. "agent:*" matches "agent:crew-1"), or by the
+// global wildcard "*".
+type Policy struct {
+	Resource string      `yaml:"resource"`
+	Access   AccessLevel `yaml:"access"`
+}
+
+// matches reports whether p covers resource.
+func (p Policy) matches(resource string) bool {
+	if p.Resource == "*" || p.Resource == resource {
+		return true
+	}
+	if prefix, ok := wildcardPrefix(p.Resource); ok {
+		return len(resource) > len(prefix) && resource[:len(prefix)] == prefix
+	}
+	return false
+}
+
+// wildcardPrefix reports whether pattern is of the form "<prefix>:*" and, if
+// so, returns "<prefix>:".
+func wildcardPrefix(pattern string) (string, bool) {
+	if len(pattern) < 2 || pattern[len(pattern)-1] != '*' {
+		return "", false
+	}
+	prefix := pattern[:len(pattern)-1]
+	if prefix == "" || prefix[len(prefix)-1] != ':' {
+		return "", false
+	}
+	return prefix, true
+}
+
+// tokenEntry is a token's resolved policy set, keyed by the raw token value
+// in Resolver.tokens.
+type tokenEntry struct {
+	name     string
+	policies []Policy
+}
+
+func (e *tokenEntry) authorized(resource string, need AccessLevel) bool {
+	for _, p := range e.policies {
+		if p.matches(resource) && p.Access.allows(need) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForbiddenError is returned by Authorize when a token (or the anonymous
+// policy, for an empty token) doesn't grant sufficient access over a
+// resource. Name is the token's configured name, for audit logging,
+// redacted to "anonymous" when no token was presented.
+type ForbiddenError struct {
+	Name     string
+	Resource string
+	Op       AccessLevel
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("acl: %s: forbidden: %s requires %s", e.Name, e.Resource, e.Op)
+}
+
+// NotFoundError is returned by Authorize when a non-empty token doesn't
+// match any configured entry, as distinct from ForbiddenError's "known
+// token, insufficient access" -- useful for an operator to tell a typo'd
+// token apart from a token that's simply under-provisioned.
+type NotFoundError struct {
+	Token string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("acl: unknown token %s", Redact(e.Token))
+}
+
+// Redact returns a token's first 4 characters plus an ellipsis, for log
+// lines and error messages that shouldn't leak the full credential.
+func Redact(token string) string {
+	if len(token) <= 4 {
+		return "***"
+	}
+	return token[:4] + "…"
+}
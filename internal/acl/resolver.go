@@ -0,0 +1,175 @@
+package acl
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the on-disk YAML shape loaded by LoadResolver: an anonymous
+// policy set applied to callers with no token, plus a list of named
+// tokens each with their own policy set.
+type Config struct {
+	Anonymous []Policy      `yaml:"anonymous"`
+	Tokens    []TokenConfig `yaml:"tokens"`
+}
+
+// TokenConfig is one entry under Config.Tokens.
+type TokenConfig struct {
+	Token    string   `yaml:"token"`
+	Name     string   `yaml:"name"`
+	Policies []Policy `yaml:"policies"`
+}
+
+// Resolver maps bearer tokens to policy sets and answers Authorize calls
+// for the proxy's management API. It's safe for concurrent use; Reload
+// swaps in a newly parsed Config atomically.
+type Resolver struct {
+	mu        sync.RWMutex
+	path      string
+	tokens    map[string]*tokenEntry
+	anonymous *tokenEntry
+}
+
+// LoadResolver reads and parses path, the ACL YAML config file. An empty
+// path returns a Resolver with no tokens and no anonymous policy, so every
+// call is forbidden by default -- callers that want the previous wide-open
+// behavior must configure an explicit `anonymous: [{resource: "*", access:
+// admin}]` entry.
+func LoadResolver(path string) (*Resolver, error) {
+	r := &Resolver{path: path}
+	if path == "" {
+		r.tokens = make(map[string]*tokenEntry)
+		return r, nil
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the config file at r's path and atomically swaps in the
+// parsed tokens and anonymous policy. The previous policy set is left in
+// place if the file is missing or fails to parse.
+func (r *Resolver) Reload() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("acl: read config %s: %w", r.path, err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("acl: parse config %s: %w", r.path, err)
+	}
+
+	tokens := make(map[string]*tokenEntry, len(config.Tokens))
+	for _, tc := range config.Tokens {
+		tokens[tc.Token] = &tokenEntry{name: tc.Name, policies: tc.Policies}
+	}
+
+	var anon *tokenEntry
+	if len(config.Anonymous) > 0 {
+		anon = &tokenEntry{name: "anonymous", policies: config.Anonymous}
+	}
+
+	r.mu.Lock()
+	r.tokens = tokens
+	r.anonymous = anon
+	r.mu.Unlock()
+	return nil
+}
+
+// Authorize reports whether token grants at least need access over
+// resource. An empty token is resolved against the anonymous policy (see
+// LoadResolver). A non-empty token not present in the config returns
+// *NotFoundError; a token (or anonymous policy) present but lacking
+// sufficient access returns *ForbiddenError.
+func (r *Resolver) Authorize(token, resource string, need AccessLevel) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry := r.anonymous
+	name := "anonymous"
+	if token != "" {
+		te, ok := r.tokens[token]
+		if !ok {
+			return &NotFoundError{Token: token}
+		}
+		entry = te
+		name = te.name
+	}
+
+	if entry == nil || !entry.authorized(resource, need) {
+		return &ForbiddenError{Name: name, Resource: resource, Op: need}
+	}
+	return nil
+}
+
+// Tokens returns every configured token's name and policies, for the
+// /acl/tokens list endpoint. Token values themselves are included since the
+// endpoint is admin-only and operators need them to hand out or revoke.
+func (r *Resolver) Tokens() []TokenConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]TokenConfig, 0, len(r.tokens))
+	for token, entry := range r.tokens {
+		out = append(out, TokenConfig{Token: token, Name: entry.name, Policies: entry.policies})
+	}
+	return out
+}
+
+// AddToken adds or replaces a token's policy set and persists the change to
+// the config file, so it survives a restart and is picked up by any other
+// process watching the same file.
+func (r *Resolver) AddToken(tc TokenConfig) error {
+	r.mu.Lock()
+	if r.tokens == nil {
+		r.tokens = make(map[string]*tokenEntry)
+	}
+	r.tokens[tc.Token] = &tokenEntry{name: tc.Name, policies: tc.Policies}
+	r.mu.Unlock()
+	return r.persist()
+}
+
+// RemoveToken deletes a token and persists the change. Reports whether the
+// token existed.
+func (r *Resolver) RemoveToken(token string) (bool, error) {
+	r.mu.Lock()
+	_, ok := r.tokens[token]
+	delete(r.tokens, token)
+	r.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, r.persist()
+}
+
+// persist writes the resolver's current tokens and anonymous policy back to
+// r.path. Must be called without r.mu held.
+func (r *Resolver) persist() error {
+	if r.path == "" {
+		return nil
+	}
+
+	r.mu.RLock()
+	config := Config{Tokens: make([]TokenConfig, 0, len(r.tokens))}
+	for token, entry := range r.tokens {
+		config.Tokens = append(config.Tokens, TokenConfig{Token: token, Name: entry.name, Policies: entry.policies})
+	}
+	if r.anonymous != nil {
+		config.Anonymous = r.anonymous.policies
+	}
+	r.mu.RUnlock()
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("acl: marshal config: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0600); err != nil {
+		return fmt.Errorf("acl: write config %s: %w", r.path, err)
+	}
+	return nil
+}
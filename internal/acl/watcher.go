@@ -0,0 +1,100 @@
+package acl
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher observes a Resolver's config file for changes and reloads it on
+// each change, mirroring rules.Watcher's fsnotify+debounce+SIGHUP pattern.
+// A bad update is logged and skipped; the resolver keeps serving the last
+// good policy set.
+type Watcher struct {
+	resolver *Resolver
+	path     string
+	watcher  *fsnotify.Watcher
+	sigCh    chan os.Signal
+	done     chan struct{}
+}
+
+// WatchResolver starts watching r's config file for changes and SIGHUP,
+// reloading r on each. Close must be called on shutdown to stop the
+// watcher goroutine. r must have been created with a non-empty path (see
+// LoadResolver).
+func WatchResolver(r *Resolver) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(r.path); err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		resolver: r,
+		path:     r.path,
+		watcher:  fw,
+		sigCh:    make(chan os.Signal, 1),
+		done:     make(chan struct{}),
+	}
+	signal.Notify(w.sigCh, syscall.SIGHUP)
+	go w.run()
+	return w, nil
+}
+
+// run processes fsnotify events, debouncing bursts of writes (editors often
+// emit several events for a single save) into a single reload.
+func (w *Watcher) run() {
+	var pending *time.Timer
+	reload := func() { w.reload() }
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if pending != nil {
+				pending.Stop()
+			}
+			pending = time.AfterFunc(100*time.Millisecond, reload)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("acl watcher error: %v", err)
+
+		case <-w.sigCh:
+			log.Println("acl: received SIGHUP, reloading token config")
+			w.reload()
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *Watcher) reload() {
+	if err := w.resolver.Reload(); err != nil {
+		log.Printf("acl: reload failed for %s: %v", w.path, err)
+		return
+	}
+	log.Printf("acl: reloaded token config from %s", w.path)
+}
+
+// Close stops the watcher and releases its fsnotify and signal resources.
+func (w *Watcher) Close() error {
+	close(w.done)
+	signal.Stop(w.sigCh)
+	return w.watcher.Close()
+}
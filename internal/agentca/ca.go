@@ -0,0 +1,301 @@
+// Package agentca issues short-lived X.509 client certificates that
+// authenticate agents to the management API via mTLS, in place of the
+// long-lived static bearer tokens in fleet.TokenConfig.
+package agentca
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultCAKeyPath = "agent_ca_key"
+
+// AgentCA manages the X.509 CA used to issue and revoke agent client
+// certificates.
+type AgentCA struct {
+	mu sync.RWMutex // guards cert, signer, and serial across Rotate and concurrent signing
+
+	cert        *x509.Certificate
+	signer      crypto.Signer
+	keyPath     string
+	now         func() time.Time
+	revocations *revocationStore
+	serial      int64
+}
+
+// NewAgentCA loads or creates a CA keypair at path.
+func NewAgentCA(path string) (*AgentCA, error) {
+	return NewAgentCAWithClock(path, func() time.Time { return time.Now().UTC() })
+}
+
+// NewAgentCAWithClock loads or creates a CA keypair with a custom clock.
+func NewAgentCAWithClock(path string, now func() time.Time) (*AgentCA, error) {
+	if now == nil {
+		panic("agentca: nil clock")
+	}
+	if path == "" {
+		path = defaultCAKeyPath
+	}
+
+	signer, cert, err := loadOrCreateCAKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentCA{
+		cert:        cert,
+		signer:      signer,
+		keyPath:     path,
+		now:         now,
+		revocations: newRevocationStore(path+".revoked.json", now),
+	}, nil
+}
+
+// CertPool returns a pool containing only the CA certificate, suitable for
+// tls.Config.ClientCAs.
+func (c *AgentCA) CertPool() *x509.CertPool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(c.cert)
+	return pool
+}
+
+// IssueCertificate signs a CSR into a short-lived client certificate whose
+// SAN URIs encode the given identity. The CSR's own subject/SANs are
+// ignored; only its public key is used, so a compromised enrollment
+// secret can't be used to mint an arbitrary identity.
+func (c *AgentCA) IssueCertificate(csrPEM []byte, tenantID, agentID, tier string, ttl time.Duration) (*x509.Certificate, error) {
+	if tenantID == "" || agentID == "" {
+		return nil, errors.New("agentca: tenant_id and agent_id are required")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("agentca: ttl must be positive")
+	}
+
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("agentca: csr must be PEM-encoded with type CERTIFICATE REQUEST")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("agentca: parse csr: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("agentca: csr signature invalid: %w", err)
+	}
+
+	identity := Identity{TenantID: tenantID, AgentID: agentID, Tier: tier}
+	sanURI, err := url.Parse(identity.sanURI())
+	if err != nil {
+		return nil, fmt.Errorf("agentca: build identity URI: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano() + c.serial),
+		Subject:      pkix.Name{CommonName: agentID},
+		NotBefore:    now,
+		NotAfter:     now.Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{sanURI},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, csr.PublicKey, c.signer)
+	if err != nil {
+		return nil, fmt.Errorf("agentca: sign certificate: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// IsRevoked reports whether serial has been revoked.
+func (c *AgentCA) IsRevoked(serial *big.Int) bool {
+	return c.revocations.isRevoked(serial.String())
+}
+
+// Revoke marks a certificate serial as revoked.
+func (c *AgentCA) Revoke(serial *big.Int, reason string) error {
+	return c.revocations.revoke(serial.String(), reason)
+}
+
+// GenerateCRL returns a PEM-encoded X.509 certificate revocation list
+// covering every serial revoked so far.
+func (c *AgentCA) GenerateCRL() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	entries := c.revocations.entries()
+	revoked := make([]pkix.RevokedCertificate, 0, len(entries))
+	for _, entry := range entries {
+		serial, ok := new(big.Int).SetString(entry.Serial, 10)
+		if !ok {
+			continue
+		}
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: entry.RevokedAt,
+		})
+	}
+
+	template := &x509.RevocationList{
+		Number:              big.NewInt(now.UnixNano()),
+		ThisUpdate:          now,
+		NextUpdate:          now.Add(24 * time.Hour),
+		RevokedCertificates: revoked,
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, template, c.cert, c.signer)
+	if err != nil {
+		return nil, fmt.Errorf("agentca: generate crl: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), nil
+}
+
+// Rotate generates a fresh CA keypair, archiving the previous key and
+// certificate alongside it (suffixed with the rotation timestamp) so
+// certificates issued by the old CA can still be validated against it
+// directly if needed, and atomically swaps in the new CA for signing.
+// Existing agent certificates issued by the old CA only verify against the
+// old CA cert, so a rotation should be followed by re-enrolling agents (see
+// /agents/reenroll) before the old CA cert is removed from circulation.
+func (c *AgentCA) Rotate() (*x509.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	archiveSuffix := "." + c.now().Format("20060102T150405Z")
+	if err := archiveFile(c.keyPath, c.keyPath+archiveSuffix); err != nil {
+		return nil, err
+	}
+	if err := archiveFile(c.keyPath+".crt", c.keyPath+".crt"+archiveSuffix); err != nil {
+		return nil, err
+	}
+
+	signer, cert, err := generateCAKey(c.keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.signer = signer
+	c.cert = cert
+	c.serial = 0
+	return cert, nil
+}
+
+// archiveFile copies the file at path to archivePath, doing nothing if path
+// doesn't exist yet (the very first Rotate call on a CA with no prior key).
+func archiveFile(path, archivePath string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("agentca: read %s for archive: %w", path, err)
+	}
+	return os.WriteFile(archivePath, data, 0600)
+}
+
+func loadOrCreateCAKey(path string) (crypto.Signer, *x509.Certificate, error) {
+	keyData, err := os.ReadFile(path)
+	if err == nil {
+		certData, err := os.ReadFile(path + ".crt")
+		if err != nil {
+			return nil, nil, fmt.Errorf("agentca: read CA certificate: %w", err)
+		}
+		return parseCAKeyAndCert(keyData, certData)
+	}
+	if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	return generateCAKey(path)
+}
+
+// generateCAKey creates a brand new, self-signed CA keypair and persists it
+// at path/path.crt, overwriting whatever was there before. Used both by
+// loadOrCreateCAKey (first run) and Rotate (replacing an existing CA).
+func generateCAKey(path string) (crypto.Signer, *x509.Certificate, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Plasma Shield Agent CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, publicKey, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	if err := os.WriteFile(path, keyPEM, 0600); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(path+".crt", certPEM, 0644); err != nil {
+		return nil, nil, err
+	}
+
+	return parseCAKeyAndCert(keyPEM, certPEM)
+}
+
+func parseCAKeyAndCert(keyPEM, certPEM []byte) (crypto.Signer, *x509.Certificate, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, errors.New("agentca: invalid CA key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("agentca: parse CA key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("agentca: CA key is not a signer")
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, errors.New("agentca: invalid CA certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("agentca: parse CA certificate: %w", err)
+	}
+
+	return signer, cert, nil
+}
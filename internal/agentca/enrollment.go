@@ -0,0 +1,153 @@
+package agentca
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrSecretNotFound is returned by Consume when secret doesn't match a
+// known, unused, unexpired enrollment secret.
+var ErrSecretNotFound = errors.New("agentca: enrollment secret not found, expired, or already used")
+
+// EnrollmentSecret is a one-time secret that authorizes an agent to enroll
+// for a client certificate bound to a specific identity.
+type EnrollmentSecret struct {
+	Secret    string    `json:"secret"`
+	TenantID  string    `json:"tenant_id"`
+	AgentID   string    `json:"agent_id"`
+	Tier      string    `json:"tier"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Used      bool      `json:"used"`
+}
+
+func (s EnrollmentSecret) expired(now time.Time) bool {
+	return now.After(s.ExpiresAt)
+}
+
+// EnrollmentStore stores one-time enrollment secrets in memory with
+// optional JSON file persistence, mirroring bastion.GrantStore.
+type EnrollmentStore struct {
+	mu       sync.Mutex
+	secrets  map[string]*EnrollmentSecret
+	filePath string
+	now      func() time.Time
+}
+
+// NewEnrollmentStore creates an EnrollmentStore with optional file
+// persistence. If filePath is empty, secrets are only stored in memory.
+func NewEnrollmentStore(filePath string) *EnrollmentStore {
+	return NewEnrollmentStoreWithClock(filePath, func() time.Time { return time.Now().UTC() })
+}
+
+// NewEnrollmentStoreWithClock creates an EnrollmentStore with a custom
+// clock (for testing).
+func NewEnrollmentStoreWithClock(filePath string, now func() time.Time) *EnrollmentStore {
+	if now == nil {
+		panic("agentca: nil clock")
+	}
+	s := &EnrollmentStore{
+		secrets:  make(map[string]*EnrollmentSecret),
+		filePath: filePath,
+		now:      now,
+	}
+	if filePath != "" {
+		s.load()
+	}
+	return s
+}
+
+// Create mints a new one-time enrollment secret bound to an identity.
+func (s *EnrollmentStore) Create(tenantID, agentID, tier string, ttl time.Duration) (*EnrollmentSecret, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &EnrollmentSecret{
+		Secret:    secret,
+		TenantID:  tenantID,
+		AgentID:   agentID,
+		Tier:      tier,
+		ExpiresAt: s.now().Add(ttl),
+	}
+	s.secrets[secret] = entry
+	if err := s.persist(); err != nil {
+		delete(s.secrets, secret)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Consume looks up secret and, if it's valid and unused, marks it used and
+// returns it. Callers must treat the returned secret as single-use proof
+// of identity for exactly one enrollment.
+func (s *EnrollmentStore) Consume(secret string) (*EnrollmentSecret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.secrets[secret]
+	if !ok || entry.Used || entry.expired(s.now()) {
+		return nil, ErrSecretNotFound
+	}
+
+	entry.Used = true
+	if err := s.persist(); err != nil {
+		entry.Used = false
+		return nil, err
+	}
+	return entry, nil
+}
+
+// persist writes the store to disk. Caller must hold s.mu.
+func (s *EnrollmentStore) persist() error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal enrollment store: %w", err)
+	}
+
+	if dir := filepath.Dir(s.filePath); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("create enrollment store dir: %w", err)
+		}
+	}
+
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write enrollment store: %w", err)
+	}
+	return os.Rename(tmp, s.filePath)
+}
+
+func (s *EnrollmentStore) load() {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return
+	}
+	var secrets map[string]*EnrollmentSecret
+	if json.Unmarshal(data, &secrets) == nil {
+		s.secrets = secrets
+	}
+}
+
+// randomSecret returns a cryptographically random 32-byte hex string.
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
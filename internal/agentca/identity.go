@@ -0,0 +1,54 @@
+package agentca
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const uriScheme = "spiffe"
+const uriHost = "plasma"
+
+// Identity is the agent identity encoded in a client certificate's SPIFFE-
+// style SAN URI, e.g. spiffe://plasma/tenant/{tenant_id}/agent/{agent_id}/tier/{tier}.
+type Identity struct {
+	TenantID string
+	AgentID  string
+	Tier     string
+}
+
+// sanURI builds the SAN URI a certificate for this identity should carry.
+func (id Identity) sanURI() string {
+	return fmt.Sprintf("%s://%s/tenant/%s/agent/%s/tier/%s", uriScheme, uriHost, id.TenantID, id.AgentID, id.Tier)
+}
+
+// IdentityFromCertificate extracts the Identity encoded in cert's SAN URIs.
+// Certificates issued by IssueCertificate always carry exactly one such
+// URI; this rejects certificates that don't.
+func IdentityFromCertificate(cert *x509.Certificate) (*Identity, error) {
+	for _, rawURI := range cert.URIs {
+		id, err := parseSANURI(rawURI.String())
+		if err == nil {
+			return id, nil
+		}
+	}
+	return nil, fmt.Errorf("agentca: no spiffe://%s identity URI in certificate", uriHost)
+}
+
+func parseSANURI(raw string) (*Identity, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("agentca: parse SAN URI: %w", err)
+	}
+	if u.Scheme != uriScheme || u.Host != uriHost {
+		return nil, fmt.Errorf("agentca: unrecognized SAN URI %q", raw)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 6 || parts[0] != "tenant" || parts[2] != "agent" || parts[4] != "tier" {
+		return nil, fmt.Errorf("agentca: malformed identity URI %q", raw)
+	}
+
+	return &Identity{TenantID: parts[1], AgentID: parts[3], Tier: parts[5]}, nil
+}
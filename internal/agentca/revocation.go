@@ -0,0 +1,100 @@
+package agentca
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// revokedEntry is a single revoked certificate serial, persisted to disk.
+type revokedEntry struct {
+	Serial    string    `json:"serial"` // big.Int.String()
+	Reason    string    `json:"reason,omitempty"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// revocationStore tracks revoked certificate serials in memory, with
+// optional JSON file persistence (atomic write-then-rename, mirroring
+// bastion.GrantStore).
+type revocationStore struct {
+	mu       sync.RWMutex
+	serials  map[string]revokedEntry
+	filePath string
+	now      func() time.Time
+}
+
+func newRevocationStore(filePath string, now func() time.Time) *revocationStore {
+	s := &revocationStore{
+		serials:  make(map[string]revokedEntry),
+		filePath: filePath,
+		now:      now,
+	}
+	if filePath != "" {
+		s.load()
+	}
+	return s
+}
+
+func (s *revocationStore) revoke(serial, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.serials[serial] = revokedEntry{Serial: serial, Reason: reason, RevokedAt: s.now()}
+	return s.persist()
+}
+
+func (s *revocationStore) isRevoked(serial string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, revoked := s.serials[serial]
+	return revoked
+}
+
+func (s *revocationStore) entries() []revokedEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]revokedEntry, 0, len(s.serials))
+	for _, entry := range s.serials {
+		out = append(out, entry)
+	}
+	return out
+}
+
+// persist writes the store to disk. Caller must hold s.mu.
+func (s *revocationStore) persist() error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.serials, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(s.filePath); dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	tmp := s.filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filePath)
+}
+
+func (s *revocationStore) load() {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return
+	}
+	var serials map[string]revokedEntry
+	if json.Unmarshal(data, &serials) == nil {
+		s.serials = serials
+	}
+}
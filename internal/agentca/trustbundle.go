@@ -0,0 +1,48 @@
+package agentca
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+)
+
+// TrustBundleKey describes the CA's signing key in a JWKS-like shape, so
+// external verifiers (other services accepting agent mTLS connections) can
+// validate agent certificates without depending on this package.
+type TrustBundleKey struct {
+	Kty string   `json:"kty"`           // "OKP" (Octet Key Pair, per RFC 8037)
+	Crv string   `json:"crv"`           // "Ed25519"
+	X   string   `json:"x"`             // base64url-encoded raw public key
+	Use string   `json:"use"`           // "x509-svid"
+	X5c []string `json:"x5c,omitempty"` // base64-encoded DER certificate chain
+}
+
+// TrustBundle is the SPIFFE-style trust bundle served at GET
+// /agents/trust-bundle: the CA's current public key and certificate,
+// re-fetched by external verifiers after each Rotate.
+type TrustBundle struct {
+	Keys []TrustBundleKey `json:"keys"`
+}
+
+// TrustBundle returns the current CA trust bundle.
+func (c *AgentCA) TrustBundle() (*TrustBundle, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	pub, ok := c.cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("agentca: CA public key is not ed25519")
+	}
+
+	return &TrustBundle{
+		Keys: []TrustBundleKey{
+			{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+				Use: "x509-svid",
+				X5c: []string{base64.StdEncoding.EncodeToString(c.cert.Raw)},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,755 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
+)
+
+// maxAuditEntries bounds the in-memory audit log scrollback, mirroring
+// Store.logs. When a persistent storage.Store is wired up, the full,
+// untruncated history lives there instead; GET /admin/audit/verify
+// verifies against persist when available.
+const maxAuditEntries = 10000
+
+// ListTenantsHandler handles GET /admin/tenants.
+func (h *Handlers) ListTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+
+	tenants := make([]Tenant, 0, len(h.store.tenants))
+	for _, t := range h.store.tenants {
+		if expr != nil && !expr.Match(*t) {
+			continue
+		}
+		tenants = append(tenants, *t)
+	}
+
+	writeJSON(w, http.StatusOK, TenantListResponse{Tenants: tenants, Total: len(tenants)})
+}
+
+// CreateTenantHandler handles POST /admin/tenants.
+func (h *Handlers) CreateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ID == "" {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "isolated"
+	}
+	if req.Mode != "isolated" && req.Mode != "fleet" {
+		writeError(w, http.StatusBadRequest, "mode must be 'isolated' or 'fleet'")
+		return
+	}
+
+	h.store.mu.Lock()
+	if _, exists := h.store.tenants[req.ID]; exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusConflict, "tenant already exists")
+		return
+	}
+
+	now := time.Now().UTC()
+	tenant := &Tenant{ID: req.ID, Mode: req.Mode, CreatedAt: now, UpdatedAt: now}
+	h.store.tenants[req.ID] = tenant
+	h.persistTenantLocked(req.ID)
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "create", "tenant", tenant.ID, nil, tenant)
+	writeJSON(w, http.StatusCreated, *tenant)
+}
+
+// TenantHandler handles PATCH and DELETE /admin/tenants/{id}.
+func (h *Handlers) TenantHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/tenants/")
+	if id == "" || id == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing tenant ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		h.updateTenant(w, r, id)
+	case http.MethodDelete:
+		h.deleteTenant(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handlers) updateTenant(w http.ResponseWriter, r *http.Request, id string) {
+	var req UpdateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Mode != "isolated" && req.Mode != "fleet" {
+		writeError(w, http.StatusBadRequest, "mode must be 'isolated' or 'fleet'")
+		return
+	}
+
+	h.store.mu.Lock()
+	tenant, exists := h.store.tenants[id]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	before := *tenant
+	tenant.Mode = req.Mode
+	tenant.UpdatedAt = time.Now().UTC()
+	h.persistTenantLocked(id)
+	after := *tenant
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "update", "tenant", id, before, after)
+	writeJSON(w, http.StatusOK, after)
+}
+
+func (h *Handlers) deleteTenant(w http.ResponseWriter, r *http.Request, id string) {
+	h.store.mu.Lock()
+	tenant, exists := h.store.tenants[id]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "tenant not found")
+		return
+	}
+	before := *tenant
+	delete(h.store.tenants, id)
+	if h.store.persist != nil {
+		if err := h.store.persist.DeleteTenant(id); err != nil {
+			log.Printf("storage: delete tenant %q: %v", id, err)
+		}
+	}
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "delete", "tenant", id, before, nil)
+	writeJSON(w, http.StatusOK, DeleteResponse{ID: id, Message: "tenant deleted successfully"})
+}
+
+// CreateAgentHandler handles POST /admin/agents. Unlike RegisterAgent (used
+// at startup for the demo agent), this is reachable over the admin API and
+// every call is audited.
+func (h *Handlers) CreateAgentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateAgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.ID == "" {
+		writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	h.store.mu.Lock()
+	if _, exists := h.store.agents[req.ID]; exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusConflict, "agent already exists")
+		return
+	}
+
+	now := time.Now()
+	agent := &Agent{ID: req.ID, Name: req.Name, IP: req.IP, Status: "active", LastSeen: now, CreatedAt: now}
+	h.store.agents[req.ID] = agent
+	h.persistAgentLocked(req.ID)
+	after := *agent
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "create", "agent", after.ID, nil, after)
+	writeJSON(w, http.StatusCreated, after)
+}
+
+// AgentAdminHandler handles PATCH and DELETE /admin/agents/{id}.
+func (h *Handlers) AgentAdminHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/agents/")
+	if id == "" || id == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing agent ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		h.updateAgent(w, r, id)
+	case http.MethodDelete:
+		h.deleteAgentAdmin(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handlers) updateAgent(w http.ResponseWriter, r *http.Request, id string) {
+	var req UpdateAgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	h.store.mu.Lock()
+	agent, exists := h.store.agents[id]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "agent not found")
+		return
+	}
+	before := *agent
+	if req.Name != "" {
+		agent.Name = req.Name
+	}
+	if req.IP != "" {
+		agent.IP = req.IP
+	}
+	h.persistAgentLocked(id)
+	after := *agent
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "update", "agent", id, before, after)
+	writeJSON(w, http.StatusOK, after)
+}
+
+func (h *Handlers) deleteAgentAdmin(w http.ResponseWriter, r *http.Request, id string) {
+	h.store.mu.Lock()
+	agent, exists := h.store.agents[id]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "agent not found")
+		return
+	}
+	before := *agent
+	delete(h.store.agents, id)
+	if h.store.persist != nil {
+		if err := h.store.persist.DeleteAgent(id); err != nil {
+			log.Printf("storage: delete agent %q: %v", id, err)
+		}
+	}
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "delete", "agent", id, before, nil)
+	writeJSON(w, http.StatusOK, DeleteResponse{ID: id, Message: "agent deleted successfully"})
+}
+
+// CreateRuleAdminHandler handles POST /admin/rules. It behaves like
+// CreateRuleHandler but also records an audit entry.
+func (h *Handlers) CreateRuleAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Action != "block" && req.Action != "allow" {
+		writeError(w, http.StatusBadRequest, "action must be 'block' or 'allow'")
+		return
+	}
+	if req.Pattern == "" && req.Domain == "" {
+		writeError(w, http.StatusBadRequest, "pattern or domain is required")
+		return
+	}
+
+	h.store.mu.Lock()
+	id := generateID()
+	rule := &Rule{
+		ID:          id,
+		Pattern:     req.Pattern,
+		Domain:      req.Domain,
+		Action:      req.Action,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+		CreatedAt:   time.Now(),
+	}
+	h.store.rules[id] = rule
+	h.persistRuleLocked(id)
+	after := *rule
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "create", "rule", id, nil, after)
+	writeJSON(w, http.StatusCreated, after)
+}
+
+// RuleAdminHandler handles PUT and DELETE /admin/rules/{id}.
+func (h *Handlers) RuleAdminHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/rules/")
+	if id == "" || id == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing rule ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		h.replaceRule(w, r, id)
+	case http.MethodDelete:
+		h.deleteRuleAdmin(w, r, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handlers) replaceRule(w http.ResponseWriter, r *http.Request, id string) {
+	var req UpdateRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Action != "block" && req.Action != "allow" {
+		writeError(w, http.StatusBadRequest, "action must be 'block' or 'allow'")
+		return
+	}
+	if req.Pattern == "" && req.Domain == "" {
+		writeError(w, http.StatusBadRequest, "pattern or domain is required")
+		return
+	}
+
+	h.store.mu.Lock()
+	rule, exists := h.store.rules[id]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	before := *rule
+	rule.Pattern = req.Pattern
+	rule.Domain = req.Domain
+	rule.Action = req.Action
+	rule.Description = req.Description
+	rule.Enabled = req.Enabled
+	h.persistRuleLocked(id)
+	after := *rule
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "update", "rule", id, before, after)
+	writeJSON(w, http.StatusOK, after)
+}
+
+func (h *Handlers) deleteRuleAdmin(w http.ResponseWriter, r *http.Request, id string) {
+	h.store.mu.Lock()
+	rule, exists := h.store.rules[id]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "rule not found")
+		return
+	}
+	before := *rule
+	delete(h.store.rules, id)
+	if h.store.persist != nil {
+		if err := h.store.persist.DeleteRule(id); err != nil {
+			log.Printf("storage: delete rule %q: %v", id, err)
+		}
+	}
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "delete", "rule", id, before, nil)
+	writeJSON(w, http.StatusOK, DeleteResponse{ID: id, Message: "rule deleted successfully"})
+}
+
+// ListTokensHandler handles GET /admin/tokens.
+func (h *Handlers) ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+
+	tokens := make([]Token, 0, len(h.store.tokens))
+	for _, t := range h.store.tokens {
+		tokens = append(tokens, *t)
+	}
+
+	writeJSON(w, http.StatusOK, TokenListResponse{Tokens: tokens, Total: len(tokens)})
+}
+
+// CreateTokenHandler handles POST /admin/tokens.
+func (h *Handlers) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.TenantID == "" {
+		writeError(w, http.StatusBadRequest, "tenant_id is required")
+		return
+	}
+
+	tokenValue := req.Token
+	if tokenValue == "" {
+		generated, err := randomToken()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "generate token: "+err.Error())
+			return
+		}
+		tokenValue = generated
+	}
+
+	h.store.mu.Lock()
+	if _, exists := h.store.tokens[tokenValue]; exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusConflict, "token already exists")
+		return
+	}
+
+	token := &Token{Token: tokenValue, TenantID: req.TenantID, Name: req.Name, CreatedAt: time.Now().UTC()}
+	h.store.tokens[tokenValue] = token
+	h.persistTokenLocked(tokenValue)
+	after := *token
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "create", "token", redactToken(tokenValue), nil, after)
+	writeJSON(w, http.StatusCreated, after)
+}
+
+// TokenHandler handles DELETE /admin/tokens/{token}.
+func (h *Handlers) TokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	tokenValue := strings.TrimPrefix(r.URL.Path, "/admin/tokens/")
+	if tokenValue == "" || tokenValue == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing token")
+		return
+	}
+
+	h.store.mu.Lock()
+	token, exists := h.store.tokens[tokenValue]
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	before := *token
+	delete(h.store.tokens, tokenValue)
+	if h.store.persist != nil {
+		if err := h.store.persist.DeleteToken(tokenValue); err != nil {
+			log.Printf("storage: delete token %q: %v", redactToken(tokenValue), err)
+		}
+	}
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "delete", "token", redactToken(tokenValue), before, nil)
+	writeJSON(w, http.StatusOK, DeleteResponse{ID: redactToken(tokenValue), Message: "token deleted successfully"})
+}
+
+// AuditHandler handles GET /admin/audit. Entries are returned most-recent
+// first; pass ?limit= to page back through the full append-only history.
+func (h *Handlers) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	entries, err := h.auditEntries(limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list audit entries: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AuditListResponse{Entries: entries, Total: len(entries)})
+}
+
+// AuditVerifyHandler handles GET /admin/audit/verify. It recomputes the hash
+// chain over the full audit history and reports the first entry (by
+// most-recent-first index) whose hash doesn't match.
+func (h *Handlers) AuditVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	entries, err := h.auditEntries(0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "list audit entries: "+err.Error())
+		return
+	}
+
+	// entries is most-recent-first; walk oldest-first to verify the chain
+	// in the order it was built.
+	brokenAt := -1
+	prevHash := ""
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.PrevHash != prevHash || e.Hash != hashAuditEntry(e) {
+			brokenAt = i
+			break
+		}
+		prevHash = e.Hash
+	}
+
+	if brokenAt >= 0 {
+		writeJSON(w, http.StatusOK, AuditVerifyResponse{
+			Valid:      false,
+			EntryCount: len(entries),
+			BrokenAt:   brokenAt,
+			Message:    fmt.Sprintf("chain broken at entry index %d (%s)", brokenAt, entries[brokenAt].ID),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AuditVerifyResponse{
+		Valid:      true,
+		EntryCount: len(entries),
+		BrokenAt:   -1,
+		Message:    "chain intact",
+	})
+}
+
+// auditEntries returns up to limit audit entries, most recent first,
+// preferring persistent storage (the full, untruncated history) over the
+// bounded in-memory scrollback. limit <= 0 means no limit.
+func (h *Handlers) auditEntries(limit int) ([]AuditEntry, error) {
+	h.store.auditMu.Lock()
+	persist := h.store.persist
+	h.store.auditMu.Unlock()
+
+	if persist != nil {
+		stored, err := persist.ListAudit(limit)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]AuditEntry, 0, len(stored))
+		for _, e := range stored {
+			out = append(out, auditEntryFromStorage(e))
+		}
+		return out, nil
+	}
+
+	h.store.auditMu.Lock()
+	defer h.store.auditMu.Unlock()
+	n := len(h.store.auditLog)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	out := make([]AuditEntry, 0, n)
+	for i := len(h.store.auditLog) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, h.store.auditLog[i])
+	}
+	return out, nil
+}
+
+// recordAudit appends an entry to the hash-chained audit log, serializing
+// before/after to canonical JSON. before and after may be nil.
+func (h *Handlers) recordAudit(actor, action, resourceType, resourceID string, before, after interface{}) {
+	beforeJSON, err := marshalAuditValue(before)
+	if err != nil {
+		log.Printf("storage: marshal audit before value: %v", err)
+	}
+	afterJSON, err := marshalAuditValue(after)
+	if err != nil {
+		log.Printf("storage: marshal audit after value: %v", err)
+	}
+
+	h.store.auditMu.Lock()
+	defer h.store.auditMu.Unlock()
+
+	entry := AuditEntry{
+		ID:           generateID(),
+		Timestamp:    time.Now().UTC(),
+		Actor:        actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Before:       beforeJSON,
+		After:        afterJSON,
+		PrevHash:     h.store.auditLastHash,
+	}
+	entry.Hash = hashAuditEntry(entry)
+
+	h.store.auditLastHash = entry.Hash
+	h.store.auditLog = append(h.store.auditLog, entry)
+	if len(h.store.auditLog) > maxAuditEntries {
+		h.store.auditLog = h.store.auditLog[len(h.store.auditLog)-maxAuditEntries:]
+	}
+
+	if h.store.persist != nil {
+		if err := h.store.persist.AppendAudit(storage.AuditEntry{
+			ID:           entry.ID,
+			Timestamp:    entry.Timestamp,
+			Actor:        entry.Actor,
+			Action:       entry.Action,
+			ResourceType: entry.ResourceType,
+			ResourceID:   entry.ResourceID,
+			Before:       string(entry.Before),
+			After:        string(entry.After),
+			PrevHash:     entry.PrevHash,
+			Hash:         entry.Hash,
+		}); err != nil {
+			log.Printf("storage: append audit entry: %v", err)
+		}
+	}
+}
+
+// marshalAuditValue returns nil (not "null") for a nil value, so
+// AuditEntry.Before/After is omitted in the JSON response for creates and
+// deletes rather than rendering a literal null.
+func marshalAuditValue(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// hashAuditEntry computes entry's chain hash as
+// sha256(prev_hash || canonical_json(entry minus hash)), matching the
+// documented audit log format.
+func hashAuditEntry(e AuditEntry) string {
+	canonical, _ := json.Marshal(struct {
+		Timestamp    time.Time       `json:"timestamp"`
+		Actor        string          `json:"actor"`
+		Action       string          `json:"action"`
+		ResourceType string          `json:"resource_type"`
+		ResourceID   string          `json:"resource_id"`
+		Before       json.RawMessage `json:"before,omitempty"`
+		After        json.RawMessage `json:"after,omitempty"`
+		PrevHash     string          `json:"prev_hash"`
+	}{e.Timestamp, e.Actor, e.Action, e.ResourceType, e.ResourceID, e.Before, e.After, e.PrevHash})
+
+	sum := sha256.Sum256(append([]byte(e.PrevHash), canonical...))
+	return hex.EncodeToString(sum[:])
+}
+
+func auditEntryFromStorage(e storage.AuditEntry) AuditEntry {
+	return AuditEntry{
+		ID:           e.ID,
+		Timestamp:    e.Timestamp,
+		Actor:        e.Actor,
+		Action:       e.Action,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Before:       json.RawMessage(e.Before),
+		After:        json.RawMessage(e.After),
+		PrevHash:     e.PrevHash,
+		Hash:         e.Hash,
+	}
+}
+
+// persistTenantLocked mirrors the in-memory tenant identified by id to
+// store.persist, if configured. Caller must hold store.mu.
+func (h *Handlers) persistTenantLocked(id string) {
+	if h.store.persist == nil {
+		return
+	}
+	tenant, ok := h.store.tenants[id]
+	if !ok {
+		return
+	}
+	if err := h.store.persist.UpsertTenant(storage.Tenant{
+		ID:        tenant.ID,
+		Mode:      tenant.Mode,
+		CreatedAt: tenant.CreatedAt,
+		UpdatedAt: tenant.UpdatedAt,
+	}); err != nil {
+		log.Printf("storage: persist tenant %q: %v", id, err)
+	}
+}
+
+// persistRuleLocked mirrors the in-memory rule identified by id to
+// store.persist, if configured. Caller must hold store.mu.
+func (h *Handlers) persistRuleLocked(id string) {
+	if h.store.persist == nil {
+		return
+	}
+	rule, ok := h.store.rules[id]
+	if !ok {
+		return
+	}
+	if err := h.store.persist.UpsertRule(storage.Rule{
+		ID:          rule.ID,
+		Pattern:     rule.Pattern,
+		Domain:      rule.Domain,
+		Action:      rule.Action,
+		Description: rule.Description,
+		Enabled:     rule.Enabled,
+		CreatedAt:   rule.CreatedAt,
+	}); err != nil {
+		log.Printf("storage: persist rule %q: %v", id, err)
+	}
+}
+
+// persistTokenLocked mirrors the in-memory token identified by value to
+// store.persist, if configured. Caller must hold store.mu.
+func (h *Handlers) persistTokenLocked(value string) {
+	if h.store.persist == nil {
+		return
+	}
+	token, ok := h.store.tokens[value]
+	if !ok {
+		return
+	}
+	if err := h.store.persist.UpsertToken(storage.Token{
+		Token:     token.Token,
+		TenantID:  token.TenantID,
+		Name:      token.Name,
+		CreatedAt: token.CreatedAt,
+	}); err != nil {
+		log.Printf("storage: persist token %q: %v", redactToken(value), err)
+	}
+}
+
+// randomToken returns a cryptographically random 32-byte hex string,
+// mirroring agentca's enrollment secret generation.
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// redactToken returns a token value safe to log or record as an audit
+// resource ID: its first 8 hex characters, rather than the live credential.
+func redactToken(token string) string {
+	if len(token) <= 8 {
+		return token
+	}
+	return token[:8] + "..."
+}
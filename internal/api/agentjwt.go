@@ -0,0 +1,285 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultAgentTokenTTL bounds how long an agent session JWT from
+// EnrollAgentTokenHandler is valid before the agent must re-enroll.
+// Shorter than defaultAgentCertTTL since a session token, unlike a
+// certificate, has no CRL to revoke it early outside of
+// RevokeAgentTokenHandler bumping the agent's generation.
+const defaultAgentTokenTTL = time.Hour
+
+// AgentJWTKey signs and verifies agent session JWTs, wired onto Handlers
+// via SetAgentJWTKey. Exactly one of HMACSecret or Ed25519Key should be
+// set; HMACSecret takes precedence if both are.
+type AgentJWTKey struct {
+	HMACSecret []byte
+	Ed25519Key ed25519.PrivateKey
+}
+
+// agentTokenClaims is the JSON payload of an agent session JWT: the agent
+// id it's bound to, issued-at/expiry, and the token generation it was
+// minted under, so RevokeAgentTokenHandler can kill every outstanding
+// token for an agent by bumping Store.agentTokenGen past it.
+type agentTokenClaims struct {
+	Sub string `json:"sub"`
+	Iat int64  `json:"iat"`
+	Exp int64  `json:"exp"`
+	Gen int    `json:"gen"`
+}
+
+// agentJWTHeader is the JOSE header of a signed agent token.
+type agentJWTHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// signAgentToken encodes claims as a compact JWT (header.payload.signature,
+// each base64url-encoded) signed with key, HS256 if key.HMACSecret is set,
+// EdDSA otherwise.
+func signAgentToken(key AgentJWTKey, claims agentTokenClaims) (string, error) {
+	alg := "EdDSA"
+	if len(key.HMACSecret) > 0 {
+		alg = "HS256"
+	}
+
+	headerJSON, err := json.Marshal(agentJWTHeader{Alg: alg, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.HMACSecret)
+		mac.Write([]byte(signingInput))
+		sig = mac.Sum(nil)
+	case "EdDSA":
+		if len(key.Ed25519Key) == 0 {
+			return "", errors.New("agentjwt: no signing key configured")
+		}
+		sig = ed25519.Sign(key.Ed25519Key, []byte(signingInput))
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyAgentToken checks a compact JWT's signature against key and
+// decodes its claims, additionally rejecting an expired token. It does
+// not check the token's subject or generation against a Store -- that's
+// ExecCheckHandler's job, since only it knows the AgentID a request
+// claims and the agent's current generation.
+func verifyAgentToken(key AgentJWTKey, token string) (agentTokenClaims, error) {
+	var claims agentTokenClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("agentjwt: malformed token")
+	}
+	signingInput := parts[0] + "." + parts[1]
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, fmt.Errorf("agentjwt: decode header: %w", err)
+	}
+	var header agentJWTHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return claims, fmt.Errorf("agentjwt: parse header: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, fmt.Errorf("agentjwt: decode signature: %w", err)
+	}
+
+	switch header.Alg {
+	case "HS256":
+		if len(key.HMACSecret) == 0 {
+			return claims, errors.New("agentjwt: token is HS256 but no HMAC secret is configured")
+		}
+		mac := hmac.New(sha256.New, key.HMACSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return claims, errors.New("agentjwt: signature mismatch")
+		}
+	case "EdDSA":
+		if len(key.Ed25519Key) == 0 {
+			return claims, errors.New("agentjwt: token is EdDSA but no Ed25519 key is configured")
+		}
+		if !ed25519.Verify(key.Ed25519Key.Public().(ed25519.PublicKey), []byte(signingInput), sig) {
+			return claims, errors.New("agentjwt: signature mismatch")
+		}
+	default:
+		return claims, fmt.Errorf("agentjwt: unsupported alg %q", header.Alg)
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, fmt.Errorf("agentjwt: decode claims: %w", err)
+	}
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return claims, fmt.Errorf("agentjwt: parse claims: %w", err)
+	}
+
+	if time.Now().Unix() >= claims.Exp {
+		return claims, errors.New("agentjwt: token expired")
+	}
+	return claims, nil
+}
+
+// SetAgentJWTKey wires the signing key EnrollAgentTokenHandler and
+// ExecCheckHandler use to issue and verify agent session JWTs. A nil key
+// disables the JWT flow entirely, leaving /exec/check authenticated only
+// by AgentAccess (mTLS or the static agent token).
+func (h *Handlers) SetAgentJWTKey(key *AgentJWTKey) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.agentJWTKey = key
+}
+
+// AgentJWTKey returns the signing key wired by SetAgentJWTKey, or nil if
+// the JWT flow isn't configured.
+func (h *Handlers) AgentJWTKey() *AgentJWTKey {
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+	return h.store.agentJWTKey
+}
+
+// checkAgentToken validates the Authorization bearer token on an
+// /exec/check request against key and agentID: the token must parse and
+// verify, be unexpired, carry a subject matching agentID, and carry a
+// generation at least as new as the agent's current one (bumped by
+// RevokeAgentTokenHandler). It returns a human-readable reason alongside
+// ok=false for writeError to surface as a 401.
+func (h *Handlers) checkAgentToken(key AgentJWTKey, r *http.Request, agentID string) (reason string, ok bool) {
+	token := extractBearerToken(r)
+	if token == "" {
+		return "missing authorization token", false
+	}
+
+	claims, err := verifyAgentToken(key, token)
+	if err != nil {
+		return err.Error(), false
+	}
+	if claims.Sub != agentID {
+		return "token subject does not match agent_id", false
+	}
+
+	h.store.mu.RLock()
+	gen := h.store.agentTokenGen[claims.Sub]
+	h.store.mu.RUnlock()
+	if claims.Gen < gen {
+		return "agent token has been revoked", false
+	}
+
+	return "", true
+}
+
+// EnrollAgentTokenHandler handles POST /agents/enroll-token. It's the JWT
+// counterpart to EnrollAgentHandler: an agent redeems the same kind of
+// one-time enrollment secret, but for a signed session token instead of a
+// client certificate -- for deployments with an agentJWTKey configured but
+// no agentca.AgentCA (mTLS is more setup than some deployments want).
+func (h *Handlers) EnrollAgentTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	key := h.store.agentJWTKey
+	enrollments := h.store.enrollments
+	h.store.mu.RUnlock()
+
+	if key == nil || enrollments == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent token enrollment not configured")
+		return
+	}
+
+	var req EnrollAgentTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Secret == "" {
+		writeError(w, http.StatusBadRequest, "secret is required")
+		return
+	}
+
+	secret, err := enrollments.Consume(req.Secret)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	h.store.mu.RLock()
+	gen := h.store.agentTokenGen[secret.AgentID]
+	h.store.mu.RUnlock()
+
+	now := time.Now().UTC()
+	claims := agentTokenClaims{
+		Sub: secret.AgentID,
+		Iat: now.Unix(),
+		Exp: now.Add(defaultAgentTokenTTL).Unix(),
+		Gen: gen,
+	}
+	token, err := signAgentToken(*key, claims)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "sign token: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, EnrollAgentTokenResponse{
+		Token:     token,
+		ExpiresAt: time.Unix(claims.Exp, 0).UTC(),
+	})
+}
+
+// RevokeAgentTokenHandler handles POST /agents/{id}/revoke. It bumps the
+// agent's token generation, which ExecCheckHandler compares against the
+// Gen claim of every presented token -- so every session token issued
+// before this call, no matter its expiry, is rejected from now on.
+func (h *Handlers) RevokeAgentTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	agentID := extractAgentID(r.URL.Path, "/agents/", "/revoke")
+	if agentID == "" {
+		writeError(w, http.StatusBadRequest, "missing agent ID")
+		return
+	}
+
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	if _, exists := h.store.agents[agentID]; !exists {
+		writeError(w, http.StatusNotFound, "agent not found")
+		return
+	}
+
+	h.store.agentTokenGen[agentID]++
+
+	writeJSON(w, http.StatusOK, RevokeAgentTokenResponse{
+		AgentID:    agentID,
+		Generation: h.store.agentTokenGen[agentID],
+		Message:    "outstanding agent tokens revoked",
+	})
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultApprovalTimeout is how long awaitApproval waits for a human
+// decision on a "review" rule match before falling back to a deny -- see
+// SetApprovalTimeout.
+const defaultApprovalTimeout = 30 * time.Second
+
+// SetApprovalTimeout overrides how long a pending approval waits for a
+// decision before timing out. d <= 0 is ignored; NewStore already defaults
+// to defaultApprovalTimeout.
+func (s *Store) SetApprovalTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.approvalMu.Lock()
+	s.approvalTimeout = d
+	s.approvalMu.Unlock()
+}
+
+// createPendingApproval registers a new Approval for agentID/command/ruleID
+// and publishes it to every GET /approvals/stream subscriber. The caller
+// must not hold Store.mu: ExecCheckHandler calls this only after releasing
+// it, since the returned Approval is then awaited (for up to
+// Store.approvalTimeout) outside any lock.
+func (s *Store) createPendingApproval(agentID, command, ruleID string) *Approval {
+	approval := &Approval{
+		ID:          generateID(),
+		AgentID:     agentID,
+		Command:     command,
+		RuleID:      ruleID,
+		RequestedAt: time.Now(),
+		decisionCh:  make(chan approvalDecision, 1),
+	}
+
+	s.approvalMu.Lock()
+	s.pendingApprovals[approval.ID] = approval
+	s.approvalMu.Unlock()
+
+	s.publishApproval(*approval)
+	return approval
+}
+
+// awaitApproval blocks until approval is resolved via resolveApproval or
+// Store.approvalTimeout elapses, whichever comes first. A timeout resolves
+// as a deny -- the same conservative default a "block" rule would have
+// applied outright.
+func (s *Store) awaitApproval(approval *Approval) approvalDecision {
+	s.approvalMu.Lock()
+	timeout := s.approvalTimeout
+	s.approvalMu.Unlock()
+
+	select {
+	case decision := <-approval.decisionCh:
+		return decision
+	case <-time.After(timeout):
+		s.approvalMu.Lock()
+		if _, pending := s.pendingApprovals[approval.ID]; pending {
+			delete(s.pendingApprovals, approval.ID)
+			approval.Resolved = true
+			approval.Decision = "timeout"
+		}
+		s.approvalMu.Unlock()
+		return approvalDecision{allow: false, reason: "approval timed out"}
+	}
+}
+
+// resolveApproval records decision against the pending approval with id,
+// delivering it to the goroutine blocked in awaitApproval. Reports false
+// if no such pending approval exists (already resolved, timed out, or
+// never existed).
+func (s *Store) resolveApproval(id string, allow bool, reason, resolvedBy string) bool {
+	s.approvalMu.Lock()
+	approval, ok := s.pendingApprovals[id]
+	if !ok {
+		s.approvalMu.Unlock()
+		return false
+	}
+	delete(s.pendingApprovals, id)
+	approval.Resolved = true
+	approval.ResolvedBy = resolvedBy
+	approval.Reason = reason
+	if allow {
+		approval.Decision = "allow"
+	} else {
+		approval.Decision = "deny"
+	}
+	s.approvalMu.Unlock()
+
+	approval.decisionCh <- approvalDecision{allow: allow, reason: reason}
+	return true
+}
+
+// pendingApprovalsList returns a snapshot of every unresolved approval,
+// oldest first, for GET /approvals.
+func (s *Store) pendingApprovalsList() []Approval {
+	s.approvalMu.Lock()
+	defer s.approvalMu.Unlock()
+
+	out := make([]Approval, 0, len(s.pendingApprovals))
+	for _, a := range s.pendingApprovals {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RequestedAt.Before(out[j].RequestedAt) })
+	return out
+}
+
+// subscribeApprovals registers a new GET /approvals/stream subscriber,
+// returning a channel of newly created pending approvals and an
+// unsubscribe func that must be called (typically via defer) when the
+// client disconnects.
+func (s *Store) subscribeApprovals() (<-chan Approval, func()) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	id := s.nextSubscriberID
+	s.nextSubscriberID++
+	ch := make(chan Approval, subscriberBufferSize)
+	s.approvalSubscribers[id] = ch
+
+	return ch, func() {
+		s.streamMu.Lock()
+		defer s.streamMu.Unlock()
+		delete(s.approvalSubscribers, id)
+	}
+}
+
+// publishApproval fans a newly created pending approval out to every
+// GET /approvals/stream subscriber. A subscriber that isn't keeping up has
+// the event dropped rather than blocking the caller.
+func (s *Store) publishApproval(approval Approval) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	for _, ch := range s.approvalSubscribers {
+		select {
+		case ch <- approval:
+		default:
+		}
+	}
+}
+
+// ListApprovalsHandler handles GET /approvals: every pending approval
+// still awaiting a decision, oldest first.
+func (h *Handlers) ListApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	approvals := h.store.pendingApprovalsList()
+	writeJSON(w, http.StatusOK, ApprovalListResponse{Approvals: approvals, Total: len(approvals)})
+}
+
+// ResolveApprovalHandler handles POST /approvals/{id} (`approvals resolve`),
+// delivering an allow/deny decision to the ExecCheckHandler goroutine
+// blocked in awaitApproval for it.
+func (h *Handlers) ResolveApprovalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/approvals/")
+	if id == "" || id == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing approval id")
+		return
+	}
+
+	var req ResolveApprovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var allow bool
+	switch req.Decision {
+	case "allow":
+		allow = true
+	case "deny":
+		allow = false
+	default:
+		writeError(w, http.StatusBadRequest, `decision must be "allow" or "deny"`)
+		return
+	}
+
+	resolvedBy := actorFromContext(r.Context())
+	if !h.store.resolveApproval(id, allow, req.Reason, resolvedBy) {
+		writeError(w, http.StatusNotFound, "pending approval not found")
+		return
+	}
+
+	h.recordAudit(resolvedBy, "resolve", "approval", id, nil, req)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "id": id, "decision": req.Decision})
+}
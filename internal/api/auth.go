@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Auth validates an incoming request's credentials and reports the
+// principal it authenticated as. ManagementAuth and AgentAuth delegate to
+// one of these instead of comparing a single hard-coded bearer token, so a
+// deployment can swap in a multi-operator credential store without
+// touching the HTTP layer.
+type Auth interface {
+	Validate(r *http.Request) (principal string, ok bool)
+}
+
+// NewAuth parses paramstr's "scheme://rest" prefix and returns the matching
+// backend, mirroring storage.Open's DSN-scheme dispatch:
+//
+//   - a bare string, or "static://TOKEN": a single shared bearer token
+//     (the prior hard-coded-string behavior), principal "token:static"
+//   - "none://": accepts every request without checking credentials,
+//     principal "anonymous" -- for local/dev use; never point a
+//     deployment reachable off-box at it
+//   - "bcryptfile:///path/to/file": a file of "id:bcryptHash" lines, one
+//     per operator; the bearer token must be "id:secret" and is checked
+//     against id's hash with bcrypt.CompareHashAndPassword. The file is
+//     reloaded whenever its mtime changes, so operators can add or revoke
+//     a line without a restart.
+//
+// It returns an error naming the scheme for a typo or an unimplemented
+// scheme (e.g. "cert://") -- certificate identity already comes from
+// AgentCertAuth/AdminAuth's mTLS handling, which lives outside this
+// Authorization-header-based interface.
+func NewAuth(paramstr string) (Auth, error) {
+	scheme, rest := splitAuthScheme(paramstr)
+	switch scheme {
+	case "static":
+		if rest == "" {
+			return nil, fmt.Errorf("api: static auth requires a token")
+		}
+		return &staticAuth{token: rest}, nil
+	case "none":
+		return noneAuth{}, nil
+	case "bcryptfile":
+		return newBcryptFileAuth(rest)
+	default:
+		return nil, fmt.Errorf("api: unsupported auth scheme %q", scheme)
+	}
+}
+
+// splitAuthScheme splits paramstr into its scheme and the remainder. A
+// paramstr with no "scheme://" prefix is treated as a bare static token.
+func splitAuthScheme(paramstr string) (scheme, rest string) {
+	if i := strings.Index(paramstr, "://"); i >= 0 {
+		return paramstr[:i], paramstr[i+len("://"):]
+	}
+	return "static", paramstr
+}
+
+// staticAuth is the "static://" backend: a single shared bearer token.
+type staticAuth struct {
+	token string
+}
+
+func (a *staticAuth) Validate(r *http.Request) (string, bool) {
+	token := extractBearerToken(r)
+	if token == "" || token != a.token {
+		return "", false
+	}
+	return "token:static", true
+}
+
+// noneAuth is the "none://" backend.
+type noneAuth struct{}
+
+func (noneAuth) Validate(r *http.Request) (string, bool) {
+	return "anonymous", true
+}
+
+// bcryptFileAuth is the "bcryptfile://" backend: operator credentials
+// loaded from a flat file of "id:bcryptHash" lines, reloaded lazily
+// whenever the file's mtime changes.
+type bcryptFileAuth struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	hashes  map[string]string
+}
+
+func newBcryptFileAuth(path string) (*bcryptFileAuth, error) {
+	if path == "" {
+		return nil, fmt.Errorf("api: bcryptfile auth requires a file path")
+	}
+	a := &bcryptFileAuth{path: path}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// reload re-reads a.path if its mtime has changed since the last load,
+// swapping in the parsed hashes atomically. This mirrors acl.Resolver's
+// reload, checked lazily on each Validate call instead of a background
+// fsnotify watcher, since a credentials file is small and read rarely
+// compared to the cost of a stat per request.
+func (a *bcryptFileAuth) reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return fmt.Errorf("api: stat bcryptfile %s: %w", a.path, err)
+	}
+
+	a.mu.RLock()
+	unchanged := a.hashes != nil && info.ModTime().Equal(a.modTime)
+	a.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	f, err := os.Open(a.path)
+	if err != nil {
+		return fmt.Errorf("api: open bcryptfile %s: %w", a.path, err)
+	}
+	defer f.Close()
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, hash, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("api: bcryptfile %s: malformed line %q (want id:bcryptHash)", a.path, line)
+		}
+		hashes[id] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("api: read bcryptfile %s: %w", a.path, err)
+	}
+
+	a.mu.Lock()
+	a.hashes = hashes
+	a.modTime = info.ModTime()
+	a.mu.Unlock()
+	return nil
+}
+
+// Validate expects an "id:secret" bearer token, not the htpasswd-style line
+// itself, and checks secret against id's stored hash with
+// bcrypt.CompareHashAndPassword, which is constant-time over the
+// comparison by construction.
+func (a *bcryptFileAuth) Validate(r *http.Request) (string, bool) {
+	token := extractBearerToken(r)
+	if token == "" {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(token, ":")
+	if !ok {
+		return "", false
+	}
+
+	if err := a.reload(); err != nil {
+		log.Printf("api: bcryptfile auth: %v", err)
+	}
+
+	a.mu.RLock()
+	hash, known := a.hashes[id]
+	a.mu.RUnlock()
+	if !known {
+		return "", false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) != nil {
+		return "", false
+	}
+	return "user:" + id, true
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestNewAuth_Static(t *testing.T) {
+	for _, paramstr := range []string{"secret-token", "static://secret-token"} {
+		auth, err := NewAuth(paramstr)
+		if err != nil {
+			t.Fatalf("NewAuth(%q): %v", paramstr, err)
+		}
+
+		if principal, ok := auth.Validate(bearerRequest("secret-token")); !ok || principal != "token:static" {
+			t.Errorf("NewAuth(%q): expected valid token:static, got %q, %v", paramstr, principal, ok)
+		}
+		if _, ok := auth.Validate(bearerRequest("wrong-token")); ok {
+			t.Errorf("NewAuth(%q): expected an unrecognized token to be rejected", paramstr)
+		}
+		if _, ok := auth.Validate(bearerRequest("")); ok {
+			t.Errorf("NewAuth(%q): expected a missing token to be rejected", paramstr)
+		}
+	}
+}
+
+func TestNewAuth_StaticRequiresToken(t *testing.T) {
+	if _, err := NewAuth("static://"); err == nil {
+		t.Fatal("expected an error for static:// with no token")
+	}
+}
+
+func TestNewAuth_None(t *testing.T) {
+	auth, err := NewAuth("none://")
+	if err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+	principal, ok := auth.Validate(bearerRequest(""))
+	if !ok || principal != "anonymous" {
+		t.Errorf("expected anonymous to be accepted, got %q, %v", principal, ok)
+	}
+}
+
+func TestNewAuth_UnsupportedScheme(t *testing.T) {
+	if _, err := NewAuth("cert://"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
+
+func writeBcryptFile(t *testing.T, entries map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokens")
+	var contents string
+	for id, secret := range entries {
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			t.Fatalf("generate bcrypt hash: %v", err)
+		}
+		contents += id + ":" + string(hash) + "\n"
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write bcrypt file: %v", err)
+	}
+	return path
+}
+
+func TestNewAuth_BcryptFile(t *testing.T) {
+	path := writeBcryptFile(t, map[string]string{"alice": "hunter2"})
+
+	auth, err := NewAuth("bcryptfile://" + path)
+	if err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+
+	if principal, ok := auth.Validate(bearerRequest("alice:hunter2")); !ok || principal != "user:alice" {
+		t.Errorf("expected valid user:alice, got %q, %v", principal, ok)
+	}
+	if _, ok := auth.Validate(bearerRequest("alice:wrong")); ok {
+		t.Error("expected a wrong secret to be rejected")
+	}
+	if _, ok := auth.Validate(bearerRequest("bob:hunter2")); ok {
+		t.Error("expected an unknown id to be rejected")
+	}
+	if _, ok := auth.Validate(bearerRequest("not-id-colon-secret")); ok {
+		t.Error("expected a token with no id:secret separator to be rejected")
+	}
+}
+
+func TestNewAuth_BcryptFileReloadsOnChange(t *testing.T) {
+	path := writeBcryptFile(t, map[string]string{"alice": "hunter2"})
+
+	auth, err := NewAuth("bcryptfile://" + path)
+	if err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+
+	if _, ok := auth.Validate(bearerRequest("bob:swordfish")); ok {
+		t.Fatal("bob should not be registered yet")
+	}
+
+	// Rewrite with an additional entry; bump mtime forward so the
+	// lazy-reload's mtime comparison definitely observes a change.
+	newPath := writeBcryptFile(t, map[string]string{"alice": "hunter2", "bob": "swordfish"})
+	data, err := os.ReadFile(newPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	if principal, ok := auth.Validate(bearerRequest("bob:swordfish")); !ok || principal != "user:bob" {
+		t.Errorf("expected bob to be recognized after reload, got %q, %v", principal, ok)
+	}
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// bootstrapAdminToken mints a ScopeAdmin API token on every process start
+// (apiTokens has no persistence, so there's no way to tell "already
+// bootstrapped" from "fresh process" apart) and logs it, Consul-style, so an
+// operator can start minting narrower tokens via POST /auth/token without
+// handing out the static ManagementAuth credential itself.
+func bootstrapAdminToken(h *Handlers) error {
+	value, err := randomToken()
+	if err != nil {
+		return err
+	}
+	accessorID, err := randomToken()
+	if err != nil {
+		return err
+	}
+	accessorID = accessorID[:16]
+
+	token := &APIToken{
+		AccessorID: accessorID,
+		Token:      value,
+		Scopes:     []string{string(ScopeAdmin)},
+		Name:       "bootstrap",
+		CreatedAt:  time.Now().UTC(),
+	}
+	h.store.mu.Lock()
+	h.store.apiTokens[value] = token
+	h.store.mu.Unlock()
+
+	log.Printf("Bootstrap admin API token (accessor %s): %s", accessorID, value)
+	return nil
+}
+
+// CreateAPITokenHandler handles POST /auth/token, minting a bearer token
+// scoped to the requested Scopes. Minting a credential is itself a
+// management action, so this is gated the same as every other
+// ManagementAuth endpoint rather than being self-service.
+func (h *Handlers) CreateAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeError(w, http.StatusBadRequest, "scopes is required")
+		return
+	}
+	for _, s := range req.Scopes {
+		if !validScopes[Scope(s)] {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown scope %q", s))
+			return
+		}
+	}
+
+	value, err := randomToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "generate token: "+err.Error())
+		return
+	}
+	accessorID, err := randomToken()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "generate accessor id: "+err.Error())
+		return
+	}
+	accessorID = accessorID[:16]
+
+	var expiresAt *time.Time
+	if req.TTLSeconds > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.TTLSeconds) * time.Second)
+		expiresAt = &t
+	}
+
+	token := &APIToken{
+		AccessorID: accessorID,
+		Token:      value,
+		Scopes:     req.Scopes,
+		Name:       req.Name,
+		CreatedAt:  time.Now().UTC(),
+		ExpiresAt:  expiresAt,
+		TTLSeconds: req.TTLSeconds,
+		Renewable:  req.Renewable,
+	}
+	h.store.mu.Lock()
+	h.store.apiTokens[value] = token
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "create", "api_token", redactToken(value), nil, *token)
+	writeJSON(w, http.StatusCreated, token)
+}
+
+// ListAPITokensHandler handles GET /auth/tokens. The secret itself is
+// stripped from every entry -- listing shows only what's needed to pick a
+// token to revoke (AccessorID), not a way to recover a live credential.
+func (h *Handlers) ListAPITokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	tokens := make([]APIToken, 0, len(h.store.apiTokens))
+	for _, t := range h.store.apiTokens {
+		redacted := *t
+		redacted.Token = ""
+		tokens = append(tokens, redacted)
+	}
+	h.store.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, ListAPITokensResponse{Tokens: tokens, Total: len(tokens)})
+}
+
+// DeleteAPITokenHandler handles DELETE /auth/token/{id}, where id is either
+// the token's secret value or its AccessorID -- `auth revoke <accessor>`
+// only ever knows the latter.
+func (h *Handlers) DeleteAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/auth/token/")
+	if id == "" || id == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing token")
+		return
+	}
+
+	h.store.mu.Lock()
+	value := id
+	token, exists := h.store.apiTokens[value]
+	if !exists {
+		for v, t := range h.store.apiTokens {
+			if t.AccessorID == id {
+				value, token, exists = v, t, true
+				break
+			}
+		}
+	}
+	if !exists {
+		h.store.mu.Unlock()
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	before := *token
+	delete(h.store.apiTokens, value)
+	h.store.mu.Unlock()
+
+	h.recordAudit(actorFromContext(r.Context()), "delete", "api_token", redactToken(value), before, nil)
+	writeJSON(w, http.StatusOK, DeleteResponse{ID: token.AccessorID, Message: "token deleted successfully"})
+}
+
+// RenewAPITokenHandler handles POST /auth/token/renew. Unlike minting or
+// revoking a token, renewal is self-service: the token being renewed is
+// its own credential, presented as the bearer token, rather than requiring
+// a management credential. Only tokens created with Renewable=true and a
+// TTLSeconds can be renewed; renewal resets ExpiresAt to now plus the
+// token's original TTL, rather than granting an unbounded extension.
+func (h *Handlers) RenewAPITokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	value := extractBearerToken(r)
+	if value == "" {
+		writeError(w, http.StatusUnauthorized, "missing authorization token")
+		return
+	}
+
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	token, ok := h.store.apiTokens[value]
+	if !ok {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+	if token.ExpiresAt != nil && time.Now().UTC().After(*token.ExpiresAt) {
+		writeError(w, http.StatusForbidden, "token has already expired")
+		return
+	}
+	if !token.Renewable || token.TTLSeconds <= 0 {
+		writeError(w, http.StatusForbidden, "token is not renewable")
+		return
+	}
+
+	newExpiry := time.Now().UTC().Add(time.Duration(token.TTLSeconds) * time.Second)
+	token.ExpiresAt = &newExpiry
+
+	writeJSON(w, http.StatusOK, RenewAPITokenResponse{ExpiresAt: newExpiry})
+}
+
+// lookupAPIToken returns the APIToken for value, if one was minted via
+// POST /auth/token, not since revoked, and not expired.
+func (h *Handlers) lookupAPIToken(value string) (*APIToken, bool) {
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+	token, ok := h.store.apiTokens[value]
+	if !ok {
+		return nil, false
+	}
+	if token.ExpiresAt != nil && time.Now().UTC().After(*token.ExpiresAt) {
+		return nil, false
+	}
+	return token, true
+}
+
+// IntrospectHandler handles GET /auth/introspect: it reports what the
+// presented bearer credential resolves to, checking it against the scoped
+// API tokens minted via POST /auth/token and then cfg's blanket
+// management/admin/agent tokens. `auth whoami` and the CLI's login flow
+// both call this to validate a credential before persisting it.
+func IntrospectHandler(handlers *Handlers, cfg *AuthConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		token := extractBearerToken(r)
+		if token == "" {
+			writeJSON(w, http.StatusOK, IntrospectResponse{Valid: false})
+			return
+		}
+
+		if apiToken, ok := handlers.lookupAPIToken(token); ok {
+			writeJSON(w, http.StatusOK, IntrospectResponse{
+				Valid:      true,
+				AccessorID: apiToken.AccessorID,
+				Scopes:     apiToken.Scopes,
+				Kind:       "api_token",
+				ExpiresAt:  apiToken.ExpiresAt,
+			})
+			return
+		}
+
+		switch {
+		case cfg.AdminToken != "" && token == cfg.AdminToken:
+			writeJSON(w, http.StatusOK, IntrospectResponse{Valid: true, Kind: "admin"})
+		case isValid(cfg.Management, r):
+			writeJSON(w, http.StatusOK, IntrospectResponse{Valid: true, Kind: "management"})
+		case isValid(cfg.Agent, r):
+			writeJSON(w, http.StatusOK, IntrospectResponse{Valid: true, Kind: "agent"})
+		default:
+			writeJSON(w, http.StatusOK, IntrospectResponse{Valid: false})
+		}
+	}
+}
+
+// isValid reports whether auth accepts r, discarding the resolved
+// principal -- IntrospectHandler only needs the yes/no to pick a Kind.
+func isValid(auth Auth, r *http.Request) bool {
+	_, ok := auth.Validate(r)
+	return ok
+}
+
+// ScopedOr authenticates a request by a scoped API token holding scope,
+// falling back to fallback (typically ManagementAuth or AgentAccess) when
+// the caller presents no such token. A token that IS recognized but lacks
+// scope is rejected outright rather than falling through, so a narrowly
+// scoped token never gets a second chance to match a broader credential.
+func (h *Handlers) ScopedOr(scope Scope, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token := extractBearerToken(r); token != "" {
+				if apiToken, ok := h.lookupAPIToken(token); ok {
+					if !hasScope(apiToken.Scopes, scope) {
+						writeError(w, http.StatusForbidden, fmt.Sprintf("token lacks required scope %q", scope))
+						return
+					}
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			fallback(next).ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+)
+
+// defaultListWait is the blocking-query timeout a List* handler uses when
+// ?index= is set without an explicit ?wait=, matching rulesHandler's
+// defaultRulesWait in cmd/proxy.
+const defaultListWait = 30 * time.Second
+
+// changeNotifier is a per-collection close-and-replace broadcast channel,
+// generalizing the blocking-query primitive rules.Engine.WaitForChange uses
+// for GET /rules?index=&wait= to every other list endpoint that wants
+// Consul-style long polling (GET /agents, /rules, /logs). Mutating handlers
+// call notify(collection) to wake anyone blocked in waitForChange.
+type changeNotifier struct {
+	mu    sync.Mutex
+	chans map[string]chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{chans: make(map[string]chan struct{})}
+}
+
+// chanFor returns the current broadcast channel for collection, creating one
+// the first time it's asked for.
+func (n *changeNotifier) chanFor(collection string) chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ch, ok := n.chans[collection]
+	if !ok {
+		ch = make(chan struct{})
+		n.chans[collection] = ch
+	}
+	return ch
+}
+
+// notify wakes every waiter blocked on collection by closing its current
+// channel and installing a fresh one for the next wait.
+func (n *changeNotifier) notify(collection string) {
+	n.mu.Lock()
+	ch, ok := n.chans[collection]
+	n.chans[collection] = make(chan struct{})
+	n.mu.Unlock()
+	if ok {
+		close(ch)
+	}
+}
+
+// waitForChange blocks until compute() returns a hash different from
+// sinceHash, ctx is done, or timeout elapses, then returns the current hash.
+// An empty sinceHash returns immediately, matching rules.Engine.WaitForChange.
+func (n *changeNotifier) waitForChange(ctx context.Context, collection, sinceHash string, timeout time.Duration, compute func() string) string {
+	if sinceHash == "" {
+		return compute()
+	}
+	if h := compute(); h != sinceHash {
+		return h
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		ch := n.chanFor(collection)
+		select {
+		case <-ch:
+			if h := compute(); h != sinceHash {
+				return h
+			}
+		case <-deadline.C:
+			return compute()
+		case <-ctx.Done():
+			return compute()
+		}
+	}
+}
+
+// hashJSON returns a stable sha256 hex digest of v's JSON encoding, for the
+// X-Plasma-Index value a List* handler returns and compares against the
+// ?index= a caller long-polls with, the same role rules.Engine.Hash plays
+// for GET /rules.
+func hashJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// parseWait parses the optional ?wait= duration a blocking ?index= query
+// uses, defaulting to defaultListWait (matching Consul's own default for an
+// ?index= with no explicit ?wait=). It writes a 400 and returns ok=false if
+// ?wait= is present but malformed.
+func parseWait(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	raw := r.URL.Query().Get("wait")
+	if raw == "" {
+		return defaultListWait, true
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid wait: "+err.Error())
+		return 0, false
+	}
+	return d, true
+}
+
+// waitForBastionChange blocks until compute() returns a hash different from
+// sinceHash, a new bastion session event is recorded, ctx is done, or
+// timeout elapses, then returns the current hash. Unlike changeNotifier
+// (used by the agents/rules/logs collections), it rides bastionLogs' own
+// logs.Broker tail subscription rather than a private broadcast channel,
+// since bastion.LogStore already publishes one for GET
+// /bastion/sessions/{id}/tail.
+func waitForBastionChange(ctx context.Context, bastionLogs *bastion.LogStore, sinceHash string, timeout time.Duration, compute func() string) string {
+	if sinceHash == "" {
+		return compute()
+	}
+	if h := compute(); h != sinceHash {
+		return h
+	}
+
+	sub := bastionLogs.Tail()
+	defer sub.Close()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-sub.Events():
+			if h := compute(); h != sinceHash {
+				return h
+			}
+		case <-deadline.C:
+			return compute()
+		case <-ctx.Done():
+			return compute()
+		}
+	}
+}
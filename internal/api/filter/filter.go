@@ -0,0 +1,665 @@
+// Package filter implements the small boolean expression language accepted
+// by the `?filter=` query parameter on the management list endpoints
+// (/agents, /rules, /logs, /grants, /bastion/sessions). It's modeled after
+// Consul's go-bexpr: selectors like `Principal == "alice" and Target
+// matches "^web-"` are tokenized, parsed into an AST, and evaluated against
+// a list element by reflecting over its exported struct fields, so a single
+// expression syntax works across every resource type without per-resource
+// glue code.
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a parsed filter expression, ready to be evaluated against list
+// elements with Match.
+type Expr struct {
+	root node
+}
+
+// Parse parses a filter expression. A SyntaxError is returned on malformed
+// input, with Pos pointing at the offending rune so callers can report
+// exactly where the expression went wrong.
+func Parse(s string) (*Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: fmt.Sprintf("unexpected token %q", p.peek().text)}
+	}
+	return &Expr{root: root}, nil
+}
+
+// Match reports whether v, a struct or pointer to struct, satisfies the
+// expression. Unexported fields and fields not referenced by the
+// expression are ignored.
+func (e *Expr) Match(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	return e.root.eval(rv)
+}
+
+// SyntaxError is returned by Parse for a malformed expression. Pos is a
+// rune offset (not a byte offset) into the original expression string,
+// suitable for rendering a "^" pointer under the offending token.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+// --- lexer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+	tokEQ
+	tokNEQ
+	tokLT
+	tokLE
+	tokGT
+	tokGE
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(s string) ([]token, error) {
+	runes := []rune(s)
+	var toks []token
+	pos := 0
+
+	skipSpace := func() {
+		for pos < len(runes) && (runes[pos] == ' ' || runes[pos] == '\t' || runes[pos] == '\n' || runes[pos] == '\r') {
+			pos++
+		}
+	}
+	peekAt := func(offset int) rune {
+		if pos+offset >= len(runes) {
+			return 0
+		}
+		return runes[pos+offset]
+	}
+
+	for {
+		skipSpace()
+		if pos >= len(runes) {
+			toks = append(toks, token{kind: tokEOF, pos: pos})
+			return toks, nil
+		}
+
+		start := pos
+		c := runes[pos]
+		switch {
+		case c == '(':
+			pos++
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: start})
+		case c == ')':
+			pos++
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: start})
+		case c == '[':
+			pos++
+			toks = append(toks, token{kind: tokLBracket, text: "[", pos: start})
+		case c == ']':
+			pos++
+			toks = append(toks, token{kind: tokRBracket, text: "]", pos: start})
+		case c == ',':
+			pos++
+			toks = append(toks, token{kind: tokComma, text: ",", pos: start})
+		case c == '=' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokEQ, text: "==", pos: start})
+		case c == '!' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokNEQ, text: "!=", pos: start})
+		case c == '<' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokLE, text: "<=", pos: start})
+		case c == '<':
+			pos++
+			toks = append(toks, token{kind: tokLT, text: "<", pos: start})
+		case c == '>' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokGE, text: ">=", pos: start})
+		case c == '>':
+			pos++
+			toks = append(toks, token{kind: tokGT, text: ">", pos: start})
+		case c == '"':
+			pos++
+			var sb strings.Builder
+			closed := false
+			for pos < len(runes) {
+				if runes[pos] == '"' {
+					closed = true
+					pos++
+					break
+				}
+				if runes[pos] == '\\' && pos+1 < len(runes) {
+					pos++
+				}
+				sb.WriteRune(runes[pos])
+				pos++
+			}
+			if !closed {
+				return nil, &SyntaxError{Pos: start, Msg: "unterminated string literal"}
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String(), pos: start})
+		case c == '-' || (c >= '0' && c <= '9'):
+			if c == '-' {
+				pos++
+			}
+			for pos < len(runes) && runes[pos] >= '0' && runes[pos] <= '9' {
+				pos++
+			}
+			if pos < len(runes) && runes[pos] == '.' {
+				pos++
+				for pos < len(runes) && runes[pos] >= '0' && runes[pos] <= '9' {
+					pos++
+				}
+			}
+			toks = append(toks, token{kind: tokNumber, text: string(runes[start:pos]), pos: start})
+		case isIdentStart(c):
+			for pos < len(runes) && isIdentPart(runes[pos]) {
+				pos++
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(runes[start:pos]), pos: start})
+		default:
+			return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", string(c))}
+		}
+	}
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// --- parser ---
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) advance() token {
+	t := p.toks[p.pos]
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokIdent && p.peek().text == "or" {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokIdent && p.peek().text == "and" {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokIdent && p.peek().text == "not" {
+		p.advance()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.peek().kind == tokLParen {
+		p.advance()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, &SyntaxError{Pos: p.peek().pos, Msg: "expected ')'"}
+		}
+		p.advance()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch tok.kind {
+	case tokEQ, tokNEQ, tokLT, tokLE, tokGT, tokGE:
+		p.advance()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{left: left, op: tok.text, right: right}, nil
+	case tokIdent:
+		switch tok.text {
+		case "matches":
+			p.advance()
+			pat := p.peek()
+			if pat.kind != tokString {
+				return nil, &SyntaxError{Pos: pat.pos, Msg: "matches requires a string literal pattern"}
+			}
+			p.advance()
+			re, err := regexp.Compile(pat.text)
+			if err != nil {
+				return nil, &SyntaxError{Pos: pat.pos, Msg: fmt.Sprintf("invalid regex in matches: %v", err)}
+			}
+			return matchesNode{left: left, re: re}, nil
+		case "in":
+			p.advance()
+			list, err := p.parseListLiteral()
+			if err != nil {
+				return nil, err
+			}
+			return inNode{left: left, list: list}, nil
+		}
+	}
+	return truthyNode{left}, nil
+}
+
+func (p *parser) parseOperand() (valueNode, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokString:
+		p.advance()
+		return literalNode{tok.text}, nil
+	case tokNumber:
+		p.advance()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		return literalNode{f}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			p.advance()
+			return literalNode{true}, nil
+		case "false":
+			p.advance()
+			return literalNode{false}, nil
+		}
+		p.advance()
+		return fieldNode{name: tok.text}, nil
+	}
+	return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected token %q", tok.text)}
+}
+
+func (p *parser) parseListLiteral() ([]interface{}, error) {
+	if p.peek().kind != tokLBracket {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: "expected '['"}
+	}
+	p.advance()
+	var vals []interface{}
+	if p.peek().kind == tokRBracket {
+		p.advance()
+		return vals, nil
+	}
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case tokString:
+			vals = append(vals, tok.text)
+			p.advance()
+		case tokNumber:
+			f, err := strconv.ParseFloat(tok.text, 64)
+			if err != nil {
+				return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+			}
+			vals = append(vals, f)
+			p.advance()
+		default:
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a string or number in list literal"}
+		}
+		if p.peek().kind == tokComma {
+			p.advance()
+			continue
+		}
+		break
+	}
+	if p.peek().kind != tokRBracket {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: "expected ']'"}
+	}
+	p.advance()
+	return vals, nil
+}
+
+// --- AST nodes ---
+
+// node is a boolean-valued expression: and/or/not, comparisons, and a bare
+// operand used as a boolean (e.g. `Enabled` alone means "Enabled is true").
+type node interface {
+	eval(v reflect.Value) bool
+}
+
+// valueNode is an operand: a literal or a struct field reference.
+type valueNode interface {
+	value(v reflect.Value) interface{}
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(v reflect.Value) bool { return n.left.eval(v) && n.right.eval(v) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(v reflect.Value) bool { return n.left.eval(v) || n.right.eval(v) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(v reflect.Value) bool { return !n.inner.eval(v) }
+
+type truthyNode struct{ v valueNode }
+
+func (n truthyNode) eval(v reflect.Value) bool { return isTruthy(n.v.value(v)) }
+
+type comparisonNode struct {
+	left  valueNode
+	op    string
+	right valueNode
+}
+
+func (n comparisonNode) eval(v reflect.Value) bool {
+	return compareValues(n.op, n.left.value(v), n.right.value(v))
+}
+
+// matchesNode's regex is compiled once at parse time, not on every eval.
+type matchesNode struct {
+	left valueNode
+	re   *regexp.Regexp
+}
+
+func (n matchesNode) eval(v reflect.Value) bool {
+	return n.re.MatchString(asString(n.left.value(v)))
+}
+
+type inNode struct {
+	left valueNode
+	list []interface{}
+}
+
+func (n inNode) eval(v reflect.Value) bool {
+	lv := n.left.value(v)
+	for _, item := range n.list {
+		if compareValues("==", lv, item) {
+			return true
+		}
+	}
+	return false
+}
+
+type literalNode struct{ v interface{} }
+
+func (n literalNode) value(reflect.Value) interface{} { return n.v }
+
+// fieldNode resolves a dotted field path (e.g. Grant.Principal) against the
+// struct being matched, via reflection over exported fields. A segment that
+// doesn't resolve (unknown field, nil pointer, non-struct) returns nil,
+// which compareValues/isTruthy treat as the zero value of whatever it's
+// compared against, so evaluation stays total and never panics.
+type fieldNode struct{ name string }
+
+func (n fieldNode) value(v reflect.Value) interface{} {
+	cur := v
+	for _, seg := range strings.Split(n.name, ".") {
+		for cur.Kind() == reflect.Ptr {
+			if cur.IsNil() {
+				return nil
+			}
+			cur = cur.Elem()
+		}
+		if cur.Kind() != reflect.Struct {
+			return nil
+		}
+		cur = cur.FieldByName(seg)
+		if !cur.IsValid() {
+			return nil
+		}
+	}
+	if !cur.CanInterface() {
+		return nil
+	}
+	return cur.Interface()
+}
+
+// --- value helpers ---
+
+func isTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case float64:
+		return t != 0
+	case int:
+		return t != 0
+	case int64:
+		return t != 0
+	case time.Time:
+		return !t.IsZero()
+	default:
+		return true
+	}
+}
+
+func asString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		return t.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+// zeroLike infers the zero value an unknown field should take on, based on
+// the type of the operand it's being compared against.
+func zeroLike(v interface{}) interface{} {
+	switch v.(type) {
+	case float64, int, int64:
+		return float64(0)
+	case bool:
+		return false
+	case time.Time:
+		return time.Time{}
+	default:
+		return ""
+	}
+}
+
+// compareValues compares two operand values under op. An operand that's
+// nil (an unknown or unresolved field) is coerced to the zero value
+// inferred from the other side before comparing, per fieldNode's doc
+// comment. time.Time fields are compared as times (parsing the other side
+// as RFC 3339) rather than as formatted strings, so `<`/`>` behave
+// correctly regardless of timezone.
+func compareValues(op string, left, right interface{}) bool {
+	if left == nil {
+		left = zeroLike(right)
+	}
+	if right == nil {
+		right = zeroLike(left)
+	}
+
+	if lt, ok := left.(time.Time); ok {
+		if rt, ok := toTime(right); ok {
+			switch op {
+			case "==":
+				return lt.Equal(rt)
+			case "!=":
+				return !lt.Equal(rt)
+			case "<":
+				return lt.Before(rt)
+			case "<=":
+				return lt.Before(rt) || lt.Equal(rt)
+			case ">":
+				return lt.After(rt)
+			case ">=":
+				return lt.After(rt) || lt.Equal(rt)
+			}
+			return false
+		}
+	}
+	if rt, ok := right.(time.Time); ok {
+		if lt, ok := toTime(left); ok {
+			return compareValues(op, lt, rt)
+		}
+	}
+
+	switch l := left.(type) {
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		default:
+			return false
+		}
+	default:
+		if lf, ok := toFloat(left); ok {
+			if rf, ok := toFloat(right); ok {
+				switch op {
+				case "==":
+					return lf == rf
+				case "!=":
+					return lf != rf
+				case "<":
+					return lf < rf
+				case "<=":
+					return lf <= rf
+				case ">":
+					return lf > rf
+				case ">=":
+					return lf >= rf
+				}
+				return false
+			}
+		}
+		ls, rs := asString(left), asString(right)
+		switch op {
+		case "==":
+			return ls == rs
+		case "!=":
+			return ls != rs
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		}
+		return false
+	}
+}
@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	Principal string
+	Target    string
+	Priority  int
+	Enabled   bool
+	ExpiresAt time.Time
+}
+
+func TestMatchComparison(t *testing.T) {
+	e, err := Parse(`Principal == "alice"`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{Principal: "alice"}) {
+		t.Error("expected match")
+	}
+	if e.Match(testItem{Principal: "bob"}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestMatchMatches(t *testing.T) {
+	e, err := Parse(`Target matches "^web-"`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{Target: "web-1"}) {
+		t.Error("expected regex match")
+	}
+	if e.Match(testItem{Target: "db-1"}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestMatchAndOrNot(t *testing.T) {
+	e, err := Parse(`Principal == "alice" and not Target == "db-1"`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{Principal: "alice", Target: "web-1"}) {
+		t.Error("expected match")
+	}
+	if e.Match(testItem{Principal: "alice", Target: "db-1"}) {
+		t.Error("expected no match (target excluded)")
+	}
+	if e.Match(testItem{Principal: "bob", Target: "web-1"}) {
+		t.Error("expected no match (wrong principal)")
+	}
+}
+
+func TestMatchIn(t *testing.T) {
+	e, err := Parse(`Principal in ["alice", "carol"]`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{Principal: "carol"}) {
+		t.Error("expected match")
+	}
+	if e.Match(testItem{Principal: "bob"}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestMatchNumericComparison(t *testing.T) {
+	e, err := Parse(`Priority > 5`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{Priority: 10}) {
+		t.Error("expected match")
+	}
+	if e.Match(testItem{Priority: 1}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestMatchBoolTruthy(t *testing.T) {
+	e, err := Parse(`Enabled`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{Enabled: true}) {
+		t.Error("expected match")
+	}
+	if e.Match(testItem{Enabled: false}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestMatchTimeComparison(t *testing.T) {
+	e, err := Parse(`ExpiresAt > "2024-01-01T00:00:00Z"`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{ExpiresAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}) {
+		t.Error("expected match")
+	}
+	if e.Match(testItem{ExpiresAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestMatchUnknownFieldTreatedAsZeroValue(t *testing.T) {
+	e, err := Parse(`Nonexistent == ""`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(testItem{}) {
+		t.Error("expected an unresolved field to compare as the zero value")
+	}
+}
+
+func TestMatchPointerToStruct(t *testing.T) {
+	e, err := Parse(`Principal == "alice"`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !e.Match(&testItem{Principal: "alice"}) {
+		t.Error("expected match against a pointer")
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Parse(`Principal == `)
+	if err == nil {
+		t.Fatal("expected an error for a malformed expression")
+	}
+	synErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected a *SyntaxError, got %T", err)
+	}
+	if synErr.Pos == 0 {
+		t.Error("expected a non-zero position pointing at the missing operand")
+	}
+}
+
+func TestParseInvalidRegex(t *testing.T) {
+	if _, err := Parse(`Target matches "("`); err == nil {
+		t.Error("expected an invalid regex to fail to parse")
+	}
+}
+
+func TestParseTrailingTokens(t *testing.T) {
+	if _, err := Parse(`Principal == "alice" )`); err == nil {
+		t.Error("expected a stray trailing token to fail to parse")
+	}
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	shieldpb "github.com/Extra-Chill/plasma-shield/api/proto"
+)
+
+// GRPCServer is the gRPC counterpart to Server: it exposes the
+// agent-facing surface (ExecCheck, ValidateGrant, StreamMode, ReportEvent)
+// over a second listener, for agents that want lower-latency checks or
+// live mode updates instead of REST polling. Construct with
+// NewGRPCServer and serve it on its own net.Listener -- it's independent
+// of Server's http.Server entirely, the same way cmd/proxy's metrics
+// endpoint runs on its own listener alongside the proxy's main one.
+type GRPCServer struct {
+	shieldpb.UnimplementedShieldAgentServer
+
+	server   *grpc.Server
+	handlers *Handlers
+}
+
+// NewGRPCServer builds a *grpc.Server with the ShieldAgent service
+// registered, wired with a recovery interceptor (so a panic in a handler
+// becomes a gRPC Internal error instead of tearing down the process, the
+// same contract Recovery provides for the REST API), an auth interceptor
+// validating the caller's "authorization" metadata against agentAuth, and
+// a stats.Handler recording per-method latency and active stream counts
+// into handlers.metrics.
+func NewGRPCServer(handlers *Handlers, agentAuth Auth) *GRPCServer {
+	g := &GRPCServer{handlers: handlers}
+
+	recoveryOpts := []recovery.Option{
+		recovery.WithRecoveryHandlerContext(func(ctx context.Context, p interface{}) error {
+			handlers.metrics.recordPanic("grpc")
+			return status.Errorf(codes.Internal, "internal error")
+		}),
+	}
+
+	g.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(recoveryOpts...),
+			grpcAgentAuthUnaryInterceptor(agentAuth),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(recoveryOpts...),
+			grpcAgentAuthStreamInterceptor(agentAuth),
+		),
+		grpc.StatsHandler(&grpcStatsHandler{metrics: handlers.metrics}),
+	)
+	shieldpb.RegisterShieldAgentServer(g.server, g)
+	return g
+}
+
+// Server returns the underlying *grpc.Server, for the caller to Serve on a
+// net.Listener and GracefulStop on shutdown.
+func (g *GRPCServer) Server() *grpc.Server {
+	return g.server
+}
+
+// grpcAgentAuthUnaryInterceptor validates the "authorization" gRPC
+// metadata value against auth the same way AgentAuth validates the HTTP
+// Authorization header, by wrapping the token in a throwaway *http.Request
+// and calling auth.Validate -- this reuses every existing Auth backend
+// (static/none/bcryptfile) instead of a second agentToken comparison.
+func grpcAgentAuthUnaryInterceptor(auth Auth) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := validateGRPCAuth(ctx, auth); !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid agent credential")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func grpcAgentAuthStreamInterceptor(auth Auth) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := validateGRPCAuth(ss.Context(), auth); !ok {
+			return status.Error(codes.Unauthenticated, "invalid agent credential")
+		}
+		return handler(srv, ss)
+	}
+}
+
+func validateGRPCAuth(ctx context.Context, auth Auth) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false
+	}
+	req := &http.Request{Header: http.Header{"Authorization": []string{values[0]}}}
+	return auth.Validate(req)
+}
+
+// grpcStatsHandler implements google.golang.org/grpc/stats.Handler,
+// recording shield_grpc_request_duration_seconds and
+// shield_grpc_active_streams for every ShieldAgent call. It's a parallel
+// mechanism to the recovery/auth interceptors above: interceptors wrap a
+// single handler invocation, while stats.Handler sees the RPC's full
+// lifecycle (including connection-level events), which is what a gauge of
+// in-flight calls needs.
+type grpcStatsHandler struct {
+	metrics *execMetrics
+}
+
+type grpcStatsContextKey int
+
+const grpcStatsStartKey grpcStatsContextKey = iota
+
+func (h *grpcStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, grpcStatsStartKey, grpcStatsTag{method: info.FullMethodName})
+}
+
+type grpcStatsTag struct {
+	method string
+}
+
+func (h *grpcStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	tag, _ := ctx.Value(grpcStatsStartKey).(grpcStatsTag)
+	switch st := s.(type) {
+	case *stats.Begin:
+		h.metrics.grpcStreamOpened(tag.method)
+	case *stats.End:
+		h.metrics.grpcStreamClosed(tag.method)
+		code := codes.OK
+		if st.Error != nil {
+			code = status.Code(st.Error)
+		}
+		h.metrics.observeGRPC(tag.method, code.String(), st.EndTime.Sub(st.BeginTime).Seconds())
+	}
+}
+
+func (h *grpcStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *grpcStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+// ExecCheck implements shieldpb.ShieldAgentServer by replaying the request
+// through the existing POST /exec/check handler (ExecCheckHandler), rather
+// than duplicating its rule evaluation, approval-wait, metrics, and audit
+// logging here. An httptest.ResponseRecorder stands in for the
+// http.ResponseWriter ExecCheckHandler expects.
+func (g *GRPCServer) ExecCheck(ctx context.Context, req *shieldpb.ExecCheckRequest) (*shieldpb.ExecCheckResponse, error) {
+	body, err := json.Marshal(ExecCheckRequest{
+		AgentID: req.AgentId,
+		Command: req.Command,
+		User:    req.User,
+		Cwd:     req.Cwd,
+		Env:     req.Env,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal request: %v", err)
+	}
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body)).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	g.handlers.ExecCheckHandler(rec, httpReq)
+
+	var resp ExecCheckResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "decode response: %v", err)
+	}
+	return &shieldpb.ExecCheckResponse{
+		Allowed: resp.Allowed,
+		Reason:  resp.Reason,
+		RuleId:  resp.RuleID,
+	}, nil
+}
+
+// ValidateGrant implements shieldpb.ShieldAgentServer directly against
+// bastion.GrantStore, since ValidateAccess/ValidateShellAccess are already
+// cheap in-memory lookups with no REST-only side effects to reuse.
+func (g *GRPCServer) ValidateGrant(ctx context.Context, req *shieldpb.ValidateGrantRequest) (*shieldpb.ValidateGrantResponse, error) {
+	grants := g.handlers.store.bastionGrants
+	if grants == nil {
+		return &shieldpb.ValidateGrantResponse{Valid: false}, nil
+	}
+
+	grant := grants.ValidateAccess(req.Principal, req.Target)
+	if grant == nil {
+		return &shieldpb.ValidateGrantResponse{Valid: false}, nil
+	}
+	return &shieldpb.ValidateGrantResponse{
+		Valid:         true,
+		GrantId:       grant.ID,
+		Shell:         grant.Shell,
+		ExpiresAtUnix: grant.ExpiresAt.Unix(),
+	}, nil
+}
+
+// StreamMode implements shieldpb.ShieldAgentServer. The proto was written
+// against mode.Manager's enforce/audit/lockdown broadcaster, but that
+// manager belongs to cmd/proxy/cmd/gateway, not this binary -- cmd/api (the
+// only binary with the bastion grants and PLASMA_AGENT_TOKEN auth the other
+// three RPCs need) has no mode.Manager at all. So StreamMode instead relays
+// Store's existing agent status broadcaster (see subscribeAgentEvents,
+// already used by GET /agents/stream), translating each pause/kill/resume
+// into a ModeChange the same shape a mode.Manager change would have taken:
+// "paused"/"killed" become "lockdown" for that agent, "active" becomes
+// "enforce".
+func (g *GRPCServer) StreamMode(req *shieldpb.StreamModeRequest, stream shieldpb.ShieldAgent_StreamModeServer) error {
+	events, unsubscribe := g.handlers.store.subscribeAgentEvents()
+	defer unsubscribe()
+
+	for {
+		select {
+		case event := <-events:
+			if req.AgentId != "" && event.AgentID != req.AgentId {
+				continue
+			}
+			err := stream.Send(&shieldpb.ModeChange{
+				AgentId:       event.AgentID,
+				Mode:          agentStatusToMode(event.Status),
+				TimestampUnix: event.Timestamp.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// agentStatusToMode maps an AgentEvent.Status (as published by
+// PauseAgentHandler/KillAgentHandler/ResumeAgentHandler) onto the
+// mode.Manager vocabulary StreamMode's caller expects: a paused or killed
+// agent behaves like it's under lockdown, an active one like enforce.
+func agentStatusToMode(agentStatus string) string {
+	switch agentStatus {
+	case "paused", "killed":
+		return "lockdown"
+	default:
+		return "enforce"
+	}
+}
+
+// ReportEvent implements shieldpb.ShieldAgentServer by recording req as a
+// log entry, the same destination POST /exec/check's decisions and the
+// rest of the management API's audit trail feed into.
+func (g *GRPCServer) ReportEvent(ctx context.Context, req *shieldpb.ReportEventRequest) (*shieldpb.ReportEventResponse, error) {
+	g.handlers.store.mu.Lock()
+	g.handlers.addLog(req.AgentId, req.Type, req.Detail, "reported", "")
+	g.handlers.store.mu.Unlock()
+	return &shieldpb.ReportEventResponse{Ok: true}, nil
+}
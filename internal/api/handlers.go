@@ -2,13 +2,29 @@ package api
 
 import (
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/prometheus/common/expfmt"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
+	"github.com/Extra-Chill/plasma-shield/internal/api/filter"
 	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+	"github.com/Extra-Chill/plasma-shield/internal/logs"
+	"github.com/Extra-Chill/plasma-shield/internal/matcher"
+	"github.com/Extra-Chill/plasma-shield/internal/mitmca"
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
 )
 
 // Store holds the in-memory state for the shield.
@@ -17,21 +33,127 @@ type Store struct {
 	mu            sync.RWMutex
 	agents        map[string]*Agent
 	rules         map[string]*Rule
+	tenants       map[string]*Tenant
+	tokens        map[string]*Token
+	apiTokens     map[string]*APIToken
 	logs          []LogEntry
 	bastionLogs   *bastion.LogStore
+	bastionCA     *bastion.CertificateAuthority
+	bastionGrants *bastion.GrantStore
+	provisioners  map[string]bastion.Provisioner
+	// bastionRecordingDir is the directory bastion.FileRecorder writes
+	// session recordings to, wired via SetBastionRecordingDir. Empty
+	// means no recordings are available to serve.
+	bastionRecordingDir string
+
+	// locked and lockedBastionCAPath back the encrypted-at-rest bastion CA
+	// key flow (see bastion/keystore): SetLockedBastionCA sets locked and
+	// records the CA key path, and UnlockHandler clears locked once it
+	// successfully decrypts that key. While locked, Server rejects every
+	// request except /health, /healthz/rules, /unlock, and /unlock/rotate.
+	locked              bool
+	lockedBastionCAPath string
+
+	agentCA       *agentca.AgentCA
+	enrollments   *agentca.EnrollmentStore
+	mitmCA        *mitmca.CA
+	persist       storage.Store
 	startedAt     time.Time
 	requestsTotal int64
 	blockedTotal  int64
+
+	// rulesReloadedAt/rulesLoadErr track the last RulesLoader.Load attempt
+	// against a configured rules file, for StatusHandler and
+	// HealthzRulesHandler. Both zero/empty if no rules file is configured.
+	rulesReloadedAt time.Time
+	rulesLoadErr    string
+
+	// agentJWTKey and agentTokenGen back the agent session JWT flow (see
+	// agentjwt.go): agentJWTKey is nil unless SetAgentJWTKey was called,
+	// and agentTokenGen tracks each agent's current token generation, so
+	// RevokeAgentTokenHandler can invalidate every token issued before a
+	// revoke just by bumping the counter ExecCheckHandler compares against.
+	agentJWTKey   *AgentJWTKey
+	agentTokenGen map[string]int
+
+	// Audit log state, guarded by its own mutex rather than mu: admin
+	// handlers record an entry after already releasing mu, and verification
+	// reads don't need to block on unrelated agent/rule mutations.
+	auditMu       sync.Mutex
+	auditLog      []AuditEntry
+	auditLastHash string
+
+	// Fan-out state for GET /agents/stream and GET /exec/events, guarded by
+	// its own mutex rather than mu: the agent status handlers and
+	// recordExecEvent already hold mu (or their own lock) when they publish,
+	// and subscribe/unsubscribe from an SSE handler shouldn't block on
+	// unrelated agent/rule mutations.
+	streamMu         sync.Mutex
+	agentSubscribers map[int]chan AgentEvent
+	execSubscribers  map[int]chan ExecDecisionEvent
+	execEvents       []ExecDecisionEvent
+	nextSubscriberID int
+
+	// logBroker backs GET /logs/stream: addLog publishes every LogEntry to
+	// it, and the handler subscribes for the live tail and replays recent
+	// history for ?since=. Capacity is much smaller than store.logs (which
+	// backs GET /logs's full pagination) since it only needs to cover
+	// catch-up for a client reconnecting after a brief gap.
+	logBroker *logs.Broker
+
+	// approvalSubscribers fans newly created pending approvals out to
+	// GET /approvals/stream, guarded by streamMu like agentSubscribers and
+	// execSubscribers above.
+	approvalSubscribers map[int]chan Approval
+
+	// approvalMu guards pendingApprovals and approvalTimeout, separately
+	// from mu: ExecCheckHandler releases mu before calling awaitApproval,
+	// since blocking on a human decision (for up to approvalTimeout) must
+	// never hold up unrelated agent/rule state.
+	approvalMu       sync.Mutex
+	pendingApprovals map[string]*Approval
+	approvalTimeout  time.Duration
+
+	// wsMaxMessageBytes caps a single WebSocket frame written by
+	// BastionSessionsStreamWSHandler/GrantEventsWSHandler, set via
+	// Server.SetWSMaxMessageBytes. Guarded by streamMu like the other
+	// fan-out state above.
+	wsMaxMessageBytes int
+
+	// stats backs GET /stats/rules/{id} and GET /stats/top with an
+	// in-memory bucketed counter instead of scanning logs, guarded by its
+	// own mutex for the same reason approvalMu is separate from mu.
+	stats *statsRing
+
+	// changes backs the ?index=&wait= blocking queries ListAgentsHandler,
+	// ListRulesHandler, and ListLogsHandler support (see blocking.go);
+	// ListBastionSessionsHandler long-polls via bastionLogs' own tail
+	// broker instead, since that already publishes on every new session
+	// event.
+	changes *changeNotifier
 }
 
 // NewStore creates a new in-memory store.
 func NewStore() *Store {
 	return &Store{
-		agents:      make(map[string]*Agent),
-		rules:       make(map[string]*Rule),
-		logs:        make([]LogEntry, 0),
-		bastionLogs: bastion.NewLogStore(bastion.DefaultLogLimit),
-		startedAt:   time.Now(),
+		agents:              make(map[string]*Agent),
+		rules:               make(map[string]*Rule),
+		tenants:             make(map[string]*Tenant),
+		tokens:              make(map[string]*Token),
+		apiTokens:           make(map[string]*APIToken),
+		logs:                make([]LogEntry, 0),
+		bastionLogs:         bastion.NewLogStore(bastion.DefaultLogLimit),
+		startedAt:           time.Now(),
+		agentSubscribers:    make(map[int]chan AgentEvent),
+		execSubscribers:     make(map[int]chan ExecDecisionEvent),
+		approvalSubscribers: make(map[int]chan Approval),
+		pendingApprovals:    make(map[string]*Approval),
+		approvalTimeout:     defaultApprovalTimeout,
+		wsMaxMessageBytes:   defaultWSMaxMessageBytes,
+		agentTokenGen:       make(map[string]int),
+		logBroker:           logs.NewBroker(logBrokerCapacity),
+		stats:               newStatsRing(),
+		changes:             newChangeNotifier(),
 	}
 }
 
@@ -39,6 +161,12 @@ func NewStore() *Store {
 type Handlers struct {
 	store   *Store
 	version string
+	metrics *execMetrics
+
+	// recoveryHandler is Recovery's optional alerting hook, set via
+	// SetRecoveryHandler. Not guarded by a mutex: it's wired once at
+	// startup, before the server is serving traffic, like metrics above.
+	recoveryHandler RecoveryHandlerFunc
 }
 
 // NewHandlers creates a new Handlers instance.
@@ -46,6 +174,7 @@ func NewHandlers(store *Store, version string) *Handlers {
 	return &Handlers{
 		store:   store,
 		version: version,
+		metrics: newExecMetrics(),
 	}
 }
 
@@ -61,38 +190,119 @@ func (h *Handlers) StatusHandler(w http.ResponseWriter, r *http.Request) {
 
 	uptime := time.Since(h.store.startedAt)
 	resp := StatusResponse{
-		Status:        "operational",
-		Version:       h.version,
-		Uptime:        uptime.Round(time.Second).String(),
-		StartedAt:     h.store.startedAt,
-		AgentCount:    len(h.store.agents),
-		RuleCount:     len(h.store.rules),
-		RequestsTotal: h.store.requestsTotal,
-		BlockedTotal:  h.store.blockedTotal,
+		Status:          "operational",
+		Version:         h.version,
+		Uptime:          uptime.Round(time.Second).String(),
+		StartedAt:       h.store.startedAt,
+		AgentCount:      len(h.store.agents),
+		RuleCount:       len(h.store.rules),
+		RequestsTotal:   h.store.requestsTotal,
+		BlockedTotal:    h.store.blockedTotal,
+		RulesReloadedAt: h.store.rulesReloadedAt,
 	}
 
-	writeJSON(w, http.StatusOK, resp)
+	respondNegotiated(w, r, resp,
+		negotiatedEncoder{contentType: "text/plain; version=0.0.4", render: renderStatusPrometheus},
+		negotiatedEncoder{contentType: "text/plain", render: renderStatusText},
+	)
 }
 
-// ListAgentsHandler handles GET /agents.
-func (h *Handlers) ListAgentsHandler(w http.ResponseWriter, r *http.Request) {
+// MetricsHandler handles GET /metrics. It leads with the same agent/rule
+// count samples as StatusHandler's Accept: text/plain; version=0.0.4 form,
+// for scrapers that expect a dedicated metrics path rather than content
+// negotiation on /status, then appends the richer series tracked in
+// execMetrics: per-agent/per-rule exec check counters, plasma_requests_total/
+// plasma_blocked_total (replacing Store.requestsTotal/blockedTotal with real
+// Counters so a scrape never blocks on Store.mu), plasma_agents/plasma_rules
+// gauge vectors, and shield_panics_total.
+func (h *Handlers) MetricsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
 	h.store.mu.RLock()
-	defer h.store.mu.RUnlock()
+	resp := StatusResponse{
+		Status:          "operational",
+		Version:         h.version,
+		Uptime:          time.Since(h.store.startedAt).Round(time.Second).String(),
+		StartedAt:       h.store.startedAt,
+		AgentCount:      len(h.store.agents),
+		RuleCount:       len(h.store.rules),
+		RequestsTotal:   h.store.requestsTotal,
+		BlockedTotal:    h.store.blockedTotal,
+		RulesReloadedAt: h.store.rulesReloadedAt,
+	}
+	h.metrics.refreshGauges(h.store)
+	h.store.mu.RUnlock()
 
-	agents := make([]Agent, 0, len(h.store.agents))
-	for _, a := range h.store.agents {
-		agents = append(agents, *a)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	renderCountsPrometheus(w, resp)
+
+	mfs, err := h.metrics.registry.Gather()
+	if err != nil {
+		log.Printf("gather exec metrics: %v", err)
+		return
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			log.Printf("encode exec metrics: %v", err)
+			return
+		}
 	}
+}
 
-	writeJSON(w, http.StatusOK, AgentListResponse{
-		Agents: agents,
-		Total:  len(agents),
-	})
+// ListAgentsHandler handles GET /agents. Results can be narrowed with
+// ?filter=<expr> (see internal/api/filter), and callers can long-poll for
+// the next change via ?index=<hash>&wait=<duration> (a Consul-style
+// blocking query, see changeNotifier.waitForChange) instead of
+// tight-polling; the response's content hash is always echoed as
+// X-Plasma-Index.
+func (h *Handlers) ListAgentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	compute := func() (AgentListResponse, string) {
+		h.store.mu.RLock()
+		defer h.store.mu.RUnlock()
+
+		agents := make([]Agent, 0, len(h.store.agents))
+		for _, a := range h.store.agents {
+			if expr != nil && !expr.Match(*a) {
+				continue
+			}
+			agents = append(agents, *a)
+		}
+		sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+
+		resp := AgentListResponse{Agents: agents, Total: len(agents)}
+		return resp, hashJSON(resp)
+	}
+
+	resp, hash := compute()
+	if index := r.URL.Query().Get("index"); index != "" {
+		wait, ok := parseWait(w, r)
+		if !ok {
+			return
+		}
+		hash = h.store.changes.waitForChange(r.Context(), "agents", index, wait, func() string {
+			_, h := compute()
+			return h
+		})
+		resp, _ = compute()
+	}
+
+	w.Header().Set("X-Plasma-Index", hash)
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // PauseAgentHandler handles POST /agents/{id}/pause.
@@ -118,6 +328,9 @@ func (h *Handlers) PauseAgentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	agent.Status = "paused"
+	h.persistAgentLocked(agentID)
+	h.store.publishAgentEvent(AgentEvent{AgentID: agentID, Status: "paused", Timestamp: time.Now()})
+	h.store.changes.notify("agents")
 
 	writeJSON(w, http.StatusOK, AgentActionResponse{
 		ID:      agentID,
@@ -149,6 +362,9 @@ func (h *Handlers) KillAgentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	agent.Status = "killed"
+	h.persistAgentLocked(agentID)
+	h.store.publishAgentEvent(AgentEvent{AgentID: agentID, Status: "killed", Timestamp: time.Now()})
+	h.store.changes.notify("agents")
 
 	// In production, this would trigger an alert system
 	writeJSON(w, http.StatusOK, AgentActionResponse{
@@ -186,6 +402,9 @@ func (h *Handlers) ResumeAgentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	agent.Status = "active"
+	h.persistAgentLocked(agentID)
+	h.store.publishAgentEvent(AgentEvent{AgentID: agentID, Status: "active", Timestamp: time.Now()})
+	h.store.changes.notify("agents")
 
 	writeJSON(w, http.StatusOK, AgentActionResponse{
 		ID:      agentID,
@@ -194,25 +413,55 @@ func (h *Handlers) ResumeAgentHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListRulesHandler handles GET /rules.
+// ListRulesHandler handles GET /rules. Results can be narrowed with
+// ?filter=<expr> (see internal/api/filter), and callers can long-poll for
+// the next change via ?index=<hash>&wait=<duration>, the same
+// blocking-query mechanism ListAgentsHandler supports (see
+// changeNotifier.waitForChange); cmd/proxy's standalone rulesHandler is
+// the equivalent for that binary's rules.Engine.
 func (h *Handlers) ListRulesHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	h.store.mu.RLock()
-	defer h.store.mu.RUnlock()
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
 
-	rules := make([]Rule, 0, len(h.store.rules))
-	for _, rule := range h.store.rules {
-		rules = append(rules, *rule)
+	compute := func() (RuleListResponse, string) {
+		h.store.mu.RLock()
+		defer h.store.mu.RUnlock()
+
+		rules := make([]Rule, 0, len(h.store.rules))
+		for _, rule := range h.store.rules {
+			if expr != nil && !expr.Match(*rule) {
+				continue
+			}
+			rules = append(rules, *rule)
+		}
+		sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+		resp := RuleListResponse{Rules: rules, Total: len(rules)}
+		return resp, hashJSON(resp)
 	}
 
-	writeJSON(w, http.StatusOK, RuleListResponse{
-		Rules: rules,
-		Total: len(rules),
-	})
+	resp, hash := compute()
+	if index := r.URL.Query().Get("index"); index != "" {
+		wait, ok := parseWait(w, r)
+		if !ok {
+			return
+		}
+		hash = h.store.changes.waitForChange(r.Context(), "rules", index, wait, func() string {
+			_, h := compute()
+			return h
+		})
+		resp, _ = compute()
+	}
+
+	w.Header().Set("X-Plasma-Index", hash)
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // CreateRuleHandler handles POST /rules.
@@ -228,38 +477,218 @@ func (h *Handlers) CreateRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Action != "block" && req.Action != "allow" {
-		writeError(w, http.StatusBadRequest, "action must be 'block' or 'allow'")
+	rule, err := buildRule(req, generateID(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if req.Pattern == "" && req.Domain == "" {
-		writeError(w, http.StatusBadRequest, "pattern or domain is required")
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	h.store.rules[rule.ID] = rule
+	h.persistFullRuleLocked(rule.ID)
+	h.store.changes.notify("rules")
+
+	writeJSON(w, http.StatusCreated, CreateRuleResponse{
+		Rule:    *rule,
+		Message: "rule created successfully",
+	})
+}
+
+// persistFullRuleLocked mirrors the in-memory rule identified by id to
+// store.persist, if configured, including MatchType and Priority. Unlike
+// admin.go's persistRuleLocked, which predates those fields and is used
+// only by handlers that never set them, this is for handlers that build
+// their rules through buildRule. Caller must hold store.mu.
+func (h *Handlers) persistFullRuleLocked(id string) {
+	if h.store.persist == nil {
+		return
+	}
+	rule, ok := h.store.rules[id]
+	if !ok {
 		return
 	}
+	if err := h.store.persist.UpsertRule(storage.Rule{
+		ID:          rule.ID,
+		Pattern:     rule.Pattern,
+		Domain:      rule.Domain,
+		MatchType:   rule.MatchType,
+		Priority:    rule.Priority,
+		Action:      rule.Action,
+		Description: rule.Description,
+		Enabled:     rule.Enabled,
+		CreatedAt:   rule.CreatedAt,
+	}); err != nil {
+		log.Printf("storage: persist rule %q: %v", id, err)
+	}
+}
 
-	h.store.mu.Lock()
-	defer h.store.mu.Unlock()
+// buildRule validates req the same way CreateRuleHandler always has and
+// returns a compiled Rule ready to insert into the store, with id and
+// createdAt assigned by the caller rather than generateID/time.Now(), so
+// CreateRulesBulkHandler can validate an entire batch before committing
+// any of it, and ExecCheckDryRunHandler can build an ad hoc rule set that
+// never touches the store at all.
+func buildRule(req CreateRuleRequest, id string, createdAt time.Time) (*Rule, error) {
+	if req.Action != "block" && req.Action != "allow" && req.Action != "review" {
+		return nil, fmt.Errorf("action must be 'block', 'allow', or 'review'")
+	}
+	if req.Pattern == "" && req.Domain == "" {
+		return nil, fmt.Errorf("pattern or domain is required")
+	}
+	switch matcher.Type(req.MatchType) {
+	case "", matcher.Substring, matcher.Glob, matcher.Regex, matcher.CEL, matcher.Expr:
+	default:
+		return nil, fmt.Errorf("match_type must be one of substring, glob, regex, cel, expr, got %q", req.MatchType)
+	}
 
-	id := generateID()
 	rule := &Rule{
 		ID:          id,
 		Pattern:     req.Pattern,
 		Domain:      req.Domain,
+		MatchType:   req.MatchType,
+		Priority:    req.Priority,
 		Action:      req.Action,
 		Description: req.Description,
 		Enabled:     req.Enabled,
-		CreatedAt:   time.Now(),
+		CreatedAt:   createdAt,
+	}
+	if err := compileRuleMatcher(rule); err != nil {
+		return nil, err
 	}
+	return rule, nil
+}
 
-	h.store.rules[id] = rule
+// compileRuleMatcher compiles rule.Pattern under rule.MatchType and caches
+// the result on rule.compiled, so ExecCheckHandler never recompiles a
+// regex or CEL program on the hot path. A rule with no Pattern (e.g. a
+// domain-only rule) is left uncompiled.
+func compileRuleMatcher(rule *Rule) error {
+	if rule.Pattern == "" {
+		return nil
+	}
+	compiled, err := matcher.Compile(matcher.Type(rule.MatchType), rule.Pattern)
+	if err != nil {
+		return err
+	}
+	rule.compiled = compiled
+	return nil
+}
 
-	writeJSON(w, http.StatusCreated, CreateRuleResponse{
-		Rule:    *rule,
-		Message: "rule created successfully",
+// CreateRulesBulkHandler handles POST /rules/bulk. The body is a JSON or
+// YAML array of CreateRuleRequest (YAML if Content-Type names it,
+// otherwise JSON). Every rule is validated up front; if any of them is
+// invalid, none are added, so a single bad rule in the batch leaves the
+// store untouched.
+func (h *Handlers) CreateRulesBulkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var reqs []CreateRuleRequest
+	if isYAMLContentType(r.Header.Get("Content-Type")) {
+		if err := yaml.Unmarshal(data, &reqs); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid YAML body: "+err.Error())
+			return
+		}
+	} else if err := json.Unmarshal(data, &reqs); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	if len(reqs) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one rule is required")
+		return
+	}
+
+	now := time.Now()
+	rules := make([]*Rule, len(reqs))
+	for i, req := range reqs {
+		rule, err := buildRule(req, generateID(), now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("rule %d: %v", i, err))
+			return
+		}
+		rules[i] = rule
+	}
+
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	created := make([]Rule, len(rules))
+	for i, rule := range rules {
+		h.store.rules[rule.ID] = rule
+		h.persistFullRuleLocked(rule.ID)
+		created[i] = *rule
+	}
+	h.store.changes.notify("rules")
+
+	writeJSON(w, http.StatusCreated, BulkCreateRulesResponse{
+		Rules:   created,
+		Message: fmt.Sprintf("%d rules created successfully", len(created)),
 	})
 }
 
+// ExportRulesHandler handles GET /rules/export. It dumps every configured
+// rule as a YAML array of CreateRuleRequest, the same shape
+// POST /rules/bulk accepts, so rules can be checked into a GitOps repo
+// and reapplied from there. Rules are sorted by ID for a stable diff
+// between exports.
+func (h *Handlers) ExportRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	rules := make([]*Rule, 0, len(h.store.rules))
+	for _, rule := range h.store.rules {
+		rules = append(rules, rule)
+	}
+	h.store.mu.RUnlock()
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	reqs := make([]CreateRuleRequest, len(rules))
+	for i, rule := range rules {
+		reqs[i] = CreateRuleRequest{
+			Pattern:     rule.Pattern,
+			Domain:      rule.Domain,
+			MatchType:   rule.MatchType,
+			Priority:    rule.Priority,
+			Action:      rule.Action,
+			Description: rule.Description,
+			Enabled:     rule.Enabled,
+		}
+	}
+
+	data, err := yaml.Marshal(reqs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "marshal rules: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// isYAMLContentType reports whether contentType names a YAML media type,
+// e.g. "application/yaml" or "text/yaml; charset=utf-8".
+func isYAMLContentType(contentType string) bool {
+	mediaType := strings.SplitN(contentType, ";", 2)[0]
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasSuffix(mediaType, "/yaml") || strings.HasSuffix(mediaType, "/x-yaml")
+}
+
 // DeleteRuleHandler handles DELETE /rules/{id}.
 func (h *Handlers) DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -282,6 +711,12 @@ func (h *Handlers) DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	delete(h.store.rules, ruleID)
+	if h.store.persist != nil {
+		if err := h.store.persist.DeleteRule(ruleID); err != nil {
+			log.Printf("storage: delete rule %q: %v", ruleID, err)
+		}
+	}
+	h.store.changes.notify("rules")
 
 	writeJSON(w, http.StatusOK, DeleteRuleResponse{
 		ID:      ruleID,
@@ -289,7 +724,38 @@ func (h *Handlers) DeleteRuleHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// ListLogsHandler handles GET /logs.
+// HealthzRulesHandler handles GET /healthz/rules, reporting whether the
+// rules file wired via Server.SetRulesFile (if any) is loading cleanly.
+// It returns 200 with the last successful reload time, or 503 with the
+// error from the most recent failed reload -- the store keeps serving the
+// ruleset from the last successful one either way.
+func (h *Handlers) HealthzRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	resp := RulesHealthResponse{
+		ReloadedAt: h.store.rulesReloadedAt,
+		Error:      h.store.rulesLoadErr,
+	}
+	h.store.mu.RUnlock()
+
+	status := http.StatusOK
+	if resp.Error != "" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
+
+// ListLogsHandler handles GET /logs. The agent_id/action/type query params
+// are shortcuts for the common cases; ?filter=<expr> (see internal/api/filter)
+// covers everything else, e.g. `filter=Action == "blocked" and AgentID
+// matches "prod-.*"`. Callers can long-poll for the next change via
+// ?index=<hash>&wait=<duration>, the same blocking-query mechanism
+// ListAgentsHandler/ListRulesHandler support (see
+// changeNotifier.waitForChange).
 func (h *Handlers) ListLogsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -317,44 +783,78 @@ func (h *Handlers) ListLogsHandler(w http.ResponseWriter, r *http.Request) {
 	actionFilter := query.Get("action")
 	typeFilter := query.Get("type")
 
-	h.store.mu.RLock()
-	defer h.store.mu.RUnlock()
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
 
-	// Filter logs
-	filtered := make([]LogEntry, 0)
-	for _, log := range h.store.logs {
-		if agentFilter != "" && log.AgentID != agentFilter {
-			continue
+	compute := func() (LogListResponse, string) {
+		h.store.mu.RLock()
+		defer h.store.mu.RUnlock()
+
+		// Filter logs
+		filtered := make([]LogEntry, 0)
+		for _, log := range h.store.logs {
+			if agentFilter != "" && log.AgentID != agentFilter {
+				continue
+			}
+			if actionFilter != "" && log.Action != actionFilter {
+				continue
+			}
+			if typeFilter != "" && log.Type != typeFilter {
+				continue
+			}
+			if expr != nil && !expr.Match(log) {
+				continue
+			}
+			filtered = append(filtered, log)
 		}
-		if actionFilter != "" && log.Action != actionFilter {
-			continue
+
+		// Apply pagination
+		total := len(filtered)
+		start := offset
+		if start > total {
+			start = total
 		}
-		if typeFilter != "" && log.Type != typeFilter {
-			continue
+		end := start + limit
+		if end > total {
+			end = total
 		}
-		filtered = append(filtered, log)
-	}
 
-	// Apply pagination
-	total := len(filtered)
-	start := offset
-	if start > total {
-		start = total
+		resp := LogListResponse{
+			Logs:   filtered[start:end],
+			Total:  total,
+			Offset: offset,
+			Limit:  limit,
+		}
+		return resp, hashJSON(resp)
 	}
-	end := start + limit
-	if end > total {
-		end = total
+
+	resp, hash := compute()
+	if index := query.Get("index"); index != "" {
+		wait, ok := parseWait(w, r)
+		if !ok {
+			return
+		}
+		hash = h.store.changes.waitForChange(r.Context(), "logs", index, wait, func() string {
+			_, h := compute()
+			return h
+		})
+		resp, _ = compute()
 	}
 
-	writeJSON(w, http.StatusOK, LogListResponse{
-		Logs:   filtered[start:end],
-		Total:  total,
-		Offset: offset,
-		Limit:  limit,
-	})
+	w.Header().Set("X-Plasma-Index", hash)
+	respondNegotiated(w, r, resp, negotiatedEncoder{contentType: "text/csv", render: renderLogsCSV})
 }
 
-// ListBastionSessionsHandler handles GET /bastion/sessions.
+// ListBastionSessionsHandler handles GET /bastion/sessions. Results can be
+// narrowed with ?filter=<expr> (see internal/api/filter), e.g. `filter=
+// Principal == "alice" and Event == "connect"`. Callers can long-poll for
+// the next change via ?index=<hash>&wait=<duration>, the same
+// blocking-query contract as the other List* handlers, but riding
+// bastionLogs' own tail broker (see waitForBastionChange) rather than
+// changeNotifier since bastion.LogStore already publishes one for GET
+// /bastion/sessions/{id}/tail.
 func (h *Handlers) ListBastionSessionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
@@ -377,109 +877,1367 @@ func (h *Handlers) ListBastionSessionsHandler(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	events, total := h.store.bastionLogs.List(offset, limit)
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
 
-	writeJSON(w, http.StatusOK, BastionSessionListResponse{
-		Sessions: events,
-		Total:    total,
-		Offset:   offset,
-		Limit:    limit,
-	})
+	compute := func() (BastionSessionListResponse, string) {
+		var events []bastion.SessionEvent
+		var total int
+		if expr == nil {
+			events, total = h.store.bastionLogs.List(offset, limit)
+		} else {
+			// A filter narrows the result set before pagination is applied, so
+			// offset/limit walk the filtered events rather than the full log.
+			all, _ := h.store.bastionLogs.List(0, 0)
+			filtered := make([]bastion.SessionEvent, 0, len(all))
+			for _, event := range all {
+				if expr.Match(event) {
+					filtered = append(filtered, event)
+				}
+			}
+			total = len(filtered)
+			start := offset
+			if start > total {
+				start = total
+			}
+			end := start + limit
+			if end > total {
+				end = total
+			}
+			events = filtered[start:end]
+		}
+
+		h.store.mu.RLock()
+		recordingDir := h.store.bastionRecordingDir
+		h.store.mu.RUnlock()
+
+		var recordings map[string]bastion.RecordingMeta
+		if recordingDir != "" {
+			recordings = make(map[string]bastion.RecordingMeta)
+			for _, event := range events {
+				if _, seen := recordings[event.SessionID]; seen {
+					continue
+				}
+				if meta, err := bastion.ReadMeta(recordingDir, event.SessionID); err == nil {
+					recordings[event.SessionID] = meta
+				}
+			}
+		}
+
+		resp := BastionSessionListResponse{
+			Sessions:   events,
+			Total:      total,
+			Offset:     offset,
+			Limit:      limit,
+			Recordings: recordings,
+		}
+		return resp, hashJSON(resp)
+	}
+
+	resp, hash := compute()
+	if index := query.Get("index"); index != "" {
+		wait, ok := parseWait(w, r)
+		if !ok {
+			return
+		}
+		hash = waitForBastionChange(r.Context(), h.store.bastionLogs, index, wait, func() string {
+			_, h := compute()
+			return h
+		})
+		resp, _ = compute()
+	}
+
+	w.Header().Set("X-Plasma-Index", hash)
+	writeJSON(w, http.StatusOK, resp)
 }
 
-// ExecCheckHandler handles POST /exec/check.
-func (h *Handlers) ExecCheckHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// GetBastionSessionStreamHandler handles GET /bastion/sessions/{id}/stream.
+// It serves the session's raw .stream file verbatim, framed per
+// bastion.ReadFrame, for callers that want to decode frames themselves.
+func (h *Handlers) GetBastionSessionStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
-	var req ExecCheckRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid request body")
+	sessionID := extractAgentID(r.URL.Path, "/bastion/sessions/", "/stream")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
 		return
 	}
 
-	if req.Command == "" {
-		writeError(w, http.StatusBadRequest, "command is required")
+	h.store.mu.RLock()
+	recordingDir := h.store.bastionRecordingDir
+	h.store.mu.RUnlock()
+	if recordingDir == "" {
+		writeError(w, http.StatusServiceUnavailable, "bastion session recording not configured")
 		return
 	}
 
-	h.store.mu.Lock()
-	defer h.store.mu.Unlock()
+	file, err := bastion.OpenStream(recordingDir, sessionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no recording for session "+sessionID)
+		return
+	}
+	defer file.Close()
 
-	h.store.requestsTotal++
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
 
-	// Check agent status
-	if agent, exists := h.store.agents[req.AgentID]; exists {
-		if agent.Status == "paused" || agent.Status == "killed" {
-			h.store.blockedTotal++
-			h.addLog(req.AgentID, "command", req.Command, "blocked", "agent-status")
-			writeJSON(w, http.StatusOK, ExecCheckResponse{
-				Allowed: false,
-				Reason:  "agent is " + agent.Status,
-			})
-			return
-		}
-		agent.LastSeen = time.Now()
+// GetBastionSessionReplayHandler handles GET /bastion/sessions/{id}/replay.
+// It decodes the session's recorded frames into BastionSessionReplayFrame
+// entries, alongside the session's meta.json, so a caller can pretty-print
+// or replay the session without itself speaking the binary frame format.
+func (h *Handlers) GetBastionSessionReplayHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
 
-	// Check rules
-	for _, rule := range h.store.rules {
-		if !rule.Enabled {
-			continue
+	sessionID := extractAgentID(r.URL.Path, "/bastion/sessions/", "/replay")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
+	}
+
+	h.store.mu.RLock()
+	recordingDir := h.store.bastionRecordingDir
+	h.store.mu.RUnlock()
+	if recordingDir == "" {
+		writeError(w, http.StatusServiceUnavailable, "bastion session recording not configured")
+		return
+	}
+
+	meta, err := bastion.ReadMeta(recordingDir, sessionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no recording for session "+sessionID)
+		return
+	}
+
+	file, err := bastion.OpenStream(recordingDir, sessionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no recording for session "+sessionID)
+		return
+	}
+	defer file.Close()
+
+	var frames []BastionSessionReplayFrame
+	for {
+		offsetMS, dir, payload, err := bastion.ReadFrame(file)
+		if err == io.EOF {
+			break
 		}
-		if rule.Pattern != "" && matchPattern(req.Command, rule.Pattern) {
-			if rule.Action == "block" {
-				h.store.blockedTotal++
-				h.addLog(req.AgentID, "command", req.Command, "blocked", rule.ID)
-				writeJSON(w, http.StatusOK, ExecCheckResponse{
-					Allowed: false,
-					Reason:  rule.Description,
-					RuleID:  rule.ID,
-				})
-				return
-			}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "decode recording: "+err.Error())
+			return
 		}
+		direction := "client"
+		if dir == bastion.FrameServer {
+			direction = "server"
+		}
+		frames = append(frames, BastionSessionReplayFrame{
+			OffsetMS:  offsetMS,
+			Direction: direction,
+			Data:      payload,
+		})
 	}
 
-	h.addLog(req.AgentID, "command", req.Command, "allowed", "")
-	writeJSON(w, http.StatusOK, ExecCheckResponse{
-		Allowed: true,
+	writeJSON(w, http.StatusOK, BastionSessionReplayResponse{
+		Meta:   meta,
+		Frames: frames,
 	})
 }
 
-// addLog adds a log entry to the store (must be called with lock held).
-func (h *Handlers) addLog(agentID, logType, request, action, ruleID string) {
-	entry := LogEntry{
-		ID:        generateID(),
-		Timestamp: time.Now(),
-		AgentID:   agentID,
-		Type:      logType,
-		Request:   request,
-		Action:    action,
-		RuleID:    ruleID,
+// GetBastionSessionRecordingHandler handles GET /bastion/sessions/{id}/recording.
+// It serves the session's asciicast v2 recording (see
+// bastion.WriteAsciicast), gzip-compressed the way bastion.exportAsciicast
+// wrote it, looked up from the most recent SessionEventRecordingAvailable
+// event logged for the session rather than from bastionRecordingDir
+// directly, since asciicast files live in their own dated shard.
+func (h *Handlers) GetBastionSessionRecordingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
 	}
-	h.store.logs = append(h.store.logs, entry)
 
-	// Keep only last 10000 logs
-	if len(h.store.logs) > 10000 {
-		h.store.logs = h.store.logs[len(h.store.logs)-10000:]
+	sessionID := extractAgentID(r.URL.Path, "/bastion/sessions/", "/recording")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
 	}
-}
 
-// RegisterAgent registers a new agent (for testing/setup).
-func (h *Handlers) RegisterAgent(id, name, ip string) {
-	h.store.mu.Lock()
-	defer h.store.mu.Unlock()
+	path, ok := h.store.bastionLogs.FindRecordingPath(sessionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no asciicast recording for session "+sessionID)
+		return
+	}
 
-	h.store.agents[id] = &Agent{
-		ID:        id,
-		Name:      name,
-		IP:        ip,
-		Status:    "active",
-		LastSeen:  time.Now(),
-		CreatedAt: time.Now(),
+	file, err := os.Open(path)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "no asciicast recording for session "+sessionID)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+const defaultHostCertTTL = 30 * 24 * time.Hour
+
+// IssueHostCertificateHandler handles POST /bastion/hosts/{id}/host-cert.
+// Operators enroll a target/agent's SSH host public key and receive back a
+// CA-signed host certificate, so bastion sessions can verify the target's
+// host key against the CA instead of trust-on-first-use.
+func (h *Handlers) IssueHostCertificateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	hostID := extractAgentID(r.URL.Path, "/bastion/hosts/", "/host-cert")
+	if hostID == "" {
+		writeError(w, http.StatusBadRequest, "missing host ID")
+		return
+	}
+
+	if h.store.bastionCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "bastion CA not configured")
+		return
+	}
+
+	var req IssueHostCertificateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.PublicKey == "" {
+		writeError(w, http.StatusBadRequest, "public_key is required")
+		return
+	}
+	if len(req.Hostnames) == 0 {
+		writeError(w, http.StatusBadRequest, "hostnames is required")
+		return
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid public_key: "+err.Error())
+		return
+	}
+
+	ttl := defaultHostCertTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	cert, err := h.store.bastionCA.IssueHostCertificate(publicKey, req.Hostnames, ttl)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "issue host certificate: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, IssueHostCertificateResponse{
+		HostID:      hostID,
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0).UTC(),
+		Message:     "host certificate issued successfully",
+	})
+}
+
+// DeleteBastionGrantHandler handles DELETE /bastion/grants/{id}. Deleting a
+// grant also revokes any certificate already issued under it: the grant ID
+// is a certificate's KeyId, so adding it to the CA's revocation store is
+// enough to reject those certs even though they haven't expired yet.
+func (h *Handlers) DeleteBastionGrantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	grantID := strings.TrimPrefix(r.URL.Path, "/bastion/grants/")
+	if grantID == "" || grantID == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing grant ID")
+		return
+	}
+
+	if h.store.bastionCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "bastion CA not configured")
+		return
+	}
+
+	if err := h.store.bastionCA.RevokeGrant(grantID, "grant deleted"); err != nil {
+		writeError(w, http.StatusInternalServerError, "revoke grant: "+err.Error())
+		return
+	}
+
+	if h.store.bastionGrants != nil {
+		h.store.bastionGrants.Delete(grantID)
+	}
+
+	writeJSON(w, http.StatusOK, DeleteBastionGrantResponse{
+		ID:      grantID,
+		Message: "grant revoked",
+	})
+}
+
+// GetBastionKRLHandler handles GET /bastion/krl. Downstream sshd instances
+// can fetch this periodically and point their RevokedKeys directive at the
+// saved file, so they honor bastion revocations without querying the CA
+// for every connection.
+func (h *Handlers) GetBastionKRLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.bastionCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "bastion CA not configured")
+		return
+	}
+
+	krl, err := h.store.bastionCA.GenerateKRL()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "generate KRL: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	w.Write(krl)
+}
+
+// CreateBastionGrantHandler handles POST /bastion/grants. It dispatches
+// req.Credential to the named provisioner, which verifies it and, on
+// success, creates the grant. If req.PublicKey is also set and a bastion
+// CA is configured, the response includes a certificate signed for that
+// grant, saving the caller a separate round trip.
+func (h *Handlers) CreateBastionGrantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateBastionGrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Provisioner == "" {
+		writeError(w, http.StatusBadRequest, "provisioner is required")
+		return
+	}
+	if req.Credential == "" {
+		writeError(w, http.StatusBadRequest, "credential is required")
+		return
+	}
+	if req.Target == "" {
+		writeError(w, http.StatusBadRequest, "target is required")
+		return
+	}
+
+	h.store.mu.RLock()
+	provisioner, ok := h.store.provisioners[req.Provisioner]
+	h.store.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "no such provisioner: "+req.Provisioner)
+		return
+	}
+
+	grant, err := provisioner.AuthorizeGrant(r.Context(), bastion.GrantRequest{
+		Credential: req.Credential,
+		Target:     req.Target,
+		Duration:   time.Duration(req.TTLSeconds) * time.Second,
+		Record:     req.Record,
+		Shell:      req.Shell,
+	})
+	if err != nil {
+		writeError(w, http.StatusForbidden, "authorize grant: "+err.Error())
+		return
+	}
+
+	resp := CreateBastionGrantResponse{Grant: grant}
+
+	if req.PublicKey != "" {
+		if h.store.bastionCA == nil {
+			writeError(w, http.StatusServiceUnavailable, "bastion CA not configured")
+			return
+		}
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid public_key: "+err.Error())
+			return
+		}
+		cert, err := h.store.bastionCA.IssueUserCertificate(publicKey, grant)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "issue user certificate: "+err.Error())
+			return
+		}
+		resp.Certificate = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert)))
+		validBefore := time.Unix(int64(cert.ValidBefore), 0).UTC()
+		resp.ValidBefore = &validBefore
+	}
+
+	// grant.CreatedBy already records the provisioner-verified credential's
+	// identity (see bastion.GrantRequest); the audit log additionally
+	// records the management API principal that invoked this endpoint,
+	// which may be a different, coarser identity (e.g. a service token
+	// used by several provisioners).
+	h.recordAudit(actorFromContext(r.Context()), "create", "bastion_grant", grant.ID, nil, *grant)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+const defaultGrantTTL = time.Hour
+
+// ListGrantsHandler handles GET /grants. Unlike /bastion/grants, which is
+// gated behind a provisioner-verified credential, this lists every grant
+// currently in the bastion's GrantStore -- the same data the SSH bastion
+// itself authorizes sessions against.
+func (h *Handlers) ListGrantsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	if h.store.bastionGrants == nil {
+		writeJSON(w, http.StatusOK, GrantListResponse{Grants: []*bastion.Grant{}, Total: 0})
+		return
+	}
+
+	grants := make([]*bastion.Grant, 0)
+	for _, g := range h.store.bastionGrants.List() {
+		if expr != nil && !expr.Match(*g) {
+			continue
+		}
+		grants = append(grants, g)
+	}
+
+	writeJSON(w, http.StatusOK, GrantListResponse{Grants: grants, Total: len(grants)})
+}
+
+// CreateGrantHandler handles POST /grants, issuing a grant directly rather
+// than through a provisioner -- for management clients that already
+// authenticate via the management token and don't need a second credential
+// verified on top of it.
+func (h *Handlers) CreateGrantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req CreateGrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Principal == "" {
+		writeError(w, http.StatusBadRequest, "principal is required")
+		return
+	}
+	if req.Target == "" {
+		writeError(w, http.StatusBadRequest, "target is required")
+		return
+	}
+	if h.store.bastionGrants == nil {
+		writeError(w, http.StatusServiceUnavailable, "bastion grant store not configured")
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if ttl <= 0 {
+		ttl = defaultGrantTTL
+	}
+	createdBy := req.CreatedBy
+	if createdBy == "" {
+		createdBy = actorFromContext(r.Context())
+	}
+
+	grant := h.store.bastionGrants.AddWithOptions(req.Principal, req.Target, createdBy, ttl, req.Record, req.Shell)
+
+	writeJSON(w, http.StatusCreated, CreateGrantResponse{Grant: grant})
+}
+
+// DeleteGrantHandler handles DELETE /grants/{id}.
+func (h *Handlers) DeleteGrantHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	grantID := strings.TrimPrefix(r.URL.Path, "/grants/")
+	if grantID == "" || grantID == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing grant ID")
+		return
+	}
+
+	if h.store.bastionGrants == nil {
+		writeError(w, http.StatusServiceUnavailable, "bastion grant store not configured")
+		return
+	}
+
+	if !h.store.bastionGrants.Delete(grantID) {
+		writeError(w, http.StatusNotFound, "grant not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, DeleteGrantResponse{ID: grantID, Message: "grant deleted"})
+}
+
+const defaultAgentCertTTL = 24 * time.Hour
+
+// CreateEnrollmentSecretHandler handles POST /agents/enroll-secrets.
+// Operators mint a one-time secret bound to an agent identity; the agent
+// then redeems it at POST /agents/enroll for a client certificate.
+func (h *Handlers) CreateEnrollmentSecretHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.enrollments == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent enrollment not configured")
+		return
+	}
+
+	var req CreateEnrollmentSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.TenantID == "" || req.AgentID == "" {
+		writeError(w, http.StatusBadRequest, "tenant_id and agent_id are required")
+		return
+	}
+
+	ttl := defaultEnrollmentSecretTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	secret, err := h.store.enrollments.Create(req.TenantID, req.AgentID, req.Tier, ttl)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "create enrollment secret: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CreateEnrollmentSecretResponse{
+		Secret:    secret.Secret,
+		ExpiresAt: secret.ExpiresAt,
+	})
+}
+
+const defaultEnrollmentSecretTTL = time.Hour
+
+// EnrollAgentHandler handles POST /agents/enroll. An agent redeems a
+// one-time enrollment secret plus a CSR for a client certificate scoped to
+// the identity the secret was created for; the CSR's own subject/SANs are
+// ignored.
+func (h *Handlers) EnrollAgentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.agentCA == nil || h.store.enrollments == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent enrollment not configured")
+		return
+	}
+
+	var req EnrollAgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Secret == "" || req.CSR == "" {
+		writeError(w, http.StatusBadRequest, "secret and csr are required")
+		return
+	}
+
+	secret, err := h.store.enrollments.Consume(req.Secret)
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	cert, err := h.store.agentCA.IssueCertificate([]byte(req.CSR), secret.TenantID, secret.AgentID, secret.Tier, defaultAgentCertTTL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "issue certificate: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, EnrollAgentResponse{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		ValidBefore: cert.NotAfter,
+	})
+}
+
+// ReenrollAgentHandler handles POST /agents/reenroll. Unlike
+// EnrollAgentHandler, identity comes from the caller's existing client
+// certificate (presented over mTLS), not a one-time secret, so an agent
+// can renew its certificate before it expires without operator
+// involvement.
+func (h *Handlers) ReenrollAgentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.agentCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent CA not configured")
+		return
+	}
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		writeError(w, http.StatusUnauthorized, "client certificate required")
+		return
+	}
+	identity, err := agentca.IdentityFromCertificate(r.TLS.PeerCertificates[0])
+	if err != nil {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	var req ReenrollAgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.CSR == "" {
+		writeError(w, http.StatusBadRequest, "csr is required")
+		return
+	}
+
+	cert, err := h.store.agentCA.IssueCertificate([]byte(req.CSR), identity.TenantID, identity.AgentID, identity.Tier, defaultAgentCertTTL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "issue certificate: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, EnrollAgentResponse{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		ValidBefore: cert.NotAfter,
+	})
+}
+
+// AgentCRLHandler handles GET /agents/crl. Downstream mTLS listeners (or
+// the API server itself) can fetch this periodically and enforce it
+// alongside certificate validation, so revoked agent certs stop working
+// immediately instead of lingering until they expire.
+func (h *Handlers) AgentCRLHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.agentCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent CA not configured")
+		return
+	}
+
+	crl, err := h.store.agentCA.GenerateCRL()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "generate CRL: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	w.WriteHeader(http.StatusOK)
+	w.Write(crl)
+}
+
+// RotateAgentCAHandler handles POST /agents/rotate-ca. It replaces the CA's
+// signing key, archiving the old one, so an operator can recover from a
+// suspected CA key compromise; every enrolled agent must then re-enroll
+// (see ReenrollAgentHandler) before the old CA cert is removed from
+// circulation.
+func (h *Handlers) RotateAgentCAHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.agentCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent CA not configured")
+		return
+	}
+
+	cert, err := h.store.agentCA.Rotate()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "rotate CA: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RotateAgentCAResponse{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		ValidBefore: cert.NotAfter,
+	})
+}
+
+// AgentTrustBundleHandler handles GET /agents/trust-bundle. Like
+// AgentCRLHandler, the document itself is the access control: external
+// verifiers fetch the CA's current public key and certificate to validate
+// agent mTLS connections without depending on this package.
+func (h *Handlers) AgentTrustBundleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.agentCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "agent CA not configured")
+		return
+	}
+
+	bundle, err := h.store.agentCA.TrustBundle()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "build trust bundle: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, bundle)
+}
+
+// MITMCACertHandler handles GET /mitm/ca-cert. Like AgentCRLHandler, the
+// document itself is the access control: an agent (or operator, via
+// cmd/plasma-shield's "ca init") fetches the MITM root certificate and
+// trusts it locally so interception (see proxy.WithMITM) stops tripping
+// its own TLS verification.
+func (h *Handlers) MITMCACertHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.mitmCA == nil {
+		writeError(w, http.StatusServiceUnavailable, "mitm CA not configured")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.store.mitmCA.RootCertPEM())
+}
+
+// ExecCheckHandler handles POST /exec/check.
+func (h *Handlers) ExecCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req ExecCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.Command == "" {
+		writeError(w, http.StatusBadRequest, "command is required")
+		return
+	}
+
+	if key := h.AgentJWTKey(); key != nil {
+		if reason, ok := h.checkAgentToken(*key, r, req.AgentID); !ok {
+			writeError(w, http.StatusUnauthorized, reason)
+			return
+		}
+	}
+
+	start := time.Now()
+
+	h.store.mu.Lock()
+
+	h.store.requestsTotal++
+
+	ctx := matcher.ExecContext{
+		Command: req.Command,
+		Argv:    strings.Fields(req.Command),
+		User:    req.User,
+		Cwd:     req.Cwd,
+		AgentID: req.AgentID,
+		Env:     req.Env,
+	}
+
+	// Check agent status
+	if agent, exists := h.store.agents[req.AgentID]; exists {
+		if agent.Status == "paused" || agent.Status == "killed" {
+			h.store.blockedTotal++
+			h.metrics.observeCheck(req.AgentID, "block", "agent-status", time.Since(start).Seconds())
+			h.addLog(req.AgentID, "command", req.Command, "blocked", "agent-status")
+			h.store.recordExecEvent(ExecDecisionEvent{
+				AgentID:   req.AgentID,
+				Command:   req.Command,
+				Allowed:   false,
+				Timestamp: time.Now(),
+				LatencyMS: time.Since(start).Milliseconds(),
+			})
+			h.store.mu.Unlock()
+			writeJSON(w, http.StatusOK, ExecCheckResponse{
+				Allowed: false,
+				Reason:  "agent is " + agent.Status,
+			})
+			return
+		}
+		agent.LastSeen = time.Now()
+		ctx.AgentIP = agent.IP
+	}
+
+	// Check rules in (priority desc, created_at asc) order: the first rule
+	// whose pattern matches wins outright, whether it allows or blocks, so
+	// an explicit allow at a higher priority short-circuits a lower-priority
+	// block instead of both being evaluated independently.
+	enabled := make([]*Rule, 0, len(h.store.rules))
+	for _, rule := range h.store.rules {
+		if rule.Enabled {
+			enabled = append(enabled, rule)
+		}
+	}
+	sortedEnabledRules(enabled)
+
+	allowed, reason, ruleID := evaluateCommand(enabled, ctx)
+
+	// A "review" rule match doesn't resolve here: it hands off to a human
+	// operator via the pending-approval queue, which can take up to
+	// Store.approvalTimeout to resolve, so mu is released before waiting.
+	if !allowed && ruleID != "" {
+		if rule, ok := h.store.rules[ruleID]; ok && rule.Action == "review" {
+			h.store.mu.Unlock()
+			h.awaitApprovalAndRespond(w, req, ruleID, start)
+			return
+		}
+	}
+
+	h.store.recordExecEvent(ExecDecisionEvent{
+		AgentID:   req.AgentID,
+		Command:   req.Command,
+		RuleID:    ruleID,
+		Allowed:   allowed,
+		Timestamp: time.Now(),
+		LatencyMS: time.Since(start).Milliseconds(),
+	})
+	h.store.stats.record(ruleID, req.AgentID, allowed, time.Now())
+	if allowed {
+		h.metrics.observeCheck(req.AgentID, "allow", "", time.Since(start).Seconds())
+		h.addLog(req.AgentID, "command", req.Command, "allowed", ruleID)
+		h.store.mu.Unlock()
+		writeJSON(w, http.StatusOK, ExecCheckResponse{
+			Allowed: true,
+			RuleID:  ruleID,
+		})
+		return
+	}
+
+	h.store.blockedTotal++
+	h.metrics.observeCheck(req.AgentID, "block", ruleID, time.Since(start).Seconds())
+	h.addLog(req.AgentID, "command", req.Command, "blocked", ruleID)
+	h.store.mu.Unlock()
+	writeJSON(w, http.StatusOK, ExecCheckResponse{
+		Allowed: false,
+		Reason:  reason,
+		RuleID:  ruleID,
+	})
+}
+
+// awaitApprovalAndRespond creates a pending Approval for req's command
+// (matched against a "review" rule), blocks until POST /approvals/{id}
+// resolves it or Store.approvalTimeout elapses, then records the outcome
+// the same way ExecCheckHandler does for an immediate decision before
+// writing the response. Called with Store.mu already released, since the
+// wait can take up to Store.approvalTimeout.
+func (h *Handlers) awaitApprovalAndRespond(w http.ResponseWriter, req ExecCheckRequest, ruleID string, start time.Time) {
+	approval := h.store.createPendingApproval(req.AgentID, req.Command, ruleID)
+	decision := h.store.awaitApproval(approval)
+
+	h.store.mu.Lock()
+	h.store.recordExecEvent(ExecDecisionEvent{
+		AgentID:   req.AgentID,
+		Command:   req.Command,
+		RuleID:    ruleID,
+		Allowed:   decision.allow,
+		Timestamp: time.Now(),
+		LatencyMS: time.Since(start).Milliseconds(),
+	})
+	h.store.stats.record(ruleID, req.AgentID, decision.allow, time.Now())
+	if decision.allow {
+		h.metrics.observeCheck(req.AgentID, "allow", ruleID, time.Since(start).Seconds())
+		h.addLog(req.AgentID, "command", req.Command, "allowed", ruleID)
+	} else {
+		h.store.blockedTotal++
+		h.metrics.observeCheck(req.AgentID, "block", ruleID, time.Since(start).Seconds())
+		h.addLog(req.AgentID, "command", req.Command, "blocked", ruleID)
+	}
+	h.store.mu.Unlock()
+
+	reason := decision.reason
+	if reason == "" && !decision.allow {
+		reason = "denied by operator review"
+	}
+	writeJSON(w, http.StatusOK, ExecCheckResponse{
+		Allowed: decision.allow,
+		Reason:  reason,
+		RuleID:  ruleID,
+	})
+}
+
+// ExecCheckDryRunHandler handles POST /exec/check/dryrun. It evaluates
+// Commands against the ad hoc Rules supplied in the request body instead
+// of the store's configured rules, using the same (priority desc,
+// declaration order) precedence as ExecCheckHandler, but never mutates
+// the store, Store.requestsTotal/blockedTotal, or Store.logs — useful for
+// testing a rule change in CI before applying it for real.
+func (h *Handlers) ExecCheckDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req ExecCheckDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	rules := make([]*Rule, 0, len(req.Rules))
+	for i, ruleReq := range req.Rules {
+		rule, err := buildRule(ruleReq, fmt.Sprintf("rules[%d]", i), time.Unix(int64(i), 0))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("rules[%d]: %v", i, err))
+			return
+		}
+		if rule.Enabled {
+			rules = append(rules, rule)
+		}
+	}
+	sortedEnabledRules(rules)
+
+	results := make([]DryRunResult, len(req.Commands))
+	for i, cmd := range req.Commands {
+		ctx := matcher.ExecContext{
+			Command: cmd.Command,
+			Argv:    strings.Fields(cmd.Command),
+			User:    cmd.User,
+			Cwd:     cmd.Cwd,
+			AgentID: cmd.AgentID,
+			Env:     cmd.Env,
+		}
+		allowed, reason, ruleID := evaluateCommand(rules, ctx)
+		results[i] = DryRunResult{
+			Command: cmd.Command,
+			Allowed: allowed,
+			Reason:  reason,
+			RuleID:  ruleID,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, ExecCheckDryRunResponse{Results: results})
+}
+
+// sortedEnabledRules sorts rules in place by (priority desc, created_at
+// asc), the order ExecCheckHandler and ExecCheckDryRunHandler evaluate
+// them in, and returns it for convenient chaining.
+func sortedEnabledRules(rules []*Rule) []*Rule {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+	return rules
+}
+
+// evaluateCommand checks ctx against rules, already sorted by
+// sortedEnabledRules, and returns the outcome of the first one that
+// matches: whether it's allowed, the block reason (the matched rule's
+// Description, if any), and the matched rule's ID. It performs no locking
+// or store mutation, so ExecCheckHandler and ExecCheckDryRunHandler share
+// it safely.
+func evaluateCommand(rules []*Rule, ctx matcher.ExecContext) (allowed bool, reason, ruleID string) {
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			continue
+		}
+		if rule.compiled == nil {
+			// Rule was constructed directly (e.g. loaded from storage
+			// written before MatchType existed, or a test fixture) rather
+			// than through CreateRuleHandler. Compile lazily and cache it,
+			// same as CreateRuleHandler would have.
+			if err := compileRuleMatcher(rule); err != nil {
+				log.Printf("rule %q: %v", rule.ID, err)
+				continue
+			}
+		}
+
+		matched := false
+		if cm, ok := rule.compiled.(matcher.ContextMatcher); ok {
+			matched = cm.MatchContext(ctx)
+		} else {
+			matched = rule.compiled.Match(ctx.Command, ctx.AgentID, ctx.Env)
+		}
+		if !matched {
+			continue
+		}
+
+		if rule.Action == "allow" {
+			return true, "", rule.ID
+		}
+		return false, rule.Description, rule.ID
+	}
+	return true, "", ""
+}
+
+// addLog adds a log entry to the store (must be called with lock held).
+func (h *Handlers) addLog(agentID, logType, request, action, ruleID string) {
+	entry := LogEntry{
+		ID:        generateID(),
+		Timestamp: time.Now(),
+		AgentID:   agentID,
+		Type:      logType,
+		Request:   request,
+		Action:    action,
+		RuleID:    ruleID,
+	}
+	h.store.logs = append(h.store.logs, entry)
+
+	// Keep only last 10000 logs
+	if len(h.store.logs) > 10000 {
+		h.store.logs = h.store.logs[len(h.store.logs)-10000:]
+	}
+
+	h.store.logBroker.Publish(entry, entry.Timestamp)
+	h.store.changes.notify("logs")
+
+	if h.store.persist != nil {
+		if err := h.store.persist.AppendLog(storage.LogEntry{
+			ID:        entry.ID,
+			Timestamp: entry.Timestamp,
+			AgentID:   entry.AgentID,
+			Type:      entry.Type,
+			Request:   entry.Request,
+			Action:    entry.Action,
+			RuleID:    entry.RuleID,
+		}); err != nil {
+			log.Printf("storage: append log: %v", err)
+		}
+	}
+}
+
+// SetBastionCA wires the bastion CA used to issue host certificates. If
+// never called, the host-cert endpoint responds 503.
+func (h *Handlers) SetBastionCA(ca *bastion.CertificateAuthority) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.bastionCA = ca
+}
+
+// SetApprovalTimeout overrides how long a "review" rule match waits for a
+// human decision via POST /approvals/{id} before falling back to a deny.
+func (h *Handlers) SetApprovalTimeout(d time.Duration) {
+	h.store.SetApprovalTimeout(d)
+}
+
+// SetRecoveryHandler wires fn as Recovery's alerting hook, called every
+// time an HTTP handler panics, after Recovery's own logging/audit/
+// SessionEventPanic bookkeeping.
+func (h *Handlers) SetRecoveryHandler(fn RecoveryHandlerFunc) {
+	h.recoveryHandler = fn
+}
+
+// SetBastionGrants wires the grant store used by
+// DELETE /bastion/grants/{id} to also remove the grant record, not just
+// revoke its certificates.
+func (h *Handlers) SetBastionGrants(grants *bastion.GrantStore) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.bastionGrants = grants
+}
+
+// SetBastionProvisioners wires the named provisioners available to
+// POST /bastion/grants. If never called, that endpoint responds 503.
+func (h *Handlers) SetBastionProvisioners(provisioners map[string]bastion.Provisioner) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.provisioners = provisioners
+}
+
+// SetBastionRecordingDir wires the directory a bastion.FileRecorder writes
+// session recordings to, so GetBastionSessionStreamHandler and
+// GetBastionSessionReplayHandler can find a session's .stream/meta.json
+// files. If never called, those endpoints respond 503.
+func (h *Handlers) SetBastionRecordingDir(dir string) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.bastionRecordingDir = dir
+}
+
+// SetLockedBastionCA marks the server locked: path is an encrypted-at-rest
+// bastion CA key (see bastion/keystore) that must be unlocked via
+// POST /unlock before the server leaves its locked state. Until then,
+// lockedGateHandler rejects every request except /health, /healthz/rules,
+// /unlock, and /unlock/rotate.
+func (h *Handlers) SetLockedBastionCA(path string) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.locked = true
+	h.store.lockedBastionCAPath = path
+}
+
+// Locked reports whether the server is still waiting on POST /unlock.
+func (h *Handlers) Locked() bool {
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+	return h.store.locked
+}
+
+// UnlockHandler handles POST /unlock. It decrypts the bastion CA key
+// configured by SetLockedBastionCA using the request passphrase and, on
+// success, wires it as the bastion CA and clears the locked state.
+func (h *Handlers) UnlockHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	locked := h.store.locked
+	path := h.store.lockedBastionCAPath
+	h.store.mu.RUnlock()
+
+	if !locked {
+		writeError(w, http.StatusConflict, "server is not locked")
+		return
+	}
+
+	var req UnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Passphrase == "" {
+		writeError(w, http.StatusBadRequest, "passphrase is required")
+		return
+	}
+
+	ca, err := bastion.NewLockedCertificateAuthority(path, req.Passphrase)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "unlock: "+err.Error())
+		return
+	}
+
+	h.store.mu.Lock()
+	h.store.bastionCA = ca
+	h.store.locked = false
+	h.store.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, UnlockResponse{Message: "unlocked"})
+}
+
+// RotateUnlockKeyHandler handles POST /unlock/rotate. It re-wraps the
+// bastion CA key configured by SetLockedBastionCA under a new passphrase,
+// without changing the key material itself.
+func (h *Handlers) RotateUnlockKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	h.store.mu.RLock()
+	path := h.store.lockedBastionCAPath
+	h.store.mu.RUnlock()
+	if path == "" {
+		writeError(w, http.StatusServiceUnavailable, "no locked bastion CA key configured")
+		return
+	}
+
+	var req RotateUnlockKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.OldPassphrase == "" || req.NewPassphrase == "" {
+		writeError(w, http.StatusBadRequest, "old_passphrase and new_passphrase are required")
+		return
+	}
+
+	if err := bastion.RotateCAKeyPassphrase(path, req.OldPassphrase, req.NewPassphrase); err != nil {
+		writeError(w, http.StatusUnauthorized, "rotate: "+err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RotateUnlockKeyResponse{Message: "unlock key rotated"})
+}
+
+// SetAgentCA wires the CA used to issue and revoke agent mTLS client
+// certificates. If never called, the enrollment endpoints respond 503.
+func (h *Handlers) SetAgentCA(ca *agentca.AgentCA) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.agentCA = ca
+}
+
+// SetMITMCA wires the CA the forward proxy's MITM mode (proxy.WithMITM)
+// mints leaf certificates from, so GET /mitm/ca-cert can hand its root
+// certificate to agents. If never called, that endpoint responds 503.
+func (h *Handlers) SetMITMCA(ca *mitmca.CA) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.mitmCA = ca
+}
+
+// SetAgentEnrollments wires the one-time enrollment secret store used by
+// POST /agents/enroll.
+func (h *Handlers) SetAgentEnrollments(enrollments *agentca.EnrollmentStore) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.enrollments = enrollments
+}
+
+// AgentCA returns the configured agent CA, or nil if none is wired up.
+// Used by AgentAccess to decide between mTLS and bearer-token auth.
+func (h *Handlers) AgentCA() *agentca.AgentCA {
+	h.store.mu.RLock()
+	defer h.store.mu.RUnlock()
+	return h.store.agentCA
+}
+
+// RegisterAgent registers a new agent (for testing/setup).
+func (h *Handlers) RegisterAgent(id, name, ip string) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	now := time.Now()
+	h.store.agents[id] = &Agent{
+		ID:        id,
+		Name:      name,
+		IP:        ip,
+		Status:    "active",
+		LastSeen:  now,
+		CreatedAt: now,
+	}
+	h.persistAgentLocked(id)
+	h.store.changes.notify("agents")
+}
+
+// SetStorage wires a persistent storage.Store so agents, rules, and logs
+// survive a restart. Reads are still served from the in-memory Store;
+// writes are mirrored to persist. If never called, the API is in-memory
+// only, as before.
+func (h *Handlers) SetStorage(persist storage.Store) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.persist = persist
+}
+
+// LoadFromStorage hydrates the in-memory Store's agents and rules from a
+// previously wired persist.Store. Call it once at startup, after
+// SetStorage, before serving traffic. Logs aren't replayed: they're
+// bounded scrollback, not state that needs to survive a restart intact.
+func (h *Handlers) LoadFromStorage() error {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	if h.store.persist == nil {
+		return nil
+	}
+
+	agents, err := h.store.persist.ListAgents()
+	if err != nil {
+		return fmt.Errorf("load agents from storage: %w", err)
+	}
+	for _, a := range agents {
+		h.store.agents[a.ID] = &Agent{
+			ID:        a.ID,
+			Name:      a.Name,
+			IP:        a.IP,
+			Status:    a.Status,
+			LastSeen:  a.UpdatedAt,
+			CreatedAt: a.CreatedAt,
+		}
+	}
+
+	rules, err := h.store.persist.ListRules()
+	if err != nil {
+		return fmt.Errorf("load rules from storage: %w", err)
+	}
+	for _, r := range rules {
+		rule := &Rule{
+			ID:          r.ID,
+			Pattern:     r.Pattern,
+			Domain:      r.Domain,
+			MatchType:   r.MatchType,
+			Priority:    r.Priority,
+			Action:      r.Action,
+			Description: r.Description,
+			Enabled:     r.Enabled,
+			CreatedAt:   r.CreatedAt,
+		}
+		if err := compileRuleMatcher(rule); err != nil {
+			log.Printf("storage: rule %q: %v", r.ID, err)
+		}
+		h.store.rules[r.ID] = rule
+	}
+
+	tenants, err := h.store.persist.ListTenants()
+	if err != nil {
+		return fmt.Errorf("load tenants from storage: %w", err)
+	}
+	for _, t := range tenants {
+		h.store.tenants[t.ID] = &Tenant{ID: t.ID, Mode: t.Mode, CreatedAt: t.CreatedAt, UpdatedAt: t.UpdatedAt}
+	}
+
+	tokens, err := h.store.persist.ListTokens()
+	if err != nil {
+		return fmt.Errorf("load tokens from storage: %w", err)
+	}
+	for _, t := range tokens {
+		h.store.tokens[t.Token] = &Token{Token: t.Token, TenantID: t.TenantID, Name: t.Name, CreatedAt: t.CreatedAt}
+	}
+
+	h.store.auditMu.Lock()
+	latest, err := h.store.persist.ListAudit(1)
+	if err != nil {
+		h.store.auditMu.Unlock()
+		return fmt.Errorf("load audit chain head from storage: %w", err)
+	}
+	if len(latest) > 0 {
+		h.store.auditLastHash = latest[0].Hash
+	}
+	h.store.auditMu.Unlock()
+
+	return nil
+}
+
+// persistAgentLocked mirrors the in-memory agent identified by id to
+// store.persist, if configured. Caller must hold store.mu.
+func (h *Handlers) persistAgentLocked(id string) {
+	if h.store.persist == nil {
+		return
+	}
+	agent, ok := h.store.agents[id]
+	if !ok {
+		return
+	}
+	if err := h.store.persist.UpsertAgent(storage.Agent{
+		ID:        agent.ID,
+		Name:      agent.Name,
+		IP:        agent.IP,
+		Status:    agent.Status,
+		CreatedAt: agent.CreatedAt,
+		UpdatedAt: time.Now(),
+	}); err != nil {
+		log.Printf("storage: persist agent %q: %v", id, err)
 	}
 }
 
@@ -498,6 +2256,36 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	})
 }
 
+// parseListFilter parses the `?filter=` query parameter shared by the
+// management list endpoints. It returns ok=false after writing a 400 (with
+// a "^" pointer into the expression in Details) if the parameter is present
+// but malformed; a missing parameter returns a nil *filter.Expr and ok=true,
+// meaning "no filtering".
+func parseListFilter(w http.ResponseWriter, r *http.Request) (*filter.Expr, bool) {
+	raw := r.URL.Query().Get("filter")
+	if raw == "" {
+		return nil, true
+	}
+	expr, err := filter.Parse(raw)
+	if err != nil {
+		details := ""
+		if synErr, ok := err.(*filter.SyntaxError); ok {
+			pos := synErr.Pos
+			if pos > len(raw) {
+				pos = len(raw)
+			}
+			details = raw + "\n" + strings.Repeat(" ", pos) + "^"
+		}
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error:   fmt.Sprintf("invalid filter: %s", err.Error()),
+			Code:    http.StatusBadRequest,
+			Details: details,
+		})
+		return nil, false
+	}
+	return expr, true
+}
+
 func extractAgentID(path, prefix, suffix string) string {
 	path = strings.TrimPrefix(path, prefix)
 	path = strings.TrimSuffix(path, suffix)
@@ -507,9 +2295,3 @@ func extractAgentID(path, prefix, suffix string) string {
 func generateID() string {
 	return strconv.FormatInt(time.Now().UnixNano(), 36)
 }
-
-func matchPattern(command, pattern string) bool {
-	// Simple substring match for now
-	// Could be extended to support glob or regex
-	return strings.Contains(command, pattern)
-}
@@ -2,12 +2,50 @@ package api
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
 )
 
+// newTestCSR generates a PEM-encoded CSR for use in enrollment handler tests.
+func newTestCSR(t *testing.T) []byte {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, priv)
+	if err != nil {
+		t.Fatalf("create csr: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
 func TestStatusHandler(t *testing.T) {
 	store := NewStore()
 	handlers := NewHandlers(store, "1.0.0")
@@ -55,6 +93,57 @@ func TestStatusHandler(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
 		}
 	})
+
+	t.Run("returns a text/plain summary when requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Accept", "text/plain")
+		rec := httptest.NewRecorder()
+
+		handlers.StatusHandler(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/plain" {
+			t.Errorf("expected Content-Type %q, got %q", "text/plain", ct)
+		}
+		if !strings.Contains(rec.Body.String(), "agents: 1") {
+			t.Errorf("expected agent count in body, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("returns a Prometheus exposition when requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.Header.Set("Accept", "text/plain; version=0.0.4")
+		rec := httptest.NewRecorder()
+
+		handlers.StatusHandler(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+			t.Errorf("expected Content-Type %q, got %q", "text/plain; version=0.0.4", ct)
+		}
+		if !strings.Contains(rec.Body.String(), "plasma_agents_total 1") {
+			t.Errorf("expected a plasma_agents_total sample, got %q", rec.Body.String())
+		}
+	})
+}
+
+func TestMetricsHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+
+	handlers.MetricsHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4" {
+		t.Errorf("expected Content-Type %q, got %q", "text/plain; version=0.0.4", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "plasma_agents_total 1") {
+		t.Errorf("expected a plasma_agents_total sample, got %q", rec.Body.String())
+	}
 }
 
 func TestListAgentsHandler(t *testing.T) {
@@ -119,6 +208,82 @@ func TestListAgentsHandler(t *testing.T) {
 	})
 }
 
+func TestListAgentsHandlerBlockingQuery(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Alpha", "10.0.0.1")
+
+	req := httptest.NewRequest(http.MethodGet, "/agents", nil)
+	rec := httptest.NewRecorder()
+	handlers.ListAgentsHandler(rec, req)
+	index := rec.Header().Get("X-Plasma-Index")
+	if index == "" {
+		t.Fatalf("expected an X-Plasma-Index header, got none")
+	}
+
+	t.Run("unblocks once a matching index changes the result", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/agents?index="+index+"&wait=2s", nil)
+		rec := httptest.NewRecorder()
+
+		done := make(chan struct{})
+		go func() {
+			handlers.ListAgentsHandler(rec, req)
+			close(done)
+		}()
+
+		// Give the handler a moment to start waiting before the register below
+		// notifies it, so this exercises the wake path rather than racing the
+		// initial compute.
+		time.Sleep(10 * time.Millisecond)
+		handlers.RegisterAgent("agent-2", "Beta", "10.0.0.2")
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("ListAgentsHandler did not unblock after RegisterAgent")
+		}
+
+		var resp AgentListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Total != 2 {
+			t.Errorf("expected total 2, got %d", resp.Total)
+		}
+		newIndex := rec.Header().Get("X-Plasma-Index")
+		if newIndex == index {
+			t.Errorf("expected X-Plasma-Index to change, still %q", newIndex)
+		}
+		index = newIndex
+	})
+
+	t.Run("returns once the wait deadline elapses with no change", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/agents?index="+index+"&wait=20ms", nil)
+		rec := httptest.NewRecorder()
+
+		start := time.Now()
+		handlers.ListAgentsHandler(rec, req)
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("expected ListAgentsHandler to wait out the deadline, returned after %s", elapsed)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("rejects a malformed wait duration", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/agents?index="+index+"&wait=notaduration", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListAgentsHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
 func TestPauseAgentHandler(t *testing.T) {
 	store := NewStore()
 	handlers := NewHandlers(store, "1.0.0")
@@ -481,7 +646,7 @@ func TestCreateRuleHandler(t *testing.T) {
 			t.Fatalf("failed to decode response: %v", err)
 		}
 
-		if resp.Error != "action must be 'block' or 'allow'" {
+		if resp.Error != "action must be 'block', 'allow', or 'review'" {
 			t.Errorf("unexpected error: %q", resp.Error)
 		}
 	})
@@ -532,6 +697,82 @@ func TestCreateRuleHandler(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
 		}
 	})
+
+	t.Run("rejects an unknown match_type", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := CreateRuleRequest{Pattern: "test", Action: "block", MatchType: "xpath"}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateRuleHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("rejects a regex pattern that fails to compile", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := CreateRuleRequest{Pattern: "(", Action: "block", MatchType: "regex"}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateRuleHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+
+		var resp ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Error == "" {
+			t.Error("expected the regex compile error in ErrorResponse.Error")
+		}
+	})
+
+	t.Run("rejects a CEL expression that fails to type-check", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := CreateRuleRequest{Pattern: "command", Action: "block", MatchType: "cel"}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateRuleHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("accepts a valid CEL expression", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := CreateRuleRequest{Pattern: `command.contains("rm -rf")`, Action: "block", MatchType: "cel"}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateRuleHandler(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Errorf("expected status %d, got %d", http.StatusCreated, rec.Code)
+		}
+	})
 }
 
 func TestDeleteRuleHandler(t *testing.T) {
@@ -614,132 +855,303 @@ func TestDeleteRuleHandler(t *testing.T) {
 	})
 }
 
-func TestListLogsHandler(t *testing.T) {
-	store := NewStore()
-	handlers := NewHandlers(store, "1.0.0")
+func TestCreateRulesBulkHandler(t *testing.T) {
+	t.Run("creates every rule from a JSON array", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
 
-	// Add test logs
-	store.mu.Lock()
-	store.logs = []LogEntry{
-		{ID: "1", AgentID: "agent-1", Type: "command", Request: "ls", Action: "allowed"},
-		{ID: "2", AgentID: "agent-1", Type: "command", Request: "rm", Action: "blocked"},
-		{ID: "3", AgentID: "agent-2", Type: "http", Request: "GET /api", Action: "allowed"},
-		{ID: "4", AgentID: "agent-1", Type: "dns", Request: "evil.com", Action: "blocked"},
-		{ID: "5", AgentID: "agent-2", Type: "command", Request: "cat", Action: "allowed"},
-	}
-	store.mu.Unlock()
+		body := []CreateRuleRequest{
+			{Pattern: "rm -rf", Action: "block", Enabled: true},
+			{Domain: "evil.example", Action: "block", Enabled: true},
+		}
+		bodyBytes, _ := json.Marshal(body)
 
-	t.Run("returns all logs with defaults", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/logs", nil)
+		req := httptest.NewRequest(http.MethodPost, "/rules/bulk", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
 		rec := httptest.NewRecorder()
 
-		handlers.ListLogsHandler(rec, req)
+		handlers.CreateRulesBulkHandler(rec, req)
 
-		if rec.Code != http.StatusOK {
-			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
 		}
 
-		var resp LogListResponse
+		var resp BulkCreateRulesResponse
 		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 			t.Fatalf("failed to decode response: %v", err)
 		}
-
-		if resp.Total != 5 {
-			t.Errorf("expected total 5, got %d", resp.Total)
-		}
-		if len(resp.Logs) != 5 {
-			t.Errorf("expected 5 logs, got %d", len(resp.Logs))
+		if len(resp.Rules) != 2 {
+			t.Fatalf("expected 2 rules, got %d", len(resp.Rules))
 		}
-		if resp.Limit != 100 {
-			t.Errorf("expected default limit 100, got %d", resp.Limit)
+
+		store.mu.RLock()
+		total := len(store.rules)
+		store.mu.RUnlock()
+		if total != 2 {
+			t.Errorf("expected 2 rules in store, got %d", total)
 		}
 	})
 
-	t.Run("respects limit and offset", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/logs?limit=2&offset=1", nil)
+	t.Run("creates every rule from a YAML array", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		yamlBody := "- pattern: rm -rf\n  action: block\n  enabled: true\n- domain: evil.example\n  action: allow\n  enabled: true\n"
+
+		req := httptest.NewRequest(http.MethodPost, "/rules/bulk", strings.NewReader(yamlBody))
+		req.Header.Set("Content-Type", "application/yaml")
 		rec := httptest.NewRecorder()
 
-		handlers.ListLogsHandler(rec, req)
+		handlers.CreateRulesBulkHandler(rec, req)
 
-		if rec.Code != http.StatusOK {
-			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
 		}
 
-		var resp LogListResponse
+		var resp BulkCreateRulesResponse
 		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 			t.Fatalf("failed to decode response: %v", err)
 		}
-
-		if resp.Total != 5 {
-			t.Errorf("expected total 5, got %d", resp.Total)
-		}
-		if len(resp.Logs) != 2 {
-			t.Errorf("expected 2 logs, got %d", len(resp.Logs))
-		}
-		if resp.Offset != 1 {
-			t.Errorf("expected offset 1, got %d", resp.Offset)
-		}
-		if resp.Limit != 2 {
-			t.Errorf("expected limit 2, got %d", resp.Limit)
+		if len(resp.Rules) != 2 {
+			t.Fatalf("expected 2 rules, got %d", len(resp.Rules))
 		}
 	})
 
-	t.Run("filters by agent_id", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/logs?agent_id=agent-1", nil)
-		rec := httptest.NewRecorder()
-
-		handlers.ListLogsHandler(rec, req)
+	t.Run("one bad rule in the batch leaves the store untouched", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
 
-		var resp LogListResponse
-		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-			t.Fatalf("failed to decode response: %v", err)
-		}
+		store.mu.Lock()
+		store.rules["existing"] = &Rule{ID: "existing", Pattern: "test", Action: "block", Enabled: true}
+		store.mu.Unlock()
 
-		if resp.Total != 3 {
-			t.Errorf("expected total 3 for agent-1, got %d", resp.Total)
+		body := []CreateRuleRequest{
+			{Pattern: "rm -rf", Action: "block", Enabled: true},
+			{Pattern: "bad", Action: "not-a-valid-action"},
 		}
-	})
+		bodyBytes, _ := json.Marshal(body)
 
-	t.Run("filters by action", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/logs?action=blocked", nil)
+		req := httptest.NewRequest(http.MethodPost, "/rules/bulk", bytes.NewReader(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
 		rec := httptest.NewRecorder()
 
-		handlers.ListLogsHandler(rec, req)
+		handlers.CreateRulesBulkHandler(rec, req)
 
-		var resp LogListResponse
-		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-			t.Fatalf("failed to decode response: %v", err)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
 		}
 
-		if resp.Total != 2 {
-			t.Errorf("expected total 2 blocked logs, got %d", resp.Total)
+		store.mu.RLock()
+		total := len(store.rules)
+		_, stillExists := store.rules["existing"]
+		store.mu.RUnlock()
+		if total != 1 || !stillExists {
+			t.Errorf("expected the store to still contain only the pre-existing rule, got %d rules", total)
 		}
 	})
 
-	t.Run("filters by type", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/logs?type=command", nil)
-		rec := httptest.NewRecorder()
+	t.Run("rejects an empty array", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
 
-		handlers.ListLogsHandler(rec, req)
+		req := httptest.NewRequest(http.MethodPost, "/rules/bulk", strings.NewReader("[]"))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
 
-		var resp LogListResponse
-		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-			t.Fatalf("failed to decode response: %v", err)
-		}
+		handlers.CreateRulesBulkHandler(rec, req)
 
-		if resp.Total != 3 {
-			t.Errorf("expected total 3 command logs, got %d", resp.Total)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
 		}
 	})
 
-	t.Run("combines multiple filters", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodGet, "/logs?agent_id=agent-1&action=blocked", nil)
+	t.Run("rejects non-POST methods", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/rules/bulk", nil)
 		rec := httptest.NewRecorder()
 
-		handlers.ListLogsHandler(rec, req)
+		handlers.CreateRulesBulkHandler(rec, req)
 
-		var resp LogListResponse
-		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
+
+func TestExportRulesHandler(t *testing.T) {
+	t.Run("dumps every rule as a YAML array", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		store.mu.Lock()
+		store.rules["rule-a"] = &Rule{ID: "rule-a", Pattern: "rm -rf", Action: "block", Enabled: true}
+		store.rules["rule-b"] = &Rule{ID: "rule-b", Domain: "evil.example", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		req := httptest.NewRequest(http.MethodGet, "/rules/export", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ExportRulesHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/yaml" {
+			t.Errorf("expected Content-Type 'application/yaml', got %q", ct)
+		}
+
+		var reqs []CreateRuleRequest
+		if err := yaml.Unmarshal(rec.Body.Bytes(), &reqs); err != nil {
+			t.Fatalf("failed to decode YAML response: %v", err)
+		}
+		if len(reqs) != 2 {
+			t.Fatalf("expected 2 rules, got %d", len(reqs))
+		}
+	})
+
+	t.Run("rejects non-GET methods", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodPost, "/rules/export", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ExportRulesHandler(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
+
+func TestListLogsHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+
+	// Add test logs
+	store.mu.Lock()
+	store.logs = []LogEntry{
+		{ID: "1", AgentID: "agent-1", Type: "command", Request: "ls", Action: "allowed"},
+		{ID: "2", AgentID: "agent-1", Type: "command", Request: "rm", Action: "blocked"},
+		{ID: "3", AgentID: "agent-2", Type: "http", Request: "GET /api", Action: "allowed"},
+		{ID: "4", AgentID: "agent-1", Type: "dns", Request: "evil.com", Action: "blocked"},
+		{ID: "5", AgentID: "agent-2", Type: "command", Request: "cat", Action: "allowed"},
+	}
+	store.mu.Unlock()
+
+	t.Run("returns all logs with defaults", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var resp LogListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.Total != 5 {
+			t.Errorf("expected total 5, got %d", resp.Total)
+		}
+		if len(resp.Logs) != 5 {
+			t.Errorf("expected 5 logs, got %d", len(resp.Logs))
+		}
+		if resp.Limit != 100 {
+			t.Errorf("expected default limit 100, got %d", resp.Limit)
+		}
+	})
+
+	t.Run("respects limit and offset", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?limit=2&offset=1", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var resp LogListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.Total != 5 {
+			t.Errorf("expected total 5, got %d", resp.Total)
+		}
+		if len(resp.Logs) != 2 {
+			t.Errorf("expected 2 logs, got %d", len(resp.Logs))
+		}
+		if resp.Offset != 1 {
+			t.Errorf("expected offset 1, got %d", resp.Offset)
+		}
+		if resp.Limit != 2 {
+			t.Errorf("expected limit 2, got %d", resp.Limit)
+		}
+	})
+
+	t.Run("filters by agent_id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?agent_id=agent-1", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		var resp LogListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.Total != 3 {
+			t.Errorf("expected total 3 for agent-1, got %d", resp.Total)
+		}
+	})
+
+	t.Run("filters by action", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?action=blocked", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		var resp LogListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.Total != 2 {
+			t.Errorf("expected total 2 blocked logs, got %d", resp.Total)
+		}
+	})
+
+	t.Run("filters by type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?type=command", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		var resp LogListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if resp.Total != 3 {
+			t.Errorf("expected total 3 command logs, got %d", resp.Total)
+		}
+	})
+
+	t.Run("combines multiple filters", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?agent_id=agent-1&action=blocked", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		var resp LogListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 			t.Fatalf("failed to decode response: %v", err)
 		}
 
@@ -758,6 +1170,33 @@ func TestListLogsHandler(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
 		}
 	})
+
+	t.Run("exports as CSV when requested", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/logs?agent_id=agent-1", nil)
+		req.Header.Set("Accept", "text/csv")
+		rec := httptest.NewRecorder()
+
+		handlers.ListLogsHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+			t.Errorf("expected Content-Type %q, got %q", "text/csv", ct)
+		}
+
+		reader := csv.NewReader(rec.Body)
+		rows, err := reader.ReadAll()
+		if err != nil {
+			t.Fatalf("failed to parse CSV: %v", err)
+		}
+		if len(rows) != 4 { // header + 3 agent-1 rows
+			t.Fatalf("expected 4 CSV rows (header + 3 logs), got %d: %v", len(rows), rows)
+		}
+		if rows[0][0] != "id" {
+			t.Errorf("expected a header row, got %v", rows[0])
+		}
+	})
 }
 
 func TestExecCheckHandler(t *testing.T) {
@@ -837,6 +1276,92 @@ func TestExecCheckHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("holds command matching a review rule for operator approval", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{
+			ID:          "rule-1",
+			Pattern:     "curl",
+			Action:      "review",
+			Description: "Needs a human look",
+			Enabled:     true,
+		}
+		store.mu.Unlock()
+
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			body := ExecCheckRequest{Command: "curl example.com", AgentID: "agent-1"}
+			bodyBytes, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+			rec := httptest.NewRecorder()
+			handlers.ExecCheckHandler(rec, req)
+			done <- rec
+		}()
+
+		var approvalID string
+		for i := 0; i < 100; i++ {
+			pending := store.pendingApprovalsList()
+			if len(pending) == 1 {
+				approvalID = pending[0].ID
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if approvalID == "" {
+			t.Fatal("expected a pending approval to show up")
+		}
+
+		if !store.resolveApproval(approvalID, true, "looks fine", "operator-1") {
+			t.Fatal("expected resolveApproval to find the pending approval")
+		}
+
+		rec := <-done
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !resp.Allowed {
+			t.Error("expected command to be allowed once the operator approved it")
+		}
+		if resp.RuleID != "rule-1" {
+			t.Errorf("expected rule_id 'rule-1', got %q", resp.RuleID)
+		}
+	})
+
+	t.Run("denies a review rule match that times out", func(t *testing.T) {
+		store := NewStore()
+		store.SetApprovalTimeout(10 * time.Millisecond)
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{
+			ID:      "rule-1",
+			Pattern: "curl",
+			Action:  "review",
+			Enabled: true,
+		}
+		store.mu.Unlock()
+
+		body := ExecCheckRequest{Command: "curl example.com", AgentID: "agent-1"}
+		bodyBytes, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected a timed-out review to deny")
+		}
+	})
+
 	t.Run("blocks when agent is paused", func(t *testing.T) {
 		store := NewStore()
 		handlers := NewHandlers(store, "1.0.0")
@@ -903,22 +1428,14 @@ func TestExecCheckHandler(t *testing.T) {
 		}
 	})
 
-	t.Run("skips disabled rules", func(t *testing.T) {
+	t.Run("blocks when agent token is missing", func(t *testing.T) {
 		store := NewStore()
 		handlers := NewHandlers(store, "1.0.0")
 		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
-
-		store.mu.Lock()
-		store.rules["rule-1"] = &Rule{
-			ID:      "rule-1",
-			Pattern: "rm",
-			Action:  "block",
-			Enabled: false, // Disabled
-		}
-		store.mu.Unlock()
+		handlers.SetAgentJWTKey(&AgentJWTKey{HMACSecret: []byte("test-secret")})
 
 		body := ExecCheckRequest{
-			Command: "rm file.txt",
+			Command: "ls",
 			AgentID: "agent-1",
 		}
 		bodyBytes, _ := json.Marshal(body)
@@ -928,27 +1445,194 @@ func TestExecCheckHandler(t *testing.T) {
 
 		handlers.ExecCheckHandler(rec, req)
 
-		var resp ExecCheckResponse
-		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-			t.Fatalf("failed to decode response: %v", err)
-		}
-
-		if !resp.Allowed {
-			t.Error("expected command to be allowed (rule disabled)")
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
 		}
 	})
 
-	t.Run("increments request and blocked counters", func(t *testing.T) {
+	t.Run("blocks when agent token is expired", func(t *testing.T) {
 		store := NewStore()
 		handlers := NewHandlers(store, "1.0.0")
 		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+		key := AgentJWTKey{HMACSecret: []byte("test-secret")}
+		handlers.SetAgentJWTKey(&key)
 
-		store.mu.Lock()
-		store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "block-me", Action: "block", Enabled: true}
-		store.mu.Unlock()
+		token, err := signAgentToken(key, agentTokenClaims{
+			Sub: "agent-1",
+			Iat: time.Now().Add(-2 * time.Hour).Unix(),
+			Exp: time.Now().Add(-time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
 
-		// Allowed request
-		body1, _ := json.Marshal(ExecCheckRequest{Command: "ls", AgentID: "agent-1"})
+		body := ExecCheckRequest{
+			Command: "ls",
+			AgentID: "agent-1",
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("blocks when agent token subject does not match agent_id", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+		key := AgentJWTKey{HMACSecret: []byte("test-secret")}
+		handlers.SetAgentJWTKey(&key)
+
+		token, err := signAgentToken(key, agentTokenClaims{
+			Sub: "agent-2",
+			Iat: time.Now().Unix(),
+			Exp: time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		body := ExecCheckRequest{
+			Command: "ls",
+			AgentID: "agent-1",
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("blocks when agent token has been revoked", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+		key := AgentJWTKey{HMACSecret: []byte("test-secret")}
+		handlers.SetAgentJWTKey(&key)
+
+		token, err := signAgentToken(key, agentTokenClaims{
+			Sub: "agent-1",
+			Iat: time.Now().Unix(),
+			Exp: time.Now().Add(time.Hour).Unix(),
+			Gen: 0,
+		})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		store.mu.Lock()
+		store.agentTokenGen["agent-1"] = 1
+		store.mu.Unlock()
+
+		body := ExecCheckRequest{
+			Command: "ls",
+			AgentID: "agent-1",
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+
+	t.Run("allows valid agent token", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+		key := AgentJWTKey{HMACSecret: []byte("test-secret")}
+		handlers.SetAgentJWTKey(&key)
+
+		token, err := signAgentToken(key, agentTokenClaims{
+			Sub: "agent-1",
+			Iat: time.Now().Unix(),
+			Exp: time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+
+		body := ExecCheckRequest{
+			Command: "ls",
+			AgentID: "agent-1",
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+
+	t.Run("skips disabled rules", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{
+			ID:      "rule-1",
+			Pattern: "rm",
+			Action:  "block",
+			Enabled: false, // Disabled
+		}
+		store.mu.Unlock()
+
+		body := ExecCheckRequest{
+			Command: "rm file.txt",
+			AgentID: "agent-1",
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		if !resp.Allowed {
+			t.Error("expected command to be allowed (rule disabled)")
+		}
+	})
+
+	t.Run("increments request and blocked counters", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "block-me", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		// Allowed request
+		body1, _ := json.Marshal(ExecCheckRequest{Command: "ls", AgentID: "agent-1"})
 		req1 := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body1))
 		rec1 := httptest.NewRecorder()
 		handlers.ExecCheckHandler(rec1, req1)
@@ -1018,4 +1702,2179 @@ func TestExecCheckHandler(t *testing.T) {
 			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
 		}
 	})
+
+	t.Run("blocks command matching a glob rule", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "rm -rf *", MatchType: "glob", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{Command: "rm -rf /var", AgentID: "agent-1"})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected command to be blocked by glob rule")
+		}
+	})
+
+	t.Run("blocks command matching a regex rule", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: `^rm\s+-rf\s+/`, MatchType: "regex", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{Command: "rm -rf /var", AgentID: "agent-1"})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected command to be blocked by regex rule")
+		}
+	})
+
+	t.Run("blocks command matching a CEL rule using the env map", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{
+			ID:        "rule-1",
+			Pattern:   `env["tier"] == "production"`,
+			MatchType: "cel",
+			Action:    "block",
+			Enabled:   true,
+		}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{
+			Command: "deploy",
+			AgentID: "agent-1",
+			Env:     map[string]string{"tier": "production"},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected command to be blocked by CEL rule")
+		}
+	})
+
+	t.Run("blocks command matching an expr rule referencing agent.ip and user", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "10.0.0.5")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{
+			ID:        "rule-1",
+			Pattern:   `command matches "^rm " and agent.ip == "10.0.0.5" and not user in ["root", "deploy"]`,
+			MatchType: "expr",
+			Action:    "block",
+			Enabled:   true,
+		}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{
+			Command: "rm -rf /var",
+			AgentID: "agent-1",
+			User:    "intern",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected command to be blocked by expr rule")
+		}
+
+		t.Run("allows when user is in the exempt list", func(t *testing.T) {
+			body, _ := json.Marshal(ExecCheckRequest{
+				Command: "rm -rf /var",
+				AgentID: "agent-1",
+				User:    "deploy",
+			})
+			req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			handlers.ExecCheckHandler(rec, req)
+
+			var resp ExecCheckResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !resp.Allowed {
+				t.Error("expected command to be allowed for an exempt user")
+			}
+		})
+	})
+
+	t.Run("rejects an invalid expr rule at creation time", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateRuleRequest{
+			Pattern:   `command == `,
+			MatchType: "expr",
+			Action:    "block",
+			Enabled:   true,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateRuleHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("higher-priority allow rule short-circuits a lower-priority block rule", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-block"] = &Rule{ID: "rule-block", Pattern: "deploy", Action: "block", Priority: 0, Enabled: true}
+		store.rules["rule-allow"] = &Rule{ID: "rule-allow", Pattern: "deploy", Action: "allow", Priority: 10, Enabled: true}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{Command: "deploy", AgentID: "agent-1"})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !resp.Allowed {
+			t.Error("expected the higher-priority allow rule to win")
+		}
+		if resp.RuleID != "rule-allow" {
+			t.Errorf("expected rule_id 'rule-allow', got %q", resp.RuleID)
+		}
+	})
+
+	t.Run("paused agent takes precedence over an allow rule", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.agents["agent-1"].Status = "paused"
+		store.rules["rule-allow"] = &Rule{ID: "rule-allow", Pattern: "deploy", Action: "allow", Priority: 10, Enabled: true}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{Command: "deploy", AgentID: "agent-1"})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected the paused agent to be blocked despite the allow rule")
+		}
+		if resp.Reason != "agent is paused" {
+			t.Errorf("expected reason 'agent is paused', got %q", resp.Reason)
+		}
+	})
+
+	t.Run("killed agent takes precedence over an allow rule", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.agents["agent-1"].Status = "killed"
+		store.rules["rule-allow"] = &Rule{ID: "rule-allow", Pattern: "deploy", Action: "allow", Priority: 10, Enabled: true}
+		store.mu.Unlock()
+
+		body, _ := json.Marshal(ExecCheckRequest{Command: "deploy", AgentID: "agent-1"})
+		req := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckHandler(rec, req)
+
+		var resp ExecCheckResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Allowed {
+			t.Error("expected the killed agent to be blocked despite the allow rule")
+		}
+		if resp.Reason != "agent is killed" {
+			t.Errorf("expected reason 'agent is killed', got %q", resp.Reason)
+		}
+	})
+}
+
+func TestExecCheckDryRunHandler(t *testing.T) {
+	t.Run("reports which rule would fire for each command", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := ExecCheckDryRunRequest{
+			Rules: []CreateRuleRequest{
+				{Pattern: "rm -rf", Action: "block", Description: "dangerous", Enabled: true},
+				{Pattern: "deploy", Action: "allow", Enabled: true},
+			},
+			Commands: []DryRunCommand{
+				{Command: "rm -rf /", AgentID: "agent-1"},
+				{Command: "deploy prod", AgentID: "agent-1"},
+				{Command: "ls -la", AgentID: "agent-1"},
+			},
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check/dryrun", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckDryRunHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp ExecCheckDryRunResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Results) != 3 {
+			t.Fatalf("expected 3 results, got %d", len(resp.Results))
+		}
+		if resp.Results[0].Allowed {
+			t.Error("expected 'rm -rf /' to be blocked")
+		}
+		if resp.Results[0].RuleID != "rules[0]" {
+			t.Errorf("expected rule_id 'rules[0]', got %q", resp.Results[0].RuleID)
+		}
+		if !resp.Results[1].Allowed || resp.Results[1].RuleID != "rules[1]" {
+			t.Errorf("expected 'deploy prod' to be allowed by rules[1], got %+v", resp.Results[1])
+		}
+		if !resp.Results[2].Allowed || resp.Results[2].RuleID != "" {
+			t.Errorf("expected 'ls -la' to be allowed by no rule, got %+v", resp.Results[2])
+		}
+	})
+
+	t.Run("does not mutate the store or its logs", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		store.mu.Lock()
+		logsBefore := len(store.logs)
+		rulesBefore := len(store.rules)
+		store.mu.Unlock()
+
+		body := ExecCheckDryRunRequest{
+			Rules:    []CreateRuleRequest{{Pattern: "rm -rf", Action: "block", Enabled: true}},
+			Commands: []DryRunCommand{{Command: "rm -rf /", AgentID: "agent-1"}},
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check/dryrun", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckDryRunHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		if len(store.logs) != logsBefore {
+			t.Errorf("expected store.logs to be unchanged, had %d, now %d", logsBefore, len(store.logs))
+		}
+		if len(store.rules) != rulesBefore {
+			t.Errorf("expected store.rules to be unchanged, had %d, now %d", rulesBefore, len(store.rules))
+		}
+	})
+
+	t.Run("rejects an invalid rule in the set", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := ExecCheckDryRunRequest{
+			Rules: []CreateRuleRequest{{Pattern: "test", Action: "not-a-valid-action"}},
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/exec/check/dryrun", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckDryRunHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("rejects non-POST methods", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/exec/check/dryrun", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.ExecCheckDryRunHandler(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
+
+func TestIssueHostCertificateHandler(t *testing.T) {
+	newSignerPublicKey := func(t *testing.T) string {
+		t.Helper()
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("generate key: %v", err)
+		}
+		signer, err := ssh.NewSignerFromKey(priv)
+		if err != nil {
+			t.Fatalf("signer: %v", err)
+		}
+		return string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	}
+
+	t.Run("issues a host certificate", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := bastion.NewCertificateAuthority(filepath.Join(t.TempDir(), "bastion_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetBastionCA(ca)
+
+		body := IssueHostCertificateRequest{
+			PublicKey: newSignerPublicKey(t),
+			Hostnames: []string{"agent-1", "10.0.0.5"},
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/bastion/hosts/agent-1/host-cert", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.IssueHostCertificateHandler(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		var resp IssueHostCertificateResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.HostID != "agent-1" {
+			t.Errorf("expected host_id 'agent-1', got %q", resp.HostID)
+		}
+		if resp.Certificate == "" {
+			t.Error("expected a non-empty certificate")
+		}
+	})
+
+	t.Run("returns 503 when no CA is configured", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body := IssueHostCertificateRequest{
+			PublicKey: newSignerPublicKey(t),
+			Hostnames: []string{"agent-1"},
+		}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/bastion/hosts/agent-1/host-cert", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.IssueHostCertificateHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+
+	t.Run("rejects missing hostnames", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := bastion.NewCertificateAuthority(filepath.Join(t.TempDir(), "bastion_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetBastionCA(ca)
+
+		body := IssueHostCertificateRequest{PublicKey: newSignerPublicKey(t)}
+		bodyBytes, _ := json.Marshal(body)
+
+		req := httptest.NewRequest(http.MethodPost, "/bastion/hosts/agent-1/host-cert", bytes.NewReader(bodyBytes))
+		rec := httptest.NewRecorder()
+
+		handlers.IssueHostCertificateHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("rejects non-POST methods", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/hosts/agent-1/host-cert", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.IssueHostCertificateHandler(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
+
+func TestDeleteBastionGrantHandler(t *testing.T) {
+	t.Run("revokes the grant's certs and deletes it", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := bastion.NewCertificateAuthority(filepath.Join(t.TempDir(), "bastion_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetBastionCA(ca)
+		handlers.SetBastionGrants(bastion.NewGrantStore(""))
+
+		req := httptest.NewRequest(http.MethodDelete, "/bastion/grants/grant-1", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.DeleteBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp DeleteBastionGrantResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.ID != "grant-1" {
+			t.Errorf("expected id 'grant-1', got %q", resp.ID)
+		}
+	})
+
+	t.Run("returns 503 when no CA is configured", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodDelete, "/bastion/grants/grant-1", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.DeleteBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+
+	t.Run("rejects non-DELETE methods", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/grants/grant-1", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.DeleteBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
+
+func TestGetBastionKRLHandler(t *testing.T) {
+	t.Run("returns a KRL once revocations exist", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := bastion.NewCertificateAuthority(filepath.Join(t.TempDir(), "bastion_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetBastionCA(ca)
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/krl", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionKRLHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if rec.Body.Len() == 0 {
+			t.Error("expected a non-empty KRL body")
+		}
+	})
+
+	t.Run("returns 503 when no CA is configured", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/krl", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionKRLHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+}
+
+type fakeProvisioner struct {
+	name string
+	err  error
+}
+
+func (p *fakeProvisioner) Name() string { return p.name }
+
+func (p *fakeProvisioner) AuthorizeGrant(ctx context.Context, req bastion.GrantRequest) (*bastion.Grant, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &bastion.Grant{ID: "grant-1", Principal: "alice", Target: req.Target}, nil
+}
+
+func TestCreateBastionGrantHandler(t *testing.T) {
+	t.Run("authorizes a grant via the named provisioner", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.SetBastionProvisioners(map[string]bastion.Provisioner{
+			"ops": &fakeProvisioner{name: "ops"},
+		})
+
+		body, _ := json.Marshal(CreateBastionGrantRequest{
+			Provisioner: "ops",
+			Credential:  "claim.sig",
+			Target:      "agent-1",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/bastion/grants", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		var resp CreateBastionGrantResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.Grant == nil || resp.Grant.Principal != "alice" {
+			t.Errorf("unexpected grant in response: %+v", resp.Grant)
+		}
+
+		store.auditMu.Lock()
+		n := len(store.auditLog)
+		store.auditMu.Unlock()
+		if n == 0 {
+			t.Fatal("expected an audit entry for the created grant")
+		}
+		last := store.auditLog[n-1]
+		if last.Action != "create" || last.ResourceType != "bastion_grant" || last.ResourceID != resp.Grant.ID {
+			t.Errorf("unexpected audit entry: %+v", last)
+		}
+	})
+
+	t.Run("returns 503 for an unknown provisioner", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateBastionGrantRequest{
+			Provisioner: "nope",
+			Credential:  "claim.sig",
+			Target:      "agent-1",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/bastion/grants", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+
+	t.Run("returns 403 when the provisioner rejects the credential", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.SetBastionProvisioners(map[string]bastion.Provisioner{
+			"ops": &fakeProvisioner{name: "ops", err: errors.New("invalid signature")},
+		})
+
+		body, _ := json.Marshal(CreateBastionGrantRequest{
+			Provisioner: "ops",
+			Credential:  "claim.sig",
+			Target:      "agent-1",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/bastion/grants", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.CreateBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("rejects non-POST methods", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/grants", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.CreateBastionGrantHandler(rec, req)
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
+
+func TestGetBastionSessionRecordingHandlers(t *testing.T) {
+	writeRecording := func(t *testing.T, dir string) {
+		t.Helper()
+		recorder := bastion.NewFileRecorder(dir)
+		session, err := recorder.NewSession("session-1", "grant-1", "alice", "agent-1:22")
+		if err != nil {
+			t.Fatalf("NewSession failed: %v", err)
+		}
+		if err := session.WriteFrame(bastion.FrameClient, []byte("ls\n")); err != nil {
+			t.Fatalf("WriteFrame failed: %v", err)
+		}
+		if err := session.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+
+	t.Run("stream serves the raw recording", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		dir := t.TempDir()
+		writeRecording(t, dir)
+		handlers.SetBastionRecordingDir(dir)
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/session-1/stream", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionSessionStreamHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if rec.Body.Len() == 0 {
+			t.Error("expected non-empty stream body")
+		}
+	})
+
+	t.Run("stream returns 503 when recording isn't configured", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/session-1/stream", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionSessionStreamHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+
+	t.Run("stream returns 404 for an unknown session", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.SetBastionRecordingDir(t.TempDir())
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/does-not-exist/stream", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionSessionStreamHandler(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("replay decodes frames and meta", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		dir := t.TempDir()
+		writeRecording(t, dir)
+		handlers.SetBastionRecordingDir(dir)
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/session-1/replay", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionSessionReplayHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp BastionSessionReplayResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.Meta.Principal != "alice" {
+			t.Errorf("expected principal 'alice', got %q", resp.Meta.Principal)
+		}
+		if len(resp.Frames) != 1 || string(resp.Frames[0].Data) != "ls\n" {
+			t.Errorf("unexpected frames: %+v", resp.Frames)
+		}
+		if resp.Frames[0].Direction != "client" {
+			t.Errorf("expected direction 'client', got %q", resp.Frames[0].Direction)
+		}
+	})
+
+	t.Run("recording serves the exported asciicast file", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		dir := t.TempDir()
+		writeRecording(t, dir)
+		handlers.SetBastionRecordingDir(dir)
+
+		meta, err := bastion.ReadMeta(dir, "session-1")
+		if err != nil {
+			t.Fatalf("ReadMeta failed: %v", err)
+		}
+		recordingPath := filepath.Join(dir, strconv.Itoa(meta.StoppedAt.Year()),
+			fmt.Sprintf("%02d", int(meta.StoppedAt.Month())), "session-1.cast.gz")
+		store.bastionLogs.Add(bastion.SessionEvent{
+			SessionID: "session-1",
+			Event:     bastion.SessionEventRecordingAvailable,
+			Timestamp: meta.StoppedAt,
+			Data:      recordingPath,
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/session-1/recording", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionSessionRecordingHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		gz, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader failed: %v", err)
+		}
+		defer gz.Close()
+		decoded, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("decompress asciicast failed: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(decoded)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected a header line and one record line, got %d lines: %q", len(lines), decoded)
+		}
+		var header map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+			t.Fatalf("decode asciicast header: %v", err)
+		}
+		if header["version"] != float64(2) {
+			t.Errorf("expected version 2, got %v", header["version"])
+		}
+		if !strings.Contains(lines[1], `"i"`) || !strings.Contains(lines[1], "ls\\n") {
+			t.Errorf("expected an input record containing 'ls\\n', got %q", lines[1])
+		}
+	})
+
+	t.Run("recording returns 404 for an unknown session", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/does-not-exist/recording", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.GetBastionSessionRecordingHandler(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
+func TestGetBastionSessionTailHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	logger := bastion.NewLogger(store.bastionLogs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/session-1/tail", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.GetBastionSessionTailHandler(rec, req)
+		close(done)
+	}()
+
+	for i := 0; i < 20; i++ {
+		logger.LogCommand("session-2", "grant-2", "bob", "agent-2:22", "ls")
+		logger.LogCommand("session-1", "grant-1", "alice", "agent-1:22", "ls -la")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"session_id":"session-1"`) {
+		t.Fatalf("expected a streamed event for session-1, got body: %q", body)
+	}
+	if strings.Contains(body, `"session_id":"session-2"`) {
+		t.Fatalf("session ID wasn't filtered down to the requested session, got body: %q", body)
+	}
+}
+
+func TestStreamBastionSessionsHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	logger := bastion.NewLogger(store.bastionLogs)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/bastion/sessions/stream", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.StreamBastionSessionsHandler(rec, req)
+		close(done)
+	}()
+
+	for i := 0; i < 20; i++ {
+		logger.LogCommand("session-2", "grant-2", "bob", "agent-2:22", "ls")
+		logger.LogCommand("session-1", "grant-1", "alice", "agent-1:22", "ls -la")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"session_id":"session-1"`) {
+		t.Fatalf("expected a streamed event for session-1, got body: %q", body)
+	}
+	if !strings.Contains(body, `"session_id":"session-2"`) {
+		t.Fatalf("expected a streamed event for session-2 since this endpoint isn't scoped to one session, got body: %q", body)
+	}
+}
+
+func TestEnrollAgentHandler(t *testing.T) {
+	t.Run("issues a certificate for a valid secret", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		enrollments := agentca.NewEnrollmentStore("")
+		handlers.SetAgentCA(ca)
+		handlers.SetAgentEnrollments(enrollments)
+
+		secret, err := enrollments.Create("tenant-1", "agent-1", "standard", time.Hour)
+		if err != nil {
+			t.Fatalf("create enrollment secret: %v", err)
+		}
+
+		body, _ := json.Marshal(EnrollAgentRequest{Secret: secret.Secret, CSR: string(newTestCSR(t))})
+		req := httptest.NewRequest(http.MethodPost, "/agents/enroll", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.EnrollAgentHandler(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		var resp EnrollAgentResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.Certificate == "" {
+			t.Error("expected a non-empty certificate")
+		}
+	})
+
+	t.Run("rejects a secret that has already been used", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		enrollments := agentca.NewEnrollmentStore("")
+		handlers.SetAgentCA(ca)
+		handlers.SetAgentEnrollments(enrollments)
+
+		secret, err := enrollments.Create("tenant-1", "agent-1", "standard", time.Hour)
+		if err != nil {
+			t.Fatalf("create enrollment secret: %v", err)
+		}
+
+		body, _ := json.Marshal(EnrollAgentRequest{Secret: secret.Secret, CSR: string(newTestCSR(t))})
+
+		req := httptest.NewRequest(http.MethodPost, "/agents/enroll", bytes.NewReader(body))
+		handlers.EnrollAgentHandler(httptest.NewRecorder(), req)
+
+		req = httptest.NewRequest(http.MethodPost, "/agents/enroll", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.EnrollAgentHandler(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+		}
+	})
+
+	t.Run("returns 503 when no agent CA is configured", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(EnrollAgentRequest{Secret: "x", CSR: string(newTestCSR(t))})
+		req := httptest.NewRequest(http.MethodPost, "/agents/enroll", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.EnrollAgentHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+}
+
+func TestReenrollAgentHandler(t *testing.T) {
+	t.Run("issues a renewed certificate using the peer certificate's identity", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetAgentCA(ca)
+
+		existing, err := ca.IssueCertificate(newTestCSR(t), "tenant-1", "agent-1", "standard", time.Hour)
+		if err != nil {
+			t.Fatalf("issue initial cert: %v", err)
+		}
+
+		body, _ := json.Marshal(ReenrollAgentRequest{CSR: string(newTestCSR(t))})
+		req := httptest.NewRequest(http.MethodPost, "/agents/reenroll", bytes.NewReader(body))
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{existing}}
+		rec := httptest.NewRecorder()
+
+		handlers.ReenrollAgentHandler(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("rejects requests without a client certificate", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetAgentCA(ca)
+
+		body, _ := json.Marshal(ReenrollAgentRequest{CSR: string(newTestCSR(t))})
+		req := httptest.NewRequest(http.MethodPost, "/agents/reenroll", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		handlers.ReenrollAgentHandler(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
+
+func TestHandlersStoragePersistence(t *testing.T) {
+	t.Run("persists agents and rules, and reloads them on a fresh Store", func(t *testing.T) {
+		persist, err := storage.Open(filepath.Join(t.TempDir(), "shield.db"))
+		if err != nil {
+			t.Fatalf("open storage: %v", err)
+		}
+		defer persist.Close()
+
+		handlers := NewHandlers(NewStore(), "1.0.0")
+		handlers.SetStorage(persist)
+
+		handlers.RegisterAgent("agent-1", "sarai", "10.0.0.1")
+
+		body, _ := json.Marshal(CreateRuleRequest{Domain: "evil.example", Action: "block"})
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateRuleHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		// A fresh Handlers backed by the same storage should hydrate both.
+		reloaded := NewHandlers(NewStore(), "1.0.0")
+		reloaded.SetStorage(persist)
+		if err := reloaded.LoadFromStorage(); err != nil {
+			t.Fatalf("load from storage: %v", err)
+		}
+
+		agentsReq := httptest.NewRequest(http.MethodGet, "/agents", nil)
+		agentsRec := httptest.NewRecorder()
+		reloaded.ListAgentsHandler(agentsRec, agentsReq)
+		var agentsResp AgentListResponse
+		if err := json.Unmarshal(agentsRec.Body.Bytes(), &agentsResp); err != nil {
+			t.Fatalf("decode agents response: %v", err)
+		}
+		if agentsResp.Total != 1 || agentsResp.Agents[0].ID != "agent-1" {
+			t.Errorf("expected reloaded agent 'agent-1', got %+v", agentsResp)
+		}
+
+		rulesReq := httptest.NewRequest(http.MethodGet, "/rules", nil)
+		rulesRec := httptest.NewRecorder()
+		reloaded.ListRulesHandler(rulesRec, rulesReq)
+		var rulesResp RuleListResponse
+		if err := json.Unmarshal(rulesRec.Body.Bytes(), &rulesResp); err != nil {
+			t.Fatalf("decode rules response: %v", err)
+		}
+		if rulesResp.Total != 1 || rulesResp.Rules[0].Domain != "evil.example" {
+			t.Errorf("expected reloaded rule for 'evil.example', got %+v", rulesResp)
+		}
+	})
+}
+
+func TestAgentCRLHandler(t *testing.T) {
+	t.Run("returns a PEM-encoded CRL", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		ca, err := agentca.NewAgentCA(filepath.Join(t.TempDir(), "agent_ca_key"))
+		if err != nil {
+			t.Fatalf("create CA: %v", err)
+		}
+		handlers.SetAgentCA(ca)
+
+		req := httptest.NewRequest(http.MethodGet, "/agents/crl", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.AgentCRLHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/pkix-crl" {
+			t.Errorf("expected Content-Type application/pkix-crl, got %q", ct)
+		}
+	})
+
+	t.Run("returns 503 when no agent CA is configured", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/agents/crl", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.AgentCRLHandler(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+	})
+}
+
+func TestAdminTenantHandlers(t *testing.T) {
+	t.Run("creates, updates, and deletes a tenant", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateTenantRequest{ID: "tenant-1", Mode: "fleet"})
+		req := httptest.NewRequest(http.MethodPost, "/admin/tenants", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateTenantHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		updateBody, _ := json.Marshal(UpdateTenantRequest{Mode: "isolated"})
+		updateReq := httptest.NewRequest(http.MethodPatch, "/admin/tenants/tenant-1", bytes.NewReader(updateBody))
+		updateRec := httptest.NewRecorder()
+		handlers.TenantHandler(updateRec, updateReq)
+		if updateRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, updateRec.Code, updateRec.Body.String())
+		}
+		var updated Tenant
+		if err := json.Unmarshal(updateRec.Body.Bytes(), &updated); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if updated.Mode != "isolated" {
+			t.Errorf("expected mode 'isolated', got %q", updated.Mode)
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/admin/tenants/tenant-1", nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.TenantHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+
+		listReq := httptest.NewRequest(http.MethodGet, "/admin/tenants", nil)
+		listRec := httptest.NewRecorder()
+		handlers.ListTenantsHandler(listRec, listReq)
+		var listResp TenantListResponse
+		if err := json.Unmarshal(listRec.Body.Bytes(), &listResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if listResp.Total != 0 {
+			t.Errorf("expected no tenants after delete, got %+v", listResp)
+		}
+	})
+
+	t.Run("rejects duplicate tenant ID", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateTenantRequest{ID: "tenant-1"})
+		req := httptest.NewRequest(http.MethodPost, "/admin/tenants", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateTenantHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+		}
+
+		dupReq := httptest.NewRequest(http.MethodPost, "/admin/tenants", bytes.NewReader(body))
+		dupRec := httptest.NewRecorder()
+		handlers.CreateTenantHandler(dupRec, dupReq)
+		if dupRec.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d", http.StatusConflict, dupRec.Code)
+		}
+	})
+}
+
+func TestAdminAgentHandlers(t *testing.T) {
+	t.Run("creates, updates, and deletes an agent", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateAgentRequest{ID: "agent-9", Name: "rhea", IP: "10.0.0.9"})
+		req := httptest.NewRequest(http.MethodPost, "/admin/agents", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateAgentHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+
+		updateBody, _ := json.Marshal(UpdateAgentRequest{Name: "rhea-2"})
+		updateReq := httptest.NewRequest(http.MethodPatch, "/admin/agents/agent-9", bytes.NewReader(updateBody))
+		updateRec := httptest.NewRecorder()
+		handlers.AgentAdminHandler(updateRec, updateReq)
+		if updateRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, updateRec.Code, updateRec.Body.String())
+		}
+		var updated Agent
+		if err := json.Unmarshal(updateRec.Body.Bytes(), &updated); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if updated.Name != "rhea-2" {
+			t.Errorf("expected name 'rhea-2', got %q", updated.Name)
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/admin/agents/agent-9", nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.AgentAdminHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+
+		getReq := httptest.NewRequest(http.MethodDelete, "/admin/agents/agent-9", nil)
+		getRec := httptest.NewRecorder()
+		handlers.AgentAdminHandler(getRec, getReq)
+		if getRec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d after delete, got %d", http.StatusNotFound, getRec.Code)
+		}
+	})
+}
+
+func TestAdminRuleHandlers(t *testing.T) {
+	t.Run("creates, replaces, and deletes a rule", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateRuleRequest{Domain: "evil.example", Action: "block", Enabled: true})
+		req := httptest.NewRequest(http.MethodPost, "/admin/rules", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateRuleAdminHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var created Rule
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+
+		replaceBody, _ := json.Marshal(UpdateRuleRequest{Domain: "still-evil.example", Action: "block", Enabled: false})
+		replaceReq := httptest.NewRequest(http.MethodPut, "/admin/rules/"+created.ID, bytes.NewReader(replaceBody))
+		replaceRec := httptest.NewRecorder()
+		handlers.RuleAdminHandler(replaceRec, replaceReq)
+		if replaceRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, replaceRec.Code, replaceRec.Body.String())
+		}
+		var replaced Rule
+		if err := json.Unmarshal(replaceRec.Body.Bytes(), &replaced); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if replaced.Domain != "still-evil.example" || replaced.Enabled {
+			t.Errorf("expected replaced rule with domain 'still-evil.example' and disabled, got %+v", replaced)
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/admin/rules/"+created.ID, nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.RuleAdminHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+	})
+}
+
+func TestAdminTokenHandlers(t *testing.T) {
+	t.Run("creates and deletes a token", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateTokenRequest{TenantID: "tenant-1", Name: "ci-runner"})
+		req := httptest.NewRequest(http.MethodPost, "/admin/tokens", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateTokenHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var created Token
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if created.Token == "" {
+			t.Fatal("expected a generated token value")
+		}
+
+		listReq := httptest.NewRequest(http.MethodGet, "/admin/tokens", nil)
+		listRec := httptest.NewRecorder()
+		handlers.ListTokensHandler(listRec, listReq)
+		var listResp TokenListResponse
+		if err := json.Unmarshal(listRec.Body.Bytes(), &listResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if listResp.Total != 1 {
+			t.Errorf("expected 1 token, got %+v", listResp)
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/admin/tokens/"+created.Token, nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.TokenHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+	})
+}
+
+func TestAuditHandlers(t *testing.T) {
+	t.Run("records mutations and verifies the hash chain", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		tenantBody, _ := json.Marshal(CreateTenantRequest{ID: "tenant-1"})
+		createReq := httptest.NewRequest(http.MethodPost, "/admin/tenants", bytes.NewReader(tenantBody))
+		createRec := httptest.NewRecorder()
+		handlers.CreateTenantHandler(createRec, createReq)
+		if createRec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, createRec.Code, createRec.Body.String())
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/admin/tenants/tenant-1", nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.TenantHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+
+		auditReq := httptest.NewRequest(http.MethodGet, "/admin/audit", nil)
+		auditRec := httptest.NewRecorder()
+		handlers.AuditHandler(auditRec, auditReq)
+		var auditResp AuditListResponse
+		if err := json.Unmarshal(auditRec.Body.Bytes(), &auditResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if auditResp.Total != 2 {
+			t.Fatalf("expected 2 audit entries, got %+v", auditResp)
+		}
+		if auditResp.Entries[0].Action != "delete" || auditResp.Entries[1].Action != "create" {
+			t.Errorf("expected most-recent-first [delete, create], got %+v", auditResp.Entries)
+		}
+
+		verifyReq := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+		verifyRec := httptest.NewRecorder()
+		handlers.AuditVerifyHandler(verifyRec, verifyReq)
+		var verifyResp AuditVerifyResponse
+		if err := json.Unmarshal(verifyRec.Body.Bytes(), &verifyResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if !verifyResp.Valid || verifyResp.BrokenAt != -1 {
+			t.Errorf("expected an intact chain, got %+v", verifyResp)
+		}
+	})
+
+	t.Run("detects a tampered entry", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		handlers.recordAudit("token:admin", "create", "tenant", "tenant-1", nil, Tenant{ID: "tenant-1"})
+		handlers.recordAudit("token:admin", "update", "tenant", "tenant-1", Tenant{ID: "tenant-1"}, Tenant{ID: "tenant-1", Mode: "fleet"})
+
+		store.auditMu.Lock()
+		store.auditLog[0].Action = "delete"
+		store.auditMu.Unlock()
+
+		verifyReq := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+		verifyRec := httptest.NewRecorder()
+		handlers.AuditVerifyHandler(verifyRec, verifyReq)
+		var verifyResp AuditVerifyResponse
+		if err := json.Unmarshal(verifyRec.Body.Bytes(), &verifyResp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if verifyResp.Valid {
+			t.Fatal("expected tampered chain to be reported invalid")
+		}
+		if verifyResp.BrokenAt != 1 {
+			t.Errorf("expected break reported at most-recent-first index 1, got %d", verifyResp.BrokenAt)
+		}
+	})
+}
+
+func TestBootstrapAdminToken(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+
+	if err := bootstrapAdminToken(handlers); err != nil {
+		t.Fatalf("bootstrapAdminToken: %v", err)
+	}
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if len(store.apiTokens) != 1 {
+		t.Fatalf("expected exactly 1 bootstrapped token, got %d", len(store.apiTokens))
+	}
+	for _, tok := range store.apiTokens {
+		if !hasScope(tok.Scopes, ScopeAdmin) {
+			t.Errorf("expected the bootstrap token to hold ScopeAdmin, got %v", tok.Scopes)
+		}
+	}
+}
+
+func TestAPITokenHandlers(t *testing.T) {
+	t.Run("rejects an unknown scope", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{"rules:delete-everything"}})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("creates and deletes a scoped token", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}, Name: "agent-1"})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var created APIToken
+		if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if created.Token == "" {
+			t.Fatal("expected a generated token value")
+		}
+
+		if _, ok := handlers.lookupAPIToken(created.Token); !ok {
+			t.Fatal("expected token to be stored")
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/auth/token/"+created.Token, nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.DeleteAPITokenHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+		if _, ok := handlers.lookupAPIToken(created.Token); ok {
+			t.Fatal("expected token to be removed")
+		}
+	})
+
+	t.Run("deletes a token by accessor id", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}})
+		req := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(rec, req)
+		var created APIToken
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		if created.AccessorID == "" {
+			t.Fatal("expected a generated accessor id")
+		}
+
+		deleteReq := httptest.NewRequest(http.MethodDelete, "/auth/token/"+created.AccessorID, nil)
+		deleteRec := httptest.NewRecorder()
+		handlers.DeleteAPITokenHandler(deleteRec, deleteReq)
+		if deleteRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, deleteRec.Code, deleteRec.Body.String())
+		}
+		if _, ok := handlers.lookupAPIToken(created.Token); ok {
+			t.Fatal("expected token to be removed")
+		}
+	})
+
+	t.Run("lists tokens with the secret redacted", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}, Name: "agent-1"})
+		createReq := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		createRec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(createRec, createReq)
+
+		listReq := httptest.NewRequest(http.MethodGet, "/auth/tokens", nil)
+		listRec := httptest.NewRecorder()
+		handlers.ListAPITokensHandler(listRec, listReq)
+		if listRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, listRec.Code, listRec.Body.String())
+		}
+		var listed ListAPITokensResponse
+		if err := json.Unmarshal(listRec.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if listed.Total != 1 || len(listed.Tokens) != 1 {
+			t.Fatalf("expected 1 token, got %d", listed.Total)
+		}
+		if listed.Tokens[0].Token != "" {
+			t.Fatal("expected the secret to be redacted from the listing")
+		}
+	})
+
+	t.Run("renews a renewable token and rejects a non-renewable one", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}, TTLSeconds: 3600, Renewable: true})
+		createReq := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		createRec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(createRec, createReq)
+		var created APIToken
+		json.Unmarshal(createRec.Body.Bytes(), &created)
+		if created.ExpiresAt == nil {
+			t.Fatal("expected an expiry to be set")
+		}
+
+		renewReq := httptest.NewRequest(http.MethodPost, "/auth/token/renew", nil)
+		renewReq.Header.Set("Authorization", "Bearer "+created.Token)
+		renewRec := httptest.NewRecorder()
+		handlers.RenewAPITokenHandler(renewRec, renewReq)
+		if renewRec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, renewRec.Code, renewRec.Body.String())
+		}
+		var renewed RenewAPITokenResponse
+		if err := json.Unmarshal(renewRec.Body.Bytes(), &renewed); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if !renewed.ExpiresAt.After(*created.ExpiresAt) {
+			t.Fatal("expected renewal to push the expiry forward")
+		}
+
+		body, _ = json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}})
+		createReq = httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		createRec = httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(createRec, createReq)
+		var notRenewable APIToken
+		json.Unmarshal(createRec.Body.Bytes(), &notRenewable)
+
+		renewReq = httptest.NewRequest(http.MethodPost, "/auth/token/renew", nil)
+		renewReq.Header.Set("Authorization", "Bearer "+notRenewable.Token)
+		renewRec = httptest.NewRecorder()
+		handlers.RenewAPITokenHandler(renewRec, renewReq)
+		if renewRec.Code != http.StatusForbidden {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, renewRec.Code, renewRec.Body.String())
+		}
+	})
+}
+
+func TestIntrospectHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	cfg := testAuthConfig(t, "mgmt-token", "agent-token")
+	introspect := IntrospectHandler(handlers, cfg)
+
+	t.Run("reports an unknown token as invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/introspect", nil)
+		req.Header.Set("Authorization", "Bearer does-not-exist")
+		rec := httptest.NewRecorder()
+		introspect(rec, req)
+		var result IntrospectResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if result.Valid {
+			t.Fatal("expected an unrecognized token to be invalid")
+		}
+	})
+
+	t.Run("resolves a scoped api token", func(t *testing.T) {
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}})
+		createReq := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		createRec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(createRec, createReq)
+		var created APIToken
+		json.Unmarshal(createRec.Body.Bytes(), &created)
+
+		req := httptest.NewRequest(http.MethodGet, "/auth/introspect", nil)
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		rec := httptest.NewRecorder()
+		introspect(rec, req)
+		var result IntrospectResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if !result.Valid || result.Kind != "api_token" || result.AccessorID != created.AccessorID {
+			t.Fatalf("expected a valid api_token result, got %+v", result)
+		}
+	})
+
+	t.Run("resolves the blanket management token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/introspect", nil)
+		req.Header.Set("Authorization", "Bearer mgmt-token")
+		rec := httptest.NewRecorder()
+		introspect(rec, req)
+		var result IntrospectResponse
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		if !result.Valid || result.Kind != "management" {
+			t.Fatalf("expected a valid management result, got %+v", result)
+		}
+	})
+}
+
+func TestScopedOr(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	cfg := testAuthConfig(t, "mgmt-token", "agent-token")
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := handlers.ScopedOr(ScopeExecCheck, ManagementAuth(cfg))(next)
+
+	t.Run("falls back to the blanket management token", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/exec/check", nil)
+		req.Header.Set("Authorization", "Bearer mgmt-token")
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !called {
+			t.Fatalf("expected the fallback to authenticate, got status %d", rec.Code)
+		}
+	})
+
+	t.Run("accepts a correctly scoped token", func(t *testing.T) {
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeExecCheck)}})
+		createReq := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		createRec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(createRec, createReq)
+		var created APIToken
+		json.Unmarshal(createRec.Body.Bytes(), &created)
+
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/exec/check", nil)
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !called {
+			t.Fatalf("expected the scoped token to authenticate, got status %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects a token missing the required scope without falling back", func(t *testing.T) {
+		body, _ := json.Marshal(CreateAPITokenRequest{Scopes: []string{string(ScopeAgentsRead)}})
+		createReq := httptest.NewRequest(http.MethodPost, "/auth/token", bytes.NewReader(body))
+		createRec := httptest.NewRecorder()
+		handlers.CreateAPITokenHandler(createRec, createReq)
+		var created APIToken
+		json.Unmarshal(createRec.Body.Bytes(), &created)
+
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/exec/check", nil)
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden || called {
+			t.Fatalf("expected the mismatched scope to be rejected, got status %d", rec.Code)
+		}
+	})
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := ValidateAgainstSchema(createRuleRequestSchema)(next)
+
+	t.Run("passes a GET through untouched", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/rules", nil)
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !called {
+			t.Fatalf("expected GET to pass through, got status %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects a missing required field", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader([]byte(`{"domain":"evil.example"}`)))
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || called {
+			t.Fatalf("expected missing action to be rejected, got status %d", rec.Code)
+		}
+	})
+
+	t.Run("rejects a value outside the enum", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader([]byte(`{"action":"ignore"}`)))
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || called {
+			t.Fatalf("expected an out-of-enum action to be rejected, got status %d", rec.Code)
+		}
+	})
+
+	t.Run("passes a valid body through and leaves it readable", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/rules", bytes.NewReader([]byte(`{"action":"block","domain":"evil.example"}`)))
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || !called {
+			t.Fatalf("expected a valid body to pass through, got status %d", rec.Code)
+		}
+	})
+}
+
+func TestLogsStreamHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+	handlers.RegisterAgent("agent-2", "Other Agent", "192.168.1.2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/logs/stream?agent_id=agent-1", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.LogsStreamHandler(rec, req)
+		close(done)
+	}()
+
+	// Give the handler a moment to subscribe before anything is published;
+	// addLog is the only way to publish, so drive it through ExecCheckHandler.
+	for i := 0; i < 20; i++ {
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-2", Command: "ls"})
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "ls -la"})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"agent_id":"agent-1"`) {
+		t.Fatalf("expected a streamed event for agent-1, got body: %q", body)
+	}
+	if strings.Contains(body, `"agent_id":"agent-2"`) {
+		t.Fatalf("agent_id filter leaked an event for agent-2, got body: %q", body)
+	}
+}
+
+func TestLogsStreamHandlerSinceReplaysBufferedEntries(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+	since := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "ls"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/logs/stream?since="+url.QueryEscape(since.Format(time.RFC3339Nano)), nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.LogsStreamHandler(rec, req)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	if !strings.Contains(rec.Body.String(), `"agent_id":"agent-1"`) {
+		t.Fatalf("expected since= to replay the entry recorded after it, got body: %q", rec.Body.String())
+	}
+}
+
+func TestAgentsStreamHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/agents/stream", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.AgentsStreamHandler(rec, req)
+		close(done)
+	}()
+
+	for i := 0; i < 20; i++ {
+		pauseReq := httptest.NewRequest(http.MethodPost, "/agents/agent-1/pause", nil)
+		handlers.PauseAgentHandler(httptest.NewRecorder(), pauseReq)
+		resumeReq := httptest.NewRequest(http.MethodPost, "/agents/agent-1/resume", nil)
+		handlers.ResumeAgentHandler(httptest.NewRecorder(), resumeReq)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `"status":"paused"`) {
+		t.Fatalf("expected a streamed pause event, got body: %q", body)
+	}
+}
+
+func TestExecEventsHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+	store.mu.Lock()
+	store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "rm -rf", Action: "block", Enabled: true}
+	store.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/exec/events", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		handlers.ExecEventsHandler(rec, req)
+		close(done)
+	}()
+
+	for i := 0; i < 20; i++ {
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "ls -la"})
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "rm -rf /"})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	allowedIdx := strings.Index(body, `"allowed":true`)
+	blockedIdx := strings.Index(body, `"allowed":false`)
+	if allowedIdx == -1 {
+		t.Fatalf("expected a streamed allowed event, got body: %q", body)
+	}
+	if blockedIdx == -1 {
+		t.Fatalf("expected a streamed blocked event, got body: %q", body)
+	}
+	if allowedIdx > blockedIdx {
+		t.Error("expected the allowed event to arrive before the blocked event, matching request order")
+	}
+	if !strings.Contains(body, `"rule_id":"rule-1"`) {
+		t.Fatalf("expected the blocked event to carry rule_id, got body: %q", body)
+	}
+
+	t.Run("action filter only streams matching decisions", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "rm -rf", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(http.MethodGet, "/exec/events?action=blocked", nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+
+		done := make(chan struct{})
+		go func() {
+			handlers.ExecEventsHandler(rec, req)
+			close(done)
+		}()
+
+		for i := 0; i < 20; i++ {
+			execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "ls -la"})
+			execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "rm -rf /"})
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		cancel()
+		<-done
+
+		body := rec.Body.String()
+		if !strings.Contains(body, `"allowed":false`) {
+			t.Fatalf("expected a streamed blocked event, got body: %q", body)
+		}
+		if strings.Contains(body, `"allowed":true`) {
+			t.Fatalf("action filter leaked an allowed event, got body: %q", body)
+		}
+	})
+}
+
+func TestApprovalHandlers(t *testing.T) {
+	t.Run("lists pending approvals oldest first", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		first := store.createPendingApproval("agent-1", "curl a.example.com", "rule-1")
+		store.createPendingApproval("agent-1", "curl b.example.com", "rule-1")
+
+		req := httptest.NewRequest(http.MethodGet, "/approvals", nil)
+		rec := httptest.NewRecorder()
+		handlers.ListApprovalsHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var resp ApprovalListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Total != 2 {
+			t.Fatalf("expected 2 pending approvals, got %d", resp.Total)
+		}
+		if resp.Approvals[0].ID != first.ID {
+			t.Errorf("expected oldest approval %q first, got %q", first.ID, resp.Approvals[0].ID)
+		}
+	})
+
+	t.Run("resolves a pending approval and delivers the decision", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		approval := store.createPendingApproval("agent-1", "curl example.com", "rule-1")
+
+		reqBody, _ := json.Marshal(ResolveApprovalRequest{Decision: "allow", Reason: "looks fine"})
+		req := httptest.NewRequest(http.MethodPost, "/approvals/"+approval.ID, bytes.NewReader(reqBody))
+		req = req.WithContext(withActor(req.Context(), "operator-1"))
+		rec := httptest.NewRecorder()
+
+		handlers.ResolveApprovalHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		select {
+		case decision := <-approval.decisionCh:
+			if !decision.allow {
+				t.Error("expected the decision delivered to awaitApproval to allow")
+			}
+		default:
+			t.Fatal("expected a decision to be delivered to the pending approval's channel")
+		}
+	})
+
+	t.Run("rejects an unknown approval id", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		reqBody, _ := json.Marshal(ResolveApprovalRequest{Decision: "deny"})
+		req := httptest.NewRequest(http.MethodPost, "/approvals/does-not-exist", bytes.NewReader(reqBody))
+		rec := httptest.NewRecorder()
+
+		handlers.ResolveApprovalHandler(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("rejects an invalid decision", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		approval := store.createPendingApproval("agent-1", "curl example.com", "rule-1")
+
+		reqBody, _ := json.Marshal(ResolveApprovalRequest{Decision: "maybe"})
+		req := httptest.NewRequest(http.MethodPost, "/approvals/"+approval.ID, bytes.NewReader(reqBody))
+		rec := httptest.NewRecorder()
+
+		handlers.ResolveApprovalHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestStatsHandlers(t *testing.T) {
+	t.Run("rule stats time series reflects allowed, blocked, and unique agents", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Agent One", "192.168.1.1")
+		handlers.RegisterAgent("agent-2", "Agent Two", "192.168.1.2")
+
+		store.mu.Lock()
+		store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "rm -rf", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "rm -rf /"})
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-2", Command: "rm -rf /"})
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "ls -la"})
+
+		query := "?start=" + time.Now().Add(-time.Hour).Format(time.RFC3339) +
+			"&end=" + time.Now().Add(time.Hour).Format(time.RFC3339) + "&step=1m"
+		req := httptest.NewRequest(http.MethodGet, "/stats/rules/rule-1"+query, nil)
+		rec := httptest.NewRecorder()
+
+		handlers.StatsRuleHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp RuleStatsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Totals.Blocked != 2 {
+			t.Errorf("expected 2 blocked hits, got %d", resp.Totals.Blocked)
+		}
+		if resp.Totals.UniqueAgents != 2 {
+			t.Errorf("expected 2 unique agents, got %d", resp.Totals.UniqueAgents)
+		}
+	})
+
+	t.Run("rejects a missing rule id", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/rules/", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.StatsRuleHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("top rules ranks the noisiest rule first", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+		handlers.RegisterAgent("agent-1", "Agent One", "192.168.1.1")
+
+		store.mu.Lock()
+		store.rules["quiet"] = &Rule{ID: "quiet", Pattern: "wget", Action: "block", Enabled: true}
+		store.rules["noisy"] = &Rule{ID: "noisy", Pattern: "curl", Action: "block", Enabled: true}
+		store.mu.Unlock()
+
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "wget http://example.com"})
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "curl http://example.com"})
+		execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "curl http://example.org"})
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/top?by=rule&since=1h&limit=10", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.StatsTopHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+
+		var resp TopStatsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Rules) < 2 {
+			t.Fatalf("expected at least 2 ranked rules, got %d", len(resp.Rules))
+		}
+		if resp.Rules[0].RuleID != "noisy" {
+			t.Errorf("expected 'noisy' to rank first, got %q", resp.Rules[0].RuleID)
+		}
+	})
+
+	t.Run("rejects an invalid by value", func(t *testing.T) {
+		store := NewStore()
+		handlers := NewHandlers(store, "1.0.0")
+
+		req := httptest.NewRequest(http.MethodGet, "/stats/top?by=bogus", nil)
+		rec := httptest.NewRecorder()
+
+		handlers.StatsTopHandler(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+// testAuthConfig builds an AuthConfig with static management/agent tokens,
+// for tests that only care about the blanket-token path.
+func testAuthConfig(t *testing.T, mgmtToken, agentToken string) *AuthConfig {
+	t.Helper()
+	mgmt, err := NewAuth(mgmtToken)
+	if err != nil {
+		t.Fatalf("management auth: %v", err)
+	}
+	agent, err := NewAuth(agentToken)
+	if err != nil {
+		t.Fatalf("agent auth: %v", err)
+	}
+	return &AuthConfig{Management: mgmt, Agent: agent}
+}
+
+// execCheck is a small helper for driving ExecCheckHandler (and therefore
+// addLog) from stream handler tests.
+func execCheck(t *testing.T, handlers *Handlers, req ExecCheckRequest) {
+	t.Helper()
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("marshal exec check request: %v", err)
+	}
+	httpReq := httptest.NewRequest(http.MethodPost, "/exec/check", bytes.NewReader(data))
+	handlers.ExecCheckHandler(httptest.NewRecorder(), httpReq)
+}
+
+func TestExecCheckMetrics(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+	handlers.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+	store.mu.Lock()
+	store.rules["rule-1"] = &Rule{ID: "rule-1", Pattern: "rm -rf", Action: "block", Enabled: true}
+	store.mu.Unlock()
+
+	execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "ls -la"})
+	execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "rm -rf /"})
+	execCheck(t, handlers, ExecCheckRequest{AgentID: "agent-1", Command: "rm -rf /"})
+
+	if got := testutil.ToFloat64(handlers.metrics.checksTotal.WithLabelValues("agent-1", "allow")); got != 1 {
+		t.Errorf("expected 1 allowed check, got %v", got)
+	}
+	if got := testutil.ToFloat64(handlers.metrics.checksTotal.WithLabelValues("agent-1", "block")); got != 2 {
+		t.Errorf("expected 2 blocked checks, got %v", got)
+	}
+	if got := testutil.ToFloat64(handlers.metrics.blockedTotal.WithLabelValues("agent-1", "rule-1")); got != 2 {
+		t.Errorf("expected 2 blocks attributed to rule-1, got %v", got)
+	}
+	if got := testutil.CollectAndCount(handlers.metrics.checkDuration); got != 1 {
+		t.Errorf("expected the duration histogram to be registered, got %d collectors", got)
+	}
+	if got := testutil.ToFloat64(handlers.metrics.requestsTotal); got != 3 {
+		t.Errorf("expected 3 total requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(handlers.metrics.requestsBlocked); got != 2 {
+		t.Errorf("expected 2 blocked requests, got %v", got)
+	}
+
+	handlers.metrics.refreshGauges(store)
+	if got := testutil.ToFloat64(handlers.metrics.agents.WithLabelValues("active")); got != 1 {
+		t.Errorf("expected 1 active agent, got %v", got)
+	}
+	if got := testutil.ToFloat64(handlers.metrics.rules.WithLabelValues("block", "true")); got != 1 {
+		t.Errorf("expected 1 enabled block rule, got %v", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	handlers.MetricsHandler(rec, req)
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `plasma_exec_checks_total{action="allow",agent="agent-1"} 1`) {
+		t.Errorf("expected plasma_exec_checks_total in /metrics body, got %q", body)
+	}
+	if !strings.Contains(body, `plasma_exec_checks_blocked_total{agent="agent-1",rule="rule-1"} 2`) {
+		t.Errorf("expected plasma_exec_checks_blocked_total in /metrics body, got %q", body)
+	}
+	if !strings.Contains(body, "plasma_agents_total 1") {
+		t.Errorf("expected the legacy plasma_agents_total sample to still be present, got %q", body)
+	}
+	if !strings.Contains(body, "plasma_requests_total 3") {
+		t.Errorf("expected plasma_requests_total in /metrics body, got %q", body)
+	}
 }
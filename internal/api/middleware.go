@@ -1,45 +1,98 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strings"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
 )
 
 // AuthConfig holds authentication configuration.
 type AuthConfig struct {
-	ManagementToken string
-	AgentToken      string
+	// Management and Agent validate credentials for the management API and
+	// the agent-facing endpoints respectively. Construct with NewAuth from
+	// an auth-scheme string (see ServerConfig.ManagementAuth/AgentAuth)
+	// rather than comparing a single hard-coded token directly.
+	Management Auth
+	Agent      Auth
+	// AdminToken authenticates the /admin/* CRUD API for operators who
+	// don't hold a commodore-tier agent client certificate. Leave empty to
+	// require mTLS only.
+	AdminToken string
+}
+
+type contextKey int
+
+const actorContextKey contextKey = iota
+
+// withActor attaches the authenticated actor to ctx, for handlers to record
+// in the audit log or as a record's createdBy/resolvedBy.
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey, actor)
 }
 
-// ManagementAuth middleware validates the management bearer token.
+// actorFromContext returns the actor attached by AdminAuth, ManagementAuth,
+// or AgentAuth, or "unknown" if none was attached (shouldn't happen for a
+// request that passed one of them).
+func actorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey).(string); ok && actor != "" {
+		return actor
+	}
+	return "unknown"
+}
+
+// ManagementAuth middleware validates the management API credential via
+// cfg.Management, attaching the resolved principal to the request context
+// (see actorFromContext) so handlers and log entries can record who acted.
 func ManagementAuth(cfg *AuthConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := extractBearerToken(r)
-			if token == "" {
-				writeError(w, http.StatusUnauthorized, "missing authorization token")
+			principal, ok := cfg.Management.Validate(r)
+			if !ok {
+				writeError(w, http.StatusForbidden, "invalid management credential")
 				return
 			}
-			if token != cfg.ManagementToken {
-				writeError(w, http.StatusForbidden, "invalid management token")
+			next.ServeHTTP(w, r.WithContext(withActor(r.Context(), principal)))
+		})
+	}
+}
+
+// AgentAuth middleware validates the agent credential via cfg.Agent,
+// attaching the resolved principal to the request context the same way
+// ManagementAuth does.
+func AgentAuth(cfg *AuthConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := cfg.Agent.Validate(r)
+			if !ok {
+				writeError(w, http.StatusForbidden, "invalid agent credential")
 				return
 			}
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(withActor(r.Context(), principal)))
 		})
 	}
 }
 
-// AgentAuth middleware validates the agent bearer token.
-func AgentAuth(cfg *AuthConfig) func(http.Handler) http.Handler {
+// AgentCertAuth middleware derives agent identity from the caller's mTLS
+// client certificate instead of a bearer token, replacing AgentAuth for
+// deployments with an agentca.AgentCA configured. The server's tls.Config
+// must already require and verify client certs against ca.CertPool(); this
+// middleware rejects certificates that have since been revoked.
+func AgentCertAuth(ca *agentca.AgentCA) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			token := extractBearerToken(r)
-			if token == "" {
-				writeError(w, http.StatusUnauthorized, "missing authorization token")
+			if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+				writeError(w, http.StatusUnauthorized, "client certificate required")
+				return
+			}
+			cert := r.TLS.PeerCertificates[0]
+			if ca.IsRevoked(cert.SerialNumber) {
+				writeError(w, http.StatusForbidden, "certificate revoked")
 				return
 			}
-			if token != cfg.AgentToken {
-				writeError(w, http.StatusForbidden, "invalid agent token")
+			if _, err := agentca.IdentityFromCertificate(cert); err != nil {
+				writeError(w, http.StatusForbidden, err.Error())
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -47,6 +100,52 @@ func AgentAuth(cfg *AuthConfig) func(http.Handler) http.Handler {
 	}
 }
 
+// AgentAccess middleware authenticates agent-facing requests by mTLS
+// client certificate when an agentca.AgentCA is configured, falling back
+// to the static agent bearer token otherwise. This lets operators migrate
+// from fleet.TokenConfig tokens to per-agent certificates by wiring up an
+// AgentCA, without a hard cutover.
+func AgentAccess(cfg *AuthConfig, handlers *Handlers) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ca := handlers.AgentCA(); ca != nil {
+				AgentCertAuth(ca)(next).ServeHTTP(w, r)
+				return
+			}
+			AgentAuth(cfg)(next).ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminAuth middleware authorizes the /admin/* CRUD API. Identity comes from
+// either a commodore-tier agent mTLS client certificate (the repo's
+// highest-privilege tier, reused here rather than standing up a second CA),
+// or a bearer token matching cfg.AdminToken. On success, the authenticated
+// actor is attached to the request context via withActor, for handlers to
+// record in the audit log.
+func AdminAuth(cfg *AuthConfig, handlers *Handlers) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ca := handlers.AgentCA(); ca != nil && r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+				cert := r.TLS.PeerCertificates[0]
+				if !ca.IsRevoked(cert.SerialNumber) {
+					if identity, err := agentca.IdentityFromCertificate(cert); err == nil && identity.Tier == "commodore" {
+						next.ServeHTTP(w, r.WithContext(withActor(r.Context(), "agent:"+identity.AgentID)))
+						return
+					}
+				}
+			}
+
+			token := extractBearerToken(r)
+			if token == "" || cfg.AdminToken == "" || token != cfg.AdminToken {
+				writeError(w, http.StatusForbidden, "admin access requires a commodore-tier client certificate or a valid admin token")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withActor(r.Context(), "token:admin")))
+		})
+	}
+}
+
 // extractBearerToken extracts the bearer token from the Authorization header.
 func extractBearerToken(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// negotiatedEncoder renders a handler's response payload as contentType,
+// for a handler that supports more than plain JSON. render receives the
+// same payload writeJSON would have serialized.
+type negotiatedEncoder struct {
+	contentType string
+	render      func(w http.ResponseWriter, payload interface{})
+}
+
+// respondNegotiated writes payload as JSON, unless the request's Accept
+// header matches one of encoders (checked in order), in which case that
+// encoder renders it instead. Mirrors Consul's agent endpoints, which
+// return JSON by default but honor Accept: text/plain for a human-readable
+// summary of the same data.
+func respondNegotiated(w http.ResponseWriter, r *http.Request, payload interface{}, encoders ...negotiatedEncoder) {
+	accept := r.Header.Get("Accept")
+	for _, enc := range encoders {
+		if acceptMatches(accept, enc.contentType) {
+			w.Header().Set("Content-Type", enc.contentType)
+			w.WriteHeader(http.StatusOK)
+			enc.render(w, payload)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// acceptMatches reports whether any comma-separated entry of an Accept
+// header matches contentType exactly once both are normalized (parameters
+// trimmed of surrounding whitespace, order and case of the media type
+// preserved). This is intentionally simple — no q-value weighting or
+// wildcard (*/*) matching — since callers list their supported encoders
+// most-specific first.
+func acceptMatches(accept, contentType string) bool {
+	if accept == "" {
+		return false
+	}
+	want := normalizeMediaType(contentType)
+	for _, part := range strings.Split(accept, ",") {
+		if normalizeMediaType(part) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeMediaType(s string) string {
+	fields := strings.Split(s, ";")
+	for i, f := range fields {
+		fields[i] = strings.TrimSpace(f)
+	}
+	return strings.Join(fields, ";")
+}
+
+// renderStatusText renders a StatusResponse as a human-readable summary for
+// Accept: text/plain.
+func renderStatusText(w http.ResponseWriter, payload interface{}) {
+	status := payload.(StatusResponse)
+	fmt.Fprintf(w, "status: %s\n", status.Status)
+	fmt.Fprintf(w, "version: %s\n", status.Version)
+	fmt.Fprintf(w, "uptime: %s\n", status.Uptime)
+	fmt.Fprintf(w, "agents: %d\n", status.AgentCount)
+	fmt.Fprintf(w, "rules: %d\n", status.RuleCount)
+	fmt.Fprintf(w, "requests_total: %d\n", status.RequestsTotal)
+	fmt.Fprintf(w, "blocked_total: %d\n", status.BlockedTotal)
+}
+
+// renderCountsPrometheus renders the agent/rule count samples shared by
+// renderStatusPrometheus and MetricsHandler. MetricsHandler doesn't also
+// render requests_total/blocked_total this way, since those are now backed
+// by execMetrics.requestsTotal/requestsBlocked and come from its own
+// registry.Gather() instead, and a Prometheus exposition can't repeat a
+// metric family.
+func renderCountsPrometheus(w http.ResponseWriter, status StatusResponse) {
+	fmt.Fprintln(w, "# HELP plasma_agents_total Number of registered agents.")
+	fmt.Fprintln(w, "# TYPE plasma_agents_total gauge")
+	fmt.Fprintf(w, "plasma_agents_total %d\n", status.AgentCount)
+	fmt.Fprintln(w, "# HELP plasma_rules_total Number of configured rules.")
+	fmt.Fprintln(w, "# TYPE plasma_rules_total gauge")
+	fmt.Fprintf(w, "plasma_rules_total %d\n", status.RuleCount)
+}
+
+// renderStatusPrometheus renders a StatusResponse as a Prometheus text
+// exposition, for Accept: text/plain; version=0.0.4 on GET /status.
+func renderStatusPrometheus(w http.ResponseWriter, payload interface{}) {
+	status := payload.(StatusResponse)
+	renderCountsPrometheus(w, status)
+	fmt.Fprintln(w, "# HELP plasma_requests_total Total commands checked.")
+	fmt.Fprintln(w, "# TYPE plasma_requests_total counter")
+	fmt.Fprintf(w, "plasma_requests_total %d\n", status.RequestsTotal)
+	fmt.Fprintln(w, "# HELP plasma_blocked_total Total commands blocked.")
+	fmt.Fprintln(w, "# TYPE plasma_blocked_total counter")
+	fmt.Fprintf(w, "plasma_blocked_total %d\n", status.BlockedTotal)
+}
+
+// renderLogsCSV renders a LogListResponse as CSV, for Accept: text/csv.
+func renderLogsCSV(w http.ResponseWriter, payload interface{}) {
+	logs := payload.(LogListResponse)
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "timestamp", "agent_id", "type", "request", "action", "rule_id"})
+	for _, entry := range logs.Logs {
+		out.Write([]string{
+			entry.ID,
+			entry.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+			entry.AgentID,
+			entry.Type,
+			entry.Request,
+			entry.Action,
+			entry.RuleID,
+		})
+	}
+	out.Flush()
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// unixPeerCred is the calling process's credentials for a connection
+// accepted over a Unix domain socket, captured once at Accept time via
+// SO_PEERCRED (Linux) or LOCAL_PEERCRED (BSD/macOS, what getpeereid(3)
+// wraps) rather than per request.
+type unixPeerCred struct {
+	UID uint32
+	GID uint32
+}
+
+type peerCredContextKeyType int
+
+const peerCredContextKey peerCredContextKeyType = 0
+
+// peerCredFromContext returns the Unix-socket peer credentials attached by
+// peerCredConnContext, and whether any were attached at all -- a request
+// that arrived over TCP carries none.
+func peerCredFromContext(ctx context.Context) (unixPeerCred, bool) {
+	cred, ok := ctx.Value(peerCredContextKey).(unixPeerCred)
+	return cred, ok
+}
+
+// peerCredConn wraps a net.Conn accepted over a Unix domain socket with the
+// credentials of the process on the other end.
+type peerCredConn struct {
+	net.Conn
+	cred unixPeerCred
+}
+
+// peerCredListener wraps the Unix domain socket net.Listener returned by
+// listenUnixSocket so every accepted connection carries the caller's
+// uid/gid, for peerCredConnContext to attach to each request's context.
+type peerCredListener struct {
+	net.Listener
+}
+
+func (l *peerCredListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return conn, nil
+	}
+	// A platform with no getPeerCred support (see peercred_other.go) serves
+	// the connection without credentials rather than refusing it outright;
+	// PeerPrincipalAuth treats "no credentials" the same as a TCP
+	// connection and falls through to the usual token/certificate auth.
+	cred, err := getPeerCred(unixConn)
+	if err != nil {
+		return conn, nil
+	}
+	return &peerCredConn{Conn: conn, cred: cred}, nil
+}
+
+// peerCredConnContext is an http.Server.ConnContext hook that attaches the
+// credentials peerCredListener.Accept captured for conn to its request
+// context, for PeerPrincipalAuth (and peerPrincipalAllowed) to read.
+func peerCredConnContext(ctx context.Context, conn net.Conn) context.Context {
+	if pc, ok := conn.(*peerCredConn); ok {
+		return context.WithValue(ctx, peerCredContextKey, pc.cred)
+	}
+	return ctx
+}
+
+// peerPrincipalAllowed reports whether r is either not subject to
+// peer-credential enforcement at all -- no Unix-socket peer credentials in
+// its context (e.g. it arrived over TCP, already gated by the usual
+// bearer/certificate auth), or no allow-list configured at all (the
+// common case: an operator hasn't opted into UnixSocketAllowedUIDs, so
+// the Unix socket shouldn't suddenly start rejecting every caller) -- or
+// was made by a uid present in allowedUIDs.
+func peerPrincipalAllowed(r *http.Request, allowedUIDs map[uint32]string) bool {
+	if len(allowedUIDs) == 0 {
+		return true
+	}
+	cred, ok := peerCredFromContext(r.Context())
+	if !ok {
+		return true
+	}
+	_, allowed := allowedUIDs[cred.UID]
+	return allowed
+}
+
+// PeerPrincipalAuth middleware maps the calling uid (available only when
+// serving over the Unix socket set up by listenUnixSocket) to an internal
+// principal via allowedUIDs, and rejects the request unless that uid is
+// present. It's meant to layer on top of the usual bearer/certificate auth
+// on privileged endpoints (rule create/update/delete, agent pause/kill)
+// configured with ServerConfig.UnixSocketAllowedUIDs, restricting which
+// local users may reach them even with a valid token.
+func PeerPrincipalAuth(allowedUIDs map[uint32]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !peerPrincipalAllowed(r, allowedUIDs) {
+				cred, _ := peerCredFromContext(r.Context())
+				writeError(w, http.StatusForbidden, fmt.Sprintf("uid %d is not authorized for this endpoint", cred.UID))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+//go:build darwin
+
+package api
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// getPeerCred identifies the caller on the other end of conn using
+// LOCAL_PEERCRED, the BSD/Darwin getsockopt that libc's getpeereid(3)
+// wraps.
+func getPeerCred(conn *net.UnixConn) (unixPeerCred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return unixPeerCred{}, err
+	}
+
+	var xucred *unix.Xucred
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		xucred, sockErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	}); err != nil {
+		return unixPeerCred{}, err
+	}
+	if sockErr != nil {
+		return unixPeerCred{}, fmt.Errorf("LOCAL_PEERCRED: %w", sockErr)
+	}
+	if xucred.Ngroups < 1 {
+		return unixPeerCred{}, fmt.Errorf("LOCAL_PEERCRED: no groups returned")
+	}
+	return unixPeerCred{UID: xucred.Uid, GID: xucred.Groups[0]}, nil
+}
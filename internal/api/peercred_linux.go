@@ -0,0 +1,31 @@
+//go:build linux
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// getPeerCred identifies the caller on the other end of conn using
+// SO_PEERCRED, the Linux getsockopt that returns the credentials the
+// kernel recorded for the connecting process at connect(2) time.
+func getPeerCred(conn *net.UnixConn) (unixPeerCred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return unixPeerCred{}, err
+	}
+
+	var ucred *syscall.Ucred
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, sockErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return unixPeerCred{}, err
+	}
+	if sockErr != nil {
+		return unixPeerCred{}, fmt.Errorf("SO_PEERCRED: %w", sockErr)
+	}
+	return unixPeerCred{UID: ucred.Uid, GID: ucred.Gid}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+
+package api
+
+import (
+	"fmt"
+	"net"
+)
+
+// getPeerCred reports an error on platforms with no peer-credential
+// getsockopt wired up here (notably Windows), so listenUnixSocket's caller
+// can fall back to serving without privileged-endpoint enforcement rather
+// than fail the whole listener.
+func getPeerCred(conn *net.UnixConn) (unixPeerCred, error) {
+	return unixPeerCred{}, fmt.Errorf("peer credentials are not supported on this platform")
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// execMetrics holds the Prometheus instruments behind GET /metrics,
+// registered against an instance-owned registry (rather than the default
+// global one) so each Handlers, and each test that constructs one, gets an
+// isolated set of series.
+type execMetrics struct {
+	registry        *prometheus.Registry
+	requestsTotal   prometheus.Counter
+	requestsBlocked prometheus.Counter
+	checksTotal     *prometheus.CounterVec
+	blockedTotal    *prometheus.CounterVec
+	checkDuration   prometheus.Histogram
+	agents          *prometheus.GaugeVec
+	rules           *prometheus.GaugeVec
+	panicsTotal     *prometheus.CounterVec
+	grpcDuration    *prometheus.HistogramVec
+	grpcStreams     *prometheus.GaugeVec
+}
+
+// newExecMetrics creates an empty, registered execMetrics.
+func newExecMetrics() *execMetrics {
+	m := &execMetrics{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "plasma_requests_total",
+			Help: "Total exec check requests handled, mirroring Store.requestsTotal but scrapeable without Store.mu.",
+		}),
+		requestsBlocked: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "plasma_blocked_total",
+			Help: "Total exec check requests blocked, mirroring Store.blockedTotal but scrapeable without Store.mu.",
+		}),
+		checksTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_exec_checks_total",
+			Help: "Total exec checks evaluated, by agent and decision (allow/block).",
+		}, []string{"agent", "action"}),
+		blockedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_exec_checks_blocked_total",
+			Help: "Exec checks blocked, by agent and the rule (or reason) that blocked them.",
+		}, []string{"agent", "rule"}),
+		checkDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "plasma_exec_check_duration_seconds",
+			Help:    "Time to evaluate an exec check against the rule set.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		agents: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "plasma_agents",
+			Help: "Registered agents by status.",
+		}, []string{"status"}),
+		rules: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "plasma_rules",
+			Help: "Configured rules by action and enabled state.",
+		}, []string{"action", "enabled"}),
+		// Named shield_ rather than plasma_, since it's recovered by
+		// Recovery in this package but also by bastion's safeGo -- a
+		// shield-wide signal rather than one scoped to exec checks.
+		panicsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "shield_panics_total",
+			Help: "Panics recovered by middleware, by component.",
+		}, []string{"component"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "shield_grpc_request_duration_seconds",
+			Help:    "Time to handle a ShieldAgent gRPC call, by method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		grpcStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "shield_grpc_active_streams",
+			Help: "Active ShieldAgent gRPC server streams, by method.",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(m.requestsTotal, m.requestsBlocked, m.checksTotal, m.blockedTotal, m.checkDuration, m.agents, m.rules, m.panicsTotal, m.grpcDuration, m.grpcStreams)
+	return m
+}
+
+// recordPanic increments shield_panics_total for component (e.g. "api"),
+// called by Recovery after it recovers a panic.
+func (m *execMetrics) recordPanic(component string) {
+	m.panicsTotal.WithLabelValues(component).Inc()
+}
+
+// observeGRPC records one ShieldAgent gRPC call's latency, grouped by
+// method (e.g. "/plasma.shield.v1.ShieldAgent/ExecCheck") and the gRPC
+// status code it returned ("OK", "Internal", ...).
+func (m *execMetrics) observeGRPC(method, code string, seconds float64) {
+	m.grpcDuration.WithLabelValues(method, code).Observe(seconds)
+}
+
+// grpcStreamOpened/grpcStreamClosed track shield_grpc_active_streams per
+// method. grpcStatsHandler calls them at the begin and end of every
+// ShieldAgent RPC, unary calls such as ExecCheck included, not only
+// StreamMode.
+func (m *execMetrics) grpcStreamOpened(method string) {
+	m.grpcStreams.WithLabelValues(method).Inc()
+}
+
+func (m *execMetrics) grpcStreamClosed(method string) {
+	m.grpcStreams.WithLabelValues(method).Dec()
+}
+
+// observeCheck records one ExecCheckHandler decision. action is "allow" or
+// "block"; rule is the matched rule ID (or a synthetic reason such as
+// "agent-status") and is only recorded when action is "block".
+func (m *execMetrics) observeCheck(agentID, action, rule string, seconds float64) {
+	m.requestsTotal.Inc()
+	m.checksTotal.WithLabelValues(agentID, action).Inc()
+	if action == "block" {
+		m.requestsBlocked.Inc()
+		m.blockedTotal.WithLabelValues(agentID, rule).Inc()
+	}
+	m.checkDuration.Observe(seconds)
+}
+
+// refreshGauges recomputes plasma_agents and plasma_rules from the current
+// store contents. Called at scrape time rather than threaded through every
+// agent/rule mutation handler, so the gauges can never drift out of sync
+// with the store they describe. Caller must hold store.mu (read or write).
+func (m *execMetrics) refreshGauges(store *Store) {
+	m.agents.Reset()
+	for _, a := range store.agents {
+		m.agents.WithLabelValues(a.Status).Inc()
+	}
+	m.rules.Reset()
+	for _, r := range store.rules {
+		m.rules.WithLabelValues(r.Action, strconv.FormatBool(r.Enabled)).Inc()
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+)
+
+// RecoveryHandlerFunc lets an operator hook their own alerting (PagerDuty,
+// Sentry, a Slack webhook) into a recovered panic, set via
+// Handlers.SetRecoveryHandler/Server.SetRecoveryHandler. It runs after
+// Recovery has already logged the stack, recorded the audit entry, and (if
+// a bastion.LogStore is wired up) appended the SessionEventPanic -- it's an
+// additional hook, not a replacement, so the 500 response and incident ID
+// contract stays the same whether or not one is set.
+type RecoveryHandlerFunc func(r *http.Request, incidentID string, rec interface{}, stack []byte)
+
+// Recovery wraps the whole server in a single top-level defer/recover, so
+// a panic anywhere downstream -- in a handler, in one of applyMiddleware's
+// chains, even in the locked gate -- can't tear down the process. It's
+// installed around lockedGateHandler in NewServer, ahead of every other
+// middleware. Every store mutation already releases its lock via defer
+// (see h.store.mu.Lock()/defer h.store.mu.Unlock() throughout handlers.go),
+// so a panic while holding a lock unwinds through that defer before it
+// ever reaches this one -- Recovery doesn't need to know which locks a
+// handler took.
+//
+// On a panic it assigns an incident ID, logs the stack via
+// runtime/debug.Stack(), records a "panic" audit entry through the
+// existing audit log, appends a SessionEventPanic to the bastion LogStore
+// (if one is wired up via SetBastionGrants/NewStore) so a forensic review
+// of bastion.LogStore.List/Subscribe also surfaces API panics, counts it
+// in shield_panics_total{component="api"}, calls the pluggable
+// RecoveryHandlerFunc if one was set, and responds 500 with the incident
+// ID instead of leaving the connection hanging. Modeled on the recovery
+// interceptor in github.com/grpc-ecosystem/go-grpc-middleware.
+func Recovery(handlers *Handlers) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					incidentID := generateID()
+					stack := debug.Stack()
+					log.Printf("panic recovered in %s %s (incident=%s): %v\n%s", r.Method, r.URL.Path, incidentID, rec, stack)
+					handlers.metrics.recordPanic("api")
+					handlers.recordAudit("system", "panic", "http", r.URL.Path, nil, map[string]string{
+						"incident_id": incidentID,
+						"method":      r.Method,
+						"error":       fmt.Sprint(rec),
+						"stack":       string(stack),
+					})
+					if handlers.store.bastionLogs != nil {
+						handlers.store.bastionLogs.Add(bastion.SessionEvent{
+							Target:    r.URL.Path,
+							Event:     bastion.SessionEventPanic,
+							Timestamp: time.Now().UTC(),
+							Data:      fmt.Sprintf("incident=%s %s %s: %v", incidentID, r.Method, r.URL.Path, rec),
+						})
+					}
+					if handlers.recoveryHandler != nil {
+						handlers.recoveryHandler(r, incidentID, rec, stack)
+					}
+					w.Header().Set("X-Incident-Id", incidentID)
+					writeJSON(w, http.StatusInternalServerError, ErrorResponse{
+						Error:   "internal server error",
+						Code:    http.StatusInternalServerError,
+						Details: "incident_id=" + incidentID,
+					})
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecoveryRecoversPanicAndRecordsAudit(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	server := httptest.NewServer(Recovery(handlers)(panicking))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/anything")
+	if err != nil {
+		t.Fatalf("request after panic: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+
+	// The process (and this test server) stayed up to answer a second
+	// request, proving the panic didn't escape Recovery.
+	resp2, err := http.Get(server.URL + "/anything")
+	if err != nil {
+		t.Fatalf("request after recovery: %v", err)
+	}
+	resp2.Body.Close()
+	if resp2.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500 on second request, got %d", resp2.StatusCode)
+	}
+
+	entries, err := handlers.auditEntries(0)
+	if err != nil {
+		t.Fatalf("audit entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if e.Action != "panic" {
+			t.Fatalf("expected action %q, got %q", "panic", e.Action)
+		}
+		if e.Actor != "system" {
+			t.Fatalf("expected actor %q, got %q", "system", e.Actor)
+		}
+	}
+
+	if got := testutil.ToFloat64(handlers.metrics.panicsTotal.WithLabelValues("api")); got != 2 {
+		t.Fatalf("expected shield_panics_total{component=\"api\"} = 2, got %v", got)
+	}
+
+	if id := resp.Header.Get("X-Incident-Id"); id == "" {
+		t.Fatal("expected X-Incident-Id header on the first response")
+	}
+	if id2 := resp2.Header.Get("X-Incident-Id"); id2 == "" {
+		t.Fatal("expected X-Incident-Id header on the second response")
+	} else if id2 == resp.Header.Get("X-Incident-Id") {
+		t.Fatalf("expected distinct incident IDs per panic, got %q twice", id2)
+	}
+}
+
+func TestRecoveryLogsSessionEventPanicAndCallsHandler(t *testing.T) {
+	store := NewStore()
+	handlers := NewHandlers(store, "1.0.0")
+
+	var hookIncidentID string
+	var hookRec interface{}
+	handlers.SetRecoveryHandler(func(r *http.Request, incidentID string, rec interface{}, stack []byte) {
+		hookIncidentID = incidentID
+		hookRec = rec
+	})
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	server := httptest.NewServer(Recovery(handlers)(panicking))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/anything")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	incidentID := resp.Header.Get("X-Incident-Id")
+	if incidentID == "" {
+		t.Fatal("expected X-Incident-Id header")
+	}
+	if hookIncidentID != incidentID {
+		t.Fatalf("expected RecoveryHandlerFunc to see incident %q, got %q", incidentID, hookIncidentID)
+	}
+	if hookRec != "boom" {
+		t.Fatalf("expected RecoveryHandlerFunc to see the recovered value, got %v", hookRec)
+	}
+
+	events, _ := store.bastionLogs.List(0, 0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 bastion log event, got %d", len(events))
+	}
+	if events[0].Event != bastion.SessionEventPanic {
+		t.Fatalf("expected event %q, got %q", bastion.SessionEventPanic, events[0].Event)
+	}
+}
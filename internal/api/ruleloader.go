@@ -0,0 +1,219 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/Extra-Chill/plasma-shield/internal/matcher"
+)
+
+// rulesFileAPIVersion and rulesFileKind are the only apiVersion/kind a
+// rules file may declare, the same versioned-manifest shape Kubernetes
+// manifests use.
+const (
+	rulesFileAPIVersion = "plasma/v1"
+	rulesFileKind       = "RuleSet"
+)
+
+// RulesFile is the on-disk format RulesLoader reads.
+type RulesFile struct {
+	APIVersion string           `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string           `yaml:"kind" json:"kind"`
+	Rules      []RulesFileEntry `yaml:"rules" json:"rules"`
+}
+
+// RulesFileEntry is one rule within a RulesFile. Expr is a separate field
+// from Pattern so an expression rule reads naturally ("expr: ..."), even
+// though it's loaded the same way a pattern rule with match_type: expr
+// is: into Rule.Pattern, under MatchType "expr".
+type RulesFileEntry struct {
+	ID          string `yaml:"id" json:"id"`
+	Pattern     string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Expr        string `yaml:"expr,omitempty" json:"expr,omitempty"`
+	Domain      string `yaml:"domain,omitempty" json:"domain,omitempty"`
+	MatchType   string `yaml:"match_type,omitempty" json:"match_type,omitempty"`
+	Priority    int    `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Action      string `yaml:"action" json:"action"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Enabled     bool   `yaml:"enabled" json:"enabled"`
+}
+
+// RulesLoader loads rules from a declarative file into a Store at startup
+// and keeps watching it with fsnotify, diffing each reload against
+// store.rules and applying adds/updates/deletes atomically under
+// store.mu. A bad reload (parse error, duplicate id, invalid pattern)
+// leaves the previous ruleset in place; the error is logged and held on
+// the store for HealthzRulesHandler until the next successful reload.
+type RulesLoader struct {
+	store   *Store
+	path    string
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewRulesLoader loads path into store once, then starts watching it for
+// changes. The returned error is from that initial load; callers that
+// want to serve with an empty ruleset rather than fail startup on a bad
+// file can discard it and retry Load later. Close must be called on
+// shutdown to stop the watcher goroutine.
+func NewRulesLoader(store *Store, path string) (*RulesLoader, error) {
+	l := &RulesLoader{
+		store: store,
+		path:  path,
+		done:  make(chan struct{}),
+	}
+	loadErr := l.Load()
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(path); err != nil {
+		fw.Close()
+		return nil, err
+	}
+	l.watcher = fw
+	go l.run()
+
+	return l, loadErr
+}
+
+// Load reads l.path, parses it, and applies it to l.store: on success the
+// store's rules match the file exactly (a rule absent from the file is
+// deleted) and store.rulesReloadedAt advances; on failure the store's
+// rules are left untouched and the error is recorded as
+// store.rulesLoadErr for HealthzRulesHandler.
+func (l *RulesLoader) Load() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		err = fmt.Errorf("read rules file: %w", err)
+		l.recordErr(err)
+		return err
+	}
+
+	rules, err := parseRulesFile(data)
+	if err != nil {
+		l.recordErr(err)
+		return err
+	}
+
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+
+	for id := range l.store.rules {
+		if _, ok := rules[id]; !ok {
+			delete(l.store.rules, id)
+		}
+	}
+	for id, rule := range rules {
+		l.store.rules[id] = rule
+	}
+	l.store.rulesReloadedAt = time.Now().UTC()
+	l.store.rulesLoadErr = ""
+	return nil
+}
+
+// recordErr holds err on the store for HealthzRulesHandler, without
+// touching store.rules or store.rulesReloadedAt.
+func (l *RulesLoader) recordErr(err error) {
+	l.store.mu.Lock()
+	l.store.rulesLoadErr = err.Error()
+	l.store.mu.Unlock()
+}
+
+// run processes fsnotify events, debouncing bursts of writes (editors
+// often emit several events for a single save) into a single reload.
+func (l *RulesLoader) run() {
+	var pending *time.Timer
+	for {
+		select {
+		case event, ok := <-l.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if pending != nil {
+				pending.Stop()
+			}
+			pending = time.AfterFunc(100*time.Millisecond, func() {
+				if err := l.Load(); err != nil {
+					log.Printf("rules file %s: reload failed, keeping previous ruleset: %v", l.path, err)
+				}
+			})
+
+		case err, ok := <-l.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("rules file watcher error: %v", err)
+
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Close stops the watcher and releases its fsnotify resources.
+func (l *RulesLoader) Close() error {
+	close(l.done)
+	return l.watcher.Close()
+}
+
+// parseRulesFile parses a RulesFile and compiles each entry into a Rule,
+// keyed by ID. It rejects a wrong/missing apiVersion or kind, a missing or
+// duplicate id, and anything buildRule itself would reject (bad action,
+// bad match_type, an invalid regex/CEL/expr pattern).
+func parseRulesFile(data []byte) (map[string]*Rule, error) {
+	var file RulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rules file: %w", err)
+	}
+	if file.APIVersion != rulesFileAPIVersion {
+		return nil, fmt.Errorf("unsupported apiVersion %q, want %q", file.APIVersion, rulesFileAPIVersion)
+	}
+	if file.Kind != rulesFileKind {
+		return nil, fmt.Errorf("unsupported kind %q, want %q", file.Kind, rulesFileKind)
+	}
+
+	rules := make(map[string]*Rule, len(file.Rules))
+	now := time.Now().UTC()
+	for _, entry := range file.Rules {
+		if entry.ID == "" {
+			return nil, fmt.Errorf("rule missing id")
+		}
+		if _, dup := rules[entry.ID]; dup {
+			return nil, fmt.Errorf("duplicate rule id %q", entry.ID)
+		}
+
+		req := CreateRuleRequest{
+			Pattern:     entry.Pattern,
+			Domain:      entry.Domain,
+			MatchType:   entry.MatchType,
+			Priority:    entry.Priority,
+			Action:      entry.Action,
+			Description: entry.Description,
+			Enabled:     entry.Enabled,
+		}
+		if entry.Expr != "" {
+			if entry.Pattern != "" {
+				return nil, fmt.Errorf("rule %q: expr and pattern are mutually exclusive", entry.ID)
+			}
+			req.Pattern = entry.Expr
+			req.MatchType = string(matcher.Expr)
+		}
+
+		rule, err := buildRule(req, entry.ID, now)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", entry.ID, err)
+		}
+		rules[entry.ID] = rule
+	}
+	return rules, nil
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testRulesFileV1 = `apiVersion: plasma/v1
+kind: RuleSet
+rules:
+  - id: block-curl
+    pattern: curl
+    action: block
+    enabled: true
+`
+
+const testRulesFileV2 = `apiVersion: plasma/v1
+kind: RuleSet
+rules:
+  - id: block-wget
+    pattern: wget
+    action: block
+    enabled: true
+  - id: allow-expr
+    expr: command == "ls"
+    action: allow
+    enabled: true
+`
+
+func TestRulesLoader(t *testing.T) {
+	t.Run("loads rules at startup and reloads on change", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		if err := os.WriteFile(path, []byte(testRulesFileV1), 0644); err != nil {
+			t.Fatalf("write rules file: %v", err)
+		}
+
+		store := NewStore()
+		loader, err := NewRulesLoader(store, path)
+		if err != nil {
+			t.Fatalf("NewRulesLoader: %v", err)
+		}
+		defer loader.Close()
+
+		store.mu.RLock()
+		_, ok := store.rules["block-curl"]
+		reloadedAt := store.rulesReloadedAt
+		store.mu.RUnlock()
+		if !ok {
+			t.Fatalf("expected rule %q to be loaded", "block-curl")
+		}
+		if reloadedAt.IsZero() {
+			t.Errorf("expected rulesReloadedAt to be set after initial load")
+		}
+
+		if err := os.WriteFile(path, []byte(testRulesFileV2), 0644); err != nil {
+			t.Fatalf("rewrite rules file: %v", err)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			store.mu.RLock()
+			_, oldStillPresent := store.rules["block-curl"]
+			_, wgetPresent := store.rules["block-wget"]
+			exprRule, exprPresent := store.rules["allow-expr"]
+			store.mu.RUnlock()
+
+			if !oldStillPresent && wgetPresent && exprPresent {
+				if exprRule.MatchType != "expr" || exprRule.Pattern != `command == "ls"` {
+					t.Errorf("expr rule = %+v, want MatchType expr with Pattern set to the expr text", exprRule)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("store never reflected the updated rules file: old present=%v, wget present=%v, expr present=%v",
+					oldStillPresent, wgetPresent, exprPresent)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("a reload failure retains the previous ruleset and records the error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.yaml")
+		if err := os.WriteFile(path, []byte(testRulesFileV1), 0644); err != nil {
+			t.Fatalf("write rules file: %v", err)
+		}
+
+		store := NewStore()
+		loader, err := NewRulesLoader(store, path)
+		if err != nil {
+			t.Fatalf("NewRulesLoader: %v", err)
+		}
+		defer loader.Close()
+
+		if err := os.WriteFile(path, []byte("apiVersion: plasma/v1\nkind: RuleSet\nrules:\n  - id: dup\n    pattern: a\n    action: block\n    enabled: true\n  - id: dup\n    pattern: b\n    action: block\n    enabled: true\n"), 0644); err != nil {
+			t.Fatalf("rewrite rules file: %v", err)
+		}
+
+		if err := loader.Load(); err == nil {
+			t.Fatal("expected Load to reject a duplicate rule id")
+		}
+
+		store.mu.RLock()
+		_, ok := store.rules["block-curl"]
+		loadErr := store.rulesLoadErr
+		store.mu.RUnlock()
+		if !ok {
+			t.Errorf("expected previous ruleset to be retained after a failed reload")
+		}
+		if loadErr == "" {
+			t.Errorf("expected rulesLoadErr to be recorded after a failed reload")
+		}
+	})
+}
+
+func TestHealthzRulesHandlerNoRulesFile(t *testing.T) {
+	handlers := NewHandlers(NewStore(), "1.0.0")
+
+	req := httptest.NewRequest("GET", "/healthz/rules", nil)
+	rec := httptest.NewRecorder()
+	handlers.HealthzRulesHandler(rec, req)
+
+	if rec.Code != 200 {
+		t.Errorf("status = %d, want 200 with no rules file configured", rec.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+// Scope is a named permission a token minted via POST /auth/token can
+// hold. Unlike the blanket management/agent bearer tokens configured at
+// startup, a scoped token is restricted to exactly the capabilities it
+// was issued with, e.g. an exec:check token for a single agent can't also
+// be used to mint or delete rules.
+type Scope string
+
+const (
+	ScopeAgentsRead   Scope = "agents:read"
+	ScopeAgentsWrite  Scope = "agents:write"
+	ScopeRulesWrite   Scope = "rules:write"
+	ScopeLogsRead     Scope = "logs:read"
+	ScopeExecCheck    Scope = "exec:check"
+	ScopeBastionGrant Scope = "bastion:grant"
+	// ScopeAdmin grants every capability ScopedOr checks for, so a single
+	// `--policy admin` token behaves like the blanket management token it
+	// replaces, rather than needing every narrow scope listed individually.
+	ScopeAdmin Scope = "admin"
+)
+
+// validScopes lists every Scope a token may be issued with.
+var validScopes = map[Scope]bool{
+	ScopeAgentsRead:   true,
+	ScopeAgentsWrite:  true,
+	ScopeRulesWrite:   true,
+	ScopeLogsRead:     true,
+	ScopeExecCheck:    true,
+	ScopeBastionGrant: true,
+	ScopeAdmin:        true,
+}
+
+// hasScope reports whether scopes (as stored on an APIToken) includes want,
+// or the blanket ScopeAdmin.
+func hasScope(scopes []string, want Scope) bool {
+	for _, s := range scopes {
+		if Scope(s) == want || Scope(s) == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -2,34 +2,119 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+	"github.com/Extra-Chill/plasma-shield/internal/mitmca"
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
+	"github.com/Extra-Chill/plasma-shield/internal/tlsutil"
 )
 
+// unixSocketPrefix marks Addr as a Unix domain socket path rather than a
+// TCP address, e.g. "unix:///var/run/plasma.sock".
+const unixSocketPrefix = "unix://"
+
+// defaultUnixSocketMode is applied to a Unix socket listener when
+// ServerConfig.UnixSocketMode is empty.
+const defaultUnixSocketMode = "0770"
+
 // ServerConfig holds server configuration.
 type ServerConfig struct {
-	Addr            string
-	ManagementToken string
-	AgentToken      string
-	Version         string
+	// Addr is the listen address. A bare host:port (or :port) listens on
+	// TCP; a "unix://" prefix listens on a Unix domain socket at the given
+	// path instead, e.g. "unix:///var/run/plasma.sock".
+	Addr string
+	// ManagementAuth and AgentAuth are auth-scheme strings parsed by
+	// NewAuth, e.g. "static://TOKEN" (or a bare token, treated the same
+	// way), "none://", or "bcryptfile:///path". Required.
+	ManagementAuth string
+	AgentAuth      string
+	// AdminToken authenticates the /admin/* CRUD + audit API. A
+	// commodore-tier agent client certificate also works, independent of
+	// this field; see AdminAuth.
+	AdminToken string
+	Version    string
+	// TLSCert/TLSKey enable HTTPS. Required if an AgentCA is wired up via
+	// SetAgentCA, since mTLS is only meaningful over TLS. Ignored for a
+	// Unix socket listener, which is local-only and never TLS.
+	TLSCert string
+	TLSKey  string
+	// UnixSocketMode is the file mode applied to the socket when Addr is a
+	// "unix://" path, e.g. "0770". Defaults to defaultUnixSocketMode.
+	UnixSocketMode string
+	// UnixSocketUser/UnixSocketGroup chown the socket by name after
+	// creation, when Addr is a "unix://" path. Left as the process's own
+	// user/group if empty.
+	UnixSocketUser  string
+	UnixSocketGroup string
+	// ValidateRequests additionally validates POST/PUT request bodies
+	// against the component schemas in api/openapi.yaml before handing
+	// them to the handler. Off by default, since the handlers already
+	// validate their own inputs; this exists to catch drift between the
+	// schema and the handlers.
+	ValidateRequests bool
+	// UnixSocketAllowedUIDs maps a peer uid (captured via SO_PEERCRED/
+	// LOCAL_PEERCRED when Addr is a "unix://" path) to a principal name
+	// allowed to reach privileged endpoints: rule create/update/delete and
+	// agent pause/kill. Nil or empty turns the check off, so every
+	// Unix-socket caller with a valid token may reach them. Ignored
+	// entirely for TCP connections, which rely on the usual
+	// token/certificate auth instead.
+	UnixSocketAllowedUIDs map[uint32]string
 }
 
 // Server is the Plasma Shield management API server.
 type Server struct {
-	httpServer *http.Server
-	handlers   *Handlers
-	authConfig *AuthConfig
+	httpServer        *http.Server
+	handlers          *Handlers
+	authConfig        *AuthConfig
+	tlsCert           string
+	tlsKey            string
+	unixSocketPath    string
+	unixSocketMode    string
+	unixSocketUser    string
+	unixSocketGroup   string
+	rulesLoader       *RulesLoader
+	autocertManager   *autocert.Manager
+	tlsConfigOverride *tls.Config
 }
 
-// NewServer creates a new API server.
-func NewServer(cfg ServerConfig) *Server {
+// NewServer creates a new API server. It returns an error if
+// cfg.ManagementAuth or cfg.AgentAuth names an unknown auth scheme or is
+// otherwise malformed (see NewAuth).
+func NewServer(cfg ServerConfig) (*Server, error) {
 	store := NewStore()
 	handlers := NewHandlers(store, cfg.Version)
 
+	managementAuth, err := NewAuth(cfg.ManagementAuth)
+	if err != nil {
+		return nil, fmt.Errorf("management auth: %w", err)
+	}
+	agentAuth, err := NewAuth(cfg.AgentAuth)
+	if err != nil {
+		return nil, fmt.Errorf("agent auth: %w", err)
+	}
+
+	if err := bootstrapAdminToken(handlers); err != nil {
+		return nil, fmt.Errorf("bootstrap admin token: %w", err)
+	}
+
 	authConfig := &AuthConfig{
-		ManagementToken: cfg.ManagementToken,
-		AgentToken:      cfg.AgentToken,
+		Management: managementAuth,
+		Agent:      agentAuth,
+		AdminToken: cfg.AdminToken,
 	}
 
 	mux := http.NewServeMux()
@@ -40,51 +125,109 @@ func NewServer(cfg ServerConfig) *Server {
 		ManagementAuth(authConfig),
 	))
 
+	mux.Handle("/metrics", applyMiddleware(
+		http.HandlerFunc(handlers.MetricsHandler),
+		ManagementAuth(authConfig),
+	))
+
 	mux.Handle("/agents", applyMiddleware(
 		http.HandlerFunc(handlers.ListAgentsHandler),
-		ManagementAuth(authConfig),
+		handlers.ScopedOr(ScopeAgentsRead, ManagementAuth(authConfig)),
 	))
 
-	// Agent action endpoints
+	// Agent action endpoints. pause/kill are privileged (also gated by
+	// UnixSocketAllowedUIDs when served over the Unix socket); resume is
+	// not.
 	mux.Handle("/agents/", applyMiddleware(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			path := r.URL.Path
 			switch {
 			case hasSuffix(path, "/pause"):
+				if !peerPrincipalAllowed(r, cfg.UnixSocketAllowedUIDs) {
+					writeError(w, http.StatusForbidden, "peer uid is not authorized to pause agents")
+					return
+				}
 				handlers.PauseAgentHandler(w, r)
 			case hasSuffix(path, "/kill"):
+				if !peerPrincipalAllowed(r, cfg.UnixSocketAllowedUIDs) {
+					writeError(w, http.StatusForbidden, "peer uid is not authorized to kill agents")
+					return
+				}
 				handlers.KillAgentHandler(w, r)
 			case hasSuffix(path, "/resume"):
 				handlers.ResumeAgentHandler(w, r)
+			case hasSuffix(path, "/revoke"):
+				if !peerPrincipalAllowed(r, cfg.UnixSocketAllowedUIDs) {
+					writeError(w, http.StatusForbidden, "peer uid is not authorized to revoke agent tokens")
+					return
+				}
+				handlers.RevokeAgentTokenHandler(w, r)
 			default:
 				writeError(w, http.StatusNotFound, "not found")
 			}
 		}),
-		ManagementAuth(authConfig),
+		handlers.ScopedOr(ScopeAgentsWrite, ManagementAuth(authConfig)),
 	))
 
+	rulesMiddleware := []func(http.Handler) http.Handler{handlers.ScopedOr(ScopeRulesWrite, ManagementAuth(authConfig))}
+	if cfg.ValidateRequests {
+		rulesMiddleware = append(rulesMiddleware, ValidateAgainstSchema(createRuleRequestSchema))
+	}
 	mux.Handle("/rules", applyMiddleware(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodGet:
 				handlers.ListRulesHandler(w, r)
 			case http.MethodPost:
+				// Creating a rule is privileged, so it's also gated by
+				// UnixSocketAllowedUIDs when served over the Unix socket;
+				// listing isn't.
+				if !peerPrincipalAllowed(r, cfg.UnixSocketAllowedUIDs) {
+					writeError(w, http.StatusForbidden, "peer uid is not authorized to create rules")
+					return
+				}
 				handlers.CreateRuleHandler(w, r)
 			default:
 				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
 			}
 		}),
-		ManagementAuth(authConfig),
+		rulesMiddleware...,
 	))
 
 	mux.Handle("/rules/", applyMiddleware(
-		http.HandlerFunc(handlers.DeleteRuleHandler),
-		ManagementAuth(authConfig),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !peerPrincipalAllowed(r, cfg.UnixSocketAllowedUIDs) {
+				writeError(w, http.StatusForbidden, "peer uid is not authorized to delete rules")
+				return
+			}
+			handlers.DeleteRuleHandler(w, r)
+		}),
+		handlers.ScopedOr(ScopeRulesWrite, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/rules/bulk", applyMiddleware(
+		http.HandlerFunc(handlers.CreateRulesBulkHandler),
+		handlers.ScopedOr(ScopeRulesWrite, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/rules/export", applyMiddleware(
+		http.HandlerFunc(handlers.ExportRulesHandler),
+		handlers.ScopedOr(ScopeRulesWrite, ManagementAuth(authConfig)),
 	))
 
 	mux.Handle("/logs", applyMiddleware(
 		http.HandlerFunc(handlers.ListLogsHandler),
-		ManagementAuth(authConfig),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/logs/stream", applyMiddleware(
+		http.HandlerFunc(handlers.LogsStreamHandler),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/agents/stream", applyMiddleware(
+		http.HandlerFunc(handlers.AgentsStreamHandler),
+		handlers.ScopedOr(ScopeAgentsRead, ManagementAuth(authConfig)),
 	))
 
 	mux.Handle("/bastion/sessions", applyMiddleware(
@@ -92,6 +235,191 @@ func NewServer(cfg ServerConfig) *Server {
 		ManagementAuth(authConfig),
 	))
 
+	// Registered as an exact path ahead of the "/bastion/sessions/" prefix
+	// handler below, the same way "/logs/stream" is split out from "/logs":
+	// ServeMux always prefers the longer, exact match over a trailing-slash
+	// prefix, so this can't be shadowed by the per-session {id}/tail routes.
+	mux.Handle("/bastion/sessions/stream", applyMiddleware(
+		http.HandlerFunc(handlers.StreamBastionSessionsHandler),
+		ManagementAuth(authConfig),
+	))
+
+	// WebSocket counterpart to "/bastion/sessions/stream" above, for a
+	// client that wants a live tail without holding open an SSE response.
+	// Registered as its own exact path, also ahead of the
+	// "/bastion/sessions/" prefix handler.
+	mux.Handle("/bastion/sessions/stream/ws", applyMiddleware(
+		http.HandlerFunc(handlers.BastionSessionsStreamWSHandler),
+		ManagementAuth(authConfig),
+	))
+
+	mux.Handle("/bastion/sessions/", applyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case hasSuffix(r.URL.Path, "/stream"):
+				handlers.GetBastionSessionStreamHandler(w, r)
+			case hasSuffix(r.URL.Path, "/replay"):
+				handlers.GetBastionSessionReplayHandler(w, r)
+			case hasSuffix(r.URL.Path, "/recording"):
+				handlers.GetBastionSessionRecordingHandler(w, r)
+			case hasSuffix(r.URL.Path, "/tail"):
+				handlers.GetBastionSessionTailHandler(w, r)
+			default:
+				writeError(w, http.StatusNotFound, "not found")
+			}
+		}),
+		ManagementAuth(authConfig),
+	))
+
+	mux.Handle("/bastion/hosts/", applyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case hasSuffix(r.URL.Path, "/host-cert"):
+				handlers.IssueHostCertificateHandler(w, r)
+			default:
+				writeError(w, http.StatusNotFound, "not found")
+			}
+		}),
+		ManagementAuth(authConfig),
+	))
+
+	mux.Handle("/bastion/grants/", applyMiddleware(
+		http.HandlerFunc(handlers.DeleteBastionGrantHandler),
+		handlers.ScopedOr(ScopeBastionGrant, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/bastion/krl", applyMiddleware(
+		http.HandlerFunc(handlers.GetBastionKRLHandler),
+		ManagementAuth(authConfig),
+	))
+
+	mux.Handle("/bastion/grants", applyMiddleware(
+		http.HandlerFunc(handlers.CreateBastionGrantHandler),
+		handlers.ScopedOr(ScopeBastionGrant, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/agents/enroll-secrets", applyMiddleware(
+		http.HandlerFunc(handlers.CreateEnrollmentSecretHandler),
+		ManagementAuth(authConfig),
+	))
+
+	// Enrollment is authenticated by one-time secret, not a bearer token:
+	// the agent has no credential yet.
+	mux.Handle("/agents/enroll", applyMiddleware(
+		http.HandlerFunc(handlers.EnrollAgentHandler),
+	))
+
+	// Like /agents/enroll, identity here is proven by the one-time secret
+	// itself -- this is the JWT counterpart, for deployments that configure
+	// an agent JWT key instead of (or alongside) an agentca.AgentCA.
+	mux.Handle("/agents/enroll-token", applyMiddleware(
+		http.HandlerFunc(handlers.EnrollAgentTokenHandler),
+	))
+
+	// Re-enrollment is authenticated by the agent's existing mTLS client
+	// certificate (enforced by the server's tls.Config and checked again
+	// in the handler itself), so it needs no additional middleware.
+	mux.Handle("/agents/reenroll", applyMiddleware(
+		http.HandlerFunc(handlers.ReenrollAgentHandler),
+	))
+
+	// CRL fetches are unauthenticated, like the bastion KRL: the document
+	// itself is the access control.
+	mux.Handle("/agents/crl", applyMiddleware(
+		http.HandlerFunc(handlers.AgentCRLHandler),
+	))
+
+	// CA rotation is a destructive operator action, gated like
+	// enroll-secrets.
+	mux.Handle("/agents/rotate-ca", applyMiddleware(
+		http.HandlerFunc(handlers.RotateAgentCAHandler),
+		ManagementAuth(authConfig),
+	))
+
+	// Trust bundle fetches are unauthenticated, like /agents/crl: external
+	// verifiers need this to validate agent mTLS connections in the first
+	// place.
+	mux.Handle("/agents/trust-bundle", applyMiddleware(
+		http.HandlerFunc(handlers.AgentTrustBundleHandler),
+	))
+
+	// MITM root cert fetches are unauthenticated, like /agents/trust-bundle:
+	// an agent needs this to trust intercepted connections in the first
+	// place.
+	mux.Handle("/mitm/ca-cert", applyMiddleware(
+		http.HandlerFunc(handlers.MITMCACertHandler),
+	))
+
+	mux.Handle("/admin/tenants", applyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handlers.ListTenantsHandler(w, r)
+			case http.MethodPost:
+				handlers.CreateTenantHandler(w, r)
+			default:
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			}
+		}),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/tenants/", applyMiddleware(
+		http.HandlerFunc(handlers.TenantHandler),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/agents", applyMiddleware(
+		http.HandlerFunc(handlers.CreateAgentHandler),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/agents/", applyMiddleware(
+		http.HandlerFunc(handlers.AgentAdminHandler),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/rules", applyMiddleware(
+		http.HandlerFunc(handlers.CreateRuleAdminHandler),
+		AdminAuth(authConfig, handlers),
+		PeerPrincipalAuth(cfg.UnixSocketAllowedUIDs),
+	))
+
+	mux.Handle("/admin/rules/", applyMiddleware(
+		http.HandlerFunc(handlers.RuleAdminHandler),
+		AdminAuth(authConfig, handlers),
+		PeerPrincipalAuth(cfg.UnixSocketAllowedUIDs),
+	))
+
+	mux.Handle("/admin/tokens", applyMiddleware(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet:
+				handlers.ListTokensHandler(w, r)
+			case http.MethodPost:
+				handlers.CreateTokenHandler(w, r)
+			default:
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			}
+		}),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/tokens/", applyMiddleware(
+		http.HandlerFunc(handlers.TokenHandler),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/audit/verify", applyMiddleware(
+		http.HandlerFunc(handlers.AuditVerifyHandler),
+		AdminAuth(authConfig, handlers),
+	))
+
+	mux.Handle("/admin/audit", applyMiddleware(
+		http.HandlerFunc(handlers.AuditHandler),
+		AdminAuth(authConfig, handlers),
+	))
+
 	mux.Handle("/grants", applyMiddleware(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
@@ -106,15 +434,101 @@ func NewServer(cfg ServerConfig) *Server {
 		ManagementAuth(authConfig),
 	))
 
+	// Registered as an exact path ahead of the "/grants/" prefix handler
+	// below, the same way "/bastion/sessions/stream" is split out from
+	// "/bastion/sessions/".
+	mux.Handle("/grants/events", applyMiddleware(
+		http.HandlerFunc(handlers.GrantEventsWSHandler),
+		ManagementAuth(authConfig),
+	))
+
 	mux.Handle("/grants/", applyMiddleware(
 		http.HandlerFunc(handlers.DeleteGrantHandler),
 		ManagementAuth(authConfig),
 	))
 
-	// Agent endpoint (requires agent token)
+	// Agent endpoint (requires agent token, an exec:check-scoped API
+	// token, or an mTLS client cert if an AgentCA has been wired up via
+	// SetAgentCA)
+	execCheckMiddleware := []func(http.Handler) http.Handler{handlers.ScopedOr(ScopeExecCheck, AgentAccess(authConfig, handlers))}
+	if cfg.ValidateRequests {
+		execCheckMiddleware = append(execCheckMiddleware, ValidateAgainstSchema(execCheckRequestSchema))
+	}
 	mux.Handle("/exec/check", applyMiddleware(
 		http.HandlerFunc(handlers.ExecCheckHandler),
-		AgentAuth(authConfig),
+		execCheckMiddleware...,
+	))
+
+	// Dry-run evaluates a caller-supplied rule set, not the store's
+	// configured rules, so it's a management action (like testing a rule
+	// change in CI) rather than an agent-facing endpoint.
+	mux.Handle("/exec/check/dryrun", applyMiddleware(
+		http.HandlerFunc(handlers.ExecCheckDryRunHandler),
+		handlers.ScopedOr(ScopeRulesWrite, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/exec/events", applyMiddleware(
+		http.HandlerFunc(handlers.ExecEventsHandler),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
+	))
+
+	// Minting/revoking scoped API tokens is itself a management action.
+	mux.Handle("/auth/token", applyMiddleware(
+		http.HandlerFunc(handlers.CreateAPITokenHandler),
+		ManagementAuth(authConfig),
+	))
+
+	mux.Handle("/auth/token/", applyMiddleware(
+		http.HandlerFunc(handlers.DeleteAPITokenHandler),
+		ManagementAuth(authConfig),
+	))
+
+	mux.Handle("/auth/tokens", applyMiddleware(
+		http.HandlerFunc(handlers.ListAPITokensHandler),
+		ManagementAuth(authConfig),
+	))
+
+	// Renewal is self-service, authenticated by the token's own secret, not
+	// a management credential -- see RenewAPITokenHandler.
+	mux.Handle("/auth/token/renew", applyMiddleware(
+		http.HandlerFunc(handlers.RenewAPITokenHandler),
+	))
+
+	// Introspection validates whatever bearer credential the caller already
+	// presents, so it needs no additional middleware of its own.
+	mux.Handle("/auth/introspect", applyMiddleware(
+		IntrospectHandler(handlers, authConfig),
+	))
+
+	// Pending human-in-the-loop approvals for "review" rule matches (see
+	// ExecCheckHandler/awaitApproval). Listing/resolving/streaming is a
+	// management action, same as /exec/events.
+	mux.Handle("/approvals", applyMiddleware(
+		http.HandlerFunc(handlers.ListApprovalsHandler),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/approvals/stream", applyMiddleware(
+		http.HandlerFunc(handlers.ApprovalsStreamHandler),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/approvals/", applyMiddleware(
+		http.HandlerFunc(handlers.ResolveApprovalHandler),
+		handlers.ScopedOr(ScopeRulesWrite, ManagementAuth(authConfig)),
+	))
+
+	// Per-rule/agent hit statistics, read from the in-memory statsRing
+	// rather than scanning logs (see stats.go). Read-only, same scope as
+	// /exec/events.
+	mux.Handle("/stats/rules/", applyMiddleware(
+		http.HandlerFunc(handlers.StatsRuleHandler),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
+	))
+
+	mux.Handle("/stats/top", applyMiddleware(
+		http.HandlerFunc(handlers.StatsTopHandler),
+		handlers.ScopedOr(ScopeLogsRead, ManagementAuth(authConfig)),
 	))
 
 	// Health check (no auth)
@@ -123,29 +537,168 @@ func NewServer(cfg ServerConfig) *Server {
 		w.Write([]byte("ok"))
 	})
 
+	// Rules-file reload health, like /health: a caller deciding whether
+	// the shield's rules are actually current shouldn't need a token.
+	mux.HandleFunc("/healthz/rules", handlers.HealthzRulesHandler)
+
+	// /unlock and /unlock/rotate are unauthenticated, like /bastion/krl:
+	// before the server is unlocked there's no bearer token to check
+	// against, and the passphrase itself is the access control. Reachable
+	// even while locked (see lockedGateHandler below).
+	mux.HandleFunc("/unlock", handlers.UnlockHandler)
+	mux.HandleFunc("/unlock/rotate", handlers.RotateUnlockKeyHandler)
+
+	unixSocketPath := ""
+	addr := cfg.Addr
+	if strings.HasPrefix(cfg.Addr, unixSocketPrefix) {
+		unixSocketPath = strings.TrimPrefix(cfg.Addr, unixSocketPrefix)
+		addr = ""
+	}
+
 	httpServer := &http.Server{
-		Addr:         cfg.Addr,
-		Handler:      mux,
+		Addr:         addr,
+		Handler:      Recovery(handlers)(lockedGateHandler(handlers, mux)),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
 	return &Server{
-		httpServer: httpServer,
-		handlers:   handlers,
-		authConfig: authConfig,
-	}
+		httpServer:      httpServer,
+		handlers:        handlers,
+		authConfig:      authConfig,
+		tlsCert:         cfg.TLSCert,
+		tlsKey:          cfg.TLSKey,
+		unixSocketPath:  unixSocketPath,
+		unixSocketMode:  cfg.UnixSocketMode,
+		unixSocketUser:  cfg.UnixSocketUser,
+		unixSocketGroup: cfg.UnixSocketGroup,
+	}, nil
 }
 
-// Start starts the HTTP server.
+// Start starts the HTTP server. If Addr was a "unix://" path, it serves
+// over that Unix domain socket instead of TCP, identically to every other
+// handler registered on the mux, except that each connection's peer
+// uid/gid (see listenUnixSocket) is attached to its requests' contexts for
+// PeerPrincipalAuth. Otherwise, if SetAutocertManager or SetSelfSignedTLS
+// was called, it serves HTTPS with that certificate; else if TLSCert/
+// TLSKey were set in the ServerConfig, it serves HTTPS from that file pair
+// instead of plain HTTP. Either way, if an AgentCA has also been wired up
+// via SetAgentCA, the listener requests (but does not require) a client
+// certificate, so AgentAccess can authenticate by mTLS when one is
+// presented and fall back to the bearer token otherwise.
 func (s *Server) Start() error {
-	log.Printf("Starting Plasma Shield API on %s", s.httpServer.Addr)
-	return s.httpServer.ListenAndServe()
+	if s.unixSocketPath != "" {
+		listener, err := s.listenUnixSocket()
+		if err != nil {
+			return err
+		}
+		s.httpServer.ConnContext = peerCredConnContext
+		log.Printf("Starting Plasma Shield API on unix://%s", s.unixSocketPath)
+		return s.httpServer.Serve(listener)
+	}
+
+	if s.tlsConfigOverride != nil {
+		if ca := s.handlers.AgentCA(); ca != nil {
+			s.tlsConfigOverride.ClientCAs = ca.CertPool()
+			s.tlsConfigOverride.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		s.httpServer.TLSConfig = s.tlsConfigOverride
+		mode := "self-signed"
+		if s.autocertManager != nil {
+			mode = "autocert"
+		}
+		log.Printf("Starting Plasma Shield API on %s (TLS via %s)", s.httpServer.Addr, mode)
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+
+	if s.tlsCert == "" && s.tlsKey == "" {
+		log.Printf("Starting Plasma Shield API on %s", s.httpServer.Addr)
+		return s.httpServer.ListenAndServe()
+	}
+
+	if ca := s.handlers.AgentCA(); ca != nil {
+		s.httpServer.TLSConfig = &tls.Config{
+			ClientCAs:  ca.CertPool(),
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		}
+	}
+
+	log.Printf("Starting Plasma Shield API on %s (TLS)", s.httpServer.Addr)
+	return s.httpServer.ListenAndServeTLS(s.tlsCert, s.tlsKey)
+}
+
+// listenUnixSocket creates the Unix domain socket at s.unixSocketPath,
+// applies its configured mode and owner, and wraps it in a
+// peerCredListener so every accepted connection's SO_PEERCRED/
+// LOCAL_PEERCRED uid/gid is available to PeerPrincipalAuth. A stale socket
+// file left behind by an unclean shutdown is removed first, mirroring
+// what net/http.Server examples and tools like Consul do for their own
+// Unix listeners.
+func (s *Server) listenUnixSocket() (net.Listener, error) {
+	if err := os.Remove(s.unixSocketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove stale unix socket %q: %w", s.unixSocketPath, err)
+	}
+
+	listener, err := net.Listen("unix", s.unixSocketPath)
+	if err != nil {
+		return nil, fmt.Errorf("listen on unix socket %q: %w", s.unixSocketPath, err)
+	}
+
+	mode := s.unixSocketMode
+	if mode == "" {
+		mode = defaultUnixSocketMode
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("invalid unix socket mode %q: %w", mode, err)
+	}
+	if err := os.Chmod(s.unixSocketPath, os.FileMode(perm)); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("chmod unix socket %q: %w", s.unixSocketPath, err)
+	}
+
+	uid, gid := -1, -1
+	if s.unixSocketUser != "" {
+		u, err := user.Lookup(s.unixSocketUser)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("lookup unix socket user %q: %w", s.unixSocketUser, err)
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("unix socket user %q: %w", s.unixSocketUser, err)
+		}
+	}
+	if s.unixSocketGroup != "" {
+		g, err := user.LookupGroup(s.unixSocketGroup)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("lookup unix socket group %q: %w", s.unixSocketGroup, err)
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("unix socket group %q: %w", s.unixSocketGroup, err)
+		}
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(s.unixSocketPath, uid, gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("chown unix socket %q: %w", s.unixSocketPath, err)
+		}
+	}
+
+	return &peerCredListener{Listener: listener}, nil
 }
 
 // Shutdown gracefully shuts down the server.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.rulesLoader != nil {
+		if err := s.rulesLoader.Close(); err != nil {
+			log.Printf("rules loader close: %v", err)
+		}
+	}
 	return s.httpServer.Shutdown(ctx)
 }
 
@@ -154,6 +707,140 @@ func (s *Server) RegisterAgent(id, name, ip string) {
 	s.handlers.RegisterAgent(id, name, ip)
 }
 
+// SetBastionCA wires the bastion CA used to issue host certificates via
+// POST /bastion/hosts/{id}/host-cert.
+func (s *Server) SetBastionCA(ca *bastion.CertificateAuthority) {
+	s.handlers.SetBastionCA(ca)
+}
+
+// SetLockedBastionCA marks the server locked until POST /unlock decrypts
+// the encrypted-at-rest bastion CA key at path (see bastion/keystore).
+// While locked, every endpoint except /health, /healthz/rules, /unlock,
+// and /unlock/rotate responds 503.
+func (s *Server) SetLockedBastionCA(path string) {
+	s.handlers.SetLockedBastionCA(path)
+}
+
+// SetBastionGrants wires the bastion grant store used by
+// DELETE /bastion/grants/{id}.
+func (s *Server) SetBastionGrants(grants *bastion.GrantStore) {
+	s.handlers.SetBastionGrants(grants)
+}
+
+// SetBastionProvisioners wires the named provisioners available to
+// POST /bastion/grants.
+func (s *Server) SetBastionProvisioners(provisioners map[string]bastion.Provisioner) {
+	s.handlers.SetBastionProvisioners(provisioners)
+}
+
+// SetBastionRecordingDir wires the directory a bastion.FileRecorder writes
+// session recordings to, serving them via GET /bastion/sessions/{id}/stream
+// and /replay.
+func (s *Server) SetBastionRecordingDir(dir string) {
+	s.handlers.SetBastionRecordingDir(dir)
+}
+
+// SetAgentCA wires the CA used to issue and revoke agent mTLS client
+// certificates, enabling AgentAccess to authenticate /exec/check by
+// client certificate instead of (or in addition to) the agent bearer
+// token. Requires TLSCert/TLSKey to have been set in the ServerConfig.
+func (s *Server) SetAgentCA(ca *agentca.AgentCA) {
+	s.handlers.SetAgentCA(ca)
+}
+
+// SetMITMCA wires the CA used to mint leaf certificates for the forward
+// proxy's MITM mode, enabling GET /mitm/ca-cert.
+func (s *Server) SetMITMCA(ca *mitmca.CA) {
+	s.handlers.SetMITMCA(ca)
+}
+
+// SetAgentEnrollments wires the one-time enrollment secret store used by
+// POST /agents/enroll.
+func (s *Server) SetAgentEnrollments(enrollments *agentca.EnrollmentStore) {
+	s.handlers.SetAgentEnrollments(enrollments)
+}
+
+// SetAgentJWTKey wires the signing key used to issue and verify agent
+// session JWTs via POST /agents/enroll-token and /exec/check. A nil key
+// (the default) leaves /exec/check authenticated only by AgentAccess
+// (mTLS or the static agent token).
+func (s *Server) SetAgentJWTKey(key *AgentJWTKey) {
+	s.handlers.SetAgentJWTKey(key)
+}
+
+// SetStorage wires a persistent storage.Store so agents, rules, and logs
+// survive a restart. Call LoadFromStorage afterward to hydrate the
+// in-memory state from it.
+func (s *Server) SetStorage(store storage.Store) {
+	s.handlers.SetStorage(store)
+}
+
+// SetAutocertManager switches Start to provision and renew the server's
+// TLS certificate via ACME (see tlsutil.NewAutocertTLSConfig) instead of
+// the TLSCert/TLSKey file pair in ServerConfig. tlsConfig is m's
+// *tls.Config, as returned alongside it by NewAutocertTLSConfig; Start
+// layers AgentCA's ClientCAs/ClientAuth onto it, the same way it does for
+// the file-based TLS path. Call AutocertHTTPHandler afterward to get the
+// ACME HTTP-01 challenge responder to mount on :80.
+func (s *Server) SetAutocertManager(m *autocert.Manager, tlsConfig *tls.Config) {
+	s.autocertManager = m
+	s.tlsConfigOverride = tlsConfig
+}
+
+// AutocertHTTPHandler returns the ACME HTTP-01 challenge handler for the
+// manager passed to SetAutocertManager, or nil if none was set.
+func (s *Server) AutocertHTTPHandler() http.Handler {
+	if s.autocertManager == nil {
+		return nil
+	}
+	return tlsutil.HTTPChallengeHandler(s.autocertManager, nil)
+}
+
+// SetSelfSignedTLS switches Start to serve HTTPS with an ephemeral,
+// in-memory self-signed certificate (see tlsutil.NewSelfSignedTLSConfig)
+// instead of the TLSCert/TLSKey file pair or ACME. Meant for local
+// development or an internal network that already carries its own
+// transport encryption, where there's no certificate to manage but a
+// client still expects HTTPS.
+func (s *Server) SetSelfSignedTLS(tlsConfig *tls.Config) {
+	s.tlsConfigOverride = tlsConfig
+}
+
+// SetApprovalTimeout overrides how long a "review" rule match waits for a
+// human decision via POST /approvals/{id} before falling back to a deny.
+// d <= 0 is ignored; the default is 30s.
+func (s *Server) SetApprovalTimeout(d time.Duration) {
+	s.handlers.SetApprovalTimeout(d)
+}
+
+// SetRecoveryHandler wires fn as Recovery's alerting hook -- see
+// RecoveryHandlerFunc.
+func (s *Server) SetRecoveryHandler(fn RecoveryHandlerFunc) {
+	s.handlers.SetRecoveryHandler(fn)
+}
+
+// LoadFromStorage hydrates agents and rules from the store wired by
+// SetStorage. A no-op if SetStorage was never called.
+func (s *Server) LoadFromStorage() error {
+	return s.handlers.LoadFromStorage()
+}
+
+// SetRulesFile makes path the source of truth for the store's rules:
+// loads it once immediately and keeps watching it with fsnotify,
+// reloading on every change. The returned error is from that initial
+// load; a caller that wants to serve with whatever rules already exist
+// (e.g. from storage) rather than fail startup outright can log it and
+// continue; the watcher keeps running either way; and /healthz/rules
+// keeps reporting it until a reload succeeds. Call Shutdown to stop the
+// watcher.
+func (s *Server) SetRulesFile(path string) error {
+	loader, err := NewRulesLoader(s.handlers.store, path)
+	if loader != nil {
+		s.rulesLoader = loader
+	}
+	return err
+}
+
 // Handlers returns the handlers (for testing).
 func (s *Server) Handlers() *Handlers {
 	return s.handlers
@@ -171,3 +858,28 @@ func applyMiddleware(h http.Handler, middleware ...func(http.Handler) http.Handl
 func hasSuffix(path, suffix string) bool {
 	return len(path) > len(suffix) && path[len(path)-len(suffix):] == suffix
 }
+
+// lockedUnauthenticatedPaths are reachable even while the server is locked
+// (see Handlers.SetLockedBastionCA): /health and /healthz/rules so
+// orchestrators can still probe liveness, and /unlock(/rotate) so an
+// operator can actually unlock it.
+var lockedUnauthenticatedPaths = map[string]bool{
+	"/health":        true,
+	"/healthz/rules": true,
+	"/unlock":        true,
+	"/unlock/rotate": true,
+}
+
+// lockedGateHandler wraps mux so that, while handlers reports the server
+// locked, every request except lockedUnauthenticatedPaths gets a 503
+// instead of reaching mux -- a minimal pre-unlock surface, per
+// Handlers.SetLockedBastionCA.
+func lockedGateHandler(handlers *Handlers, mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handlers.Locked() && !lockedUnauthenticatedPaths[r.URL.Path] {
+			writeError(w, http.StatusServiceUnavailable, "server is locked; POST /unlock with the passphrase")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestUnixSocketListener verifies that a "unix://" Addr is served over a
+// Unix domain socket with the configured mode, and that handlers remain
+// reachable through it exactly as they are over TCP.
+func TestUnixSocketListener(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "plasma.sock")
+
+	server, err := NewServer(ServerConfig{
+		Addr:           "unix://" + socketPath,
+		ManagementAuth: "mgmt-token",
+		AgentAuth:      "agent-token",
+		Version:        "1.0.0",
+		UnixSocketMode: "0770",
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if err := waitForSocket(socketPath, 2*time.Second); err != nil {
+		t.Fatalf("socket never appeared: %v", err)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0770 {
+		t.Errorf("socket mode = %o, want %o", got, 0770)
+	}
+
+	sysInfo, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Fatalf("unexpected Sys() type %T", info.Sys())
+	}
+	if int(sysInfo.Uid) != os.Getuid() {
+		t.Errorf("socket uid = %d, want %d (no --unix-socket-user configured)", sysInfo.Uid, os.Getuid())
+	}
+	if int(sysInfo.Gid) != os.Getgid() {
+		t.Errorf("socket gid = %d, want %d (no --unix-socket-group configured)", sysInfo.Gid, os.Getgid())
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://unix/health")
+	if err != nil {
+		t.Fatalf("GET /health over unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://unix/status", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer mgmt-token")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("GET /status over unix socket: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+// TestUnixSocketPeerCredAuth verifies that the Unix socket listener
+// identifies the calling uid via SO_PEERCRED and enforces
+// UnixSocketAllowedUIDs on a privileged endpoint (agent pause) only once
+// it's configured -- leaving it unset must not lock every caller out of
+// a deployment that never opted in -- while leaving /exec/check
+// reachable either way -- mirroring Consul's own dial-a-Unix-socket-and-
+// round-trip style of test.
+func TestUnixSocketPeerCredAuth(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix domain sockets with peer credentials are not exercised on Windows")
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "plasma.sock")
+
+	server, err := NewServer(ServerConfig{
+		Addr:           "unix://" + socketPath,
+		ManagementAuth: "mgmt-token",
+		AgentAuth:      "agent-token",
+		Version:        "1.0.0",
+		UnixSocketMode: "0770",
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	server.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Start()
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if err := waitForSocket(socketPath, 2*time.Second); err != nil {
+		t.Fatalf("socket never appeared: %v", err)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+
+	execBody, _ := json.Marshal(ExecCheckRequest{AgentID: "agent-1", Command: "ls -la"})
+	execReq, err := http.NewRequest(http.MethodPost, "http://unix/exec/check", bytes.NewReader(execBody))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	execReq.Header.Set("Authorization", "Bearer agent-token")
+	resp, err := client.Do(execReq)
+	if err != nil {
+		t.Fatalf("POST /exec/check over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("/exec/check status = %d, want %d (not a privileged endpoint)", resp.StatusCode, http.StatusOK)
+	}
+
+	pauseReq := func() *http.Request {
+		req, err := http.NewRequest(http.MethodPost, "http://unix/agents/agent-1/pause", nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer mgmt-token")
+		return req
+	}
+
+	resp, err = client.Do(pauseReq())
+	if err != nil {
+		t.Fatalf("POST pause over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("pause status = %d, want %d (no UnixSocketAllowedUIDs configured, so enforcement is off)", resp.StatusCode, http.StatusOK)
+	}
+
+	deniedServer, err := NewServer(ServerConfig{
+		Addr:                  "unix://" + filepath.Join(t.TempDir(), "plasma-denied.sock"),
+		ManagementAuth:        "mgmt-token",
+		AgentAuth:             "agent-token",
+		Version:               "1.0.0",
+		UnixSocketAllowedUIDs: map[uint32]string{uint32(os.Getuid()) + 1: "someone-else"},
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	deniedServer.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+	deniedSocketPath := deniedServer.unixSocketPath
+
+	go func() {
+		errCh <- deniedServer.Start()
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		deniedServer.Shutdown(ctx)
+	}()
+
+	if err := waitForSocket(deniedSocketPath, 2*time.Second); err != nil {
+		t.Fatalf("socket never appeared: %v", err)
+	}
+
+	deniedClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", deniedSocketPath)
+			},
+		},
+	}
+
+	resp, err = deniedClient.Do(pauseReq())
+	if err != nil {
+		t.Fatalf("POST pause over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("pause status = %d, want %d (uid not in UnixSocketAllowedUIDs)", resp.StatusCode, http.StatusForbidden)
+	}
+
+	allowedServer, err := NewServer(ServerConfig{
+		Addr:                  "unix://" + filepath.Join(t.TempDir(), "plasma-allowed.sock"),
+		ManagementAuth:        "mgmt-token",
+		AgentAuth:             "agent-token",
+		Version:               "1.0.0",
+		UnixSocketAllowedUIDs: map[uint32]string{uint32(os.Getuid()): "operator"},
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	allowedServer.RegisterAgent("agent-1", "Test Agent", "192.168.1.1")
+	allowedSocketPath := allowedServer.unixSocketPath
+
+	go func() {
+		errCh <- allowedServer.Start()
+	}()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		allowedServer.Shutdown(ctx)
+	}()
+
+	if err := waitForSocket(allowedSocketPath, 2*time.Second); err != nil {
+		t.Fatalf("socket never appeared: %v", err)
+	}
+
+	allowedClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", allowedSocketPath)
+			},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://unix/agents/agent-1/pause", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer mgmt-token")
+	resp, err = allowedClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST pause over unix socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("pause status = %d, want %d (uid present in UnixSocketAllowedUIDs)", resp.StatusCode, http.StatusOK)
+	}
+}
+
+// waitForSocket polls for socketPath to exist, since Start() creates the
+// listener in a goroutine.
+func waitForSocket(socketPath string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(socketPath); err == nil {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err := os.Stat(socketPath)
+	return err
+}
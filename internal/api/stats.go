@@ -0,0 +1,357 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsBucketWidth is the finest granularity statsRing tracks hits at;
+// GET /stats/rules/{id} aggregates these into coarser points as requested
+// via ?step=.
+const statsBucketWidth = time.Minute
+
+// statsRetention is how far back statsRing covers. A query whose --since
+// reaches further back than this only gets whatever's still in the ring.
+const statsRetention = 24 * time.Hour
+
+const statsBucketCount = int(statsRetention / statsBucketWidth)
+
+// ruleStats aggregates one rule's exec check decisions within a single
+// statsBucketWidth window.
+type ruleStats struct {
+	allowed uint64
+	blocked uint64
+	agents  map[string]struct{}
+}
+
+// agentStats aggregates one agent's exec check decisions within a single
+// statsBucketWidth window.
+type agentStats struct {
+	allowed uint64
+	blocked uint64
+}
+
+// statsBucket holds every rule's and agent's decision counts within one
+// statsBucketWidth window.
+type statsBucket struct {
+	start   time.Time
+	byRule  map[string]*ruleStats
+	byAgent map[string]*agentStats
+}
+
+// statsRing is a fixed-size circular buffer of statsBucket, recording exec
+// check decisions so GET /stats/rules/{id} and GET /stats/top answer from
+// memory instead of scanning the log table. Guarded by its own mutex,
+// separate from Store.mu, since ExecCheckHandler records a decision on
+// every request and shouldn't contend with unrelated agent/rule state.
+type statsRing struct {
+	mu      sync.Mutex
+	buckets [statsBucketCount]statsBucket
+}
+
+// newStatsRing creates an empty statsRing.
+func newStatsRing() *statsRing {
+	return &statsRing{}
+}
+
+// bucketIndex maps a bucket-aligned time to its slot in the ring.
+func bucketIndex(bucketStart time.Time) int {
+	return int(bucketStart.Unix()/int64(statsBucketWidth/time.Second)) % statsBucketCount
+}
+
+// record adds one exec check decision to the ring. ruleID empty (no rule
+// matched, or the block was a synthetic reason like "agent-status") is a
+// no-op: rule/agent stats only track decisions attributable to a rule.
+func (s *statsRing) record(ruleID, agentID string, allowed bool, at time.Time) {
+	if ruleID == "" {
+		return
+	}
+
+	bucketStart := at.Truncate(statsBucketWidth)
+	idx := bucketIndex(bucketStart)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := &s.buckets[idx]
+	if !b.start.Equal(bucketStart) {
+		*b = statsBucket{
+			start:   bucketStart,
+			byRule:  make(map[string]*ruleStats),
+			byAgent: make(map[string]*agentStats),
+		}
+	}
+
+	rs, ok := b.byRule[ruleID]
+	if !ok {
+		rs = &ruleStats{agents: make(map[string]struct{})}
+		b.byRule[ruleID] = rs
+	}
+	if allowed {
+		rs.allowed++
+	} else {
+		rs.blocked++
+	}
+	if agentID != "" {
+		rs.agents[agentID] = struct{}{}
+	}
+
+	if agentID != "" {
+		as, ok := b.byAgent[agentID]
+		if !ok {
+			as = &agentStats{}
+			b.byAgent[agentID] = as
+		}
+		if allowed {
+			as.allowed++
+		} else {
+			as.blocked++
+		}
+	}
+}
+
+// ruleStatsQuery aggregates ruleID's buckets within [start, end) into
+// step-sized points, oldest first, plus totals across the whole range
+// (unique_agents is a union across the range, not a sum of per-point
+// counts, so an agent seen in every step is still only counted once).
+func (s *statsRing) ruleStatsQuery(ruleID string, start, end time.Time, step time.Duration) ([]RuleStatsPoint, RuleStatsTotals) {
+	if step < statsBucketWidth {
+		step = statsBucketWidth
+	}
+
+	type accum struct {
+		allowed, blocked uint64
+		agents           map[string]struct{}
+	}
+	byStep := make(map[int64]*accum)
+	totalAgents := make(map[string]struct{})
+	var totals RuleStatsTotals
+
+	s.mu.Lock()
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.byRule == nil || b.start.Before(start) || !b.start.Before(end) {
+			continue
+		}
+		rs, ok := b.byRule[ruleID]
+		if !ok {
+			continue
+		}
+
+		stepStart := start.Add((b.start.Sub(start) / step) * step)
+		key := stepStart.Unix()
+		a, ok := byStep[key]
+		if !ok {
+			a = &accum{agents: make(map[string]struct{})}
+			byStep[key] = a
+		}
+		a.allowed += rs.allowed
+		a.blocked += rs.blocked
+		for agentID := range rs.agents {
+			a.agents[agentID] = struct{}{}
+			totalAgents[agentID] = struct{}{}
+		}
+
+		totals.Allowed += rs.allowed
+		totals.Blocked += rs.blocked
+	}
+	s.mu.Unlock()
+
+	totals.UniqueAgents = len(totalAgents)
+
+	keys := make([]int64, 0, len(byStep))
+	for k := range byStep {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	points := make([]RuleStatsPoint, 0, len(keys))
+	for _, k := range keys {
+		a := byStep[k]
+		points = append(points, RuleStatsPoint{
+			Timestamp:    time.Unix(k, 0).UTC(),
+			Allowed:      a.allowed,
+			Blocked:      a.blocked,
+			UniqueAgents: len(a.agents),
+		})
+	}
+	return points, totals
+}
+
+// topRules sums every bucket since the given time by rule, returning the
+// limit rules with the most hits (allowed + blocked), busiest first.
+func (s *statsRing) topRules(since time.Time, limit int) []TopRuleStat {
+	totals := make(map[string]*TopRuleStat)
+
+	s.mu.Lock()
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.byRule == nil || b.start.Before(since) {
+			continue
+		}
+		for ruleID, rs := range b.byRule {
+			t, ok := totals[ruleID]
+			if !ok {
+				t = &TopRuleStat{RuleID: ruleID}
+				totals[ruleID] = t
+			}
+			t.Allowed += rs.allowed
+			t.Blocked += rs.blocked
+			t.Total += rs.allowed + rs.blocked
+		}
+	}
+	s.mu.Unlock()
+
+	out := make([]TopRuleStat, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Total > out[j].Total })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// topAgents sums every bucket since the given time by agent, returning the
+// limit agents with the most blocked commands, worst first.
+func (s *statsRing) topAgents(since time.Time, limit int) []TopAgentStat {
+	totals := make(map[string]*TopAgentStat)
+
+	s.mu.Lock()
+	for i := range s.buckets {
+		b := &s.buckets[i]
+		if b.byAgent == nil || b.start.Before(since) {
+			continue
+		}
+		for agentID, as := range b.byAgent {
+			t, ok := totals[agentID]
+			if !ok {
+				t = &TopAgentStat{AgentID: agentID}
+				totals[agentID] = t
+			}
+			t.Allowed += as.allowed
+			t.Blocked += as.blocked
+		}
+	}
+	s.mu.Unlock()
+
+	out := make([]TopAgentStat, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Blocked > out[j].Blocked })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// StatsRuleHandler handles GET /stats/rules/{id}: a per-step time series
+// of hits for the rule id within [start, end), aggregated from statsRing
+// instead of scanning the log table.
+func (h *Handlers) StatsRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ruleID := strings.TrimPrefix(r.URL.Path, "/stats/rules/")
+	if ruleID == "" || ruleID == r.URL.Path {
+		writeError(w, http.StatusBadRequest, "missing rule id")
+		return
+	}
+
+	query := r.URL.Query()
+
+	end := time.Now()
+	if v := query.Get("end"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid end: "+err.Error())
+			return
+		}
+		end = t
+	}
+
+	start := end.Add(-time.Hour)
+	if v := query.Get("start"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid start: "+err.Error())
+			return
+		}
+		start = t
+	}
+
+	step := statsBucketWidth
+	if v := query.Get("step"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid step: "+err.Error())
+			return
+		}
+		step = d
+	}
+
+	points, totals := h.store.stats.ruleStatsQuery(ruleID, start, end, step)
+	writeJSON(w, http.StatusOK, RuleStatsResponse{
+		RuleID: ruleID,
+		Start:  start,
+		End:    end,
+		Step:   step.String(),
+		Points: points,
+		Totals: totals,
+	})
+}
+
+// StatsTopHandler handles GET /stats/top?by=rule|agent: the noisiest
+// rules, or the agents with the most blocked commands, within the last
+// ?since= window (default 1h).
+func (h *Handlers) StatsTopHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+
+	by := query.Get("by")
+	if by != "rule" && by != "agent" {
+		writeError(w, http.StatusBadRequest, `by must be "rule" or "agent"`)
+		return
+	}
+
+	sinceDur := time.Hour
+	if v := query.Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: "+err.Error())
+			return
+		}
+		sinceDur = d
+	}
+	since := time.Now().Add(-sinceDur)
+
+	limit := 20
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	resp := TopStatsResponse{By: by, Since: since}
+	if by == "rule" {
+		resp.Rules = h.store.stats.topRules(since, limit)
+	} else {
+		resp.Agents = h.store.stats.topAgents(since, limit)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
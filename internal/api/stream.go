@@ -0,0 +1,592 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/api/filter"
+	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+)
+
+// subscriberBufferSize bounds how far a GET /agents/stream subscriber can
+// lag before events are dropped for it. A slow or stalled client must
+// never block the agent status handlers, which publish while holding
+// Store.mu.
+const subscriberBufferSize = 16
+
+// execEventsCapacity bounds Store.execEvents, the same way addLog bounds
+// Store.logs to its last 10000 entries.
+const execEventsCapacity = 1000
+
+// logBrokerCapacity bounds how many LogEntry values Store.logBroker keeps
+// for ?since= replay on GET /logs/stream. It's much smaller than
+// Store.logs (which backs GET /logs's full pagination), since replay only
+// needs to cover catch-up for a client reconnecting after a brief gap.
+const logBrokerCapacity = 1000
+
+// sseHeartbeatInterval is how often GET /exec/events sends a heartbeat
+// comment to keep idle connections alive through proxies that time out
+// connections with no traffic.
+const sseHeartbeatInterval = 15 * time.Second
+
+// subscribeAgentEvents registers a new GET /agents/stream subscriber,
+// returning a channel of agent status transitions and an unsubscribe func
+// that must be called (typically via defer) when the client disconnects.
+func (s *Store) subscribeAgentEvents() (<-chan AgentEvent, func()) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	id := s.nextSubscriberID
+	s.nextSubscriberID++
+	ch := make(chan AgentEvent, subscriberBufferSize)
+	s.agentSubscribers[id] = ch
+
+	return ch, func() {
+		s.streamMu.Lock()
+		defer s.streamMu.Unlock()
+		delete(s.agentSubscribers, id)
+	}
+}
+
+// publishAgentEvent fans an agent status transition out to every
+// GET /agents/stream subscriber. A subscriber that isn't keeping up has the
+// event dropped rather than blocking the caller.
+func (s *Store) publishAgentEvent(event AgentEvent) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	for _, ch := range s.agentSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// matchesLogFilters applies the agent_id/action/type query filters and the
+// optional ?filter= expression (see internal/api/filter) to entry, returning
+// false if it should be skipped.
+func matchesLogFilters(entry LogEntry, agentFilter, actionFilter, typeFilter string, expr *filter.Expr) bool {
+	if agentFilter != "" && entry.AgentID != agentFilter {
+		return false
+	}
+	if actionFilter != "" && entry.Action != actionFilter {
+		return false
+	}
+	if typeFilter != "" && entry.Type != typeFilter {
+		return false
+	}
+	if expr != nil && !expr.Match(entry) {
+		return false
+	}
+	return true
+}
+
+// LogsStreamHandler handles GET /logs/stream, pushing each new LogEntry as
+// Server-Sent Events so a dashboard can follow activity live instead of
+// polling GET /logs. Query filters mirror ListLogsHandler, plus:
+//
+//   - since: an RFC3339 timestamp. Entries recorded after it (up to
+//     logBrokerCapacity of them) are replayed before the live tail begins,
+//     so a reconnecting client can catch up on what it missed.
+//
+// If the subscriber falls behind and entries are dropped for it, a
+// "dropped" named SSE event is sent alongside the next delivered entry
+// reporting the total dropped so far. A heartbeat comment is sent every 15s
+// so idle connections survive proxies that time out quiet ones.
+func (h *Handlers) LogsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	agentFilter := query.Get("agent_id")
+	actionFilter := query.Get("action")
+	typeFilter := query.Get("type")
+
+	var since time.Time
+	if raw := query.Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: "+err.Error())
+			return
+		}
+		since = parsed
+	}
+
+	sub := h.store.logBroker.Subscribe()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var lastDropped uint64
+	writeEntry := func(entry LogEntry) bool {
+		if !matchesLogFilters(entry, agentFilter, actionFilter, typeFilter, expr) {
+			return true
+		}
+		if dropped := sub.Dropped(); dropped != lastDropped {
+			lastDropped = dropped
+			if !writeSSENamedEvent(w, "dropped", struct {
+				Dropped uint64 `json:"dropped"`
+			}{dropped}) {
+				return false
+			}
+		}
+		return writeSSEEvent(w, entry)
+	}
+
+	for _, rec := range h.store.logBroker.Since(since) {
+		entry, ok := rec.Payload.(LogEntry)
+		if !ok {
+			continue
+		}
+		if !writeEntry(entry) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case rec := <-sub.Events():
+			entry, ok := rec.Payload.(LogEntry)
+			if !ok {
+				continue
+			}
+			if !writeEntry(entry) {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// AgentsStreamHandler handles GET /agents/stream, pushing each agent status
+// transition (pause/resume/kill) as Server-Sent Events. The optional
+// agent_id query parameter restricts the stream to a single agent.
+func (h *Handlers) AgentsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	agentFilter := r.URL.Query().Get("agent_id")
+
+	ch, unsubscribe := h.store.subscribeAgentEvents()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-ch:
+			if agentFilter != "" && event.AgentID != agentFilter {
+				continue
+			}
+			if !writeSSEEvent(w, event) {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// subscribeExecEvents registers a new GET /exec/events subscriber,
+// returning a channel of exec decisions and an unsubscribe func that must
+// be called (typically via defer) when the client disconnects.
+func (s *Store) subscribeExecEvents() (<-chan ExecDecisionEvent, func()) {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	id := s.nextSubscriberID
+	s.nextSubscriberID++
+	ch := make(chan ExecDecisionEvent, subscriberBufferSize)
+	s.execSubscribers[id] = ch
+
+	return ch, func() {
+		s.streamMu.Lock()
+		defer s.streamMu.Unlock()
+		delete(s.execSubscribers, id)
+	}
+}
+
+// recordExecEvent appends event to the bounded ring buffer of recent exec
+// decisions and fans it out to every GET /exec/events subscriber. A
+// subscriber that isn't keeping up has the event dropped rather than
+// blocking the caller. Must be called with Store.mu held, same as addLog.
+func (s *Store) recordExecEvent(event ExecDecisionEvent) {
+	s.execEvents = append(s.execEvents, event)
+	if len(s.execEvents) > execEventsCapacity {
+		s.execEvents = s.execEvents[len(s.execEvents)-execEventsCapacity:]
+	}
+
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+
+	for _, ch := range s.execSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// ExecEventsHandler handles GET /exec/events, pushing every real (non-dry-run)
+// exec decision as Server-Sent Events so a dashboard can watch allow/block
+// decisions live instead of polling GET /logs. Query filters: agent_id,
+// action ("allowed" or "blocked"), rule_id. A heartbeat comment is sent
+// every 15s so idle connections survive proxies that time out quiet ones.
+func (h *Handlers) ExecEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	query := r.URL.Query()
+	agentFilter := query.Get("agent_id")
+	actionFilter := query.Get("action")
+	ruleFilter := query.Get("rule_id")
+
+	ch, unsubscribe := h.store.subscribeExecEvents()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case event := <-ch:
+			if agentFilter != "" && event.AgentID != agentFilter {
+				continue
+			}
+			if actionFilter != "" && (event.Allowed != (actionFilter == "allowed")) {
+				continue
+			}
+			if ruleFilter != "" && event.RuleID != ruleFilter {
+				continue
+			}
+			if !writeSSEEvent(w, event) {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// ApprovalsStreamHandler handles GET /approvals/stream, pushing each newly
+// created pending approval as Server-Sent Events so `approvals watch` can
+// react to review-rule matches live instead of polling GET /approvals. A
+// heartbeat comment is sent every 15s so idle connections survive proxies
+// that time out quiet ones.
+func (h *Handlers) ApprovalsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	ch, unsubscribe := h.store.subscribeApprovals()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case approval := <-ch:
+			if !writeSSEEvent(w, approval) {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeSSEEvent JSON-encodes v as a single "data: ...\n\n" SSE event. It
+// reports false if the write failed, so the caller can stop streaming.
+func writeSSEEvent(w http.ResponseWriter, v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return true
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err == nil
+}
+
+// writeSSENamedEvent JSON-encodes v as an "event: name\ndata: ...\n\n" SSE
+// event, so a client can distinguish it from the default "message" events
+// written by writeSSEEvent. It reports false if the write failed, so the
+// caller can stop streaming.
+func writeSSENamedEvent(w http.ResponseWriter, name string, v interface{}) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return true
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err == nil
+}
+
+// GetBastionSessionTailHandler handles GET /bastion/sessions/{id}/tail,
+// streaming a single bastion session's connect/disconnect/command events
+// as Server-Sent Events, the same way LogsStreamHandler does for GET
+// /logs/stream. The optional since (RFC3339) and filter query parameters
+// behave the same way: since replays buffered events for the session
+// before the live tail begins, and filter is a boolean expression (see
+// internal/api/filter) matched against bastion.SessionEvent.
+func (h *Handlers) GetBastionSessionTailHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	sessionID := extractAgentID(r.URL.Path, "/bastion/sessions/", "/tail")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "missing session ID")
+		return
+	}
+
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var since time.Time
+	if raw := query.Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: "+err.Error())
+			return
+		}
+		since = parsed
+	}
+
+	sub := h.store.bastionLogs.Tail()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var lastDropped uint64
+	writeEvent := func(event bastion.SessionEvent) bool {
+		if event.SessionID != sessionID {
+			return true
+		}
+		if expr != nil && !expr.Match(event) {
+			return true
+		}
+		if dropped := sub.Dropped(); dropped != lastDropped {
+			lastDropped = dropped
+			if !writeSSENamedEvent(w, "dropped", struct {
+				Dropped uint64 `json:"dropped"`
+			}{dropped}) {
+				return false
+			}
+		}
+		return writeSSEEvent(w, event)
+	}
+
+	for _, event := range h.store.bastionLogs.Replay(since) {
+		if !writeEvent(event) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case rec := <-sub.Events():
+			event, ok := rec.Payload.(bastion.SessionEvent)
+			if !ok {
+				continue
+			}
+			if !writeEvent(event) {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// StreamBastionSessionsHandler handles GET /bastion/sessions/stream,
+// streaming every bastion session's connect/disconnect/command events as
+// Server-Sent Events, the same way LogsStreamHandler does for GET
+// /logs/stream, rather than GetBastionSessionTailHandler's one-session-at-a-
+// time /bastion/sessions/{id}/tail. The optional since and filter query
+// parameters behave the same way as the other stream endpoints: since
+// replays buffered events before the live tail begins, and filter is a
+// boolean expression (see internal/api/filter) matched against
+// bastion.SessionEvent. A heartbeat comment is sent every 15s so idle
+// connections survive proxies that time out quiet ones.
+func (h *Handlers) StreamBastionSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	expr, ok := parseListFilter(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var since time.Time
+	if raw := query.Get("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid since: "+err.Error())
+			return
+		}
+		since = parsed
+	}
+
+	sub := h.store.bastionLogs.Tail()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var lastDropped uint64
+	writeEvent := func(event bastion.SessionEvent) bool {
+		if expr != nil && !expr.Match(event) {
+			return true
+		}
+		if dropped := sub.Dropped(); dropped != lastDropped {
+			lastDropped = dropped
+			if !writeSSENamedEvent(w, "dropped", struct {
+				Dropped uint64 `json:"dropped"`
+			}{dropped}) {
+				return false
+			}
+		}
+		return writeSSEEvent(w, event)
+	}
+
+	for _, event := range h.store.bastionLogs.Replay(since) {
+		if !writeEvent(event) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case rec := <-sub.Events():
+			event, ok := rec.Payload.(bastion.SessionEvent)
+			if !ok {
+				continue
+			}
+			if !writeEvent(event) {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
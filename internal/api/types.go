@@ -2,9 +2,11 @@
 package api
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/Extra-Chill/plasma-shield/internal/bastion"
+	"github.com/Extra-Chill/plasma-shield/internal/matcher"
 )
 
 // StatusResponse is the response for GET /status.
@@ -17,6 +19,18 @@ type StatusResponse struct {
 	RuleCount     int       `json:"rule_count"`
 	RequestsTotal int64     `json:"requests_total"`
 	BlockedTotal  int64     `json:"blocked_total"`
+	// RulesReloadedAt is the last time a rules file wired via
+	// Server.SetRulesFile reloaded successfully. Zero if no rules file is
+	// configured, or none has reloaded yet.
+	RulesReloadedAt time.Time `json:"rules_reloaded_at,omitempty"`
+}
+
+// RulesHealthResponse is the response for GET /healthz/rules.
+type RulesHealthResponse struct {
+	ReloadedAt time.Time `json:"rules_reloaded_at,omitempty"`
+	// Error is the error from the most recent failed reload attempt, or
+	// empty if the last attempt (or the only attempt, at startup) succeeded.
+	Error string `json:"error,omitempty"`
 }
 
 // Agent represents a registered agent.
@@ -47,10 +61,18 @@ type Rule struct {
 	ID          string    `json:"id"`
 	Pattern     string    `json:"pattern,omitempty"`
 	Domain      string    `json:"domain,omitempty"`
-	Action      string    `json:"action"` // "block" or "allow"
+	MatchType   string    `json:"match_type,omitempty"` // "substring" (default), "glob", "regex", "cel", "expr"
+	Priority    int       `json:"priority"`
+	Action      string    `json:"action"` // "block", "allow", or "review"
 	Description string    `json:"description,omitempty"`
 	Enabled     bool      `json:"enabled"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// compiled is the Matcher built from Pattern/MatchType by
+	// CreateRuleHandler, so ExecCheckHandler never recompiles a regex or
+	// CEL program on the hot path. Unexported: it never round-trips
+	// through JSON or storage.Rule.
+	compiled matcher.Matcher
 }
 
 // RuleListResponse is the response for GET /rules.
@@ -59,13 +81,17 @@ type RuleListResponse struct {
 	Total int    `json:"total"`
 }
 
-// CreateRuleRequest is the request body for POST /rules.
+// CreateRuleRequest is the request body for POST /rules. It also doubles as
+// the element type for POST /rules/bulk and GET /rules/export, so it
+// carries yaml tags alongside its json ones.
 type CreateRuleRequest struct {
-	Pattern     string `json:"pattern,omitempty"`
-	Domain      string `json:"domain,omitempty"`
-	Action      string `json:"action"`
-	Description string `json:"description,omitempty"`
-	Enabled     bool   `json:"enabled"`
+	Pattern     string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Domain      string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	MatchType   string `json:"match_type,omitempty" yaml:"match_type,omitempty"` // "substring" (default), "glob", "regex", "cel", "expr"
+	Priority    int    `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Action      string `json:"action" yaml:"action"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
 }
 
 // CreateRuleResponse is the response for POST /rules.
@@ -74,6 +100,12 @@ type CreateRuleResponse struct {
 	Message string `json:"message"`
 }
 
+// BulkCreateRulesResponse is the response for POST /rules/bulk.
+type BulkCreateRulesResponse struct {
+	Rules   []Rule `json:"rules"`
+	Message string `json:"message"`
+}
+
 // DeleteRuleResponse is the response for DELETE /rules/{id}.
 type DeleteRuleResponse struct {
 	ID      string `json:"id"`
@@ -91,6 +123,14 @@ type LogEntry struct {
 	RuleID    string    `json:"rule_id,omitempty"`
 }
 
+// AgentEvent is broadcast over GET /agents/stream whenever an agent's
+// status changes (pause/resume/kill).
+type AgentEvent struct {
+	AgentID   string    `json:"agent_id"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // LogListResponse is the response for GET /logs.
 type LogListResponse struct {
 	Logs   []LogEntry `json:"logs"`
@@ -105,12 +145,176 @@ type BastionSessionListResponse struct {
 	Total    int                    `json:"total"`
 	Offset   int                    `json:"offset"`
 	Limit    int                    `json:"limit"`
+	// Recordings maps session ID to recording metadata, for sessions that
+	// have one and bastion session recording is configured. Nil if
+	// recording isn't configured at all.
+	Recordings map[string]bastion.RecordingMeta `json:"recordings,omitempty"`
+}
+
+// BastionSessionReplayFrame is one decoded frame of a session recording,
+// as returned by GET /bastion/sessions/{id}/replay.
+type BastionSessionReplayFrame struct {
+	OffsetMS  uint32 `json:"offset_ms"`
+	Direction string `json:"direction"` // "client" or "server"
+	Data      []byte `json:"data"`
+}
+
+// BastionSessionReplayResponse is the response for
+// GET /bastion/sessions/{id}/replay.
+type BastionSessionReplayResponse struct {
+	Meta   bastion.RecordingMeta       `json:"meta"`
+	Frames []BastionSessionReplayFrame `json:"frames"`
+}
+
+// IssueHostCertificateRequest is the request body for
+// POST /bastion/hosts/{id}/host-cert.
+type IssueHostCertificateRequest struct {
+	PublicKey  string   `json:"public_key"` // authorized_keys format
+	Hostnames  []string `json:"hostnames"`
+	TTLSeconds int      `json:"ttl_seconds,omitempty"`
+}
+
+// IssueHostCertificateResponse is the response for
+// POST /bastion/hosts/{id}/host-cert.
+type IssueHostCertificateResponse struct {
+	HostID      string    `json:"host_id"`
+	Certificate string    `json:"certificate"` // authorized_keys-style cert line
+	ValidBefore time.Time `json:"valid_before"`
+	Message     string    `json:"message"`
+}
+
+// DeleteBastionGrantResponse is the response for DELETE /bastion/grants/{id}.
+type DeleteBastionGrantResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// CreateBastionGrantRequest is the request body for POST /bastion/grants.
+// Credential is interpreted according to the named provisioner (a signed
+// JWK claim, an OIDC ID token, or a PEM certificate chain). PublicKey is
+// optional; if set and a bastion CA is configured, the response includes
+// a signed user certificate for the grant.
+type CreateBastionGrantRequest struct {
+	Provisioner string `json:"provisioner"`
+	Credential  string `json:"credential"`
+	Target      string `json:"target"`
+	PublicKey   string `json:"public_key,omitempty"` // authorized_keys format
+	TTLSeconds  int    `json:"ttl_seconds,omitempty"`
+	// Record requests that the grant's sessions be teed to the bastion's
+	// configured Recorder, retrievable later via /bastion/sessions/{id}/stream
+	// and /replay.
+	Record bool `json:"record,omitempty"`
+	// Shell requests that the grant authorize an interactive SSH session
+	// (handleSession), not just direct-tcpip tunneling.
+	Shell bool `json:"shell,omitempty"`
+}
+
+// CreateBastionGrantResponse is the response for POST /bastion/grants.
+type CreateBastionGrantResponse struct {
+	Grant       *bastion.Grant `json:"grant"`
+	Certificate string         `json:"certificate,omitempty"` // authorized_keys-style cert line, if public_key was supplied
+	ValidBefore *time.Time     `json:"valid_before,omitempty"`
+}
+
+// GrantListResponse is the response for GET /grants.
+type GrantListResponse struct {
+	Grants []*bastion.Grant `json:"grants"`
+	Total  int              `json:"total"`
+}
+
+// CreateGrantRequest is the request body for POST /grants. Unlike
+// POST /bastion/grants, the caller is trusted by virtue of already holding
+// a management token, so there's no provisioner credential to verify.
+type CreateGrantRequest struct {
+	Principal  string `json:"principal"`
+	Target     string `json:"target"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+	CreatedBy  string `json:"created_by,omitempty"`
+	Record     bool   `json:"record,omitempty"`
+	Shell      bool   `json:"shell,omitempty"`
+}
+
+// CreateGrantResponse is the response for POST /grants.
+type CreateGrantResponse struct {
+	Grant *bastion.Grant `json:"grant"`
+}
+
+// DeleteGrantResponse is the response for DELETE /grants/{id}.
+type DeleteGrantResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// CreateEnrollmentSecretRequest is the request body for
+// POST /agents/enroll-secrets.
+type CreateEnrollmentSecretRequest struct {
+	TenantID   string `json:"tenant_id"`
+	AgentID    string `json:"agent_id"`
+	Tier       string `json:"tier"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+}
+
+// CreateEnrollmentSecretResponse is the response for
+// POST /agents/enroll-secrets.
+type CreateEnrollmentSecretResponse struct {
+	Secret    string    `json:"secret"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EnrollAgentRequest is the request body for POST /agents/enroll.
+type EnrollAgentRequest struct {
+	Secret string `json:"secret"`
+	CSR    string `json:"csr"` // PEM-encoded certificate signing request
+}
+
+// ReenrollAgentRequest is the request body for POST /agents/reenroll.
+// The caller's identity comes from its existing client certificate, not
+// the request body.
+type ReenrollAgentRequest struct {
+	CSR string `json:"csr"` // PEM-encoded certificate signing request
+}
+
+// EnrollAgentResponse is the response for POST /agents/enroll and
+// POST /agents/reenroll.
+type EnrollAgentResponse struct {
+	Certificate string    `json:"certificate"` // PEM-encoded
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+// EnrollAgentTokenRequest is the request body for POST /agents/enroll-token.
+type EnrollAgentTokenRequest struct {
+	Secret string `json:"secret"`
+}
+
+// EnrollAgentTokenResponse is the response for POST /agents/enroll-token.
+type EnrollAgentTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// ExecCheckRequest is the request body for POST /exec/check.
+// RotateAgentCAResponse is the response for POST /agents/rotate-ca.
+type RotateAgentCAResponse struct {
+	Certificate string    `json:"certificate"` // PEM-encoded new CA certificate
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+// RevokeAgentTokenResponse is the response for POST /agents/{id}/revoke.
+type RevokeAgentTokenResponse struct {
+	AgentID    string `json:"agent_id"`
+	Generation int    `json:"generation"`
+	Message    string `json:"message"`
+}
+
+// ExecCheckRequest is the request body for POST /exec/check. User and Cwd
+// are optional context an agent may report alongside the command; they're
+// only consulted by "expr" match-type rules (as the `user` and `cwd`
+// identifiers), which also see AgentID's registered IP as `agent.ip`.
 type ExecCheckRequest struct {
-	Command string `json:"command"`
-	AgentID string `json:"agent_id"`
+	Command string            `json:"command"`
+	AgentID string            `json:"agent_id"`
+	Env     map[string]string `json:"env,omitempty"` // available to "cel" and "expr" match-type rules as the `env` variable/map
+	User    string            `json:"user,omitempty"`
+	Cwd     string            `json:"cwd,omitempty"`
 }
 
 // ExecCheckResponse is the response for POST /exec/check.
@@ -120,9 +324,332 @@ type ExecCheckResponse struct {
 	RuleID  string `json:"rule_id,omitempty"`
 }
 
+// DryRunCommand is one entry of ExecCheckDryRunRequest.Commands. User and
+// Cwd mirror ExecCheckRequest's, for testing "expr" rules; since dry-run
+// rules never touch the agent registry, `agent.ip` always evaluates as
+// unset.
+type DryRunCommand struct {
+	Command string            `json:"command"`
+	AgentID string            `json:"agent_id,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	User    string            `json:"user,omitempty"`
+	Cwd     string            `json:"cwd,omitempty"`
+}
+
+// ExecCheckDryRunRequest is the request body for POST /exec/check/dryrun.
+// Rules are evaluated exactly like ExecCheckHandler (priority desc, then
+// declaration order), but against this ad hoc set instead of the store's
+// configured rules, and Commands are never logged or counted against
+// Store.requestsTotal/blockedTotal.
+type ExecCheckDryRunRequest struct {
+	Rules    []CreateRuleRequest `json:"rules"`
+	Commands []DryRunCommand     `json:"commands"`
+}
+
+// DryRunResult is the outcome of evaluating one ExecCheckDryRunRequest
+// command against ExecCheckDryRunRequest.Rules.
+type DryRunResult struct {
+	Command string `json:"command"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+	RuleID  string `json:"rule_id,omitempty"` // index into Rules, e.g. "rules[2]"
+}
+
+// ExecCheckDryRunResponse is the response for POST /exec/check/dryrun.
+type ExecCheckDryRunResponse struct {
+	Results []DryRunResult `json:"results"`
+}
+
+// ExecDecisionEvent is broadcast over GET /exec/events whenever
+// ExecCheckHandler reaches a decision for a real (non-dry-run) command.
+type ExecDecisionEvent struct {
+	AgentID   string    `json:"agent_id"`
+	Command   string    `json:"command"`
+	RuleID    string    `json:"rule_id,omitempty"`
+	Allowed   bool      `json:"allowed"`
+	Timestamp time.Time `json:"timestamp"`
+	LatencyMS int64     `json:"latency_ms"`
+}
+
+// Approval is a pending human-in-the-loop decision for a command that
+// matched a "review" rule: ExecCheckHandler blocks the agent's request on
+// it until POST /approvals/{id} resolves it or Store.approvalTimeout
+// elapses, whichever comes first. New approvals are broadcast over
+// GET /approvals/stream; GET /approvals lists every one still unresolved.
+type Approval struct {
+	ID          string    `json:"id"`
+	AgentID     string    `json:"agent_id"`
+	Command     string    `json:"command"`
+	RuleID      string    `json:"rule_id,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+	Resolved    bool      `json:"resolved"`
+	Decision    string    `json:"decision,omitempty"` // "allow", "deny", or "timeout", set once Resolved
+	ResolvedBy  string    `json:"resolved_by,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+
+	// decisionCh delivers the operator's decision to the ExecCheckHandler
+	// goroutine blocked in Store.awaitApproval. Unexported: it never
+	// round-trips through JSON.
+	decisionCh chan approvalDecision
+}
+
+// approvalDecision is what POST /approvals/{id} sends on Approval.decisionCh.
+type approvalDecision struct {
+	allow  bool
+	reason string
+}
+
+// ApprovalListResponse is the response for GET /approvals.
+type ApprovalListResponse struct {
+	Approvals []Approval `json:"approvals"`
+	Total     int        `json:"total"`
+}
+
+// ResolveApprovalRequest is the request body for POST /approvals/{id}
+// (`approvals resolve`).
+type ResolveApprovalRequest struct {
+	Decision string `json:"decision"` // "allow" or "deny"
+	Reason   string `json:"reason,omitempty"`
+}
+
+// RuleStatsPoint is one step of a rule's aggregated hit counts, part of
+// the response for GET /stats/rules/{id}.
+type RuleStatsPoint struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Allowed      uint64    `json:"allowed"`
+	Blocked      uint64    `json:"blocked"`
+	UniqueAgents int       `json:"unique_agents"`
+}
+
+// RuleStatsTotals summarizes a RuleStatsResponse's points across its whole
+// [start, end) range. UniqueAgents is a union across the range, not a sum
+// of each point's count.
+type RuleStatsTotals struct {
+	Allowed      uint64 `json:"allowed"`
+	Blocked      uint64 `json:"blocked"`
+	UniqueAgents int    `json:"unique_agents"`
+}
+
+// RuleStatsResponse is the response for GET /stats/rules/{id}.
+type RuleStatsResponse struct {
+	RuleID string           `json:"rule_id"`
+	Start  time.Time        `json:"start"`
+	End    time.Time        `json:"end"`
+	Step   string           `json:"step"`
+	Points []RuleStatsPoint `json:"points"`
+	Totals RuleStatsTotals  `json:"totals"`
+}
+
+// TopRuleStat is one rule's aggregate hit count within a time range, part
+// of the response for GET /stats/top?by=rule.
+type TopRuleStat struct {
+	RuleID  string `json:"rule_id"`
+	Allowed uint64 `json:"allowed"`
+	Blocked uint64 `json:"blocked"`
+	Total   uint64 `json:"total"`
+}
+
+// TopAgentStat is one agent's aggregate decision counts within a time
+// range, part of the response for GET /stats/top?by=agent.
+type TopAgentStat struct {
+	AgentID string `json:"agent_id"`
+	Allowed uint64 `json:"allowed"`
+	Blocked uint64 `json:"blocked"`
+}
+
+// TopStatsResponse is the response for GET /stats/top.
+type TopStatsResponse struct {
+	By     string         `json:"by"`
+	Since  time.Time      `json:"since"`
+	Rules  []TopRuleStat  `json:"rules,omitempty"`
+	Agents []TopAgentStat `json:"agents,omitempty"`
+}
+
+// Tenant represents a tenant managed via the admin CRUD API.
+type Tenant struct {
+	ID        string    `json:"id"`
+	Mode      string    `json:"mode"` // "isolated" or "fleet"
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TenantListResponse is the response for GET /admin/tenants.
+type TenantListResponse struct {
+	Tenants []Tenant `json:"tenants"`
+	Total   int      `json:"total"`
+}
+
+// CreateTenantRequest is the request body for POST /admin/tenants.
+type CreateTenantRequest struct {
+	ID   string `json:"id"`
+	Mode string `json:"mode"`
+}
+
+// UpdateTenantRequest is the request body for PATCH /admin/tenants/{id}.
+type UpdateTenantRequest struct {
+	Mode string `json:"mode"`
+}
+
+// CreateAgentRequest is the request body for POST /admin/agents.
+type CreateAgentRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+// UpdateAgentRequest is the request body for PATCH /admin/agents/{id}.
+// Only non-empty fields are applied.
+type UpdateAgentRequest struct {
+	Name string `json:"name,omitempty"`
+	IP   string `json:"ip,omitempty"`
+}
+
+// UpdateRuleRequest is the request body for PUT /admin/rules/{id}. Unlike
+// CreateRuleRequest, it replaces the rule in full.
+type UpdateRuleRequest struct {
+	Pattern     string `json:"pattern,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	Action      string `json:"action"`
+	Description string `json:"description,omitempty"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// Token represents an auth token managed via the admin CRUD API.
+type Token struct {
+	Token     string    `json:"token"`
+	TenantID  string    `json:"tenant_id"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenListResponse is the response for GET /admin/tokens.
+type TokenListResponse struct {
+	Tokens []Token `json:"tokens"`
+	Total  int     `json:"total"`
+}
+
+// CreateTokenRequest is the request body for POST /admin/tokens. If Token is
+// empty, one is generated.
+type CreateTokenRequest struct {
+	Token    string `json:"token,omitempty"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name,omitempty"`
+}
+
+// APIToken is a bearer credential minted via POST /auth/token, restricted
+// to the Scopes it was issued with rather than the blanket
+// management/agent token configured at server startup. AccessorID is a
+// public identifier safe to log and list; Token is the secret itself,
+// returned only once, at creation.
+type APIToken struct {
+	AccessorID string     `json:"accessor_id"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	Name       string     `json:"name,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	// TTLSeconds is the token's original lifetime, used by
+	// POST /auth/token/renew to reset ExpiresAt to now plus that amount
+	// each time rather than granting an unbounded extension.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// Renewable marks whether POST /auth/token/renew accepts this token's
+	// own secret to extend its ExpiresAt, instead of requiring a fresh
+	// token minted by a management credential.
+	Renewable bool `json:"renewable,omitempty"`
+}
+
+// CreateAPITokenRequest is the request body for POST /auth/token.
+type CreateAPITokenRequest struct {
+	Scopes     []string `json:"scopes"`
+	Name       string   `json:"name,omitempty"`
+	TTLSeconds int      `json:"ttl_seconds,omitempty"`
+	Renewable  bool     `json:"renewable,omitempty"`
+}
+
+// ListAPITokensResponse is the response for GET /auth/tokens. Tokens never
+// carry their secret here -- only AccessorID, Scopes, and timestamps -- so
+// listing can't be used to recover a live credential.
+type ListAPITokensResponse struct {
+	Tokens []APIToken `json:"tokens"`
+	Total  int        `json:"total"`
+}
+
+// RenewAPITokenResponse is the response for POST /auth/token/renew.
+type RenewAPITokenResponse struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IntrospectResponse is the response for GET /auth/introspect: what the
+// presented bearer credential resolves to, mirroring Consul's ACL
+// token-introspect shape closely enough for `auth whoami` to render it.
+type IntrospectResponse struct {
+	Valid      bool       `json:"valid"`
+	AccessorID string     `json:"accessor_id,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	Kind       string     `json:"kind,omitempty"` // "api_token", "management", or "agent"
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// DeleteResponse is the response for admin DELETE endpoints.
+type DeleteResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// AuditEntry is a single entry in the tamper-evident admin audit log. Hash
+// chains to PrevHash, so altering or removing an entry breaks the chain for
+// every entry recorded after it; see GET /admin/audit/verify.
+type AuditEntry struct {
+	ID           string          `json:"id"`
+	Timestamp    time.Time       `json:"timestamp"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	PrevHash     string          `json:"prev_hash"`
+	Hash         string          `json:"hash"`
+}
+
+// AuditListResponse is the response for GET /admin/audit.
+type AuditListResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+}
+
+// AuditVerifyResponse is the response for GET /admin/audit/verify.
+type AuditVerifyResponse struct {
+	Valid      bool   `json:"valid"`
+	EntryCount int    `json:"entry_count"`
+	BrokenAt   int    `json:"broken_at,omitempty"` // index of first broken entry, -1 if none
+	Message    string `json:"message"`
+}
+
 // ErrorResponse is the standard error response.
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Code    int    `json:"code"`
 	Details string `json:"details,omitempty"`
 }
+
+// UnlockRequest is the request body for POST /unlock.
+type UnlockRequest struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// UnlockResponse is the response for POST /unlock.
+type UnlockResponse struct {
+	Message string `json:"message"`
+}
+
+// RotateUnlockKeyRequest is the request body for POST /unlock/rotate.
+type RotateUnlockKeyRequest struct {
+	OldPassphrase string `json:"old_passphrase"`
+	NewPassphrase string `json:"new_passphrase"`
+}
+
+// RotateUnlockKeyResponse is the response for POST /unlock/rotate.
+type RotateUnlockKeyResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Extra-Chill/plasma-shield/internal/tunnel"
+)
+
+// defaultWSMaxMessageBytes caps a single WebSocket frame written by
+// BastionSessionsStreamWSHandler/GrantEventsWSHandler before
+// SetWSMaxMessageBytes overrides it. internal/tunnel's frames have no
+// inherent size limit, but bastion.SessionEvent.Data can carry a full
+// command line or paste buffer -- operators doing forensic captures of
+// those should raise this explicitly rather than have long events
+// silently dropped.
+const defaultWSMaxMessageBytes = 64 * 1024
+
+// SetWSMaxMessageBytes overrides the per-frame size cap for
+// BastionSessionsStreamWSHandler and GrantEventsWSHandler; an event whose
+// JSON encoding exceeds it is dropped rather than sent. n <= 0 is ignored;
+// the default is 64 KiB.
+func (s *Server) SetWSMaxMessageBytes(n int) {
+	s.handlers.SetWSMaxMessageBytes(n)
+}
+
+// SetWSMaxMessageBytes is the Handlers-level counterpart to
+// Server.SetWSMaxMessageBytes.
+func (h *Handlers) SetWSMaxMessageBytes(n int) {
+	if n <= 0 {
+		return
+	}
+	h.store.streamMu.Lock()
+	h.store.wsMaxMessageBytes = n
+	h.store.streamMu.Unlock()
+}
+
+func (s *Store) wsMaxMessageBytesLimit() int {
+	s.streamMu.Lock()
+	defer s.streamMu.Unlock()
+	return s.wsMaxMessageBytes
+}
+
+// writeWSEvent JSON-encodes v and writes it as a single WebSocket text
+// frame. An event whose encoding exceeds maxBytes is dropped (logged, not
+// sent) rather than truncated, since internal/tunnel's Conn refuses
+// fragmentation and a truncated JSON payload would just break the client's
+// decoder. Reports false if the write failed, so the caller can stop
+// streaming.
+func writeWSEvent(conn *tunnel.Conn, v interface{}, maxBytes int) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return true
+	}
+	if len(data) > maxBytes {
+		log.Printf("api: dropping oversized WebSocket event (%d bytes exceeds %d byte limit)", len(data), maxBytes)
+		return true
+	}
+	return conn.WriteFrame(tunnel.OpText, data) == nil
+}
+
+// BastionSessionsStreamWSHandler handles GET /bastion/sessions/stream/ws,
+// the WebSocket counterpart to StreamBastionSessionsHandler's SSE stream:
+// every bastion session event (connect/disconnect/command) as it happens,
+// with no replay buffer -- a client that needs catch-up should use the SSE
+// endpoint's since= instead. Upgrades via internal/tunnel's hand-rolled
+// WebSocket transport, the same one agent tunneling uses, rather than
+// adding a third-party client dependency.
+func (h *Handlers) BastionSessionsStreamWSHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	conn, err := tunnel.Upgrade(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := h.store.bastionLogs.Subscribe()
+	defer unsubscribe()
+
+	closed := watchForWSClose(conn)
+	maxBytes := h.store.wsMaxMessageBytesLimit()
+	for {
+		select {
+		case event := <-events:
+			if !writeWSEvent(conn, event, maxBytes) {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// GrantEventsWSHandler handles GET /grants/events, the WebSocket
+// counterpart to GET /grants: every grant add/delete as it happens
+// (including one replicated in from another router via GrantStore.
+// StartWatch, for a deployment using NewGrantStoreWithBackend), so an
+// operator tool can react to grant changes live instead of polling
+// GET /grants.
+func (h *Handlers) GrantEventsWSHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if h.store.bastionGrants == nil {
+		writeError(w, http.StatusServiceUnavailable, "bastion grant store not configured")
+		return
+	}
+
+	conn, err := tunnel.Upgrade(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := h.store.bastionGrants.Subscribe()
+	defer unsubscribe()
+
+	closed := watchForWSClose(conn)
+	maxBytes := h.store.wsMaxMessageBytesLimit()
+	for {
+		select {
+		case event := <-events:
+			if !writeWSEvent(conn, event, maxBytes) {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// watchForWSClose runs conn's read side in the background, since a
+// hijacked Conn has no request context left to select on, and reports
+// when the client closes the connection (or the read otherwise fails) so
+// a write-only streaming loop knows to stop.
+func watchForWSClose(conn *tunnel.Conn) <-chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			op, _, err := conn.ReadFrame()
+			if err != nil || op == tunnel.OpClose {
+				return
+			}
+		}
+	}()
+	return closed
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// JSONStore is a JSON-on-disk Store implementation. It keeps the full
+// token set in memory and rewrites the file atomically (write to a temp
+// file, then rename) on every mutation, so a crash mid-write can't corrupt
+// the store.
+type JSONStore struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[string]*Token // token ID -> Token
+	now    func() time.Time
+}
+
+// NewJSONStore loads (or creates) a token store at path. An empty path
+// keeps the store in memory only, useful for tests and for callers that
+// manage persistence themselves.
+func NewJSONStore(path string) (*JSONStore, error) {
+	s := &JSONStore{
+		path:   path,
+		tokens: make(map[string]*Token),
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("read token store: %w", err)
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(data, &s.tokens); err != nil {
+		return nil, fmt.Errorf("parse token store: %w", err)
+	}
+	return s, nil
+}
+
+// CreateToken implements Store.
+func (s *JSONStore) CreateToken(policy Policy, ttl time.Duration) (string, string, error) {
+	secret, err := randomSecret()
+	if err != nil {
+		return "", "", err
+	}
+	id, err := s.PutToken(secret, policy, ttl)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, id, nil
+}
+
+// PutToken implements Store.
+func (s *JSONStore) PutToken(secret string, policy Policy, ttl time.Duration) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash token secret: %w", err)
+	}
+
+	id, err := randomSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now()
+	token := &Token{
+		ID:         id,
+		SecretHash: string(hash),
+		Policy:     policy,
+		CreatedAt:  now,
+	}
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[id] = token
+	if err := s.saveLocked(); err != nil {
+		delete(s.tokens, id)
+		return "", err
+	}
+	return id, nil
+}
+
+// RevokeToken implements Store.
+func (s *JSONStore) RevokeToken(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok {
+		return ErrTokenNotFound
+	}
+	token.Revoked = true
+	return s.saveLocked()
+}
+
+// ResolveToken implements Store. It's O(n) in the number of tokens since
+// secrets are hashed at rest and can't be looked up by index; this is the
+// same tradeoff Consul's ACL system makes for hashed tokens.
+func (s *JSONStore) ResolveToken(secret string) (*Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, token := range s.tokens {
+		if token.Revoked || token.expired(now) {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(token.SecretHash), []byte(secret)) == nil {
+			policy := token.Policy
+			return &policy, nil
+		}
+	}
+	return nil, ErrTokenNotFound
+}
+
+// Bootstrap implements Store.
+func (s *JSONStore) Bootstrap() (string, error) {
+	s.mu.Lock()
+	if len(s.tokens) > 0 {
+		s.mu.Unlock()
+		return "", ErrAlreadyBootstrapped
+	}
+	s.mu.Unlock()
+
+	secret, _, err := s.CreateToken(Policy{}, 0)
+	return secret, err
+}
+
+// saveLocked persists the token map. Caller must hold s.mu. A no-op when
+// the store was opened without a path (in-memory mode).
+func (s *JSONStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal token store: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("create token store dir: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write token store: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// randomSecret returns a cryptographically random 32-byte hex string.
+func randomSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
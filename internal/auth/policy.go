@@ -0,0 +1,66 @@
+// Package auth provides a Consul-ACL-style token store: opaque bearer
+// tokens are bound to a Policy document instead of a bare tenant string,
+// so the reverse proxy can scope access by agent, HTTP method, and path.
+package auth
+
+import "strings"
+
+// Policy describes what a token is allowed to do.
+type Policy struct {
+	// TenantID is the fleet tenant this token acts on behalf of.
+	TenantID string `json:"tenant_id"`
+	// AgentIDs restricts the token to specific agents. Empty means any
+	// agent in the tenant's fleet.
+	AgentIDs []string `json:"agent_ids,omitempty"`
+	// Methods restricts the token to specific HTTP methods (e.g. "GET").
+	// Empty means any method.
+	Methods []string `json:"methods,omitempty"`
+	// PathPrefixes restricts the token to paths with one of these
+	// prefixes. Empty means any path.
+	PathPrefixes []string `json:"path_prefixes,omitempty"`
+	// Tier is the agent tier this token is scoped to (commodore, captain,
+	// crew). Empty means no tier restriction.
+	Tier string `json:"tier,omitempty"`
+}
+
+// Allows reports whether the policy permits a request for agentID using
+// method against path.
+func (p Policy) Allows(agentID, method, path string) bool {
+	if len(p.AgentIDs) > 0 && !contains(p.AgentIDs, agentID) {
+		return false
+	}
+	if len(p.Methods) > 0 && !containsFold(p.Methods, method) {
+		return false
+	}
+	if len(p.PathPrefixes) > 0 && !hasAnyPrefix(p.PathPrefixes, path) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, v string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(prefixes []string, path string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTokenNotFound is returned by ResolveToken when the secret doesn't
+// match any non-revoked, non-expired token.
+var ErrTokenNotFound = errors.New("auth: token not found")
+
+// ErrAlreadyBootstrapped is returned by Bootstrap once a store already has
+// at least one token.
+var ErrAlreadyBootstrapped = errors.New("auth: store already bootstrapped")
+
+// Token is a persisted ACL token. SecretHash never stores the plaintext
+// secret; only CreateToken's caller ever sees that, at creation time.
+type Token struct {
+	ID         string    `json:"id"`
+	SecretHash string    `json:"secret_hash"`
+	Policy     Policy    `json:"policy"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"` // zero means no expiry
+	Revoked    bool      `json:"revoked"`
+}
+
+// expired reports whether the token's TTL has elapsed as of now.
+func (t Token) expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt)
+}
+
+// Store manages ACL tokens and their policies.
+type Store interface {
+	// CreateToken mints a new token bound to policy, returning its
+	// plaintext secret (shown to the caller exactly once) and ID. A zero
+	// ttl means the token never expires.
+	CreateToken(policy Policy, ttl time.Duration) (secret string, id string, err error)
+	// PutToken binds a caller-chosen plaintext secret to a policy. Unlike
+	// CreateToken, the secret isn't generated by the store - this exists
+	// for migrating pre-existing static tokens into the ACL system.
+	PutToken(secret string, policy Policy, ttl time.Duration) (id string, err error)
+	// RevokeToken marks a token as revoked; ResolveToken will reject it
+	// from then on.
+	RevokeToken(id string) error
+	// ResolveToken looks up the policy bound to a plaintext secret.
+	ResolveToken(secret string) (*Policy, error)
+	// Bootstrap seeds the first admin token (unrestricted policy) if the
+	// store is empty, returning ErrAlreadyBootstrapped otherwise.
+	Bootstrap() (secret string, err error)
+}
@@ -0,0 +1,113 @@
+package bastion
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// defaultAsciicastWidth and defaultAsciicastHeight stand in for a real
+// negotiated terminal size. Only direct-tcpip streams are recorded (see
+// handleDirectTCPIP); those carry no pty-req to read an actual COLS/ROWS
+// from and no window-change requests to emit [t, "r", ...] resize records
+// for, so every exported recording reports these fixed dimensions for its
+// whole duration. Interactive sessions (handleSession) are not recorded.
+const (
+	defaultAsciicastWidth  = 80
+	defaultAsciicastHeight = 24
+)
+
+// asciicastHeader is the first line of an asciicast v2 recording. See
+// https://docs.asciinema.org/manual/asciicast/v2/.
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// WriteAsciicast converts a session's recorded frames into asciicast v2: a
+// header line followed by newline-delimited [elapsed_seconds, "o"|"i",
+// data] records. Frames tagged FrameServer (the target's output) become
+// "o" records and FrameClient (what was typed into the tunnel) become "i"
+// records. Elapsed time comes straight from each frame's recorded
+// offset-from-session-start, the same value bastion.ReadFrame reports in
+// milliseconds.
+func WriteAsciicast(w io.Writer, meta RecordingMeta, stream io.Reader) error {
+	enc := json.NewEncoder(w)
+
+	header := asciicastHeader{
+		Version:   2,
+		Width:     defaultAsciicastWidth,
+		Height:    defaultAsciicastHeight,
+		Timestamp: meta.StartedAt.Unix(),
+		// TERM/SHELL are never actually negotiated for a direct-tcpip
+		// session, so these are placeholders rather than real values.
+		Env: map[string]string{"TERM": "unknown", "SHELL": "unknown"},
+	}
+	if err := enc.Encode(header); err != nil {
+		return fmt.Errorf("bastion: encode asciicast header: %w", err)
+	}
+
+	for {
+		offsetMS, dir, payload, err := ReadFrame(stream)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("bastion: read frame for asciicast export: %w", err)
+		}
+
+		eventType := "o"
+		if dir == FrameClient {
+			eventType = "i"
+		}
+		record := [3]interface{}{float64(offsetMS) / 1000, eventType, string(payload)}
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("bastion: encode asciicast record: %w", err)
+		}
+	}
+}
+
+// asciicastPath returns the path a session's gzip-compressed asciicast
+// recording is written to: <dir>/<year>/<month>/<sessionID>.cast.gz,
+// sharded by the month the session ended so the directory stays usable as
+// recordings accumulate.
+func asciicastPath(dir, sessionID string, at time.Time) string {
+	return filepath.Join(dir, strconv.Itoa(at.Year()), fmt.Sprintf("%02d", int(at.Month())), sessionID+".cast.gz")
+}
+
+// exportAsciicast reads a just-closed session's .stream file and writes
+// its asciicast v2 equivalent, gzip-compressed, to its sharded path.
+// There's no separate log-rotation pass in this tree: a session's
+// recording is written exactly once, so compressing it as part of Close
+// stands in for rotating it.
+func exportAsciicast(dir, sessionID string, meta RecordingMeta) (string, error) {
+	stream, err := OpenStream(dir, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("bastion: open stream for asciicast export: %w", err)
+	}
+	defer stream.Close()
+
+	path := asciicastPath(dir, sessionID, meta.StoppedAt)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("bastion: create asciicast dir: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("bastion: create asciicast file: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	if err := WriteAsciicast(gz, meta, stream); err != nil {
+		return "", fmt.Errorf("bastion: write asciicast: %w", err)
+	}
+	return path, gz.Close()
+}
@@ -0,0 +1,98 @@
+package bastion
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRecorder_ClosePopulatesRecordingPath(t *testing.T) {
+	start := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	current := start
+	recorder := NewFileRecorderWithClock(t.TempDir(), func() time.Time { return current })
+
+	session, err := recorder.NewSession("session-1", "grant-1", "alice", "agent-1:22")
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if err := session.WriteFrame(FrameClient, []byte("ls\n")); err != nil {
+		t.Fatalf("WriteFrame (client) failed: %v", err)
+	}
+	current = start.Add(500 * time.Millisecond)
+	if err := session.WriteFrame(FrameServer, []byte("total 0\n")); err != nil {
+		t.Fatalf("WriteFrame (server) failed: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	provider, ok := session.(RecordingPathProvider)
+	if !ok {
+		t.Fatal("expected fileSessionRecording to implement RecordingPathProvider")
+	}
+	path := provider.RecordingPath()
+	wantSuffix := filepath.Join("2024", "03", "session-1.cast.gz")
+	if !strings.HasSuffix(path, wantSuffix) {
+		t.Errorf("expected recording path to end in %q, got %q", wantSuffix, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open exported asciicast failed: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("gzip.NewReader failed: %v", err)
+	}
+	defer gz.Close()
+
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("decompress asciicast failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(decoded)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header line and two record lines, got %d: %q", len(lines), decoded)
+	}
+
+	var header asciicastHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	if header.Version != 2 || header.Width != defaultAsciicastWidth || header.Height != defaultAsciicastHeight {
+		t.Errorf("unexpected header: %+v", header)
+	}
+
+	var clientRecord [3]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &clientRecord); err != nil {
+		t.Fatalf("decode client record: %v", err)
+	}
+	if clientRecord[1] != "i" || clientRecord[2] != "ls\n" {
+		t.Errorf("unexpected client record: %+v", clientRecord)
+	}
+
+	var serverRecord [3]interface{}
+	if err := json.Unmarshal([]byte(lines[2]), &serverRecord); err != nil {
+		t.Fatalf("decode server record: %v", err)
+	}
+	if serverRecord[1] != "o" || serverRecord[2] != "total 0\n" || serverRecord[0].(float64) != 0.5 {
+		t.Errorf("unexpected server record: %+v", serverRecord)
+	}
+}
+
+func TestAsciicastPath_ShardsByYearAndMonth(t *testing.T) {
+	at := time.Date(2025, 11, 7, 0, 0, 0, 0, time.UTC)
+	got := asciicastPath("/recordings", "session-1", at)
+	want := filepath.Join("/recordings", "2025", "11", "session-1.cast.gz")
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
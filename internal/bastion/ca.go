@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/bastion/keystore"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -19,19 +20,46 @@ const defaultCAKeyPath = "bastion_ca_key"
 
 // CertificateAuthority manages SSH certificate signing and validation.
 type CertificateAuthority struct {
-	signer    ssh.Signer
-	publicKey ssh.PublicKey
-	keyPath   string
-	now       func() time.Time
+	signer      ssh.Signer
+	publicKey   ssh.PublicKey
+	keyPath     string
+	now         func() time.Time
+	revocations RevocationStore
 }
 
 // NewCertificateAuthority loads or creates a CA keypair.
 func NewCertificateAuthority(path string) (*CertificateAuthority, error) {
-	return NewCertificateAuthorityWithClock(path, func() time.Time { return time.Now().UTC() })
+	return NewCertificateAuthorityWithClock(path, "", func() time.Time { return time.Now().UTC() })
 }
 
-// NewCertificateAuthorityWithClock loads or creates a CA keypair with a custom clock.
-func NewCertificateAuthorityWithClock(path string, now func() time.Time) (*CertificateAuthority, error) {
+// NewLockedCertificateAuthority is like NewCertificateAuthority, but the CA
+// private key is encrypted at rest (see bastion/keystore): unlockKey
+// bootstraps it on first use or decrypts it on reload. An empty unlockKey
+// returns keystore.ErrLocked whenever a *.enc key already exists, so
+// callers can surface "needs /unlock" instead of starting up unlocked.
+func NewLockedCertificateAuthority(path, unlockKey string) (*CertificateAuthority, error) {
+	return NewCertificateAuthorityWithClock(path, unlockKey, func() time.Time { return time.Now().UTC() })
+}
+
+// HasLockedCAKey reports whether path already has an encrypted-at-rest CA
+// key on disk (see NewLockedCertificateAuthority), so a caller that starts
+// up without an unlock passphrase can tell "needs /unlock" apart from
+// "never locked, fall back to cleartext".
+func HasLockedCAKey(path string) bool {
+	return keystore.Exists(caKeyEncPath(path))
+}
+
+// RotateCAKeyPassphrase re-wraps path's encrypted-at-rest CA key (see
+// NewLockedCertificateAuthority) under newPassphrase, after verifying
+// oldPassphrase. The key material itself is unchanged.
+func RotateCAKeyPassphrase(path, oldPassphrase, newPassphrase string) error {
+	return keystore.Rotate(caKeyEncPath(path), oldPassphrase, newPassphrase)
+}
+
+// NewCertificateAuthorityWithClock loads or creates a CA keypair with a
+// custom clock. unlockKey selects locked mode (see NewLockedCertificateAuthority);
+// pass "" for the default cleartext-on-disk behavior.
+func NewCertificateAuthorityWithClock(path, unlockKey string, now func() time.Time) (*CertificateAuthority, error) {
 	if now == nil {
 		panic("bastion: nil clock")
 	}
@@ -39,16 +67,17 @@ func NewCertificateAuthorityWithClock(path string, now func() time.Time) (*Certi
 		path = defaultCAKeyPath
 	}
 
-	signer, publicKey, err := loadOrCreateCAKey(path)
+	signer, publicKey, err := loadOrCreateCAKey(path, unlockKey)
 	if err != nil {
 		return nil, err
 	}
 
 	return &CertificateAuthority{
-		signer:    signer,
-		publicKey: publicKey,
-		keyPath:   path,
-		now:       now,
+		signer:      signer,
+		publicKey:   publicKey,
+		keyPath:     path,
+		now:         now,
+		revocations: NewRevocationStoreWithClock(path+".revoked.json", now),
 	}, nil
 }
 
@@ -103,42 +132,131 @@ func (c *CertificateAuthority) ValidateUserCertificate(cert *ssh.Certificate, pr
 		IsUserAuthority: func(key ssh.PublicKey) bool {
 			return bytes.Equal(key.Marshal(), c.publicKey.Marshal())
 		},
+		IsRevoked: func(cert *ssh.Certificate) bool {
+			return c.revocations.IsRevoked(cert.Serial, cert.KeyId)
+		},
 	}
 
 	return checker.CheckCert(principal, cert)
 }
 
-func loadOrCreateCAKey(path string) (ssh.Signer, ssh.PublicKey, error) {
-	data, err := os.ReadFile(path)
-	if err == nil {
-		signer, err := ssh.ParsePrivateKey(data)
-		if err != nil {
-			return nil, nil, err
-		}
-		return signer, signer.PublicKey(), nil
+// Revoke marks a certificate serial as revoked, so ValidateUserCertificate
+// rejects it immediately instead of waiting for it to expire.
+func (c *CertificateAuthority) Revoke(serial uint64, reason string) error {
+	return c.revocations.Revoke(serial, reason)
+}
+
+// RevokeGrant revokes every certificate issued under the given grant ID
+// (KeyId). Called when a grant is deleted, so any certificate already
+// issued under it stops working immediately rather than lingering until
+// its ValidBefore.
+func (c *CertificateAuthority) RevokeGrant(grantID, reason string) error {
+	return c.revocations.RevokeKeyID(grantID, reason)
+}
+
+// IssueHostCertificate signs a host certificate for an enrolled agent/target
+// SSH host key, binding it to the given hostnames/IPs. Unlike user
+// certificates, host certificates aren't tied to a grant: they attest to an
+// identity (the target host), not a time-limited session.
+func (c *CertificateAuthority) IssueHostCertificate(publicKey ssh.PublicKey, hostnames []string, ttl time.Duration) (*ssh.Certificate, error) {
+	if publicKey == nil {
+		return nil, errors.New("public key required")
 	}
-	if !os.IsNotExist(err) {
-		return nil, nil, err
+	if len(hostnames) == 0 {
+		return nil, errors.New("at least one hostname required")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("ttl must be positive")
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
-		return nil, nil, err
+	now := c.now()
+	cert := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          uint64(now.UnixNano()),
+		CertType:        ssh.HostCert,
+		KeyId:           hostnames[0],
+		ValidPrincipals: hostnames,
+		ValidAfter:      uint64(now.Unix()),
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+	}
+
+	if err := cert.SignCert(rand.Reader, c.signer); err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// ValidateHostCertificate verifies a target's host certificate against the
+// CA, in place of trust-on-first-use. hostname is the address the bastion
+// dialed (matched against ValidPrincipals).
+func (c *CertificateAuthority) ValidateHostCertificate(cert *ssh.Certificate, hostname string) error {
+	if cert == nil {
+		return errors.New("certificate required")
 	}
 
+	checker := ssh.CertChecker{
+		IsHostAuthority: func(key ssh.PublicKey, address string) bool {
+			return bytes.Equal(key.Marshal(), c.publicKey.Marshal())
+		},
+	}
+
+	return checker.CheckCert(hostname, cert)
+}
+
+// caKeyEncPath returns the encrypted-at-rest sibling of a cleartext CA key
+// path, used when unlockKey is set (see loadOrCreateCAKey).
+func caKeyEncPath(path string) string {
+	return path + ".enc"
+}
+
+func generateCAKeyPEM() ([]byte, error) {
 	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	pemBlock := &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}
-	pemBytes := pem.EncodeToMemory(pemBlock)
-	if err := os.WriteFile(path, pemBytes, 0600); err != nil {
-		return nil, nil, err
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), nil
+}
+
+func loadOrCreateCAKey(path, unlockKey string) (ssh.Signer, ssh.PublicKey, error) {
+	var pemBytes []byte
+
+	if unlockKey != "" || keystore.Exists(caKeyEncPath(path)) {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, nil, err
+		}
+
+		data, err := keystore.LoadOrCreate(caKeyEncPath(path), unlockKey, generateCAKeyPEM)
+		if err != nil {
+			return nil, nil, err
+		}
+		pemBytes = data
+	} else {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			pemBytes = data
+		case !os.IsNotExist(err):
+			return nil, nil, err
+		default:
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				return nil, nil, err
+			}
+
+			generated, err := generateCAKeyPEM()
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := os.WriteFile(path, generated, 0600); err != nil {
+				return nil, nil, err
+			}
+			pemBytes = generated
+		}
 	}
 
 	signer, err := ssh.ParsePrivateKey(pemBytes)
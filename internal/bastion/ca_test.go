@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/binary"
 	"os"
 	"path/filepath"
 	"testing"
@@ -75,6 +76,162 @@ func TestIssueAndValidateUserCertificate(t *testing.T) {
 	}
 }
 
+func TestIssueAndValidateHostCertificate(t *testing.T) {
+	tempDir := t.TempDir()
+	ca, err := NewCertificateAuthority(filepath.Join(tempDir, "bastion_ca_key"))
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+
+	cert, err := ca.IssueHostCertificate(signer.PublicKey(), []string{"agent-1", "10.0.0.5"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("issue host cert: %v", err)
+	}
+
+	if err := ca.ValidateHostCertificate(cert, "agent-1"); err != nil {
+		t.Fatalf("validate host cert: %v", err)
+	}
+
+	if err := ca.ValidateHostCertificate(cert, "other-host"); err == nil {
+		t.Fatal("expected validation to fail for unlisted hostname")
+	}
+}
+
+func TestIssueHostCertificateRequiresHostnames(t *testing.T) {
+	tempDir := t.TempDir()
+	ca, err := NewCertificateAuthority(filepath.Join(tempDir, "bastion_ca_key"))
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+
+	if _, err := ca.IssueHostCertificate(signer.PublicKey(), nil, 24*time.Hour); err == nil {
+		t.Fatal("expected error issuing host certificate without hostnames")
+	}
+}
+
+func TestRevokeBySerialRejectsCertificate(t *testing.T) {
+	tempDir := t.TempDir()
+	ca, err := NewCertificateAuthority(filepath.Join(tempDir, "bastion_ca_key"))
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+
+	grant := &Grant{
+		ID:        "grant-1",
+		Principal: "alice",
+		Target:    "agent-1",
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+	}
+
+	cert, err := ca.IssueUserCertificate(signer.PublicKey(), grant)
+	if err != nil {
+		t.Fatalf("issue cert: %v", err)
+	}
+
+	if err := ca.ValidateUserCertificate(cert, "alice"); err != nil {
+		t.Fatalf("expected cert to validate before revocation: %v", err)
+	}
+
+	if err := ca.Revoke(cert.Serial, "compromised"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	if err := ca.ValidateUserCertificate(cert, "alice"); err == nil {
+		t.Fatal("expected validation to fail for revoked serial")
+	}
+}
+
+func TestRevokeGrantRejectsAllCertsForThatKeyID(t *testing.T) {
+	tempDir := t.TempDir()
+	ca, err := NewCertificateAuthority(filepath.Join(tempDir, "bastion_ca_key"))
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+
+	grant := &Grant{
+		ID:        "grant-compromised",
+		Principal: "alice",
+		Target:    "agent-1",
+		ExpiresAt: time.Now().Add(15 * time.Minute),
+	}
+
+	cert, err := ca.IssueUserCertificate(signer.PublicKey(), grant)
+	if err != nil {
+		t.Fatalf("issue cert: %v", err)
+	}
+
+	if err := ca.RevokeGrant(grant.ID, "grant deleted"); err != nil {
+		t.Fatalf("revoke grant: %v", err)
+	}
+
+	if err := ca.ValidateUserCertificate(cert, "alice"); err == nil {
+		t.Fatal("expected validation to fail for cert issued under a revoked grant")
+	}
+}
+
+func TestGenerateKRLIncludesRevokedSerialsAndKeyIDs(t *testing.T) {
+	tempDir := t.TempDir()
+	ca, err := NewCertificateAuthority(filepath.Join(tempDir, "bastion_ca_key"))
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+
+	if err := ca.Revoke(42, "compromised"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := ca.RevokeGrant("grant-1", "grant deleted"); err != nil {
+		t.Fatalf("revoke grant: %v", err)
+	}
+
+	krl, err := ca.GenerateKRL()
+	if err != nil {
+		t.Fatalf("generate KRL: %v", err)
+	}
+
+	if len(krl) < 8 || binary.BigEndian.Uint64(krl[:8]) != krlMagic {
+		t.Fatal("expected KRL to start with the SSHKRL magic number")
+	}
+	if !bytes.Contains(krl, []byte("grant-1")) {
+		t.Fatal("expected KRL to contain the revoked KeyId")
+	}
+}
+
 func TestIssueUserCertificateRejectsExpiredGrant(t *testing.T) {
 	tempDir := t.TempDir()
 	ca, err := NewCertificateAuthority(filepath.Join(tempDir, "bastion_ca_key"))
@@ -0,0 +1,176 @@
+package bastion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrGrantConflict is returned by GrantBackend.Save/Delete when the
+// caller's compare-and-swap lost a race against another writer (a
+// different shield router or bastion node sharing the same backend). The
+// caller should re-Load the grant and retry.
+var ErrGrantConflict = errors.New("bastion: grant backend conflict, retry")
+
+// GrantEventKind distinguishes the two kinds of change GrantBackend.Watch
+// reports.
+type GrantEventKind int
+
+const (
+	// GrantAdded reports that a grant was created or updated.
+	GrantAdded GrantEventKind = iota
+	// GrantDeleted reports that a grant was removed.
+	GrantDeleted
+)
+
+// GrantEvent is pushed by GrantBackend.Watch when a grant changes in the
+// backend, so every GrantStore sharing that backend can update its
+// in-memory map without polling. Grant is nil for a GrantDeleted event if
+// the backend can't cheaply supply the deleted value; ID is always set.
+type GrantEvent struct {
+	Kind  GrantEventKind
+	ID    string
+	Grant *Grant
+}
+
+// GrantBackend is the storage and replication plane a GrantStore can use
+// in place of (or in addition to) its local JSON file, so grants issued
+// by one shield router or bastion node are immediately visible to every
+// other one sharing the same backend. Grants are stored under a
+// configurable key prefix (e.g. "plasma/grants/<id>") with the same JSON
+// payload as Grant itself, so migrating from the file store to a backend
+// is a one-shot copy of that JSON.
+type GrantBackend interface {
+	// Load returns every grant currently in the backend.
+	Load() ([]*Grant, error)
+	// Save creates or updates grant. Implementations perform this as a
+	// compare-and-swap against the grant's current backend revision (not
+	// tracked by Grant itself, so a backend that needs one keeps its own
+	// revision table keyed by ID): two routers racing to add or renew the
+	// same grant at the same instant must not silently clobber each
+	// other. The loser gets ErrGrantConflict and should Load and retry.
+	Save(grant *Grant) error
+	// Delete removes a grant by ID, with the same compare-and-swap
+	// semantics as Save. Deleting an ID that's already gone is not an
+	// error.
+	Delete(id string) error
+	// Watch streams add/delete events for every grant change in the
+	// backend from the point Watch is called, until ctx is canceled. The
+	// returned channel is closed when ctx is done or the backend's watch
+	// ends unrecoverably.
+	Watch(ctx context.Context) (<-chan GrantEvent, error)
+}
+
+// FileGrantBackend adapts GrantStore's original single-file JSON
+// persistence to the GrantBackend interface. Since the file has no
+// remote readers, Save/Delete's compare-and-swap is just the backend's
+// own mutex (there's nothing else to race against), and Watch never
+// emits anything -- a single process is the only writer, so there's
+// nothing to replicate from.
+type FileGrantBackend struct {
+	mu       sync.Mutex
+	filePath string
+}
+
+// NewFileGrantBackend creates a GrantBackend backed by a single JSON file
+// at filePath, using the same schema GrantStore's original file
+// persistence wrote.
+func NewFileGrantBackend(filePath string) *FileGrantBackend {
+	return &FileGrantBackend{filePath: filePath}
+}
+
+// Load implements GrantBackend.
+func (b *FileGrantBackend) Load() ([]*Grant, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.load()
+}
+
+func (b *FileGrantBackend) load() ([]*Grant, error) {
+	data, err := os.ReadFile(b.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var grants []*Grant
+	if err := json.Unmarshal(data, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// Save implements GrantBackend.
+func (b *FileGrantBackend) Save(grant *Grant) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	grants, err := b.load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i, g := range grants {
+		if g.ID == grant.ID {
+			grants[i] = grant
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		grants = append(grants, grant)
+	}
+	return b.persist(grants)
+}
+
+// Delete implements GrantBackend.
+func (b *FileGrantBackend) Delete(id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	grants, err := b.load()
+	if err != nil {
+		return err
+	}
+	kept := grants[:0]
+	for _, g := range grants {
+		if g.ID != id {
+			kept = append(kept, g)
+		}
+	}
+	return b.persist(kept)
+}
+
+// Watch implements GrantBackend. A single-process file backend has no
+// other writer to replicate from, so the returned channel is closed
+// immediately when ctx is done and otherwise never receives anything.
+func (b *FileGrantBackend) Watch(ctx context.Context) (<-chan GrantEvent, error) {
+	ch := make(chan GrantEvent)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+func (b *FileGrantBackend) persist(grants []*Grant) error {
+	data, err := json.MarshalIndent(grants, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(b.filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmpFile := b.filePath + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, b.filePath)
+}
@@ -0,0 +1,300 @@
+package bastion
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// consulWatchWait is the blocking-query wait Consul is asked to hold a
+// GET open for in ConsulGrantBackend.Watch, mirroring the
+// Consul-style long-polling convention internal/api/blocking.go uses for
+// its own list endpoints.
+const consulWatchWait = 55 * time.Second
+
+// consulKVEntry is a single GET /v1/kv/... result. Value is base64, as
+// Consul's API always returns it.
+type consulKVEntry struct {
+	Key         string `json:"Key"`
+	Value       string `json:"Value"`
+	ModifyIndex uint64 `json:"ModifyIndex"`
+}
+
+// ConsulGrantBackend stores grants in Consul's KV store under Prefix,
+// using Consul's built-in compare-and-swap (the "cas" query parameter,
+// keyed by each key's ModifyIndex) for Save/Delete and a blocking query
+// loop for Watch.
+type ConsulGrantBackend struct {
+	baseURL string
+	prefix  string
+	client  *http.Client
+}
+
+// NewConsulGrantBackend creates a GrantBackend backed by the Consul agent
+// at baseURL (e.g. "http://127.0.0.1:8500"), storing grants under prefix
+// (e.g. "plasma/grants/"; a trailing slash is added if missing).
+func NewConsulGrantBackend(baseURL, prefix string) *ConsulGrantBackend {
+	if prefix == "" {
+		prefix = "plasma/grants/"
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &ConsulGrantBackend{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		prefix:  prefix,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (b *ConsulGrantBackend) keyURL(id string) string {
+	return fmt.Sprintf("%s/v1/kv/%s%s", b.baseURL, b.prefix, id)
+}
+
+// Load implements GrantBackend.
+func (b *ConsulGrantBackend) Load() ([]*Grant, error) {
+	entries, _, err := b.list(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]*Grant, 0, len(entries))
+	for _, e := range entries {
+		grant, err := decodeConsulGrant(e.Value)
+		if err != nil {
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// Save implements GrantBackend.
+func (b *ConsulGrantBackend) Save(grant *Grant) error {
+	data, err := json.Marshal(grant)
+	if err != nil {
+		return err
+	}
+	modifyIndex, err := b.modifyIndex(grant.ID)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s?cas=%d", b.keyURL(grant.ID), modifyIndex)
+	req, err := http.NewRequest(http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	ok, err := b.doBoolRequest(req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrGrantConflict
+	}
+	return nil
+}
+
+// Delete implements GrantBackend.
+func (b *ConsulGrantBackend) Delete(id string) error {
+	modifyIndex, err := b.modifyIndex(id)
+	if err != nil {
+		return err
+	}
+	if modifyIndex == 0 {
+		return nil // already gone
+	}
+
+	u := fmt.Sprintf("%s?cas=%d", b.keyURL(id), modifyIndex)
+	req, err := http.NewRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	ok, err := b.doBoolRequest(req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrGrantConflict
+	}
+	return nil
+}
+
+// Watch implements GrantBackend by repeatedly issuing Consul blocking
+// queries against the grant prefix, diffing each response's ModifyIndex
+// per key against what was last seen to synthesize add/delete events --
+// Consul's HTTP API has no server-push equivalent to etcd's gRPC Watch,
+// so polling (with the server holding the connection open via ?wait=) is
+// the idiomatic way to do this against Consul.
+func (b *ConsulGrantBackend) Watch(ctx context.Context) (<-chan GrantEvent, error) {
+	entries, index, err := b.list(0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]uint64, len(entries))
+	for _, e := range entries {
+		known[strings.TrimPrefix(e.Key, b.prefix)] = e.ModifyIndex
+	}
+
+	ch := make(chan GrantEvent)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			entries, newIndex, err := b.list(index, consulWatchWait)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+					continue
+				}
+			}
+			if newIndex == index {
+				continue // blocking query timed out with nothing new
+			}
+			index = newIndex
+
+			seen := make(map[string]bool, len(entries))
+			for _, e := range entries {
+				id := strings.TrimPrefix(e.Key, b.prefix)
+				seen[id] = true
+				if known[id] == e.ModifyIndex {
+					continue
+				}
+				known[id] = e.ModifyIndex
+				grant, err := decodeConsulGrant(e.Value)
+				if err != nil {
+					continue
+				}
+				if !sendGrantEvent(ctx, ch, GrantEvent{Kind: GrantAdded, ID: id, Grant: grant}) {
+					return
+				}
+			}
+			for id := range known {
+				if seen[id] {
+					continue
+				}
+				delete(known, id)
+				if !sendGrantEvent(ctx, ch, GrantEvent{Kind: GrantDeleted, ID: id}) {
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// sendGrantEvent delivers event to ch, returning false if ctx was
+// canceled first so the caller can stop instead of blocking forever.
+func sendGrantEvent(ctx context.Context, ch chan<- GrantEvent, event GrantEvent) bool {
+	select {
+	case ch <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// modifyIndex fetches the current ModifyIndex for id, or 0 if the key
+// doesn't exist yet (Consul's own convention for "create this key" via
+// ?cas=0).
+func (b *ConsulGrantBackend) modifyIndex(id string) (uint64, error) {
+	resp, err := b.client.Get(b.keyURL(id))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("consul: get %s: status %d", id, resp.StatusCode)
+	}
+
+	var entries []consulKVEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	return entries[0].ModifyIndex, nil
+}
+
+// list issues GET /v1/kv/{prefix}?recurse=true, optionally as a blocking
+// query (index > 0) that waits up to wait for a change past index.
+func (b *ConsulGrantBackend) list(index uint64, wait time.Duration) ([]consulKVEntry, uint64, error) {
+	u := fmt.Sprintf("%s/v1/kv/%s?recurse=true", b.baseURL, b.prefix)
+	client := b.client
+	if index > 0 {
+		u += fmt.Sprintf("&index=%d&wait=%s", index, wait)
+		client = &http.Client{Timeout: wait + 10*time.Second}
+	}
+
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	consulIndex := parseConsulIndex(resp.Header)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, consulIndex, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consul: list %s: status %d", b.prefix, resp.StatusCode)
+	}
+
+	var entries []consulKVEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, consulIndex, nil
+}
+
+// doBoolRequest sends req (a Consul PUT or DELETE) and decodes its
+// "true"/"false" JSON response body, the convention Consul's KV CAS
+// operations use to report whether the compare-and-swap succeeded.
+func (b *ConsulGrantBackend) doBoolRequest(req *http.Request) (bool, error) {
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var ok bool
+	if err := json.NewDecoder(resp.Body).Decode(&ok); err != nil {
+		return false, fmt.Errorf("consul: decode response: %w", err)
+	}
+	return ok, nil
+}
+
+func parseConsulIndex(header http.Header) uint64 {
+	idx, _ := strconv.ParseUint(header.Get("X-Consul-Index"), 10, 64)
+	return idx
+}
+
+func decodeConsulGrant(base64Value string) (*Grant, error) {
+	data, err := base64.StdEncoding.DecodeString(base64Value)
+	if err != nil {
+		return nil, err
+	}
+	var g Grant
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
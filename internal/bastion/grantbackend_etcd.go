@@ -0,0 +1,162 @@
+//go:build !noetcd
+
+package bastion
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcdRequestTimeout bounds each individual etcd call EtcdGrantBackend
+// makes (Load/Save/Delete); Watch is long-lived and uses the caller's ctx
+// directly instead.
+const etcdRequestTimeout = 10 * time.Second
+
+// EtcdGrantBackend stores grants in etcd v3 under a configurable key
+// prefix, using clientv3's Txn for compare-and-swap (keyed by each key's
+// mod revision) and its native Watch API for replication -- unlike
+// ConsulGrantBackend, which has to re-poll via blocking queries, etcd's
+// gRPC Watch pushes changes to the client directly.
+type EtcdGrantBackend struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// NewEtcdGrantBackend creates a GrantBackend backed by client, storing
+// grants under prefix (e.g. "plasma/grants/"; a trailing slash is added
+// if missing).
+func NewEtcdGrantBackend(client *clientv3.Client, prefix string) *EtcdGrantBackend {
+	if prefix == "" {
+		prefix = "plasma/grants/"
+	}
+	if prefix[len(prefix)-1] != '/' {
+		prefix += "/"
+	}
+	return &EtcdGrantBackend{client: client, prefix: prefix}
+}
+
+func (b *EtcdGrantBackend) key(id string) string {
+	return b.prefix + id
+}
+
+// Load implements GrantBackend.
+func (b *EtcdGrantBackend) Load() ([]*Grant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	resp, err := b.client.Get(ctx, b.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]*Grant, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var g Grant
+		if err := json.Unmarshal(kv.Value, &g); err != nil {
+			continue
+		}
+		grants = append(grants, &g)
+	}
+	return grants, nil
+}
+
+// Save implements GrantBackend: a Txn commits the Put only if the key's
+// mod revision hasn't changed since it was last read, so two routers
+// racing to add or renew the same grant can't silently clobber each
+// other.
+func (b *EtcdGrantBackend) Save(grant *Grant) error {
+	data, err := json.Marshal(grant)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	key := b.key(grant.ID)
+	getResp, err := b.client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	var modRev int64
+	if len(getResp.Kvs) > 0 {
+		modRev = getResp.Kvs[0].ModRevision
+	}
+
+	txnResp, err := b.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRev)).
+		Then(clientv3.OpPut(key, string(data))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnResp.Succeeded {
+		return ErrGrantConflict
+	}
+	return nil
+}
+
+// Delete implements GrantBackend, using the same mod-revision
+// compare-and-swap as Save.
+func (b *EtcdGrantBackend) Delete(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdRequestTimeout)
+	defer cancel()
+
+	key := b.key(id)
+	getResp, err := b.client.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(getResp.Kvs) == 0 {
+		return nil // already gone
+	}
+	modRev := getResp.Kvs[0].ModRevision
+
+	txnResp, err := b.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", modRev)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txnResp.Succeeded {
+		return ErrGrantConflict
+	}
+	return nil
+}
+
+// Watch implements GrantBackend using etcd's native watch stream.
+func (b *EtcdGrantBackend) Watch(ctx context.Context) (<-chan GrantEvent, error) {
+	watchCh := b.client.Watch(ctx, b.prefix, clientv3.WithPrefix())
+	ch := make(chan GrantEvent)
+
+	go func() {
+		defer close(ch)
+		for resp := range watchCh {
+			for _, ev := range resp.Events {
+				id := string(ev.Kv.Key)[len(b.prefix):]
+
+				var event GrantEvent
+				if ev.Type == clientv3.EventTypeDelete {
+					event = GrantEvent{Kind: GrantDeleted, ID: id}
+				} else {
+					var g Grant
+					if err := json.Unmarshal(ev.Kv.Value, &g); err != nil {
+						continue
+					}
+					event = GrantEvent{Kind: GrantAdded, ID: id, Grant: &g}
+				}
+
+				select {
+				case ch <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return ch, nil
+}
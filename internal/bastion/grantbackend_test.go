@@ -0,0 +1,257 @@
+package bastion
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileGrantBackend_SaveLoadDelete(t *testing.T) {
+	dir := t.TempDir()
+	backend := NewFileGrantBackend(filepath.Join(dir, "grants.json"))
+
+	grant := &Grant{ID: "grant-1", Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := backend.Save(grant); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "grant-1" {
+		t.Fatalf("expected 1 grant with ID grant-1, got %+v", loaded)
+	}
+
+	if err := backend.Delete("grant-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	loaded, err = backend.Load()
+	if err != nil {
+		t.Fatalf("Load after delete: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("expected no grants after delete, got %+v", loaded)
+	}
+}
+
+func TestFileGrantBackend_LoadMissingFile(t *testing.T) {
+	backend := NewFileGrantBackend(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	grants, err := backend.Load()
+	if err != nil {
+		t.Fatalf("expected no error for a missing file, got %v", err)
+	}
+	if grants != nil {
+		t.Fatalf("expected nil grants, got %+v", grants)
+	}
+}
+
+func TestGrantStore_WithBackendReplication(t *testing.T) {
+	dir := t.TempDir()
+	backend := NewFileGrantBackend(filepath.Join(dir, "grants.json"))
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	store := NewGrantStoreWithBackend(backend, func() time.Time { return now })
+	grant := store.Add("alice", "agent-1", "admin", 30*time.Minute)
+
+	// A second store instance sharing the same backend should see the
+	// grant on construction, the way a second shield router would.
+	peer := NewGrantStoreWithBackend(backend, func() time.Time { return now })
+	if got := peer.Get(grant.ID); got == nil {
+		t.Fatalf("expected peer store to see grant %s via the shared backend", grant.ID)
+	}
+
+	if !store.Delete(grant.ID) {
+		t.Fatalf("expected Delete to report the grant existed")
+	}
+	grants, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(grants) != 0 {
+		t.Fatalf("expected backend to have no grants after Delete, got %+v", grants)
+	}
+}
+
+// consulKVServer fakes just enough of Consul's KV HTTP API for
+// ConsulGrantBackend's tests: GET (single key and recurse), PUT with
+// ?cas=, and DELETE with ?cas=.
+type consulKVServer struct {
+	mu      sync.Mutex
+	entries map[string]consulKVEntry
+	index   uint64
+}
+
+func newConsulKVServer() *httptest.Server {
+	s := &consulKVServer{entries: make(map[string]consulKVEntry)}
+	return httptest.NewServer(http.HandlerFunc(s.serve))
+}
+
+func (s *consulKVServer) serve(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Path[len("/v1/kv/"):]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(s.index, 10))
+		if r.URL.Query().Get("recurse") == "true" {
+			var out []consulKVEntry
+			for k, e := range s.entries {
+				if len(k) >= len(key) && k[:len(key)] == key {
+					out = append(out, e)
+				}
+			}
+			json.NewEncoder(w).Encode(out)
+			return
+		}
+		e, ok := s.entries[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]consulKVEntry{e})
+	case http.MethodPut:
+		cas, _ := strconv.ParseUint(r.URL.Query().Get("cas"), 10, 64)
+		existing, ok := s.entries[key]
+		if cas != 0 && (!ok || existing.ModifyIndex != cas) {
+			json.NewEncoder(w).Encode(false)
+			return
+		}
+		if cas == 0 && ok {
+			json.NewEncoder(w).Encode(false)
+			return
+		}
+		body := make([]byte, r.ContentLength)
+		r.Body.Read(body)
+		s.index++
+		s.entries[key] = consulKVEntry{
+			Key:         key,
+			Value:       base64.StdEncoding.EncodeToString(body),
+			ModifyIndex: s.index,
+		}
+		json.NewEncoder(w).Encode(true)
+	case http.MethodDelete:
+		cas, _ := strconv.ParseUint(r.URL.Query().Get("cas"), 10, 64)
+		existing, ok := s.entries[key]
+		if !ok || existing.ModifyIndex != cas {
+			json.NewEncoder(w).Encode(false)
+			return
+		}
+		delete(s.entries, key)
+		s.index++
+		json.NewEncoder(w).Encode(true)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestConsulGrantBackend_SaveLoadDelete(t *testing.T) {
+	srv := newConsulKVServer()
+	defer srv.Close()
+	backend := NewConsulGrantBackend(srv.URL, "plasma/grants/")
+
+	grant := &Grant{ID: "grant-1", Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := backend.Save(grant); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := backend.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].ID != "grant-1" {
+		t.Fatalf("expected 1 grant with ID grant-1, got %+v", loaded)
+	}
+
+	if err := backend.Delete("grant-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	loaded, err = backend.Load()
+	if err != nil {
+		t.Fatalf("Load after delete: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("expected no grants after delete, got %+v", loaded)
+	}
+}
+
+func TestConsulGrantBackend_SaveConflict(t *testing.T) {
+	srv := newConsulKVServer()
+	defer srv.Close()
+	backend := NewConsulGrantBackend(srv.URL, "plasma/grants/")
+
+	grant := &Grant{ID: "grant-1", Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := backend.Save(grant); err != nil {
+		t.Fatalf("first Save: %v", err)
+	}
+
+	// Simulate a peer's concurrent write bumping the key's ModifyIndex:
+	// this Save's cas was already computed against the pre-bump revision,
+	// so it must lose the race instead of silently overwriting the peer.
+	second := NewConsulGrantBackend(srv.URL, "plasma/grants/")
+	staleModifyIndex, err := backend.modifyIndex(grant.ID)
+	if err != nil {
+		t.Fatalf("modifyIndex: %v", err)
+	}
+	if err := second.Save(&Grant{ID: "grant-1", Principal: "bob", Target: "agent-1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("peer Save: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, backend.keyURL(grant.ID)+"?cas="+strconv.FormatUint(staleModifyIndex, 10), nil)
+	ok, err := backend.doBoolRequest(req)
+	if err != nil {
+		t.Fatalf("doBoolRequest: %v", err)
+	}
+	if ok {
+		t.Fatalf("expected a CAS against a stale ModifyIndex to fail")
+	}
+}
+
+func TestConsulGrantBackend_Watch(t *testing.T) {
+	srv := newConsulKVServer()
+	defer srv.Close()
+	backend := NewConsulGrantBackend(srv.URL, "plasma/grants/")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := backend.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	grant := &Grant{ID: "grant-1", Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := backend.Save(grant); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Kind != GrantAdded || event.ID != "grant-1" {
+			t.Fatalf("expected a GrantAdded event for grant-1, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for add event")
+	}
+
+	if err := backend.Delete("grant-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.Kind != GrantDeleted || event.ID != "grant-1" {
+			t.Fatalf("expected a GrantDeleted event for grant-1, got %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for delete event")
+	}
+}
@@ -1,21 +1,41 @@
 package bastion
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
 )
 
-// GrantStore stores access grants in memory with JSON file persistence.
+// grantSubscriberBufferSize bounds how far a GET /grants/events subscriber
+// can lag before events are dropped for it, mirroring
+// internal/api.Store.agentSubscribers.
+const grantSubscriberBufferSize = 16
+
+// GrantStore stores access grants in memory with JSON file persistence,
+// or -- when constructed with NewGrantStoreWithBackend -- replicated
+// through a GrantBackend so grants issued by one shield router are
+// visible to every other one sharing that backend.
 type GrantStore struct {
 	mu       sync.RWMutex
 	grants   map[string]*Grant
 	filePath string
 	now      func() time.Time
 	counter  int64
+	metrics  *limiter.Metrics
+	backend  GrantBackend
+
+	subMu            sync.Mutex
+	subscribers      map[int]chan GrantEvent
+	nextSubscriberID int
 }
 
 // NewGrantStore creates a new GrantStore with optional file persistence.
@@ -30,9 +50,10 @@ func NewGrantStoreWithClock(filePath string, now func() time.Time) *GrantStore {
 		panic("bastion: nil clock")
 	}
 	s := &GrantStore{
-		grants:   make(map[string]*Grant),
-		filePath: filePath,
-		now:      now,
+		grants:      make(map[string]*Grant),
+		filePath:    filePath,
+		now:         now,
+		subscribers: make(map[int]chan GrantEvent),
 	}
 	if filePath != "" {
 		s.load()
@@ -40,8 +61,123 @@ func NewGrantStoreWithClock(filePath string, now func() time.Time) *GrantStore {
 	return s
 }
 
+// NewGrantStoreWithBackend creates a GrantStore whose Add/Delete/Cleanup
+// replicate through backend (see GrantBackend) instead of a local JSON
+// file, so grants are visible to every shield router or bastion node
+// sharing the same backend. Call StartWatch afterwards to also pull in
+// grants another instance adds or removes.
+func NewGrantStoreWithBackend(backend GrantBackend, now func() time.Time) *GrantStore {
+	if now == nil {
+		panic("bastion: nil clock")
+	}
+	s := &GrantStore{
+		grants:      make(map[string]*Grant),
+		now:         now,
+		backend:     backend,
+		subscribers: make(map[int]chan GrantEvent),
+	}
+	grants, err := backend.Load()
+	if err != nil {
+		log.Printf("bastion: initial grant backend load failed: %v", err)
+	}
+	for _, g := range grants {
+		s.grants[g.ID] = g
+	}
+	return s
+}
+
+// StartWatch consumes backend's Watch stream, applying each GrantEvent to
+// the in-memory map as it arrives -- the live-replication counterpart to
+// StartCleanup's periodic expiry sweep. It's a no-op if the store wasn't
+// constructed with a backend.
+func (s *GrantStore) StartWatch(ctx context.Context) error {
+	if s.backend == nil {
+		return nil
+	}
+	events, err := s.backend.Watch(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for event := range events {
+			s.mu.Lock()
+			switch event.Kind {
+			case GrantAdded:
+				if event.Grant != nil {
+					s.grants[event.ID] = event.Grant
+				}
+			case GrantDeleted:
+				delete(s.grants, event.ID)
+			}
+			s.mu.Unlock()
+			s.publish(event)
+		}
+	}()
+	return nil
+}
+
+// Subscribe registers a new live subscriber for GET /grants/events,
+// returning a channel of every grant add/delete (local or, for a store
+// constructed with NewGrantStoreWithBackend, replicated in from another
+// router via StartWatch) and an unsubscribe func that must be called
+// (typically via defer) when the client disconnects.
+func (s *GrantStore) Subscribe() (<-chan GrantEvent, func()) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	id := s.nextSubscriberID
+	s.nextSubscriberID++
+	ch := make(chan GrantEvent, grantSubscriberBufferSize)
+	s.subscribers[id] = ch
+
+	return ch, func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// publish fans event out to every live Subscribe channel. A subscriber
+// that isn't keeping up has the event dropped rather than blocking the
+// caller, the same drop-if-full semantics as internal/api's stream
+// subscribers.
+func (s *GrantStore) publish(event GrantEvent) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SetMetrics wires a Prometheus-style metrics registry that Add/AddWithRecord
+// and Cleanup report grant issuance/expiry events to. Nil (the default)
+// disables reporting.
+func (s *GrantStore) SetMetrics(m *limiter.Metrics) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = m
+}
+
 // Add creates a new grant and persists it.
 func (s *GrantStore) Add(principal, target, createdBy string, duration time.Duration) *Grant {
+	return s.AddWithOptions(principal, target, createdBy, duration, false, false)
+}
+
+// AddWithRecord is like Add, but also sets Grant.Record, gating whether
+// handleDirectTCPIP tees the grant's sessions to the bastion's configured
+// Recorder.
+func (s *GrantStore) AddWithRecord(principal, target, createdBy string, duration time.Duration, record bool) *Grant {
+	return s.AddWithOptions(principal, target, createdBy, duration, record, false)
+}
+
+// AddWithOptions is like AddWithRecord, but also sets Grant.Shell, gating
+// whether handleSession authorizes the grant's principal to broker an
+// interactive SSH session onto its target, not just a direct-tcpip tunnel.
+func (s *GrantStore) AddWithOptions(principal, target, createdBy string, duration time.Duration, record, shell bool) *Grant {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -54,13 +190,62 @@ func (s *GrantStore) Add(principal, target, createdBy string, duration time.Dura
 		ExpiresAt: now.Add(duration),
 		CreatedBy: createdBy,
 		CreatedAt: now,
+		Record:    record,
+		Shell:     shell,
 	}
 
 	s.grants[grant.ID] = grant
-	s.persist()
+	if s.backend != nil {
+		s.saveToBackend(grant)
+	} else {
+		s.persist()
+	}
+	if s.metrics != nil {
+		s.metrics.IncGrantEvent("issued")
+	}
+	s.publish(GrantEvent{Kind: GrantAdded, ID: grant.ID, Grant: grant})
 	return grant
 }
 
+// saveToBackend writes grant to s.backend, retrying a bounded number of
+// times if another instance's concurrent write loses the
+// compare-and-swap race. generateGrantID mints a fresh ID per call, so a
+// conflict here would mean two routers somehow picked the same one, or
+// (for a caller that mutates a grant in place and re-Adds it) a
+// concurrent renewal -- either way, retrying against the latest backend
+// revision resolves it.
+func (s *GrantStore) saveToBackend(grant *Grant) {
+	for attempt := 0; attempt < 3; attempt++ {
+		err := s.backend.Save(grant)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, ErrGrantConflict) {
+			continue
+		}
+		log.Printf("bastion: grant backend save failed: %v", err)
+		return
+	}
+	log.Printf("bastion: grant backend save for %s gave up after repeated conflicts", grant.ID)
+}
+
+// deleteFromBackend removes id from s.backend, with the same
+// bounded-retry compare-and-swap handling as saveToBackend.
+func (s *GrantStore) deleteFromBackend(id string) {
+	for attempt := 0; attempt < 3; attempt++ {
+		err := s.backend.Delete(id)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, ErrGrantConflict) {
+			continue
+		}
+		log.Printf("bastion: grant backend delete failed: %v", err)
+		return
+	}
+	log.Printf("bastion: grant backend delete for %s gave up after repeated conflicts", id)
+}
+
 // Get retrieves a grant by ID. Returns nil if not found or expired.
 func (s *GrantStore) Get(id string) *Grant {
 	s.mu.RLock()
@@ -86,7 +271,12 @@ func (s *GrantStore) Delete(id string) bool {
 	}
 
 	delete(s.grants, id)
-	s.persist()
+	if s.backend != nil {
+		s.deleteFromBackend(id)
+	} else {
+		s.persist()
+	}
+	s.publish(GrantEvent{Kind: GrantDeleted, ID: id})
 	return true
 }
 
@@ -134,6 +324,27 @@ func (s *GrantStore) ValidateAccess(principal, target string) *Grant {
 	return nil
 }
 
+// ValidateShellAccess checks if principal holds an active grant authorizing
+// an interactive "session" channel (Grant.Shell), returning it so
+// handleSession can dial its Target. Unlike ValidateAccess, there's no
+// requested target to match against: a "session" channel's payload carries
+// no destination, so the grant's own Target is what gets dialed.
+func (s *GrantStore) ValidateShellAccess(principal string) *Grant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := s.now()
+	for _, g := range s.grants {
+		if now.After(g.ExpiresAt) {
+			continue
+		}
+		if g.Shell && g.Principal == principal {
+			return g
+		}
+	}
+	return nil
+}
+
 // Cleanup removes all expired grants.
 func (s *GrantStore) Cleanup() int {
 	s.mu.Lock()
@@ -141,18 +352,54 @@ func (s *GrantStore) Cleanup() int {
 
 	now := s.now()
 	removed := 0
+	var removedIDs []string
 	for id, g := range s.grants {
 		if now.After(g.ExpiresAt) {
 			delete(s.grants, id)
+			removedIDs = append(removedIDs, id)
 			removed++
 		}
 	}
 	if removed > 0 {
-		s.persist()
+		if s.backend != nil {
+			for _, id := range removedIDs {
+				s.deleteFromBackend(id)
+			}
+		} else {
+			s.persist()
+		}
+		if s.metrics != nil {
+			s.metrics.AddGrantEvents("expired", int64(removed))
+		}
+		for _, id := range removedIDs {
+			s.publish(GrantEvent{Kind: GrantDeleted, ID: id})
+		}
 	}
 	return removed
 }
 
+// StartCleanup runs Cleanup on a ticker every interval until ctx is
+// canceled, so expired grants don't accumulate in memory and on disk
+// between process restarts. Cleanup already persists when it removes
+// anything, so there's nothing more to save here; this just logs how many
+// were pruned each pass (Cleanup itself reports the count via SetMetrics).
+func (s *GrantStore) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if removed := s.Cleanup(); removed > 0 {
+					log.Printf("bastion: pruned %d expired grant(s)", removed)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
 // persist saves grants to the JSON file (must be called with lock held).
 func (s *GrantStore) persist() {
 	if s.filePath == "" {
@@ -199,7 +446,10 @@ func (s *GrantStore) load() {
 }
 
 // matchTarget checks if a grant target matches a requested target.
-// Supports exact match and wildcard (*) patterns.
+// Supports exact match, the bare wildcard (*), and a trailing "/*" prefix
+// glob (e.g. "fleet/prod/*" matches "fleet/prod/agent-1"), so a grant can
+// cover a whole SPIFFE trust-domain path without enumerating every agent
+// in it.
 func matchTarget(grantTarget, requestedTarget string) bool {
 	if grantTarget == "*" {
 		return true
@@ -207,7 +457,10 @@ func matchTarget(grantTarget, requestedTarget string) bool {
 	if grantTarget == requestedTarget {
 		return true
 	}
-	// Could extend to support glob patterns later
+	if strings.HasSuffix(grantTarget, "/*") {
+		prefix := strings.TrimSuffix(grantTarget, "/*")
+		return requestedTarget == prefix || strings.HasPrefix(requestedTarget, prefix+"/")
+	}
 	return false
 }
 
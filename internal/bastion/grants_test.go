@@ -32,6 +32,58 @@ func TestGrantStore_Add(t *testing.T) {
 	if !grant.ExpiresAt.Equal(expectedExpiry) {
 		t.Errorf("expected expires_at %v, got %v", expectedExpiry, grant.ExpiresAt)
 	}
+	if grant.Record {
+		t.Error("expected Add to default Record to false")
+	}
+}
+
+func TestGrantStore_AddWithRecord(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	store := NewGrantStoreWithClock("", func() time.Time { return now })
+
+	grant := store.AddWithRecord("alice", "sarai-chinwag", "admin", 30*time.Minute, true)
+
+	if !grant.Record {
+		t.Error("expected Record to be true")
+	}
+}
+
+func TestGrantStore_AddWithOptions(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	store := NewGrantStoreWithClock("", func() time.Time { return now })
+
+	grant := store.AddWithOptions("alice", "sarai-chinwag", "admin", 30*time.Minute, true, true)
+
+	if !grant.Record {
+		t.Error("expected Record to be true")
+	}
+	if !grant.Shell {
+		t.Error("expected Shell to be true")
+	}
+}
+
+func TestGrantStore_ValidateShellAccess(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	currentTime := now
+	store := NewGrantStoreWithClock("", func() time.Time { return currentTime })
+
+	// alice's grant doesn't authorize shell access
+	store.Add("alice", "sarai-chinwag", "admin", 30*time.Minute)
+	// bob's does
+	shellGrant := store.AddWithOptions("bob", "star-fleet", "admin", 30*time.Minute, false, true)
+
+	if store.ValidateShellAccess("alice") != nil {
+		t.Error("expected alice to NOT have shell access")
+	}
+	if got := store.ValidateShellAccess("bob"); got == nil || got.ID != shellGrant.ID {
+		t.Error("expected bob to have shell access via his Shell grant")
+	}
+
+	// Expired grants don't validate
+	currentTime = now.Add(31 * time.Minute)
+	if store.ValidateShellAccess("bob") != nil {
+		t.Error("expected expired shell grant to not validate")
+	}
 }
 
 func TestGrantStore_Get(t *testing.T) {
@@ -247,6 +299,10 @@ func TestMatchTarget(t *testing.T) {
 		{"*", "any-target", true},
 		{"", "", true},
 		{"", "something", false},
+		{"fleet/prod/*", "fleet/prod/agent-1", true},
+		{"fleet/prod/*", "fleet/prod", true},
+		{"fleet/prod/*", "fleet/staging/agent-1", false},
+		{"fleet/prod/*", "fleet/prod-west/agent-1", false},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,179 @@
+// Package keystore implements encryption-at-rest for bastion private keys,
+// inspired by Swarmkit's node-locking model: a key is wrapped under a
+// passphrase-derived AES-256-GCM key (argon2id) and persisted as
+// {salt, nonce, ciphertext} JSON, rather than cleartext PEM on disk.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrLocked is returned when an encrypted-at-rest key needs a passphrase
+// that wasn't supplied: either an *.enc file already exists and no
+// passphrase was given to decrypt it, or none exists yet and no passphrase
+// was given to create one.
+var ErrLocked = errors.New("keystore: locked; unlock passphrase required")
+
+const (
+	saltSize = 16
+
+	// argon2id parameters, chosen per the library's recommended
+	// interactive-login baseline.
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	argonKeyLen  = 32 // AES-256
+)
+
+// Wrapped is the on-disk encoding of a key encrypted with a passphrase.
+type Wrapped struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+}
+
+// Seal encrypts plaintext with a key derived from passphrase, generating a
+// fresh random salt and nonce.
+func Seal(plaintext []byte, passphrase string) (*Wrapped, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("keystore: generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("keystore: generate nonce: %w", err)
+	}
+
+	return &Wrapped{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, nil
+}
+
+// Open decrypts w with a key derived from passphrase, failing if the
+// passphrase is wrong or the ciphertext has been tampered with.
+func Open(w *Wrapped, passphrase string) ([]byte, error) {
+	gcm, err := newGCM(passphrase, w.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, w.Nonce, w.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("keystore: wrong passphrase or corrupted key")
+	}
+	return plaintext, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(passphrase, salt))
+	if err != nil {
+		return nil, fmt.Errorf("keystore: new cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: new gcm: %w", err)
+	}
+	return gcm, nil
+}
+
+// Save writes w as JSON to path.
+func Save(path string, w *Wrapped) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return fmt.Errorf("keystore: marshal %s: %w", path, err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Load reads and parses a Wrapped key from path.
+func Load(path string) (*Wrapped, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var w Wrapped
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, fmt.Errorf("keystore: parse %s: %w", path, err)
+	}
+	return &w, nil
+}
+
+// Exists reports whether an encrypted-at-rest key already exists at path.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// LoadOrCreate loads and decrypts the key wrapped at encPath using
+// passphrase, bootstrapping it on first use: if encPath doesn't exist yet,
+// generate is called to produce the plaintext key, which is then sealed
+// under passphrase and written to encPath. Either way, passphrase == ""
+// returns ErrLocked rather than touching the file, so callers can surface
+// "needs /unlock" distinctly from other I/O errors.
+func LoadOrCreate(encPath, passphrase string, generate func() ([]byte, error)) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrLocked
+	}
+
+	wrapped, err := Load(encPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("keystore: read %s: %w", encPath, err)
+		}
+
+		plaintext, err := generate()
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := Seal(plaintext, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if err := Save(encPath, sealed); err != nil {
+			return nil, err
+		}
+		return plaintext, nil
+	}
+
+	return Open(wrapped, passphrase)
+}
+
+// Rotate re-wraps the key at encPath under newPassphrase, after verifying
+// oldPassphrase. The key material itself is unchanged.
+func Rotate(encPath, oldPassphrase, newPassphrase string) error {
+	wrapped, err := Load(encPath)
+	if err != nil {
+		return fmt.Errorf("keystore: read %s: %w", encPath, err)
+	}
+
+	plaintext, err := Open(wrapped, oldPassphrase)
+	if err != nil {
+		return err
+	}
+
+	resealed, err := Seal(plaintext, newPassphrase)
+	if err != nil {
+		return err
+	}
+	return Save(encPath, resealed)
+}
@@ -0,0 +1,123 @@
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	plaintext := []byte("super secret key material")
+
+	wrapped, err := Seal(plaintext, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	got, err := Open(wrapped, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("expected %q, got %q", plaintext, got)
+	}
+}
+
+func TestOpenWrongPassphraseRefused(t *testing.T) {
+	wrapped, err := Seal([]byte("super secret key material"), "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	if _, err := Open(wrapped, "wrong passphrase"); err == nil {
+		t.Fatal("expected open with wrong passphrase to fail")
+	}
+}
+
+func TestLoadOrCreateBootstrapsThenReloads(t *testing.T) {
+	encPath := filepath.Join(t.TempDir(), "key.enc")
+	calls := 0
+	generate := func() ([]byte, error) {
+		calls++
+		return []byte("generated key material"), nil
+	}
+
+	first, err := LoadOrCreate(encPath, "hunter2", generate)
+	if err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+	if string(first) != "generated key material" {
+		t.Fatalf("unexpected bootstrapped key: %q", first)
+	}
+	if !Exists(encPath) {
+		t.Fatal("expected *.enc file to be written")
+	}
+
+	second, err := LoadOrCreate(encPath, "hunter2", generate)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !bytes.Equal(second, first) {
+		t.Fatal("expected reload to return the same key, not regenerate it")
+	}
+	if calls != 1 {
+		t.Fatalf("expected generate to run once, ran %d times", calls)
+	}
+}
+
+func TestLoadOrCreateRefusesWithoutPassphrase(t *testing.T) {
+	encPath := filepath.Join(t.TempDir(), "key.enc")
+	generate := func() ([]byte, error) { return []byte("generated key material"), nil }
+
+	if _, err := LoadOrCreate(encPath, "", generate); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked when bootstrapping without a passphrase, got %v", err)
+	}
+
+	if _, err := LoadOrCreate(encPath, "hunter2", generate); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	if _, err := LoadOrCreate(encPath, "", generate); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked when reloading without a passphrase, got %v", err)
+	}
+}
+
+func TestRotateReencryptsUnderNewPassphrase(t *testing.T) {
+	encPath := filepath.Join(t.TempDir(), "key.enc")
+	generate := func() ([]byte, error) { return []byte("generated key material"), nil }
+
+	original, err := LoadOrCreate(encPath, "old-passphrase", generate)
+	if err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	if err := Rotate(encPath, "old-passphrase", "new-passphrase"); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	if _, err := LoadOrCreate(encPath, "old-passphrase", generate); err == nil {
+		t.Fatal("expected old passphrase to be rejected after rotation")
+	}
+
+	rotated, err := LoadOrCreate(encPath, "new-passphrase", generate)
+	if err != nil {
+		t.Fatalf("load with new passphrase: %v", err)
+	}
+	if !bytes.Equal(rotated, original) {
+		t.Fatal("expected rotation to preserve the key material")
+	}
+}
+
+func TestRotateWrongOldPassphraseRefused(t *testing.T) {
+	encPath := filepath.Join(t.TempDir(), "key.enc")
+	generate := func() ([]byte, error) { return []byte("generated key material"), nil }
+
+	if _, err := LoadOrCreate(encPath, "old-passphrase", generate); err != nil {
+		t.Fatalf("bootstrap: %v", err)
+	}
+
+	if err := Rotate(encPath, "wrong-passphrase", "new-passphrase"); err == nil {
+		t.Fatal("expected rotate with the wrong old passphrase to fail")
+	}
+}
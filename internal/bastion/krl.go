@@ -0,0 +1,86 @@
+package bastion
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// OpenSSH Key Revocation List format, see ssh-keygen(1) and the PROTOCOL.krl
+// file shipped with OpenSSH. Only the two section types plasma-shield needs
+// are implemented: revoked certs by serial, and revoked cert KeyIds.
+const (
+	krlMagic         uint64 = 0x5353484b524c0a00 // "SSHKRL\n\0"
+	krlFormatVersion uint32 = 1
+
+	krlSectionCertificates byte = 1
+
+	krlCertSectionSerialList byte = 0x20
+	krlCertSectionKeyID      byte = 0x22
+)
+
+// GenerateKRL renders an OpenSSH-format Key Revocation List covering every
+// serial and KeyId currently in the CA's revocation store. The result can
+// be written to a file and referenced from sshd_config's RevokedKeys
+// directive, so downstream sshd instances can honor bastion revocations
+// without querying the CA directly.
+func (c *CertificateAuthority) GenerateKRL() ([]byte, error) {
+	entries := c.revocations.Entries()
+
+	var serials []uint64
+	var keyIDs []string
+	for _, e := range entries {
+		if e.KeyID != "" {
+			keyIDs = append(keyIDs, e.KeyID)
+			continue
+		}
+		serials = append(serials, e.Serial)
+	}
+
+	now := uint64(c.now().Unix())
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, krlMagic)
+	binary.Write(&buf, binary.BigEndian, krlFormatVersion)
+	binary.Write(&buf, binary.BigEndian, now)       // krl_version
+	binary.Write(&buf, binary.BigEndian, now)       // generated_date
+	binary.Write(&buf, binary.BigEndian, uint64(0)) // flags
+	writeKRLString(&buf, nil)                       // reserved
+	writeKRLString(&buf, []byte("plasma-shield bastion CA"))
+
+	var section bytes.Buffer
+	writeKRLString(&section, c.publicKey.Marshal())     // ca_key
+	binary.Write(&section, binary.BigEndian, uint64(0)) // reserved
+
+	if len(serials) > 0 {
+		var sub bytes.Buffer
+		for _, serial := range serials {
+			// Single-serial ranges: [serial, serial].
+			binary.Write(&sub, binary.BigEndian, serial)
+			binary.Write(&sub, binary.BigEndian, serial)
+		}
+		section.WriteByte(krlCertSectionSerialList)
+		writeKRLUint32Prefixed(&section, sub.Bytes())
+	}
+
+	for _, keyID := range keyIDs {
+		var sub bytes.Buffer
+		writeKRLString(&sub, []byte(keyID))
+		section.WriteByte(krlCertSectionKeyID)
+		writeKRLUint32Prefixed(&section, sub.Bytes())
+	}
+
+	buf.WriteByte(krlSectionCertificates)
+	writeKRLUint32Prefixed(&buf, section.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func writeKRLString(buf *bytes.Buffer, s []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(s)))
+	buf.Write(s)
+}
+
+func writeKRLUint32Prefixed(buf *bytes.Buffer, data []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+}
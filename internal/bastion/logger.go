@@ -1,19 +1,38 @@
 package bastion
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/logs"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
 )
 
 const DefaultLogLimit = 10000
 
+// subscriberBufferSize bounds a Subscribe channel. Unlike Tail's broker
+// subscribers (which drop an incoming event when a subscriber is full, so a
+// slow reader never loses what's already queued), Subscribe drops the
+// oldest queued event instead: a forensic WebSocket tail (GET
+// /bastion/sessions/stream/ws) cares about staying current, not about
+// preserving a backlog a slow client may never catch up on.
+const subscriberBufferSize = 64
+
 // LogStore stores session events in memory.
 type LogStore struct {
 	mu     sync.RWMutex
 	events []SessionEvent
 	limit  int
+	broker *logs.Broker
+	logger *slog.Logger
+
+	subMu            sync.Mutex
+	subscribers      map[int]chan SessionEvent
+	nextSubscriberID int
 }
 
 // NewLogStore creates a new LogStore with a limit.
@@ -22,24 +41,131 @@ func NewLogStore(limit int) *LogStore {
 		limit = DefaultLogLimit
 	}
 	return &LogStore{
-		events: make([]SessionEvent, 0, limit),
-		limit:  limit,
+		events:      make([]SessionEvent, 0, limit),
+		limit:       limit,
+		broker:      logs.NewBroker(limit),
+		logger:      plasmalog.Default(),
+		subscribers: make(map[int]chan SessionEvent),
 	}
 }
 
-// Add stores a session event and logs it as JSON.
-func (s *LogStore) Add(event SessionEvent) {
-	data, err := json.Marshal(event)
-	if err == nil {
-		log.Println(string(data))
+// Subscribe registers a new live subscriber, returning a channel of every
+// session event published after it was created and an unsubscribe func
+// that must be called (typically via defer) when the client disconnects.
+// Unlike Tail, Subscribe has no replay buffer -- it's meant for a live
+// WebSocket tail (GET /bastion/sessions/stream/ws) that wants the freshest
+// events, not SSE's since= catch-up semantics.
+func (s *LogStore) Subscribe() (<-chan SessionEvent, func()) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	id := s.nextSubscriberID
+	s.nextSubscriberID++
+	ch := make(chan SessionEvent, subscriberBufferSize)
+	s.subscribers[id] = ch
+
+	return ch, func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+		delete(s.subscribers, id)
 	}
+}
+
+// publishToSubscribers fans event out to every live Subscribe channel,
+// evicting the oldest queued event for a subscriber that's fallen behind
+// rather than dropping the new one, so a reconnecting-is-not-an-option
+// WebSocket tail always sees the most recent activity.
+func (s *LogStore) publishToSubscribers(event SessionEvent) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// SetLogger wires the structured application logger Add emits events to
+// (see internal/plasmalog). Mirrors Logger.SetMetrics's wire-it-in-after-
+// construction convention.
+func (s *LogStore) SetLogger(logger *slog.Logger) {
+	s.mu.Lock()
+	s.logger = logger
+	s.mu.Unlock()
+}
 
+// Add stores a session event, logs it as a slog.Record with plasmalog's
+// stable attribute keys, and fans it out to any live GET
+// /bastion/sessions/{id}/tail subscriber via Tail.
+func (s *LogStore) Add(event SessionEvent) {
 	s.mu.Lock()
+	logger := s.logger
 	s.events = append(s.events, event)
 	if len(s.events) > s.limit {
 		s.events = s.events[len(s.events)-s.limit:]
 	}
 	s.mu.Unlock()
+
+	level := slog.LevelInfo
+	if event.Event == SessionEventPanic {
+		level = slog.LevelError
+	}
+	logger.Log(context.Background(), level, "bastion session event",
+		slog.String(plasmalog.KeySessionID, event.SessionID),
+		slog.String(plasmalog.KeyPrincipal, event.Principal),
+		slog.String(plasmalog.KeyDomain, event.Target),
+		slog.String(plasmalog.KeyAction, event.Event),
+		slog.String(plasmalog.KeyReason, event.Data),
+	)
+
+	s.broker.Publish(event, event.Timestamp)
+	s.publishToSubscribers(event)
+}
+
+// Tail subscribes to live session events across every session; the caller
+// (GET /bastion/sessions/{id}/tail) filters down to the session it cares
+// about.
+func (s *LogStore) Tail() *logs.Subscription {
+	return s.broker.Subscribe()
+}
+
+// Replay returns buffered session events published strictly after ts, for
+// the ?since= replay window on GET /bastion/sessions/{id}/tail.
+func (s *LogStore) Replay(ts time.Time) []SessionEvent {
+	records := s.broker.Since(ts)
+	out := make([]SessionEvent, 0, len(records))
+	for _, r := range records {
+		if event, ok := r.Payload.(SessionEvent); ok {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+// FindRecordingPath returns the path from the most recent
+// SessionEventRecordingAvailable event logged for sessionID, for GET
+// /bastion/sessions/{id}/recording to serve the file from.
+func (s *LogStore) FindRecordingPath(sessionID string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.events) - 1; i >= 0; i-- {
+		event := s.events[i]
+		if event.SessionID == sessionID && event.Event == SessionEventRecordingAvailable {
+			return event.Data, true
+		}
+	}
+	return "", false
 }
 
 // List returns a paginated list of events.
@@ -75,6 +201,7 @@ type Logger struct {
 	mu            sync.Mutex
 	sessionStarts map[string]time.Time
 	now           func() time.Time
+	metrics       *limiter.Metrics
 }
 
 // NewLogger creates a new Logger.
@@ -97,14 +224,29 @@ func NewLoggerWithClock(store *LogStore, now func() time.Time) *Logger {
 	}
 }
 
+// SetMetrics wires a Prometheus-style metrics registry that
+// LogConnect/LogDisconnect feed plasma_bastion_sessions_active and
+// plasma_bastion_session_duration_seconds into. Mirrors
+// bastion.GrantStore.SetMetrics.
+func (l *Logger) SetMetrics(m *limiter.Metrics) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics = m
+}
+
 // LogConnect logs a new session connection.
 func (l *Logger) LogConnect(sessionID, grantID, principal, target string) {
 	now := l.now()
 
 	l.mu.Lock()
 	l.sessionStarts[sessionID] = now
+	metrics := l.metrics
 	l.mu.Unlock()
 
+	if metrics != nil {
+		metrics.IncBastionSessionActive()
+	}
+
 	l.store.Add(SessionEvent{
 		SessionID: sessionID,
 		GrantID:   grantID,
@@ -126,8 +268,14 @@ func (l *Logger) LogDisconnect(sessionID, grantID, principal, target string) {
 		duration = now.Sub(start)
 		delete(l.sessionStarts, sessionID)
 	}
+	metrics := l.metrics
 	l.mu.Unlock()
 
+	if ok && metrics != nil {
+		metrics.DecBastionSessionActive()
+		metrics.ObserveBastionSessionDuration(duration.Seconds())
+	}
+
 	l.store.Add(SessionEvent{
 		SessionID: sessionID,
 		GrantID:   grantID,
@@ -151,3 +299,32 @@ func (l *Logger) LogCommand(sessionID, grantID, principal, target, command strin
 		Data:      command,
 	})
 }
+
+// LogRecordingAvailable logs that a session's asciicast v2 recording (see
+// WriteAsciicast) has finished writing and is available at path, for GET
+// /bastion/sessions/{id}/recording to serve.
+func (l *Logger) LogRecordingAvailable(sessionID, grantID, principal, target, path string) {
+	l.store.Add(SessionEvent{
+		SessionID: sessionID,
+		GrantID:   grantID,
+		Principal: principal,
+		Target:    target,
+		Event:     SessionEventRecordingAvailable,
+		Timestamp: l.now(),
+		Data:      path,
+	})
+}
+
+// LogPanic logs a panic recovered by safeGo. There's no SessionID yet for
+// a connection safeGo recovers before the SSH handshake completes, so
+// remoteAddr (reused as Target) identifies the connection instead, and
+// principal may be empty.
+func (l *Logger) LogPanic(component, remoteAddr, principal, errMsg string) {
+	l.store.Add(SessionEvent{
+		Principal: principal,
+		Target:    remoteAddr,
+		Event:     SessionEventPanic,
+		Timestamp: l.now(),
+		Data:      fmt.Sprintf("%s: %s", component, errMsg),
+	})
+}
@@ -0,0 +1,61 @@
+package bastion
+
+import (
+	"context"
+	"time"
+)
+
+// GrantRequest describes a request to provision a bastion access grant.
+// The field set is intentionally provisioner-agnostic: each Provisioner
+// implementation interprets Credential however its identity system
+// requires, then maps the verified identity onto the grant's principal.
+type GrantRequest struct {
+	// Principal is the SSH principal being requested. Provisioners that
+	// derive identity from the credential itself (OIDC, X5C) ignore this
+	// and use the verified identity instead.
+	Principal string
+	// Target is the agent or fleet pattern being requested.
+	Target string
+	// Duration is how long the grant should remain valid for, capped by
+	// the provisioner's configured max TTL.
+	Duration time.Duration
+	// Credential carries the provisioner-specific proof of identity: a
+	// signed claim, an OIDC ID token, or a PEM-encoded X.509 chain.
+	Credential string
+	// CreatedBy records who/what requested the grant, for audit purposes.
+	CreatedBy string
+	// Record requests that the issued grant's sessions be teed to the
+	// bastion's configured Recorder. See Grant.Record.
+	Record bool
+	// Shell requests that the issued grant authorize an interactive
+	// "session" channel (handleSession), not just direct-tcpip tunneling.
+	// See Grant.Shell.
+	Shell bool
+}
+
+// Provisioner authorizes a GrantRequest against an identity system and, if
+// approved, issues a Grant. This mirrors smallstep-certificates'
+// provisioner model, so a grant can come from a signed local claim, an
+// OIDC login, or an mTLS client chain instead of a hard-coded token.
+type Provisioner interface {
+	// Name identifies the provisioner, as referenced by
+	// fleet.ProvisionerConfig.Name and the "provisioner" field of a
+	// POST /bastion/grants request.
+	Name() string
+	// AuthorizeGrant verifies req's credential and, if valid, issues and
+	// persists the resulting Grant. Implementations enforce their own max
+	// TTL and allowed-principal/target policy.
+	AuthorizeGrant(ctx context.Context, req GrantRequest) (*Grant, error)
+}
+
+// clampTTL caps requested at max, falling back to max when requested is
+// zero, negative, or exceeds it. A zero max means unbounded.
+func clampTTL(requested, max time.Duration) time.Duration {
+	if max <= 0 {
+		return requested
+	}
+	if requested <= 0 || requested > max {
+		return max
+	}
+	return requested
+}
@@ -0,0 +1,83 @@
+package bastion
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// jwkClaim is the payload an operator signs offline to authorize a grant
+// through a JWKProvisioner.
+type jwkClaim struct {
+	Principal string `json:"principal"`
+	Target    string `json:"target"`
+	ExpiresAt int64  `json:"expires_at"` // unix seconds
+}
+
+// JWKProvisioner authorizes grants signed offline by an operator holding a
+// known ed25519 keypair -- the simplest provisioner, with no external
+// identity system involved. Credential is "claim.signature", each
+// base64url-encoded, where claim is a JSON-encoded jwkClaim.
+type JWKProvisioner struct {
+	name      string
+	publicKey ed25519.PublicKey
+	store     *GrantStore
+	maxTTL    time.Duration
+	now       func() time.Time
+}
+
+// NewJWKProvisioner creates a JWKProvisioner. maxTTL caps how long a claim
+// may request; zero means unbounded.
+func NewJWKProvisioner(name string, publicKey ed25519.PublicKey, store *GrantStore, maxTTL time.Duration) *JWKProvisioner {
+	return &JWKProvisioner{
+		name:      name,
+		publicKey: publicKey,
+		store:     store,
+		maxTTL:    maxTTL,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (p *JWKProvisioner) Name() string { return p.name }
+
+// AuthorizeGrant verifies the claim's signature against the provisioner's
+// configured public key and, if valid and unexpired, issues the grant it
+// describes.
+func (p *JWKProvisioner) AuthorizeGrant(ctx context.Context, req GrantRequest) (*Grant, error) {
+	claimB64, sigB64, ok := strings.Cut(req.Credential, ".")
+	if !ok {
+		return nil, errors.New("jwk: credential must be \"claim.signature\"")
+	}
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(claimB64)
+	if err != nil {
+		return nil, fmt.Errorf("jwk: decode claim: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigB64)
+	if err != nil {
+		return nil, fmt.Errorf("jwk: decode signature: %w", err)
+	}
+
+	if !ed25519.Verify(p.publicKey, claimBytes, sig) {
+		return nil, errors.New("jwk: invalid signature")
+	}
+
+	var claim jwkClaim
+	if err := json.Unmarshal(claimBytes, &claim); err != nil {
+		return nil, fmt.Errorf("jwk: decode claim body: %w", err)
+	}
+
+	now := p.now()
+	expiry := time.Unix(claim.ExpiresAt, 0)
+	if !expiry.After(now) {
+		return nil, errors.New("jwk: claim expired")
+	}
+
+	duration := clampTTL(expiry.Sub(now), p.maxTTL)
+	return p.store.AddWithOptions(claim.Principal, claim.Target, "provisioner:"+p.name, duration, req.Record, req.Shell), nil
+}
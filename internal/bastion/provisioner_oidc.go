@@ -0,0 +1,108 @@
+package bastion
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// oidcClaims are the ID token claims OIDCProvisioner cares about.
+type oidcClaims struct {
+	Issuer string   `json:"iss"`
+	Email  string   `json:"email"`
+	Groups []string `json:"groups"`
+	Expiry int64    `json:"exp"`
+}
+
+// OIDCProvisioner authorizes grants by verifying an ID token against a
+// configured issuer and mapping its email/groups claims onto allowed
+// principals/targets.
+type OIDCProvisioner struct {
+	name              string
+	issuer            string
+	verificationKey   crypto.PublicKey
+	allowedPrincipals map[string]struct{} // email -> allowed; empty means any
+	allowedTargets    []string            // empty means any
+	store             *GrantStore
+	maxTTL            time.Duration
+	now               func() time.Time
+}
+
+// NewOIDCProvisioner creates an OIDCProvisioner. verificationKey is the
+// issuer's signing key, normally resolved from its jwks_uri via
+// FetchJWKSKey and refreshed out-of-band. allowedPrincipals restricts
+// which verified emails may receive a grant; allowedTargets restricts
+// which targets may be requested, matching either the target directly or
+// a claims group via "group:<name>".
+func NewOIDCProvisioner(name, issuer string, verificationKey crypto.PublicKey, allowedPrincipals, allowedTargets []string, store *GrantStore, maxTTL time.Duration) *OIDCProvisioner {
+	principals := make(map[string]struct{}, len(allowedPrincipals))
+	for _, p := range allowedPrincipals {
+		principals[p] = struct{}{}
+	}
+	return &OIDCProvisioner{
+		name:              name,
+		issuer:            issuer,
+		verificationKey:   verificationKey,
+		allowedPrincipals: principals,
+		allowedTargets:    allowedTargets,
+		store:             store,
+		maxTTL:            maxTTL,
+		now:               func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (p *OIDCProvisioner) Name() string { return p.name }
+
+// AuthorizeGrant verifies req.Credential as an ID token, checks its issuer
+// and expiry, and requires its email claim to be an allowed principal (if
+// any are configured) before issuing a grant for req.Target.
+func (p *OIDCProvisioner) AuthorizeGrant(ctx context.Context, req GrantRequest) (*Grant, error) {
+	var claims oidcClaims
+	if err := verifyJWT(req.Credential, p.verificationKey, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: %w", err)
+	}
+
+	if claims.Issuer != p.issuer {
+		return nil, fmt.Errorf("oidc: unexpected issuer %q", claims.Issuer)
+	}
+
+	now := p.now()
+	if claims.Expiry == 0 || !time.Unix(claims.Expiry, 0).After(now) {
+		return nil, errors.New("oidc: id token expired")
+	}
+
+	if claims.Email == "" {
+		return nil, errors.New("oidc: id token missing email claim")
+	}
+	if len(p.allowedPrincipals) > 0 {
+		if _, ok := p.allowedPrincipals[claims.Email]; !ok {
+			return nil, fmt.Errorf("oidc: %s is not an allowed principal", claims.Email)
+		}
+	}
+
+	if err := p.checkTarget(req.Target, claims.Groups); err != nil {
+		return nil, err
+	}
+
+	duration := clampTTL(req.Duration, p.maxTTL)
+	return p.store.AddWithOptions(claims.Email, req.Target, "provisioner:"+p.name, duration, req.Record, req.Shell), nil
+}
+
+func (p *OIDCProvisioner) checkTarget(target string, groups []string) error {
+	if len(p.allowedTargets) == 0 {
+		return nil
+	}
+	for _, t := range p.allowedTargets {
+		if t == "*" || t == target {
+			return nil
+		}
+		for _, g := range groups {
+			if t == "group:"+g {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("oidc: target %q not allowed", target)
+}
@@ -0,0 +1,261 @@
+package bastion
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestJWKProvisionerAuthorizesSignedClaim(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	store := NewGrantStore("")
+	p := NewJWKProvisioner("ops", pub, store, time.Hour)
+
+	claim := jwkClaim{Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(10 * time.Minute).Unix()}
+	credential := signJWKClaim(t, priv, claim)
+
+	grant, err := p.AuthorizeGrant(context.Background(), GrantRequest{Credential: credential})
+	if err != nil {
+		t.Fatalf("authorize grant: %v", err)
+	}
+	if grant.Principal != "alice" || grant.Target != "agent-1" {
+		t.Errorf("unexpected grant: %+v", grant)
+	}
+}
+
+func TestJWKProvisionerRejectsBadSignature(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	store := NewGrantStore("")
+	p := NewJWKProvisioner("ops", pub, store, time.Hour)
+
+	claim := jwkClaim{Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(10 * time.Minute).Unix()}
+	credential := signJWKClaim(t, otherPriv, claim)
+
+	if _, err := p.AuthorizeGrant(context.Background(), GrantRequest{Credential: credential}); err == nil {
+		t.Fatal("expected error for claim signed by the wrong key")
+	}
+}
+
+func TestJWKProvisionerRejectsExpiredClaim(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	store := NewGrantStore("")
+	p := NewJWKProvisioner("ops", pub, store, time.Hour)
+
+	claim := jwkClaim{Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+	credential := signJWKClaim(t, priv, claim)
+
+	if _, err := p.AuthorizeGrant(context.Background(), GrantRequest{Credential: credential}); err == nil {
+		t.Fatal("expected error for expired claim")
+	}
+}
+
+func signJWKClaim(t *testing.T, priv ed25519.PrivateKey, claim jwkClaim) string {
+	t.Helper()
+	claimBytes, err := json.Marshal(claim)
+	if err != nil {
+		t.Fatalf("marshal claim: %v", err)
+	}
+	sig := ed25519.Sign(priv, claimBytes)
+	return base64.RawURLEncoding.EncodeToString(claimBytes) + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCProvisionerAuthorizesValidToken(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	store := NewGrantStore("")
+	p := NewOIDCProvisioner("okta", "https://issuer.example.com", &priv.PublicKey,
+		[]string{"alice@example.com"}, []string{"agent-1"}, store, time.Hour)
+
+	token := signRS256(t, priv, oidcClaims{
+		Issuer: "https://issuer.example.com",
+		Email:  "alice@example.com",
+		Expiry: time.Now().Add(10 * time.Minute).Unix(),
+	})
+
+	grant, err := p.AuthorizeGrant(context.Background(), GrantRequest{Target: "agent-1", Credential: token})
+	if err != nil {
+		t.Fatalf("authorize grant: %v", err)
+	}
+	if grant.Principal != "alice@example.com" {
+		t.Errorf("expected principal alice@example.com, got %q", grant.Principal)
+	}
+}
+
+func TestOIDCProvisionerRejectsDisallowedPrincipal(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	store := NewGrantStore("")
+	p := NewOIDCProvisioner("okta", "https://issuer.example.com", &priv.PublicKey,
+		[]string{"alice@example.com"}, nil, store, time.Hour)
+
+	token := signRS256(t, priv, oidcClaims{
+		Issuer: "https://issuer.example.com",
+		Email:  "mallory@example.com",
+		Expiry: time.Now().Add(10 * time.Minute).Unix(),
+	})
+
+	if _, err := p.AuthorizeGrant(context.Background(), GrantRequest{Target: "agent-1", Credential: token}); err == nil {
+		t.Fatal("expected error for principal not in the allow-list")
+	}
+}
+
+func TestOIDCProvisionerRejectsWrongIssuer(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	store := NewGrantStore("")
+	p := NewOIDCProvisioner("okta", "https://issuer.example.com", &priv.PublicKey, nil, nil, store, time.Hour)
+
+	token := signRS256(t, priv, oidcClaims{
+		Issuer: "https://attacker.example.com",
+		Email:  "alice@example.com",
+		Expiry: time.Now().Add(10 * time.Minute).Unix(),
+	})
+
+	if _, err := p.AuthorizeGrant(context.Background(), GrantRequest{Target: "agent-1", Credential: token}); err == nil {
+		t.Fatal("expected error for mismatched issuer")
+	}
+}
+
+func signRS256(t *testing.T, priv *rsa.PrivateKey, claims oidcClaims) string {
+	t.Helper()
+	header, err := json.Marshal(jwtHeader{Alg: "RS256"})
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestX5CProvisionerAuthorizesChainRootedAtTrustBundle(t *testing.T) {
+	rootCert, rootKey := generateTestCA(t, "Test Root CA")
+	leafPEM := generateTestLeaf(t, rootCert, rootKey, "alice")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(rootCert)
+
+	store := NewGrantStore("")
+	p := NewX5CProvisioner("corp-ca", roots, []string{"agent-1"}, store, time.Hour)
+
+	grant, err := p.AuthorizeGrant(context.Background(), GrantRequest{Target: "agent-1", Credential: leafPEM})
+	if err != nil {
+		t.Fatalf("authorize grant: %v", err)
+	}
+	if grant.Principal != "alice" {
+		t.Errorf("expected principal alice, got %q", grant.Principal)
+	}
+}
+
+func TestX5CProvisionerRejectsUntrustedChain(t *testing.T) {
+	rootCert, rootKey := generateTestCA(t, "Test Root CA")
+	leafPEM := generateTestLeaf(t, rootCert, rootKey, "alice")
+
+	otherRoot, _ := generateTestCA(t, "Other Root CA")
+	roots := x509.NewCertPool()
+	roots.AddCert(otherRoot)
+
+	store := NewGrantStore("")
+	p := NewX5CProvisioner("corp-ca", roots, nil, store, time.Hour)
+
+	if _, err := p.AuthorizeGrant(context.Background(), GrantRequest{Target: "agent-1", Credential: leafPEM}); err == nil {
+		t.Fatal("expected error for chain not rooted at the trust bundle")
+	}
+}
+
+func generateTestCA(t *testing.T, commonName string) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA certificate: %v", err)
+	}
+	return cert, key
+}
+
+func generateTestLeaf(t *testing.T, ca *x509.Certificate, caKey *rsa.PrivateKey, commonName string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate leaf key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create leaf certificate: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
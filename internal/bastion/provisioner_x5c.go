@@ -0,0 +1,109 @@
+package bastion
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// X5CProvisioner authorizes grants by verifying a client-presented X.509
+// certificate chain against a configured trust bundle, then mapping the
+// leaf certificate's Subject Common Name onto the grant's principal.
+type X5CProvisioner struct {
+	name           string
+	roots          *x509.CertPool
+	allowedTargets []string // empty means any
+	store          *GrantStore
+	maxTTL         time.Duration
+}
+
+// NewX5CProvisioner creates an X5CProvisioner. roots is the CA bundle the
+// presented chain must verify against.
+func NewX5CProvisioner(name string, roots *x509.CertPool, allowedTargets []string, store *GrantStore, maxTTL time.Duration) *X5CProvisioner {
+	return &X5CProvisioner{
+		name:           name,
+		roots:          roots,
+		allowedTargets: allowedTargets,
+		store:          store,
+		maxTTL:         maxTTL,
+	}
+}
+
+func (p *X5CProvisioner) Name() string { return p.name }
+
+// AuthorizeGrant verifies req.Credential (a PEM-encoded leaf certificate
+// followed by any intermediates) against the provisioner's trust bundle
+// and issues a grant for the leaf's subject CN.
+func (p *X5CProvisioner) AuthorizeGrant(ctx context.Context, req GrantRequest) (*Grant, error) {
+	leaf, intermediates, err := parseCertChain(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("x5c: %w", err)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         p.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("x5c: verify chain: %w", err)
+	}
+
+	if leaf.Subject.CommonName == "" {
+		return nil, errors.New("x5c: certificate has no subject CN to use as principal")
+	}
+
+	if err := p.checkTarget(req.Target); err != nil {
+		return nil, err
+	}
+
+	duration := clampTTL(req.Duration, p.maxTTL)
+	return p.store.AddWithOptions(leaf.Subject.CommonName, req.Target, "provisioner:"+p.name, duration, req.Record, req.Shell), nil
+}
+
+func (p *X5CProvisioner) checkTarget(target string) error {
+	if len(p.allowedTargets) == 0 {
+		return nil
+	}
+	for _, t := range p.allowedTargets {
+		if t == "*" || t == target {
+			return nil
+		}
+	}
+	return fmt.Errorf("x5c: target %q not allowed", target)
+}
+
+// parseCertChain parses a PEM-encoded certificate chain, leaf first,
+// followed by any intermediates.
+func parseCertChain(pemChain string) (*x509.Certificate, *x509.CertPool, error) {
+	var leaf *x509.Certificate
+	intermediates := x509.NewCertPool()
+
+	rest := []byte(pemChain)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse certificate: %w", err)
+		}
+		if leaf == nil {
+			leaf = cert
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	if leaf == nil {
+		return nil, nil, errors.New("no certificate found in chain")
+	}
+	return leaf, intermediates, nil
+}
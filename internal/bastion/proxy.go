@@ -56,21 +56,276 @@ func (s *Server) handleDirectTCPIP(sshConn *ssh.ServerConn, channel ssh.NewChann
 	s.logger.LogConnect(sessionID, grantID, principal, address)
 	defer s.logger.LogDisconnect(sessionID, grantID, principal, address)
 
-	proxyBidirectional(conn, targetConn)
+	var recording SessionRecording
+	if s.recorder != nil && grant.Record {
+		rec, err := s.recorder.NewSession(sessionID, grantID, principal, address)
+		if err != nil {
+			log.Printf("bastion: start recording for session %s failed: %v", sessionID, err)
+		} else {
+			recording = rec
+			defer func() {
+				if err := recording.Close(); err != nil {
+					log.Printf("bastion: finalize recording for session %s failed: %v", sessionID, err)
+					return
+				}
+				if provider, ok := recording.(RecordingPathProvider); ok {
+					if path := provider.RecordingPath(); path != "" {
+						s.logger.LogRecordingAvailable(sessionID, grantID, principal, address, path)
+					}
+				}
+			}()
+		}
+	}
+
+	proxyBidirectional(conn, targetConn, recording)
+}
+
+// maxCommandLineLength bounds how many bytes of un-newline-terminated
+// stdin commandLineWriter will buffer before flushing anyway, so a binary
+// paste or a client that never sends a newline can't grow the buffer (or
+// the resulting audit record) without limit.
+const maxCommandLineLength = 4096
+
+// commandLineWriter tees an interactive session's stdin into
+// newline-delimited SessionEventCommand records, auditing shell keystrokes
+// the same way handleSession already audits a single exec payload.
+type commandLineWriter struct {
+	logger                                *Logger
+	sessionID, grantID, principal, target string
+	buf                                    []byte
+}
+
+func newCommandLineWriter(logger *Logger, sessionID, grantID, principal, target string) *commandLineWriter {
+	return &commandLineWriter{logger: logger, sessionID: sessionID, grantID: grantID, principal: principal, target: target}
+}
+
+func (w *commandLineWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if b == '\n' {
+			w.flush()
+			continue
+		}
+		w.buf = append(w.buf, b)
+		if len(w.buf) >= maxCommandLineLength {
+			w.flush()
+		}
+	}
+	return len(p), nil
+}
+
+// flush emits the buffered line, if any, as a command event. Called on
+// every newline and once more after the session ends, so a final line with
+// no trailing newline still gets audited.
+func (w *commandLineWriter) flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+	w.logger.LogCommand(w.sessionID, w.grantID, w.principal, w.target, string(w.buf))
+	w.buf = w.buf[:0]
+}
+
+// ptyRequestPayload mirrors RFC 4254 4.5.1's pty-req payload layout, which
+// golang.org/x/crypto/ssh only marshals client-side -- handleSession needs
+// to unmarshal one arriving from the client instead.
+type ptyRequestPayload struct {
+	Term                                   string
+	Width, Height, PixelWidth, PixelHeight uint32
+	Modes                                   string
+}
+
+func parsePtyRequest(payload []byte) (term string, width, height uint32, ok bool) {
+	var p ptyRequestPayload
+	if err := ssh.Unmarshal(payload, &p); err != nil {
+		return "", 0, 0, false
+	}
+	return p.Term, p.Width, p.Height, true
+}
+
+// windowChangePayload mirrors RFC 4254 4.7's window-change payload.
+type windowChangePayload struct {
+	Width, Height, PixelWidth, PixelHeight uint32
+}
+
+func parseWindowChange(payload []byte) (width, height uint32, ok bool) {
+	var p windowChangePayload
+	if err := ssh.Unmarshal(payload, &p); err != nil {
+		return 0, 0, false
+	}
+	return p.Width, p.Height, true
+}
+
+// envRequestPayload mirrors RFC 4254 6.4's env payload.
+type envRequestPayload struct {
+	Name, Value string
+}
+
+func parseEnvRequest(payload []byte) (name, value string, ok bool) {
+	var p envRequestPayload
+	if err := ssh.Unmarshal(payload, &p); err != nil {
+		return "", "", false
+	}
+	return p.Name, p.Value, true
+}
+
+// execRequestPayload mirrors RFC 4254 6.5's exec payload.
+type execRequestPayload struct {
+	Command string
+}
+
+// validateTargetHostKey is handleSession's ssh.ClientConfig.HostKeyCallback.
+// It requires the target to present a host certificate signed by this
+// bastion's own CA (see CertificateAuthority.ValidateHostCertificate),
+// rather than trusting the target's key on first use the way a bare
+// ssh.Dial would.
+func (s *Server) validateTargetHostKey(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return fmt.Errorf("target %s did not present a host certificate", hostname)
+	}
+	return s.ca.ValidateHostCertificate(cert, hostname)
+}
+
+// handleSession handles a "session" channel, brokering an interactive SSH
+// session onto the grant's target rather than handleDirectTCPIP's raw TCP
+// tunnel. The client never dials the target directly: the bastion itself
+// opens a second, outbound SSH connection (authenticating with its own
+// host key, the same identity handed out by loadOrCreateHostKey) and
+// relays pty-req/window-change/env/shell/exec requests onto it, so every
+// keystroke and command passes through s.logger for auditing.
+func (s *Server) handleSession(sshConn *ssh.ServerConn, channel ssh.NewChannel) {
+	principal := sshConn.User()
+
+	// Check grant BEFORE accepting channel, same as handleDirectTCPIP. A
+	// "session" channel carries no destination in its payload, so the
+	// grant's own Target is what gets dialed.
+	grant := s.grants.ValidateShellAccess(principal)
+	if grant == nil {
+		channel.Reject(ssh.Prohibited, "no shell grant for principal")
+		return
+	}
+
+	conn, reqs, err := channel.Accept()
+	if err != nil {
+		log.Printf("bastion channel accept failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	client, err := ssh.Dial("tcp", grant.Target, &ssh.ClientConfig{
+		User:            principal,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(s.hostSigner)},
+		HostKeyCallback: s.validateTargetHostKey,
+		Timeout:         10 * time.Second,
+	})
+	if err != nil {
+		log.Printf("bastion dial %s failed: %v", grant.Target, err)
+		return
+	}
+	defer client.Close()
+
+	targetSession, err := client.NewSession()
+	if err != nil {
+		log.Printf("bastion open session on %s failed: %v", grant.Target, err)
+		return
+	}
+	defer targetSession.Close()
+
+	stdin, err := targetSession.StdinPipe()
+	if err != nil {
+		log.Printf("bastion stdin pipe for %s failed: %v", grant.Target, err)
+		return
+	}
+	stdout, err := targetSession.StdoutPipe()
+	if err != nil {
+		log.Printf("bastion stdout pipe for %s failed: %v", grant.Target, err)
+		return
+	}
+	stderr, err := targetSession.StderrPipe()
+	if err != nil {
+		log.Printf("bastion stderr pipe for %s failed: %v", grant.Target, err)
+		return
+	}
+
+	sessionID := fmt.Sprintf("%x-%d", sshConn.SessionID(), time.Now().UnixNano())
+	grantID := grant.ID
+
+	s.logger.LogConnect(sessionID, grantID, principal, grant.Target)
+	defer s.logger.LogDisconnect(sessionID, grantID, principal, grant.Target)
+
+	go io.Copy(conn, stdout)
+	go io.Copy(conn.Stderr(), stderr)
+
+	commands := newCommandLineWriter(s.logger, sessionID, grantID, principal, grant.Target)
+	go io.Copy(io.MultiWriter(stdin, commands), conn)
+
+	for req := range reqs {
+		switch req.Type {
+		case "pty-req":
+			term, width, height, ok := parsePtyRequest(req.Payload)
+			if ok {
+				ok = targetSession.RequestPty(term, int(height), int(width), ssh.TerminalModes{}) == nil
+			}
+			if req.WantReply {
+				req.Reply(ok, nil)
+			}
+		case "window-change":
+			width, height, ok := parseWindowChange(req.Payload)
+			if ok {
+				ok = targetSession.WindowChange(int(height), int(width)) == nil
+			}
+			if req.WantReply {
+				req.Reply(ok, nil)
+			}
+		case "env":
+			name, value, ok := parseEnvRequest(req.Payload)
+			if ok {
+				ok = targetSession.Setenv(name, value) == nil
+			}
+			if req.WantReply {
+				req.Reply(ok, nil)
+			}
+		case "shell":
+			err := targetSession.Shell()
+			if req.WantReply {
+				req.Reply(err == nil, nil)
+			}
+		case "exec":
+			var payload execRequestPayload
+			ok := ssh.Unmarshal(req.Payload, &payload) == nil
+			if ok {
+				s.logger.LogCommand(sessionID, grantID, principal, grant.Target, payload.Command)
+				ok = targetSession.Start(payload.Command) == nil
+			}
+			if req.WantReply {
+				req.Reply(ok, nil)
+			}
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+
+	commands.flush()
+	targetSession.Wait()
 }
 
-func proxyBidirectional(left io.ReadWriteCloser, right io.ReadWriteCloser) {
+// proxyBidirectional pipes left and right's traffic onto each other,
+// tee-ing each half through recording (a no-op if recording is nil) so a
+// session's full byte stream, client and server sides, ends up recorded
+// with the direction it traveled.
+func proxyBidirectional(left io.ReadWriteCloser, right io.ReadWriteCloser, recording SessionRecording) {
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		_, _ = io.Copy(left, right)
+		_, _ = io.Copy(left, io.TeeReader(right, recordingWriter(recording, FrameServer)))
 	}()
 
 	go func() {
 		defer wg.Done()
-		_, _ = io.Copy(right, left)
+		_, _ = io.Copy(right, io.TeeReader(left, recordingWriter(recording, FrameClient)))
 	}()
 
 	wg.Wait()
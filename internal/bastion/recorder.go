@@ -0,0 +1,265 @@
+package bastion
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FrameClient marks a recorded frame of bytes the client sent into the
+// tunnel; FrameServer marks bytes the target sent back.
+const (
+	FrameClient byte = 0
+	FrameServer byte = 1
+)
+
+// frameHeaderSize is the size in bytes of a frame's fixed-size header:
+// offset_ms uint32, dir uint8, len uint32, all big-endian.
+const frameHeaderSize = 4 + 1 + 4
+
+// RecordingMeta is the content of a session recording's meta.json: enough
+// to attribute and verify a .stream file without decoding it.
+type RecordingMeta struct {
+	SessionID string    `json:"session_id"`
+	GrantID   string    `json:"grant_id"`
+	Principal string    `json:"principal"`
+	Target    string    `json:"target"`
+	StartedAt time.Time `json:"started_at"`
+	StoppedAt time.Time `json:"stopped_at"`
+	SHA256    string    `json:"sha256"`
+}
+
+// Recorder captures the byte streams tunneled through bastion sessions to
+// a durable sink. FileRecorder is the default, on-disk implementation;
+// an S3 or other remote sink can satisfy the same interface and be
+// injected into Server in its place.
+type Recorder interface {
+	// NewSession opens a recording for sessionID, tagged with the
+	// session's grant, principal, and target for the recording's
+	// metadata. Callers must Close the returned SessionRecording when
+	// the session ends.
+	NewSession(sessionID, grantID, principal, target string) (SessionRecording, error)
+}
+
+// SessionRecording receives frames for one session and finalizes the
+// recording when the session ends.
+type SessionRecording interface {
+	// WriteFrame appends one timestamped frame. dir is FrameClient or
+	// FrameServer depending on which leg of the tunnel p came from.
+	WriteFrame(dir byte, p []byte) error
+	// Close finalizes the recording (e.g. writing meta.json) and
+	// releases any resources.
+	Close() error
+}
+
+// RecordingPathProvider is implemented by SessionRecording implementations
+// that can name a single file their finished recording lives at, for
+// Server to log as a SessionEventRecordingAvailable event once Close
+// returns. Not every Recorder backend has one addressable path (a remote
+// sink might stream straight to object storage instead), so callers
+// type-assert for this rather than requiring it of every SessionRecording.
+type RecordingPathProvider interface {
+	RecordingPath() string
+}
+
+// FileRecorder is the default Recorder: it writes each session's frames to
+// <dir>/<session-id>.stream, and a sibling <session-id>.meta.json on
+// Close.
+type FileRecorder struct {
+	dir string
+	now func() time.Time
+}
+
+// NewFileRecorder creates a FileRecorder rooted at dir. dir is created
+// lazily, by the first NewSession call.
+func NewFileRecorder(dir string) *FileRecorder {
+	return NewFileRecorderWithClock(dir, func() time.Time { return time.Now().UTC() })
+}
+
+// NewFileRecorderWithClock creates a FileRecorder with a custom clock (for
+// testing).
+func NewFileRecorderWithClock(dir string, now func() time.Time) *FileRecorder {
+	if now == nil {
+		panic("bastion: nil clock")
+	}
+	return &FileRecorder{dir: dir, now: now}
+}
+
+func streamPath(dir, sessionID string) string {
+	return filepath.Join(dir, sessionID+".stream")
+}
+
+func metaPath(dir, sessionID string) string {
+	return filepath.Join(dir, sessionID+".meta.json")
+}
+
+// NewSession implements Recorder.
+func (f *FileRecorder) NewSession(sessionID, grantID, principal, target string) (SessionRecording, error) {
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return nil, fmt.Errorf("bastion: create recording dir: %w", err)
+	}
+	file, err := os.Create(streamPath(f.dir, sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("bastion: create stream file: %w", err)
+	}
+	start := f.now()
+	return &fileSessionRecording{
+		file: file,
+		hash: sha256.New(),
+		meta: RecordingMeta{
+			SessionID: sessionID,
+			GrantID:   grantID,
+			Principal: principal,
+			Target:    target,
+			StartedAt: start,
+		},
+		dir:      f.dir,
+		metaPath: metaPath(f.dir, sessionID),
+		now:      f.now,
+		start:    start,
+	}, nil
+}
+
+type fileSessionRecording struct {
+	mu            sync.Mutex
+	file          *os.File
+	hash          hash.Hash
+	meta          RecordingMeta
+	dir           string
+	metaPath      string
+	now           func() time.Time
+	start         time.Time
+	recordingPath string
+}
+
+// WriteFrame implements SessionRecording.
+func (s *fileSessionRecording) WriteFrame(dir byte, p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	header := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], uint32(s.now().Sub(s.start).Milliseconds()))
+	header[4] = dir
+	binary.BigEndian.PutUint32(header[5:9], uint32(len(p)))
+
+	for _, b := range [][]byte{header, p} {
+		if _, err := s.file.Write(b); err != nil {
+			return fmt.Errorf("bastion: write frame: %w", err)
+		}
+		s.hash.Write(b)
+	}
+	return nil
+}
+
+// Close implements SessionRecording: it finalizes meta.json with the
+// session's stop time and the stream file's sha256, then exports the
+// session's asciicast v2 recording (see exportAsciicast) for
+// RecordingPath to report.
+func (s *fileSessionRecording) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.meta.StoppedAt = s.now()
+	s.meta.SHA256 = hex.EncodeToString(s.hash.Sum(nil))
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("bastion: close stream file: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s.meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("bastion: marshal meta: %w", err)
+	}
+	if err := os.WriteFile(s.metaPath, data, 0600); err != nil {
+		return fmt.Errorf("bastion: write meta: %w", err)
+	}
+
+	path, err := exportAsciicast(s.dir, s.meta.SessionID, s.meta)
+	if err != nil {
+		return err
+	}
+	s.recordingPath = path
+	return nil
+}
+
+// RecordingPath implements RecordingPathProvider, returning the
+// gzip-compressed asciicast v2 file Close exported this session's
+// recording to, or "" before Close has run.
+func (s *fileSessionRecording) RecordingPath() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recordingPath
+}
+
+// OpenStream opens a session's raw .stream file for reading, e.g. with
+// ReadFrame, or to serve GET /bastion/sessions/{id}/stream verbatim.
+func OpenStream(dir, sessionID string) (*os.File, error) {
+	return os.Open(streamPath(dir, sessionID))
+}
+
+// ReadMeta reads and parses a session's meta.json from dir.
+func ReadMeta(dir, sessionID string) (RecordingMeta, error) {
+	data, err := os.ReadFile(metaPath(dir, sessionID))
+	if err != nil {
+		return RecordingMeta{}, err
+	}
+	var meta RecordingMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return RecordingMeta{}, fmt.Errorf("bastion: parse meta: %w", err)
+	}
+	return meta, nil
+}
+
+// ReadFrame reads one frame from r, returning io.EOF once the stream is
+// exhausted.
+func ReadFrame(r io.Reader) (offsetMS uint32, dir byte, payload []byte, err error) {
+	header := make([]byte, frameHeaderSize)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+	offsetMS = binary.BigEndian.Uint32(header[0:4])
+	dir = header[4]
+	length := binary.BigEndian.Uint32(header[5:9])
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, fmt.Errorf("bastion: read frame payload: %w", err)
+	}
+	return offsetMS, dir, payload, nil
+}
+
+// recordingWriter adapts a SessionRecording into an io.Writer tagged with
+// dir, so it can be used as the sink of an io.TeeReader around one leg of
+// handleDirectTCPIP's proxy loop. A nil recording yields a writer that
+// discards everything, so callers don't need to branch on whether
+// recording is enabled.
+func recordingWriter(recording SessionRecording, dir byte) io.Writer {
+	if recording == nil {
+		return io.Discard
+	}
+	return &frameWriter{recording: recording, dir: dir}
+}
+
+type frameWriter struct {
+	recording SessionRecording
+	dir       byte
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	if err := w.recording.WriteFrame(w.dir, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,104 @@
+package bastion
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileRecorder_RoundTrip(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	current := start
+	recorder := NewFileRecorderWithClock(t.TempDir(), func() time.Time { return current })
+
+	session, err := recorder.NewSession("session-1", "grant-1", "alice", "agent-1:22")
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+
+	if err := session.WriteFrame(FrameClient, []byte("ls -la\n")); err != nil {
+		t.Fatalf("WriteFrame (client) failed: %v", err)
+	}
+
+	current = start.Add(250 * time.Millisecond)
+	if err := session.WriteFrame(FrameServer, []byte("total 0\n")); err != nil {
+		t.Fatalf("WriteFrame (server) failed: %v", err)
+	}
+
+	current = start.Add(1 * time.Second)
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	meta, err := ReadMeta(recorder.dir, "session-1")
+	if err != nil {
+		t.Fatalf("ReadMeta failed: %v", err)
+	}
+	if meta.Principal != "alice" || meta.GrantID != "grant-1" || meta.Target != "agent-1:22" {
+		t.Errorf("unexpected meta: %+v", meta)
+	}
+	if !meta.StartedAt.Equal(start) {
+		t.Errorf("expected started_at %v, got %v", start, meta.StartedAt)
+	}
+	if !meta.StoppedAt.Equal(start.Add(time.Second)) {
+		t.Errorf("expected stopped_at %v, got %v", start.Add(time.Second), meta.StoppedAt)
+	}
+	if meta.SHA256 == "" {
+		t.Error("expected non-empty sha256")
+	}
+
+	file, err := OpenStream(recorder.dir, "session-1")
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	defer file.Close()
+
+	offsetMS, dir, payload, err := ReadFrame(file)
+	if err != nil {
+		t.Fatalf("ReadFrame (1) failed: %v", err)
+	}
+	if offsetMS != 0 || dir != FrameClient || string(payload) != "ls -la\n" {
+		t.Errorf("unexpected first frame: offset=%d dir=%d payload=%q", offsetMS, dir, payload)
+	}
+
+	offsetMS, dir, payload, err = ReadFrame(file)
+	if err != nil {
+		t.Fatalf("ReadFrame (2) failed: %v", err)
+	}
+	if offsetMS != 250 || dir != FrameServer || string(payload) != "total 0\n" {
+		t.Errorf("unexpected second frame: offset=%d dir=%d payload=%q", offsetMS, dir, payload)
+	}
+
+	if _, _, _, err := ReadFrame(file); err != io.EOF {
+		t.Errorf("expected io.EOF at end of stream, got %v", err)
+	}
+}
+
+func TestFileRecorder_NewSessionCreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "recordings")
+	recorder := NewFileRecorder(dir)
+
+	session, err := recorder.NewSession("session-1", "grant-1", "alice", "agent-1:22")
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if _, err := OpenStream(dir, "session-1"); err != nil {
+		t.Errorf("expected stream file to exist: %v", err)
+	}
+}
+
+func TestRecordingWriter_NilRecordingDiscards(t *testing.T) {
+	w := recordingWriter(nil, FrameClient)
+	n, err := w.Write([]byte("hello"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 5 {
+		t.Errorf("expected 5 bytes written, got %d", n)
+	}
+}
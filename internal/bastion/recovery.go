@@ -0,0 +1,75 @@
+package bastion
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+)
+
+// panicCounts tracks shield_panics_total{component} for safeGo recoveries.
+// Hand-rolled rather than pulling in the prometheus client library, the
+// same way internal/limiter.Metrics hand-rolls its own counters: bastion
+// has no /metrics endpoint of its own to register against, unlike
+// internal/api's execMetrics.
+var panicCounts = struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+// recoveryMetrics, when set via SetRecoveryMetrics, also receives every
+// safeGo recovery as plasma_shield_panics_total{component="bastion.<name>"},
+// alongside the package-local panicCounts above. This is the "SSH channel"
+// counterpart to middleware.Recover's HTTP-side reporting into the same
+// metric.
+var recoveryMetrics *limiter.Metrics
+
+// SetRecoveryMetrics wires m to receive safeGo's panic recoveries. Called
+// once from NewServer with Config.Metrics; nil (the default) disables it.
+func SetRecoveryMetrics(m *limiter.Metrics) {
+	recoveryMetrics = m
+}
+
+func incPanicsTotal(component string) {
+	panicCounts.mu.Lock()
+	panicCounts.counts[component]++
+	panicCounts.mu.Unlock()
+
+	if recoveryMetrics != nil {
+		recoveryMetrics.IncPanic("bastion." + component)
+	}
+}
+
+// PanicsTotal returns the current shield_panics_total count for component,
+// for tests asserting safeGo recovered a panic.
+func PanicsTotal(component string) int64 {
+	panicCounts.mu.Lock()
+	defer panicCounts.mu.Unlock()
+	return panicCounts.counts[component]
+}
+
+// safeGo runs fn in a new goroutine, recovering any panic so a single
+// malformed SSH channel or request can't tear down the whole bastion
+// process. component names the call site (e.g. "handleDirectTCPIP") for
+// the log line and shield_panics_total; remoteAddr and principal identify
+// the connection, though principal may be empty before the SSH handshake
+// completes (see Server.serve). Modeled on the recovery interceptor in
+// github.com/grpc-ecosystem/go-grpc-middleware, applied to a goroutine
+// instead of a single request/response.
+func safeGo(logger *Logger, component, remoteAddr, principal string, fn func()) {
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				stack := debug.Stack()
+				log.Printf("bastion: panic recovered in %s (remote=%s principal=%s): %v\n%s", component, remoteAddr, principal, rec, stack)
+				incPanicsTotal(component)
+				if logger != nil {
+					logger.LogPanic(component, remoteAddr, principal, fmt.Sprintf("%v", rec))
+				}
+			}
+		}()
+		fn()
+	}()
+}
@@ -0,0 +1,76 @@
+package bastion
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSafeGoRecoversPanicAndLogs(t *testing.T) {
+	store := NewLogStore(10)
+	logger := NewLogger(store)
+
+	done := make(chan struct{})
+	safeGo(logger, "testComponent", "127.0.0.1:1234", "alice", func() {
+		defer close(done)
+		panic("boom")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("safeGo goroutine never ran")
+	}
+
+	// Give the recover's deferred logging a moment to land, since it runs
+	// after the fn's own deferred close(done).
+	deadline := time.Now().Add(2 * time.Second)
+	var events []SessionEvent
+	for time.Now().Before(deadline) {
+		events, _ = store.List(0, 10)
+		if len(events) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected 1 logged event, got %d", len(events))
+	}
+	if events[0].Event != SessionEventPanic {
+		t.Fatalf("expected panic event, got %q", events[0].Event)
+	}
+	if events[0].Principal != "alice" {
+		t.Fatalf("expected principal alice, got %q", events[0].Principal)
+	}
+	if events[0].Target != "127.0.0.1:1234" {
+		t.Fatalf("expected target 127.0.0.1:1234, got %q", events[0].Target)
+	}
+
+	if got := PanicsTotal("testComponent"); got != 1 {
+		t.Fatalf("expected shield_panics_total{component=\"testComponent\"} = 1, got %d", got)
+	}
+}
+
+func TestSafeGoNoPanicDoesNotLog(t *testing.T) {
+	store := NewLogStore(10)
+	logger := NewLogger(store)
+
+	done := make(chan struct{})
+	safeGo(logger, "quietComponent", "127.0.0.1:1234", "bob", func() {
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("safeGo goroutine never ran")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if _, total := store.List(0, 10); total != 0 {
+		t.Fatalf("expected no logged events, got %d", total)
+	}
+	if got := PanicsTotal("quietComponent"); got != 0 {
+		t.Fatalf("expected shield_panics_total{component=\"quietComponent\"} = 0, got %d", got)
+	}
+}
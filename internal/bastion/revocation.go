@@ -0,0 +1,181 @@
+package bastion
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RevokedEntry describes a single revoked certificate serial or KeyId
+// (grant ID).
+type RevokedEntry struct {
+	Serial    uint64    `json:"serial,omitempty"`
+	KeyID     string    `json:"key_id,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+// RevocationStore tracks revoked SSH certificates by serial number and by
+// KeyId (grant ID), so a CA can reject a still-valid certificate without
+// waiting for it to expire.
+type RevocationStore interface {
+	// Revoke marks a certificate serial as revoked.
+	Revoke(serial uint64, reason string) error
+	// RevokeKeyID marks every certificate issued with the given KeyId
+	// (grant ID) as revoked.
+	RevokeKeyID(keyID, reason string) error
+	// IsRevoked reports whether the given serial or KeyId has been revoked.
+	IsRevoked(serial uint64, keyID string) bool
+	// Entries returns every revoked serial and KeyId, for KRL generation.
+	Entries() []RevokedEntry
+}
+
+// MemoryRevocationStore is an in-memory RevocationStore. It is the backing
+// store for FileRevocationStore and is useful on its own in tests.
+type MemoryRevocationStore struct {
+	mu      sync.RWMutex
+	serials map[uint64]RevokedEntry
+	keyIDs  map[string]RevokedEntry
+	now     func() time.Time
+}
+
+// NewMemoryRevocationStore creates an in-memory RevocationStore.
+func NewMemoryRevocationStore(now func() time.Time) *MemoryRevocationStore {
+	if now == nil {
+		now = func() time.Time { return time.Now().UTC() }
+	}
+	return &MemoryRevocationStore{
+		serials: make(map[uint64]RevokedEntry),
+		keyIDs:  make(map[string]RevokedEntry),
+		now:     now,
+	}
+}
+
+func (s *MemoryRevocationStore) Revoke(serial uint64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serials[serial] = RevokedEntry{Serial: serial, Reason: reason, RevokedAt: s.now()}
+	return nil
+}
+
+func (s *MemoryRevocationStore) RevokeKeyID(keyID, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyIDs[keyID] = RevokedEntry{KeyID: keyID, Reason: reason, RevokedAt: s.now()}
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(serial uint64, keyID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.serials[serial]; ok {
+		return true
+	}
+	if keyID != "" {
+		if _, ok := s.keyIDs[keyID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryRevocationStore) Entries() []RevokedEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]RevokedEntry, 0, len(s.serials)+len(s.keyIDs))
+	for _, e := range s.serials {
+		entries = append(entries, e)
+	}
+	for _, e := range s.keyIDs {
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// FileRevocationStore is a MemoryRevocationStore that persists its entries
+// to a JSON file on every change, following the same load-on-start,
+// write-then-rename pattern as GrantStore.
+type FileRevocationStore struct {
+	*MemoryRevocationStore
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewRevocationStore creates a RevocationStore. If filePath is empty,
+// entries are only kept in memory; otherwise they're persisted to
+// filePath and reloaded from it on startup.
+func NewRevocationStore(filePath string) RevocationStore {
+	return NewRevocationStoreWithClock(filePath, nil)
+}
+
+// NewRevocationStoreWithClock creates a RevocationStore with a custom
+// clock (for testing).
+func NewRevocationStoreWithClock(filePath string, now func() time.Time) RevocationStore {
+	mem := NewMemoryRevocationStore(now)
+	if filePath == "" {
+		return mem
+	}
+
+	s := &FileRevocationStore{MemoryRevocationStore: mem, filePath: filePath}
+	s.load()
+	return s
+}
+
+func (s *FileRevocationStore) Revoke(serial uint64, reason string) error {
+	if err := s.MemoryRevocationStore.Revoke(serial, reason); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func (s *FileRevocationStore) RevokeKeyID(keyID, reason string) error {
+	if err := s.MemoryRevocationStore.RevokeKeyID(keyID, reason); err != nil {
+		return err
+	}
+	return s.persist()
+}
+
+func (s *FileRevocationStore) persist() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.MemoryRevocationStore.Entries()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0700); err != nil {
+		return err
+	}
+
+	tmpFile := s.filePath + ".tmp"
+	if err := os.WriteFile(tmpFile, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, s.filePath)
+}
+
+func (s *FileRevocationStore) load() {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return
+	}
+
+	var entries []RevokedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		if e.KeyID != "" {
+			s.keyIDs[e.KeyID] = e
+			continue
+		}
+		s.serials[e.Serial] = e
+	}
+}
@@ -12,7 +12,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/bastion/keystore"
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -25,6 +28,19 @@ type Config struct {
 	CAKeyPath          string
 	GrantStore         *GrantStore
 	Logger             *Logger
+	// Recorder, if set, records the traffic of sessions whose grant has
+	// Record set, gated per-session in handleDirectTCPIP. Nil disables
+	// recording entirely regardless of Grant.Record.
+	Recorder Recorder
+	// UnlockKey, if set, encrypts the host key and CA key at rest (see
+	// bastion/keystore) instead of writing cleartext PEM to disk. If a
+	// *.enc key already exists and UnlockKey is empty, NewServer fails
+	// with keystore.ErrLocked rather than starting unlocked.
+	UnlockKey string
+	// Metrics, if set, receives the plasma_bastion_active_sessions gauge and
+	// (via SetRecoveryMetrics) safeGo's panic recoveries. Grant issuance/
+	// expiry is reported separately via GrantStore.SetMetrics.
+	Metrics *limiter.Metrics
 }
 
 type Server struct {
@@ -37,6 +53,11 @@ type Server struct {
 	logger         *Logger
 	ca             *CertificateAuthority
 	grants         *GrantStore
+	recorder       Recorder
+	metrics        *limiter.Metrics
+	// hostSigner is the bastion's own host key, reused in handleSession as
+	// the bastion's client identity when it dials a session's target.
+	hostSigner ssh.Signer
 }
 
 func NewServer(config Config) (*Server, error) {
@@ -47,7 +68,7 @@ func NewServer(config Config) (*Server, error) {
 		config.HostKeyPath = defaultHostKeyPath
 	}
 
-	signer, err := loadOrCreateHostKey(config.HostKeyPath)
+	signer, err := loadOrCreateHostKey(config.HostKeyPath, config.UnlockKey)
 	if err != nil {
 		return nil, fmt.Errorf("load host key: %w", err)
 	}
@@ -63,7 +84,7 @@ func NewServer(config Config) (*Server, error) {
 		return nil, errors.New("bastion grant store required")
 	}
 
-	ca, err := NewCertificateAuthority(config.CAKeyPath)
+	ca, err := NewCertificateAuthorityWithClock(config.CAKeyPath, config.UnlockKey, func() time.Time { return time.Now().UTC() })
 	if err != nil {
 		return nil, fmt.Errorf("load CA: %w", err)
 	}
@@ -89,6 +110,10 @@ func NewServer(config Config) (*Server, error) {
 	}
 	sshConfig.AddHostKey(signer)
 
+	if config.Metrics != nil {
+		SetRecoveryMetrics(config.Metrics)
+	}
+
 	return &Server{
 		config:         config,
 		sshConfig:      sshConfig,
@@ -96,6 +121,9 @@ func NewServer(config Config) (*Server, error) {
 		logger:         config.Logger,
 		ca:             ca,
 		grants:         config.GrantStore,
+		recorder:       config.Recorder,
+		metrics:        config.Metrics,
+		hostSigner:     signer,
 	}, nil
 }
 
@@ -139,7 +167,9 @@ func (s *Server) serve() {
 			log.Printf("bastion accept error: %v", err)
 			continue
 		}
-		go s.handleConn(conn)
+		safeGo(s.logger, "handleConn", conn.RemoteAddr().String(), "", func() {
+			s.handleConn(conn)
+		})
 	}
 }
 
@@ -158,19 +188,60 @@ func (s *Server) handleConn(netConn net.Conn) {
 	}
 	defer sshConn.Close()
 
-	go ssh.DiscardRequests(requests)
+	if s.metrics != nil {
+		s.metrics.IncActiveSSHSessions()
+		defer s.metrics.DecActiveSSHSessions()
+	}
+
+	safeGo(s.logger, "discardRequests", sshConn.RemoteAddr().String(), sshConn.User(), func() {
+		ssh.DiscardRequests(requests)
+	})
 
 	for channel := range channels {
 		switch channel.ChannelType() {
 		case "direct-tcpip":
-			go s.handleDirectTCPIP(sshConn, channel)
+			ch := channel
+			safeGo(s.logger, "handleDirectTCPIP", sshConn.RemoteAddr().String(), sshConn.User(), func() {
+				s.handleDirectTCPIP(sshConn, ch)
+			})
+		case "session":
+			ch := channel
+			safeGo(s.logger, "handleSession", sshConn.RemoteAddr().String(), sshConn.User(), func() {
+				s.handleSession(sshConn, ch)
+			})
 		default:
 			channel.Reject(ssh.UnknownChannelType, "unsupported channel type")
 		}
 	}
 }
 
-func loadOrCreateHostKey(path string) (ssh.Signer, error) {
+// hostKeyEncPath returns the encrypted-at-rest sibling of a cleartext host
+// key path, used when unlockKey is set (see loadOrCreateHostKey).
+func hostKeyEncPath(path string) string {
+	return path + ".enc"
+}
+
+func generateHostKeyPEM() ([]byte, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}), nil
+}
+
+func loadOrCreateHostKey(path, unlockKey string) (ssh.Signer, error) {
+	if unlockKey != "" || keystore.Exists(hostKeyEncPath(path)) {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+
+		pemBytes, err := keystore.LoadOrCreate(hostKeyEncPath(path), unlockKey, generateHostKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+		return ssh.ParsePrivateKey(pemBytes)
+	}
+
 	data, err := os.ReadFile(path)
 	if err == nil {
 		return ssh.ParsePrivateKey(data)
@@ -183,13 +254,10 @@ func loadOrCreateHostKey(path string) (ssh.Signer, error) {
 		return nil, err
 	}
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	pemBytes, err := generateHostKeyPEM()
 	if err != nil {
 		return nil, err
 	}
-
-	pemBlock := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}
-	pemBytes := pem.EncodeToMemory(pemBlock)
 	if err := os.WriteFile(path, pemBytes, 0600); err != nil {
 		return nil, err
 	}
@@ -8,23 +8,27 @@ type SessionEvent struct {
 	GrantID   string    `json:"grant_id"`
 	Principal string    `json:"principal"`
 	Target    string    `json:"target"`
-	Event     string    `json:"event"` // connect, disconnect, command
+	Event     string    `json:"event"` // connect, disconnect, command, recording_available
 	Timestamp time.Time `json:"timestamp"`
 	Data      string    `json:"data,omitempty"`
 }
 
 const (
-	SessionEventConnect    = "connect"
-	SessionEventDisconnect = "disconnect"
-	SessionEventCommand    = "command"
+	SessionEventConnect            = "connect"
+	SessionEventDisconnect         = "disconnect"
+	SessionEventCommand            = "command"
+	SessionEventPanic              = "panic"
+	SessionEventRecordingAvailable = "recording_available"
 )
 
 // Grant represents a time-limited access grant for SSH bastion access.
 type Grant struct {
 	ID        string    `json:"id"`
-	Principal string    `json:"principal"`   // who can use this grant
-	Target    string    `json:"target"`      // agent or fleet pattern
+	Principal string    `json:"principal"` // who can use this grant
+	Target    string    `json:"target"`    // agent or fleet pattern
 	ExpiresAt time.Time `json:"expires_at"`
-	CreatedBy string    `json:"created_by"`  // audit trail
+	CreatedBy string    `json:"created_by"` // audit trail
 	CreatedAt time.Time `json:"created_at"`
+	Record    bool      `json:"record,omitempty"` // tee session traffic to Server.recorder
+	Shell     bool      `json:"shell,omitempty"`  // authorize an interactive "session" channel, not just direct-tcpip
 }
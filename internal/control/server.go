@@ -0,0 +1,479 @@
+// Package control implements ShieldControl, the gRPC control-plane
+// counterpart to cmd/proxy's REST management API (GET/PUT /rules,
+// POST /rules/reload, GET/PUT /mode, PUT/DELETE /agent/{id}/mode): live
+// rule and mode management for plasma-shieldctl and similar operator
+// tooling, backed directly by the same *rules.Engine and *mode.Manager the
+// REST handlers use, rather than round-tripping through HTTP the way
+// internal/api's GRPCServer replays ExecCheck through ExecCheckHandler --
+// cmd/proxy's rules_handler.go/main.go have no single Handlers type to
+// replay through, so Server calls the engine and mode manager directly.
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	controlpb "github.com/Extra-Chill/plasma-shield/api/proto"
+	"github.com/Extra-Chill/plasma-shield/internal/acl"
+	"github.com/Extra-Chill/plasma-shield/internal/logs"
+	"github.com/Extra-Chill/plasma-shield/internal/metrics"
+	"github.com/Extra-Chill/plasma-shield/internal/mode"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+	"github.com/Extra-Chill/plasma-shield/internal/rules"
+)
+
+// DecisionSource is implemented by plasmalog.BroadcastSink: it's the source
+// StreamDecisions tails. Declared as an interface rather than depending on
+// BroadcastSink's concrete type so a test can substitute a fake.
+type DecisionSource interface {
+	Subscribe() *logs.Subscription
+}
+
+// AuditSource is implemented by cmd/proxy's LogStore: it's the source
+// StreamAudit tails. Separate from DecisionSource even though both are
+// "something with a Subscribe method" so a caller can't accidentally wire
+// the wrong broker to the wrong RPC.
+type AuditSource interface {
+	Subscribe() *logs.Subscription
+}
+
+// Server implements controlpb.ShieldControlServer. Construct with NewServer
+// and serve the result of Server() on a net.Listener dedicated to it, the
+// same way internal/api.GRPCServer runs independently of cmd/api's
+// http.Server.
+type Server struct {
+	controlpb.UnimplementedShieldControlServer
+
+	server      *grpc.Server
+	engine      *rules.Engine
+	modeManager *mode.Manager
+	decisions   DecisionSource
+	audit       AuditSource
+	resolver    *acl.Resolver
+	extraOpts   []grpc.ServerOption
+}
+
+// Option configures a Server at construction time.
+type Option func(*Server)
+
+// WithGRPCServerOptions appends extra grpc.ServerOption values (most
+// usefully grpc.Creds, to require mTLS client certificates) onto the
+// ChainUnaryInterceptor/ChainStreamInterceptor/StatsHandler options
+// NewServer already builds.
+func WithGRPCServerOptions(opts ...grpc.ServerOption) Option {
+	return func(s *Server) {
+		s.extraOpts = append(s.extraOpts, opts...)
+	}
+}
+
+// NewServer builds a *grpc.Server with the ShieldControl service
+// registered, wired with a recovery interceptor (a panic in a handler
+// becomes a gRPC Internal error instead of tearing down the proxy, the
+// same contract middleware.Recover provides for the REST listener), an ACL
+// auth interceptor validating the caller's bearer token against resolver
+// (nil resolver means wide open, matching checkACL's REST behavior), and a
+// stats.Handler recording plasma_grpc_request_duration_seconds/
+// plasma_grpc_active_streams into m. decisions/audit may be nil, in which
+// case StreamDecisions/StreamAudit fail with Unavailable rather than
+// blocking forever with nothing to send.
+func NewServer(engine *rules.Engine, modeManager *mode.Manager, decisions DecisionSource, audit AuditSource, resolver *acl.Resolver, m *metrics.Metrics, opts ...Option) *Server {
+	s := &Server{
+		engine:      engine,
+		modeManager: modeManager,
+		decisions:   decisions,
+		audit:       audit,
+		resolver:    resolver,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	recoveryOpts := []recovery.Option{
+		recovery.WithRecoveryHandlerContext(func(ctx context.Context, p interface{}) error {
+			return status.Errorf(codes.Internal, "internal error")
+		}),
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(recoveryOpts...),
+			controlAuthUnaryInterceptor(resolver),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(recoveryOpts...),
+			controlAuthStreamInterceptor(resolver),
+		),
+	}
+	if m != nil {
+		serverOpts = append(serverOpts, grpc.StatsHandler(&controlStatsHandler{metrics: m}))
+	}
+	serverOpts = append(serverOpts, s.extraOpts...)
+
+	s.server = grpc.NewServer(serverOpts...)
+	controlpb.RegisterShieldControlServer(s.server, s)
+	return s
+}
+
+// Server returns the underlying *grpc.Server, for the caller to Serve on a
+// net.Listener and GracefulStop on shutdown.
+func (s *Server) Server() *grpc.Server {
+	return s.server
+}
+
+// controlWriteMethods lists the ShieldControl methods (matched by
+// FullMethod suffix in controlAccessLevel) that need the write level the
+// REST handler for the equivalent action requires (see checkACL's call
+// sites in cmd/proxy/main.go); every other method needs only read.
+var controlWriteMethods = map[string]bool{
+	"PutRules":    true,
+	"ReloadRules": true,
+	"SetMode":     true,
+}
+
+func controlAccessLevel(fullMethod string) acl.AccessLevel {
+	for name := range controlWriteMethods {
+		if len(fullMethod) >= len(name) && fullMethod[len(fullMethod)-len(name):] == name {
+			return acl.Write
+		}
+	}
+	return acl.Read
+}
+
+// controlAuthUnaryInterceptor validates the "authorization" gRPC metadata
+// value against resolver, the gRPC analogue of checkACL/authorizeACL for
+// the REST rules/mode endpoints: same acl.Resolver, same "control" resource
+// name, same nil-resolver-means-wide-open default.
+func controlAuthUnaryInterceptor(resolver *acl.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorizeControl(ctx, resolver, controlAccessLevel(info.FullMethod)); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func controlAuthStreamInterceptor(resolver *acl.Resolver) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorizeControl(ss.Context(), resolver, controlAccessLevel(info.FullMethod)); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authorizeControl(ctx context.Context, resolver *acl.Resolver, need acl.AccessLevel) error {
+	if resolver == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		const prefix = "Bearer "
+		auth := values[0]
+		if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+			token = auth[len(prefix):]
+		} else {
+			token = auth
+		}
+	}
+	if err := resolver.Authorize(token, "control", need); err != nil {
+		if _, ok := err.(*acl.NotFoundError); ok {
+			return status.Error(codes.Unauthenticated, err.Error())
+		}
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return nil
+}
+
+// controlStatsHandler implements google.golang.org/grpc/stats.Handler,
+// recording plasma_grpc_request_duration_seconds and
+// plasma_grpc_active_streams for every ShieldControl call, the same
+// parallel-to-interceptors pattern internal/api's grpcStatsHandler uses.
+type controlStatsHandler struct {
+	metrics *metrics.Metrics
+}
+
+type controlStatsContextKey int
+
+const controlStatsStartKey controlStatsContextKey = iota
+
+type controlStatsTag struct {
+	method string
+}
+
+func (h *controlStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, controlStatsStartKey, controlStatsTag{method: info.FullMethodName})
+}
+
+func (h *controlStatsHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	tag, _ := ctx.Value(controlStatsStartKey).(controlStatsTag)
+	switch st := s.(type) {
+	case *stats.Begin:
+		h.metrics.GRPCActiveStreams.WithLabelValues(tag.method).Inc()
+	case *stats.End:
+		h.metrics.GRPCActiveStreams.WithLabelValues(tag.method).Dec()
+		code := codes.OK
+		if st.Error != nil {
+			code = status.Code(st.Error)
+		}
+		h.metrics.GRPCRequestDuration.WithLabelValues(tag.method, code.String()).Observe(st.EndTime.Sub(st.BeginTime).Seconds())
+	}
+}
+
+func (h *controlStatsHandler) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *controlStatsHandler) HandleConn(ctx context.Context, s stats.ConnStats) {}
+
+// GetRules implements controlpb.ShieldControlServer by encoding the active
+// ruleset as a rules.Bundle, mirroring GET /rules?format=bundle.
+func (s *Server) GetRules(ctx context.Context, req *controlpb.GetRulesRequest) (*controlpb.RuleBundle, error) {
+	rs := &rules.RuleSet{Rules: s.engine.Snapshot()}
+	bundle := rules.BundleFromRuleSet(rs, req.Group)
+	data, err := bundle.ToYAML()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal rule bundle: %v", err)
+	}
+	return &controlpb.RuleBundle{
+		Yaml:      data,
+		RulesHash: s.engine.Hash(),
+		RuleCount: int32(s.engine.RuleCount()),
+	}, nil
+}
+
+// PutRules implements controlpb.ShieldControlServer, mirroring
+// rules_bundle_handler.go's rulesBundleHandler: parse the bundle, merge (or
+// replace) it against the active ruleset, diff, and -- unless dry_run --
+// validate-and-swap it in via Engine.LoadRuleSet.
+func (s *Server) PutRules(ctx context.Context, req *controlpb.PutRulesRequest) (*controlpb.PutRulesResponse, error) {
+	bundle, err := rules.ParseBundle(req.Yaml)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "parse rule bundle: %v", err)
+	}
+	overlay := bundle.Flatten()
+
+	current := &rules.RuleSet{Rules: s.engine.Snapshot()}
+	candidate := overlay
+	if !req.Replace {
+		candidate = rules.MergeRuleSets(current, overlay)
+	}
+	diff := rules.DiffRuleSets(current, candidate)
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "marshal diff: %v", err)
+	}
+
+	if req.DryRun {
+		return &controlpb.PutRulesResponse{
+			Status:           "dry_run",
+			DiffJson:         string(diffJSON),
+			WouldBeRuleCount: int32(len(candidate.Rules)),
+		}, nil
+	}
+
+	if err := s.engine.LoadRuleSet(candidate); err != nil {
+		var verr *rules.ValidationError
+		if e, ok := err.(*rules.ValidationError); ok {
+			verr = e
+		}
+		msg := err.Error()
+		if verr != nil {
+			msg = status.Newf(codes.InvalidArgument, "rule %d: %v", verr.Index, verr.Err).Message()
+		}
+		return nil, status.Error(codes.InvalidArgument, msg)
+	}
+
+	return &controlpb.PutRulesResponse{
+		Status:    "ok",
+		DiffJson:  string(diffJSON),
+		RulesHash: s.engine.Hash(),
+		RuleCount: int32(s.engine.RuleCount()),
+	}, nil
+}
+
+// ReloadRules implements controlpb.ShieldControlServer, mirroring
+// rulesReloadHandler: re-read and validate-and-swap the file the engine
+// was started with.
+func (s *Server) ReloadRules(ctx context.Context, req *controlpb.ReloadRulesRequest) (*controlpb.ReloadRulesResponse, error) {
+	path := s.engine.RulesPath()
+	if path == "" {
+		return nil, status.Error(codes.FailedPrecondition, "no rules file configured; start the proxy with -rules")
+	}
+	if err := s.engine.LoadRules(path); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	return &controlpb.ReloadRulesResponse{
+		Status:    "ok",
+		RulesHash: s.engine.Hash(),
+		RuleCount: int32(s.engine.RuleCount()),
+	}, nil
+}
+
+// TestCommand implements controlpb.ShieldControlServer, mirroring
+// rules.Engine.CheckCommand.
+func (s *Server) TestCommand(ctx context.Context, req *controlpb.TestCommandRequest) (*controlpb.TestResult, error) {
+	allowed, matchedRule, reason := s.engine.CheckCommand(req.Command)
+	return testResult(allowed, matchedRule, reason), nil
+}
+
+// TestDomain implements controlpb.ShieldControlServer, mirroring
+// rules.Engine.CheckDomain.
+func (s *Server) TestDomain(ctx context.Context, req *controlpb.TestDomainRequest) (*controlpb.TestResult, error) {
+	allowed, matchedRule, reason := s.engine.CheckDomain(req.Domain)
+	return testResult(allowed, matchedRule, reason), nil
+}
+
+func testResult(allowed bool, matchedRule *rules.Rule, reason string) *controlpb.TestResult {
+	ruleID := ""
+	if matchedRule != nil {
+		ruleID = matchedRule.ID
+	}
+	return &controlpb.TestResult{Allowed: allowed, Reason: reason, RuleId: ruleID}
+}
+
+// GetMode implements controlpb.ShieldControlServer, mirroring GET /mode.
+func (s *Server) GetMode(ctx context.Context, req *controlpb.GetModeRequest) (*controlpb.ModeState, error) {
+	return s.modeState(), nil
+}
+
+// SetMode implements controlpb.ShieldControlServer, mirroring PUT /mode
+// (agent_id empty) and PUT/DELETE /agent/{id}/mode (agent_id set, DELETE
+// when mode is also empty).
+func (s *Server) SetMode(ctx context.Context, req *controlpb.SetModeRequest) (*controlpb.ModeState, error) {
+	if req.AgentId == "" {
+		m, err := parseMode(req.Mode)
+		if err != nil {
+			return nil, err
+		}
+		s.modeManager.SetGlobalMode(m)
+		return s.modeState(), nil
+	}
+
+	if req.Mode == "" {
+		s.modeManager.ClearAgentMode(req.AgentId)
+		return s.modeState(), nil
+	}
+
+	m, err := parseMode(req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	s.modeManager.SetAgentMode(req.AgentId, m)
+	return s.modeState(), nil
+}
+
+func parseMode(raw string) (mode.Mode, error) {
+	switch m := mode.Mode(raw); m {
+	case mode.Enforce, mode.Audit, mode.Lockdown:
+		return m, nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "invalid mode %q; use enforce, audit, or lockdown", raw)
+	}
+}
+
+func (s *Server) modeState() *controlpb.ModeState {
+	agentModes := make(map[string]string)
+	for agentID, m := range s.modeManager.AllAgentModes() {
+		agentModes[agentID] = string(m)
+	}
+	return &controlpb.ModeState{
+		GlobalMode: string(s.modeManager.GlobalMode()),
+		AgentModes: agentModes,
+	}
+}
+
+// StreamDecisions implements controlpb.ShieldControlServer by relaying
+// s.decisions, translating each plasmalog.Record's flattened Attrs back
+// into a DecisionEvent using the same Key* names Inspector.CheckRequest
+// logged them under.
+func (s *Server) StreamDecisions(req *controlpb.StreamDecisionsRequest, stream controlpb.ShieldControl_StreamDecisionsServer) error {
+	if s.decisions == nil {
+		return status.Error(codes.Unavailable, "decision streaming is not configured")
+	}
+	sub := s.decisions.Subscribe()
+	defer sub.Close()
+
+	for {
+		select {
+		case rec := <-sub.Events():
+			record, ok := rec.Payload.(plasmalog.Record)
+			if !ok {
+				continue
+			}
+			attrs := record.Attrs
+			agentID := attrs[plasmalog.KeyAgentID]
+			if req.AgentId != "" && agentID != req.AgentId {
+				continue
+			}
+			var latencyMs int64
+			if raw, ok := attrs[plasmalog.KeyLatencyMs]; ok {
+				json.Unmarshal([]byte(raw), &latencyMs)
+			}
+			err := stream.Send(&controlpb.DecisionEvent{
+				AgentId:       agentID,
+				Tier:          attrs[plasmalog.KeyTier],
+				Domain:        attrs[plasmalog.KeyDomain],
+				Method:        attrs[plasmalog.KeyMethod],
+				Path:          attrs[plasmalog.KeyPath],
+				RuleId:        attrs[plasmalog.KeyRuleID],
+				Mode:          attrs[plasmalog.KeyMode],
+				Action:        attrs[plasmalog.KeyAction],
+				LatencyMs:     latencyMs,
+				ClientIp:      attrs[plasmalog.KeyClientIP],
+				TimestampUnix: record.Time.Unix(),
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// StreamAudit implements controlpb.ShieldControlServer by relaying
+// s.audit, the same proxy.LogEntry records GET /logs/stream serves.
+func (s *Server) StreamAudit(req *controlpb.StreamAuditRequest, stream controlpb.ShieldControl_StreamAuditServer) error {
+	if s.audit == nil {
+		return status.Error(codes.Unavailable, "audit streaming is not configured")
+	}
+	sub := s.audit.Subscribe()
+	defer sub.Close()
+
+	for {
+		select {
+		case rec := <-sub.Events():
+			entry, ok := rec.Payload.(proxy.LogEntry)
+			if !ok {
+				continue
+			}
+			if req.AgentId != "" && entry.AgentID != req.AgentId {
+				continue
+			}
+			err := stream.Send(&controlpb.AuditEvent{
+				TimestampUnix: entry.Timestamp.Unix(),
+				SourceIp:      entry.SourceIP,
+				AgentId:       entry.AgentID,
+				Domain:        entry.Domain,
+				Method:        entry.Method,
+				Action:        entry.Action,
+				Reason:        entry.Reason,
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
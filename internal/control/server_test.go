@@ -0,0 +1,267 @@
+package control
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	controlpb "github.com/Extra-Chill/plasma-shield/api/proto"
+	"github.com/Extra-Chill/plasma-shield/internal/logs"
+	"github.com/Extra-Chill/plasma-shield/internal/mode"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
+	"github.com/Extra-Chill/plasma-shield/internal/rules"
+)
+
+const testRulesYAML = `
+rules:
+  - id: block-test
+    domain: "blocked.test"
+    action: block
+    description: "Test block"
+    enabled: true
+`
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	engine := rules.NewEngine()
+	if err := engine.LoadRulesFromBytes([]byte(testRulesYAML)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+	return NewServer(engine, mode.NewManager(), nil, nil, nil, nil)
+}
+
+func TestGetRules(t *testing.T) {
+	s := testServer(t)
+
+	bundle, err := s.GetRules(context.Background(), &controlpb.GetRulesRequest{})
+	if err != nil {
+		t.Fatalf("GetRules: %v", err)
+	}
+	if bundle.RuleCount != 1 {
+		t.Errorf("RuleCount = %d, want 1", bundle.RuleCount)
+	}
+	if bundle.RulesHash == "" {
+		t.Error("RulesHash should not be empty")
+	}
+
+	parsed, err := rules.ParseBundle(bundle.Yaml)
+	if err != nil {
+		t.Fatalf("ParseBundle: %v", err)
+	}
+	flat := parsed.Flatten()
+	if len(flat.Rules) != 1 || flat.Rules[0].ID != "block-test" {
+		t.Errorf("unexpected rules in bundle: %+v", flat.Rules)
+	}
+}
+
+func TestPutRules(t *testing.T) {
+	s := testServer(t)
+
+	addYAML := `
+groups:
+  - name: added
+    rules:
+      - id: block-added
+        domain: "added.test"
+        action: block
+        enabled: true
+`
+
+	t.Run("dry run leaves the engine untouched", func(t *testing.T) {
+		resp, err := s.PutRules(context.Background(), &controlpb.PutRulesRequest{Yaml: []byte(addYAML), DryRun: true})
+		if err != nil {
+			t.Fatalf("PutRules: %v", err)
+		}
+		if resp.Status != "dry_run" {
+			t.Errorf("Status = %q, want dry_run", resp.Status)
+		}
+		if resp.WouldBeRuleCount != 2 {
+			t.Errorf("WouldBeRuleCount = %d, want 2", resp.WouldBeRuleCount)
+		}
+		if s.engine.RuleCount() != 1 {
+			t.Errorf("engine.RuleCount() = %d, want 1 (dry run must not apply)", s.engine.RuleCount())
+		}
+	})
+
+	t.Run("merges onto the active ruleset by default", func(t *testing.T) {
+		resp, err := s.PutRules(context.Background(), &controlpb.PutRulesRequest{Yaml: []byte(addYAML)})
+		if err != nil {
+			t.Fatalf("PutRules: %v", err)
+		}
+		if resp.Status != "ok" {
+			t.Errorf("Status = %q, want ok", resp.Status)
+		}
+		if resp.RuleCount != 2 {
+			t.Errorf("RuleCount = %d, want 2", resp.RuleCount)
+		}
+		if s.engine.RuleCount() != 2 {
+			t.Errorf("engine.RuleCount() = %d, want 2", s.engine.RuleCount())
+		}
+	})
+
+	t.Run("rejects an invalid bundle", func(t *testing.T) {
+		_, err := s.PutRules(context.Background(), &controlpb.PutRulesRequest{Yaml: []byte("not: [valid")})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+		}
+	})
+}
+
+func TestReloadRules(t *testing.T) {
+	t.Run("fails with no rules path configured", func(t *testing.T) {
+		s := testServer(t)
+		_, err := s.ReloadRules(context.Background(), &controlpb.ReloadRulesRequest{})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Errorf("code = %v, want FailedPrecondition", status.Code(err))
+		}
+	})
+
+	t.Run("re-reads the configured rules file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "rules.yaml")
+		writeFile(t, path, testRulesYAML)
+
+		engine := rules.NewEngine()
+		if err := engine.LoadRules(path); err != nil {
+			t.Fatalf("LoadRules: %v", err)
+		}
+		s := NewServer(engine, mode.NewManager(), nil, nil, nil, nil)
+
+		writeFile(t, path, `
+rules:
+  - id: block-test
+    domain: "blocked.test"
+    action: block
+    enabled: true
+  - id: block-other
+    domain: "other.test"
+    action: block
+    enabled: true
+`)
+
+		resp, err := s.ReloadRules(context.Background(), &controlpb.ReloadRulesRequest{})
+		if err != nil {
+			t.Fatalf("ReloadRules: %v", err)
+		}
+		if resp.RuleCount != 2 {
+			t.Errorf("RuleCount = %d, want 2", resp.RuleCount)
+		}
+	})
+}
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestSetMode(t *testing.T) {
+	s := testServer(t)
+
+	t.Run("sets the global mode", func(t *testing.T) {
+		state, err := s.SetMode(context.Background(), &controlpb.SetModeRequest{Mode: "lockdown"})
+		if err != nil {
+			t.Fatalf("SetMode: %v", err)
+		}
+		if state.GlobalMode != "lockdown" {
+			t.Errorf("GlobalMode = %q, want lockdown", state.GlobalMode)
+		}
+	})
+
+	t.Run("sets a per-agent mode", func(t *testing.T) {
+		state, err := s.SetMode(context.Background(), &controlpb.SetModeRequest{AgentId: "agent-1", Mode: "audit"})
+		if err != nil {
+			t.Fatalf("SetMode: %v", err)
+		}
+		if state.AgentModes["agent-1"] != "audit" {
+			t.Errorf("AgentModes[agent-1] = %q, want audit", state.AgentModes["agent-1"])
+		}
+	})
+
+	t.Run("clears a per-agent mode", func(t *testing.T) {
+		state, err := s.SetMode(context.Background(), &controlpb.SetModeRequest{AgentId: "agent-1"})
+		if err != nil {
+			t.Fatalf("SetMode: %v", err)
+		}
+		if _, ok := state.AgentModes["agent-1"]; ok {
+			t.Errorf("AgentModes[agent-1] should have been cleared, got %+v", state.AgentModes)
+		}
+	})
+
+	t.Run("rejects an invalid mode", func(t *testing.T) {
+		_, err := s.SetMode(context.Background(), &controlpb.SetModeRequest{Mode: "bogus"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+		}
+	})
+}
+
+// fakeDecisionsStream is a minimal ShieldControl_StreamDecisionsServer: it
+// embeds a nil grpc.ServerStream (StreamDecisions never calls any method
+// but Context) and forwards every sent event onto a channel.
+type fakeDecisionsStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *controlpb.DecisionEvent
+}
+
+func (f *fakeDecisionsStream) Context() context.Context { return f.ctx }
+
+func (f *fakeDecisionsStream) Send(e *controlpb.DecisionEvent) error {
+	f.sent <- e
+	return nil
+}
+
+func TestStreamDecisions(t *testing.T) {
+	broker := logs.NewBroker(16)
+	s := NewServer(rules.NewEngine(), mode.NewManager(), broker, nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &fakeDecisionsStream{ctx: ctx, sent: make(chan *controlpb.DecisionEvent, 1)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.StreamDecisions(&controlpb.StreamDecisionsRequest{}, stream)
+	}()
+
+	// StreamDecisions subscribes to broker in its own goroutine above, so
+	// there's a race between that Subscribe and this Publish; keep
+	// publishing until one lands on a subscriber that's ready.
+	var got *controlpb.DecisionEvent
+	deadline := time.After(2 * time.Second)
+publish:
+	for {
+		broker.Publish(plasmalog.Record{
+			Attrs: map[string]string{
+				plasmalog.KeyAgentID: "agent-1",
+				plasmalog.KeyDomain:  "blocked.test",
+				plasmalog.KeyAction:  "block",
+			},
+		}, time.Now())
+
+		select {
+		case got = <-stream.sent:
+			break publish
+		case <-deadline:
+			t.Fatal("timed out waiting for decision event")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("StreamDecisions returned %v, want context.Canceled", err)
+	}
+
+	if got.AgentId != "agent-1" || got.Domain != "blocked.test" || got.Action != "block" {
+		t.Errorf("unexpected decision event: %+v", got)
+	}
+}
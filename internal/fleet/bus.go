@@ -0,0 +1,292 @@
+package fleet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Event is a message one agent publishes for delivery to every peer it
+// CanCommunicate with.
+type Event struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	FromAgentID string          `json:"from_agent_id"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// DeadLetter records an event delivery that exhausted its retries.
+type DeadLetter struct {
+	Event     Event     `json:"event"`
+	ToAgentID string    `json:"to_agent_id"`
+	TenantID  string    `json:"tenant_id"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// SignatureHeader is the HTTP header a Bus delivery's HMAC signature is
+// sent in, and the header POST /fleet/events verifies an incoming
+// publish against. The value is "t=<unix-seconds>,v1=<hex-hmac>", signed
+// over "<timestamp>.<body>" -- the same shape Stripe webhooks use.
+const SignatureHeader = "X-Plasma-Signature"
+
+// ReplayWindow is how old a signature's timestamp may be before a
+// delivery (inbound or outbound) is rejected as a replay.
+const ReplayWindow = 5 * time.Minute
+
+// deliveryQueueSize bounds how many pending deliveries a single peer's
+// worker goroutine will queue before new publishes to that peer are
+// dead-lettered immediately instead of blocking the publisher.
+const deliveryQueueSize = 64
+
+// maxDeliveryAttempts is the number of times a Bus retries a delivery
+// before giving up and recording a DeadLetter.
+const maxDeliveryAttempts = 5
+
+// Bus delivers Events published by one agent to every peer it
+// CanCommunicate with, via a signed HTTP POST to the peer's WebhookURL.
+// Deliveries to a given destination agent are serialized through a
+// bounded per-peer queue and retried with exponential backoff; a
+// delivery that exhausts its retries is recorded as a DeadLetter instead
+// of being dropped silently.
+type Bus struct {
+	mgr    *Manager
+	client *http.Client
+
+	queuesMu sync.Mutex
+	queues   map[string]chan delivery // agent ID -> worker queue
+
+	deadMu     sync.Mutex
+	deadLetter []DeadLetter
+
+	// sleep is overridden in tests to avoid real backoff delays.
+	sleep func(time.Duration)
+}
+
+type delivery struct {
+	event  Event
+	peer   Agent
+	secret []byte
+}
+
+// BusOption configures a Bus.
+type BusOption func(*Bus)
+
+// WithBusHTTPClient overrides the HTTP client used to deliver webhooks.
+// If not set, a client with a 10 second timeout is used.
+func WithBusHTTPClient(c *http.Client) BusOption {
+	return func(b *Bus) {
+		b.client = c
+	}
+}
+
+// NewBus creates a Bus that delivers events between agents managed by mgr.
+func NewBus(mgr *Manager, opts ...BusOption) *Bus {
+	b := &Bus{
+		mgr:    mgr,
+		client: &http.Client{Timeout: 10 * time.Second},
+		queues: make(map[string]chan delivery),
+		sleep:  time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish signs event and enqueues it for delivery to every agent
+// fromAgentID CanCommunicate with (same tenant, Fleet mode, has a
+// WebhookURL). Returns the number of peers the event was enqueued for.
+// A peer whose queue is already full is dead-lettered immediately rather
+// than blocking the caller.
+func (b *Bus) Publish(fromAgentID string, event Event) (queued int, err error) {
+	tenantID := b.mgr.GetTenantForAgent(fromAgentID)
+	if tenantID == "" {
+		return 0, fmt.Errorf("fleet: unknown agent %q", fromAgentID)
+	}
+
+	if event.ID == "" {
+		return 0, fmt.Errorf("fleet: event ID is required")
+	}
+	event.FromAgentID = fromAgentID
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	secret := b.mgr.TenantSecret(tenantID)
+
+	for _, peer := range b.mgr.GetAgentsForAgent(fromAgentID) {
+		if !b.mgr.CanCommunicate(fromAgentID, peer.ID) || peer.WebhookURL == "" {
+			continue
+		}
+		if b.enqueue(peer, tenantID, secret, event) {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// enqueue hands a delivery to peer's worker queue, starting the worker if
+// this is its first delivery. Reports whether the delivery was queued;
+// false means the queue was full and the delivery was dead-lettered.
+func (b *Bus) enqueue(peer Agent, tenantID string, secret []byte, event Event) bool {
+	b.queuesMu.Lock()
+	q, ok := b.queues[peer.ID]
+	if !ok {
+		q = make(chan delivery, deliveryQueueSize)
+		b.queues[peer.ID] = q
+		go b.worker(q)
+	}
+	b.queuesMu.Unlock()
+
+	d := delivery{event: event, peer: peer, secret: secret}
+	select {
+	case q <- d:
+		return true
+	default:
+		b.recordDeadLetter(d, tenantID, fmt.Errorf("delivery queue full for agent %s", peer.ID), 0)
+		return false
+	}
+}
+
+// worker delivers every delivery sent to q in order, retrying each with
+// exponential backoff before giving up. One worker runs per destination
+// peer for as long as the Bus is alive, so a slow or down peer never
+// blocks delivery to any other peer.
+func (b *Bus) worker(q chan delivery) {
+	for d := range q {
+		b.deliver(d)
+	}
+}
+
+func (b *Bus) deliver(d delivery) {
+	tenantID := b.mgr.GetTenantForAgent(d.event.FromAgentID)
+
+	body, err := json.Marshal(d.event)
+	if err != nil {
+		b.recordDeadLetter(d, tenantID, fmt.Errorf("marshal event: %w", err), 0)
+		return
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxDeliveryAttempts; attempt++ {
+		if attempt > 0 {
+			b.sleep(backoff(attempt))
+		}
+
+		if err := b.post(d.peer.WebhookURL, d.secret, body); err != nil {
+			lastErr = err
+			continue
+		}
+		return
+	}
+
+	b.recordDeadLetter(d, tenantID, lastErr, maxDeliveryAttempts)
+}
+
+func (b *Bus) post(webhookURL string, secret []byte, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post to %s: %w", webhookURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post to %s: status %d", webhookURL, resp.StatusCode)
+	}
+	return nil
+}
+
+func (b *Bus) recordDeadLetter(d delivery, tenantID string, cause error, attempts int) {
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	b.deadMu.Lock()
+	defer b.deadMu.Unlock()
+	b.deadLetter = append(b.deadLetter, DeadLetter{
+		Event:     d.event,
+		ToAgentID: d.peer.ID,
+		TenantID:  tenantID,
+		Error:     msg,
+		Attempts:  attempts,
+		FailedAt:  time.Now().UTC(),
+	})
+}
+
+// DeadLetters returns every delivery that has exhausted its retries, most
+// recent last, for GET /fleet/deadletter.
+func (b *Bus) DeadLetters() []DeadLetter {
+	b.deadMu.Lock()
+	defer b.deadMu.Unlock()
+	out := make([]DeadLetter, len(b.deadLetter))
+	copy(out, b.deadLetter)
+	return out
+}
+
+// backoff returns the delay before retry attempt n (1-indexed), doubling
+// from 200ms with up to 30% random jitter so peers whose webhooks all
+// failed at once don't retry in lockstep.
+func backoff(attempt int) time.Duration {
+	base := 200 * time.Millisecond
+	for i := 0; i < attempt-1; i++ {
+		base *= 2
+	}
+	if base > 10*time.Second {
+		base = 10 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base) * 3 / 10))
+	return base + jitter
+}
+
+// Sign computes the SignatureHeader value for body at t, HMAC-SHA256'd
+// with secret over "<unix-seconds>.<body>".
+func Sign(secret []byte, t time.Time, body []byte) string {
+	ts := t.Unix()
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.%s", ts, body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// VerifySignature checks that header is a valid SignatureHeader value for
+// body signed with secret, and that its timestamp is within ReplayWindow
+// of now. Used by POST /fleet/events to authenticate an agent's publish.
+func VerifySignature(secret []byte, header string, body []byte, now time.Time) error {
+	var ts int64
+	var sig string
+	if _, err := fmt.Sscanf(header, "t=%d,v1=%s", &ts, &sig); err != nil {
+		return fmt.Errorf("malformed signature header")
+	}
+
+	age := now.Sub(time.Unix(ts, 0))
+	if age < 0 {
+		age = -age
+	}
+	if age > ReplayWindow {
+		return fmt.Errorf("signature timestamp outside replay window")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.%s", ts, body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(sig)) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package fleet
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignVerifySignatureRoundTrip(t *testing.T) {
+	secret := []byte("shh")
+	body := []byte(`{"hello":"world"}`)
+	now := time.Now()
+
+	header := Sign(secret, now, body)
+	if err := VerifySignature(secret, header, body, now); err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+
+	if err := VerifySignature([]byte("wrong"), header, body, now); err == nil {
+		t.Fatal("expected error for wrong secret")
+	}
+	if err := VerifySignature(secret, header, []byte("tampered"), now); err == nil {
+		t.Fatal("expected error for tampered body")
+	}
+	if err := VerifySignature(secret, header, body, now.Add(ReplayWindow+time.Minute)); err == nil {
+		t.Fatal("expected error for stale signature")
+	}
+}
+
+func TestBusPublishDeliversToPeersOnly(t *testing.T) {
+	var received int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	mgr := NewManager()
+	mgr.SetMode("t1", Fleet)
+	mgr.AddAgent("t1", Agent{ID: "a1"})
+	mgr.AddAgent("t1", Agent{ID: "a2", WebhookURL: srv.URL})
+	mgr.AddAgent("t1", Agent{ID: "a3", WebhookURL: srv.URL})
+
+	// Outside the fleet entirely: must never receive a1's events.
+	mgr.SetMode("t2", Fleet)
+	mgr.AddAgent("t2", Agent{ID: "b1", WebhookURL: srv.URL})
+
+	bus := NewBus(mgr)
+	bus.sleep = func(time.Duration) {}
+
+	queued, err := bus.Publish("a1", Event{ID: "evt-1", Type: "ping"})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if queued != 2 {
+		t.Fatalf("expected 2 peers queued, got %d", queued)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&received) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&received); got != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", got)
+	}
+}
+
+func TestBusPublishUnknownAgent(t *testing.T) {
+	bus := NewBus(NewManager())
+	if _, err := bus.Publish("ghost", Event{ID: "evt-1", Type: "ping"}); err == nil {
+		t.Fatal("expected error for unknown agent")
+	}
+}
+
+func TestBusDeadLetterOnPermanentFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	mgr := NewManager()
+	mgr.SetMode("t1", Fleet)
+	mgr.AddAgent("t1", Agent{ID: "a1"})
+	mgr.AddAgent("t1", Agent{ID: "a2", WebhookURL: srv.URL})
+
+	bus := NewBus(mgr)
+	bus.sleep = func(time.Duration) {}
+
+	if _, err := bus.Publish("a1", Event{ID: "evt-1", Type: "ping"}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(bus.DeadLetters()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	letters := bus.DeadLetters()
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	if letters[0].ToAgentID != "a2" || letters[0].Attempts != maxDeliveryAttempts {
+		t.Fatalf("unexpected dead letter: %+v", letters[0])
+	}
+}
+
+func TestTenantSecret(t *testing.T) {
+	mgr := NewManager()
+	if got := mgr.TenantSecret("t1"); got != nil {
+		t.Fatalf("expected nil secret for unknown tenant, got %v", got)
+	}
+	mgr.SetTenantSecret("t1", []byte("s3cr3t"))
+	if string(mgr.TenantSecret("t1")) != "s3cr3t" {
+		t.Fatalf("expected secret to round-trip")
+	}
+}
@@ -0,0 +1,53 @@
+package fleet
+
+import (
+	"crypto/x509"
+
+	"github.com/Extra-Chill/plasma-shield/internal/agentca"
+)
+
+// ValidateAgentCert checks a TLS client certificate presented to the
+// forward proxy listener against the fleet's registered agents. It
+// implements proxy.CertRegistry, the mTLS counterpart to ValidateAgentIP:
+// unlike an IP, a certificate survives NAT and roaming, and (because the
+// listener's tls.Config.ClientCAs already verified the chain during the
+// handshake) can't be replayed by a different peer the way a shared
+// bearer token can.
+//
+// The certificate's own chain-of-trust is the listener's job (see
+// tls.Config.ClientAuth); this only resolves the agentca-encoded identity
+// to a tenant's registered agent and looks up its tier. Returns empty
+// strings if cert doesn't carry a recognized identity, or the identity
+// doesn't match a currently registered agent.
+func (m *Manager) ValidateAgentCert(cert *x509.Certificate) (agentID string, tier string, valid bool) {
+	identity, err := agentca.IdentityFromCertificate(cert)
+	if err != nil {
+		return "", "", false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenantID, ok := m.agentToTenant[identity.AgentID]
+	if !ok || tenantID != identity.TenantID {
+		return "", "", false
+	}
+
+	tenant := m.tenants[tenantID]
+	if tenant == nil {
+		return "", "", false
+	}
+	agent, ok := tenant.Agents[identity.AgentID]
+	if !ok {
+		return "", "", false
+	}
+
+	tier = agent.Tier
+	if tier == "" {
+		tier = identity.Tier
+	}
+	if tier == "" {
+		tier = "crew"
+	}
+	return identity.AgentID, tier, true
+}
@@ -0,0 +1,107 @@
+package fleet
+
+import (
+	"crypto/x509"
+	"net/url"
+	"testing"
+)
+
+// identityCert builds an *x509.Certificate carrying the same
+// spiffe://plasma/tenant/{t}/agent/{a}/tier/{tier} SAN URI agentca.
+// IssueCertificate would encode, without going through a real CA --
+// ValidateAgentCert only reads cert.URIs, so no signature is needed.
+func identityCert(t *testing.T, tenantID, agentID, tier string) *x509.Certificate {
+	t.Helper()
+	raw := "spiffe://plasma/tenant/" + tenantID + "/agent/" + agentID + "/tier/" + tier
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse identity URI: %v", err)
+	}
+	return &x509.Certificate{URIs: []*url.URL{u}}
+}
+
+func TestValidateAgentCert(t *testing.T) {
+	mgr := NewManager()
+	mgr.CreateTenant("test-tenant")
+	mgr.AddAgent("test-tenant", Agent{
+		ID:   "agent-1",
+		Tier: "crew",
+	})
+	mgr.AddAgent("test-tenant", Agent{
+		ID:   "agent-2",
+		Tier: "commodore",
+	})
+
+	tests := []struct {
+		name      string
+		cert      *x509.Certificate
+		wantID    string
+		wantTier  string
+		wantValid bool
+	}{
+		{
+			name:      "registered crew agent",
+			cert:      identityCert(t, "test-tenant", "agent-1", "crew"),
+			wantID:    "agent-1",
+			wantTier:  "crew",
+			wantValid: true,
+		},
+		{
+			name:      "registered commodore agent",
+			cert:      identityCert(t, "test-tenant", "agent-2", "commodore"),
+			wantID:    "agent-2",
+			wantTier:  "commodore",
+			wantValid: true,
+		},
+		{
+			name:      "unregistered agent id",
+			cert:      identityCert(t, "test-tenant", "agent-3", "crew"),
+			wantID:    "",
+			wantTier:  "",
+			wantValid: false,
+		},
+		{
+			name:      "tenant mismatch",
+			cert:      identityCert(t, "other-tenant", "agent-1", "crew"),
+			wantID:    "",
+			wantTier:  "",
+			wantValid: false,
+		},
+		{
+			name:      "no identity URI",
+			cert:      &x509.Certificate{},
+			wantID:    "",
+			wantTier:  "",
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, tier, valid := mgr.ValidateAgentCert(tt.cert)
+			if id != tt.wantID {
+				t.Errorf("id = %q, want %q", id, tt.wantID)
+			}
+			if tier != tt.wantTier {
+				t.Errorf("tier = %q, want %q", tier, tt.wantTier)
+			}
+			if valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v", valid, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestValidateAgentCert_DefaultsToCertTierWhenUnset(t *testing.T) {
+	mgr := NewManager()
+	mgr.CreateTenant("test")
+	mgr.AddAgent("test", Agent{ID: "agent-1"})
+
+	_, tier, valid := mgr.ValidateAgentCert(identityCert(t, "test", "agent-1", "captain"))
+	if !valid {
+		t.Fatal("expected valid")
+	}
+	if tier != "captain" {
+		t.Errorf("expected cert-carried tier 'captain', got %q", tier)
+	}
+}
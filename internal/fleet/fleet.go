@@ -3,6 +3,8 @@ package fleet
 
 import (
 	"sync"
+
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
 )
 
 // Mode represents the fleet communication mode.
@@ -15,14 +17,45 @@ const (
 	Fleet Mode = "fleet"
 )
 
+// TLSMode controls how the reverse proxy handles TLS for inbound traffic
+// destined for an agent.
+type TLSMode string
+
+const (
+	// TLSPassthrough forwards encrypted bytes unmodified (default); identity
+	// masking headers cannot be injected since the payload is opaque.
+	TLSPassthrough TLSMode = "passthrough"
+	// TLSTerminate terminates TLS at the shield using the tenant/agent's SNI
+	// certificate, masks identity, then re-encrypts (or not) to the agent.
+	TLSTerminate TLSMode = "terminate"
+	// TLSReencrypt terminates inbound TLS and re-encrypts a fresh TLS
+	// connection to the agent's backend.
+	TLSReencrypt TLSMode = "reencrypt"
+)
+
 // Agent represents an agent in a fleet.
 type Agent struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	IP          string `json:"ip,omitempty"`
-	WebhookURL  string `json:"webhook_url,omitempty"`
-	Tier        string `json:"tier,omitempty"` // "commodore", "captain", "crew"
-	Description string `json:"description,omitempty"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	IP          string  `json:"ip,omitempty"`
+	WebhookURL  string  `json:"webhook_url,omitempty"`
+	Tier        string  `json:"tier,omitempty"` // "commodore", "captain", "crew"
+	Description string  `json:"description,omitempty"`
+	TLSMode     TLSMode `json:"tls_mode,omitempty"` // passthrough, terminate, reencrypt (default: passthrough)
+
+	// BasicUser and BasicPassHash are this agent's Proxy-Authorization:
+	// Basic credentials (see ValidateAgentBasic), for agents sharing a
+	// NAT/egress IP that ValidateAgentIP can't tell apart.
+	// BasicPassHash is bcrypt, never the plaintext password.
+	BasicUser     string `json:"basic_user,omitempty"`
+	BasicPassHash string `json:"basic_pass_hash,omitempty"`
+
+	// BearerTokenHash is the bcrypt hash of this agent's Proxy-Authorization:
+	// Bearer credential (see ValidateAgentBearer). The token itself must be
+	// formatted "<agent ID>:<secret>", the same "id:secret" shape
+	// api.bcryptFileAuth uses, so the agent can be looked up before the
+	// secret half is bcrypt-compared.
+	BearerTokenHash string `json:"bearer_token_hash,omitempty"`
 }
 
 // Tenant represents a customer/user with their fleet configuration.
@@ -30,6 +63,15 @@ type Tenant struct {
 	ID     string           `json:"id"`
 	Mode   Mode             `json:"mode"`
 	Agents map[string]Agent `json:"agents"`
+	// Secret is the shared secret used to sign and verify Bus event
+	// deliveries for this tenant's agents (see Bus.SetTenantSecret).
+	// Never serialized: it's set separately via SetTenantSecret, not
+	// loaded from the same config struct as the rest of Tenant.
+	Secret []byte `json:"-"`
+	// CaptainName is the display name the reverse proxy substitutes for
+	// this tenant's ID in the X-Captain header when masking a request's
+	// identity (see ReverseHandler.ServeHTTP). Set via SetCaptainName.
+	CaptainName string `json:"captain_name,omitempty"`
 }
 
 // Manager handles fleet configuration and inter-agent communication rules.
@@ -40,14 +82,60 @@ type Manager struct {
 	agentToTenant map[string]string
 	// ipToAgent maps IP addresses to agent info for fast validation
 	ipToAgent map[string]*Agent
+	// basicUserToAgent maps Proxy-Authorization: Basic usernames to agent
+	// info for ValidateAgentBasic.
+	basicUserToAgent map[string]*Agent
+	// configPath is the file Reload re-reads, set by LoadAndApply.
+	configPath string
+	// metrics is wired by SetMetrics and refreshed by ReportFleetGauges.
+	metrics *limiter.Metrics
 }
 
 // NewManager creates a new fleet manager.
 func NewManager() *Manager {
 	return &Manager{
-		tenants:       make(map[string]*Tenant),
-		agentToTenant: make(map[string]string),
-		ipToAgent:     make(map[string]*Agent),
+		tenants:          make(map[string]*Tenant),
+		agentToTenant:    make(map[string]string),
+		ipToAgent:        make(map[string]*Agent),
+		basicUserToAgent: make(map[string]*Agent),
+	}
+}
+
+// SetMetrics wires a Prometheus-style metrics registry that
+// ReportFleetGauges refreshes plasma_fleet_agents into. Mirrors
+// bastion.GrantStore.SetMetrics.
+func (m *Manager) SetMetrics(metrics *limiter.Metrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics = metrics
+}
+
+// ReportFleetGauges recomputes plasma_fleet_agents{tenant,tier} from the
+// manager's current state. Called at scrape time (see cmd/gateway's
+// /metrics handler) rather than threaded through every AddAgent/RemoveAgent
+// call, so the gauge can never drift out of sync with the fleets it
+// describes -- the same reasoning behind execMetrics.refreshGauges in
+// internal/api.
+func (m *Manager) ReportFleetGauges() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.metrics == nil {
+		return
+	}
+
+	m.metrics.ResetFleetAgents()
+	for tenantID, tenant := range m.tenants {
+		counts := make(map[string]int64)
+		for _, agent := range tenant.Agents {
+			tier := agent.Tier
+			if tier == "" {
+				tier = "crew"
+			}
+			counts[tier]++
+		}
+		for tier, count := range counts {
+			m.metrics.SetFleetAgents(tenantID, tier, count)
+		}
 	}
 }
 
@@ -124,6 +212,67 @@ func (m *Manager) SetMode(tenantID string, mode Mode) {
 	}
 }
 
+// SetConfigPath records path as the file Reload re-reads. LoadAndApply
+// calls this itself; a caller that instead drives LoadConfig/ApplyConfig
+// directly (e.g. to layer in extra reconciliation) must call it too for
+// Reload to work.
+func (m *Manager) SetConfigPath(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configPath = path
+}
+
+// SetTenantSecret sets the shared secret a tenant's agents use to sign and
+// verify Bus event deliveries. Creates the tenant (in Isolated mode) if it
+// doesn't already exist, the same as SetMode.
+func (m *Manager) SetTenantSecret(tenantID string, secret []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenant, exists := m.tenants[tenantID]
+	if !exists {
+		tenant = &Tenant{
+			ID:     tenantID,
+			Mode:   Isolated,
+			Agents: make(map[string]Agent),
+		}
+		m.tenants[tenantID] = tenant
+	}
+	tenant.Secret = secret
+}
+
+// TenantSecret returns the shared secret set via SetTenantSecret for a
+// tenant, or nil if none is set (or the tenant doesn't exist).
+func (m *Manager) TenantSecret(tenantID string) []byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[tenantID]
+	if !exists {
+		return nil
+	}
+	return tenant.Secret
+}
+
+// SetCaptainName sets the display name a tenant's agents see in the
+// X-Captain identity-masking header. Creates the tenant (in Isolated
+// mode) if it doesn't already exist, the same as SetTenantSecret.
+func (m *Manager) SetCaptainName(tenantID string, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenant, exists := m.tenants[tenantID]
+	if !exists {
+		tenant = &Tenant{
+			ID:     tenantID,
+			Mode:   Isolated,
+			Agents: make(map[string]Agent),
+		}
+		m.tenants[tenantID] = tenant
+	}
+	tenant.CaptainName = name
+}
+
 // GetMode returns the fleet mode for a tenant.
 func (m *Manager) GetMode(tenantID string) Mode {
 	m.mu.RLock()
@@ -159,6 +308,12 @@ func (m *Manager) AddAgent(tenantID string, agent Agent) {
 		agentCopy := agent // Store copy so pointer remains valid
 		m.ipToAgent[agent.IP] = &agentCopy
 	}
+
+	// Add Basic auth username lookup if agent has one configured
+	if agent.BasicUser != "" {
+		agentCopy := agent
+		m.basicUserToAgent[agent.BasicUser] = &agentCopy
+	}
 }
 
 // RemoveAgent removes an agent from a tenant's fleet.
@@ -171,15 +326,61 @@ func (m *Manager) RemoveAgent(tenantID, agentID string) {
 		return
 	}
 
-	// Remove IP lookup
-	if agent, ok := tenant.Agents[agentID]; ok && agent.IP != "" {
-		delete(m.ipToAgent, agent.IP)
+	// Remove IP and Basic auth username lookups
+	if agent, ok := tenant.Agents[agentID]; ok {
+		if agent.IP != "" {
+			delete(m.ipToAgent, agent.IP)
+		}
+		if agent.BasicUser != "" {
+			delete(m.basicUserToAgent, agent.BasicUser)
+		}
 	}
 
 	delete(tenant.Agents, agentID)
 	delete(m.agentToTenant, agentID)
 }
 
+// RemoveTenant removes a tenant and every agent in its fleet, for
+// reconciling a hot-reloaded config that has dropped it entirely.
+func (m *Manager) RemoveTenant(tenantID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenant, exists := m.tenants[tenantID]
+	if !exists {
+		return
+	}
+	for agentID, agent := range tenant.Agents {
+		if agent.IP != "" {
+			delete(m.ipToAgent, agent.IP)
+		}
+		if agent.BasicUser != "" {
+			delete(m.basicUserToAgent, agent.BasicUser)
+		}
+		delete(m.agentToTenant, agentID)
+	}
+	delete(m.tenants, tenantID)
+}
+
+// TenantAgentIDs returns the IDs of every agent in a tenant's fleet,
+// regardless of fleet mode. Unlike GetAgents (which hides agents from
+// each other in Isolated mode), this is for reconciliation/diffing, not
+// for what an agent is allowed to see.
+func (m *Manager) TenantAgentIDs(tenantID string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	tenant, exists := m.tenants[tenantID]
+	if !exists {
+		return nil
+	}
+	ids := make([]string, 0, len(tenant.Agents))
+	for id := range tenant.Agents {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 // GetAgents returns all agents in a tenant's fleet.
 // In isolated mode, this returns an empty list (agents don't know about each other).
 func (m *Manager) GetAgents(tenantID string) []Agent {
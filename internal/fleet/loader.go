@@ -4,16 +4,20 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
 )
 
 var envVarRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
 
 // Config represents the shield configuration file.
 type Config struct {
-	Tenants []TenantConfig `yaml:"tenants"`
-	Tokens  []TokenConfig  `yaml:"tokens"`
+	Tenants      []TenantConfig      `yaml:"tenants"`
+	Tokens       []TokenConfig       `yaml:"tokens"`
+	Provisioners []ProvisionerConfig `yaml:"provisioners"`
 }
 
 // TenantConfig represents a tenant in the config file.
@@ -21,6 +25,10 @@ type TenantConfig struct {
 	ID     string        `yaml:"id"`
 	Mode   string        `yaml:"mode"` // "isolated" or "fleet"
 	Agents []AgentConfig `yaml:"agents"`
+	// Secret is the shared secret this tenant's agents use to sign and
+	// verify Bus event deliveries (see fleet.Bus). Empty disables
+	// signature verification on POST /fleet/events for this tenant.
+	Secret string `yaml:"secret"`
 }
 
 // AgentConfig represents an agent in the config file.
@@ -40,6 +48,22 @@ type TokenConfig struct {
 	Name     string `yaml:"name"` // Optional human-readable name
 }
 
+// ProvisionerConfig represents a bastion grant provisioner in the config
+// file. Type selects which concrete bastion.Provisioner is constructed
+// ("jwk", "oidc", or "x5c"); the remaining fields are interpreted
+// according to Type and wired up by cmd/api.
+type ProvisionerConfig struct {
+	Type              string   `yaml:"type"` // "jwk", "oidc", or "x5c"
+	Name              string   `yaml:"name"`
+	PublicKeyFile     string   `yaml:"public_key_file"`    // jwk
+	Issuer            string   `yaml:"issuer"`             // oidc
+	JWKSURL           string   `yaml:"jwks_url"`           // oidc
+	TrustBundleFile   string   `yaml:"trust_bundle_file"`  // x5c
+	AllowedPrincipals []string `yaml:"allowed_principals"` // oidc
+	AllowedTargets    []string `yaml:"allowed_targets"`    // oidc, x5c
+	MaxTTL            string   `yaml:"max_ttl"`
+}
+
 // LoadConfig loads fleet configuration from a YAML file.
 func LoadConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -65,8 +89,16 @@ func LoadConfig(path string) (*Config, error) {
 	return &config, nil
 }
 
-// ApplyConfig applies a config to a fleet manager.
-func ApplyConfig(mgr *Manager, config *Config) {
+// ApplyConfig applies a config to a fleet manager and, if store is
+// non-nil, reconciles it against persistent storage: every agent in
+// config is upserted, and any agent previously persisted but no longer
+// present in config is deleted. This makes repeated calls with the same
+// config idempotent and means the fleet survives a restart even before
+// the in-memory Manager is repopulated from config on the next boot.
+// store may be nil, in which case agents only ever live in mgr.
+func ApplyConfig(mgr *Manager, store storage.Store, config *Config) error {
+	desired := make(map[string]bool)
+
 	for _, tc := range config.Tenants {
 		// Create tenant
 		mgr.CreateTenant(tc.ID)
@@ -78,6 +110,10 @@ func ApplyConfig(mgr *Manager, config *Config) {
 			mgr.SetMode(tc.ID, Isolated)
 		}
 
+		if tc.Secret != "" {
+			mgr.SetTenantSecret(tc.ID, []byte(tc.Secret))
+		}
+
 		// Add agents
 		for _, ac := range tc.Agents {
 			tier := ac.Tier
@@ -93,16 +129,145 @@ func ApplyConfig(mgr *Manager, config *Config) {
 				Description: ac.Description,
 			}
 			mgr.AddAgent(tc.ID, agent)
+			desired[ac.ID] = true
+
+			if store != nil {
+				now := time.Now().UTC()
+				createdAt := now
+				if prev, err := store.GetAgent(ac.ID); err == nil {
+					createdAt = prev.CreatedAt
+				} else if err != storage.ErrNotFound {
+					return fmt.Errorf("reconcile agent %q: %w", ac.ID, err)
+				}
+				if err := store.UpsertAgent(storage.Agent{
+					ID:          ac.ID,
+					TenantID:    tc.ID,
+					Name:        ac.Name,
+					IP:          ac.IP,
+					WebhookURL:  ac.WebhookURL,
+					Tier:        tier,
+					Description: ac.Description,
+					Status:      "active",
+					CreatedAt:   createdAt,
+					UpdatedAt:   now,
+				}); err != nil {
+					return fmt.Errorf("reconcile agent %q: %w", ac.ID, err)
+				}
+			}
+		}
+	}
+
+	if store == nil {
+		return nil
+	}
+
+	existing, err := store.ListAgents()
+	if err != nil {
+		return fmt.Errorf("list agents for reconcile: %w", err)
+	}
+	for _, a := range existing {
+		if desired[a.ID] {
+			continue
+		}
+		if err := store.DeleteAgent(a.ID); err != nil {
+			return fmt.Errorf("reconcile: remove stale agent %q: %w", a.ID, err)
 		}
 	}
+	return nil
 }
 
-// LoadAndApply loads config from file and applies to manager.
-func LoadAndApply(mgr *Manager, path string) error {
+// LoadAndApply loads config from file and applies it to manager, optionally
+// reconciling against store (see ApplyConfig). store may be nil.
+func LoadAndApply(mgr *Manager, store storage.Store, path string) error {
 	config, err := LoadConfig(path)
 	if err != nil {
 		return err
 	}
-	ApplyConfig(mgr, config)
-	return nil
+	mgr.SetConfigPath(path)
+	return ApplyConfig(mgr, store, config)
+}
+
+// ConfigDiff summarizes what a Reload changed, for the caller to log.
+type ConfigDiff struct {
+	TenantsAdded   []string
+	TenantsRemoved []string
+	AgentsAdded    []string
+	AgentsRemoved  []string
+}
+
+// Empty reports whether the reload changed nothing.
+func (d ConfigDiff) Empty() bool {
+	return len(d.TenantsAdded) == 0 && len(d.TenantsRemoved) == 0 &&
+		len(d.AgentsAdded) == 0 && len(d.AgentsRemoved) == 0
+}
+
+// Reload re-reads the config file path set by the most recent LoadAndApply
+// and atomically reconciles it into the manager: tenants/agents present
+// in the new config are upserted, and any tenant or agent no longer
+// present is removed from both the manager and store. The previous state
+// is left untouched if the file is missing or fails to parse. store may
+// be nil. The parsed config is returned alongside the diff so a caller
+// that also manages tokens (see TokenConfig) doesn't need to re-read and
+// re-parse the file itself.
+func (m *Manager) Reload(store storage.Store) (*Config, ConfigDiff, error) {
+	m.mu.RLock()
+	path := m.configPath
+	m.mu.RUnlock()
+	if path == "" {
+		return nil, ConfigDiff{}, fmt.Errorf("fleet: no config path set; call LoadAndApply first")
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		return nil, ConfigDiff{}, err
+	}
+
+	before := make(map[string]map[string]bool, len(m.AllTenants()))
+	for _, tenantID := range m.AllTenants() {
+		ids := make(map[string]bool)
+		for _, agentID := range m.TenantAgentIDs(tenantID) {
+			ids[agentID] = true
+		}
+		before[tenantID] = ids
+	}
+
+	if err := ApplyConfig(m, store, config); err != nil {
+		return nil, ConfigDiff{}, err
+	}
+
+	after := make(map[string]bool, len(config.Tenants))
+	var diff ConfigDiff
+	for _, tc := range config.Tenants {
+		after[tc.ID] = true
+		oldAgents, existed := before[tc.ID]
+		if !existed {
+			diff.TenantsAdded = append(diff.TenantsAdded, tc.ID)
+		}
+		newAgents := make(map[string]bool, len(tc.Agents))
+		for _, ac := range tc.Agents {
+			newAgents[ac.ID] = true
+			if !oldAgents[ac.ID] {
+				diff.AgentsAdded = append(diff.AgentsAdded, ac.ID)
+			}
+		}
+		for agentID := range oldAgents {
+			if !newAgents[agentID] {
+				diff.AgentsRemoved = append(diff.AgentsRemoved, agentID)
+				m.RemoveAgent(tc.ID, agentID)
+			}
+		}
+	}
+
+	for tenantID, agents := range before {
+		if after[tenantID] {
+			continue
+		}
+		diff.TenantsRemoved = append(diff.TenantsRemoved, tenantID)
+		for agentID := range agents {
+			diff.AgentsRemoved = append(diff.AgentsRemoved, agentID)
+		}
+		m.RemoveTenant(tenantID)
+	}
+
+	return config, diff, nil
 }
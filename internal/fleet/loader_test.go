@@ -0,0 +1,88 @@
+package fleet
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Extra-Chill/plasma-shield/internal/storage"
+)
+
+func openTestStore(t *testing.T) storage.Store {
+	t.Helper()
+	store, err := storage.Open(filepath.Join(t.TempDir(), "shield.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestApplyConfigReconcilesAgents(t *testing.T) {
+	store := openTestStore(t)
+	mgr := NewManager()
+
+	config := &Config{
+		Tenants: []TenantConfig{
+			{
+				ID:   "tenant1",
+				Mode: "fleet",
+				Agents: []AgentConfig{
+					{ID: "agent-1", Name: "Agent 1", IP: "1.2.3.4"},
+					{ID: "agent-2", Name: "Agent 2", IP: "1.2.3.5"},
+				},
+			},
+		},
+	}
+
+	if err := ApplyConfig(mgr, store, config); err != nil {
+		t.Fatalf("apply config: %v", err)
+	}
+
+	list, err := store.ListAgents()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 persisted agents, got %d, err %v", len(list), err)
+	}
+
+	agent1, err := store.GetAgent("agent-1")
+	if err != nil {
+		t.Fatalf("get agent-1: %v", err)
+	}
+	createdAt := agent1.CreatedAt
+
+	// Reapplying with agent-2 dropped should delete it from storage, and
+	// should preserve agent-1's CreatedAt rather than resetting it.
+	config.Tenants[0].Agents = []AgentConfig{
+		{ID: "agent-1", Name: "Agent 1", IP: "1.2.3.4"},
+	}
+	if err := ApplyConfig(mgr, store, config); err != nil {
+		t.Fatalf("reapply config: %v", err)
+	}
+
+	if _, err := store.GetAgent("agent-2"); err != storage.ErrNotFound {
+		t.Fatalf("expected agent-2 to be removed from storage, got err %v", err)
+	}
+
+	agent1, err = store.GetAgent("agent-1")
+	if err != nil {
+		t.Fatalf("get agent-1 after reapply: %v", err)
+	}
+	if !agent1.CreatedAt.Equal(createdAt) {
+		t.Errorf("expected CreatedAt to be preserved across reconcile, got %v, want %v", agent1.CreatedAt, createdAt)
+	}
+}
+
+func TestApplyConfigNilStore(t *testing.T) {
+	mgr := NewManager()
+	config := &Config{
+		Tenants: []TenantConfig{
+			{ID: "tenant1", Agents: []AgentConfig{{ID: "agent-1", Name: "Agent 1"}}},
+		},
+	}
+
+	if err := ApplyConfig(mgr, nil, config); err != nil {
+		t.Fatalf("apply config with nil store: %v", err)
+	}
+	if mgr.GetTenantForAgent("agent-1") != "tenant1" {
+		t.Error("expected agent to be added to manager even without a store")
+	}
+}
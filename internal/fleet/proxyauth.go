@@ -0,0 +1,62 @@
+package fleet
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ValidateAgentBasic checks the decoded user/pass from a
+// Proxy-Authorization: Basic header against a registered agent's
+// BasicUser/BasicPassHash. It implements the Basic half of
+// proxy.Handler's RFC 7235 407 challenge, for agents sharing a NAT/egress
+// IP that ValidateAgentIP can't tell apart.
+func (m *Manager) ValidateAgentBasic(user, pass string) (agentID, tier string, valid bool) {
+	m.mu.RLock()
+	agent, ok := m.basicUserToAgent[user]
+	m.mu.RUnlock()
+	if !ok || agent.BasicPassHash == "" {
+		return "", "", false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(agent.BasicPassHash), []byte(pass)) != nil {
+		return "", "", false
+	}
+
+	tier = agent.Tier
+	if tier == "" {
+		tier = "crew"
+	}
+	return agent.ID, tier, true
+}
+
+// ValidateAgentBearer checks a Proxy-Authorization: Bearer token. The
+// token must be formatted "<agent ID>:<secret>", the same "id:secret"
+// shape api.bcryptFileAuth's operator tokens use, so the agent ID
+// selects which BearerTokenHash to bcrypt-compare against instead of
+// requiring a scan over every registered agent's hash.
+func (m *Manager) ValidateAgentBearer(token string) (agentID, tier string, valid bool) {
+	id, secret, ok := strings.Cut(token, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	m.mu.RLock()
+	tenantID, known := m.agentToTenant[id]
+	var agent Agent
+	if known {
+		agent, known = m.tenants[tenantID].Agents[id]
+	}
+	m.mu.RUnlock()
+	if !known || agent.BearerTokenHash == "" {
+		return "", "", false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(agent.BearerTokenHash), []byte(secret)) != nil {
+		return "", "", false
+	}
+
+	tier = agent.Tier
+	if tier == "" {
+		tier = "crew"
+	}
+	return agent.ID, tier, true
+}
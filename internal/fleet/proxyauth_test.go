@@ -0,0 +1,96 @@
+package fleet
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func mustHash(t *testing.T, secret string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("hash secret: %v", err)
+	}
+	return string(hash)
+}
+
+func TestValidateAgentBasic(t *testing.T) {
+	mgr := NewManager()
+	mgr.CreateTenant("test-tenant")
+	mgr.AddAgent("test-tenant", Agent{
+		ID:            "agent-1",
+		Tier:          "crew",
+		BasicUser:     "agent-1",
+		BasicPassHash: mustHash(t, "s3cret"),
+	})
+
+	tests := []struct {
+		name      string
+		user      string
+		pass      string
+		wantID    string
+		wantTier  string
+		wantValid bool
+	}{
+		{"correct credentials", "agent-1", "s3cret", "agent-1", "crew", true},
+		{"wrong password", "agent-1", "wrong", "", "", false},
+		{"unknown user", "agent-2", "s3cret", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, tier, valid := mgr.ValidateAgentBasic(tt.user, tt.pass)
+			if id != tt.wantID || tier != tt.wantTier || valid != tt.wantValid {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", id, tier, valid, tt.wantID, tt.wantTier, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestValidateAgentBasic_RemoveAgentClearsIndex(t *testing.T) {
+	mgr := NewManager()
+	mgr.CreateTenant("test-tenant")
+	mgr.AddAgent("test-tenant", Agent{
+		ID:            "agent-1",
+		BasicUser:     "agent-1",
+		BasicPassHash: mustHash(t, "s3cret"),
+	})
+	mgr.RemoveAgent("test-tenant", "agent-1")
+
+	if _, _, valid := mgr.ValidateAgentBasic("agent-1", "s3cret"); valid {
+		t.Error("expected credentials to be rejected after RemoveAgent")
+	}
+}
+
+func TestValidateAgentBearer(t *testing.T) {
+	mgr := NewManager()
+	mgr.CreateTenant("test-tenant")
+	mgr.AddAgent("test-tenant", Agent{
+		ID:              "agent-1",
+		Tier:            "commodore",
+		BearerTokenHash: mustHash(t, "s3cret"),
+	})
+
+	tests := []struct {
+		name      string
+		token     string
+		wantID    string
+		wantTier  string
+		wantValid bool
+	}{
+		{"correct token", "agent-1:s3cret", "agent-1", "commodore", true},
+		{"wrong secret", "agent-1:wrong", "", "", false},
+		{"unknown agent id", "agent-2:s3cret", "", "", false},
+		{"malformed token (no colon)", "s3cret", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, tier, valid := mgr.ValidateAgentBearer(tt.token)
+			if id != tt.wantID || tier != tt.wantTier || valid != tt.wantValid {
+				t.Errorf("got (%q, %q, %v), want (%q, %q, %v)", id, tier, valid, tt.wantID, tt.wantTier, tt.wantValid)
+			}
+		})
+	}
+}
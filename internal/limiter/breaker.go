@@ -0,0 +1,158 @@
+package limiter
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// BreakerState is the circuit breaker's current state for a given
+// (agent, upstream host) pair.
+type BreakerState int
+
+const (
+	// Closed lets all requests through and counts failures.
+	Closed BreakerState = iota
+	// Open short-circuits requests until the cooldown elapses.
+	Open
+	// HalfOpen lets requests through again to test recovery; the next
+	// RecordResult closes or re-opens the breaker. Requests are not limited
+	// to a single probe while half-open.
+	HalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerConfig controls when a breaker opens and how long it stays open.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive 5xx/dial errors within
+	// Window before the breaker opens.
+	FailureThreshold int
+	// Window bounds how long consecutive failures are tracked; a failure
+	// older than Window resets the streak instead of accumulating.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before half-opening to
+	// probe recovery.
+	Cooldown time.Duration
+}
+
+// DefaultBreakerConfig is a reasonable starting point: 5 consecutive
+// failures within 30s opens the breaker for 15s.
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		Window:           30 * time.Second,
+		Cooldown:         15 * time.Second,
+	}
+}
+
+type breakerEntry struct {
+	mu               sync.Mutex
+	state            BreakerState
+	consecutiveFails int
+	lastFailure      time.Time
+	openedAt         time.Time
+}
+
+// Breaker is a circuit breaker per (agent, upstream host), so one agent's
+// flaky backend doesn't need to affect another's.
+type Breaker struct {
+	mu      sync.Mutex
+	entries map[string]*breakerEntry
+	cfg     BreakerConfig
+	metrics *Metrics
+}
+
+// NewBreaker creates a Breaker. metrics may be nil to disable
+// instrumentation.
+func NewBreaker(cfg BreakerConfig, metrics *Metrics) *Breaker {
+	return &Breaker{
+		entries: make(map[string]*breakerEntry),
+		cfg:     cfg,
+		metrics: metrics,
+	}
+}
+
+func (b *Breaker) entryFor(agentID, upstreamHost string) *breakerEntry {
+	key := strings.Join([]string{agentID, upstreamHost}, "|")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.entries[key]
+	if !ok {
+		e = &breakerEntry{}
+		b.entries[key] = e
+	}
+	return e
+}
+
+// Allow reports whether a request to (agentID, upstreamHost) may proceed.
+// When it returns false, retryAfter is how long the caller should tell the
+// client to wait before retrying.
+func (b *Breaker) Allow(agentID, upstreamHost string) (allowed bool, retryAfter time.Duration) {
+	e := b.entryFor(agentID, upstreamHost)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state != Open {
+		return true, 0
+	}
+
+	elapsed := time.Since(e.openedAt)
+	if elapsed < b.cfg.Cooldown {
+		return false, b.cfg.Cooldown - elapsed
+	}
+
+	// Cooldown elapsed - half-open and let requests through until the next
+	// RecordResult decides the state.
+	e.state = HalfOpen
+	if b.metrics != nil {
+		b.metrics.SetBreakerState(agentID, upstreamHost, HalfOpen)
+	}
+	return true, 0
+}
+
+// RecordResult updates the breaker after an attempt to (agentID,
+// upstreamHost). success is false for a 5xx response or a dial/transport
+// error.
+func (b *Breaker) RecordResult(agentID, upstreamHost string, success bool) {
+	e := b.entryFor(agentID, upstreamHost)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if success {
+		e.consecutiveFails = 0
+		if e.state != Closed {
+			e.state = Closed
+			if b.metrics != nil {
+				b.metrics.SetBreakerState(agentID, upstreamHost, Closed)
+			}
+		}
+		return
+	}
+
+	now := time.Now()
+	if e.lastFailure.IsZero() || now.Sub(e.lastFailure) > b.cfg.Window {
+		e.consecutiveFails = 0
+	}
+	e.consecutiveFails++
+	e.lastFailure = now
+
+	// A failed probe while half-open re-opens the breaker immediately.
+	if e.state == HalfOpen || e.consecutiveFails >= b.cfg.FailureThreshold {
+		e.state = Open
+		e.openedAt = now
+		if b.metrics != nil {
+			b.metrics.SetBreakerState(agentID, upstreamHost, Open)
+		}
+	}
+}
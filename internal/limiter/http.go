@@ -0,0 +1,43 @@
+package limiter
+
+import "net/http"
+
+// MetricsHandler serves m in Prometheus text exposition format at
+// /metrics. If refresh is non-nil, it's called first on every scrape, for
+// gauges (like fleet.Manager's plasma_fleet_agents) that are recomputed
+// from their source of truth rather than kept in sync on every mutation.
+func MetricsHandler(m *Metrics, refresh func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if refresh != nil {
+			refresh()
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.WriteProm(w)
+	})
+}
+
+// HealthzHandler serves a liveness probe at /healthz. It always reports OK
+// once the process is up and serving; it does not check downstream agent
+// health.
+func HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+}
+
+// ReadyzHandler serves a readiness probe at /readyz: it reports OK only
+// once ready() returns true, so orchestrators hold traffic back until
+// startup-critical state (fleet config loaded, storage opened, ...) is in
+// place instead of routing to a process that will just reject requests.
+func ReadyzHandler(ready func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("not ready"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+}
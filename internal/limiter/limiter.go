@@ -0,0 +1,199 @@
+// Package limiter enforces per-tenant/per-agent rate limits and upstream
+// circuit breaking for the shield's forward and reverse proxies.
+package limiter
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitRule declares a token-bucket rate limit for traffic matching a
+// tier and/or destination domain, e.g. "crew tier: 5 rps to
+// api.openai.com". Declared alongside filtering rules so operators can
+// express both in the same YAML file.
+type RateLimitRule struct {
+	Tier   string  `yaml:"tier,omitempty"`   // empty = applies to all tiers
+	Domain string  `yaml:"domain,omitempty"` // empty = applies to all destinations
+	RPS    float64 `yaml:"rps"`
+	Burst  int     `yaml:"burst"`
+}
+
+// compiledRateLimitRule pairs a RateLimitRule with its compiled domain
+// matcher so Limiter.Allow doesn't recompile a regex per request.
+type compiledRateLimitRule struct {
+	rule          RateLimitRule
+	domainMatcher *regexp.Regexp // nil if rule.Domain == ""
+}
+
+// Direction distinguishes outbound (forward Handler) from inbound
+// (ReverseHandler) and exec-check (ExecCheckHandler) traffic, since a
+// tenant/agent may have different budgets in each.
+type Direction string
+
+const (
+	Outbound Direction = "outbound"
+	Inbound  Direction = "inbound"
+	Exec     Direction = "exec"
+)
+
+// bucket is a single token bucket, refilled lazily on each Allow call.
+type bucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	rps      float64
+	burst    float64
+	lastFill time.Time
+}
+
+func newBucket(rps float64, burst int, now time.Time) *bucket {
+	return &bucket{
+		tokens:   float64(burst),
+		rps:      rps,
+		burst:    float64(burst),
+		lastFill: now,
+	}
+}
+
+func (b *bucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elapsed := now.Sub(b.lastFill).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * b.rps
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.lastFill = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Limiter enforces token-bucket rate limits keyed by (tenant, agent,
+// direction). The RPS/burst applied to a given key is whichever
+// RateLimitRule first matches the request's tier and domain; a key with no
+// matching rule is unrestricted.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	rules   []compiledRateLimitRule
+	metrics *Metrics
+}
+
+// NewLimiter creates a Limiter from a set of YAML-declared rate limit
+// rules. metrics may be nil to disable instrumentation.
+func NewLimiter(rules []RateLimitRule, metrics *Metrics) (*Limiter, error) {
+	compiled := make([]compiledRateLimitRule, 0, len(rules))
+	for _, r := range rules {
+		cr := compiledRateLimitRule{rule: r}
+		if r.Domain != "" {
+			matcher, err := domainPatternToRegex(r.Domain)
+			if err != nil {
+				return nil, err
+			}
+			cr.domainMatcher = matcher
+		}
+		compiled = append(compiled, cr)
+	}
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		rules:   compiled,
+		metrics: metrics,
+	}, nil
+}
+
+// Allow reports whether a request from (tenant, agent) of the given tier,
+// destined for domain, may proceed in direction dir. It records a
+// plasma_ratelimit_dropped_total sample when the request is dropped.
+func (l *Limiter) Allow(tenant, agent, tier, domain string, dir Direction) bool {
+	rule, ok := l.matchRule(tier, domain)
+	if !ok {
+		return true
+	}
+
+	key := strings.Join([]string{tenant, agent, string(dir)}, "|")
+
+	l.mu.Lock()
+	b, exists := l.buckets[key]
+	if !exists {
+		b = newBucket(rule.RPS, rule.Burst, time.Now())
+		l.buckets[key] = b
+	}
+	l.mu.Unlock()
+
+	if b.allow(time.Now()) {
+		return true
+	}
+	if l.metrics != nil {
+		l.metrics.IncRateLimitDropped(tenant, agent, string(dir))
+	}
+	return false
+}
+
+// matchRule returns the first rule whose tier/domain constraints match,
+// treating an empty constraint as a wildcard.
+func (l *Limiter) matchRule(tier, domain string) (RateLimitRule, bool) {
+	for _, cr := range l.rules {
+		if cr.rule.Tier != "" && !strings.EqualFold(cr.rule.Tier, tier) {
+			continue
+		}
+		if cr.domainMatcher != nil && !cr.domainMatcher.MatchString(strings.ToLower(domain)) {
+			continue
+		}
+		return cr.rule, true
+	}
+	return RateLimitRule{}, false
+}
+
+// domainPatternToRegex compiles a rate-limit domain pattern the same way
+// the rules engine does: exact match, "*.example.com" subdomain wildcard,
+// or a general "*" wildcard.
+func domainPatternToRegex(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.ToLower(pattern)
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+
+	switch {
+	case strings.HasPrefix(pattern, "*."):
+		b.WriteString("([a-z0-9-]+\\.)*")
+		b.WriteString(escapeDomain(pattern[2:]))
+	case strings.Contains(pattern, "*"):
+		for i := 0; i < len(pattern); i++ {
+			switch c := pattern[i]; c {
+			case '*':
+				b.WriteString(".*")
+			case '.':
+				b.WriteString("\\.")
+			default:
+				b.WriteByte(c)
+			}
+		}
+	default:
+		b.WriteString(escapeDomain(pattern))
+	}
+
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func escapeDomain(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '.', '+', '?', '^', '$', '(', ')', '[', ']', '{', '}', '|', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
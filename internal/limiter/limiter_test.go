@@ -0,0 +1,94 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_AllowsWithinBurstThenDrops(t *testing.T) {
+	l, err := NewLimiter([]RateLimitRule{
+		{Tier: "crew", Domain: "api.openai.com", RPS: 1, Burst: 2},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	if !l.Allow("tenant1", "agent1", "crew", "api.openai.com", Outbound) {
+		t.Fatal("expected first request to be allowed")
+	}
+	if !l.Allow("tenant1", "agent1", "crew", "api.openai.com", Outbound) {
+		t.Fatal("expected second request (within burst) to be allowed")
+	}
+	if l.Allow("tenant1", "agent1", "crew", "api.openai.com", Outbound) {
+		t.Fatal("expected third request to be dropped (burst exhausted)")
+	}
+}
+
+func TestLimiter_UnmatchedRuleIsUnrestricted(t *testing.T) {
+	l, err := NewLimiter([]RateLimitRule{
+		{Tier: "crew", Domain: "api.openai.com", RPS: 1, Burst: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if !l.Allow("tenant1", "agent1", "commodore", "api.openai.com", Outbound) {
+			t.Fatal("expected unmatched tier to be unrestricted")
+		}
+	}
+}
+
+func TestLimiter_WildcardDomain(t *testing.T) {
+	l, err := NewLimiter([]RateLimitRule{
+		{Domain: "*.openai.com", RPS: 1, Burst: 1},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	if !l.Allow("tenant1", "agent1", "crew", "api.openai.com", Outbound) {
+		t.Fatal("expected subdomain to match wildcard")
+	}
+	if l.Allow("tenant1", "agent1", "crew", "api.openai.com", Outbound) {
+		t.Fatal("expected second request to be dropped (burst exhausted)")
+	}
+}
+
+func TestBreaker_OpensAfterThreshold(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 2, Window: time.Second, Cooldown: 50 * time.Millisecond}, nil)
+
+	if allowed, _ := b.Allow("agent1", "host1"); !allowed {
+		t.Fatal("expected breaker to start closed")
+	}
+
+	b.RecordResult("agent1", "host1", false)
+	if allowed, _ := b.Allow("agent1", "host1"); !allowed {
+		t.Fatal("expected breaker to stay closed below threshold")
+	}
+
+	b.RecordResult("agent1", "host1", false)
+	if allowed, retryAfter := b.Allow("agent1", "host1"); allowed || retryAfter <= 0 {
+		t.Fatalf("expected breaker to open after threshold, allowed=%v retryAfter=%v", allowed, retryAfter)
+	}
+}
+
+func TestBreaker_HalfOpensAfterCooldown(t *testing.T) {
+	b := NewBreaker(BreakerConfig{FailureThreshold: 1, Window: time.Second, Cooldown: 10 * time.Millisecond}, nil)
+
+	b.RecordResult("agent1", "host1", false)
+	if allowed, _ := b.Allow("agent1", "host1"); allowed {
+		t.Fatal("expected breaker to be open immediately after threshold failure")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	allowed, _ := b.Allow("agent1", "host1")
+	if !allowed {
+		t.Fatal("expected breaker to half-open and allow a probe after cooldown")
+	}
+
+	b.RecordResult("agent1", "host1", true)
+	if allowed, _ := b.Allow("agent1", "host1"); !allowed {
+		t.Fatal("expected breaker to close after a successful probe")
+	}
+}
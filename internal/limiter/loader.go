@@ -0,0 +1,36 @@
+package limiter
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rateLimitDocument is the shape rate limits are declared in: a
+// "rate_limits:" top-level key living in the same YAML file as the rules
+// engine's "rules:" key.
+type rateLimitDocument struct {
+	RateLimits []RateLimitRule `yaml:"rate_limits"`
+}
+
+// LoadRateLimitsFromFile reads the "rate_limits:" section of a rules YAML
+// file. Returns an empty slice (not an error) if the file has no such
+// section.
+func LoadRateLimitsFromFile(path string) ([]RateLimitRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit config: %w", err)
+	}
+	return LoadRateLimitsFromBytes(data)
+}
+
+// LoadRateLimitsFromBytes parses the "rate_limits:" section of rules YAML
+// bytes.
+func LoadRateLimitsFromBytes(data []byte) ([]RateLimitRule, error) {
+	var doc rateLimitDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limit YAML: %w", err)
+	}
+	return doc.RateLimits, nil
+}
@@ -0,0 +1,538 @@
+package limiter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metrics holds the shield's Prometheus counters/gauges. Implemented by
+// hand rather than pulling in the prometheus client library, the same way
+// internal/accesslog hand-rolls its OTLP exporter.
+type Metrics struct {
+	mu sync.Mutex
+
+	requestsTotal       map[requestLabels]int64
+	ratelimitDropped    map[rateLimitLabels]int64
+	breakerState        map[breakerLabels]BreakerState
+	ruleEvalDuration    map[directionLabel]*histogram
+	upstreamDuration    map[upstreamLabels]*histogram
+	tlsHandshakeFailure map[listenerLabel]int64
+	activeConnections   map[directionLabel]int64
+	activeSSHSessions   int64
+	grantEvents         map[grantEventLabel]int64
+	panics              map[componentLabel]int64
+
+	proxyRequestsTotal     map[proxyRequestLabels]int64
+	proxyRequestDuration   map[proxyDurationLabels]*histogram
+	bastionSessionsActive  int64
+	bastionSessionDuration *histogram
+	fleetAgents            map[fleetAgentLabels]int64
+}
+
+type requestLabels struct {
+	tenant, agent, direction, decision string
+}
+
+type rateLimitLabels struct {
+	tenant, agent, direction string
+}
+
+type breakerLabels struct {
+	agent, upstreamHost string
+}
+
+type directionLabel struct {
+	direction string
+}
+
+type upstreamLabels struct {
+	direction, agent string
+}
+
+type listenerLabel struct {
+	listener string
+}
+
+type grantEventLabel struct {
+	event string
+}
+
+type componentLabel struct {
+	component string
+}
+
+type proxyRequestLabels struct {
+	agent, tier, action, method string
+}
+
+type proxyDurationLabels struct {
+	tier, method string
+}
+
+type fleetAgentLabels struct {
+	tenant, tier string
+}
+
+// durationBuckets are the histogram bucket upper bounds (seconds) shared by
+// every latency histogram in this registry.
+var durationBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// proxyDurationBuckets are the bucket upper bounds (seconds) for
+// plasma_proxy_request_duration_seconds and plasma_bastion_session_duration_seconds,
+// matching the defaults Traefik and Consul Connect ship rather than this
+// registry's own durationBuckets.
+var proxyDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewMetrics creates an empty Metrics registry.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requestsTotal:        make(map[requestLabels]int64),
+		ratelimitDropped:     make(map[rateLimitLabels]int64),
+		breakerState:         make(map[breakerLabels]BreakerState),
+		ruleEvalDuration:     make(map[directionLabel]*histogram),
+		upstreamDuration:     make(map[upstreamLabels]*histogram),
+		tlsHandshakeFailure:  make(map[listenerLabel]int64),
+		activeConnections:    make(map[directionLabel]int64),
+		grantEvents:          make(map[grantEventLabel]int64),
+		panics:               make(map[componentLabel]int64),
+		proxyRequestsTotal:   make(map[proxyRequestLabels]int64),
+		proxyRequestDuration: make(map[proxyDurationLabels]*histogram),
+		fleetAgents:          make(map[fleetAgentLabels]int64),
+	}
+}
+
+// IncRequests increments plasma_requests_total for a (tenant, agent,
+// direction, decision) combination. decision is typically "forward",
+// "block", or "breaker_open".
+func (m *Metrics) IncRequests(tenant, agent, direction, decision string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requestsTotal[requestLabels{tenant, agent, direction, decision}]++
+}
+
+// IncRateLimitDropped increments plasma_ratelimit_dropped_total for a
+// (tenant, agent, direction) combination.
+func (m *Metrics) IncRateLimitDropped(tenant, agent, direction string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ratelimitDropped[rateLimitLabels{tenant, agent, direction}]++
+}
+
+// SetBreakerState records the current plasma_breaker_state gauge for an
+// (agent, upstream host) pair.
+func (m *Metrics) SetBreakerState(agentID, upstreamHost string, state BreakerState) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.breakerState[breakerLabels{agentID, upstreamHost}] = state
+}
+
+// ObserveRuleEval records how long a rules.Engine check took for a
+// direction ("inbound" or "outbound"), for plasma_rule_eval_duration_seconds.
+func (m *Metrics) ObserveRuleEval(direction string, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := directionLabel{direction}
+	h, ok := m.ruleEvalDuration[key]
+	if !ok {
+		h = newHistogram(durationBuckets)
+		m.ruleEvalDuration[key] = h
+	}
+	h.observe(seconds)
+}
+
+// ObserveUpstreamResponse records how long an upstream (agent backend or
+// outbound target) took to respond, for
+// plasma_upstream_response_duration_seconds.
+func (m *Metrics) ObserveUpstreamResponse(direction, agentID string, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := upstreamLabels{direction, agentID}
+	h, ok := m.upstreamDuration[key]
+	if !ok {
+		h = newHistogram(durationBuckets)
+		m.upstreamDuration[key] = h
+	}
+	h.observe(seconds)
+}
+
+// IncTLSHandshakeFailure increments plasma_tls_handshake_failures_total for
+// a listener ("inbound", e.g.).
+func (m *Metrics) IncTLSHandshakeFailure(listener string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tlsHandshakeFailure[listenerLabel{listener}]++
+}
+
+// IncActiveConnections increments the plasma_active_connections gauge for a
+// direction. Pair with DecActiveConnections via defer.
+func (m *Metrics) IncActiveConnections(direction string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.activeConnections[directionLabel{direction}]++
+}
+
+// DecActiveConnections decrements the plasma_active_connections gauge for a
+// direction.
+func (m *Metrics) DecActiveConnections(direction string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.activeConnections[directionLabel{direction}]--
+}
+
+// IncActiveSSHSessions increments the plasma_bastion_active_sessions gauge.
+// Pair with DecActiveSSHSessions via defer.
+func (m *Metrics) IncActiveSSHSessions() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.activeSSHSessions++
+}
+
+// DecActiveSSHSessions decrements the plasma_bastion_active_sessions gauge.
+func (m *Metrics) DecActiveSSHSessions() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.activeSSHSessions--
+}
+
+// AddGrantEvents increments plasma_bastion_grants_total for an event
+// ("issued" or "expired") by n, for batch reporting (e.g. GrantStore.Cleanup
+// removing several grants at once).
+func (m *Metrics) AddGrantEvents(event string, n int64) {
+	if n == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.grantEvents[grantEventLabel{event}] += n
+}
+
+// IncGrantEvent increments plasma_bastion_grants_total for a single event.
+func (m *Metrics) IncGrantEvent(event string) {
+	m.AddGrantEvents(event, 1)
+}
+
+// IncPanic increments plasma_shield_panics_total for component, the call
+// site that recovered (e.g. "proxy.forward", "reverse", "bastion.handleConn").
+// See middleware.Recover and bastion's safeGo, the two recovery points that
+// report here.
+func (m *Metrics) IncPanic(component string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.panics[componentLabel{component}]++
+}
+
+// IncProxyRequest increments plasma_proxy_requests_total for a (agent,
+// tier, action, method) combination, from the forward proxy's
+// Handler.ServeHTTP (see internal/proxy). action is the same
+// allow/block/audit/ratelimited/breaker_open/bad_gateway value reported to
+// IncRequests.
+func (m *Metrics) IncProxyRequest(agentID, tier, action, method string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.proxyRequestsTotal[proxyRequestLabels{agentID, tier, action, method}]++
+}
+
+// ObserveProxyRequestDuration records plasma_proxy_request_duration_seconds
+// for a (tier, method) combination, covering the full Handler.ServeHTTP
+// call rather than just the upstream round trip (see
+// ObserveUpstreamResponse for that).
+func (m *Metrics) ObserveProxyRequestDuration(tier, method string, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := proxyDurationLabels{tier, method}
+	h, ok := m.proxyRequestDuration[key]
+	if !ok {
+		h = newHistogram(proxyDurationBuckets)
+		m.proxyRequestDuration[key] = h
+	}
+	h.observe(seconds)
+}
+
+// IncBastionSessionActive increments the plasma_bastion_sessions_active
+// gauge. Pair with DecBastionSessionActive via defer. Unlike
+// IncActiveSSHSessions (one per accepted SSH connection), this tracks
+// proxied sessions reported by bastion.Logger.LogConnect/LogDisconnect --
+// a single SSH connection can open more than one direct-tcpip session.
+func (m *Metrics) IncBastionSessionActive() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bastionSessionsActive++
+}
+
+// DecBastionSessionActive decrements the plasma_bastion_sessions_active
+// gauge.
+func (m *Metrics) DecBastionSessionActive() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bastionSessionsActive--
+}
+
+// ObserveBastionSessionDuration records plasma_bastion_session_duration_seconds,
+// called by bastion.Logger.LogDisconnect with the elapsed time since the
+// matching LogConnect.
+func (m *Metrics) ObserveBastionSessionDuration(seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bastionSessionDuration == nil {
+		m.bastionSessionDuration = newHistogram(proxyDurationBuckets)
+	}
+	m.bastionSessionDuration.observe(seconds)
+}
+
+// ResetFleetAgents clears the plasma_fleet_agents gauge, for
+// fleet.Manager.ReportFleetGauges to recompute it from scratch on every
+// scrape rather than risk it drifting out of sync with incremental
+// AddAgent/RemoveAgent calls.
+func (m *Metrics) ResetFleetAgents() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fleetAgents = make(map[fleetAgentLabels]int64)
+}
+
+// SetFleetAgents sets the plasma_fleet_agents gauge for a (tenant, tier)
+// combination.
+func (m *Metrics) SetFleetAgents(tenantID, tier string, count int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fleetAgents[fleetAgentLabels{tenantID, tier}] = count
+}
+
+// WriteProm writes the registry in Prometheus text exposition format.
+func (m *Metrics) WriteProm(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := writeCounter(w, "plasma_requests_total", "Total proxied requests by outcome.", m.requestsByLine()); err != nil {
+		return err
+	}
+	if err := writeCounter(w, "plasma_ratelimit_dropped_total", "Requests dropped by the rate limiter.", m.ratelimitByLine()); err != nil {
+		return err
+	}
+	if err := writeGauge(w, "plasma_breaker_state", "Circuit breaker state (0=closed, 1=open, 2=half-open).", m.breakerByLine()); err != nil {
+		return err
+	}
+	if err := writeHistogram(w, "plasma_rule_eval_duration_seconds", "Time spent evaluating rules for a request.", m.ruleEvalByLine()); err != nil {
+		return err
+	}
+	if err := writeHistogram(w, "plasma_upstream_response_duration_seconds", "Time spent waiting for an upstream (agent or outbound target) to respond.", m.upstreamByLine()); err != nil {
+		return err
+	}
+	if err := writeCounter(w, "plasma_tls_handshake_failures_total", "Failed inbound TLS handshakes.", m.tlsHandshakeByLine()); err != nil {
+		return err
+	}
+	if err := writeGauge(w, "plasma_active_connections", "Active proxied connections by direction.", m.activeConnectionsByLine()); err != nil {
+		return err
+	}
+	if err := writeGauge(w, "plasma_bastion_active_sessions", "Active SSH bastion sessions.", []metricLine{{labels: "", value: float64(m.activeSSHSessions)}}); err != nil {
+		return err
+	}
+	if err := writeCounter(w, "plasma_bastion_grants_total", "Bastion access grants by event (issued, expired).", m.grantEventsByLine()); err != nil {
+		return err
+	}
+	if err := writeCounter(w, "plasma_shield_panics_total", "Panics recovered by component.", m.panicsByLine()); err != nil {
+		return err
+	}
+	if err := writeCounter(w, "plasma_proxy_requests_total", "Forward proxy requests by agent, tier, action, and method.", m.proxyRequestsByLine()); err != nil {
+		return err
+	}
+	if err := writeHistogram(w, "plasma_proxy_request_duration_seconds", "Time spent handling a forward proxy request end to end.", m.proxyRequestDurationByLine()); err != nil {
+		return err
+	}
+	if err := writeGauge(w, "plasma_bastion_sessions_active", "Active bastion proxied sessions.", []metricLine{{labels: "", value: float64(m.bastionSessionsActive)}}); err != nil {
+		return err
+	}
+	if m.bastionSessionDuration != nil {
+		if err := writeHistogram(w, "plasma_bastion_session_duration_seconds", "Bastion session duration from connect to disconnect.", map[string]*histogram{"": m.bastionSessionDuration}); err != nil {
+			return err
+		}
+	}
+	return writeGauge(w, "plasma_fleet_agents", "Registered fleet agents by tenant and tier.", m.fleetAgentsByLine())
+}
+
+func (m *Metrics) requestsByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.requestsTotal))
+	for k, v := range m.requestsTotal {
+		labels := fmt.Sprintf(`tenant="%s",agent="%s",direction="%s",decision="%s"`, k.tenant, k.agent, k.direction, k.decision)
+		lines = append(lines, metricLine{labels: labels, value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) ratelimitByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.ratelimitDropped))
+	for k, v := range m.ratelimitDropped {
+		labels := fmt.Sprintf(`tenant="%s",agent="%s",direction="%s"`, k.tenant, k.agent, k.direction)
+		lines = append(lines, metricLine{labels: labels, value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) breakerByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.breakerState))
+	for k, v := range m.breakerState {
+		labels := fmt.Sprintf(`agent="%s",upstream_host="%s"`, k.agent, k.upstreamHost)
+		lines = append(lines, metricLine{labels: labels, value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) ruleEvalByLine() map[string]*histogram {
+	out := make(map[string]*histogram, len(m.ruleEvalDuration))
+	for k, v := range m.ruleEvalDuration {
+		out[fmt.Sprintf(`direction="%s"`, k.direction)] = v
+	}
+	return out
+}
+
+func (m *Metrics) upstreamByLine() map[string]*histogram {
+	out := make(map[string]*histogram, len(m.upstreamDuration))
+	for k, v := range m.upstreamDuration {
+		out[fmt.Sprintf(`direction="%s",agent="%s"`, k.direction, k.agent)] = v
+	}
+	return out
+}
+
+func (m *Metrics) tlsHandshakeByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.tlsHandshakeFailure))
+	for k, v := range m.tlsHandshakeFailure {
+		lines = append(lines, metricLine{labels: fmt.Sprintf(`listener="%s"`, k.listener), value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) activeConnectionsByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.activeConnections))
+	for k, v := range m.activeConnections {
+		lines = append(lines, metricLine{labels: fmt.Sprintf(`direction="%s"`, k.direction), value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) grantEventsByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.grantEvents))
+	for k, v := range m.grantEvents {
+		lines = append(lines, metricLine{labels: fmt.Sprintf(`event="%s"`, k.event), value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) panicsByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.panics))
+	for k, v := range m.panics {
+		lines = append(lines, metricLine{labels: fmt.Sprintf(`component="%s"`, k.component), value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) proxyRequestsByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.proxyRequestsTotal))
+	for k, v := range m.proxyRequestsTotal {
+		labels := fmt.Sprintf(`agent_id="%s",tier="%s",action="%s",method="%s"`, k.agent, k.tier, k.action, k.method)
+		lines = append(lines, metricLine{labels: labels, value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+func (m *Metrics) proxyRequestDurationByLine() map[string]*histogram {
+	out := make(map[string]*histogram, len(m.proxyRequestDuration))
+	for k, v := range m.proxyRequestDuration {
+		out[fmt.Sprintf(`tier="%s",method="%s"`, k.tier, k.method)] = v
+	}
+	return out
+}
+
+func (m *Metrics) fleetAgentsByLine() []metricLine {
+	lines := make([]metricLine, 0, len(m.fleetAgents))
+	for k, v := range m.fleetAgents {
+		labels := fmt.Sprintf(`tenant="%s",tier="%s"`, k.tenant, k.tier)
+		lines = append(lines, metricLine{labels: labels, value: float64(v)})
+	}
+	return sortedLines(lines)
+}
+
+type metricLine struct {
+	labels string
+	value  float64
+}
+
+// histogram is a fixed-bucket Prometheus-style histogram: counts per
+// bucket upper bound plus a running sum and count, enough to compute
+// quantiles downstream without keeping raw samples.
+type histogram struct {
+	buckets []float64
+	counts  []int64 // counts[i] = number of observations <= buckets[i]
+	sum     float64
+	count   int64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]int64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// writeHistogram writes a set of labeled histograms in Prometheus text
+// exposition format: one "_bucket" line per bucket (cumulative, plus a
+// "+Inf" bucket), then "_sum" and "_count".
+func writeHistogram(w io.Writer, name, help string, byLabels map[string]*histogram) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&b, "# TYPE %s histogram\n", name)
+
+	labelSet := make([]string, 0, len(byLabels))
+	for labels := range byLabels {
+		labelSet = append(labelSet, labels)
+	}
+	sort.Strings(labelSet)
+
+	for _, labels := range labelSet {
+		h := byLabels[labels]
+		prefix := labels
+		if prefix != "" {
+			prefix += ","
+		}
+		for i, bound := range h.buckets {
+			fmt.Fprintf(&b, "%s_bucket{%sle=\"%v\"} %d\n", name, prefix, bound, h.counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{%sle=\"+Inf\"} %d\n", name, prefix, h.count)
+		fmt.Fprintf(&b, "%s_sum{%s} %v\n", name, labels, h.sum)
+		fmt.Fprintf(&b, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedLines(lines []metricLine) []metricLine {
+	sort.Slice(lines, func(i, j int) bool { return lines[i].labels < lines[j].labels })
+	return lines
+}
+
+func writeCounter(w io.Writer, name, help string, lines []metricLine) error {
+	return writeMetric(w, name, help, "counter", lines)
+}
+
+func writeGauge(w io.Writer, name, help string, lines []metricLine) error {
+	return writeMetric(w, name, help, "gauge", lines)
+}
+
+func writeMetric(w io.Writer, name, help, metricType string, lines []metricLine) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&b, "# TYPE %s %s\n", name, metricType)
+	for _, l := range lines {
+		fmt.Fprintf(&b, "%s{%s} %v\n", name, l.labels, l.value)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,134 @@
+// Package logs implements a small ring-buffer-backed publish/subscribe
+// broker for the SSE "tail" endpoints (GET /logs/stream, GET
+// /bastion/sessions/{id}/tail): every published record is kept in a bounded
+// history buffer so a newly-connecting client can replay recent activity
+// via Since, then fan out live as it's published, mirroring the pattern
+// Consul uses for agent/monitor.
+package logs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// subscriberBufferSize bounds how far a slow subscriber can lag before new
+// records are dropped for it rather than blocking Publish.
+const subscriberBufferSize = 64
+
+// Record is one entry published through a Broker. Payload is whatever the
+// caller published (already JSON-marshalable); Timestamp is used only for
+// Since replay, not re-rendered to subscribers.
+type Record struct {
+	Timestamp time.Time
+	Payload   interface{}
+}
+
+// Broker fans published records out to live subscribers and retains a
+// bounded ring buffer of recent ones for replay.
+type Broker struct {
+	mu          sync.Mutex
+	capacity    int
+	buf         []Record
+	subscribers map[int]*subscriberState
+	nextID      int
+}
+
+type subscriberState struct {
+	ch      chan Record
+	dropped uint64
+}
+
+// NewBroker creates a Broker retaining up to capacity records for replay.
+func NewBroker(capacity int) *Broker {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Broker{
+		capacity:    capacity,
+		subscribers: make(map[int]*subscriberState),
+	}
+}
+
+// Publish appends payload (timestamped ts) to the replay buffer and fans it
+// out to every live subscriber. A subscriber that isn't keeping up has the
+// record dropped for it rather than blocking the publisher; Subscription.Dropped
+// reports how many it has missed.
+func (b *Broker) Publish(payload interface{}, ts time.Time) {
+	rec := Record{Timestamp: ts, Payload: payload}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, rec)
+	if len(b.buf) > b.capacity {
+		b.buf = b.buf[len(b.buf)-b.capacity:]
+	}
+
+	for _, sub := range b.subscribers {
+		select {
+		case sub.ch <- rec:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Since returns buffered records published strictly after ts, oldest first.
+// A zero ts returns nothing, so callers that omit ?since= get only the live
+// tail, matching the pre-broker /logs/stream behavior.
+func (b *Broker) Since(ts time.Time) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ts.IsZero() {
+		return nil
+	}
+	out := make([]Record, 0, len(b.buf))
+	for _, r := range b.buf {
+		if r.Timestamp.After(ts) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Subscription is a live feed of records published after it was created,
+// returned by Broker.Subscribe. Callers must call Close (typically via
+// defer) once they stop reading, or the subscriber channel leaks.
+type Subscription struct {
+	broker *Broker
+	id     int
+	state  *subscriberState
+}
+
+// Subscribe registers a new live subscriber.
+func (b *Broker) Subscribe() *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	state := &subscriberState{ch: make(chan Record, subscriberBufferSize)}
+	b.subscribers[id] = state
+
+	return &Subscription{broker: b, id: id, state: state}
+}
+
+// Events returns the channel of live records for this subscription.
+func (s *Subscription) Events() <-chan Record {
+	return s.state.ch
+}
+
+// Dropped reports how many records have been dropped for this subscriber
+// because it fell behind.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.state.dropped)
+}
+
+// Close unregisters the subscription. Safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	delete(s.broker.subscribers, s.id)
+}
@@ -0,0 +1,92 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrokerPublishAndSubscribe(t *testing.T) {
+	b := NewBroker(10)
+	sub := b.Subscribe()
+	defer sub.Close()
+
+	b.Publish("hello", time.Now())
+
+	select {
+	case rec := <-sub.Events():
+		if rec.Payload != "hello" {
+			t.Fatalf("expected payload %q, got %v", "hello", rec.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for published record")
+	}
+}
+
+func TestBrokerSinceReplaysOnlyAfterTimestamp(t *testing.T) {
+	b := NewBroker(10)
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b.Publish("one", t0)
+	b.Publish("two", t0.Add(time.Second))
+	b.Publish("three", t0.Add(2*time.Second))
+
+	replayed := b.Since(t0)
+	if len(replayed) != 2 {
+		t.Fatalf("expected 2 records after t0, got %d", len(replayed))
+	}
+	if replayed[0].Payload != "two" || replayed[1].Payload != "three" {
+		t.Fatalf("unexpected replay order: %+v", replayed)
+	}
+}
+
+func TestBrokerSinceZeroReturnsNothing(t *testing.T) {
+	b := NewBroker(10)
+	b.Publish("one", time.Now())
+
+	if replayed := b.Since(time.Time{}); len(replayed) != 0 {
+		t.Fatalf("expected no replay for a zero timestamp, got %d records", len(replayed))
+	}
+}
+
+func TestBrokerSinceRespectsCapacity(t *testing.T) {
+	b := NewBroker(2)
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b.Publish("one", t0)
+	b.Publish("two", t0.Add(time.Second))
+	b.Publish("three", t0.Add(2*time.Second))
+
+	replayed := b.Since(time.Time{}.Add(time.Nanosecond))
+	if len(replayed) != 2 {
+		t.Fatalf("expected only the last 2 records retained, got %d", len(replayed))
+	}
+	if replayed[0].Payload != "two" || replayed[1].Payload != "three" {
+		t.Fatalf("expected the oldest record to have been evicted, got %+v", replayed)
+	}
+}
+
+func TestBrokerDropsForSlowSubscriber(t *testing.T) {
+	b := NewBroker(10)
+	sub := b.Subscribe()
+	defer sub.Close()
+
+	// Fill the subscriber's buffer without draining it, then publish one
+	// more: that one should be dropped and counted rather than blocking.
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		b.Publish(i, time.Now())
+	}
+
+	if dropped := sub.Dropped(); dropped == 0 {
+		t.Error("expected at least one dropped record for a subscriber that never drains")
+	}
+}
+
+func TestBrokerCloseUnsubscribes(t *testing.T) {
+	b := NewBroker(10)
+	sub := b.Subscribe()
+	sub.Close()
+
+	if len(b.subscribers) != 0 {
+		t.Fatalf("expected 0 subscribers after Close, got %d", len(b.subscribers))
+	}
+}
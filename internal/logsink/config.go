@@ -0,0 +1,131 @@
+package logsink
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the on-disk YAML shape loaded by Load: a named list of sink
+// configs, each tagged by type, built into one MultiSink.
+type Config struct {
+	// QueueCapacity bounds how many batches a single sink can fall behind
+	// by before it starts dropping. defaultQueueCapacity if <= 0.
+	QueueCapacity int          `yaml:"queue_capacity"`
+	Sinks         []SinkConfig `yaml:"sinks"`
+}
+
+// SinkConfig is one entry under Config.Sinks. Only the fields relevant to
+// Type need to be set; the rest are ignored.
+type SinkConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // "file", "syslog", "webhook", or "s3"
+
+	// file
+	Path    string        `yaml:"path,omitempty"`
+	MaxSize int64         `yaml:"max_size,omitempty"`
+	MaxAge  time.Duration `yaml:"max_age,omitempty"`
+
+	// syslog
+	Network  string `yaml:"network,omitempty"` // "udp", "tcp", or "tcp+tls"
+	Addr     string `yaml:"addr,omitempty"`
+	AppName  string `yaml:"app_name,omitempty"`
+	Facility int    `yaml:"facility,omitempty"`
+
+	// webhook
+	URL        string        `yaml:"url,omitempty"`
+	Secret     string        `yaml:"secret,omitempty"`
+	BatchSize  int           `yaml:"batch_size,omitempty"`
+	FlushEvery time.Duration `yaml:"flush_every,omitempty"`
+	MaxRetries int           `yaml:"max_retries,omitempty"`
+
+	// s3
+	Endpoint        string `yaml:"endpoint,omitempty"`
+	Bucket          string `yaml:"bucket,omitempty"`
+	Prefix          string `yaml:"prefix,omitempty"`
+	Region          string `yaml:"region,omitempty"`
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+}
+
+// Load reads and parses path, the -sinks YAML config file, and builds a
+// MultiSink from it.
+func Load(path string) (*MultiSink, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("logsink: read config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("logsink: parse config %s: %w", path, err)
+	}
+
+	sinks := make(map[string]Sink, len(cfg.Sinks))
+	for i, sc := range cfg.Sinks {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("logsink: sink %d: name is required", i)
+		}
+		if _, dup := sinks[sc.Name]; dup {
+			return nil, fmt.Errorf("logsink: duplicate sink name %q", sc.Name)
+		}
+		sink, err := buildSink(sc)
+		if err != nil {
+			return nil, fmt.Errorf("logsink: sink %q: %w", sc.Name, err)
+		}
+		sinks[sc.Name] = sink
+	}
+
+	return NewMultiSink(sinks, cfg.QueueCapacity), nil
+}
+
+func buildSink(sc SinkConfig) (Sink, error) {
+	switch sc.Type {
+	case "file":
+		if sc.Path == "" {
+			return nil, fmt.Errorf("file sink requires path")
+		}
+		return NewFileSink(sc.Path, WithFileMaxSize(sc.MaxSize), WithFileMaxAge(sc.MaxAge))
+
+	case "syslog":
+		if sc.Addr == "" {
+			return nil, fmt.Errorf("syslog sink requires addr")
+		}
+		return NewSyslogSink(SyslogConfig{
+			Network:  sc.Network,
+			Addr:     sc.Addr,
+			AppName:  sc.AppName,
+			Facility: sc.Facility,
+		})
+
+	case "webhook":
+		if sc.URL == "" {
+			return nil, fmt.Errorf("webhook sink requires url")
+		}
+		return NewWebhookSink(WebhookConfig{
+			URL:        sc.URL,
+			Secret:     []byte(sc.Secret),
+			BatchSize:  sc.BatchSize,
+			FlushEvery: sc.FlushEvery,
+			MaxRetries: sc.MaxRetries,
+		}), nil
+
+	case "s3":
+		if sc.Bucket == "" {
+			return nil, fmt.Errorf("s3 sink requires bucket")
+		}
+		return NewS3Sink(S3Config{
+			Endpoint:        sc.Endpoint,
+			Bucket:          sc.Bucket,
+			Prefix:          sc.Prefix,
+			Region:          sc.Region,
+			AccessKeyID:     sc.AccessKeyID,
+			SecretAccessKey: sc.SecretAccessKey,
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", sc.Type)
+	}
+}
@@ -0,0 +1,184 @@
+// Package logsink ships proxy.LogEntry records to durable destinations --
+// a local file, a syslog collector, a webhook, or an S3-compatible bucket
+// -- so the audit trail survives a restart instead of only living in
+// cmd/proxy's in-memory LogStore ring.
+package logsink
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+)
+
+// defaultQueueCapacity bounds how many pending batches a single sink's
+// worker can fall behind by before MultiSink starts dropping for it.
+const defaultQueueCapacity = 256
+
+// Sink is implemented by each log destination logsink knows how to write
+// to. Implementations must be safe for concurrent use; MultiSink only ever
+// calls a given Sink's methods from its own single worker goroutine, but a
+// Sink may also be used standalone.
+type Sink interface {
+	// Write delivers a batch of entries to the sink.
+	Write(ctx context.Context, entries []proxy.LogEntry) error
+	// Flush forces any buffered entries out immediately.
+	Flush() error
+	// Close flushes and releases the sink's resources.
+	Close() error
+}
+
+// SinkStats reports one configured sink's health for GET /sinks.
+type SinkStats struct {
+	Name        string    `json:"name"`
+	QueueDepth  int       `json:"queue_depth"`
+	QueueCap    int       `json:"queue_capacity"`
+	Dropped     uint64    `json:"dropped"`
+	BytesSent   uint64    `json:"bytes_sent"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastWriteAt time.Time `json:"last_write_at,omitempty"`
+}
+
+// namedSink pairs a configured Sink with its own bounded queue and worker
+// goroutine, so a slow or stuck destination (a flaky webhook, a stalled S3
+// PUT) only ever backs up its own queue.
+type namedSink struct {
+	name  string
+	sink  Sink
+	queue chan []proxy.LogEntry
+	done  chan struct{}
+
+	dropped   uint64 // atomic
+	bytesSent uint64 // atomic
+
+	mu          sync.Mutex
+	lastErr     error
+	lastWriteAt time.Time
+}
+
+// MultiSink fans Write calls out to a set of named Sinks, each behind its
+// own bounded queue and worker goroutine, and reports their health via
+// Stats for GET /sinks. It implements Sink itself, so cmd/proxy can treat
+// "one sink" and "all configured sinks" identically.
+type MultiSink struct {
+	sinks []*namedSink
+}
+
+// NewMultiSink starts one worker goroutine per entry in sinks, each
+// reading off a queue of capacity queueCapacity (defaultQueueCapacity if
+// queueCapacity <= 0). A sink whose queue fills up has the offending batch
+// dropped and counted (see Stats) rather than blocking Write or any other
+// sink's delivery.
+func NewMultiSink(sinks map[string]Sink, queueCapacity int) *MultiSink {
+	if queueCapacity <= 0 {
+		queueCapacity = defaultQueueCapacity
+	}
+	m := &MultiSink{sinks: make([]*namedSink, 0, len(sinks))}
+	for name, sink := range sinks {
+		ns := &namedSink{
+			name:  name,
+			sink:  sink,
+			queue: make(chan []proxy.LogEntry, queueCapacity),
+			done:  make(chan struct{}),
+		}
+		m.sinks = append(m.sinks, ns)
+		go ns.run()
+	}
+	return m
+}
+
+func (ns *namedSink) run() {
+	defer close(ns.done)
+	for batch := range ns.queue {
+		err := ns.sink.Write(context.Background(), batch)
+
+		ns.mu.Lock()
+		ns.lastErr = err
+		ns.lastWriteAt = time.Now()
+		ns.mu.Unlock()
+
+		if err != nil {
+			continue
+		}
+		atomic.AddUint64(&ns.bytesSent, uint64(entrySize(batch)))
+	}
+}
+
+// entrySize estimates the wire size of a batch for Stats().BytesSent.
+// Sinks encode entries in their own formats (NDJSON, RFC 5424, ...), so
+// this is a size estimate for observability, not a byte-exact accounting.
+func entrySize(entries []proxy.LogEntry) int {
+	n := 0
+	for _, e := range entries {
+		n += len(e.SourceIP) + len(e.AgentID) + len(e.Domain) + len(e.Method) + len(e.Action) + len(e.Reason) + 64
+	}
+	return n
+}
+
+// Write enqueues entries onto every sink's queue without blocking; a full
+// queue drops this batch for that sink and increments its Dropped counter
+// instead of stalling the caller (cmd/proxy's LogStore.Add, on the proxy's
+// request-handling path).
+func (m *MultiSink) Write(ctx context.Context, entries []proxy.LogEntry) error {
+	for _, ns := range m.sinks {
+		select {
+		case ns.queue <- entries:
+		default:
+			atomic.AddUint64(&ns.dropped, 1)
+		}
+	}
+	return nil
+}
+
+// Flush flushes every sink in turn, returning the first error encountered.
+func (m *MultiSink) Flush() error {
+	var firstErr error
+	for _, ns := range m.sinks {
+		if err := ns.sink.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close stops every sink's worker, waits for its queue to drain, and
+// closes the underlying Sink.
+func (m *MultiSink) Close() error {
+	var firstErr error
+	for _, ns := range m.sinks {
+		close(ns.queue)
+		<-ns.done
+		if err := ns.sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Stats reports every configured sink's current queue depth, drop count,
+// bytes written, and last error, for GET /sinks.
+func (m *MultiSink) Stats() []SinkStats {
+	out := make([]SinkStats, 0, len(m.sinks))
+	for _, ns := range m.sinks {
+		ns.mu.Lock()
+		lastErr := ""
+		if ns.lastErr != nil {
+			lastErr = ns.lastErr.Error()
+		}
+		lastWriteAt := ns.lastWriteAt
+		ns.mu.Unlock()
+
+		out = append(out, SinkStats{
+			Name:        ns.name,
+			QueueDepth:  len(ns.queue),
+			QueueCap:    cap(ns.queue),
+			Dropped:     atomic.LoadUint64(&ns.dropped),
+			BytesSent:   atomic.LoadUint64(&ns.bytesSent),
+			LastError:   lastErr,
+			LastWriteAt: lastWriteAt,
+		})
+	}
+	return out
+}
@@ -0,0 +1,168 @@
+package logsink
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+)
+
+// fakeSink is a test double recording every batch it receives, optionally
+// blocking writes until released so tests can exercise MultiSink's
+// backpressure-drop behavior.
+type fakeSink struct {
+	mu      sync.Mutex
+	batches [][]proxy.LogEntry
+	block   chan struct{}
+	closed  bool
+}
+
+func (f *fakeSink) Write(ctx context.Context, entries []proxy.LogEntry) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	f.batches = append(f.batches, entries)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeSink) Flush() error { return nil }
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeSink) batchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.batches)
+}
+
+func TestMultiSinkFansOutToEverySink(t *testing.T) {
+	a, b := &fakeSink{}, &fakeSink{}
+	m := NewMultiSink(map[string]Sink{"a": a, "b": b}, 10)
+	defer m.Close()
+
+	entries := []proxy.LogEntry{{Domain: "example.com", Action: "allow"}}
+	if err := m.Write(context.Background(), entries); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for a.batchCount() == 0 || b.batchCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for both sinks to receive the batch (a=%d b=%d)", a.batchCount(), b.batchCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMultiSinkDropsOnFullQueueWithoutBlocking(t *testing.T) {
+	slow := &fakeSink{block: make(chan struct{})}
+	m := NewMultiSink(map[string]Sink{"slow": slow}, 1)
+	defer func() {
+		close(slow.block)
+		m.Close()
+	}()
+
+	entries := []proxy.LogEntry{{Domain: "example.com"}}
+
+	// First Write is picked up by the worker and blocks inside Write;
+	// the next two fill and then overflow the capacity-1 queue.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			m.Write(context.Background(), entries)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MultiSink.Write blocked instead of dropping once the queue filled")
+	}
+
+	stats := m.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 sink stat, got %d", len(stats))
+	}
+	if stats[0].Dropped == 0 {
+		t.Error("expected at least one dropped batch once the queue filled")
+	}
+}
+
+func TestMultiSinkCloseClosesEverySink(t *testing.T) {
+	a := &fakeSink{}
+	m := NewMultiSink(map[string]Sink{"a": a}, 10)
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !a.closed {
+		t.Error("expected the underlying sink to be closed")
+	}
+}
+
+func TestLoadBuildsConfiguredSinks(t *testing.T) {
+	dir := t.TempDir()
+	logPath := dir + "/audit.ndjson"
+	cfgPath := dir + "/sinks.yaml"
+
+	cfg := "sinks:\n" +
+		"  - name: audit-file\n" +
+		"    type: file\n" +
+		"    path: " + logPath + "\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	m, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	defer m.Close()
+
+	stats := m.Stats()
+	if len(stats) != 1 || stats[0].Name != "audit-file" {
+		t.Fatalf("expected one sink named audit-file, got %+v", stats)
+	}
+}
+
+func TestLoadRejectsUnknownSinkType(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := dir + "/sinks.yaml"
+	cfg := "sinks:\n  - name: bogus\n    type: carrier-pigeon\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, err := Load(cfgPath)
+	if err == nil {
+		t.Fatal("expected an error for an unknown sink type")
+	}
+}
+
+func TestLoadRejectsDuplicateSinkNames(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := dir + "/sinks.yaml"
+	logPath := dir + "/audit.ndjson"
+	cfg := "sinks:\n" +
+		"  - name: dup\n    type: file\n    path: " + logPath + "\n" +
+		"  - name: dup\n    type: file\n    path: " + logPath + "\n"
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	_, err := Load(cfgPath)
+	if err == nil {
+		t.Fatal("expected an error for a duplicate sink name")
+	}
+}
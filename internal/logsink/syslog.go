@@ -0,0 +1,139 @@
+package logsink
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+)
+
+// SyslogConfig configures a SyslogSink.
+type SyslogConfig struct {
+	// Network is "udp", "tcp", or "tcp+tls".
+	Network string
+	// Addr is the collector's host:port.
+	Addr string
+	// AppName is the RFC 5424 APP-NAME field. Defaults to "plasma-shield".
+	AppName string
+	// Facility is the RFC 5424 facility number (0-23). Defaults to 1
+	// (user-level messages).
+	Facility int
+	// TLSConfig is used when Network is "tcp+tls". Nil uses Go's default
+	// verification against the system root pool.
+	TLSConfig *tls.Config
+}
+
+// SyslogSink writes each proxy.LogEntry as one RFC 5424 message to a
+// syslog collector, with its JSON encoding carried as the message body.
+type SyslogSink struct {
+	cfg      SyslogConfig
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink dials cfg.Addr over cfg.Network and returns a SyslogSink
+// ready to Write.
+func NewSyslogSink(cfg SyslogConfig) (*SyslogSink, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = "plasma-shield"
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = 1
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	s := &SyslogSink{cfg: cfg, hostname: hostname}
+	if err := s.dialLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) dialLocked() error {
+	var conn net.Conn
+	var err error
+	switch s.cfg.Network {
+	case "tcp+tls":
+		conn, err = tls.Dial("tcp", s.cfg.Addr, s.cfg.TLSConfig)
+	default:
+		conn, err = net.Dial(s.cfg.Network, s.cfg.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("logsink: dial syslog %s (%s): %w", s.cfg.Addr, s.cfg.Network, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Write implements Sink, sending each entry as its own RFC 5424 message. A
+// write that fails gets one reconnect attempt before the batch is aborted,
+// mirroring FileSink.rotateLocked's best-effort recovery.
+func (s *SyslogSink) Write(ctx context.Context, entries []proxy.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range entries {
+		msg, err := s.format(e)
+		if err != nil {
+			continue
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			if derr := s.dialLocked(); derr != nil {
+				return fmt.Errorf("logsink: write syslog: %w", err)
+			}
+			if _, err := s.conn.Write(msg); err != nil {
+				return fmt.Errorf("logsink: write syslog after reconnect: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// format renders e as an RFC 5424 message: block/inspect decisions map to
+// WARNING severity so collector-side alerting can filter on PRI alone.
+func (s *SyslogSink) format(e proxy.LogEntry) ([]byte, error) {
+	severity := 6 // informational
+	if e.Action == "block" {
+		severity = 4 // warning
+	}
+	pri := s.cfg.Facility*8 + severity
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s\n",
+		pri,
+		e.Timestamp.UTC().Format(time.RFC3339),
+		s.hostname,
+		s.cfg.AppName,
+		os.Getpid(),
+		body,
+	)
+	return []byte(msg), nil
+}
+
+// Flush is a no-op: every Write already sends its messages synchronously.
+func (s *SyslogSink) Flush() error { return nil }
+
+// Close closes the underlying connection.
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.Close()
+}
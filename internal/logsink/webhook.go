@@ -0,0 +1,163 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/proxy"
+)
+
+// WebhookConfig configures a WebhookSink.
+type WebhookConfig struct {
+	// URL is the collector's HTTPS endpoint.
+	URL string
+	// Secret HMAC-signs each batch; empty disables the signature header.
+	Secret []byte
+	// BatchSize flushes once this many entries have accumulated. Defaults
+	// to 100 if <= 0.
+	BatchSize int
+	// FlushEvery flushes on a timer regardless of BatchSize, so a quiet
+	// period doesn't leave entries buffered indefinitely. Defaults to 5s
+	// if <= 0.
+	FlushEvery time.Duration
+	// MaxRetries is how many additional attempts a failed POST gets,
+	// with exponential backoff between them. Defaults to 3 if < 0.
+	MaxRetries int
+}
+
+// WebhookSink batches entries and POSTs them as a JSON array to a
+// webhook, signing each batch with HMAC-SHA256 (X-Plasma-Signature) the
+// way GitHub signs its webhook deliveries, and retrying with exponential
+// backoff on failure.
+type WebhookSink struct {
+	cfg    WebhookConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []proxy.LogEntry
+
+	done chan struct{}
+}
+
+// NewWebhookSink creates a WebhookSink posting batches to cfg.URL.
+func NewWebhookSink(cfg WebhookConfig) *WebhookSink {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushEvery <= 0 {
+		cfg.FlushEvery = 5 * time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 3
+	}
+	s := &WebhookSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		done:   make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+// Write implements Sink, buffering entries until BatchSize is reached.
+func (s *WebhookSink) Write(ctx context.Context, entries []proxy.LogEntry) error {
+	s.mu.Lock()
+	s.pending = append(s.pending, entries...)
+	full := len(s.pending) >= s.cfg.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *WebhookSink) flushLoop() {
+	ticker := time.NewTicker(s.cfg.FlushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Flush POSTs any buffered entries now, retrying transient failures with
+// exponential backoff before giving up.
+func (s *WebhookSink) Flush() error {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return s.send(batch)
+}
+
+func (s *WebhookSink) send(batch []proxy.LogEntry) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("logsink: marshal webhook batch: %w", err)
+	}
+
+	var sig string
+	if len(s.cfg.Secret) > 0 {
+		mac := hmac.New(sha256.New, s.cfg.Secret)
+		mac.Write(body)
+		sig = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	backoff := 500 * time.Millisecond
+	var lastErr error
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("logsink: build webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if sig != "" {
+			req.Header.Set("X-Plasma-Signature", sig)
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("webhook returned %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("logsink: webhook returned unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("logsink: webhook giving up after %d attempt(s): %w", s.cfg.MaxRetries+1, lastErr)
+}
+
+// Close stops the flush timer and sends any remaining buffered entries.
+func (s *WebhookSink) Close() error {
+	close(s.done)
+	return s.Flush()
+}
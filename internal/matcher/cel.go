@@ -0,0 +1,67 @@
+package matcher
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// env is the fixed CEL environment every rule expression is type-checked
+// against: the command being checked, the agent that issued it, and its
+// fleet-config environment variables.
+var env = mustNewEnv()
+
+func mustNewEnv() *cel.Env {
+	e, err := cel.NewEnv(
+		cel.Variable("command", cel.StringType),
+		cel.Variable("agent_id", cel.StringType),
+		cel.Variable("env", cel.MapType(cel.StringType, cel.StringType)),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("matcher: build CEL environment: %v", err))
+	}
+	return e
+}
+
+type celMatcher struct {
+	program cel.Program
+}
+
+// compileCEL parses and type-checks expr against env, returning an error
+// that's safe to surface directly to a CreateRuleHandler caller if it
+// fails to compile or doesn't evaluate to a bool.
+func compileCEL(expr string) (Matcher, error) {
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid CEL expression: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("CEL expression must evaluate to bool, got %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("build CEL program: %w", err)
+	}
+
+	return celMatcher{program: program}, nil
+}
+
+func (c celMatcher) Match(command, agentID string, envVars map[string]string) bool {
+	vars := make(map[string]string, len(envVars))
+	for k, v := range envVars {
+		vars[k] = v
+	}
+
+	out, _, err := c.program.Eval(map[string]interface{}{
+		"command":  command,
+		"agent_id": agentID,
+		"env":      vars,
+	})
+	if err != nil {
+		return false
+	}
+
+	matched, ok := out.Value().(bool)
+	return ok && matched
+}
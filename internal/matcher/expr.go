@@ -0,0 +1,85 @@
+package matcher
+
+import "strings"
+
+// ExecContext is the full set of command metadata an "expr" rule can
+// reference: the command line and its derived argv, the user and working
+// directory that issued it (if the caller reports them), the requesting
+// agent's id/ip, and its env vars. A plain Match call (the interface every
+// other match type implements) can't carry all of this, so fields it
+// doesn't have are left at their zero value, which expr rules see as an
+// empty string (or an empty argv/env), not as an unknown identifier.
+type ExecContext struct {
+	Command string
+	Argv    []string
+	User    string
+	Cwd     string
+	AgentID string
+	AgentIP string
+	Env     map[string]string
+}
+
+// vars builds the identifier namespace expr rules are evaluated against:
+// command, argv, user, cwd, env, and agent.{id,ip}.
+func (c ExecContext) vars() map[string]interface{} {
+	argv := make([]interface{}, len(c.Argv))
+	for i, a := range c.Argv {
+		argv[i] = a
+	}
+	env := make(map[string]interface{}, len(c.Env))
+	for k, v := range c.Env {
+		env[k] = v
+	}
+	return map[string]interface{}{
+		"command": c.Command,
+		"argv":    argv,
+		"user":    c.User,
+		"cwd":     c.Cwd,
+		"env":     env,
+		"agent": map[string]interface{}{
+			"id": c.AgentID,
+			"ip": c.AgentIP,
+		},
+	}
+}
+
+// ContextMatcher is implemented by matchers that need more than
+// Matcher.Match's command/agentID/env to evaluate. Only "expr" rules
+// implement it today. ExecCheckHandler type-asserts for this so
+// substring/glob/regex/cel rules, which only need Matcher, don't pay for
+// building an ExecContext.
+type ContextMatcher interface {
+	MatchContext(ctx ExecContext) bool
+}
+
+// exprMatcher evaluates a parsed boolean expression (see exprlang.go)
+// against an ExecContext.
+type exprMatcher struct {
+	root node
+}
+
+// Match satisfies Matcher for callers with no ExecContext to build (e.g. a
+// bare Compile/Match round trip in a test); argv is derived from command,
+// and user/cwd/agent.ip are left at their zero value.
+func (m exprMatcher) Match(command, agentID string, env map[string]string) bool {
+	return m.MatchContext(ExecContext{
+		Command: command,
+		Argv:    strings.Fields(command),
+		AgentID: agentID,
+		Env:     env,
+	})
+}
+
+func (m exprMatcher) MatchContext(ctx ExecContext) bool {
+	return m.root.eval(ctx.vars())
+}
+
+// compileExpr parses expr into an exprMatcher, so ExecCheckHandler never
+// reparses a rule expression on the hot path.
+func compileExpr(expr string) (Matcher, error) {
+	root, err := parseExprString(expr)
+	if err != nil {
+		return nil, err
+	}
+	return exprMatcher{root: root}, nil
+}
@@ -0,0 +1,649 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// This file implements the small boolean expression language "expr" rules
+// are written in: string/number/bool literals, dotted identifiers with
+// optional [index] segments (command, argv[0], agent.ip, ...), the
+// operators ==, !=, <, <=, >, >=, matches (regex), contains (substring),
+// and in (list membership), and and/or/not with parenthesization. It's a
+// hand-rolled recursive-descent parser; there's no need for a parser
+// generator at this size.
+
+// node is a boolean-valued expression: and/or/not, comparisons, and a bare
+// operand used as a boolean (e.g. `user` alone means "user is non-empty").
+type node interface {
+	eval(vars map[string]interface{}) bool
+}
+
+// valueNode is an operand: a literal, a dotted identifier, or a list
+// literal (only valid on the right-hand side of "in").
+type valueNode interface {
+	value(vars map[string]interface{}) interface{}
+}
+
+// parseExprString parses a complete expr rule pattern, rejecting anything
+// left over after the expression (e.g. a stray token), so CreateRuleHandler
+// can reject invalid syntax with 400 at rule-creation time.
+func parseExprString(expr string) (node, error) {
+	toks, err := lexExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	p := &exprParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("invalid expression: unexpected token %q", p.peek().text)
+	}
+	return root, nil
+}
+
+// --- lexer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+	tokDot
+	tokEQ
+	tokNEQ
+	tokLT
+	tokLE
+	tokGT
+	tokGE
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func lexExpr(s string) ([]token, error) {
+	runes := []rune(s)
+	var toks []token
+	pos := 0
+
+	skipSpace := func() {
+		for pos < len(runes) && (runes[pos] == ' ' || runes[pos] == '\t' || runes[pos] == '\n' || runes[pos] == '\r') {
+			pos++
+		}
+	}
+	peekAt := func(offset int) rune {
+		if pos+offset >= len(runes) {
+			return 0
+		}
+		return runes[pos+offset]
+	}
+
+	for {
+		skipSpace()
+		if pos >= len(runes) {
+			toks = append(toks, token{kind: tokEOF})
+			return toks, nil
+		}
+
+		c := runes[pos]
+		switch {
+		case c == '(':
+			pos++
+			toks = append(toks, token{kind: tokLParen, text: "("})
+		case c == ')':
+			pos++
+			toks = append(toks, token{kind: tokRParen, text: ")"})
+		case c == '[':
+			pos++
+			toks = append(toks, token{kind: tokLBracket, text: "["})
+		case c == ']':
+			pos++
+			toks = append(toks, token{kind: tokRBracket, text: "]"})
+		case c == ',':
+			pos++
+			toks = append(toks, token{kind: tokComma, text: ","})
+		case c == '.':
+			pos++
+			toks = append(toks, token{kind: tokDot, text: "."})
+		case c == '=' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokEQ, text: "=="})
+		case c == '!' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokNEQ, text: "!="})
+		case c == '<' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokLE, text: "<="})
+		case c == '<':
+			pos++
+			toks = append(toks, token{kind: tokLT, text: "<"})
+		case c == '>' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, token{kind: tokGE, text: ">="})
+		case c == '>':
+			pos++
+			toks = append(toks, token{kind: tokGT, text: ">"})
+		case c == '"':
+			start := pos + 1
+			pos++
+			var sb strings.Builder
+			closed := false
+			for pos < len(runes) {
+				if runes[pos] == '"' {
+					closed = true
+					pos++
+					break
+				}
+				if runes[pos] == '\\' && pos+1 < len(runes) {
+					pos++
+				}
+				sb.WriteRune(runes[pos])
+				pos++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string literal starting at position %d", start-1)
+			}
+			toks = append(toks, token{kind: tokString, text: sb.String()})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := pos
+			if c == '-' {
+				pos++
+			}
+			for pos < len(runes) && runes[pos] >= '0' && runes[pos] <= '9' {
+				pos++
+			}
+			if pos < len(runes) && runes[pos] == '.' {
+				pos++
+				for pos < len(runes) && runes[pos] >= '0' && runes[pos] <= '9' {
+					pos++
+				}
+			}
+			toks = append(toks, token{kind: tokNumber, text: string(runes[start:pos])})
+		case isIdentStart(c):
+			start := pos
+			for pos < len(runes) && isIdentPart(runes[pos]) {
+				pos++
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(runes[start:pos])})
+		default:
+			return nil, fmt.Errorf("unexpected character %q", string(c))
+		}
+	}
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// --- parser ---
+
+type exprParser struct {
+	toks []token
+	pos  int
+}
+
+func (p *exprParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) advance() token {
+	t := p.toks[p.pos]
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokIdent && p.peek().text == "or" {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokIdent && p.peek().text == "and" {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if p.peek().kind == tokIdent && p.peek().text == "not" {
+		p.advance()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (node, error) {
+	if p.peek().kind == tokLParen {
+		p.advance()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, fmt.Errorf("expected ')'")
+		}
+		p.advance()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch tok.kind {
+	case tokEQ, tokNEQ, tokLT, tokLE, tokGT, tokGE:
+		p.advance()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonNode{left: left, op: tok.text, right: right}, nil
+	case tokIdent:
+		switch tok.text {
+		case "matches":
+			p.advance()
+			pat := p.peek()
+			if pat.kind != tokString {
+				return nil, fmt.Errorf("matches requires a string literal pattern")
+			}
+			p.advance()
+			re, err := regexp.Compile(pat.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex in matches: %w", err)
+			}
+			return matchesNode{left: left, re: re}, nil
+		case "contains":
+			p.advance()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return containsNode{left: left, right: right}, nil
+		case "in":
+			p.advance()
+			list, err := p.parseListLiteral()
+			if err != nil {
+				return nil, err
+			}
+			return inNode{left: left, list: list}, nil
+		}
+	}
+	return truthyNode{left}, nil
+}
+
+func (p *exprParser) parseOperand() (valueNode, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case tokString:
+		p.advance()
+		return literalNode{tok.text}, nil
+	case tokNumber:
+		p.advance()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return literalNode{f}, nil
+	case tokLBracket:
+		list, err := p.parseListLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return literalNode{list}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			p.advance()
+			return literalNode{true}, nil
+		case "false":
+			p.advance()
+			return literalNode{false}, nil
+		}
+		return p.parseIdentPath()
+	}
+	return nil, fmt.Errorf("unexpected token %q", tok.text)
+}
+
+func (p *exprParser) parseIdentPath() (valueNode, error) {
+	tok := p.advance()
+	segs := []pathSeg{{name: tok.text}}
+	for {
+		switch p.peek().kind {
+		case tokDot:
+			p.advance()
+			next := p.peek()
+			if next.kind != tokIdent {
+				return nil, fmt.Errorf("expected identifier after '.'")
+			}
+			p.advance()
+			segs = append(segs, pathSeg{name: next.text})
+		case tokLBracket:
+			p.advance()
+			idxTok := p.peek()
+			if idxTok.kind != tokNumber {
+				return nil, fmt.Errorf("expected a number index in '[...]'")
+			}
+			p.advance()
+			idx, err := strconv.Atoi(idxTok.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", idxTok.text)
+			}
+			if p.peek().kind != tokRBracket {
+				return nil, fmt.Errorf("expected ']'")
+			}
+			p.advance()
+			segs = append(segs, pathSeg{index: idx, isIndex: true})
+		default:
+			return identNode{path: segs}, nil
+		}
+	}
+}
+
+func (p *exprParser) parseListLiteral() ([]interface{}, error) {
+	if p.peek().kind != tokLBracket {
+		return nil, fmt.Errorf("expected '['")
+	}
+	p.advance()
+	var vals []interface{}
+	if p.peek().kind == tokRBracket {
+		p.advance()
+		return vals, nil
+	}
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case tokString:
+			vals = append(vals, tok.text)
+			p.advance()
+		case tokNumber:
+			f, err := strconv.ParseFloat(tok.text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", tok.text)
+			}
+			vals = append(vals, f)
+			p.advance()
+		default:
+			return nil, fmt.Errorf("expected a string or number in list literal")
+		}
+		if p.peek().kind == tokComma {
+			p.advance()
+			continue
+		}
+		break
+	}
+	if p.peek().kind != tokRBracket {
+		return nil, fmt.Errorf("expected ']'")
+	}
+	p.advance()
+	return vals, nil
+}
+
+// --- AST nodes ---
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(vars map[string]interface{}) bool { return n.left.eval(vars) && n.right.eval(vars) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(vars map[string]interface{}) bool { return n.left.eval(vars) || n.right.eval(vars) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(vars map[string]interface{}) bool { return !n.inner.eval(vars) }
+
+// truthyNode lets a bare operand stand in for a boolean, e.g. a rule of
+// just `user` means "user is set".
+type truthyNode struct{ v valueNode }
+
+func (n truthyNode) eval(vars map[string]interface{}) bool { return isTruthy(n.v.value(vars)) }
+
+type comparisonNode struct {
+	left  valueNode
+	op    string
+	right valueNode
+}
+
+func (n comparisonNode) eval(vars map[string]interface{}) bool {
+	return compareValues(n.op, n.left.value(vars), n.right.value(vars))
+}
+
+// matchesNode's regex is compiled once at parse time, not on every eval.
+type matchesNode struct {
+	left valueNode
+	re   *regexp.Regexp
+}
+
+func (n matchesNode) eval(vars map[string]interface{}) bool {
+	return n.re.MatchString(asString(n.left.value(vars)))
+}
+
+type containsNode struct{ left, right valueNode }
+
+func (n containsNode) eval(vars map[string]interface{}) bool {
+	return strings.Contains(asString(n.left.value(vars)), asString(n.right.value(vars)))
+}
+
+type inNode struct {
+	left valueNode
+	list []interface{}
+}
+
+func (n inNode) eval(vars map[string]interface{}) bool {
+	v := n.left.value(vars)
+	for _, item := range n.list {
+		if compareValues("==", v, item) {
+			return true
+		}
+	}
+	return false
+}
+
+type literalNode struct{ v interface{} }
+
+func (n literalNode) value(map[string]interface{}) interface{} { return n.v }
+
+type pathSeg struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// identNode resolves a dotted/indexed path (agent.ip, argv[0]) against the
+// vars map built by ExecContext.vars. A path that doesn't resolve (unknown
+// field, out-of-range index) returns nil, which compareValues/isTruthy
+// treat as the zero value of whatever it's compared against, rather than
+// erroring, so evaluation stays total.
+type identNode struct{ path []pathSeg }
+
+func (n identNode) value(vars map[string]interface{}) interface{} {
+	var cur interface{} = vars
+	for _, seg := range n.path {
+		if seg.isIndex {
+			arr, ok := cur.([]interface{})
+			if !ok || seg.index < 0 || seg.index >= len(arr) {
+				return nil
+			}
+			cur = arr[seg.index]
+			continue
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v, exists := m[seg.name]
+		if !exists {
+			return nil
+		}
+		cur = v
+	}
+	return cur
+}
+
+// --- value helpers ---
+
+func isTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case float64:
+		return t != 0
+	default:
+		return true
+	}
+}
+
+func asString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// zeroLike infers the zero value an unknown identifier should take on,
+// based on the type of the operand it's being compared against.
+func zeroLike(v interface{}) interface{} {
+	switch v.(type) {
+	case float64:
+		return float64(0)
+	case bool:
+		return false
+	default:
+		return ""
+	}
+}
+
+// compareValues compares two operand values under op. An operand that's
+// nil (an unknown identifier) is coerced to the zero value inferred from
+// the other side before comparing, per identNode's doc comment.
+func compareValues(op string, left, right interface{}) bool {
+	if left == nil {
+		left = zeroLike(right)
+	}
+	if right == nil {
+		right = zeroLike(left)
+	}
+	switch l := left.(type) {
+	case float64:
+		r, ok := toFloat(right)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		case "<":
+			return l < r
+		case "<=":
+			return l <= r
+		case ">":
+			return l > r
+		case ">=":
+			return l >= r
+		}
+		return false
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		default:
+			return false
+		}
+	default:
+		ls, rs := asString(left), asString(right)
+		switch op {
+		case "==":
+			return ls == rs
+		case "!=":
+			return ls != rs
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		}
+		return false
+	}
+}
@@ -0,0 +1,116 @@
+// Package matcher compiles a Plasma Shield rule's pattern into a reusable
+// Matcher, so ExecCheckHandler can evaluate a command against it without
+// recompiling a regex or CEL program on every check.
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Type selects how a rule's pattern is interpreted.
+type Type string
+
+const (
+	Substring Type = "substring"
+	Glob      Type = "glob"
+	Regex     Type = "regex"
+	CEL       Type = "cel"
+	Expr      Type = "expr"
+)
+
+// Matcher decides whether a command, issued by an agent with the given
+// environment, matches a compiled rule. Implementations are safe for
+// concurrent use.
+type Matcher interface {
+	Match(command, agentID string, env map[string]string) bool
+}
+
+// Compile parses pattern according to typ, returning a Matcher ready to
+// evaluate repeatedly. It returns an error if pattern doesn't compile under
+// typ (invalid regex, a CEL expression that fails to parse or type-check,
+// etc.), so CreateRuleHandler can reject a bad rule at creation time rather
+// than having it silently never match.
+func Compile(typ Type, pattern string) (Matcher, error) {
+	switch typ {
+	case "", Substring:
+		return substringMatcher(pattern), nil
+	case Glob:
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern: %w", err)
+		}
+		return globMatcher{re}, nil
+	case Regex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		return regexMatcher{re}, nil
+	case CEL:
+		return compileCEL(pattern)
+	case Expr:
+		return compileExpr(pattern)
+	default:
+		return nil, fmt.Errorf("unknown match type %q", typ)
+	}
+}
+
+type substringMatcher string
+
+func (s substringMatcher) Match(command, _ string, _ map[string]string) bool {
+	return strings.Contains(command, string(s))
+}
+
+// compileGlob translates a shell-style glob pattern into an equivalent
+// anchored regexp: * matches any run of characters and ? matches any one
+// character, same as filepath.Match's wildcards, but -- unlike
+// filepath.Match -- free to cross '/'. A rule author writing a glob like
+// "rm -rf *" means "anything after", including a path; filepath.Match's
+// refusal to let '*' match a path separator made such rules silently
+// never match.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated [ in %q", pattern)
+			}
+			end += i + 1
+			class := pattern[i+1 : end]
+			if strings.HasPrefix(class, "^") || strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteByte('$')
+	return regexp.Compile(b.String())
+}
+
+type globMatcher struct {
+	re *regexp.Regexp
+}
+
+func (g globMatcher) Match(command, _ string, _ map[string]string) bool {
+	return g.re.MatchString(command)
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (r regexMatcher) Match(command, _ string, _ map[string]string) bool {
+	return r.re.MatchString(command)
+}
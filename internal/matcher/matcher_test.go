@@ -0,0 +1,165 @@
+package matcher
+
+import "testing"
+
+func TestCompileSubstring(t *testing.T) {
+	m, err := Compile(Substring, "rm -rf")
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !m.Match("rm -rf /", "agent-1", nil) {
+		t.Error("expected substring match")
+	}
+	if m.Match("ls -la", "agent-1", nil) {
+		t.Error("expected no match")
+	}
+}
+
+func TestCompileDefaultsToSubstring(t *testing.T) {
+	m, err := Compile("", "curl")
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !m.Match("curl https://evil.example", "agent-1", nil) {
+		t.Error("expected empty match type to behave as substring")
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	m, err := Compile(Glob, "rm -rf *")
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !m.Match("rm -rf /", "agent-1", nil) {
+		t.Error("expected glob match")
+	}
+	if m.Match("ls -la", "agent-1", nil) {
+		t.Error("expected no match")
+	}
+
+	if _, err := Compile(Glob, "["); err == nil {
+		t.Error("expected an invalid glob pattern to fail to compile")
+	}
+}
+
+func TestCompileRegex(t *testing.T) {
+	m, err := Compile(Regex, `^rm\s+-rf\s+/`)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !m.Match("rm -rf /var", "agent-1", nil) {
+		t.Error("expected regex match")
+	}
+	if m.Match("echo rm -rf /", "agent-1", nil) {
+		t.Error("expected no match (anchored pattern)")
+	}
+
+	if _, err := Compile(Regex, "("); err == nil {
+		t.Error("expected an invalid regex to fail to compile")
+	}
+}
+
+func TestCompileCEL(t *testing.T) {
+	m, err := Compile(CEL, `command.contains("rm -rf") && agent_id == "agent-1"`)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !m.Match("rm -rf /", "agent-1", nil) {
+		t.Error("expected CEL match")
+	}
+	if m.Match("rm -rf /", "agent-2", nil) {
+		t.Error("expected no match for a different agent_id")
+	}
+
+	t.Run("reads the env map", func(t *testing.T) {
+		m, err := Compile(CEL, `env["tier"] == "production"`)
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		if !m.Match("anything", "agent-1", map[string]string{"tier": "production"}) {
+			t.Error("expected env-based match")
+		}
+		if m.Match("anything", "agent-1", map[string]string{"tier": "staging"}) {
+			t.Error("expected no match for a different env value")
+		}
+	})
+
+	t.Run("rejects an expression that fails to parse", func(t *testing.T) {
+		if _, err := Compile(CEL, "command +"); err == nil {
+			t.Error("expected a malformed CEL expression to fail to compile")
+		}
+	})
+
+	t.Run("rejects an expression that doesn't type-check to bool", func(t *testing.T) {
+		if _, err := Compile(CEL, "command"); err == nil {
+			t.Error("expected a non-bool CEL expression to be rejected")
+		}
+	})
+
+	t.Run("rejects an undeclared variable", func(t *testing.T) {
+		if _, err := Compile(CEL, "unknown_field == \"x\""); err == nil {
+			t.Error("expected an undeclared variable to fail to compile")
+		}
+	})
+}
+
+func TestCompileExpr(t *testing.T) {
+	m, err := Compile(Expr, `command matches "^rm " and not user in ["root", "deploy"]`)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !m.Match("rm -rf /", "agent-1", nil) {
+		t.Error("expected expr match (command matches, user unset so not-in-list is true)")
+	}
+
+	t.Run("evaluates agent.ip and argv[0] via MatchContext", func(t *testing.T) {
+		m, err := Compile(Expr, `agent.ip == "10.0.0.1" and argv[0] == "curl"`)
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		cm, ok := m.(ContextMatcher)
+		if !ok {
+			t.Fatal("expected expr matcher to implement ContextMatcher")
+		}
+		if !cm.MatchContext(ExecContext{Command: "curl https://evil.example", Argv: []string{"curl", "https://evil.example"}, AgentIP: "10.0.0.1"}) {
+			t.Error("expected context match")
+		}
+		if cm.MatchContext(ExecContext{Command: "curl https://evil.example", Argv: []string{"curl", "https://evil.example"}, AgentIP: "10.0.0.2"}) {
+			t.Error("expected no match for a different agent ip")
+		}
+	})
+
+	t.Run("treats an unsupplied field as its zero value instead of erroring", func(t *testing.T) {
+		m, err := Compile(Expr, `cwd == "/var/www"`)
+		if err != nil {
+			t.Fatalf("Compile: %v", err)
+		}
+		if m.Match("ls", "agent-1", nil) {
+			t.Error("expected no match when cwd wasn't supplied")
+		}
+	})
+
+	t.Run("rejects an expression that fails to parse", func(t *testing.T) {
+		if _, err := Compile(Expr, `command == `); err == nil {
+			t.Error("expected a malformed expression to fail to compile")
+		}
+	})
+
+	t.Run("rejects matches with a non-literal pattern", func(t *testing.T) {
+		if _, err := Compile(Expr, `command matches user`); err == nil {
+			t.Error("expected matches to require a string literal pattern")
+		}
+	})
+
+	t.Run("rejects an invalid regex in matches", func(t *testing.T) {
+		if _, err := Compile(Expr, `command matches "("`); err == nil {
+			t.Error("expected an invalid regex to fail to compile")
+		}
+	})
+}
+
+func TestCompileUnknownType(t *testing.T) {
+	if _, err := Compile("bogus", "x"); err == nil {
+		t.Error("expected an unknown match type to return an error")
+	}
+}
@@ -0,0 +1,184 @@
+// Package metrics defines the Prometheus collectors that instrument
+// cmd/proxy's hot paths -- the proxy.Inspector decision pipeline,
+// mode.Manager's block/allow decisions, fleet.Manager's tenant/agent
+// counts, and rules.Engine's loaded ruleset -- and serves them at
+// GET /metrics. Modeled on internal/api's execMetrics: every collector is
+// registered against an instance-owned prometheus.Registry rather than the
+// default global one, so each process (and each test that constructs a
+// Metrics) gets an isolated set of series.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+)
+
+// Metrics holds every Prometheus instrument behind GET /metrics.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	RequestsTotal       *prometheus.CounterVec
+	RequestDuration     prometheus.Histogram
+	RuleMatchesTotal    *prometheus.CounterVec
+	BytesInspectedTotal prometheus.Counter
+	ModeDecisionsTotal  *prometheus.CounterVec
+	FleetAgents         *prometheus.GaugeVec
+	FleetMode           *prometheus.GaugeVec
+	RulesLoaded         prometheus.Gauge
+	RulesEnabled        prometheus.Gauge
+	RuleCompileDuration prometheus.Histogram
+	HTTPRequestSummary  *prometheus.SummaryVec
+	RuleEvaluationsTotal *prometheus.CounterVec
+	RuleEvalDuration     prometheus.Histogram
+	BlockedTotal         *prometheus.CounterVec
+	ProxyRequestDuration *prometheus.HistogramVec
+	RateLimitedTotal     *prometheus.CounterVec
+	GRPCRequestDuration  *prometheus.HistogramVec
+	GRPCActiveStreams    *prometheus.GaugeVec
+}
+
+// New creates an empty, registered Metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_requests_total",
+			Help: "Total requests inspected, by decision, agent, and tenant.",
+		}, []string{"decision", "agent", "tenant"}),
+		RequestDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "plasma_request_duration_seconds",
+			Help:    "Time to inspect and decide on a single request.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		RuleMatchesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_rule_matches_total",
+			Help: "Rule matches during inspection, by rule ID and action (allow/block).",
+		}, []string{"rule_id", "action"}),
+		BytesInspectedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "plasma_bytes_inspected_total",
+			Help: "Total request bytes passed through inspection.",
+		}),
+		ModeDecisionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_mode_decisions_total",
+			Help: "ShouldBlock decisions, by mode and whether the call blocked.",
+		}, []string{"mode", "blocked"}),
+		FleetAgents: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "plasma_fleet_agents",
+			Help: "Registered agents, by tenant.",
+		}, []string{"tenant"}),
+		FleetMode: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "plasma_fleet_mode",
+			Help: "Tenant fleet mode: 1 for the active mode, 0 otherwise.",
+		}, []string{"tenant", "mode"}),
+		RulesLoaded: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "plasma_rules_loaded",
+			Help: "Number of rules currently loaded in the engine.",
+		}),
+		RulesEnabled: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "plasma_rules_enabled",
+			Help: "Number of currently loaded rules with enabled: true.",
+		}),
+		RuleCompileDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "plasma_rule_compile_duration_seconds",
+			Help:    "Time to validate and compile a loaded rule set.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		HTTPRequestSummary: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:       "plasma_http_request_duration_seconds",
+			Help:       "Management API request duration, by HTTP method and path.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, []string{"method", "path"}),
+		RuleEvaluationsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_rule_evaluations_total",
+			Help: "Rule evaluations that reached a decision (allow/block), by rule ID, action, tier, and matcher kind.",
+		}, []string{"rule_id", "action", "tier", "matcher"}),
+		RuleEvalDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "plasma_rule_eval_seconds",
+			Help:    "Time to evaluate a single command/domain/HTTP check against the loaded ruleset.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		BlockedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_blocked_total",
+			Help: "Requests actually blocked (post-mode), by rule ID and host.",
+		}, []string{"rule_id", "host"}),
+		ProxyRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "plasma_proxy_request_seconds",
+			Help:    "End-to-end Inspector.CheckRequest duration, by decision.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"decision"}),
+		RateLimitedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "plasma_rate_limited_total",
+			Help: "Requests rejected by a rule's rate_limit spec, by rule ID and scope.",
+		}, []string{"rule_id", "scope"}),
+		GRPCRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "plasma_grpc_request_duration_seconds",
+			Help:    "internal/control ShieldControl RPC duration, by method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		GRPCActiveStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "plasma_grpc_active_streams",
+			Help: "In-flight ShieldControl server-streaming RPCs, by method.",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(
+		m.RequestsTotal, m.RequestDuration, m.RuleMatchesTotal, m.BytesInspectedTotal,
+		m.ModeDecisionsTotal, m.FleetAgents, m.FleetMode, m.RulesLoaded, m.RulesEnabled, m.RuleCompileDuration,
+		m.HTTPRequestSummary, m.RuleEvaluationsTotal, m.RuleEvalDuration, m.BlockedTotal, m.ProxyRequestDuration,
+		m.RateLimitedTotal, m.GRPCRequestDuration, m.GRPCActiveStreams,
+	)
+	return m
+}
+
+// InstrumentHandler wraps h so every request observes
+// plasma_http_request_duration_seconds, labeled by method and path.
+func (m *Metrics) InstrumentHandler(path string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		h(w, r)
+		m.HTTPRequestSummary.WithLabelValues(r.Method, path).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RefreshFleetGauges recomputes plasma_fleet_agents and plasma_fleet_mode
+// from fleetMgr's current state. Called at scrape time rather than
+// threaded through every fleet mutation, so the gauges can never drift out
+// of sync with the manager they describe -- the same reasoning behind
+// execMetrics.refreshGauges in internal/api.
+func (m *Metrics) RefreshFleetGauges(fleetMgr *fleet.Manager) {
+	m.FleetAgents.Reset()
+	m.FleetMode.Reset()
+	for _, tenantID := range fleetMgr.AllTenants() {
+		m.FleetAgents.WithLabelValues(tenantID).Set(float64(len(fleetMgr.TenantAgentIDs(tenantID))))
+		m.FleetMode.WithLabelValues(tenantID, string(fleetMgr.GetMode(tenantID))).Set(1)
+	}
+}
+
+// Handler returns the GET /metrics HTTP handler, encoding every registered
+// series in Prometheus text exposition format.
+func (m *Metrics) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mfs, err := m.registry.Gather()
+		if err != nil {
+			http.Error(w, "gather metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package middleware provides small, composable http.Handler wrappers
+// shared across the gateway's HTTP surfaces (forward proxy, reverse proxy).
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+)
+
+// recoverConfig holds the options a RecoverOption can set. The zero value
+// matches Recover's long-standing default: 500 with a plain-text body.
+type recoverConfig struct {
+	statusCode int
+	jsonError  bool
+}
+
+// RecoverOption configures Recover's response to a recovered panic.
+type RecoverOption func(*recoverConfig)
+
+// WithStatusCode overrides the status code written on a recovered panic
+// (500 if not set). The forward proxy uses this to report 502, since a
+// panic there is usually an upstream dial/rule-evaluation failure rather
+// than a fault in the gateway itself.
+func WithStatusCode(code int) RecoverOption {
+	return func(c *recoverConfig) {
+		c.statusCode = code
+	}
+}
+
+// WithJSONError switches the recovered-panic response from a plain-text
+// body to a stable JSON body ({"error": "..."}), for callers whose clients
+// expect JSON on every response path.
+func WithJSONError() RecoverOption {
+	return func(c *recoverConfig) {
+		c.jsonError = true
+	}
+}
+
+// Recover returns middleware that recovers a panic from next's ServeHTTP,
+// logs the stack trace, increments plasma_shield_panics_total{component},
+// and returns an error response instead of crashing the process or
+// leaking the connection. component names the call site (e.g. "forward",
+// "reverse") for the log line and metric. metrics may be nil to skip the
+// counter. opts can override the response status/body shape; with none
+// given, this is 500 with a plain-text body as before. Modeled on the
+// recovery interceptor in github.com/grpc-ecosystem/go-grpc-middleware,
+// and on bastion.safeGo's equivalent for SSH channel goroutines.
+func Recover(component string, metrics *limiter.Metrics, opts ...RecoverOption) func(http.Handler) http.Handler {
+	cfg := recoverConfig{statusCode: http.StatusInternalServerError}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					stack := debug.Stack()
+					log.Printf("%s: panic recovered (remote=%s %s %s): %v\n%s", component, r.RemoteAddr, r.Method, r.URL.Path, rec, stack)
+					if metrics != nil {
+						metrics.IncPanic(component)
+					}
+					if cfg.jsonError {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(cfg.statusCode)
+						json.NewEncoder(w).Encode(map[string]string{"error": "internal server error"})
+					} else {
+						http.Error(w, "internal server error", cfg.statusCode)
+					}
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// Package mitmca issues ephemeral, per-hostname TLS server certificates
+// signed by a local root CA, so the forward proxy's MITM mode (see
+// proxy.WithMITM) can terminate an agent's CONNECT tunnel instead of
+// blindly relaying encrypted bytes. An agent must be configured to trust
+// the root certificate (see cmd/plasma-shield's "ca init" subcommand and
+// GET /mitm/ca-cert) before interception stops tripping its own TLS
+// verification.
+package mitmca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultCAKeyPath = "mitm_ca_key"
+
+// leafTTL is how long a minted leaf certificate is valid. Short-lived
+// since a leaf is cheap to re-mint and there's no revocation path for one.
+const leafTTL = 24 * time.Hour
+
+// maxCachedLeaves bounds the per-SNI leaf cache so a proxy fielding CONNECTs
+// to many distinct hostnames can't grow it without bound; the least
+// recently used entry is evicted once full.
+const maxCachedLeaves = 4096
+
+// CA issues and caches leaf TLS server certificates for MITM interception,
+// each signed by a local root certificate.
+type CA struct {
+	mu      sync.Mutex
+	cert    *x509.Certificate
+	certDER []byte
+	signer  crypto.Signer
+	keyPath string
+	leaves  *leafCache
+}
+
+// NewCA loads or creates a root CA keypair at path.
+func NewCA(path string) (*CA, error) {
+	if path == "" {
+		path = defaultCAKeyPath
+	}
+
+	signer, cert, certDER, err := loadOrCreateCAKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{
+		cert:    cert,
+		certDER: certDER,
+		signer:  signer,
+		keyPath: path,
+		leaves:  newLeafCache(maxCachedLeaves),
+	}, nil
+}
+
+// RootCertPEM returns the PEM encoding of the CA's root certificate, for an
+// operator to hand to agents so they trust leaf certificates this CA mints
+// (see cmd/plasma-shield's "ca init" subcommand and GET /mitm/ca-cert).
+func (c *CA) RootCertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.certDER})
+}
+
+// GetCertificate implements the tls.Config.GetCertificate callback, minting
+// (or returning a cached) leaf certificate for the ClientHello's SNI. A
+// client that sent no SNI falls back to "localhost".
+func (c *CA) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	sni := hello.ServerName
+	if sni == "" {
+		sni = "localhost"
+	}
+	return c.leafFor(sni)
+}
+
+// leafFor returns the cached leaf certificate for sni, minting and caching
+// one if this is the first time sni has been seen.
+func (c *CA) leafFor(sni string) (*tls.Certificate, error) {
+	if cert, ok := c.leaves.get(sni); ok {
+		return cert, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another goroutine may have minted sni's leaf while we waited for mu.
+	if cert, ok := c.leaves.get(sni); ok {
+		return cert, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("mitmca: generate leaf key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("mitmca: generate serial: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: sni},
+		DNSNames:     []string{sni},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(leafTTL),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &key.PublicKey, c.signer)
+	if err != nil {
+		return nil, fmt.Errorf("mitmca: sign leaf certificate for %q: %w", sni, err)
+	}
+
+	cert := &tls.Certificate{Certificate: [][]byte{der, c.certDER}, PrivateKey: key}
+	c.leaves.put(sni, cert)
+	return cert, nil
+}
+
+func loadOrCreateCAKey(path string) (crypto.Signer, *x509.Certificate, []byte, error) {
+	keyData, err := os.ReadFile(path)
+	if err == nil {
+		certData, err := os.ReadFile(path + ".crt")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("mitmca: read CA certificate: %w", err)
+		}
+		return parseCAKeyAndCert(keyData, certData)
+	}
+	if !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+
+	return generateCAKey(path)
+}
+
+// generateCAKey creates a brand new, self-signed root CA keypair and
+// persists it at path/path.crt, overwriting whatever was there before.
+func generateCAKey(path string) (crypto.Signer, *x509.Certificate, []byte, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Plasma Shield MITM CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	if err := os.WriteFile(path, keyPEM, 0600); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := os.WriteFile(path+".crt", certPEM, 0644); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return parseCAKeyAndCert(keyPEM, certPEM)
+}
+
+func parseCAKeyAndCert(keyPEM, certPEM []byte) (crypto.Signer, *x509.Certificate, []byte, error) {
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, nil, errors.New("mitmca: invalid CA key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("mitmca: parse CA key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, nil, errors.New("mitmca: CA key is not a signer")
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, nil, errors.New("mitmca: invalid CA certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("mitmca: parse CA certificate: %w", err)
+	}
+
+	return signer, cert, certBlock.Bytes, nil
+}
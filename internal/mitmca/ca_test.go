@@ -0,0 +1,80 @@
+package mitmca
+
+import (
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+)
+
+func TestCA_IssuesLeafSignedByRoot(t *testing.T) {
+	ca, err := NewCA(filepath.Join(t.TempDir(), "mitm_ca_key"))
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	leaf, err := ca.leafFor("example.com")
+	if err != nil {
+		t.Fatalf("leafFor: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(leaf.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse leaf: %v", err)
+	}
+	if len(cert.DNSNames) != 1 || cert.DNSNames[0] != "example.com" {
+		t.Errorf("DNSNames = %v, want [example.com]", cert.DNSNames)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca.RootCertPEM()) {
+		t.Fatal("failed to parse RootCertPEM")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: pool}); err != nil {
+		t.Errorf("leaf does not verify against root: %v", err)
+	}
+}
+
+func TestCA_CachesLeafBySNI(t *testing.T) {
+	ca, err := NewCA(filepath.Join(t.TempDir(), "mitm_ca_key"))
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	first, err := ca.leafFor("example.com")
+	if err != nil {
+		t.Fatalf("leafFor: %v", err)
+	}
+	second, err := ca.leafFor("example.com")
+	if err != nil {
+		t.Fatalf("leafFor: %v", err)
+	}
+	if first != second {
+		t.Error("expected the same cached leaf certificate to be returned for a repeated SNI")
+	}
+
+	other, err := ca.leafFor("other.example")
+	if err != nil {
+		t.Fatalf("leafFor: %v", err)
+	}
+	if string(other.Certificate[0]) == string(first.Certificate[0]) {
+		t.Error("expected distinct SNIs to get distinct leaf certificates")
+	}
+}
+
+func TestNewCA_LoadsPersistedKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mitm_ca_key")
+
+	first, err := NewCA(path)
+	if err != nil {
+		t.Fatalf("NewCA: %v", err)
+	}
+
+	second, err := NewCA(path)
+	if err != nil {
+		t.Fatalf("NewCA (reload): %v", err)
+	}
+
+	if string(first.RootCertPEM()) != string(second.RootCertPEM()) {
+		t.Error("expected reloading the same path to reuse the persisted CA certificate")
+	}
+}
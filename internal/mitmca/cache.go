@@ -0,0 +1,65 @@
+package mitmca
+
+import (
+	"crypto/tls"
+	"sync"
+)
+
+// leafCache is a small LRU cache of minted leaf certificates keyed by SNI,
+// so a proxy re-handling CONNECTs to the same hostname doesn't re-mint (and
+// re-sign) a certificate on every connection.
+type leafCache struct {
+	mu    sync.Mutex
+	cap   int
+	order []string
+	certs map[string]*tls.Certificate
+}
+
+func newLeafCache(capacity int) *leafCache {
+	return &leafCache{
+		cap:   capacity,
+		certs: make(map[string]*tls.Certificate),
+	}
+}
+
+func (c *leafCache) get(sni string) (*tls.Certificate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cert, ok := c.certs[sni]
+	if ok {
+		c.touch(sni)
+	}
+	return cert, ok
+}
+
+func (c *leafCache) put(sni string, cert *tls.Certificate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.certs[sni]; !exists && len(c.certs) >= c.cap {
+		c.evictOldest()
+	}
+	c.certs[sni] = cert
+	c.touch(sni)
+}
+
+// touch moves sni to the most-recently-used end of order.
+func (c *leafCache) touch(sni string) {
+	for i, s := range c.order {
+		if s == sni {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, sni)
+}
+
+func (c *leafCache) evictOldest() {
+	if len(c.order) == 0 {
+		return
+	}
+	oldest := c.order[0]
+	c.order = c.order[1:]
+	delete(c.certs, oldest)
+}
@@ -2,7 +2,10 @@
 package mode
 
 import (
+	"strconv"
 	"sync"
+
+	"github.com/Extra-Chill/plasma-shield/internal/metrics"
 )
 
 // Mode represents the operating mode of the shield.
@@ -22,6 +25,16 @@ type Manager struct {
 	mu          sync.RWMutex
 	globalMode  Mode
 	agentModes  map[string]Mode // agent ID -> mode override
+	metrics     *metrics.Metrics
+}
+
+// SetMetrics wires a Prometheus-style metrics registry that ShouldBlock
+// reports plasma_mode_decisions_total to. Nil (the default) disables
+// reporting.
+func (m *Manager) SetMetrics(mm *metrics.Metrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics = mm
 }
 
 // NewManager creates a new mode manager with enforce as default.
@@ -88,14 +101,24 @@ func (m *Manager) AllAgentModes() map[string]Mode {
 // In Enforce mode, returns the provided ruleMatched value.
 func (m *Manager) ShouldBlock(agentID string, ruleMatched bool) bool {
 	mode := m.AgentMode(agentID)
+	var blocked bool
 	switch mode {
 	case Audit:
-		return false // Never block in audit mode
+		blocked = false // Never block in audit mode
 	case Lockdown:
-		return true // Always block in lockdown mode
+		blocked = true // Always block in lockdown mode
 	default:
-		return ruleMatched // Enforce: block only if rule matched
+		blocked = ruleMatched // Enforce: block only if rule matched
 	}
+
+	m.mu.RLock()
+	mm := m.metrics
+	m.mu.RUnlock()
+	if mm != nil {
+		mm.ModeDecisionsTotal.WithLabelValues(string(mode), strconv.FormatBool(blocked)).Inc()
+	}
+
+	return blocked
 }
 
 // IsAudit returns whether the agent is in audit mode (for logging).
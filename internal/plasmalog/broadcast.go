@@ -0,0 +1,64 @@
+package plasmalog
+
+import (
+	"context"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/logs"
+)
+
+// broadcastBufferCapacity bounds how many Records BroadcastSink's Broker
+// keeps for Since replay, the same default logBrokerCapacity uses for
+// GET /logs/stream in cmd/proxy.
+const broadcastBufferCapacity = 1000
+
+// BroadcastSink is a Sink that fans every Record out to live subscribers
+// via an internal/logs.Broker, instead of (or alongside) writing them
+// anywhere durable. It exists for internal/control.Server's StreamDecisions
+// RPC: rather than having that RPC re-derive decision events from
+// Inspector.CheckRequest itself, it subscribes to the same Records the
+// configured --log-sink destinations receive, so "what StreamDecisions
+// sees" and "what the JSON log lines say" can never drift apart.
+type BroadcastSink struct {
+	broker *logs.Broker
+}
+
+// NewBroadcastSink creates a BroadcastSink ready to be added to the sinks
+// map passed to New/NewMultiHandler alongside stdout/file/syslog/loki.
+func NewBroadcastSink() *BroadcastSink {
+	return &BroadcastSink{broker: logs.NewBroker(broadcastBufferCapacity)}
+}
+
+// Write implements Sink by publishing each record to every live subscriber.
+func (s *BroadcastSink) Write(_ context.Context, records []Record) error {
+	for _, r := range records {
+		s.broker.Publish(r, r.Time)
+	}
+	return nil
+}
+
+// Flush implements Sink; publishing is synchronous, so there is nothing to
+// flush.
+func (s *BroadcastSink) Flush() error { return nil }
+
+// Close implements Sink; the underlying Broker has no resources to release.
+func (s *BroadcastSink) Close() error { return nil }
+
+// Subscribe registers a new live subscriber, receiving every Record
+// published from this point on.
+func (s *BroadcastSink) Subscribe() *logs.Subscription {
+	return s.broker.Subscribe()
+}
+
+// Since returns buffered records published strictly after ts, oldest
+// first, for a reconnecting stream client to replay.
+func (s *BroadcastSink) Since(ts time.Time) []Record {
+	recs := s.broker.Since(ts)
+	out := make([]Record, 0, len(recs))
+	for _, rec := range recs {
+		if r, ok := rec.Payload.(Record); ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package plasmalog
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+)
+
+// ParseSinks builds the Sinks named in spec, the comma-separated value of
+// the --log-sink flag. Each entry is either a bare sink type ("stdout") or
+// "type=value" ("file=/var/log/plasma-shield.jsonl",
+// "syslog=udp://collector:514", "loki=http://loki:3100/loki/api/v1/push").
+// An empty spec parses to a single stdout sink, so the flag can be safely
+// omitted.
+func ParseSinks(spec string) (map[string]Sink, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return map[string]Sink{"stdout": NewStdoutSink(nil)}, nil
+	}
+
+	sinks := make(map[string]Sink)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, value, _ := strings.Cut(entry, "=")
+		kind = strings.TrimSpace(kind)
+		value = strings.TrimSpace(value)
+
+		sink, err := buildSink(kind, value)
+		if err != nil {
+			return nil, fmt.Errorf("plasmalog: sink %q: %w", entry, err)
+		}
+
+		name := kind
+		for i := 2; ; i++ {
+			if _, dup := sinks[name]; !dup {
+				break
+			}
+			name = fmt.Sprintf("%s-%d", kind, i)
+		}
+		sinks[name] = sink
+	}
+	return sinks, nil
+}
+
+func buildSink(kind, value string) (Sink, error) {
+	switch kind {
+	case "stdout":
+		return NewStdoutSink(nil), nil
+
+	case "file":
+		if value == "" {
+			return nil, fmt.Errorf("file sink requires a path, e.g. file=/var/log/plasma-shield.jsonl")
+		}
+		return NewFileSink(value)
+
+	case "syslog":
+		if value == "" {
+			return nil, fmt.Errorf("syslog sink requires a URL, e.g. syslog=udp://collector:514")
+		}
+		u, err := url.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog URL %q: %w", value, err)
+		}
+		return NewSyslogSink(u.Scheme, u.Host)
+
+	case "loki":
+		if value == "" {
+			return nil, fmt.Errorf("loki sink requires a push URL, e.g. loki=http://loki:3100/loki/api/v1/push")
+		}
+		return NewLokiSink(LokiConfig{URL: value, Labels: map[string]string{"service": "plasma-shield"}}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", kind)
+	}
+}
+
+// Build parses spec (see ParseSinks) and returns a ready-to-use
+// *slog.Logger plus the underlying *MultiHandler, so the caller can
+// Close() it on shutdown and expose its Stats() the way cmd/proxy exposes
+// internal/logsink.MultiSink.Stats() at GET /sinks.
+func Build(spec string, queueCapacity int, level slog.Leveler) (*slog.Logger, *MultiHandler, error) {
+	sinks, err := ParseSinks(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, handler := New(sinks, queueCapacity, level)
+	return logger, handler, nil
+}
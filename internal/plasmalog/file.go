@@ -0,0 +1,142 @@
+package plasmalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSinkOption configures a FileSink.
+type FileSinkOption func(*FileSink)
+
+// WithFileMaxSize sets the size in bytes at which the log file is rotated.
+// Zero (the default) disables size-based rotation.
+func WithFileMaxSize(bytes int64) FileSinkOption {
+	return func(f *FileSink) {
+		f.maxSize = bytes
+	}
+}
+
+// WithFileMaxAge sets the duration after which the log file is rotated
+// regardless of size. Zero (the default) disables time-based rotation.
+func WithFileMaxAge(d time.Duration) FileSinkOption {
+	return func(f *FileSink) {
+		f.maxAge = d
+	}
+}
+
+// FileSink writes one JSON object per Record, one per line, to a file,
+// rotating it to "<path>.<RFC3339 timestamp>" when it grows past maxSize
+// or gets older than maxAge -- the same rotation scheme as
+// logsink.FileSink and accesslog.FileLogger, applied to the application
+// log instead of the durable audit trail or the per-request access log.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	maxSize  int64
+	maxAge   time.Duration
+}
+
+// NewFileSink opens (creating if necessary) the JSON-lines log at path.
+func NewFileSink(path string, opts ...FileSinkOption) (*FileSink, error) {
+	f := &FileSink{path: path}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := f.openLocked(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileSink) openLocked() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("plasmalog: open %s: %w", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("plasmalog: stat %s: %w", f.path, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// Write implements Sink, appending each record as one JSON-lines entry. A
+// marshal failure skips just that record; a write failure aborts the rest
+// of the batch and is returned to the caller.
+func (f *FileSink) Write(_ context.Context, records []Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, rec := range records {
+		data, err := json.Marshal(jsonRecord{
+			Time:    rec.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Level:   rec.Level.String(),
+			Message: rec.Message,
+			Attrs:   rec.Attrs,
+		})
+		if err != nil {
+			continue
+		}
+		data = append(data, '\n')
+
+		if f.shouldRotateLocked() {
+			f.rotateLocked()
+		}
+
+		n, err := f.file.Write(data)
+		if err != nil {
+			return fmt.Errorf("plasmalog: write %s: %w", f.path, err)
+		}
+		f.size += int64(n)
+	}
+	return nil
+}
+
+func (f *FileSink) shouldRotateLocked() bool {
+	if f.maxSize > 0 && f.size >= f.maxSize {
+		return true
+	}
+	if f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge {
+		return true
+	}
+	return false
+}
+
+func (f *FileSink) rotateLocked() {
+	f.file.Close()
+	rotated := f.path + "." + time.Now().UTC().Format(time.RFC3339)
+	os.Rename(f.path, rotated)
+	if err := f.openLocked(); err != nil {
+		// Best effort: fall back to the old handle staying closed is worse
+		// than a write error, so try once more against the original path.
+		f.file, _ = os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+}
+
+// Flush syncs the underlying file to disk.
+func (f *FileSink) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close flushes and closes the underlying file.
+func (f *FileSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
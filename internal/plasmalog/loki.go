@@ -0,0 +1,192 @@
+package plasmalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LokiConfig configures a LokiSink.
+type LokiConfig struct {
+	// URL is Loki's push endpoint, e.g. "http://loki:3100/loki/api/v1/push".
+	URL string
+	// Labels are attached to every stream this sink pushes, alongside a
+	// "level" label derived from each batch's records. Typically just
+	// {"service": "plasma-shield"}; nil is fine.
+	Labels map[string]string
+	// BatchSize flushes once this many records have accumulated. Defaults
+	// to 100 if <= 0.
+	BatchSize int
+	// FlushEvery flushes on a timer regardless of BatchSize, so a quiet
+	// period doesn't leave records buffered indefinitely. Defaults to 5s
+	// if <= 0.
+	FlushEvery time.Duration
+	// MaxRetries is how many additional attempts a failed push gets, with
+	// exponential backoff between them. Defaults to 3 if < 0.
+	MaxRetries int
+}
+
+// LokiSink batches Records and POSTs them to Loki's HTTP push API
+// (https://grafana.com/docs/loki/latest/reference/loki-http-api/#ingest-logs),
+// grouping each batch into one stream per distinct level so Loki's
+// label-indexed queries can filter ("{service=\"plasma-shield\",
+// level=\"ERROR\"}") without parsing log lines.
+type LokiSink struct {
+	cfg    LokiConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []Record
+
+	done chan struct{}
+}
+
+// NewLokiSink creates a LokiSink posting batches to cfg.URL.
+func NewLokiSink(cfg LokiConfig) *LokiSink {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushEvery <= 0 {
+		cfg.FlushEvery = 5 * time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 3
+	}
+	s := &LokiSink{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+		done:   make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+// Write implements Sink, buffering records until BatchSize is reached.
+func (s *LokiSink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	s.pending = append(s.pending, records...)
+	full := len(s.pending) >= s.cfg.BatchSize
+	s.mu.Unlock()
+
+	if full {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *LokiSink) flushLoop() {
+	ticker := time.NewTicker(s.cfg.FlushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// lokiPushRequest is the wire shape Loki's push endpoint expects.
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Flush POSTs any buffered records now, retrying transient failures with
+// exponential backoff before giving up.
+func (s *LokiSink) Flush() error {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	return s.send(batch)
+}
+
+// send groups batch into one stream per distinct level, each labeled with
+// cfg.Labels plus "level", and POSTs them as a single push request.
+func (s *LokiSink) send(batch []Record) error {
+	byLevel := make(map[string][][2]string)
+	for _, rec := range batch {
+		level := rec.Level.String()
+		line, err := json.Marshal(jsonRecord{
+			Time:    rec.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Level:   level,
+			Message: rec.Message,
+			Attrs:   rec.Attrs,
+		})
+		if err != nil {
+			continue
+		}
+		byLevel[level] = append(byLevel[level], [2]string{
+			strconv.FormatInt(rec.Time.UnixNano(), 10),
+			string(line),
+		})
+	}
+
+	req := lokiPushRequest{Streams: make([]lokiStream, 0, len(byLevel))}
+	for level, values := range byLevel {
+		labels := make(map[string]string, len(s.cfg.Labels)+1)
+		for k, v := range s.cfg.Labels {
+			labels[k] = v
+		}
+		labels["level"] = level
+		req.Streams = append(req.Streams, lokiStream{Stream: labels, Values: values})
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("plasmalog: marshal loki push request: %w", err)
+	}
+
+	backoff := 500 * time.Millisecond
+	var lastErr error
+	for attempt := 0; attempt <= s.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		httpReq, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("plasmalog: build loki push request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.client.Do(httpReq)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("loki push returned %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("plasmalog: loki push returned unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("plasmalog: loki push giving up after %d attempt(s): %w", s.cfg.MaxRetries+1, lastErr)
+}
+
+// Close stops the flush timer and sends any remaining buffered records.
+func (s *LokiSink) Close() error {
+	close(s.done)
+	return s.Flush()
+}
@@ -0,0 +1,279 @@
+// Package plasmalog is the shield's structured, leveled application log:
+// a log/slog.Logger backed by pluggable Sinks, replacing the ad hoc
+// json.Marshal-then-log.Println calls scattered across internal/proxy and
+// internal/bastion. Those calls hand-rolled their own JSON, dropped the
+// level (everything was effectively INFO), and could only ever go to
+// stdout -- this package fixes all three, mirroring internal/logsink's
+// Sink/MultiSink shape (Write/Flush/Close, one bounded queue and worker
+// goroutine per sink) but applied to leveled log Records instead of
+// logsink's proxy.LogEntry audit trail.
+package plasmalog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stable attribute keys shared across every call site that logs through
+// this package (internal/proxy.Handler, internal/proxy.ExecCheckHandler,
+// internal/bastion.LogStore), so a query against any configured sink can
+// filter on the same field name regardless of which component emitted it.
+const (
+	KeySourceIP  = "source_ip"
+	KeyAgentID   = "agent_id"
+	KeyTier      = "tier"
+	KeyDomain    = "domain"
+	KeyAction    = "action"
+	KeyReason    = "reason"
+	KeySessionID = "session_id"
+	KeyPrincipal = "principal"
+	KeyMethod    = "method"
+	KeyPath      = "path"
+	KeyRuleID    = "rule_id"
+	KeyMode      = "mode"
+	KeyLatencyMs = "latency_ms"
+	KeyClientIP  = "client_ip"
+)
+
+// defaultQueueCapacity bounds how many pending batches a single sink's
+// worker can fall behind by before MultiHandler starts dropping for it.
+// Same default as logsink.defaultQueueCapacity.
+const defaultQueueCapacity = 256
+
+// Record is one structured log line handed to a Sink: a slog.Record's
+// level/message/time plus its attributes flattened into a string map, so
+// every Sink's format (JSON, RFC 5424, Loki's line/label pairs) can
+// serialize it without re-walking slog.Record's internal attribute list.
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   map[string]string
+}
+
+// Sink is implemented by each logging destination plasmalog knows how to
+// write to. Implementations must be safe for concurrent use; MultiHandler
+// only ever calls a given Sink's methods from its own single worker
+// goroutine, but a Sink may also be used standalone.
+type Sink interface {
+	// Write delivers a batch of records to the sink.
+	Write(ctx context.Context, records []Record) error
+	// Flush forces any buffered records out immediately.
+	Flush() error
+	// Close flushes and releases the sink's resources.
+	Close() error
+}
+
+// SinkStats reports one configured sink's health, the plasmalog analogue
+// of logsink.SinkStats.
+type SinkStats struct {
+	Name        string    `json:"name"`
+	QueueDepth  int       `json:"queue_depth"`
+	QueueCap    int       `json:"queue_capacity"`
+	Dropped     uint64    `json:"dropped"`
+	Written     uint64    `json:"written"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastWriteAt time.Time `json:"last_write_at,omitempty"`
+}
+
+// namedSink pairs a configured Sink with its own bounded queue and worker
+// goroutine, so a slow or stuck destination (a stalled syslog TCP
+// connection, a rate-limited Loki push) only ever backs up its own queue.
+type namedSink struct {
+	name  string
+	sink  Sink
+	queue chan []Record
+	done  chan struct{}
+
+	dropped uint64 // atomic
+	written uint64 // atomic
+
+	mu          sync.Mutex
+	lastErr     error
+	lastWriteAt time.Time
+}
+
+func (ns *namedSink) run() {
+	defer close(ns.done)
+	for batch := range ns.queue {
+		err := ns.sink.Write(context.Background(), batch)
+
+		ns.mu.Lock()
+		ns.lastErr = err
+		ns.lastWriteAt = time.Now()
+		ns.mu.Unlock()
+
+		if err != nil {
+			continue
+		}
+		atomic.AddUint64(&ns.written, uint64(len(batch)))
+	}
+}
+
+// MultiHandler is a slog.Handler that fans every Record out to a set of
+// named Sinks, each behind its own bounded queue and worker goroutine. It
+// implements slog.Handler directly (rather than wrapping slog.NewJSONHandler
+// per sink) so a single logger call fans out once, with per-sink
+// backpressure handled the same way logsink.MultiSink handles it for the
+// durable audit trail.
+type MultiHandler struct {
+	sinks []*namedSink
+	attrs []slog.Attr
+	group string
+	level slog.Leveler
+}
+
+// NewMultiHandler starts one worker goroutine per entry in sinks, each
+// reading off a queue of capacity queueCapacity (defaultQueueCapacity if
+// queueCapacity <= 0). A sink whose queue fills up has the offending batch
+// dropped and counted (see Stats) rather than blocking the caller or any
+// other sink's delivery. level gates which records reach any sink at all;
+// slog.LevelInfo if nil.
+func NewMultiHandler(sinks map[string]Sink, queueCapacity int, level slog.Leveler) *MultiHandler {
+	if queueCapacity <= 0 {
+		queueCapacity = defaultQueueCapacity
+	}
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	h := &MultiHandler{sinks: make([]*namedSink, 0, len(sinks)), level: level}
+	for name, sink := range sinks {
+		ns := &namedSink{
+			name:  name,
+			sink:  sink,
+			queue: make(chan []Record, queueCapacity),
+			done:  make(chan struct{}),
+		}
+		h.sinks = append(h.sinks, ns)
+		go ns.run()
+	}
+	return h
+}
+
+// Enabled implements slog.Handler.
+func (h *MultiHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler, flattening r's attributes (plus any
+// bound on by WithAttrs and this handler's group prefix) into a Record and
+// enqueuing it on every sink.
+func (h *MultiHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]string, r.NumAttrs()+len(h.attrs))
+	for _, a := range h.attrs {
+		h.setAttr(attrs, a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.setAttr(attrs, a)
+		return true
+	})
+
+	record := Record{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   attrs,
+	}
+	batch := []Record{record}
+	for _, ns := range h.sinks {
+		select {
+		case ns.queue <- batch:
+		default:
+			atomic.AddUint64(&ns.dropped, 1)
+		}
+	}
+	return nil
+}
+
+func (h *MultiHandler) setAttr(attrs map[string]string, a slog.Attr) {
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if h.group != "" {
+		key = h.group + "." + key
+	}
+	attrs[key] = a.Value.String()
+}
+
+// WithAttrs implements slog.Handler, returning a new handler sharing this
+// one's sinks with attrs appended.
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &next
+}
+
+// WithGroup implements slog.Handler. Nested groups aren't a pattern this
+// codebase otherwise uses, so group just prefixes subsequent attribute
+// keys with "name." rather than building a nested attribute tree.
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	next := *h
+	if h.group != "" {
+		next.group = h.group + "." + name
+	} else {
+		next.group = name
+	}
+	return &next
+}
+
+// Stats reports every configured sink's current queue depth, drop count,
+// records written, and last error.
+func (h *MultiHandler) Stats() []SinkStats {
+	out := make([]SinkStats, 0, len(h.sinks))
+	for _, ns := range h.sinks {
+		ns.mu.Lock()
+		lastErr := ""
+		if ns.lastErr != nil {
+			lastErr = ns.lastErr.Error()
+		}
+		lastWriteAt := ns.lastWriteAt
+		ns.mu.Unlock()
+
+		out = append(out, SinkStats{
+			Name:        ns.name,
+			QueueDepth:  len(ns.queue),
+			QueueCap:    cap(ns.queue),
+			Dropped:     atomic.LoadUint64(&ns.dropped),
+			Written:     atomic.LoadUint64(&ns.written),
+			LastError:   lastErr,
+			LastWriteAt: lastWriteAt,
+		})
+	}
+	return out
+}
+
+// Close stops every sink's worker, waits for its queue to drain, and
+// closes the underlying Sink.
+func (h *MultiHandler) Close() error {
+	var firstErr error
+	for _, ns := range h.sinks {
+		close(ns.queue)
+		<-ns.done
+		if err := ns.sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// New builds a *slog.Logger backed by a MultiHandler over sinks. Returning
+// the concrete *MultiHandler alongside it lets callers register it with
+// GET /sinks (see cmd/proxy's equivalent for internal/logsink) and Close it
+// on shutdown.
+func New(sinks map[string]Sink, queueCapacity int, level slog.Leveler) (*slog.Logger, *MultiHandler) {
+	h := NewMultiHandler(sinks, queueCapacity, level)
+	return slog.New(h), h
+}
+
+// Default returns a *slog.Logger writing JSON lines to os.Stdout -- the
+// same destination and shape the code it replaces
+// (json.Marshal+log.Println) always wrote to -- for callers that don't
+// configure --log-sink explicitly.
+func Default() *slog.Logger {
+	logger, _ := New(map[string]Sink{"stdout": NewStdoutSink(nil)}, 0, nil)
+	return logger
+}
@@ -0,0 +1,117 @@
+package plasmalog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSink is a test double recording every batch it receives.
+type fakeSink struct {
+	mu      sync.Mutex
+	batches [][]Record
+	closed  bool
+}
+
+func (f *fakeSink) Write(ctx context.Context, records []Record) error {
+	f.mu.Lock()
+	f.batches = append(f.batches, records)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeSink) Flush() error { return nil }
+
+func (f *fakeSink) Close() error {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeSink) recordCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, b := range f.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestMultiHandlerFansOutToEverySink(t *testing.T) {
+	a, b := &fakeSink{}, &fakeSink{}
+	logger, handler := New(map[string]Sink{"a": a, "b": b}, 10, nil)
+	defer handler.Close()
+
+	logger.Info("request", slog.String(KeyAction, "allow"), slog.String(KeyDomain, "example.com"))
+
+	deadline := time.Now().Add(time.Second)
+	for a.recordCount() == 0 || b.recordCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for fan-out: a=%d b=%d", a.recordCount(), b.recordCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMultiHandlerRespectsLevel(t *testing.T) {
+	a := &fakeSink{}
+	logger, handler := New(map[string]Sink{"a": a}, 10, slog.LevelWarn)
+	defer handler.Close()
+
+	logger.Info("should be dropped")
+	logger.Warn("should pass")
+
+	deadline := time.Now().Add(time.Second)
+	for a.recordCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the warning record")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := a.recordCount(); n != 1 {
+		t.Errorf("expected exactly 1 record past the level filter, got %d", n)
+	}
+}
+
+func TestAllowSampler(t *testing.T) {
+	s := NewAllowSampler(1, 2)
+
+	if !s.Allow("agent-1") || !s.Allow("agent-1") {
+		t.Fatal("expected the first burst of allow-path records through")
+	}
+	if s.Allow("agent-1") {
+		t.Error("expected the bucket to be exhausted after burst")
+	}
+	if !s.Allow("agent-2") {
+		t.Error("expected a different key to have its own, unexhausted bucket")
+	}
+}
+
+func TestParseSinks(t *testing.T) {
+	sinks, err := ParseSinks("")
+	if err != nil {
+		t.Fatalf("ParseSinks(\"\"): %v", err)
+	}
+	if _, ok := sinks["stdout"]; !ok || len(sinks) != 1 {
+		t.Errorf("expected empty spec to parse to a single stdout sink, got %v", sinks)
+	}
+
+	sinks, err = ParseSinks("stdout,stdout")
+	if err != nil {
+		t.Fatalf("ParseSinks: %v", err)
+	}
+	if len(sinks) != 2 {
+		t.Errorf("expected duplicate sink types to get distinct names, got %v", sinks)
+	}
+
+	if _, err := ParseSinks("bogus"); err == nil {
+		t.Error("expected an unknown sink type to error")
+	}
+	if _, err := ParseSinks("file"); err == nil {
+		t.Error("expected a file sink with no path to error")
+	}
+}
@@ -0,0 +1,88 @@
+package plasmalog
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket is a single token bucket, refilled lazily on each allow
+// call -- the same scheme as internal/limiter's (unexported) bucket type,
+// reimplemented here rather than imported since it rate-limits log
+// volume, not proxy traffic, and has no business depending on that
+// package's RateLimitRule/Direction types.
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	rps      float64
+	burst    float64
+	lastFill time.Time
+}
+
+func newTokenBucket(rps float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		tokens:   float64(burst),
+		rps:      rps,
+		burst:    float64(burst),
+		lastFill: now,
+	}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elapsed := now.Sub(b.lastFill).Seconds()
+	if elapsed > 0 {
+		b.tokens += elapsed * b.rps
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.lastFill = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// AllowSampler rate-limits a noisy "allow" decision so one chatty agent's
+// routine traffic can't drown out the much rarer block/audit signal in
+// whatever's tailing the configured sinks. Each key (typically an agent
+// ID) gets its own token bucket; every non-allow record should bypass the
+// sampler entirely (see proxy.Handler.logRequestFull) since block/audit
+// events are exactly the ones sampling must never drop.
+type AllowSampler struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	rps     float64
+	burst   int
+	now     func() time.Time
+}
+
+// NewAllowSampler creates an AllowSampler allowing up to rps allow-path
+// records per second (with a burst of up to burst) per distinct key.
+func NewAllowSampler(rps float64, burst int) *AllowSampler {
+	return &AllowSampler{
+		buckets: make(map[string]*tokenBucket),
+		rps:     rps,
+		burst:   burst,
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether an allow-path record for key should be logged
+// (true) or sampled out (false).
+func (s *AllowSampler) Allow(key string) bool {
+	now := s.now()
+
+	s.mu.Lock()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = newTokenBucket(s.rps, s.burst, now)
+		s.buckets[key] = b
+	}
+	s.mu.Unlock()
+
+	return b.allow(now)
+}
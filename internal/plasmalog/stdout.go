@@ -0,0 +1,66 @@
+package plasmalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// StdoutSink writes one JSON object per Record, one per line, to an
+// io.Writer (os.Stdout if none is given) -- the default destination every
+// call site already wrote to before this package existed.
+type StdoutSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutSink returns a StdoutSink writing to w, or os.Stdout if w is
+// nil.
+func NewStdoutSink(w io.Writer) *StdoutSink {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &StdoutSink{w: w}
+}
+
+// jsonRecord is a Record's on-the-wire JSON shape: level and time given
+// their own fields the way most JSON log shippers (Loki, journald's
+// json-file driver, Datadog) expect them, with the rest carried as an
+// attrs object.
+type jsonRecord struct {
+	Time    string            `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"msg"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// Write implements Sink.
+func (s *StdoutSink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rec := range records {
+		data, err := json.Marshal(jsonRecord{
+			Time:    rec.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Level:   rec.Level.String(),
+			Message: rec.Message,
+			Attrs:   rec.Attrs,
+		})
+		if err != nil {
+			continue
+		}
+		data = append(data, '\n')
+		if _, err := s.w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush is a no-op: every Write already writes synchronously.
+func (s *StdoutSink) Flush() error { return nil }
+
+// Close is a no-op: StdoutSink doesn't own w's lifecycle.
+func (s *StdoutSink) Close() error { return nil }
@@ -0,0 +1,73 @@
+package plasmalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"sync"
+)
+
+// SyslogSink writes each Record to a syslog collector via the standard
+// library's log/syslog client, carrying the record's JSON encoding as the
+// message body and mapping its slog.Level onto the nearest syslog
+// severity so collector-side alerting can filter on priority alone.
+type SyslogSink struct {
+	mu sync.Mutex
+	w  *syslog.Writer
+}
+
+// NewSyslogSink dials a syslog collector at raddr over network ("udp" or
+// "tcp"; empty uses the local syslog daemon) and returns a SyslogSink
+// ready to Write, tagged with the plasma-shield process name.
+func NewSyslogSink(network, raddr string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, "plasma-shield")
+	if err != nil {
+		return nil, fmt.Errorf("plasmalog: dial syslog %s (%s): %w", raddr, network, err)
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Write implements Sink, sending each record as its own syslog message at
+// the severity matching its level.
+func (s *SyslogSink) Write(_ context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rec := range records {
+		body, err := json.Marshal(jsonRecord{
+			Time:    rec.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Level:   rec.Level.String(),
+			Message: rec.Message,
+			Attrs:   rec.Attrs,
+		})
+		if err != nil {
+			continue
+		}
+
+		var writeErr error
+		switch {
+		case rec.Level >= slog.LevelError:
+			writeErr = s.w.Err(string(body))
+		case rec.Level >= slog.LevelWarn:
+			writeErr = s.w.Warning(string(body))
+		default:
+			writeErr = s.w.Info(string(body))
+		}
+		if writeErr != nil {
+			return fmt.Errorf("plasmalog: write syslog: %w", writeErr)
+		}
+	}
+	return nil
+}
+
+// Flush is a no-op: every Write already sends its messages synchronously.
+func (s *SyslogSink) Flush() error { return nil }
+
+// Close closes the underlying syslog connection.
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Close()
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/auth"
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+)
+
+// fleetEventRequest is the body of POST /fleet/events.
+type fleetEventRequest struct {
+	FromAgentID string          `json:"from_agent_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+}
+
+// serveFleet dispatches the inter-agent event bus endpoints mounted under
+// /fleet/ on the same tenant-token-authenticated path ServeHTTP otherwise
+// uses for /agent/{id}/.... It's handled here rather than forwarded to an
+// agent backend, since the shield itself is the destination.
+func (h *ReverseHandler) serveFleet(w http.ResponseWriter, r *http.Request, tenantID string, policy *auth.Policy) {
+	switch {
+	case r.URL.Path == "/fleet/events" && r.Method == http.MethodPost:
+		h.publishFleetEvent(w, r, tenantID, policy)
+	case r.URL.Path == "/fleet/deadletter" && r.Method == http.MethodGet:
+		h.fleetDeadLetters(w, r)
+	case strings.HasSuffix(r.URL.Path, "/mode") && strings.HasPrefix(r.URL.Path, "/fleet/tenants/") && r.Method == http.MethodPost:
+		h.setFleetTenantMode(w, r, tenantID)
+	default:
+		h.jsonError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// publishFleetEvent handles POST /fleet/events: an agent publishes an
+// event, signed with its tenant's shared secret (see fleet.Sign), for
+// delivery to every peer it fleet.Manager.CanCommunicate with.
+func (h *ReverseHandler) publishFleetEvent(w http.ResponseWriter, r *http.Request, tenantID string, policy *auth.Policy) {
+	if h.bus == nil {
+		h.jsonError(w, "Fleet event bus is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		h.jsonError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var req fleetEventRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.jsonError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.FromAgentID == "" || req.Type == "" {
+		h.jsonError(w, "from_agent_id and type are required", http.StatusBadRequest)
+		return
+	}
+	if !policy.Allows(req.FromAgentID, http.MethodPost, "/fleet/events") {
+		h.jsonError(w, "Forbidden: token policy does not permit this agent", http.StatusForbidden)
+		return
+	}
+
+	if secret := h.fleet.TenantSecret(tenantID); len(secret) > 0 {
+		sig := r.Header.Get(fleet.SignatureHeader)
+		if sig == "" {
+			h.jsonError(w, "Missing "+fleet.SignatureHeader+" header", http.StatusUnauthorized)
+			return
+		}
+		// Verify over the inner event fields only, the same bytes the
+		// agent actually signed (not the outer publish envelope).
+		inner, err := json.Marshal(struct {
+			FromAgentID string          `json:"from_agent_id"`
+			Type        string          `json:"type"`
+			Payload     json.RawMessage `json:"payload,omitempty"`
+		}(req))
+		if err != nil {
+			h.jsonError(w, "Failed to canonicalize event", http.StatusInternalServerError)
+			return
+		}
+		if err := fleet.VerifySignature(secret, sig, inner, time.Now()); err != nil {
+			h.jsonError(w, "Invalid signature: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	event := fleet.Event{
+		ID:      newEventID(),
+		Type:    req.Type,
+		Payload: req.Payload,
+	}
+	queued, err := h.bus.Publish(req.FromAgentID, event)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "ok",
+		"event_id":     event.ID,
+		"peers_queued": queued,
+	})
+}
+
+// fleetDeadLetters handles GET /fleet/deadletter: the event deliveries
+// that exhausted their retries, for operators to inspect and replay.
+func (h *ReverseHandler) fleetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if h.bus == nil {
+		h.jsonError(w, "Fleet event bus is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deadletter": h.bus.DeadLetters(),
+	})
+}
+
+// setFleetTenantMode handles POST /fleet/tenants/{id}/mode: flips a
+// tenant between Isolated and Fleet mode. The caller's token must be
+// scoped to the tenant it's flipping -- there's no separate operator
+// credential here, so a tenant's own token is what authorizes it.
+func (h *ReverseHandler) setFleetTenantMode(w http.ResponseWriter, r *http.Request, tenantID string) {
+	path := strings.TrimPrefix(r.URL.Path, "/fleet/tenants/")
+	targetTenantID := strings.TrimSuffix(path, "/mode")
+	if targetTenantID == "" || targetTenantID != tenantID {
+		h.jsonError(w, "Forbidden: token is not scoped to this tenant", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.jsonError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	switch fleet.Mode(req.Mode) {
+	case fleet.Isolated, fleet.Fleet:
+		h.fleet.SetMode(tenantID, fleet.Mode(req.Mode))
+	default:
+		h.jsonError(w, "Invalid mode. Use: isolated, fleet", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "ok",
+		"tenant": tenantID,
+		"mode":   req.Mode,
+	})
+}
+
+// newEventID generates an opaque event ID, mirroring randomToken's use of
+// crypto/rand elsewhere in the codebase.
+func newEventID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand failing is effectively unrecoverable; fall back to a
+		// timestamp so delivery still proceeds rather than erroring out.
+		return "evt_" + time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return "evt_" + hex.EncodeToString(buf)
+}
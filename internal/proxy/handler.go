@@ -1,12 +1,25 @@
 package proxy
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/accesslog"
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/mitmca"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
+	"github.com/Extra-Chill/plasma-shield/internal/ratelimit"
 )
 
 // LogEntry represents a logged request.
@@ -29,11 +42,40 @@ type AgentRegistry interface {
 	ValidateAgentIP(ip string) (agentID string, tier string, valid bool)
 }
 
+// CertRegistry validates agent mTLS client certificates. An AgentRegistry
+// that also implements this (fleet.Manager does) is checked first when
+// the connection presents a peer certificate, so agent identity survives
+// IP changes (NAT, roaming) instead of falling back to ValidateAgentIP.
+// The certificate's chain-of-trust is verified at the TLS handshake layer
+// (see tls.Config.ClientCAs on the listener); this only resolves which
+// agent it belongs to.
+type CertRegistry interface {
+	ValidateAgentCert(cert *x509.Certificate) (agentID string, tier string, valid bool)
+}
+
+// ProxyAuthRegistry validates agent credentials carried in a
+// Proxy-Authorization header (fleet.Manager does), the fallback checked
+// when an agent's source IP isn't registered -- e.g. several agents
+// sharing a NAT/egress IP, which ValidateAgentIP alone can't tell apart.
+type ProxyAuthRegistry interface {
+	// ValidateAgentBasic checks decoded "Basic" user/pass credentials.
+	ValidateAgentBasic(user, pass string) (agentID string, tier string, valid bool)
+	// ValidateAgentBearer checks a "Bearer" token.
+	ValidateAgentBearer(token string) (agentID string, tier string, valid bool)
+}
+
 // Handler is the main proxy HTTP handler.
 type Handler struct {
-	inspector *Inspector
-	registry  AgentRegistry
-	client    *http.Client
+	inspector    *Inspector
+	registry     AgentRegistry
+	client       *http.Client
+	accessLog    accesslog.Logger
+	limiter      *limiter.Limiter
+	breaker      *limiter.Breaker
+	metrics      *limiter.Metrics
+	logger       *slog.Logger
+	allowSampler *plasmalog.AllowSampler
+	mitmCA       *mitmca.CA
 }
 
 // HandlerOption configures the Handler.
@@ -46,6 +88,71 @@ func WithAgentRegistry(r AgentRegistry) HandlerOption {
 	}
 }
 
+// WithAccessLog sets the structured access log sink. If not set, records
+// are discarded (accesslog.NopLogger).
+func WithAccessLog(logger accesslog.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.accessLog = logger
+	}
+}
+
+// WithLimiter sets the rate limiter applied to outbound requests. If not
+// set, requests are never rate limited.
+func WithLimiter(l *limiter.Limiter) HandlerOption {
+	return func(h *Handler) {
+		h.limiter = l
+	}
+}
+
+// WithBreaker sets the circuit breaker applied per (agent, upstream host).
+// If not set, requests are never short-circuited.
+func WithBreaker(b *limiter.Breaker) HandlerOption {
+	return func(h *Handler) {
+		h.breaker = b
+	}
+}
+
+// WithMetrics sets the Prometheus-style metrics registry. If not set,
+// metrics are not recorded.
+func WithMetrics(m *limiter.Metrics) HandlerOption {
+	return func(h *Handler) {
+		h.metrics = m
+	}
+}
+
+// WithLogger sets the structured application logger that logRequestFull
+// emits to (see internal/plasmalog). If not set, records go to
+// plasmalog.Default() -- JSON lines on stdout, matching the
+// json.Marshal+log.Println behavior this replaced.
+func WithLogger(logger *slog.Logger) HandlerOption {
+	return func(h *Handler) {
+		h.logger = logger
+	}
+}
+
+// WithMITM turns on MITM interception of allowed CONNECT tunnels: instead
+// of blindly relaying encrypted bytes, handleConnect TLS-terminates the
+// client's tunnel with a leaf certificate ca mints for the CONNECT host,
+// then originates a second, independent TLS connection upstream and routes
+// each decrypted request back through Inspector -- so a rule keyed on the
+// Host header inside the tunnel (which the CONNECT line alone can't see)
+// still applies. Unset (the default) tunnels raw bytes, as before.
+func WithMITM(ca *mitmca.CA) HandlerOption {
+	return func(h *Handler) {
+		h.mitmCA = ca
+	}
+}
+
+// WithAllowSampleRate rate-limits "action=allow" records per agent so a
+// chatty agent's routine traffic can't drown out the far rarer block/audit
+// signal in whatever's tailing the configured log sinks. Block and audit
+// records are never sampled. Unset (the default) logs every allow too.
+func WithAllowSampleRate(rps float64, burst int) HandlerOption {
+	return func(h *Handler) {
+		h.allowSampler = plasmalog.NewAllowSampler(rps, burst)
+	}
+}
+
 // NewHandler creates a new proxy handler.
 func NewHandler(inspector *Inspector, opts ...HandlerOption) *Handler {
 	h := &Handler{
@@ -57,6 +164,8 @@ func NewHandler(inspector *Inspector, opts ...HandlerOption) *Handler {
 				return http.ErrUseLastResponse
 			},
 		},
+		accessLog: accesslog.NopLogger{},
+		logger:    plasmalog.Default(),
 	}
 	for _, opt := range opts {
 		opt(h)
@@ -76,14 +185,23 @@ func extractClientIP(r *http.Request) string {
 
 // ServeHTTP handles incoming proxy requests.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Validate source IP against agent registry
+	// Validate the connection's identity: a peer certificate, if one was
+	// presented and the registry knows how to check it, takes priority
+	// over the (spoofable, NAT-unstable) source IP.
 	sourceIP := extractClientIP(r)
-	agentID, tier, valid := h.validateSource(sourceIP)
+	agentID, tier, valid := h.validateSource(r, sourceIP)
 
 	if !valid {
-		log.Printf(`{"timestamp":"%s","source_ip":"%s","action":"reject","reason":"unregistered agent IP"}`,
-			time.Now().UTC().Format(time.RFC3339), sourceIP)
-		http.Error(w, "Forbidden: unregistered agent", http.StatusForbidden)
+		h.logger.Warn("proxy request",
+			slog.String(plasmalog.KeySourceIP, sourceIP),
+			slog.String(plasmalog.KeyAction, "reject"),
+			slog.String(plasmalog.KeyReason, "unauthenticated agent"),
+		)
+		// RFC 7235: challenge for credentials rather than a bare 403, so
+		// HTTP proxy clients (curl, requests, browsers) prompt and retry
+		// automatically instead of failing outright.
+		w.Header().Set("Proxy-Authenticate", `Basic realm="plasma-shield", Bearer realm="plasma-shield"`)
+		http.Error(w, "Proxy Authentication Required", http.StatusProxyAuthRequired)
 		return
 	}
 
@@ -91,91 +209,288 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r.Header.Set("X-Agent-ID", agentID)
 	r.Header.Set("X-Agent-Tier", tier)
 
+	if h.metrics != nil {
+		h.metrics.IncActiveConnections(string(limiter.Outbound))
+		defer h.metrics.DecActiveConnections(string(limiter.Outbound))
+
+		requestStart := time.Now()
+		method := r.Method
+		defer func() {
+			h.metrics.ObserveProxyRequestDuration(tier, method, time.Since(requestStart).Seconds())
+		}()
+	}
+
 	if r.Method == http.MethodConnect {
-		h.handleConnect(w, r, sourceIP, agentID)
+		h.handleConnect(w, r, sourceIP, agentID, tier)
 		return
 	}
-	h.handleHTTP(w, r, sourceIP, agentID)
+	h.handleHTTP(w, r, sourceIP, agentID, tier)
 }
 
-// validateSource checks if the source IP is a registered agent.
-func (h *Handler) validateSource(ip string) (agentID, tier string, valid bool) {
+// validateSource resolves the calling agent's identity, preferring a
+// presented mTLS client certificate over the source IP when the registry
+// supports cert validation, then falling back to a Proxy-Authorization
+// header (Basic or Bearer) when the registry supports that and the source
+// IP isn't registered -- agents behind a shared NAT/egress IP still need
+// telling apart.
+func (h *Handler) validateSource(r *http.Request, ip string) (agentID, tier string, valid bool) {
 	if h.registry == nil {
 		// No registry configured - allow all (backwards compatibility)
 		return "unknown", "unknown", true
 	}
-	return h.registry.ValidateAgentIP(ip)
+
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if certRegistry, ok := h.registry.(CertRegistry); ok {
+			return certRegistry.ValidateAgentCert(r.TLS.PeerCertificates[0])
+		}
+	}
+
+	if agentID, tier, valid := h.registry.ValidateAgentIP(ip); valid {
+		return agentID, tier, valid
+	}
+
+	if authRegistry, ok := h.registry.(ProxyAuthRegistry); ok {
+		return validateProxyAuthorization(r, authRegistry)
+	}
+
+	return "", "", false
+}
+
+// validateProxyAuthorization checks the Proxy-Authorization header against
+// authRegistry's Basic/Bearer credentials.
+func validateProxyAuthorization(r *http.Request, authRegistry ProxyAuthRegistry) (agentID, tier string, valid bool) {
+	scheme, value, ok := strings.Cut(r.Header.Get("Proxy-Authorization"), " ")
+	if !ok {
+		return "", "", false
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", "", false
+		}
+		user, pass, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return "", "", false
+		}
+		return authRegistry.ValidateAgentBasic(user, pass)
+	case "bearer":
+		return authRegistry.ValidateAgentBearer(value)
+	default:
+		return "", "", false
+	}
 }
 
 // handleHTTP handles regular HTTP proxy requests.
-func (h *Handler) handleHTTP(w http.ResponseWriter, r *http.Request, sourceIP, agentID string) {
+func (h *Handler) handleHTTP(w http.ResponseWriter, r *http.Request, sourceIP, agentID, tier string) {
+	start := time.Now()
 	domain := h.inspector.ExtractHost(r)
-	agentToken := h.inspector.ExtractAgentToken(r)
 
 	// Check if request should be blocked (mode-aware)
-	shouldBlock, ruleMatched, reason := h.inspector.CheckRequest(r)
+	ruleEvalStart := time.Now()
+	shouldBlock, ruleMatched, reason, rl := h.inspector.CheckRequest(r)
+	if h.metrics != nil {
+		h.metrics.ObserveRuleEval(string(limiter.Outbound), time.Since(ruleEvalStart).Seconds())
+	}
 	action := "allow"
+	decision := accesslog.DecisionForward
 	if shouldBlock {
 		action = "block"
+		decision = accesslog.DecisionBlock
 	} else if ruleMatched {
 		action = "audit" // Would have blocked, but in audit mode
+		decision = accesslog.DecisionInspect
 	}
 
-	h.logRequestFull(sourceIP, agentID, agentToken, domain, r.Method, action, reason)
+	h.logRequestFull(sourceIP, agentID, tier, domain, r.Method, action, reason)
+
+	crw := accesslog.NewResponseWriter(w)
+	defer func() {
+		h.accessLog.Log(accesslog.Record{
+			Time:       start.UTC(),
+			Agent:      agentID,
+			Method:     r.Method,
+			Domain:     domain,
+			Decision:   decision,
+			StatusCode: crw.Status(),
+			ReqBytes:   r.ContentLength,
+			RespBytes:  crw.BytesWritten(),
+			Latency:    time.Since(start),
+		})
+	}()
+
+	if rl != nil && !rl.Allowed {
+		h.applyRateLimit(crw, agentID, tier, r.Method, rl)
+		return
+	}
 
 	if shouldBlock {
-		http.Error(w, "Blocked by Plasma Shield: "+reason, http.StatusForbidden)
+		h.recordMetric(agentID, tier, r.Method, decisionLabel(decision))
+		http.Error(crw, "Blocked by Plasma Shield: "+reason, http.StatusForbidden)
+		return
+	}
+	h.applyRateLimit(crw, agentID, tier, r.Method, rl)
+
+	if h.limiter != nil && !h.limiter.Allow("", agentID, tier, domain, limiter.Outbound) {
+		h.recordMetric(agentID, tier, r.Method, "ratelimited")
+		http.Error(crw, "Too many requests", http.StatusTooManyRequests)
 		return
 	}
 
+	if h.breaker != nil {
+		if allowed, retryAfter := h.breaker.Allow(agentID, domain); !allowed {
+			h.recordMetric(agentID, tier, r.Method, "breaker_open")
+			crw.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			http.Error(crw, "Upstream circuit open for "+domain, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
 	// Create outgoing request
 	outReq, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), r.Body)
 	if err != nil {
-		http.Error(w, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
+		http.Error(crw, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Copy headers, but remove proxy-specific ones
 	copyHeaders(outReq.Header, r.Header)
 	outReq.Header.Del("Proxy-Connection")
-	outReq.Header.Del("X-Agent-Token") // Don't leak agent token to upstream
+	outReq.Header.Del("X-Agent-Token")       // Don't leak agent token to upstream
+	outReq.Header.Del("Proxy-Authorization") // Don't leak agent credentials to upstream
 
 	// Forward the request
+	upstreamStart := time.Now()
 	resp, err := h.client.Do(outReq)
+	if h.metrics != nil {
+		h.metrics.ObserveUpstreamResponse(string(limiter.Outbound), agentID, time.Since(upstreamStart).Seconds())
+	}
 	if err != nil {
-		http.Error(w, "Upstream error: "+err.Error(), http.StatusBadGateway)
+		if h.breaker != nil {
+			h.breaker.RecordResult(agentID, domain, false)
+		}
+		h.recordMetric(agentID, tier, r.Method, "bad_gateway")
+		http.Error(crw, "Upstream error: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 	defer resp.Body.Close()
 
+	if h.breaker != nil {
+		h.breaker.RecordResult(agentID, domain, resp.StatusCode < 500)
+	}
+	h.recordMetric(agentID, tier, r.Method, decisionLabel(decision))
+
 	// Copy response headers
-	copyHeaders(w.Header(), resp.Header)
-	w.WriteHeader(resp.StatusCode)
+	copyHeaders(crw.Header(), resp.Header)
+	crw.WriteHeader(resp.StatusCode)
 
 	// Copy response body
-	io.Copy(w, resp.Body)
+	io.Copy(crw, resp.Body)
+}
+
+// recordMetric increments plasma_requests_total for the outbound direction
+// and plasma_proxy_requests_total for (agent, tier, action, method), if a
+// metrics registry is configured.
+func (h *Handler) recordMetric(agentID, tier, method, decision string) {
+	if h.metrics != nil {
+		h.metrics.IncRequests("", agentID, string(limiter.Outbound), decision)
+		h.metrics.IncProxyRequest(agentID, tier, decision, method)
+	}
+}
+
+// applyRateLimit applies rl -- the result of a matched rule's rate_limit
+// spec, nil if it had none -- to w: a 429 with Retry-After if the bucket
+// was exhausted (true return, caller should stop), or an
+// X-RateLimit-Remaining header on the eventual response otherwise (false
+// return, caller continues as normal).
+func (h *Handler) applyRateLimit(w http.ResponseWriter, agentID, tier, method string, rl *ratelimit.Result) bool {
+	if rl == nil {
+		return false
+	}
+	if !rl.Allowed {
+		h.recordMetric(agentID, tier, method, "ratelimited")
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(rl.RetryAfter.Seconds())+1))
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return true
+	}
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", rl.Remaining))
+	return false
+}
+
+// decisionLabel maps an accesslog.Decision to the metric label used for
+// plasma_requests_total.
+func decisionLabel(d accesslog.Decision) string {
+	switch d {
+	case accesslog.DecisionBlock:
+		return "block"
+	case accesslog.DecisionInspect:
+		return "inspect"
+	default:
+		return "forward"
+	}
 }
 
 // handleConnect handles HTTPS CONNECT tunnels.
-func (h *Handler) handleConnect(w http.ResponseWriter, r *http.Request, sourceIP, agentID string) {
+func (h *Handler) handleConnect(w http.ResponseWriter, r *http.Request, sourceIP, agentID, tier string) {
+	start := time.Now()
 	domain := h.inspector.ExtractHost(r)
-	agentToken := h.inspector.ExtractAgentToken(r)
 
 	// Check if request should be blocked (mode-aware)
-	shouldBlock, ruleMatched, reason := h.inspector.CheckRequest(r)
+	ruleEvalStart := time.Now()
+	shouldBlock, ruleMatched, reason, rl := h.inspector.CheckRequest(r)
+	if h.metrics != nil {
+		h.metrics.ObserveRuleEval(string(limiter.Outbound), time.Since(ruleEvalStart).Seconds())
+	}
 	action := "allow"
+	decision := accesslog.DecisionForward
 	if shouldBlock {
 		action = "block"
+		decision = accesslog.DecisionBlock
 	} else if ruleMatched {
 		action = "audit"
+		decision = accesslog.DecisionInspect
 	}
 
-	h.logRequestFull(sourceIP, agentID, agentToken, domain, "CONNECT", action, reason)
+	h.logRequestFull(sourceIP, agentID, tier, domain, "CONNECT", action, reason)
+	defer func() {
+		h.accessLog.Log(accesslog.Record{
+			Time:     start.UTC(),
+			Agent:    agentID,
+			Method:   "CONNECT",
+			Domain:   domain,
+			SNI:      domain, // the CONNECT host is what the client's TLS ClientHello SNI will match
+			Decision: decision,
+			Latency:  time.Since(start),
+		})
+	}()
+
+	if rl != nil && !rl.Allowed {
+		h.applyRateLimit(w, agentID, tier, "CONNECT", rl)
+		return
+	}
 
 	if shouldBlock {
+		h.recordMetric(agentID, tier, "CONNECT", decisionLabel(decision))
 		http.Error(w, "Blocked by Plasma Shield: "+reason, http.StatusForbidden)
 		return
 	}
+	h.applyRateLimit(w, agentID, tier, "CONNECT", rl)
+
+	if h.limiter != nil && !h.limiter.Allow("", agentID, tier, domain, limiter.Outbound) {
+		h.recordMetric(agentID, tier, "CONNECT", "ratelimited")
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	if h.breaker != nil {
+		if allowed, retryAfter := h.breaker.Allow(agentID, domain); !allowed {
+			h.recordMetric(agentID, tier, "CONNECT", "breaker_open")
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			http.Error(w, "Upstream circuit open for "+domain, http.StatusServiceUnavailable)
+			return
+		}
+	}
 
 	// Connect to the target host
 	targetHost := r.Host
@@ -183,12 +498,33 @@ func (h *Handler) handleConnect(w http.ResponseWriter, r *http.Request, sourceIP
 		targetHost = r.URL.Host
 	}
 
+	dialStart := time.Now()
 	targetConn, err := net.DialTimeout("tcp", targetHost, 10*time.Second)
+	if h.metrics != nil {
+		h.metrics.ObserveUpstreamResponse(string(limiter.Outbound), agentID, time.Since(dialStart).Seconds())
+	}
 	if err != nil {
+		if h.breaker != nil {
+			h.breaker.RecordResult(agentID, domain, false)
+		}
+		h.recordMetric(agentID, tier, "CONNECT", "bad_gateway")
 		http.Error(w, "Failed to connect to target: "+err.Error(), http.StatusBadGateway)
 		return
 	}
 	defer targetConn.Close()
+	if h.breaker != nil {
+		h.breaker.RecordResult(agentID, domain, true)
+	}
+	h.recordMetric(agentID, tier, "CONNECT", decisionLabel(decision))
+
+	if h.mitmCA != nil {
+		// The preflight dial above only confirmed the target is reachable;
+		// the MITM path originates its own upstream TLS connection(s) per
+		// decrypted request instead of reusing this raw one.
+		targetConn.Close()
+		h.handleConnectMITM(w, sourceIP, agentID, tier, domain, targetHost)
+		return
+	}
 
 	// Hijack the client connection
 	hijacker, ok := w.(http.Hijacker)
@@ -224,25 +560,241 @@ func (h *Handler) handleConnect(w http.ResponseWriter, r *http.Request, sourceIP
 	<-done
 }
 
-// logRequestFull logs a request with full context.
-func (h *Handler) logRequestFull(sourceIP, agentID, agentToken, domain, method, action, reason string) {
-	entry := LogEntry{
-		Timestamp:  time.Now().UTC(),
-		SourceIP:   sourceIP,
-		AgentID:    agentID,
-		AgentToken: agentToken,
-		Domain:     domain,
-		Method:     method,
-		Action:     action,
-		Reason:     reason,
+// handleConnectMITM TLS-terminates an allowed CONNECT tunnel with a leaf
+// certificate h.mitmCA mints for domain, instead of relaying raw bytes, so
+// each decrypted request can be routed back through h.inspector. It hands
+// the handshaked connection to an http.Server so both HTTP/1.1 and (via the
+// client's negotiated ALPN) HTTP/2 requests inside the tunnel are served.
+func (h *Handler) handleConnectMITM(w http.ResponseWriter, sourceIP, agentID, tier, domain, targetHost string) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, "Failed to hijack connection: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		clientConn.Close()
+		return
+	}
+
+	tlsConn := tls.Server(clientConn, &tls.Config{
+		GetCertificate: h.mitmCA.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return
+	}
+	negotiated := tlsConn.ConnectionState().NegotiatedProtocol
+
+	// Each decrypted request gets its own upstream TLS connection, dialed
+	// fresh against targetHost with the same ALPN the client negotiated
+	// with us, so e.g. an h2 tunnel stays h2 end to end.
+	transport := &http.Transport{
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return tls.Dial(network, targetHost, &tls.Config{
+				ServerName: domain,
+				NextProtos: nextProtosFor(negotiated),
+			})
+		},
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: transport,
+	}
+
+	listener := newSingleConnListener(tlsConn)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.handleMITMRequest(w, r, client, sourceIP, agentID, tier)
+		}),
+		ConnState: func(c net.Conn, state http.ConnState) {
+			if state == http.StateClosed || state == http.StateHijacked {
+				listener.Close()
+			}
+		},
+	}
+	srv.Serve(listener)
+}
+
+// handleMITMRequest applies the same rule check, rate limiting, and
+// circuit breaking handleHTTP applies to a plain-text request to a request
+// decrypted out of a MITM'd CONNECT tunnel, then forwards it upstream via
+// client.
+func (h *Handler) handleMITMRequest(w http.ResponseWriter, r *http.Request, client *http.Client, sourceIP, agentID, tier string) {
+	start := time.Now()
+	domain := h.inspector.ExtractHost(r)
+
+	ruleEvalStart := time.Now()
+	shouldBlock, ruleMatched, reason, rl := h.inspector.CheckRequest(r)
+	if h.metrics != nil {
+		h.metrics.ObserveRuleEval(string(limiter.Outbound), time.Since(ruleEvalStart).Seconds())
+	}
+	action := "allow"
+	decision := accesslog.DecisionForward
+	if shouldBlock {
+		action = "block"
+		decision = accesslog.DecisionBlock
+	} else if ruleMatched {
+		action = "audit"
+		decision = accesslog.DecisionInspect
+	}
+
+	h.logRequestFull(sourceIP, agentID, tier, domain, r.Method, action, reason)
+
+	crw := accesslog.NewResponseWriter(w)
+	defer func() {
+		h.accessLog.Log(accesslog.Record{
+			Time:       start.UTC(),
+			Agent:      agentID,
+			Method:     r.Method,
+			Domain:     domain,
+			SNI:        domain,
+			Decision:   decision,
+			StatusCode: crw.Status(),
+			ReqBytes:   r.ContentLength,
+			RespBytes:  crw.BytesWritten(),
+			Latency:    time.Since(start),
+		})
+	}()
+
+	if rl != nil && !rl.Allowed {
+		h.applyRateLimit(crw, agentID, tier, r.Method, rl)
+		return
+	}
+
+	if shouldBlock {
+		h.recordMetric(agentID, tier, r.Method, decisionLabel(decision))
+		http.Error(crw, "Blocked by Plasma Shield: "+reason, http.StatusForbidden)
+		return
+	}
+	h.applyRateLimit(crw, agentID, tier, r.Method, rl)
+
+	if h.limiter != nil && !h.limiter.Allow("", agentID, tier, domain, limiter.Outbound) {
+		h.recordMetric(agentID, tier, r.Method, "ratelimited")
+		http.Error(crw, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	if h.breaker != nil {
+		if allowed, retryAfter := h.breaker.Allow(agentID, domain); !allowed {
+			h.recordMetric(agentID, tier, r.Method, "breaker_open")
+			crw.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			http.Error(crw, "Upstream circuit open for "+domain, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	outReq, err := http.NewRequestWithContext(r.Context(), r.Method, "https://"+r.Host+r.URL.RequestURI(), r.Body)
+	if err != nil {
+		http.Error(crw, "Failed to create request: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	copyHeaders(outReq.Header, r.Header)
+	outReq.Header.Del("Proxy-Connection")
+	outReq.Header.Del("X-Agent-Token")
+	outReq.Header.Del("Proxy-Authorization")
+
+	upstreamStart := time.Now()
+	resp, err := client.Do(outReq)
+	if h.metrics != nil {
+		h.metrics.ObserveUpstreamResponse(string(limiter.Outbound), agentID, time.Since(upstreamStart).Seconds())
+	}
+	if err != nil {
+		if h.breaker != nil {
+			h.breaker.RecordResult(agentID, domain, false)
+		}
+		h.recordMetric(agentID, tier, r.Method, "bad_gateway")
+		http.Error(crw, "Upstream error: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if h.breaker != nil {
+		h.breaker.RecordResult(agentID, domain, resp.StatusCode < 500)
+	}
+	h.recordMetric(agentID, tier, r.Method, decisionLabel(decision))
+
+	copyHeaders(crw.Header(), resp.Header)
+	crw.WriteHeader(resp.StatusCode)
+	io.Copy(crw, resp.Body)
+}
+
+// nextProtosFor returns the ALPN protocol list to offer the upstream TLS
+// dial, matching whatever protocol the client negotiated with us so e.g. an
+// h2 tunnel stays h2 end to end. An empty negotiated protocol (the client
+// didn't request ALPN) falls back to plain HTTP/1.1.
+func nextProtosFor(negotiated string) []string {
+	if negotiated == "" {
+		return []string{"http/1.1"}
 	}
-	data, _ := json.Marshal(entry)
-	log.Println(string(data))
+	return []string{negotiated}
 }
 
-// logRequest logs a request to stdout (legacy, no source info).
-func (h *Handler) logRequest(agentToken, domain, method, action, reason string) {
-	h.logRequestFull("", "", agentToken, domain, method, action, reason)
+// singleConnListener is a net.Listener that yields exactly one
+// already-established connection to the first Accept call, then blocks
+// until Close is called, so a hijacked MITM tunnel can be handed to
+// http.Server.Serve without it trying to Accept a real socket.
+type singleConnListener struct {
+	conn chan net.Conn
+	addr net.Addr
+	once sync.Once
+}
+
+func newSingleConnListener(conn net.Conn) *singleConnListener {
+	ch := make(chan net.Conn, 1)
+	ch <- conn
+	return &singleConnListener{conn: ch, addr: conn.LocalAddr()}
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	conn, ok := <-l.conn
+	if !ok {
+		return nil, io.EOF
+	}
+	return conn, nil
+}
+
+func (l *singleConnListener) Close() error {
+	l.once.Do(func() { close(l.conn) })
+	return nil
+}
+
+func (l *singleConnListener) Addr() net.Addr { return l.addr }
+
+// logRequestFull logs a request with full context as a slog.Record with
+// plasmalog's stable attribute keys. Allow-path records are subject to
+// allowSampler, if configured; block and audit records always go through,
+// since those are exactly the signal sampling exists to protect.
+func (h *Handler) logRequestFull(sourceIP, agentID, tier, domain, method, action, reason string) {
+	if action == "allow" && h.allowSampler != nil && !h.allowSampler.Allow(agentID) {
+		return
+	}
+
+	level := slog.LevelInfo
+	if action == "block" {
+		level = slog.LevelWarn
+	}
+	h.logger.Log(context.Background(), level, "proxy request",
+		slog.String(plasmalog.KeySourceIP, sourceIP),
+		slog.String(plasmalog.KeyAgentID, agentID),
+		slog.String(plasmalog.KeyTier, tier),
+		slog.String(plasmalog.KeyDomain, domain),
+		slog.String("method", method),
+		slog.String(plasmalog.KeyAction, action),
+		slog.String(plasmalog.KeyReason, reason),
+	)
 }
 
 // copyHeaders copies HTTP headers from src to dst.
@@ -256,8 +808,9 @@ func copyHeaders(dst, src http.Header) {
 
 // ExecCheckRequest is the request body for /exec/check.
 type ExecCheckRequest struct {
-	Command    string `json:"command"`
-	AgentToken string `json:"agent_token,omitempty"`
+	Command    string   `json:"command"`
+	Argv       []string `json:"argv,omitempty"` // structured command argv, for rules matching via body_jsonpath (see rules.Engine.CheckCommandWithArgv)
+	AgentToken string   `json:"agent_token,omitempty"`
 }
 
 // ExecCheckResponse is the response body for /exec/check.
@@ -269,13 +822,52 @@ type ExecCheckResponse struct {
 // ExecCheckHandler handles POST /exec/check requests.
 type ExecCheckHandler struct {
 	inspector *Inspector
+	logger    *slog.Logger
+	limiter   *limiter.Limiter
+	metrics   *limiter.Metrics
+}
+
+// ExecCheckHandlerOption configures an ExecCheckHandler.
+type ExecCheckHandlerOption func(*ExecCheckHandler)
+
+// WithExecLogger sets the structured application logger ServeHTTP emits
+// to. If not set, records go to plasmalog.Default().
+func WithExecLogger(logger *slog.Logger) ExecCheckHandlerOption {
+	return func(h *ExecCheckHandler) {
+		h.logger = logger
+	}
+}
+
+// WithExecLimiter sets the rate limiter applied per agent token, keyed
+// under limiter.Exec so a runaway agent's exec checks draw from a
+// separate budget than its outbound traffic. There's no analogous
+// WithExecBreaker: a circuit breaker trips on a failing upstream host,
+// and an exec check never calls one -- it's a local decision against the
+// loaded ruleset.
+func WithExecLimiter(l *limiter.Limiter) ExecCheckHandlerOption {
+	return func(h *ExecCheckHandler) {
+		h.limiter = l
+	}
+}
+
+// WithExecMetrics sets the Prometheus-style metrics registry. If not set,
+// metrics are not recorded.
+func WithExecMetrics(m *limiter.Metrics) ExecCheckHandlerOption {
+	return func(h *ExecCheckHandler) {
+		h.metrics = m
+	}
 }
 
 // NewExecCheckHandler creates a new exec check handler.
-func NewExecCheckHandler(inspector *Inspector) *ExecCheckHandler {
-	return &ExecCheckHandler{
+func NewExecCheckHandler(inspector *Inspector, opts ...ExecCheckHandlerOption) *ExecCheckHandler {
+	h := &ExecCheckHandler{
 		inspector: inspector,
+		logger:    plasmalog.Default(),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // ServeHTTP handles exec check requests.
@@ -291,24 +883,44 @@ func (h *ExecCheckHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allowed, reason := h.inspector.CheckCommand(req.Command)
+	if h.limiter != nil && !h.limiter.Allow("", req.AgentToken, "", "", limiter.Exec) {
+		if h.metrics != nil {
+			h.metrics.IncRequests("", req.AgentToken, string(limiter.Exec), "ratelimited")
+		}
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	var allowed bool
+	var reason string
+	if len(req.Argv) > 0 {
+		allowed, reason = h.inspector.CheckCommandArgv(req.Command, req.Argv)
+	} else {
+		allowed, reason = h.inspector.CheckCommand(req.Command)
+	}
 
 	action := "allow"
 	if !allowed {
 		action = "block"
 	}
 
-	// Log the exec check
-	entry := LogEntry{
-		Timestamp:  time.Now().UTC(),
-		AgentToken: req.AgentToken,
-		Domain:     "exec",
-		Method:     "EXEC",
-		Action:     action,
-		Reason:     reason,
+	if h.metrics != nil {
+		h.metrics.IncRequests("", req.AgentToken, string(limiter.Exec), action)
+	}
+
+	// Log the exec check. req.AgentToken isn't one of plasmalog's stable
+	// keys (it's a bearer credential, not an identity), so it's left out
+	// of the record rather than risking a leak into a shipped log sink.
+	level := slog.LevelInfo
+	if !allowed {
+		level = slog.LevelWarn
 	}
-	data, _ := json.Marshal(entry)
-	log.Println(string(data))
+	h.logger.Log(context.Background(), level, "exec check",
+		slog.String(plasmalog.KeyDomain, "exec"),
+		slog.String("method", "EXEC"),
+		slog.String(plasmalog.KeyAction, action),
+		slog.String(plasmalog.KeyReason, reason),
+	)
 
 	resp := ExecCheckResponse{
 		Allowed: allowed,
@@ -1,8 +1,11 @@
 package proxy
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/Extra-Chill/plasma-shield/internal/mode"
@@ -45,8 +48,11 @@ func TestHandler_RejectsUnregisteredIP(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusForbidden {
-		t.Errorf("expected 403 Forbidden, got %d", rr.Code)
+	if rr.Code != http.StatusProxyAuthRequired {
+		t.Errorf("expected 407 Proxy Authentication Required, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Proxy-Authenticate"); !strings.Contains(got, "Basic") || !strings.Contains(got, "Bearer") {
+		t.Errorf("expected Proxy-Authenticate to offer Basic and Bearer, got %q", got)
 	}
 }
 
@@ -111,6 +117,88 @@ func TestHandler_NoRegistryAllowsAll(t *testing.T) {
 	}
 }
 
+// mockCertRegistry implements both AgentRegistry and CertRegistry, so
+// tests can observe which one the handler prefers.
+type mockCertRegistry struct {
+	mockRegistry
+	certAgentID, certTier string
+	certValid             bool
+}
+
+func (m *mockCertRegistry) ValidateAgentCert(cert *x509.Certificate) (agentID, tier string, valid bool) {
+	return m.certAgentID, m.certTier, m.certValid
+}
+
+func TestHandler_PrefersCertOverIP(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}))
+	defer backend.Close()
+
+	// The registry's IP table has no entry for 10.0.0.1 -- only the cert
+	// check, which this registry is rigged to accept, should let it through.
+	registry := &mockCertRegistry{
+		mockRegistry: mockRegistry{agents: make(map[string]struct {
+			id   string
+			tier string
+		})},
+		certAgentID: "cert-agent",
+		certTier:    "commodore",
+		certValid:   true,
+	}
+
+	engine := rules.NewEngine()
+	modeManager := mode.NewManager()
+	inspector := NewInspector(engine, modeManager)
+	handler := NewHandler(inspector, WithAgentRegistry(registry))
+
+	req := httptest.NewRequest("GET", backend.URL+"/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 OK (cert identity accepted), got %d", rr.Code)
+	}
+	if got := req.Header.Get("X-Agent-ID"); got != "cert-agent" {
+		t.Errorf("expected X-Agent-ID from cert identity, got %q", got)
+	}
+}
+
+func TestHandler_RejectsInvalidCertEvenWithMatchingIP(t *testing.T) {
+	// A peer certificate was presented but the registry rejects it -- the
+	// handler must not fall back to IP validation, even though the IP
+	// table below would otherwise accept this source.
+	registry := &mockCertRegistry{
+		mockRegistry: mockRegistry{agents: map[string]struct {
+			id   string
+			tier string
+		}{
+			"10.0.0.1": {id: "ip-agent", tier: "crew"},
+		}},
+		certValid: false,
+	}
+
+	engine := rules.NewEngine()
+	modeManager := mode.NewManager()
+	inspector := NewInspector(engine, modeManager)
+	handler := NewHandler(inspector, WithAgentRegistry(registry))
+
+	req := httptest.NewRequest("GET", "http://example.com/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusProxyAuthRequired {
+		t.Errorf("expected 407 Proxy Authentication Required, got %d", rr.Code)
+	}
+}
+
 func TestExtractClientIP(t *testing.T) {
 	tests := []struct {
 		remoteAddr string
@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Extra-Chill/plasma-shield/internal/mode"
+	"github.com/Extra-Chill/plasma-shield/internal/rules"
+)
+
+// mockProxyAuthRegistry implements AgentRegistry and ProxyAuthRegistry, so
+// tests can observe the Proxy-Authorization fallback once ValidateAgentIP
+// has already failed.
+type mockProxyAuthRegistry struct {
+	mockRegistry
+	basicUser, basicPass, basicAgentID, basicTier string
+	basicValid                                    bool
+	bearerToken, bearerAgentID, bearerTier         string
+	bearerValid                                    bool
+}
+
+func (m *mockProxyAuthRegistry) ValidateAgentBasic(user, pass string) (agentID, tier string, valid bool) {
+	if m.basicValid && user == m.basicUser && pass == m.basicPass {
+		return m.basicAgentID, m.basicTier, true
+	}
+	return "", "", false
+}
+
+func (m *mockProxyAuthRegistry) ValidateAgentBearer(token string) (agentID, tier string, valid bool) {
+	if m.bearerValid && token == m.bearerToken {
+		return m.bearerAgentID, m.bearerTier, true
+	}
+	return "", "", false
+}
+
+func newProxyAuthHandler(registry *mockProxyAuthRegistry) *Handler {
+	engine := rules.NewEngine()
+	modeManager := mode.NewManager()
+	inspector := NewInspector(engine, modeManager)
+	return NewHandler(inspector, WithAgentRegistry(registry))
+}
+
+func TestHandler_AcceptsBasicProxyAuthorization(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	registry := &mockProxyAuthRegistry{
+		mockRegistry: mockRegistry{agents: map[string]struct {
+			id   string
+			tier string
+		}{}},
+		basicUser: "agent-1", basicPass: "s3cret",
+		basicAgentID: "agent-1", basicTier: "crew", basicValid: true,
+	}
+	handler := newProxyAuthHandler(registry)
+
+	req := httptest.NewRequest("GET", backend.URL+"/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("agent-1:s3cret")))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 OK, got %d", rr.Code)
+	}
+	if got := req.Header.Get("X-Agent-ID"); got != "agent-1" {
+		t.Errorf("expected X-Agent-ID 'agent-1', got %q", got)
+	}
+}
+
+func TestHandler_AcceptsBearerProxyAuthorization(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	registry := &mockProxyAuthRegistry{
+		mockRegistry: mockRegistry{agents: map[string]struct {
+			id   string
+			tier string
+		}{}},
+		bearerToken: "agent-2:s3cret", bearerAgentID: "agent-2", bearerTier: "commodore", bearerValid: true,
+	}
+	handler := newProxyAuthHandler(registry)
+
+	req := httptest.NewRequest("GET", backend.URL+"/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("Proxy-Authorization", "Bearer agent-2:s3cret")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 OK, got %d", rr.Code)
+	}
+	if got := req.Header.Get("X-Agent-ID"); got != "agent-2" {
+		t.Errorf("expected X-Agent-ID 'agent-2', got %q", got)
+	}
+}
+
+func TestHandler_RejectsBadProxyAuthorization(t *testing.T) {
+	registry := &mockProxyAuthRegistry{
+		mockRegistry: mockRegistry{agents: map[string]struct {
+			id   string
+			tier string
+		}{}},
+	}
+	handler := newProxyAuthHandler(registry)
+
+	req := httptest.NewRequest("GET", "http://example.com/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("Proxy-Authorization", "Bearer nope")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusProxyAuthRequired {
+		t.Errorf("expected 407 Proxy Authentication Required, got %d", rr.Code)
+	}
+}
+
+func TestHandleHTTP_StripsProxyAuthorizationHeader(t *testing.T) {
+	var gotHeader string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("Proxy-Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	registry := &mockProxyAuthRegistry{
+		mockRegistry: mockRegistry{agents: map[string]struct {
+			id   string
+			tier string
+		}{}},
+		bearerToken: "agent-2:s3cret", bearerAgentID: "agent-2", bearerTier: "commodore", bearerValid: true,
+	}
+	handler := newProxyAuthHandler(registry)
+
+	req := httptest.NewRequest("GET", backend.URL+"/test", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("Proxy-Authorization", "Bearer agent-2:s3cret")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", rr.Code)
+	}
+	if gotHeader != "" {
+		t.Errorf("expected Proxy-Authorization to be stripped upstream, got %q", gotHeader)
+	}
+}
@@ -1,15 +1,26 @@
 package proxy
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/mitmca"
 	"github.com/Extra-Chill/plasma-shield/internal/mode"
 	"github.com/Extra-Chill/plasma-shield/internal/rules"
 )
@@ -27,14 +38,11 @@ func testInspector(t *testing.T, rulesYAML string) *Inspector {
 	return NewInspector(engine, modeManager)
 }
 
-// captureLog captures log output during test execution.
-func captureLog(t *testing.T, fn func()) string {
-	t.Helper()
-	var buf bytes.Buffer
-	log.SetOutput(&buf)
-	defer log.SetOutput(io.Discard)
-	fn()
-	return buf.String()
+// jsonLogger returns a *slog.Logger backed by a plain JSON slog.Handler
+// (rather than plasmalog's sink-fanout MultiHandler), writing into buf as
+// one flat JSON object per line -- easy for a test to string-match on.
+func jsonLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, nil))
 }
 
 func TestHandleHTTP_AllowedRequests(t *testing.T) {
@@ -292,21 +300,17 @@ rules:
     enabled: true
 `)
 
-	handler := NewHandler(inspector)
-
 	tests := []struct {
-		name        string
-		host        string
-		method      string
-		agentToken  string
-		wantAction  string
-		wantDomain  string
+		name       string
+		host       string
+		method     string
+		wantAction string
+		wantDomain string
 	}{
 		{
 			name:       "allowed request logged",
 			host:       "allowed.test",
 			method:     http.MethodGet,
-			agentToken: "agent-123",
 			wantAction: "allow",
 			wantDomain: "allowed.test",
 		},
@@ -314,7 +318,6 @@ rules:
 			name:       "blocked request logged",
 			host:       "blocked.test",
 			method:     http.MethodGet,
-			agentToken: "agent-456",
 			wantAction: "block",
 			wantDomain: "blocked.test",
 		},
@@ -322,7 +325,6 @@ rules:
 			name:       "CONNECT logged",
 			host:       "blocked.test:443",
 			method:     http.MethodConnect,
-			agentToken: "agent-789",
 			wantAction: "block",
 			wantDomain: "blocked.test",
 		},
@@ -330,34 +332,21 @@ rules:
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var logOutput string
+			var buf bytes.Buffer
+			handler := NewHandler(inspector, WithLogger(jsonLogger(&buf)))
 
 			req := httptest.NewRequest(tt.method, "http://"+tt.host+"/path", nil)
 			req.Host = tt.host
-			if tt.agentToken != "" {
-				req.Header.Set("X-Agent-Token", tt.agentToken)
-			}
 			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
 
-			logOutput = captureLog(t, func() {
-				handler.ServeHTTP(rec, req)
-			})
-
-			// Parse the JSON log entry
-			var entry LogEntry
-			// Extract JSON from log line (after timestamp prefix)
-			jsonStart := strings.Index(logOutput, "{")
-			if jsonStart == -1 {
-				t.Fatalf("no JSON in log output: %q", logOutput)
+			var entry struct {
+				Time   time.Time `json:"time"`
+				Action string    `json:"action"`
+				Domain string    `json:"domain"`
 			}
-			jsonEnd := strings.LastIndex(logOutput, "}")
-			if jsonEnd == -1 {
-				t.Fatalf("malformed JSON in log output: %q", logOutput)
-			}
-			jsonStr := logOutput[jsonStart : jsonEnd+1]
-
-			if err := json.Unmarshal([]byte(jsonStr), &entry); err != nil {
-				t.Fatalf("failed to parse log entry: %v, json: %q", err, jsonStr)
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to parse log entry: %v, json: %q", err, buf.String())
 			}
 
 			if entry.Action != tt.wantAction {
@@ -366,10 +355,7 @@ rules:
 			if entry.Domain != tt.wantDomain {
 				t.Errorf("domain = %q, want %q", entry.Domain, tt.wantDomain)
 			}
-			if entry.AgentToken != tt.agentToken {
-				t.Errorf("agentToken = %q, want %q", entry.AgentToken, tt.agentToken)
-			}
-			if entry.Timestamp.IsZero() {
+			if entry.Time.IsZero() {
 				t.Error("timestamp should not be zero")
 			}
 		})
@@ -386,16 +372,16 @@ rules:
     enabled: true
 `)
 
-	handler := NewHandler(inspector)
+	var buf bytes.Buffer
+	handler := NewHandler(inspector, WithLogger(jsonLogger(&buf)))
 
 	req := httptest.NewRequest(http.MethodGet, "http://reason.test/path", nil)
 	req.Host = "reason.test"
 	req.Header.Set("X-Agent-Token", "test-agent")
 	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
 
-	logOutput := captureLog(t, func() {
-		handler.ServeHTTP(rec, req)
-	})
+	logOutput := buf.String()
 
 	// Verify log contains expected JSON fields
 	if !strings.Contains(logOutput, `"action":"block"`) {
@@ -407,9 +393,6 @@ rules:
 	if !strings.Contains(logOutput, `"method":"GET"`) {
 		t.Error("log should contain method field")
 	}
-	if !strings.Contains(logOutput, `"agent_token":"test-agent"`) {
-		t.Error("log should contain agent_token field")
-	}
 	if !strings.Contains(logOutput, `"reason"`) {
 		t.Error("log should contain reason field")
 	}
@@ -590,6 +573,136 @@ rules:
 	}
 }
 
+// TestExecCheckHandler_ArgvBodyJSONPath exercises a body_jsonpath rule
+// matching on the request's structured argv rather than a command-string
+// glob, per the chunk10-5 requirement that exec rules see argv JSON.
+func TestExecCheckHandler_ArgvBodyJSONPath(t *testing.T) {
+	engine := rules.NewEngine()
+	if err := engine.LoadRulesFromBytes([]byte(`
+rules:
+  - id: block-dangerous-arg
+    body_jsonpath:
+      expression: "$.args[?(@=='--dangerous')]"
+    action: block
+    description: "No --dangerous argv entries"
+    enabled: true
+`)); err != nil {
+		t.Fatalf("failed to load rules: %v", err)
+	}
+
+	modeManager := mode.NewManager()
+	inspector := NewInspector(engine, modeManager)
+	handler := NewExecCheckHandler(inspector)
+
+	tests := []struct {
+		name        string
+		body        string
+		wantAllowed bool
+	}{
+		{
+			name:        "argv without the dangerous flag",
+			body:        `{"command": "deploy", "argv": ["deploy", "--safe"]}`,
+			wantAllowed: true,
+		},
+		{
+			name:        "argv with the dangerous flag",
+			body:        `{"command": "deploy", "argv": ["deploy", "--dangerous"]}`,
+			wantAllowed: false,
+		},
+		{
+			name:        "no argv at all - rule can't see argv, so it can't match",
+			body:        `{"command": "deploy --dangerous"}`,
+			wantAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/exec/check", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			log.SetOutput(io.Discard)
+			handler.ServeHTTP(rec, req)
+
+			var resp ExecCheckResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Allowed != tt.wantAllowed {
+				t.Errorf("allowed = %v, want %v (reason=%q)", resp.Allowed, tt.wantAllowed, resp.Reason)
+			}
+		})
+	}
+}
+
+// TestExecCheckHandler_RateLimited fires N concurrent requests from the
+// same agent token against a one-request burst and asserts both that the
+// overflow gets 429s and that the rate limiter's own metrics recorded the
+// drops.
+func TestExecCheckHandler_RateLimited(t *testing.T) {
+	engine := rules.NewEngine()
+	modeManager := mode.NewManager()
+	inspector := NewInspector(engine, modeManager)
+
+	metrics := limiter.NewMetrics()
+	rateLimiter, err := limiter.NewLimiter([]limiter.RateLimitRule{
+		{RPS: 0, Burst: 1},
+	}, metrics)
+	if err != nil {
+		t.Fatalf("NewLimiter: %v", err)
+	}
+
+	handler := NewExecCheckHandler(inspector,
+		WithExecLimiter(rateLimiter),
+		WithExecMetrics(metrics),
+	)
+	log.SetOutput(io.Discard)
+
+	const concurrency = 10
+	var wg sync.WaitGroup
+	statuses := make([]int, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/exec/check", strings.NewReader(`{"command": "ls", "agent_token": "agent-1"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			statuses[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	var ok, limited int
+	for _, status := range statuses {
+		switch status {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+			limited++
+		default:
+			t.Errorf("unexpected status %d", status)
+		}
+	}
+	if ok != 1 {
+		t.Errorf("allowed requests = %d, want 1 (burst)", ok)
+	}
+	if limited != concurrency-1 {
+		t.Errorf("rate-limited requests = %d, want %d", limited, concurrency-1)
+	}
+
+	var buf bytes.Buffer
+	if err := metrics.WriteProm(&buf); err != nil {
+		t.Fatalf("WriteProm: %v", err)
+	}
+	wantLine := fmt.Sprintf(`plasma_ratelimit_dropped_total{tenant="",agent="agent-1",direction="exec"} %d`, limited)
+	if !strings.Contains(buf.String(), wantLine) {
+		t.Errorf("metrics output missing %q; got:\n%s", wantLine, buf.String())
+	}
+}
+
 func TestHostExtraction(t *testing.T) {
 	inspector := testInspector(t, `
 rules:
@@ -712,3 +825,235 @@ rules:
 		})
 	}
 }
+
+// TestHTTPRuleFields_MethodOnly exercises a rule that matches on Methods
+// alone, with no Domain/Pattern set at all.
+func TestHTTPRuleFields_MethodOnly(t *testing.T) {
+	inspector := testInspector(t, `
+rules:
+  - id: block-delete
+    method: ["DELETE"]
+    action: block
+    description: "No deletes through the proxy"
+    enabled: true
+`)
+	handler := NewHandler(inspector)
+
+	get := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	get.Host = "example.com"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, get)
+	if rec.Code == http.StatusForbidden {
+		t.Errorf("GET: status = %d, want allowed", rec.Code)
+	}
+
+	del := httptest.NewRequest(http.MethodDelete, "http://example.com/", nil)
+	del.Host = "example.com"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, del)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("DELETE: status = %d, want 403", rec.Code)
+	}
+}
+
+// TestHTTPRuleFields_PathRegex exercises both Path syntaxes: glob and the
+// "re2:" raw-regex prefix.
+func TestHTTPRuleFields_PathRegex(t *testing.T) {
+	inspector := testInspector(t, `
+rules:
+  - id: block-admin-glob
+    path: "/admin/*"
+    action: block
+    description: "No admin paths"
+    enabled: true
+  - id: block-secrets-regex
+    path: "re2:^/api/v[0-9]+/secrets$"
+    action: block
+    description: "No versioned secrets endpoints"
+    enabled: true
+`)
+	handler := NewHandler(inspector)
+
+	tests := []struct {
+		path    string
+		blocked bool
+	}{
+		{"/admin/users", true},
+		{"/public/users", false},
+		{"/api/v2/secrets", true},
+		{"/api/v2/secrets/extra", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com"+tt.path, nil)
+		req.Host = "example.com"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		gotBlocked := rec.Code == http.StatusForbidden
+		if gotBlocked != tt.blocked {
+			t.Errorf("path %q: blocked=%v, want %v (status=%d)", tt.path, gotBlocked, tt.blocked, rec.Code)
+		}
+	}
+}
+
+// TestHTTPRuleFields_Header exercises a rule matching on a request
+// header's value via regex.
+func TestHTTPRuleFields_Header(t *testing.T) {
+	inspector := testInspector(t, `
+rules:
+  - id: block-curl
+    header:
+      User-Agent: "^curl/.*"
+    action: block
+    description: "No curl traffic"
+    enabled: true
+`)
+	handler := NewHandler(inspector)
+
+	curlReq := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	curlReq.Host = "example.com"
+	curlReq.Header.Set("User-Agent", "curl/8.4.0")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, curlReq)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("curl UA: status = %d, want 403", rec.Code)
+	}
+
+	browserReq := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	browserReq.Host = "example.com"
+	browserReq.Header.Set("User-Agent", "Mozilla/5.0")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, browserReq)
+	if rec.Code == http.StatusForbidden {
+		t.Errorf("browser UA: status = %d, want allowed", rec.Code)
+	}
+}
+
+// TestHTTPRuleFields_BodyJSONPath exercises a body_jsonpath rule that
+// blocks requests whose JSON body carries a "--dangerous" entry in its
+// "args" array, via the filter-predicate form of the constrained
+// JSONPath syntax, and confirms the body is still fully forwarded.
+func TestHTTPRuleFields_BodyJSONPath(t *testing.T) {
+	inspector := testInspector(t, `
+rules:
+  - id: block-dangerous-arg
+    body_jsonpath:
+      expression: "$.args[?(@=='--dangerous')]"
+    action: block
+    description: "No --dangerous in request bodies"
+    enabled: true
+`)
+	handler := NewHandler(inspector)
+
+	var gotBody []byte
+	handler.client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		gotBody, _ = io.ReadAll(r.Body)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}, nil
+	})}
+
+	dangerousBody := `{"args":["--safe","--dangerous"]}`
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/run", strings.NewReader(dangerousBody))
+	req.Host = "example.com"
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("dangerous body: status = %d, want 403", rec.Code)
+	}
+
+	safeBody := `{"args":["--safe","--also-safe"]}`
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/run", strings.NewReader(safeBody))
+	req.Host = "example.com"
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code == http.StatusForbidden {
+		t.Errorf("safe body: status = %d, want allowed", rec.Code)
+	}
+	if string(gotBody) != safeBody {
+		t.Errorf("forwarded body = %q, want %q (body_jsonpath peeking must not truncate the upstream request)", gotBody, safeBody)
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper, for stubbing the
+// Handler's upstream client in tests that need to inspect what actually
+// got forwarded.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// TestHandleConnectMITM_BlocksHostInsideAllowedTunnel exercises the case
+// blind tunneling can't: the CONNECT itself targets an address with no
+// blockable domain (a bare IP:port), but the decrypted request inside the
+// tunnel carries a Host header that matches a block rule. Only MITM
+// interception lets that rule apply.
+func TestHandleConnectMITM_BlocksHostInsideAllowedTunnel(t *testing.T) {
+	ca, err := mitmca.NewCA(filepath.Join(t.TempDir(), "mitm_ca_key"))
+	if err != nil {
+		t.Fatalf("mitmca.NewCA: %v", err)
+	}
+
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("upstream reached"))
+	}))
+	defer upstream.Close()
+	upstreamAddr := upstream.Listener.Addr().String()
+
+	inspector := testInspector(t, `
+rules:
+  - id: block-evil-host
+    domain: "evil.blocked"
+    action: block
+    description: "Block by the Host header seen inside the tunnel"
+    enabled: true
+`)
+
+	handler := NewHandler(inspector, WithMITM(ca))
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	// The CONNECT line itself only ever names upstreamAddr (a bare IP:port,
+	// nothing a domain rule could match); the block rule only fires because
+	// the request decrypted inside the tunnel carries Host: evil.blocked.
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", upstreamAddr, upstreamAddr)
+	connectResp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatalf("read CONNECT response: %v", err)
+	}
+	connectResp.Body.Close()
+	if connectResp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT status = %d, want 200", connectResp.StatusCode)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca.RootCertPEM()) {
+		t.Fatal("failed to load mitmca root into pool")
+	}
+	tlsConn := tls.Client(conn, &tls.Config{RootCAs: pool, ServerName: "evil.blocked"})
+	defer tlsConn.Close()
+
+	req, err := http.NewRequest(http.MethodGet, "https://evil.blocked/", nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	if err := req.Write(tlsConn); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(tlsConn), req)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		body, _ := io.ReadAll(resp.Body)
+		t.Errorf("status = %d, want 403 (body=%q)", resp.StatusCode, body)
+	}
+}
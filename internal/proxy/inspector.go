@@ -2,11 +2,18 @@
 package proxy
 
 import (
-	"log"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/metrics"
 	"github.com/Extra-Chill/plasma-shield/internal/mode"
+	"github.com/Extra-Chill/plasma-shield/internal/plasmalog"
+	"github.com/Extra-Chill/plasma-shield/internal/ratelimit"
 	"github.com/Extra-Chill/plasma-shield/internal/rules"
 )
 
@@ -14,14 +21,53 @@ import (
 type Inspector struct {
 	engine      *rules.Engine
 	modeManager *mode.Manager
+	metrics     *metrics.Metrics
+	logger      *slog.Logger
+	rateLimiter *ratelimit.Limiter
+}
+
+// InspectorOption configures an Inspector at construction time.
+type InspectorOption func(*Inspector)
+
+// WithInspectorLogger sets the structured logger CheckRequest emits one
+// decision event to per request (see plasmalog.Key* for the attribute
+// names). If not set, records go to plasmalog.Default() -- JSON lines on
+// stdout, matching proxy.Handler's own WithLogger default.
+func WithInspectorLogger(logger *slog.Logger) InspectorOption {
+	return func(i *Inspector) {
+		i.logger = logger
+	}
+}
+
+// WithRateLimiter overrides the token-bucket limiter CheckRequest consults
+// for rules with a RateLimit spec. Mostly useful for tests that want a
+// shorter WithIdleTTL; production code can rely on the default.
+func WithRateLimiter(rl *ratelimit.Limiter) InspectorOption {
+	return func(i *Inspector) {
+		i.rateLimiter = rl
+	}
 }
 
 // NewInspector creates a new traffic inspector.
-func NewInspector(engine *rules.Engine, modeManager *mode.Manager) *Inspector {
-	return &Inspector{
+func NewInspector(engine *rules.Engine, modeManager *mode.Manager, opts ...InspectorOption) *Inspector {
+	i := &Inspector{
 		engine:      engine,
 		modeManager: modeManager,
+		logger:      plasmalog.Default(),
+		rateLimiter: ratelimit.New(),
+	}
+	for _, opt := range opts {
+		opt(i)
 	}
+	return i
+}
+
+// SetMetrics wires a Prometheus-style metrics registry that CheckRequest
+// reports plasma_requests_total, plasma_request_duration_seconds,
+// plasma_rule_matches_total, and plasma_bytes_inspected_total to. Nil (the
+// default) disables reporting.
+func (i *Inspector) SetMetrics(m *metrics.Metrics) {
+	i.metrics = m
 }
 
 // ExtractHost extracts the host/domain from an HTTP request.
@@ -49,14 +95,37 @@ func (i *Inspector) ExtractAgentToken(r *http.Request) string {
 }
 
 // CheckRequest checks if a request should be blocked.
-// Returns (shouldBlock, ruleMatched, reason).
+// Returns (shouldBlock, ruleMatched, reason, rateLimit). rateLimit is nil
+// unless the matched rule sets RateLimit, in which case it reports the
+// bucket's remaining budget (or retry-after, if shouldBlock is true
+// because the budget was exhausted) for the caller to surface as
+// X-RateLimit-Remaining/Retry-After headers.
 // Respects the current mode (audit = never block, lockdown = always block).
-func (i *Inspector) CheckRequest(r *http.Request) (shouldBlock bool, ruleMatched bool, reason string) {
+func (i *Inspector) CheckRequest(r *http.Request) (shouldBlock bool, ruleMatched bool, reason string, rateLimit *ratelimit.Result) {
+	start := time.Now()
 	agentID := i.ExtractAgentToken(r)
 	host := i.ExtractHost(r)
+	tier := r.Header.Get("X-Agent-Tier")
+
+	info := rules.HTTPRequestInfo{
+		Domain:   host,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Header:   r.Header,
+		ClientIP: extractClientIP(r),
+		AgentID:  agentID,
+	}
+	if i.engine.HasBodyJSONPathRules() {
+		info.ContentType = r.Header.Get("Content-Type")
+		if isJSONContentType(info.ContentType) {
+			info.Body = i.peekJSONBody(r)
+		}
+	}
 
-	// Check if domain matches any blocking rule
-	allowed, matchedRule, ruleReason := i.engine.CheckDomain(host)
+	// Check if the request matches any blocking/allowing rule -- domain,
+	// method, path, header, and body_jsonpath all in one pass (see
+	// rules.Engine.CheckHTTPWithTier).
+	allowed, matchedRule, ruleReason := i.engine.CheckHTTPWithTier(info, tier)
 	ruleMatched = !allowed
 
 	// Determine if we should actually block based on mode
@@ -74,17 +143,129 @@ func (i *Inspector) CheckRequest(r *http.Request) (shouldBlock bool, ruleMatched
 		}
 	}
 
-	// Log the decision
+	// Consult the matched rule's rate_limit spec, if any. Only applies to
+	// a request the ruleset/mode would otherwise let through -- a rule
+	// that's already blocking doesn't need its own throttle on top.
+	if !shouldBlock && matchedRule != nil && matchedRule.RateLimit != nil {
+		res := i.checkRateLimit(matchedRule, agentID, tier, host)
+		rateLimit = &res
+		if !res.Allowed {
+			shouldBlock = true
+			ruleMatched = true
+			reason = fmt.Sprintf("%s: rate limit exceeded (%d/%s per %s)", ruleID, matchedRule.RateLimit.Requests, matchedRule.RateLimit.Per, matchedRule.RateLimit.Scope)
+		}
+	}
+
+	// Log the decision as one structured event per request -- allow
+	// included, not just block/audit -- so operators get a complete
+	// machine-parseable audit trail.
 	modeStr := string(i.modeManager.AgentMode(agentID))
-	if ruleMatched {
+	action := "allow"
+	if rateLimit != nil && !rateLimit.Allowed {
+		action = "ratelimit"
+	} else if ruleMatched {
 		if shouldBlock {
-			log.Printf("[%s] BLOCK %s (agent=%s, rule=%s)", modeStr, host, agentID, ruleID)
+			action = "block"
 		} else {
-			log.Printf("[%s] AUDIT %s (agent=%s, would block: rule=%s)", modeStr, host, agentID, ruleID)
+			action = "audit"
 		}
 	}
+	i.logger.Info("request checked",
+		plasmalog.KeyAgentID, agentID,
+		plasmalog.KeyTier, tier,
+		plasmalog.KeyDomain, host,
+		plasmalog.KeyMethod, r.Method,
+		plasmalog.KeyPath, r.URL.Path,
+		plasmalog.KeyRuleID, ruleID,
+		plasmalog.KeyMode, modeStr,
+		plasmalog.KeyAction, action,
+		plasmalog.KeyLatencyMs, time.Since(start).Milliseconds(),
+		plasmalog.KeyClientIP, info.ClientIP,
+	)
 
-	return shouldBlock, ruleMatched, reason
+	if i.metrics != nil {
+		decision := "allow"
+		if shouldBlock {
+			decision = "block"
+		} else if ruleMatched {
+			decision = "audit"
+		}
+		i.metrics.RequestsTotal.WithLabelValues(decision, agentID, "").Inc()
+		i.metrics.RequestDuration.Observe(time.Since(start).Seconds())
+		i.metrics.ProxyRequestDuration.WithLabelValues(decision).Observe(time.Since(start).Seconds())
+		if matchedRule != nil {
+			i.metrics.RuleMatchesTotal.WithLabelValues(matchedRule.ID, matchedRule.Action).Inc()
+		}
+		if shouldBlock {
+			i.metrics.BlockedTotal.WithLabelValues(ruleID, host).Inc()
+		}
+		if rateLimit != nil && !rateLimit.Allowed {
+			i.metrics.RateLimitedTotal.WithLabelValues(ruleID, matchedRule.RateLimit.Scope).Inc()
+		}
+		if r.ContentLength > 0 {
+			i.metrics.BytesInspectedTotal.Add(float64(r.ContentLength))
+		}
+	}
+
+	return shouldBlock, ruleMatched, reason, rateLimit
+}
+
+// checkRateLimit resolves rule's RateLimit scope to a bucket key and
+// consults i.rateLimiter for it. A rule-author typo in Per (caught by
+// Validate before a ruleset can ever be loaded, but defensively checked
+// again here) fails open rather than silently blocking every request.
+func (i *Inspector) checkRateLimit(rule *rules.Rule, agentID, tier, host string) ratelimit.Result {
+	per, err := time.ParseDuration(rule.RateLimit.Per)
+	if err != nil {
+		return ratelimit.Result{Allowed: true}
+	}
+
+	var scopeKey string
+	switch rule.RateLimit.Scope {
+	case "agent":
+		scopeKey = "agent:" + agentID
+	case "tier":
+		scopeKey = "tier:" + tier
+	case "host":
+		scopeKey = "host:" + host
+	default:
+		scopeKey = "global"
+	}
+
+	return i.rateLimiter.Allow(rule.ID+":"+scopeKey, rule.RateLimit.Requests, per)
+}
+
+// isJSONContentType reports whether contentType names a JSON media type,
+// ignoring any "; charset=..." parameters. Mirrors isYAMLContentType in
+// internal/api/handlers.go.
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.SplitN(contentType, ";", 2)[0]
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json")
+}
+
+// peekJSONBody buffers up to the ruleset's configured body_jsonpath cap
+// (see rules.Engine.MaxBodyJSONPathBytes) from r.Body for
+// CheckHTTP to evaluate, then reassigns r.Body so the full original
+// stream -- buffered prefix plus whatever's left unread -- is still
+// available to whatever forwards the request upstream afterwards. If the
+// body is larger than the cap, it's still fully re-injected but not
+// returned for matching: a body_jsonpath rule simply can't see past its
+// own configured limit.
+func (i *Inspector) peekJSONBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+	maxBytes := i.engine.MaxBodyJSONPathBytes()
+	buf, err := io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil
+	}
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), r.Body))
+	if len(buf) > maxBytes {
+		return nil
+	}
+	return buf
 }
 
 // CheckDomain checks if a domain is allowed by the rule engine.
@@ -102,6 +283,15 @@ func (i *Inspector) CheckCommand(command string) (bool, string) {
 	return allowed, reason
 }
 
+// CheckCommandArgv checks if a command is allowed by the rule engine,
+// additionally letting a rule's body_jsonpath match against argv's
+// structured contents (e.g. "$.args[?(@=='--dangerous')]") rather than
+// just the command string's glob pattern. Returns (allowed, reason).
+func (i *Inspector) CheckCommandArgv(command string, argv []string) (bool, string) {
+	allowed, _, reason := i.engine.CheckCommandWithArgv(command, argv, "")
+	return allowed, reason
+}
+
 // Mode returns the current mode for an agent.
 func (i *Inspector) Mode(agentID string) mode.Mode {
 	return i.modeManager.AgentMode(agentID)
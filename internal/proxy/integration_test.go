@@ -55,8 +55,8 @@ func TestIntegration_ForwardProxyWithRegistry(t *testing.T) {
 
 		handler.ServeHTTP(rr, req)
 
-		if rr.Code != http.StatusForbidden {
-			t.Errorf("expected 403, got %d", rr.Code)
+		if rr.Code != http.StatusProxyAuthRequired {
+			t.Errorf("expected 407, got %d", rr.Code)
 		}
 	})
 }
@@ -2,58 +2,273 @@
 package proxy
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Extra-Chill/plasma-shield/internal/accesslog"
+	"github.com/Extra-Chill/plasma-shield/internal/auth"
 	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/tunnel"
 )
 
 // ReverseHandler handles inbound requests and routes them to agents.
 // This is the "inbound" half of the shield - external traffic to agents.
 type ReverseHandler struct {
-	fleet  *fleet.Manager
-	tokens map[string]string // token -> tenant ID (for auth)
-	client *http.Client
+	fleet     *fleet.Manager
+	bus       *fleet.Bus
+	authStore auth.Store
+	client    *http.Client
+
+	// extraAuthenticators are tried, in order, before the default
+	// bearer-token scheme in authenticate. See WithReverseAuthenticators.
+	extraAuthenticators []ReverseAuthenticator
+	tlsConfig *tls.Config      // SNI-selected certs for inbound TLS termination
+	accessLog accesslog.Logger
+	limiter   *limiter.Limiter
+	breaker   *limiter.Breaker
+	metrics   *limiter.Metrics
+
+	transportsMu sync.Mutex
+	transports   map[string]*http.Transport // agent ID -> cached transport (for https+insecure:// backends)
+
+	registeredMu     sync.Mutex
+	registeredTokens map[string]string // token secret -> authStore token ID, for ReplaceTokens
+
+	// tunnelsMu guards tunnels, the set of agents currently connected via
+	// /agent/connect instead of a reachable WebhookURL/IP. See tunnelFor.
+	tunnelsMu sync.Mutex
+	tunnels   map[string]*tunnel.Session
+}
+
+// ReverseHandlerOption configures the ReverseHandler.
+type ReverseHandlerOption func(*ReverseHandler)
+
+// WithTLSConfig sets the tls.Config used to terminate inbound TLS for
+// agents whose TLSMode is terminate or reencrypt. The config's
+// GetCertificate should select a certificate per tenant/agent by SNI.
+func WithTLSConfig(cfg *tls.Config) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.tlsConfig = cfg
+	}
+}
+
+// WithReverseAccessLog sets the structured access log sink. If not set,
+// records are discarded (accesslog.NopLogger).
+func WithReverseAccessLog(logger accesslog.Logger) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.accessLog = logger
+	}
+}
+
+// WithAuthStore sets the ACL token store used to resolve bearer tokens to
+// policies. If not set, NewReverseHandler creates an in-memory auth.JSONStore.
+func WithAuthStore(store auth.Store) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.authStore = store
+	}
+}
+
+// WithReverseLimiter sets the rate limiter applied to inbound requests. If
+// not set, requests are never rate limited.
+func WithReverseLimiter(l *limiter.Limiter) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.limiter = l
+	}
+}
+
+// WithReverseBreaker sets the circuit breaker applied per (agent, upstream
+// host). If not set, requests are never short-circuited.
+func WithReverseBreaker(b *limiter.Breaker) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.breaker = b
+	}
+}
+
+// WithReverseMetrics sets the Prometheus-style metrics registry. If not
+// set, metrics are not recorded.
+func WithReverseMetrics(m *limiter.Metrics) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.metrics = m
+	}
+}
+
+// WithReverseAuthenticators appends ReverseAuthenticator implementations
+// (e.g. MTLSAuthenticator, JWTAuthenticator) tried, in the order given,
+// ahead of the handler's default bearer-token scheme. The first one whose
+// Authenticate doesn't return ErrNoCredential decides the request, so
+// existing RegisterToken/ReplaceTokens-based deployments keep working
+// with no config changes -- the bearer-token fallback is always there.
+func WithReverseAuthenticators(authenticators ...ReverseAuthenticator) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.extraAuthenticators = append(h.extraAuthenticators, authenticators...)
+	}
+}
+
+// WithReverseBus wires a fleet.Bus so agents can publish inter-agent
+// events via POST /fleet/events. If not set, that endpoint (and
+// GET /fleet/deadletter) respond 404 like any other unrecognized path.
+func WithReverseBus(bus *fleet.Bus) ReverseHandlerOption {
+	return func(h *ReverseHandler) {
+		h.bus = bus
+	}
 }
 
 // NewReverseHandler creates a new reverse proxy handler.
-func NewReverseHandler(fleetMgr *fleet.Manager) *ReverseHandler {
-	return &ReverseHandler{
-		fleet:  fleetMgr,
-		tokens: make(map[string]string),
+func NewReverseHandler(fleetMgr *fleet.Manager, opts ...ReverseHandlerOption) *ReverseHandler {
+	defaultStore, _ := auth.NewJSONStore("") // in-memory; never errors
+	h := &ReverseHandler{
+		fleet:     fleetMgr,
+		authStore: defaultStore,
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
 		},
+		transports:       make(map[string]*http.Transport),
+		accessLog:        accesslog.NopLogger{},
+		registeredTokens: make(map[string]string),
+		tunnels:          make(map[string]*tunnel.Session),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
+}
+
+// TLSConfig returns the configured inbound TLS config, or nil if TLS
+// termination was not enabled via WithTLSConfig.
+func (h *ReverseHandler) TLSConfig() *tls.Config {
+	return h.tlsConfig
 }
 
-// RegisterToken registers an auth token for a tenant.
+// RegisterToken registers an auth token for a tenant with an unrestricted
+// policy (any agent, method, and path). It exists for simple setups and
+// tests; use h.AuthStore().CreateToken for scoped policies. Tracked
+// alongside ReplaceTokens's bookkeeping so a later reload can revoke it.
 func (h *ReverseHandler) RegisterToken(token, tenantID string) {
-	h.tokens[token] = tenantID
+	id, err := h.authStore.PutToken(token, auth.Policy{TenantID: tenantID}, 0)
+	if err != nil {
+		return
+	}
+	h.registeredMu.Lock()
+	h.registeredTokens[token] = id
+	h.registeredMu.Unlock()
+}
+
+// ReplaceTokens atomically replaces the set of unrestricted tenant tokens
+// previously registered via RegisterToken/ReplaceTokens with tokens (a
+// token secret -> tenant ID map), for hot-reloading the agents/tokens
+// config file: every entry in tokens is (re-)registered, and any
+// previously-registered token no longer present is revoked. Scoped
+// tokens minted via AuthStore().CreateToken are untouched. Returns the
+// tenant IDs added and the token secrets removed, for the caller to log.
+func (h *ReverseHandler) ReplaceTokens(tokens map[string]string) (added []string, removed []string) {
+	h.registeredMu.Lock()
+	defer h.registeredMu.Unlock()
+
+	for token, tenantID := range tokens {
+		if _, exists := h.registeredTokens[token]; !exists {
+			added = append(added, tenantID)
+		}
+		id, err := h.authStore.PutToken(token, auth.Policy{TenantID: tenantID}, 0)
+		if err != nil {
+			continue
+		}
+		h.registeredTokens[token] = id
+	}
+
+	for token, id := range h.registeredTokens {
+		if _, keep := tokens[token]; keep {
+			continue
+		}
+		h.authStore.RevokeToken(id)
+		delete(h.registeredTokens, token)
+		removed = append(removed, token)
+	}
+	return added, removed
+}
+
+// AuthStore returns the handler's ACL token store.
+func (h *ReverseHandler) AuthStore() auth.Store {
+	return h.authStore
+}
+
+// transportFor returns the HTTP transport to use when dialing an agent's
+// backend. Webhook URLs using the "https+insecure://" scheme (borrowed from
+// Tailscale's expandProxyArg) skip backend certificate verification for
+// self-signed agent endpoints; the resulting transport is cached per agent
+// so the TLS config isn't rebuilt on every request.
+func (h *ReverseHandler) transportFor(agentID string, insecure bool) *http.Transport {
+	if !insecure {
+		return nil
+	}
+
+	h.transportsMu.Lock()
+	defer h.transportsMu.Unlock()
+
+	if t, ok := h.transports[agentID]; ok {
+		return t
+	}
+	t := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	h.transports[agentID] = t
+	return t
+}
+
+// normalizeWebhookURL strips the "+insecure" suffix from the scheme of an
+// agent webhook URL (e.g. "https+insecure://10.0.0.1:8443") and reports
+// whether backend certificate verification should be skipped.
+func normalizeWebhookURL(raw string) (normalized string, insecure bool) {
+	const prefix = "https+insecure://"
+	if strings.HasPrefix(raw, prefix) {
+		return "https://" + raw[len(prefix):], true
+	}
+	return raw, false
 }
 
 // ServeHTTP handles inbound requests.
 // Routes: /agent/{agent-id}/* -> agent's webhook
 func (h *ReverseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Extract auth token
-	token := extractBearerToken(r)
-	if token == "" {
-		h.jsonError(w, "Unauthorized: missing bearer token", http.StatusUnauthorized)
+	// Resolve credentials to an ACL policy. CONNECT requests authenticate
+	// the same way as any other request, via either Authorization or
+	// Proxy-Authorization (RFC 7231 tunnels don't carry a request body to
+	// put a bearer token in). See reverse_auth.go for the set of
+	// ReverseAuthenticator implementations this tries.
+	policy, authErr := h.authenticate(r)
+	if authErr != nil {
+		h.jsonError(w, authErr.Message, authErr.StatusCode)
+		return
+	}
+	tenantID := policy.TenantID
+
+	if h.metrics != nil {
+		h.metrics.IncActiveConnections(string(limiter.Inbound))
+		defer h.metrics.DecActiveConnections(string(limiter.Inbound))
+	}
+
+	if r.Method == http.MethodConnect {
+		h.handleConnect(w, r, tenantID, policy)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/fleet/") {
+		h.serveFleet(w, r, tenantID, policy)
 		return
 	}
 
-	// Validate token and get tenant
-	tenantID, valid := h.tokens[token]
-	if !valid {
-		h.jsonError(w, "Unauthorized: invalid token", http.StatusUnauthorized)
+	if r.URL.Path == "/agent/connect" {
+		h.serveAgentConnect(w, r, tenantID, policy)
 		return
 	}
 
@@ -72,6 +287,11 @@ func (h *ReverseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		remainingPath = "/" + parts[2]
 	}
 
+	if !policy.Allows(agentID, r.Method, remainingPath) {
+		h.jsonError(w, "Forbidden: token policy does not permit this request", http.StatusForbidden)
+		return
+	}
+
 	// Get tenant and check agent ownership
 	tenant := h.fleet.GetTenant(tenantID)
 	if tenant == nil {
@@ -85,6 +305,43 @@ func (h *ReverseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Get captain name for identity masking
+	captainName := tenant.CaptainName
+	if captainName == "" {
+		captainName = tenantID // Fallback to tenant ID
+	}
+
+	// A NAT'd agent with no reachable WebhookURL/IP registers a tunnel
+	// session via /agent/connect instead; prefer it over a direct dial
+	// whenever one is live, since that's the only way to reach such an
+	// agent at all.
+	if session := h.tunnelFor(agentID); session != nil {
+		if h.limiter != nil && !h.limiter.Allow(tenantID, agentID, agent.Tier, "tunnel:"+agentID, limiter.Inbound) {
+			h.recordMetric(tenantID, agentID, "ratelimited")
+			h.jsonError(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		start := time.Now()
+		crw := accesslog.NewResponseWriter(w)
+		h.forwardTunnel(crw, r, session, remainingPath, captainName, agentID)
+		h.recordMetric(tenantID, agentID, "forward")
+		h.accessLog.Log(accesslog.Record{
+			Time:       start.UTC(),
+			Tenant:     tenantID,
+			Agent:      agentID,
+			Captain:    captainName,
+			Method:     r.Method,
+			Path:       remainingPath,
+			Decision:   accesslog.DecisionForward,
+			StatusCode: crw.Status(),
+			ReqBytes:   r.ContentLength,
+			RespBytes:  crw.BytesWritten(),
+			Latency:    time.Since(start),
+		})
+		return
+	}
+
 	// Get agent's internal URL
 	targetURL := agent.WebhookURL
 	if targetURL == "" && agent.IP != "" {
@@ -97,6 +354,8 @@ func (h *ReverseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	targetURL, insecure := normalizeWebhookURL(targetURL)
+
 	// Build target URL
 	target, err := url.Parse(targetURL)
 	if err != nil {
@@ -106,31 +365,133 @@ func (h *ReverseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	target.Path = remainingPath
 	target.RawQuery = r.URL.RawQuery
 
-	// Get captain name for identity masking
-	captainName := tenant.CaptainName
-	if captainName == "" {
-		captainName = tenantID // Fallback to tenant ID
+	if h.limiter != nil && !h.limiter.Allow(tenantID, agentID, agent.Tier, target.Host, limiter.Inbound) {
+		h.recordMetric(tenantID, agentID, "ratelimited")
+		h.jsonError(w, "Too many requests", http.StatusTooManyRequests)
+		return
 	}
 
-	// Log the request
-	h.logRequest(tenantID, agentID, r.Method, remainingPath, "forward")
+	if h.breaker != nil {
+		if allowed, retryAfter := h.breaker.Allow(agentID, target.Host); !allowed {
+			h.recordMetric(tenantID, agentID, "breaker_open")
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			h.jsonError(w, "Upstream circuit open for "+agentID, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	start := time.Now()
+	crw := accesslog.NewResponseWriter(w)
 
 	// Forward request with identity masking
-	h.forward(w, r, target.String(), captainName)
+	h.forward(crw, r, target.String(), captainName, agentID, h.transportFor(agentID, insecure))
+	if h.breaker != nil {
+		h.breaker.RecordResult(agentID, target.Host, crw.Status() != 0 && crw.Status() < 500)
+	}
+	h.recordMetric(tenantID, agentID, "forward")
+
+	h.accessLog.Log(accesslog.Record{
+		Time:       start.UTC(),
+		Tenant:     tenantID,
+		Agent:      agentID,
+		Captain:    captainName,
+		Method:     r.Method,
+		Path:       remainingPath,
+		Decision:   accesslog.DecisionForward,
+		StatusCode: crw.Status(),
+		ReqBytes:   r.ContentLength,
+		RespBytes:  crw.BytesWritten(),
+		Latency:    time.Since(start),
+	})
 }
 
 // forward proxies the request to the target URL with identity masking.
-// The captainName is used to mask the true origin of the request.
-func (h *ReverseHandler) forward(w http.ResponseWriter, r *http.Request, targetURL, captainName string) {
+// The captainName is used to mask the true origin of the request. agentID
+// labels the upstream response time metric. transport overrides the
+// client's default transport when non-nil (used for https+insecure://
+// agent backends). Upgrade requests (WebSocket) are handled by
+// forwardUpgrade instead, since they can't go through http.Client.Do.
+func (h *ReverseHandler) forward(w http.ResponseWriter, r *http.Request, targetURL, captainName, agentID string, transport *http.Transport) {
+	if isUpgradeRequest(r) {
+		h.forwardUpgrade(w, r, targetURL, captainName, transport)
+		return
+	}
+
 	// Create outgoing request
 	outReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, r.Body)
 	if err != nil {
 		h.jsonError(w, "Internal error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	outReq.Header = maskedHeaders(r.Header, captainName)
 
-	// Copy headers (except hop-by-hop, auth, and identity-revealing headers)
-	for key, values := range r.Header {
+	// Make request. A per-agent transport is used for https+insecure://
+	// backends; otherwise the shared client keeps its default transport.
+	client := h.client
+	if transport != nil {
+		client = &http.Client{
+			Timeout:       h.client.Timeout,
+			CheckRedirect: h.client.CheckRedirect,
+			Transport:     transport,
+		}
+	}
+
+	upstreamStart := time.Now()
+	resp, err := client.Do(outReq)
+	if h.metrics != nil {
+		h.metrics.ObserveUpstreamResponse(string(limiter.Inbound), agentID, time.Since(upstreamStart).Seconds())
+	}
+	if err != nil {
+		h.jsonError(w, "Bad gateway: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Copy response headers
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	// Copy the body in chunks, flushing after each one, so SSE/long-poll
+	// responses reach the client incrementally instead of only once the
+	// upstream closes the connection.
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if rerr != nil {
+			return
+		}
+	}
+}
+
+// isUpgradeRequest reports whether r is an HTTP/1.1 protocol upgrade (e.g.
+// "Upgrade: websocket"), which must be hijacked rather than proxied through
+// http.Client.Do.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// maskedHeaders returns a copy of src with hop-by-hop and identity-revealing
+// headers stripped and the identity-masking headers set. Shared by forward
+// and forwardUpgrade so both paths mask identity the same way.
+func maskedHeaders(src http.Header, captainName string) http.Header {
+	dst := make(http.Header, len(src)+3)
+	for key, values := range src {
 		lower := strings.ToLower(key)
 		// Skip hop-by-hop headers
 		if lower == "authorization" || lower == "connection" ||
@@ -148,34 +509,117 @@ func (h *ReverseHandler) forward(w http.ResponseWriter, r *http.Request, targetU
 			continue
 		}
 		for _, value := range values {
-			outReq.Header.Add(key, value)
+			dst.Add(key, value)
 		}
 	}
 
 	// IDENTITY MASKING: Set headers that identify the request as coming from Captain
 	// The agent will see this as a request from their Captain, not from another agent
-	outReq.Header.Set("X-Captain", captainName)
-	outReq.Header.Set("X-Forwarded-Proto", "https")
-	outReq.Header.Set("X-Plasma-Shield", "true")
+	dst.Set("X-Captain", captainName)
+	dst.Set("X-Forwarded-Proto", "https")
+	dst.Set("X-Plasma-Shield", "true")
 	// Note: We deliberately do NOT set X-Forwarded-For to hide the true origin
 
-	// Make request
-	resp, err := h.client.Do(outReq)
+	return dst
+}
+
+// forwardUpgrade handles a WebSocket (or other HTTP/1.1 Upgrade) request by
+// hijacking the client connection, dialing the agent directly, and relaying
+// the upgrade handshake and subsequent frames bidirectionally. Following the
+// pattern used by cloudflared's carrier package, the proxy never parses the
+// framed protocol - it just replays the request line/headers and then
+// shuffles bytes.
+func (h *ReverseHandler) forwardUpgrade(w http.ResponseWriter, r *http.Request, targetURL, captainName string, transport *http.Transport) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		h.jsonError(w, "Bad gateway: invalid agent URL", http.StatusBadGateway)
+		return
+	}
+
+	targetConn, err := dialUpgradeTarget(target, transport)
 	if err != nil {
 		h.jsonError(w, "Bad gateway: "+err.Error(), http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
+	defer targetConn.Close()
 
-	// Copy response headers
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		h.jsonError(w, "Internal error: hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		h.jsonError(w, "Internal error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer clientConn.Close()
+
+	outReq := r.Clone(r.Context())
+	outReq.URL = target
+	outReq.Host = target.Host
+	outReq.Header = maskedHeaders(r.Header, captainName)
+	outReq.Header.Set("Connection", "Upgrade")
+	outReq.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+	if err := outReq.Write(targetConn); err != nil {
+		return
+	}
+
+	// The hijacked bufio.Reader may already hold bytes the client sent
+	// right after the handshake (e.g. the first WebSocket frame); drain
+	// those before switching to raw connection copies.
+	if n := clientBuf.Reader.Buffered(); n > 0 {
+		buffered := make([]byte, n)
+		if _, err := io.ReadFull(clientBuf.Reader, buffered); err == nil {
+			if _, err := targetConn.Write(buffered); err != nil {
+				return
+			}
 		}
 	}
 
-	w.WriteHeader(resp.StatusCode)
-	io.Copy(w, resp.Body)
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(targetConn, clientConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(clientConn, targetConn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// dialUpgradeTarget dials the agent backend named by target, re-encrypting
+// with TLS when the scheme is https. transport carries the cached
+// https+insecure:// TLS config, if any.
+func dialUpgradeTarget(target *url.URL, transport *http.Transport) (net.Conn, error) {
+	host := target.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		if target.Scheme == "https" {
+			host = net.JoinHostPort(host, "443")
+		} else {
+			host = net.JoinHostPort(host, "80")
+		}
+	}
+
+	if target.Scheme != "https" {
+		return net.DialTimeout("tcp", host, 10*time.Second)
+	}
+
+	tlsConfig := &tls.Config{ServerName: target.Hostname()}
+	if transport != nil && transport.TLSClientConfig != nil {
+		tlsConfig.InsecureSkipVerify = transport.TLSClientConfig.InsecureSkipVerify
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return tls.DialWithDialer(dialer, "tcp", host, tlsConfig)
+}
+
+// recordMetric increments plasma_requests_total for the inbound direction
+// if a metrics registry is configured.
+func (h *ReverseHandler) recordMetric(tenantID, agentID, decision string) {
+	if h.metrics != nil {
+		h.metrics.IncRequests(tenantID, agentID, string(limiter.Inbound), decision)
+	}
 }
 
 // jsonError writes a JSON error response.
@@ -187,21 +631,6 @@ func (h *ReverseHandler) jsonError(w http.ResponseWriter, message string, code i
 	})
 }
 
-// logRequest logs an inbound request.
-func (h *ReverseHandler) logRequest(tenantID, agentID, method, path, action string) {
-	entry := map[string]interface{}{
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-		"direction": "inbound",
-		"tenant":    tenantID,
-		"agent":     agentID,
-		"method":    method,
-		"path":      path,
-		"action":    action,
-	}
-	data, _ := json.Marshal(entry)
-	log.Println(string(data))
-}
-
 // extractBearerToken extracts the bearer token from Authorization header.
 func extractBearerToken(r *http.Request) string {
 	auth := r.Header.Get("Authorization")
@@ -214,3 +643,126 @@ func extractBearerToken(r *http.Request) string {
 	}
 	return parts[1]
 }
+
+// extractProxyBearerToken extracts the bearer token from the
+// Proxy-Authorization header, used by CONNECT tunnels.
+func extractProxyBearerToken(r *http.Request) string {
+	auth := r.Header.Get("Proxy-Authorization")
+	if auth == "" {
+		return ""
+	}
+	parts := strings.SplitN(auth, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return ""
+	}
+	return parts[1]
+}
+
+// handleConnect handles an RFC 7231 CONNECT tunnel to an agent's backend.
+// The request authority (r.Host / r.URL.Host) is "{agent-id}:{port}"; the
+// agent must belong to the authenticated tenant. Once the target is
+// resolved the shield hijacks the client connection and relays bytes
+// bidirectionally - the tunneled TLS is never inspected (passthrough).
+func (h *ReverseHandler) handleConnect(w http.ResponseWriter, r *http.Request, tenantID string, policy *auth.Policy) {
+	authority := r.Host
+	if r.URL.Host != "" {
+		authority = r.URL.Host
+	}
+	agentID, port, err := net.SplitHostPort(authority)
+	if err != nil {
+		h.jsonError(w, "Bad request: CONNECT target must be {agent-id}:{port}", http.StatusBadRequest)
+		return
+	}
+
+	if !policy.Allows(agentID, http.MethodConnect, "/") {
+		h.jsonError(w, "Forbidden: token policy does not permit this request", http.StatusForbidden)
+		return
+	}
+
+	tenant := h.fleet.GetTenant(tenantID)
+	if tenant == nil {
+		h.jsonError(w, "Forbidden: tenant not found", http.StatusForbidden)
+		return
+	}
+
+	agent, exists := tenant.Agents[agentID]
+	if !exists {
+		h.jsonError(w, "Forbidden: agent not in your fleet", http.StatusForbidden)
+		return
+	}
+	if agent.IP == "" {
+		h.jsonError(w, "Bad gateway: agent has no endpoint configured", http.StatusBadGateway)
+		return
+	}
+
+	targetAddr := net.JoinHostPort(agent.IP, port)
+
+	if h.limiter != nil && !h.limiter.Allow(tenantID, agentID, agent.Tier, targetAddr, limiter.Inbound) {
+		h.recordMetric(tenantID, agentID, "ratelimited")
+		h.jsonError(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	if h.breaker != nil {
+		if allowed, retryAfter := h.breaker.Allow(agentID, targetAddr); !allowed {
+			h.recordMetric(tenantID, agentID, "breaker_open")
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())+1))
+			h.jsonError(w, "Upstream circuit open for "+agentID, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	start := time.Now()
+	h.accessLog.Log(accesslog.Record{
+		Time:     start.UTC(),
+		Tenant:   tenantID,
+		Agent:    agentID,
+		Method:   http.MethodConnect,
+		Decision: accesslog.DecisionForward,
+	})
+
+	var targetConn net.Conn
+	if agent.TLSMode == fleet.TLSReencrypt {
+		targetConn, err = tls.Dial("tcp", targetAddr, &tls.Config{ServerName: agent.IP})
+	} else {
+		targetConn, err = net.DialTimeout("tcp", targetAddr, 10*time.Second)
+	}
+	if err != nil {
+		if h.breaker != nil {
+			h.breaker.RecordResult(agentID, targetAddr, false)
+		}
+		h.recordMetric(tenantID, agentID, "bad_gateway")
+		h.jsonError(w, "Bad gateway: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer targetConn.Close()
+	if h.breaker != nil {
+		h.breaker.RecordResult(agentID, targetAddr, true)
+	}
+	h.recordMetric(tenantID, agentID, "forward")
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		h.jsonError(w, "Internal error: hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		h.jsonError(w, "Internal error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer clientConn.Close()
+
+	clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(targetConn, clientConn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(clientConn, targetConn)
+		done <- struct{}{}
+	}()
+	<-done
+}
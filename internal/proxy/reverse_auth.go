@@ -0,0 +1,428 @@
+// Package proxy provides the HTTP/HTTPS proxy implementation.
+package proxy
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/auth"
+)
+
+// ErrNoCredential is returned by ReverseAuthenticator.Authenticate when the
+// request carries none of the credential types that implementation
+// handles, so ReverseHandler.authenticate falls through to the next
+// configured authenticator instead of failing the request outright.
+var ErrNoCredential = errors.New("reverseauth: no credential presented")
+
+// AuthError is returned by ReverseAuthenticator.Authenticate when the
+// request does carry its kind of credential but it's invalid, carrying
+// the HTTP status ReverseHandler.authenticate should respond with --
+// typically 401 for a missing/malformed/expired/unverifiable credential,
+// or 403 for one that's valid but scoped to the wrong tenant.
+type AuthError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *AuthError) Error() string { return e.Message }
+
+// ReverseAuthenticator resolves an inbound request to the auth.Policy it's
+// authorized under. BearerTokenAuthenticator (the original static opaque
+// bearer token scheme), MTLSAuthenticator, and JWTAuthenticator all
+// implement it; ReverseHandler.authenticate tries each configured
+// authenticator in turn and uses the first one whose Authenticate doesn't
+// return ErrNoCredential.
+type ReverseAuthenticator interface {
+	Authenticate(r *http.Request) (*auth.Policy, error)
+}
+
+// BearerTokenAuthenticator is the reverse proxy's original auth scheme: an
+// opaque bearer token (Authorization, or Proxy-Authorization for CONNECT
+// tunnels) resolved against an auth.Store. ReverseHandler always tries one
+// of these last, backed by its own authStore, so existing
+// RegisterToken/ReplaceTokens-based deployments need no config changes.
+type BearerTokenAuthenticator struct {
+	Store auth.Store
+}
+
+// Authenticate implements ReverseAuthenticator.
+func (a *BearerTokenAuthenticator) Authenticate(r *http.Request) (*auth.Policy, error) {
+	token := extractBearerToken(r)
+	if token == "" {
+		token = extractProxyBearerToken(r)
+	}
+	if token == "" {
+		return nil, ErrNoCredential
+	}
+	policy, err := a.Store.ResolveToken(token)
+	if err != nil {
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: invalid token"}
+	}
+	return policy, nil
+}
+
+// MTLSAuthenticator authenticates requests by the client certificate
+// presented during the inbound TLS handshake (see ReverseHandlerOption
+// WithTLSConfig, whose tls.Config must request a client cert for one to
+// ever be presented here). The certificate's SAN or CN names a tenant,
+// looked up in CABundles; the cert must additionally chain to that
+// tenant's own CA bundle, so no tenant's CA can mint a certificate that
+// claims to be a different tenant.
+type MTLSAuthenticator struct {
+	// CABundles maps tenant ID to the CA pool that signs that tenant's
+	// client certificates.
+	CABundles map[string]*x509.CertPool
+	// Claim selects which certificate field names the tenant: "cn" uses
+	// the Subject Common Name; anything else (including the zero value)
+	// uses the first URI or DNS SAN.
+	Claim string
+}
+
+// Authenticate implements ReverseAuthenticator.
+func (a *MTLSAuthenticator) Authenticate(r *http.Request) (*auth.Policy, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrNoCredential
+	}
+	cert := r.TLS.PeerCertificates[0]
+
+	tenantID := mtlsTenantClaim(cert, a.Claim)
+	if tenantID == "" {
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: client certificate carries no tenant claim"}
+	}
+
+	pool := a.CABundles[tenantID]
+	if pool == nil {
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: no CA bundle configured for tenant " + tenantID}
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: client certificate not trusted for tenant " + tenantID}
+	}
+
+	return &auth.Policy{TenantID: tenantID}, nil
+}
+
+// mtlsTenantClaim extracts the tenant claim named claim from cert.
+func mtlsTenantClaim(cert *x509.Certificate, claim string) string {
+	if claim == "cn" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
+
+// jwk is a single entry of a JWKS document (RFC 7517), restricted to the
+// RSA and EC fields JWKSKeySet knows how to turn into a crypto.PublicKey.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksDocument is the top-level shape of a JWKS endpoint's response.
+type jwksDocument struct {
+	Keys []jwk `json:"keys"`
+}
+
+// publicKey decodes j into an RSA or EC public key, the two key types
+// JWTAuthenticator verifies (RS256 and ES256 respectively).
+func (j jwk) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		nBytes, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		e := 0
+		for _, b := range eBytes {
+			e = e<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: e}, nil
+	case "EC":
+		if j.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported EC curve %q", j.Crv)
+		}
+		xBytes, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		yBytes, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(xBytes), Y: new(big.Int).SetBytes(yBytes)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kty %q", j.Kty)
+	}
+}
+
+// JWKSKeySet fetches and caches the RS256/ES256 public keys served by a
+// JWKS endpoint, keyed by "kid". StartRefresh keeps it current on an
+// interval so a key rotation at the issuer doesn't require restarting the
+// shield; the cached set is swapped atomically, so in-flight token
+// verification is never blocked by a refresh and a failed fetch simply
+// leaves the previous set in place.
+type JWKSKeySet struct {
+	url    string
+	client *http.Client
+	keys   atomic.Pointer[map[string]crypto.PublicKey]
+}
+
+// NewJWKSKeySet creates a key set that fetches from url, the issuer's JWKS
+// document (e.g. "https://idp.example.com/.well-known/jwks.json"). Call
+// Refresh, or start StartRefresh, before using it with a JWTAuthenticator
+// -- until the first successful fetch, Key returns nil for every kid and
+// every token is rejected as having an unknown key.
+func NewJWKSKeySet(url string) *JWKSKeySet {
+	return &JWKSKeySet{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Refresh fetches the JWKS document and atomically swaps the cached key
+// set.
+func (k *JWKSKeySet) Refresh() error {
+	resp, err := k.client.Get(k.url)
+	if err != nil {
+		return fmt.Errorf("jwks: fetch %s: %w", k.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks: fetch %s: status %d", k.url, resp.StatusCode)
+	}
+
+	var doc jwksDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("jwks: decode %s: %w", k.url, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, key := range doc.Keys {
+		pub, err := key.publicKey()
+		if err != nil {
+			continue // skip keys this shield doesn't know how to parse
+		}
+		keys[key.Kid] = pub
+	}
+	k.keys.Store(&keys)
+	return nil
+}
+
+// StartRefresh runs Refresh on a ticker every interval until ctx is
+// canceled, logging rather than failing on a fetch error -- the previous
+// key set stays in place so a transient JWKS outage doesn't reject
+// tokens signed with keys already cached.
+func (k *JWKSKeySet) StartRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		if err := k.Refresh(); err != nil {
+			log.Printf("reverseauth: JWKS refresh failed: %v", err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := k.Refresh(); err != nil {
+					log.Printf("reverseauth: JWKS refresh failed: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Key returns the cached public key for kid, or nil if it's not present
+// (never fetched, or rotated away by the issuer).
+func (k *JWKSKeySet) Key(kid string) crypto.PublicKey {
+	keys := k.keys.Load()
+	if keys == nil {
+		return nil
+	}
+	return (*keys)[kid]
+}
+
+// JWTAuthenticator authenticates requests bearing an RS256 or ES256 JWT
+// (as opposed to an opaque token, which BearerTokenAuthenticator handles
+// off the same Authorization header) issued by an external identity
+// provider. Keys should be kept current via JWKSKeySet.StartRefresh
+// against the provider's JWKS endpoint.
+type JWTAuthenticator struct {
+	Keys *JWKSKeySet
+	// TenantClaim names the JWT claim that maps to the fleet tenant (e.g.
+	// "tenant"). Required.
+	TenantClaim string
+	// AgentsClaim, if set, names a claim carrying a JSON array of agent
+	// IDs the token may reach, scoping the resulting policy's AgentIDs.
+	// Empty means the token may reach any agent in its tenant.
+	AgentsClaim string
+}
+
+// Authenticate implements ReverseAuthenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*auth.Policy, error) {
+	token := extractBearerToken(r)
+	if token == "" {
+		token = extractProxyBearerToken(r)
+	}
+	// A JWT is three dot-separated segments; an opaque token (handled by
+	// BearerTokenAuthenticator) has none. This is how the two schemes
+	// share the same header.
+	if token == "" || strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredential
+	}
+
+	claims, err := a.verify(token)
+	if err != nil {
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: " + err.Error()}
+	}
+
+	tenantID, _ := claims[a.TenantClaim].(string)
+	if tenantID == "" {
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: token missing " + a.TenantClaim + " claim"}
+	}
+
+	policy := &auth.Policy{TenantID: tenantID}
+	if a.AgentsClaim != "" {
+		if raw, ok := claims[a.AgentsClaim].([]interface{}); ok {
+			for _, v := range raw {
+				if s, ok := v.(string); ok {
+					policy.AgentIDs = append(policy.AgentIDs, s)
+				}
+			}
+		}
+	}
+	return policy, nil
+}
+
+// verify parses and verifies a compact JWT against a.Keys: the alg must
+// be RS256 or ES256, the header's kid must resolve to a known key, the
+// signature must match, and the token must not be expired. It returns the
+// claims as a raw map since which of them matter (TenantClaim,
+// AgentsClaim) is caller-configured rather than fixed.
+func (a *JWTAuthenticator) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	signingInput := parts[0] + "." + parts[1]
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+
+	if a.Keys == nil {
+		return nil, errors.New("no JWKS configured")
+	}
+	pub := a.Keys.Key(header.Kid)
+	if pub == nil {
+		return nil, fmt.Errorf("unknown key id %q", header.Kid)
+	}
+
+	digest := sha256.Sum256([]byte(signingInput))
+	switch header.Alg {
+	case "RS256":
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("key id resolves to a non-RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, errors.New("signature mismatch")
+		}
+	case "ES256":
+		ecKey, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.New("key id resolves to a non-EC key")
+		}
+		if len(sig) != 64 {
+			return nil, errors.New("malformed ES256 signature")
+		}
+		rInt := new(big.Int).SetBytes(sig[:32])
+		sInt := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(ecKey, digest[:], rInt, sInt) {
+			return nil, errors.New("signature mismatch")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", header.Alg)
+	}
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode claims: %w", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payloadBytes, &claims); err != nil {
+		return nil, fmt.Errorf("parse claims: %w", err)
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && time.Now().Unix() >= int64(exp) {
+		return nil, errors.New("token expired")
+	}
+
+	return claims, nil
+}
+
+// authenticate tries, in order, any ReverseAuthenticator set via
+// WithReverseAuthenticators and then the default bearer-token scheme
+// backed by h.authStore, returning the first policy resolved. If every
+// authenticator reports ErrNoCredential, it returns a 401 for a missing
+// credential; an authenticator's own AuthError is otherwise returned
+// as-is, so callers control the status code for an invalid credential.
+func (h *ReverseHandler) authenticate(r *http.Request) (*auth.Policy, *AuthError) {
+	authenticators := make([]ReverseAuthenticator, 0, len(h.extraAuthenticators)+1)
+	authenticators = append(authenticators, h.extraAuthenticators...)
+	authenticators = append(authenticators, &BearerTokenAuthenticator{Store: h.authStore})
+
+	for _, a := range authenticators {
+		policy, err := a.Authenticate(r)
+		if err == nil {
+			return policy, nil
+		}
+		if errors.Is(err, ErrNoCredential) {
+			continue
+		}
+		var authErr *AuthError
+		if errors.As(err, &authErr) {
+			return nil, authErr
+		}
+		return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: " + err.Error()}
+	}
+	return nil, &AuthError{StatusCode: http.StatusUnauthorized, Message: "Unauthorized: missing bearer token"}
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+)
+
+// signRS256 builds a compact RS256 JWT the way an external identity
+// provider would, for JWTAuthenticator to verify against a JWKS served by
+// rsaJWKSServer.
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	headerJSON, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// rsaJWKSServer serves a single-key JWKS document for key under kid.
+func rsaJWKSServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+	doc := map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			},
+		},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(doc)
+	}))
+}
+
+func TestJWTAuthenticator_WrongTenant(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	jwks := rsaJWKSServer(t, key, "kid1")
+	defer jwks.Close()
+
+	keySet := NewJWKSKeySet(jwks.URL)
+	if err := keySet.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	fleetMgr := fleet.NewManager()
+	fleetMgr.CreateTenant("tenant1")
+	fleetMgr.AddAgent("tenant1", fleet.Agent{ID: "agent1", WebhookURL: "http://example.invalid"})
+
+	handler := NewReverseHandler(fleetMgr, WithReverseAuthenticators(&JWTAuthenticator{
+		Keys:        keySet,
+		TenantClaim: "tenant",
+	}))
+
+	token := signRS256(t, key, "kid1", map[string]interface{}{
+		"tenant": "wrong-tenant",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+
+	req := httptest.NewRequest("GET", "/agent/agent1/hooks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a JWT naming a tenant that doesn't own agent1, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestJWTAuthenticator_Expired(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	jwks := rsaJWKSServer(t, key, "kid1")
+	defer jwks.Close()
+
+	keySet := NewJWKSKeySet(jwks.URL)
+	if err := keySet.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	fleetMgr := fleet.NewManager()
+	fleetMgr.CreateTenant("tenant1")
+	fleetMgr.AddAgent("tenant1", fleet.Agent{ID: "agent1", WebhookURL: "http://example.invalid"})
+
+	handler := NewReverseHandler(fleetMgr, WithReverseAuthenticators(&JWTAuthenticator{
+		Keys:        keySet,
+		TenantClaim: "tenant",
+	}))
+
+	token := signRS256(t, key, "kid1", map[string]interface{}{
+		"tenant": "tenant1",
+		"exp":    time.Now().Add(-time.Hour).Unix(),
+	})
+
+	req := httptest.NewRequest("GET", "/agent/agent1/hooks", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an expired JWT, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+// generateTestCA creates a self-signed CA certificate and key for
+// TestMTLSAuthenticator_UnknownCA.
+func generateTestCA(t *testing.T, cn string) (*x509.Certificate, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA cert: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA cert: %v", err)
+	}
+	return cert, key
+}
+
+func TestMTLSAuthenticator_UnknownCA(t *testing.T) {
+	tenantCACert, _ := generateTestCA(t, "tenant1-ca")
+	tenantCAPool := x509.NewCertPool()
+	tenantCAPool.AddCert(tenantCACert)
+
+	// The leaf presented by the "client" is signed by a different CA, not
+	// the one configured for tenant1.
+	unknownCACert, unknownCAKey := generateTestCA(t, "unknown-ca")
+
+	leafKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate leaf key: %v", err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "tenant1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, unknownCACert, &leafKey.PublicKey, unknownCAKey)
+	if err != nil {
+		t.Fatalf("create leaf cert: %v", err)
+	}
+	leafCert, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("parse leaf cert: %v", err)
+	}
+
+	fleetMgr := fleet.NewManager()
+	fleetMgr.CreateTenant("tenant1")
+	fleetMgr.AddAgent("tenant1", fleet.Agent{ID: "agent1", WebhookURL: "http://example.invalid"})
+
+	handler := NewReverseHandler(fleetMgr, WithReverseAuthenticators(&MTLSAuthenticator{
+		CABundles: map[string]*x509.CertPool{"tenant1": tenantCAPool},
+		Claim:     "cn",
+	}))
+
+	req := httptest.NewRequest("GET", "/agent/agent1/hooks", nil)
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leafCert}}
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a client cert signed by an unknown CA, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+)
+
+// startEchoWebSocketBackend starts a raw TCP listener that accepts a single
+// HTTP/1.1 Upgrade request, replies with "101 Switching Protocols", and then
+// echoes every byte it receives back to the caller. It stands in for a real
+// WebSocket server so the test doesn't need a client library dependency.
+func startEchoWebSocketBackend(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if line == "\r\n" {
+				break
+			}
+		}
+
+		conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+		_, _ = bufferedEcho(conn, reader)
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return ln.Addr().String()
+}
+
+// bufferedEcho copies bytes already buffered in reader, then the remainder
+// of conn, straight back to conn.
+func bufferedEcho(conn net.Conn, reader *bufio.Reader) (int64, error) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if _, werr := conn.Write(buf[:n]); werr != nil {
+				return 0, werr
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func TestReverseHandler_WebSocketUpgrade(t *testing.T) {
+	backendAddr := startEchoWebSocketBackend(t)
+
+	fleetMgr := fleet.NewManager()
+	fleetMgr.CreateTenant("tenant1")
+	fleetMgr.AddAgent("tenant1", fleet.Agent{
+		ID:         "ws-agent",
+		WebhookURL: "http://" + backendAddr,
+	})
+
+	handler := NewReverseHandler(fleetMgr)
+	handler.RegisterToken("valid-token", "tenant1")
+
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	defer conn.Close()
+
+	req := "GET /agent/ws-agent/socket HTTP/1.1\r\n" +
+		"Host: " + proxy.Listener.Addr().String() + "\r\n" +
+		"Authorization: Bearer valid-token\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	status, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read status line: %v", err)
+	}
+	if !strings.Contains(status, "101") {
+		t.Fatalf("expected 101 Switching Protocols, got %q", status)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read headers: %v", err)
+		}
+		if line == "\r\n" {
+			break
+		}
+	}
+
+	payload := "hello through the shield"
+	if _, err := conn.Write([]byte(payload)); err != nil {
+		t.Fatalf("write payload: %v", err)
+	}
+
+	echoed := make([]byte, len(payload))
+	if _, err := reader.Read(echoed); err != nil {
+		t.Fatalf("read echo: %v", err)
+	}
+	if string(echoed) != payload {
+		t.Errorf("expected echo %q, got %q", payload, echoed)
+	}
+}
+
+func TestReverseHandler_SSEStream(t *testing.T) {
+	firstEventWritten := make(chan struct{})
+	allEventsWritten := make(chan struct{})
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher := w.(http.Flusher)
+
+		for i := 0; i < 3; i++ {
+			w.Write([]byte("data: event-" + string(rune('0'+i)) + "\n\n"))
+			flusher.Flush()
+			if i == 0 {
+				close(firstEventWritten)
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+		close(allEventsWritten)
+	}))
+	defer backend.Close()
+
+	fleetMgr := fleet.NewManager()
+	fleetMgr.CreateTenant("tenant1")
+	fleetMgr.AddAgent("tenant1", fleet.Agent{
+		ID:         "sse-agent",
+		WebhookURL: backend.URL,
+	})
+
+	handler := NewReverseHandler(fleetMgr)
+	handler.RegisterToken("valid-token", "tenant1")
+
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	req, err := http.NewRequest("GET", proxy.URL+"/agent/sse-agent/events", nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer valid-token")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("do request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	firstLineAt := make(chan time.Time, 1)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		if scanner.Scan() {
+			firstLineAt <- time.Now()
+		}
+	}()
+
+	select {
+	case <-firstLineAt:
+		// Got the first event without waiting for the backend to finish.
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for first SSE event")
+	}
+
+	select {
+	case <-allEventsWritten:
+	case <-time.After(2 * time.Second):
+		t.Fatal("backend never finished writing events")
+	}
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Extra-Chill/plasma-shield/internal/fleet"
+	"github.com/Extra-Chill/plasma-shield/internal/tunnel"
+)
+
+// TestReverseHandler_TunnelRoutesToConnectedAgent proves a NAT'd agent -
+// one with no WebhookURL or IP at all - can still receive inbound
+// requests once it has dialed /agent/connect and registered a tunnel
+// session, per chunk10-4's "agents dial out" design.
+func TestReverseHandler_TunnelRoutesToConnectedAgent(t *testing.T) {
+	fleetMgr := fleet.NewManager()
+	fleetMgr.CreateTenant("tenant1")
+	fleetMgr.AddAgent("tenant1", fleet.Agent{ID: "nat-agent"})
+
+	handler := NewReverseHandler(fleetMgr)
+	handler.RegisterToken("valid-token", "tenant1")
+
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	addr := strings.TrimPrefix(proxy.URL, "http://")
+	agentConn, err := tunnel.DialAgent(addr, "/agent/connect?agent_id=nat-agent", http.Header{
+		"Authorization": {"Bearer valid-token"},
+	})
+	if err != nil {
+		t.Fatalf("DialAgent: %v", err)
+	}
+	defer agentConn.Close()
+
+	agentSession := tunnel.NewSession(agentConn)
+	defer agentSession.Close()
+	go agentSession.Serve(func(method, path string, header http.Header, body io.Reader) (int, http.Header, io.Reader) {
+		if header.Get("X-Captain") == "" {
+			t.Error("expected X-Captain header on tunneled request")
+		}
+		return http.StatusOK, http.Header{"X-Agent-Saw-Path": {path}}, strings.NewReader("ok")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, proxy.URL+"/agent/nat-agent/hello", nil)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /agent/nat-agent/hello: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Agent-Saw-Path"); got != "/hello" {
+		t.Errorf("X-Agent-Saw-Path = %q, want /hello", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want %q", body, "ok")
+	}
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/accesslog"
+	"github.com/Extra-Chill/plasma-shield/internal/auth"
+	"github.com/Extra-Chill/plasma-shield/internal/limiter"
+	"github.com/Extra-Chill/plasma-shield/internal/tunnel"
+)
+
+// tunnelFor returns the live tunnel session registered for agentID, if
+// any. Agents behind NAT have no reachable WebhookURL/IP; instead they
+// dial out to /agent/connect and register one of these, and ServeHTTP
+// prefers it over the WebhookURL/IP dial path whenever one is present.
+func (h *ReverseHandler) tunnelFor(agentID string) *tunnel.Session {
+	h.tunnelsMu.Lock()
+	defer h.tunnelsMu.Unlock()
+	return h.tunnels[agentID]
+}
+
+// registerTunnel stores session as the live connection for agentID,
+// closing and replacing whatever was registered before (an agent that
+// reconnects obsoletes its prior socket).
+func (h *ReverseHandler) registerTunnel(agentID string, session *tunnel.Session) {
+	h.tunnelsMu.Lock()
+	prev := h.tunnels[agentID]
+	h.tunnels[agentID] = session
+	h.tunnelsMu.Unlock()
+	if prev != nil {
+		prev.Close()
+	}
+}
+
+// unregisterTunnel removes session as agentID's live connection, but
+// only if it's still the current one (a newer reconnect may already have
+// replaced it by the time the old session's Serve loop returns).
+func (h *ReverseHandler) unregisterTunnel(agentID string, session *tunnel.Session) {
+	h.tunnelsMu.Lock()
+	defer h.tunnelsMu.Unlock()
+	if h.tunnels[agentID] == session {
+		delete(h.tunnels, agentID)
+	}
+}
+
+// serveAgentConnect upgrades a NAT'd agent's outbound connection to a
+// tunnel.Session, per the "agents dial in" design described on
+// ReverseHandler. The caller has already authenticated the bearer token
+// to tenantID; this just checks the agent named by the "agent_id" query
+// parameter belongs to that tenant and the token's policy permits it
+// before registering the session.
+func (h *ReverseHandler) serveAgentConnect(w http.ResponseWriter, r *http.Request, tenantID string, policy *auth.Policy) {
+	agentID := r.URL.Query().Get("agent_id")
+	if agentID == "" {
+		h.jsonError(w, "Bad request: missing agent_id", http.StatusBadRequest)
+		return
+	}
+	if !policy.Allows(agentID, http.MethodConnect, "/") {
+		h.jsonError(w, "Forbidden: token policy does not permit this request", http.StatusForbidden)
+		return
+	}
+	tenant := h.fleet.GetTenant(tenantID)
+	if tenant == nil {
+		h.jsonError(w, "Forbidden: tenant not found", http.StatusForbidden)
+		return
+	}
+	if _, exists := tenant.Agents[agentID]; !exists {
+		h.jsonError(w, "Forbidden: agent not in your fleet", http.StatusForbidden)
+		return
+	}
+
+	conn, err := tunnel.Upgrade(w, r)
+	if err != nil {
+		h.jsonError(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session := tunnel.NewSession(conn)
+	h.registerTunnel(agentID, session)
+	defer h.unregisterTunnel(agentID, session)
+
+	h.accessLog.Log(accesslog.Record{
+		Time:     time.Now().UTC(),
+		Tenant:   tenantID,
+		Agent:    agentID,
+		Method:   http.MethodConnect,
+		Path:     "/agent/connect",
+		Decision: accesslog.DecisionForward,
+	})
+
+	session.Wait()
+}
+
+// forwardTunnel proxies r to agentID over an already-registered tunnel
+// session instead of dialing its WebhookURL/IP directly. It mirrors
+// forward()'s identity masking and metrics, minus the https+insecure://
+// transport concerns that only apply to direct dials.
+func (h *ReverseHandler) forwardTunnel(w http.ResponseWriter, r *http.Request, session *tunnel.Session, remainingPath, captainName, agentID string) {
+	header := maskedHeaders(r.Header, captainName)
+
+	upstreamStart := time.Now()
+	status, respHeader, respBody, err := session.RoundTrip(r.Method, remainingPath, header, r.Body)
+	if h.metrics != nil {
+		h.metrics.ObserveUpstreamResponse(string(limiter.Inbound), agentID, time.Since(upstreamStart).Seconds())
+	}
+	if err != nil {
+		h.jsonError(w, "Bad gateway: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer respBody.Close()
+
+	for key, values := range respHeader {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := respBody.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if rerr != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package ratelimit provides the sharded, in-process token buckets behind
+// a rule's optional rate_limit field (see rules.RateLimitSpec). It's
+// deliberately separate from internal/limiter, which enforces a single
+// static-file ruleset of tenant/tier/domain rate limits at the Handler
+// layer -- this package instead hands out one bucket per (rule, scope
+// key) pair, sized and refilled from whatever that specific rule
+// declares, and is consulted inline by Inspector.CheckRequest.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// defaultIdleTTL bounds how long an idle bucket is kept before the reaper
+// reclaims it, so a rule scoped per-agent doesn't leak memory for every
+// agent ID that's ever made one request.
+const defaultIdleTTL = 10 * time.Minute
+
+// defaultReapInterval is how often the reaper sweeps for idle buckets.
+const defaultReapInterval = time.Minute
+
+// Result reports the outcome of one Allow call: whether the request fit
+// under the bucket's current budget, how many requests remain in it, and
+// -- when it didn't fit -- how long the caller should wait before
+// retrying. Mirrors the header names Inspector.CheckRequest sets from it
+// (X-RateLimit-Remaining, Retry-After).
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// bucket is a standard token bucket: Capacity tokens, refilled at
+// RefillPerSec, lazily caught up to "now" on every Allow call rather than
+// ticking on a timer (the same approach internal/limiter.bucket uses, and
+// the same semantics golang.org/x/time/rate implements, just without
+// pulling in that dependency for a single-method need).
+type bucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	capacity   float64
+	refillRate float64 // tokens per second
+	lastRefill time.Time
+	lastUsed   time.Time
+}
+
+func newBucket(capacity float64, refillRate float64, now time.Time) *bucket {
+	return &bucket{
+		tokens:     capacity,
+		capacity:   capacity,
+		refillRate: refillRate,
+		lastRefill: now,
+		lastUsed:   now,
+	}
+}
+
+func (b *bucket) allow(now time.Time) Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.lastRefill).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.refillRate)
+		b.lastRefill = now
+	}
+	b.lastUsed = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true, Remaining: int(b.tokens)}
+	}
+
+	deficit := 1 - b.tokens
+	retryAfter := time.Duration(deficit / b.refillRate * float64(time.Second))
+	return Result{Allowed: false, Remaining: 0, RetryAfter: retryAfter}
+}
+
+func (b *bucket) idleSince(cutoff time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastUsed.Before(cutoff)
+}
+
+// Limiter holds one bucket per key, created lazily on first use and
+// reclaimed by a background reaper once idle for longer than its
+// configured idleTTL.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	idleTTL time.Duration
+	done    chan struct{}
+}
+
+// Option configures a Limiter at construction time.
+type Option func(*Limiter)
+
+// WithIdleTTL overrides how long an unused bucket is kept before the
+// reaper reclaims it (10 minutes by default).
+func WithIdleTTL(d time.Duration) Option {
+	return func(l *Limiter) {
+		l.idleTTL = d
+	}
+}
+
+// New creates a Limiter and starts its background reaper goroutine; call
+// Close to stop it.
+func New(opts ...Option) *Limiter {
+	l := &Limiter{
+		buckets: make(map[string]*bucket),
+		idleTTL: defaultIdleTTL,
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	go l.reap()
+	return l
+}
+
+// Allow checks and consumes one token from the bucket for key, creating
+// it (sized requests tokens, refilled at requests/per) on first use. A
+// misconfigured spec (requests <= 0 or per <= 0) fails open rather than
+// blocking every request on a rule-author's typo -- Validate rejects both
+// at load time, so this is a last-resort guard, not the primary check.
+func (l *Limiter) Allow(key string, requests int, per time.Duration) Result {
+	if requests <= 0 || per <= 0 {
+		return Result{Allowed: true}
+	}
+
+	now := time.Now()
+	refillRate := float64(requests) / per.Seconds()
+
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(float64(requests), refillRate, now)
+		l.buckets[key] = b
+	}
+	l.mu.Unlock()
+
+	return b.allow(now)
+}
+
+// reap periodically drops buckets that have been idle longer than
+// idleTTL, so a per-agent or per-host scope doesn't accumulate one bucket
+// forever for every key it's ever seen.
+func (l *Limiter) reap() {
+	ticker := time.NewTicker(defaultReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cutoff := time.Now().Add(-l.idleTTL)
+			l.mu.Lock()
+			for key, b := range l.buckets {
+				if b.idleSince(cutoff) {
+					delete(l.buckets, key)
+				}
+			}
+			l.mu.Unlock()
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Close stops the reaper goroutine.
+func (l *Limiter) Close() {
+	close(l.done)
+}
@@ -0,0 +1,206 @@
+// Package rules provides the rule engine for filtering traffic.
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle is the portable rule-bundle format `rules export`/`rules import`
+// and POST /rules/bundle speak -- one or more named Groups, inspired by
+// Prometheus/Thanos rule groups, so a ruleset can be reviewed and diffed
+// in git instead of built up one `rules add` at a time.
+type Bundle struct {
+	Groups []Group `yaml:"groups" json:"groups"`
+}
+
+// Group is one named section of a Bundle. DefaultAction fills in any rule
+// in the group that doesn't set its own Action; Labels is carried through
+// for the caller's own bookkeeping (the engine itself doesn't interpret
+// it).
+type Group struct {
+	Name          string            `yaml:"name" json:"name"`
+	DefaultAction string            `yaml:"default_action,omitempty" json:"default_action,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Rules         []Rule            `yaml:"rules" json:"rules"`
+}
+
+// ParseBundle parses a rule bundle from YAML or JSON bytes, detected by the
+// first non-whitespace byte ('{' means JSON; anything else is tried as
+// YAML, which is also a superset of JSON for single-document files).
+func ParseBundle(data []byte) (*Bundle, error) {
+	var b Bundle
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &b); err != nil {
+			return nil, fmt.Errorf("failed to parse rule bundle JSON: %w", err)
+		}
+		return &b, nil
+	}
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse rule bundle YAML: %w", err)
+	}
+	return &b, nil
+}
+
+// ToYAML renders the bundle as YAML, the format `rules export` writes by
+// default.
+func (b *Bundle) ToYAML() ([]byte, error) {
+	return yaml.Marshal(b)
+}
+
+// Flatten collapses a Bundle into a single RuleSet, the shape Validate and
+// the Engine understand: each rule inherits its Group's DefaultAction when
+// it doesn't set its own Action, and is stamped with its Group's name.
+func (b *Bundle) Flatten() *RuleSet {
+	rs := &RuleSet{}
+	for _, g := range b.Groups {
+		for _, r := range g.Rules {
+			if r.Action == "" {
+				r.Action = g.DefaultAction
+			}
+			r.Group = g.Name
+			rs.Rules = append(rs.Rules, r)
+		}
+	}
+	return rs
+}
+
+// BundleFromRuleSet groups rs's rules by their Group field (rules with no
+// Group fall into "default"), preserving each group's first-seen order, and
+// returns them as a Bundle. If onlyGroup is non-empty, every other group is
+// left out -- the server side of `rules export --group NAME`.
+func BundleFromRuleSet(rs *RuleSet, onlyGroup string) *Bundle {
+	order := make([]string, 0)
+	byGroup := make(map[string]*Group)
+
+	for _, r := range rs.Rules {
+		name := r.Group
+		if name == "" {
+			name = "default"
+		}
+		if onlyGroup != "" && name != onlyGroup {
+			continue
+		}
+
+		g, ok := byGroup[name]
+		if !ok {
+			g = &Group{Name: name}
+			byGroup[name] = g
+			order = append(order, name)
+		}
+
+		rule := r
+		rule.Group = "" // redundant once nested under its Group
+		g.Rules = append(g.Rules, rule)
+	}
+
+	b := &Bundle{}
+	for _, name := range order {
+		b.Groups = append(b.Groups, *byGroup[name])
+	}
+	return b
+}
+
+// BundleDiff reports the rule IDs a candidate RuleSet would add, remove,
+// or change relative to the currently active one -- what `rules import
+// --dry-run` and POST /rules/bundle's dry_run mode show an operator before
+// anything is persisted.
+type BundleDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// DiffRuleSets compares current against candidate by rule ID and reports
+// what changed. IDs within each slice are sorted for a stable, diffable
+// order.
+func DiffRuleSets(current, candidate *RuleSet) BundleDiff {
+	currentByID := make(map[string]Rule, len(current.Rules))
+	for _, r := range current.Rules {
+		currentByID[r.ID] = r
+	}
+	candidateByID := make(map[string]Rule, len(candidate.Rules))
+	for _, r := range candidate.Rules {
+		candidateByID[r.ID] = r
+	}
+
+	var diff BundleDiff
+	for id, r := range candidateByID {
+		if old, existed := currentByID[id]; !existed {
+			diff.Added = append(diff.Added, id)
+		} else if !ruleEqual(old, r) {
+			diff.Modified = append(diff.Modified, id)
+		}
+	}
+	for id := range currentByID {
+		if _, stillPresent := candidateByID[id]; !stillPresent {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Modified)
+	return diff
+}
+
+func ruleEqual(a, b Rule) bool {
+	if a.Pattern != b.Pattern || a.Domain != b.Domain || a.Action != b.Action ||
+		a.Description != b.Description || a.Enabled != b.Enabled || a.Group != b.Group ||
+		a.Path != b.Path {
+		return false
+	}
+	if len(a.Tiers) != len(b.Tiers) {
+		return false
+	}
+	for i := range a.Tiers {
+		if a.Tiers[i] != b.Tiers[i] {
+			return false
+		}
+	}
+	if len(a.Methods) != len(b.Methods) {
+		return false
+	}
+	for i := range a.Methods {
+		if a.Methods[i] != b.Methods[i] {
+			return false
+		}
+	}
+	// Header and BodyJSONPath are small and rarely set; reflect.DeepEqual
+	// is simpler here than hand-rolling a map/pointer-struct comparison.
+	if !reflect.DeepEqual(a.Header, b.Header) || !reflect.DeepEqual(a.BodyJSONPath, b.BodyJSONPath) {
+		return false
+	}
+	return true
+}
+
+// MergeRuleSets upserts overlay's rules into base by ID: an ID that already
+// exists in base is updated in place (base's ordering is otherwise
+// preserved), and a new ID is appended in overlay's order. This is POST
+// /rules/bundle's default (non-"replace") import mode, so importing a
+// bundle doesn't wipe out rules the bundle doesn't mention.
+func MergeRuleSets(base, overlay *RuleSet) *RuleSet {
+	merged := &RuleSet{Rules: append([]Rule(nil), base.Rules...)}
+
+	index := make(map[string]int, len(merged.Rules))
+	for i, r := range merged.Rules {
+		index[r.ID] = i
+	}
+
+	for _, r := range overlay.Rules {
+		if i, ok := index[r.ID]; ok {
+			merged.Rules[i] = r
+		} else {
+			index[r.ID] = len(merged.Rules)
+			merged.Rules = append(merged.Rules, r)
+		}
+	}
+
+	return merged
+}
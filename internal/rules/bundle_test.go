@@ -0,0 +1,146 @@
+package rules
+
+import "testing"
+
+func TestBundleFromRuleSetGroupsByFieldAndDefaultsUngrouped(t *testing.T) {
+	rs := &RuleSet{Rules: []Rule{
+		{ID: "a", Domain: "evil.com", Action: "block", Group: "net"},
+		{ID: "b", Pattern: "rm -rf *", Action: "block"},
+		{ID: "c", Domain: "ok.com", Action: "allow", Group: "net"},
+	}}
+
+	b := BundleFromRuleSet(rs, "")
+	if len(b.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d: %+v", len(b.Groups), b.Groups)
+	}
+	if b.Groups[0].Name != "net" || len(b.Groups[0].Rules) != 2 {
+		t.Fatalf("expected group net with 2 rules first, got %+v", b.Groups[0])
+	}
+	if b.Groups[1].Name != "default" || len(b.Groups[1].Rules) != 1 {
+		t.Fatalf("expected group default with 1 rule second, got %+v", b.Groups[1])
+	}
+	for _, g := range b.Groups {
+		for _, r := range g.Rules {
+			if r.Group != "" {
+				t.Errorf("rule %s: Group should be cleared once nested under its Group, got %q", r.ID, r.Group)
+			}
+		}
+	}
+}
+
+func TestBundleFromRuleSetFiltersToOnlyGroup(t *testing.T) {
+	rs := &RuleSet{Rules: []Rule{
+		{ID: "a", Domain: "evil.com", Action: "block", Group: "net"},
+		{ID: "b", Pattern: "rm -rf *", Action: "block", Group: "exec"},
+	}}
+
+	b := BundleFromRuleSet(rs, "exec")
+	if len(b.Groups) != 1 || b.Groups[0].Name != "exec" {
+		t.Fatalf("expected only the exec group, got %+v", b.Groups)
+	}
+}
+
+func TestBundleFlattenAppliesGroupDefaultActionAndStampsGroup(t *testing.T) {
+	b := &Bundle{Groups: []Group{
+		{
+			Name:          "net",
+			DefaultAction: "block",
+			Rules: []Rule{
+				{ID: "a", Domain: "evil.com"},                 // inherits DefaultAction
+				{ID: "b", Domain: "ok.com", Action: "allow"}, // keeps its own
+			},
+		},
+	}}
+
+	rs := b.Flatten()
+	if len(rs.Rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rs.Rules))
+	}
+	if rs.Rules[0].Action != "block" || rs.Rules[0].Group != "net" {
+		t.Errorf("expected rule a to inherit block and be stamped net, got %+v", rs.Rules[0])
+	}
+	if rs.Rules[1].Action != "allow" || rs.Rules[1].Group != "net" {
+		t.Errorf("expected rule b to keep allow and be stamped net, got %+v", rs.Rules[1])
+	}
+}
+
+func TestParseBundleYAMLAndJSONRoundTrip(t *testing.T) {
+	yamlBundle := []byte(`
+groups:
+  - name: net
+    default_action: block
+    rules:
+      - id: a
+        domain: evil.com
+        enabled: true
+`)
+	b, err := ParseBundle(yamlBundle)
+	if err != nil {
+		t.Fatalf("ParseBundle yaml: %v", err)
+	}
+	if len(b.Groups) != 1 || b.Groups[0].Rules[0].ID != "a" {
+		t.Fatalf("unexpected parse result: %+v", b)
+	}
+
+	jsonBundle := []byte(`{"groups":[{"name":"net","default_action":"block","rules":[{"id":"a","domain":"evil.com","enabled":true}]}]}`)
+	b2, err := ParseBundle(jsonBundle)
+	if err != nil {
+		t.Fatalf("ParseBundle json: %v", err)
+	}
+	if len(b2.Groups) != 1 || b2.Groups[0].Rules[0].ID != "a" {
+		t.Fatalf("unexpected parse result: %+v", b2)
+	}
+}
+
+func TestDiffRuleSetsReportsAddedRemovedModified(t *testing.T) {
+	current := &RuleSet{Rules: []Rule{
+		{ID: "a", Domain: "evil.com", Action: "block", Enabled: true},
+		{ID: "b", Domain: "stale.com", Action: "block", Enabled: true},
+	}}
+	candidate := &RuleSet{Rules: []Rule{
+		{ID: "a", Domain: "evil.com", Action: "allow", Enabled: true}, // modified
+		{ID: "c", Domain: "new.com", Action: "block", Enabled: true},  // added
+		// b removed
+	}}
+
+	diff := DiffRuleSets(current, candidate)
+	if len(diff.Added) != 1 || diff.Added[0] != "c" {
+		t.Errorf("expected added=[c], got %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != "b" {
+		t.Errorf("expected removed=[b], got %v", diff.Removed)
+	}
+	if len(diff.Modified) != 1 || diff.Modified[0] != "a" {
+		t.Errorf("expected modified=[a], got %v", diff.Modified)
+	}
+}
+
+func TestMergeRuleSetsUpsertsByIDPreservingBaseOrder(t *testing.T) {
+	base := &RuleSet{Rules: []Rule{
+		{ID: "a", Domain: "one.com", Action: "block"},
+		{ID: "b", Domain: "two.com", Action: "block"},
+	}}
+	overlay := &RuleSet{Rules: []Rule{
+		{ID: "a", Domain: "one.com", Action: "allow"}, // updates in place
+		{ID: "c", Domain: "three.com", Action: "block"}, // appended
+	}}
+
+	merged := MergeRuleSets(base, overlay)
+	if len(merged.Rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(merged.Rules))
+	}
+	if merged.Rules[0].ID != "a" || merged.Rules[0].Action != "allow" {
+		t.Errorf("expected rule a updated in place with allow, got %+v", merged.Rules[0])
+	}
+	if merged.Rules[1].ID != "b" {
+		t.Errorf("expected rule b to keep its position, got %+v", merged.Rules[1])
+	}
+	if merged.Rules[2].ID != "c" {
+		t.Errorf("expected rule c appended last, got %+v", merged.Rules[2])
+	}
+
+	// base must not be mutated
+	if base.Rules[0].Action != "block" {
+		t.Errorf("MergeRuleSets must not mutate base, got %+v", base.Rules[0])
+	}
+}
@@ -0,0 +1,80 @@
+// Package rules provides the rule engine for filtering traffic.
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+)
+
+// exprEnv is the fixed CEL environment every Rule.Expr is type-checked
+// against: cmd/argv from a command check, domain from a domain or HTTP
+// check, principal/agent_id identifying the caller, and now for
+// freshness-based predicates. A check that doesn't have one of these
+// (e.g. CheckDomainWithTier has no argv) still evaluates Expr, just with
+// that identifier at its zero value -- mirrors internal/matcher's
+// smaller "cel" match type, but with the wider identifier set a
+// cross-cutting rule like `domain.endsWith(".onion") &&
+// !principal.startsWith("sre-")` needs.
+var exprEnv = mustNewExprEnv()
+
+func mustNewExprEnv() *cel.Env {
+	e, err := cel.NewEnv(
+		cel.Variable("cmd", cel.StringType),
+		cel.Variable("argv", cel.ListType(cel.StringType)),
+		cel.Variable("domain", cel.StringType),
+		cel.Variable("principal", cel.StringType),
+		cel.Variable("agent_id", cel.StringType),
+		cel.Variable("now", cel.TimestampType),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("rules: build CEL environment: %v", err))
+	}
+	return e
+}
+
+// compileExprRule parses and type-checks expr against exprEnv, returning
+// an error CompileRule can surface directly to a caller like POST
+// /rules/reload: a syntax error, a reference to an identifier outside
+// exprEnv's fixed set, or an expression that doesn't evaluate to bool.
+func compileExprRule(expr string) (cel.Program, error) {
+	ast, issues := exprEnv.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expr: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expr must evaluate to bool, got %s", ast.OutputType())
+	}
+
+	program, err := exprEnv.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("build expr program: %w", err)
+	}
+	return program, nil
+}
+
+// exprVars is the identifier namespace exprEnv's variables are bound to,
+// turned into the map a cel.Program.Eval call wants.
+type exprVars struct {
+	Cmd       string
+	Argv      []string
+	Domain    string
+	Principal string
+	AgentID   string
+}
+
+func (v exprVars) toMap() map[string]interface{} {
+	argv := make([]interface{}, len(v.Argv))
+	for i, a := range v.Argv {
+		argv[i] = a
+	}
+	return map[string]interface{}{
+		"cmd":       v.Cmd,
+		"argv":      argv,
+		"domain":    v.Domain,
+		"principal": v.Principal,
+		"agent_id":  v.AgentID,
+		"now":       time.Now(),
+	}
+}
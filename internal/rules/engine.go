@@ -2,21 +2,90 @@
 package rules
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/metrics"
+	"gopkg.in/yaml.v3"
 )
 
 // Rule defines a single filtering rule.
 type Rule struct {
-	ID          string   `yaml:"id"`
-	Pattern     string   `yaml:"pattern,omitempty"`     // Command pattern to match (glob syntax)
-	Domain      string   `yaml:"domain,omitempty"`      // Domain pattern to match
-	Action      string   `yaml:"action"`                // "block" or "allow"
-	Description string   `yaml:"description,omitempty"` // Human-readable description
-	Tiers       []string `yaml:"tiers,omitempty"`       // Tiers this rule applies to (empty = all)
-	Enabled     bool     `yaml:"enabled"`
+	ID           string   `yaml:"id" json:"id"`
+	Pattern      string   `yaml:"pattern,omitempty" json:"pattern,omitempty"`             // Command pattern to match (glob syntax, or "re2:<pattern>" for a raw regex)
+	Regex        string   `yaml:"regex,omitempty" json:"regex,omitempty"`                 // Alternative to Pattern: a raw regexp matched against the command, for cases glob can't express (variable whitespace, anchoring, alternation)
+	PatternRegex string   `yaml:"pattern_regex,omitempty" json:"pattern_regex,omitempty"` // Alias for Regex with the name the rule schema documents; equivalent if both are somehow set, Regex wins
+	Domain       string   `yaml:"domain,omitempty" json:"domain,omitempty"`               // Domain pattern to match (glob syntax, or "re2:<pattern>" for a raw regex, e.g. to catch IDN-homograph lookalikes)
+	DomainRegex  string   `yaml:"domain_regex,omitempty" json:"domain_regex,omitempty"`   // Alternative to Domain: a raw regexp matched against the domain, equivalent to setting Domain to "re2:<same regex>"
+	DomainSuffix string   `yaml:"domain_suffix,omitempty" json:"domain_suffix,omitempty"` // Alternative to Domain: matches any domain ending in this suffix (e.g. ".ru" blocks the whole TLD, not just one host)
+	Expr         string   `yaml:"expr,omitempty" json:"expr,omitempty"`                   // CEL predicate evaluated by every check (command or domain) regardless of what else is set; see exprEnv for its identifiers
+	CIDR         string   `yaml:"cidr,omitempty" json:"cidr,omitempty"`                   // Client IP CIDR block (e.g. "10.0.0.0/8"); only evaluated by Engine.CheckHTTPWithTier, against the IP Inspector.CheckRequest observed
+	When         string   `yaml:"when,omitempty" json:"when,omitempty"`                   // Compound predicate over host/command/agent/tier/method/path/client_ip/hour/weekday; see when.go
+	Action       string   `yaml:"action" json:"action"`                                   // "block" or "allow"
+	Description  string   `yaml:"description,omitempty" json:"description,omitempty"`     // Human-readable description
+	Tiers        []string `yaml:"tiers,omitempty" json:"tiers,omitempty"`                 // Tiers this rule applies to (empty = all)
+	Enabled      bool     `yaml:"enabled" json:"enabled"`
+	Group        string   `yaml:"group,omitempty" json:"group,omitempty"` // Bundle group this rule belongs to (see Bundle); empty = "default"
+
+	// Methods, Path, Header, and BodyJSONPath extend matching to the shape
+	// of an HTTP request (see Engine.CheckHTTPWithTier), and BodyJSONPath
+	// doubles as an argv matcher for exec rules (see
+	// Engine.CheckCommandWithArgv). A rule using any of these is in scope
+	// for that check only if at least one is set; every field that's set
+	// must match (AND semantics), the same way Pattern and Domain both
+	// having a value on the same rule would.
+	Methods      []string          `yaml:"method,omitempty" json:"method,omitempty"`                   // HTTP methods this rule applies to (empty = any)
+	Path         string            `yaml:"path,omitempty" json:"path,omitempty"`                       // URL path to match (glob syntax, or "re2:<pattern>" for a raw regex)
+	Header       map[string]string `yaml:"header,omitempty" json:"header,omitempty"`                   // header name -> regex that must match its value
+	BodyJSONPath *BodyJSONPathRule `yaml:"body_jsonpath,omitempty" json:"body_jsonpath,omitempty"`     // constrained JSONPath match against a JSON request body, or an exec command's argv
+
+	// RateLimit caps how often this rule may let a request through, on top
+	// of whatever Action decides. Only consulted by Inspector.CheckRequest
+	// for a rule that otherwise matched (see internal/ratelimit); it has
+	// no effect on CheckCommandWithTier/CheckDomainWithTier.
+	RateLimit *RateLimitSpec `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+}
+
+// RateLimitSpec caps requests governed by its Rule to Requests per Per
+// (a duration string parsed with time.ParseDuration, e.g. "1m", "10s"),
+// sharded by Scope. Scope is one of "agent", "tier", "host", or "global";
+// anything else fails Validate.
+type RateLimitSpec struct {
+	Requests int    `yaml:"requests" json:"requests"`
+	Per      string `yaml:"per" json:"per"`
+	Scope    string `yaml:"scope" json:"scope"`
 }
 
+// BodyJSONPathRule matches a constrained JSONPath expression (see
+// jsonPathLookup) against a JSON document -- an HTTP request's body, when
+// its Content-Type is application/json, or a synthesized
+// {"command":...,"args":[...]} document for an exec rule. Equals and
+// Regex are optional: if neither is set, the rule matches whenever
+// Expression resolves to at least one node, which is the common case for
+// a filter predicate like "$.args[?(@=='--dangerous')]" that already
+// encodes the condition it's testing. Only one of Equals/Regex should be
+// set.
+type BodyJSONPathRule struct {
+	Expression string `yaml:"expression" json:"expression"`
+	Equals     string `yaml:"equals,omitempty" json:"equals,omitempty"`
+	Regex      string `yaml:"regex,omitempty" json:"regex,omitempty"`
+	// MaxBytes caps how much of the request body CheckRequest buffers to
+	// evaluate this rule; 0 uses DefaultBodyJSONPathMaxBytes. Has no
+	// effect on exec rules, which match against an already-decoded argv
+	// document instead of a streamed body.
+	MaxBytes int `yaml:"max_bytes,omitempty" json:"max_bytes,omitempty"`
+}
+
+// DefaultBodyJSONPathMaxBytes is the buffered request body size used by a
+// body_jsonpath rule that doesn't set MaxBytes.
+const DefaultBodyJSONPathMaxBytes = 64 * 1024
+
 // appliesToTier checks if a rule applies to a given tier.
 // If rule has no tiers specified, it applies to all tiers.
 // Commodore tier is exempt from all restrictive rules by default.
@@ -57,14 +126,37 @@ type RuleSet struct {
 	Rules []Rule `yaml:"rules"`
 }
 
+// engineState is the atomically-swapped snapshot of the active ruleset.
+// Reloads build a new state and swap it in without ever exposing a
+// partially-updated ruleset to concurrent readers.
+type engineState struct {
+	rules    *RuleSet
+	compiled []*CompiledRule
+	hash     string
+}
+
 // Engine evaluates traffic against rules.
-// Thread-safe for concurrent access.
+// Thread-safe for concurrent access. The active ruleset is held behind an
+// atomic.Pointer so reloads (see Watcher) never block or race with lookups.
 type Engine struct {
-	mu            sync.RWMutex
-	rules         *RuleSet
-	compiled      []*CompiledRule
+	state atomic.Pointer[engineState]
+
+	mu            sync.RWMutex // guards rulesPath and metrics only
 	rulesPath     string
 	defaultAction string // "allow" or "block" when no rules match
+	metrics       *metrics.Metrics
+
+	notifyMu sync.Mutex
+	notify   chan struct{} // closed and replaced on every successful swap
+}
+
+// SetMetrics wires a Prometheus-style metrics registry that swap reports
+// plasma_rules_loaded and plasma_rule_compile_duration_seconds to. Nil (the
+// default) disables reporting.
+func (e *Engine) SetMetrics(m *metrics.Metrics) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.metrics = m
 }
 
 // EngineOption configures the Engine.
@@ -81,32 +173,30 @@ func WithDefaultAction(action string) EngineOption {
 // NewEngine creates a new rule engine.
 func NewEngine(opts ...EngineOption) *Engine {
 	e := &Engine{
-		rules:         &RuleSet{},
-		compiled:      make([]*CompiledRule, 0),
 		defaultAction: "allow",
+		notify:        make(chan struct{}),
 	}
+	e.state.Store(&engineState{rules: &RuleSet{}, compiled: make([]*CompiledRule, 0), hash: hashRuleSet(&RuleSet{})})
 	for _, opt := range opts {
 		opt(e)
 	}
 	return e
 }
 
-// LoadRules loads rules from a YAML file.
-// This is the primary method for loading rules.
+// LoadRules loads rules from a YAML file, validates them, and atomically
+// swaps them in. A bad update is rejected without disturbing the
+// currently-serving ruleset.
 func (e *Engine) LoadRules(path string) error {
 	rs, err := LoadFromFile(path)
 	if err != nil {
 		return err
 	}
 
-	compiled, err := compileRuleSet(rs)
-	if err != nil {
+	if err := e.swap(rs); err != nil {
 		return err
 	}
 
 	e.mu.Lock()
-	e.rules = rs
-	e.compiled = compiled
 	e.rulesPath = path
 	e.mu.Unlock()
 
@@ -121,15 +211,54 @@ func (e *Engine) LoadRulesFromBytes(data []byte) error {
 		return err
 	}
 
+	return e.swap(rs)
+}
+
+// LoadRuleSet validates rs and atomically swaps it in directly, without
+// reading anything from disk or touching rulesPath. Used by POST
+// /rules/bundle to install an already-parsed bundle (see
+// Bundle.Flatten), the same way LoadRulesFromBytes does for a raw YAML
+// reload.
+func (e *Engine) LoadRuleSet(rs *RuleSet) error {
+	return e.swap(rs)
+}
+
+// swap validates a candidate RuleSet and, if valid, atomically installs it
+// as the active state. The previous state is left untouched on error.
+func (e *Engine) swap(rs *RuleSet) error {
+	if err := Validate(rs); err != nil {
+		return fmt.Errorf("invalid ruleset: %w", err)
+	}
+
+	start := time.Now()
 	compiled, err := compileRuleSet(rs)
+
+	e.mu.RLock()
+	m := e.metrics
+	e.mu.RUnlock()
+	if m != nil {
+		m.RuleCompileDuration.Observe(time.Since(start).Seconds())
+	}
 	if err != nil {
 		return err
 	}
 
-	e.mu.Lock()
-	e.rules = rs
-	e.compiled = compiled
-	e.mu.Unlock()
+	e.state.Store(&engineState{rules: rs, compiled: compiled, hash: hashRuleSet(rs)})
+	if m != nil {
+		m.RulesLoaded.Set(float64(len(compiled)))
+		enabled := 0
+		for _, cr := range compiled {
+			if cr.Rule.Enabled {
+				enabled++
+			}
+		}
+		m.RulesEnabled.Set(float64(enabled))
+	}
+
+	e.notifyMu.Lock()
+	close(e.notify)
+	e.notify = make(chan struct{})
+	e.notifyMu.Unlock()
 
 	return nil
 }
@@ -157,9 +286,73 @@ func (e *Engine) RulesPath() string {
 
 // RuleCount returns the number of loaded rules.
 func (e *Engine) RuleCount() int {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-	return len(e.compiled)
+	return len(e.state.Load().compiled)
+}
+
+// Snapshot returns a copy of the currently active rules, safe for a caller
+// to inspect or serialize (e.g. GET /rules) without racing a concurrent
+// reload.
+func (e *Engine) Snapshot() []Rule {
+	src := e.state.Load().rules.Rules
+	out := make([]Rule, len(src))
+	for i, r := range src {
+		out[i] = r
+		out[i].Tiers = append([]string(nil), r.Tiers...)
+	}
+	return out
+}
+
+// Hash returns a stable content hash of the currently active ruleset --
+// sha256 of its canonical YAML encoding, hex-encoded -- for conditional GET
+// (If-None-Match) and blocking queries (see WaitForChange) on /rules.
+func (e *Engine) Hash() string {
+	return e.state.Load().hash
+}
+
+// hashRuleSet computes the sha256 hex digest of rs's canonical YAML
+// encoding. yaml.Marshal's field order is fixed by struct definition, so
+// two RuleSets with the same rules in the same order always hash the same.
+func hashRuleSet(rs *RuleSet) string {
+	data, err := yaml.Marshal(rs)
+	if err != nil {
+		// Marshaling a RuleSet of plain structs cannot fail; fall back to a
+		// hash of nothing rather than panicking if it somehow does.
+		data = nil
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// WaitForChange blocks until the ruleset's hash differs from sinceHash, ctx
+// is done, or timeout elapses, then returns the current hash and rule
+// count -- a Consul-style blocking query so a caller like GET
+// /rules?index=<hash>&wait=30s can long-poll for a reload instead of
+// tight-polling. An empty sinceHash returns immediately with the current
+// state, like a plain GET.
+func (e *Engine) WaitForChange(ctx context.Context, sinceHash string, timeout time.Duration) (hash string, count int) {
+	if sinceHash == "" || sinceHash != e.Hash() {
+		return e.Hash(), e.RuleCount()
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		e.notifyMu.Lock()
+		ch := e.notify
+		e.notifyMu.Unlock()
+
+		select {
+		case <-ch:
+			if h := e.Hash(); h != sinceHash {
+				return h, e.RuleCount()
+			}
+			// Spurious: another reload installed an identical ruleset.
+			// Keep waiting out the remaining deadline.
+		case <-deadline.Done():
+			return e.Hash(), e.RuleCount()
+		}
+	}
 }
 
 // CheckCommand evaluates a command against the ruleset.
@@ -174,27 +367,42 @@ func (e *Engine) CheckCommand(command string) (allowed bool, matchedRule *Rule,
 //   - matchedRule: the rule that matched (nil if no match)
 //   - reason: human-readable explanation
 func (e *Engine) CheckCommandWithTier(command, tier string) (allowed bool, matchedRule *Rule, reason string) {
+	start := time.Now()
 	e.mu.RLock()
-	defer e.mu.RUnlock()
+	m := e.metrics
+	e.mu.RUnlock()
+	if m != nil {
+		defer e.recordRuleEval(m, start, tier, &allowed, &matchedRule)
+	}
 
-	for _, cr := range e.compiled {
+	for _, cr := range e.state.Load().compiled {
 		if !cr.Rule.Enabled {
 			continue
 		}
-		if cr.Rule.Pattern == "" {
+		if cr.Rule.Pattern == "" && cr.Rule.Regex == "" && cr.Rule.PatternRegex == "" && cr.Rule.Expr == "" && cr.Rule.When == "" {
 			continue
 		}
 		// Skip rules that don't apply to this tier
 		if tier != "" && !cr.Rule.appliesToTier(tier) {
 			continue
 		}
-		if cr.MatchCommand(command) {
-			if cr.Rule.Action == "block" {
-				return false, cr.Rule, fmt.Sprintf("blocked by rule %s: %s", cr.Rule.ID, cr.Rule.Description)
+		if (cr.Rule.Pattern != "" || cr.Rule.Regex != "" || cr.Rule.PatternRegex != "") && !cr.MatchCommand(command) {
+			continue
+		}
+		if cr.Rule.Expr != "" && !cr.MatchExpr(command, nil, "", "", "") {
+			continue
+		}
+		if cr.Rule.When != "" {
+			now := time.Now()
+			if !cr.MatchWhen(whenVarsFor("", command, "", tier, "", "", "", now.Hour(), int(now.Weekday()))) {
+				continue
 			}
-			// Action is "allow" - explicitly allowed
-			return true, cr.Rule, fmt.Sprintf("allowed by rule %s: %s", cr.Rule.ID, cr.Rule.Description)
 		}
+		if cr.Rule.Action == "block" {
+			return false, cr.Rule, fmt.Sprintf("blocked by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
+		}
+		// Action is "allow" - explicitly allowed
+		return true, cr.Rule, fmt.Sprintf("allowed by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
 	}
 
 	// No rule matched - use default action
@@ -216,27 +424,42 @@ func (e *Engine) CheckDomain(domain string) (allowed bool, matchedRule *Rule, re
 //   - matchedRule: the rule that matched (nil if no match)
 //   - reason: human-readable explanation
 func (e *Engine) CheckDomainWithTier(domain, tier string) (allowed bool, matchedRule *Rule, reason string) {
+	start := time.Now()
 	e.mu.RLock()
-	defer e.mu.RUnlock()
+	m := e.metrics
+	e.mu.RUnlock()
+	if m != nil {
+		defer e.recordRuleEval(m, start, tier, &allowed, &matchedRule)
+	}
 
-	for _, cr := range e.compiled {
+	for _, cr := range e.state.Load().compiled {
 		if !cr.Rule.Enabled {
 			continue
 		}
-		if cr.Rule.Domain == "" {
+		if cr.Rule.Domain == "" && cr.Rule.DomainRegex == "" && cr.Rule.DomainSuffix == "" && cr.Rule.Expr == "" && cr.Rule.When == "" {
 			continue
 		}
 		// Skip rules that don't apply to this tier
 		if tier != "" && !cr.Rule.appliesToTier(tier) {
 			continue
 		}
-		if cr.MatchDomain(domain) {
-			if cr.Rule.Action == "block" {
-				return false, cr.Rule, fmt.Sprintf("blocked by rule %s: %s", cr.Rule.ID, cr.Rule.Description)
+		if (cr.Rule.Domain != "" || cr.Rule.DomainRegex != "" || cr.Rule.DomainSuffix != "") && !cr.MatchDomain(domain) {
+			continue
+		}
+		if cr.Rule.Expr != "" && !cr.MatchExpr("", nil, domain, "", "") {
+			continue
+		}
+		if cr.Rule.When != "" {
+			now := time.Now()
+			if !cr.MatchWhen(whenVarsFor(domain, "", "", tier, "", "", "", now.Hour(), int(now.Weekday()))) {
+				continue
 			}
-			// Action is "allow" - explicitly allowed
-			return true, cr.Rule, fmt.Sprintf("allowed by rule %s: %s", cr.Rule.ID, cr.Rule.Description)
 		}
+		if cr.Rule.Action == "block" {
+			return false, cr.Rule, fmt.Sprintf("blocked by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
+		}
+		// Action is "allow" - explicitly allowed
+		return true, cr.Rule, fmt.Sprintf("allowed by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
 	}
 
 	// No rule matched - use default action
@@ -246,6 +469,79 @@ func (e *Engine) CheckDomainWithTier(domain, tier string) (allowed bool, matched
 	return true, nil, "allowed by default policy"
 }
 
+// matcherKindsLabel lists, in a fixed order, which of a rule's non-glob
+// matcher kinds (pattern_regex/domain_regex/domain_suffix/cidr/when) are
+// set, for the Check* family to fold into their reason string -- an
+// operator reading a block log line can tell a CIDR rule fired instead
+// of a when-expression without cross-referencing the ruleset.
+func matcherKindsLabel(r *Rule) string {
+	var kinds []string
+	if r.PatternRegex != "" {
+		kinds = append(kinds, "pattern_regex")
+	}
+	if r.DomainRegex != "" {
+		kinds = append(kinds, "domain_regex")
+	}
+	if r.DomainSuffix != "" {
+		kinds = append(kinds, "domain_suffix")
+	}
+	if r.CIDR != "" {
+		kinds = append(kinds, "cidr")
+	}
+	if r.When != "" {
+		kinds = append(kinds, "when")
+	}
+	if len(kinds) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(kinds, ",") + "]"
+}
+
+// recordRuleEval observes plasma_rule_eval_seconds and increments
+// plasma_rule_evaluations_total for one Check*WithTier call. It's called
+// via defer so it can read allowed/matchedRule's final values off the
+// named return parameters, rather than duplicating the observation at
+// every return statement in the match loop above.
+func (e *Engine) recordRuleEval(m *metrics.Metrics, start time.Time, tier string, allowed *bool, matchedRule **Rule) {
+	m.RuleEvalDuration.Observe(time.Since(start).Seconds())
+	action := "allow"
+	if !*allowed {
+		action = "block"
+	}
+	ruleID, matcher := "default", "default"
+	if *matchedRule != nil {
+		ruleID = (*matchedRule).ID
+		matcher = ruleMatcherKind(*matchedRule)
+	}
+	m.RuleEvaluationsTotal.WithLabelValues(ruleID, action, tier, matcher).Inc()
+}
+
+// ruleMatcherKind names the dominant matcher a rule used to reach its
+// decision, for the matcher label on plasma_rule_evaluations_total. A rule
+// can combine several matcher-shaped fields (see Rule's doc comment for
+// the AND semantics), so this reports the most specific one set, in the
+// same priority order CompileRule applies when more than one is present.
+func ruleMatcherKind(r *Rule) string {
+	switch {
+	case r.When != "":
+		return "when"
+	case r.CIDR != "":
+		return "cidr"
+	case r.PatternRegex != "" || r.DomainRegex != "":
+		return "regex"
+	case r.DomainSuffix != "":
+		return "domain_suffix"
+	case r.Regex != "":
+		return "regex"
+	case r.Expr != "":
+		return "expr"
+	case r.Pattern != "" || r.Domain != "":
+		return "pattern"
+	default:
+		return "default"
+	}
+}
+
 // compileRuleSet compiles all rules in a RuleSet.
 func compileRuleSet(rs *RuleSet) ([]*CompiledRule, error) {
 	compiled := make([]*CompiledRule, 0, len(rs.Rules))
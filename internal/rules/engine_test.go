@@ -1,7 +1,14 @@
 package rules
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCheckCommand(t *testing.T) {
@@ -106,39 +113,114 @@ rules:
 	}
 }
 
-func TestLoadDefaultRules(t *testing.T) {
+func TestCheckHTTPWithTier(t *testing.T) {
 	e := NewEngine()
-	err := e.LoadRules("../../pkg/config/default-rules.yaml")
-	if err != nil {
-		t.Fatalf("LoadRules: %v", err)
+	yaml := `
+rules:
+  - id: block-delete
+    method: ["DELETE", "PUT"]
+    action: block
+    description: "No mutating methods"
+    enabled: true
+  - id: block-admin-path
+    path: "/admin/*"
+    action: block
+    description: "No admin paths"
+    enabled: true
+  - id: block-secrets-regex
+    path: "re2:^/api/v[0-9]+/secrets$"
+    action: block
+    description: "No versioned secrets endpoints"
+    enabled: true
+  - id: block-curl-ua
+    header:
+      User-Agent: "^curl/.*"
+    action: block
+    description: "No curl traffic"
+    enabled: true
+  - id: block-dangerous-arg
+    body_jsonpath:
+      expression: "$.args[?(@=='--dangerous')]"
+    action: block
+    description: "No --dangerous in request bodies"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
 	}
 
-	if e.RuleCount() == 0 {
-		t.Error("expected rules to be loaded")
+	tests := []struct {
+		name    string
+		req     HTTPRequestInfo
+		allowed bool
+		ruleID  string
+	}{
+		{"get allowed", HTTPRequestInfo{Method: "GET", Path: "/"}, true, ""},
+		{"delete blocked", HTTPRequestInfo{Method: "DELETE", Path: "/"}, false, "block-delete"},
+		{"put blocked", HTTPRequestInfo{Method: "PUT", Path: "/"}, false, "block-delete"},
+		{"admin glob blocked", HTTPRequestInfo{Method: "GET", Path: "/admin/users"}, false, "block-admin-path"},
+		{"non-admin glob allowed", HTTPRequestInfo{Method: "GET", Path: "/public/users"}, true, ""},
+		{"secrets regex blocked", HTTPRequestInfo{Method: "GET", Path: "/api/v2/secrets"}, false, "block-secrets-regex"},
+		{"secrets regex doesn't over-match", HTTPRequestInfo{Method: "GET", Path: "/api/v2/secrets/extra"}, true, ""},
+		{
+			"curl header blocked",
+			HTTPRequestInfo{Method: "GET", Path: "/", Header: http.Header{"User-Agent": {"curl/8.4.0"}}},
+			false, "block-curl-ua",
+		},
+		{
+			"browser header allowed",
+			HTTPRequestInfo{Method: "GET", Path: "/", Header: http.Header{"User-Agent": {"Mozilla/5.0"}}},
+			true, "",
+		},
+		{
+			"dangerous jsonpath body blocked",
+			HTTPRequestInfo{Method: "POST", Path: "/run", ContentType: "application/json", Body: []byte(`{"args":["--safe","--dangerous"]}`)},
+			false, "block-dangerous-arg",
+		},
+		{
+			"safe jsonpath body allowed",
+			HTTPRequestInfo{Method: "POST", Path: "/run", ContentType: "application/json", Body: []byte(`{"args":["--safe"]}`)},
+			true, "",
+		},
 	}
 
-	// Test a known domain rule from default-rules.yaml
-	// (command patterns were removed - shield operates at network level only)
-	allowed, rule, _ := e.CheckDomain("pastebin.com")
-	if allowed {
-		t.Error("expected pastebin.com to be blocked")
-	}
-	if rule == nil || rule.ID != "block-pastebin" {
-		t.Errorf("expected rule block-pastebin, got %v", rule)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, rule, reason := e.CheckHTTP(tt.req)
+			if allowed != tt.allowed {
+				t.Errorf("CheckHTTP allowed=%v, want %v (reason: %s)", allowed, tt.allowed, reason)
+			}
+			if tt.ruleID != "" && (rule == nil || rule.ID != tt.ruleID) {
+				ruleID := ""
+				if rule != nil {
+					ruleID = rule.ID
+				}
+				t.Errorf("CheckHTTP ruleID=%q, want %q", ruleID, tt.ruleID)
+			}
+		})
 	}
 }
 
 func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(`
+rules:
+  - id: block-pastebin
+    domain: "pastebin.com"
+    action: block
+    enabled: true
+`), 0o644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
 	e := NewEngine()
-	err := e.LoadRules("../../pkg/config/default-rules.yaml")
-	if err != nil {
+	if err := e.LoadRules(path); err != nil {
 		t.Fatalf("LoadRules: %v", err)
 	}
 
 	count := e.RuleCount()
-	
-	err = e.Reload()
-	if err != nil {
+
+	if err := e.Reload(); err != nil {
 		t.Fatalf("Reload: %v", err)
 	}
 
@@ -147,6 +229,159 @@ func TestReload(t *testing.T) {
 	}
 }
 
+func TestHashChangesOnReload(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+	h1 := e.Hash()
+
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    enabled: true
+  - id: b
+    pattern: "rm *"
+    action: block
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+	h2 := e.Hash()
+
+	if h1 == h2 {
+		t.Error("expected hash to change after reload with a different ruleset")
+	}
+
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+	if e.Hash() != h1 {
+		t.Error("expected hash to match its earlier value for an identical ruleset")
+	}
+}
+
+func TestSnapshotIsACopy(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    tiers: ["crew"]
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	snap := e.Snapshot()
+	if len(snap) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(snap))
+	}
+	snap[0].Tiers[0] = "mutated"
+
+	if e.Snapshot()[0].Tiers[0] != "crew" {
+		t.Error("mutating a Snapshot result affected the engine's active ruleset")
+	}
+}
+
+func TestWaitForChange(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+	h1 := e.Hash()
+
+	// No change within the timeout: returns the same hash once it elapses.
+	start := time.Now()
+	h, _ := e.WaitForChange(context.Background(), h1, 50*time.Millisecond)
+	if h != h1 {
+		t.Errorf("expected unchanged hash %q, got %q", h1, h)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("WaitForChange returned before its timeout elapsed")
+	}
+
+	// A concurrent reload wakes a blocked waiter immediately.
+	done := make(chan string, 1)
+	go func() {
+		h, _ := e.WaitForChange(context.Background(), h1, time.Second)
+		done <- h
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: block
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	select {
+	case h := <-done:
+		if h == h1 {
+			t.Error("expected WaitForChange to observe the new hash")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForChange did not return after a reload")
+	}
+}
+
+func TestLoadRulesFromBytesInvalidKeepsOldRulesetAndReportsIndex(t *testing.T) {
+	e := NewEngine()
+	if err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    enabled: true
+`)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+	goodHash := e.Hash()
+
+	err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: a
+    pattern: "ls *"
+    action: allow
+    enabled: true
+  - id: b
+    pattern: "rm *"
+    action: explode
+    enabled: true
+`))
+	if err == nil {
+		t.Fatal("expected an error for an unknown action")
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a *ValidationError in the chain, got %v", err)
+	}
+	if verr.Index != 1 {
+		t.Errorf("expected the offending rule's index to be 1, got %d", verr.Index)
+	}
+
+	if e.Hash() != goodHash {
+		t.Error("a failed reload should leave the previously-serving ruleset in place")
+	}
+}
+
 func TestDefaultAction(t *testing.T) {
 	e := NewEngine(WithDefaultAction("block"))
 	yaml := `
@@ -173,3 +408,276 @@ rules:
 		t.Errorf("expected cat to be blocked by default, reason: %s", reason)
 	}
 }
+
+func TestCheckCommandRegex(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-rm-spaced
+    regex: '^rm\s+-rf\s+/'
+    action: block
+    description: "Block recursive delete, any amount of whitespace"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	tests := []struct {
+		cmd     string
+		allowed bool
+	}{
+		{"rm -rf /", false},
+		{"rm    -rf     /var", false},
+		{"rm -rf", true}, // no trailing path, regex doesn't match
+		{"echo rm -rf /", true},
+	}
+	for _, tt := range tests {
+		allowed, _, reason := e.CheckCommand(tt.cmd)
+		if allowed != tt.allowed {
+			t.Errorf("CheckCommand(%q) allowed=%v, want %v (reason: %s)", tt.cmd, allowed, tt.allowed, reason)
+		}
+	}
+}
+
+func TestCheckDomainRE2Prefix(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-homograph
+    domain: 're2:^x[nck]--'
+    action: block
+    description: "Block punycode/IDN-homograph domains"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	allowed, rule, _ := e.CheckDomain("xn--pple-43d.com")
+	if allowed || rule == nil || rule.ID != "block-homograph" {
+		t.Errorf("expected xn--pple-43d.com to be blocked by block-homograph, got allowed=%v rule=%v", allowed, rule)
+	}
+
+	allowed, _, _ = e.CheckDomain("apple.com")
+	if !allowed {
+		t.Error("expected apple.com to be allowed")
+	}
+}
+
+func TestCheckCommandExpr(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-dangerous-flag
+    expr: "'--dangerous' in argv"
+    action: block
+    description: "Block any invocation carrying --dangerous"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	allowed, rule, _ := e.CheckCommandWithArgv("deploy --dangerous", []string{"deploy", "--dangerous"}, "")
+	if allowed || rule == nil || rule.ID != "block-dangerous-flag" {
+		t.Errorf("expected --dangerous to be blocked, got allowed=%v rule=%v", allowed, rule)
+	}
+
+	allowed, _, _ = e.CheckCommandWithArgv("deploy --safe", []string{"deploy", "--safe"}, "")
+	if !allowed {
+		t.Error("expected deploy --safe to be allowed")
+	}
+}
+
+func TestCheckDomainExprBlocksOnionExceptSRE(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-onion-non-sre
+    expr: 'domain.endsWith(".onion") && !principal.startsWith("sre-")'
+    action: block
+    description: "Block .onion egress for everyone except the SRE principal"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	// CheckDomain has no notion of principal, so it always evaluates as "".
+	allowed, rule, _ := e.CheckDomain("abc123.onion")
+	if allowed || rule == nil || rule.ID != "block-onion-non-sre" {
+		t.Errorf("expected abc123.onion to be blocked, got allowed=%v rule=%v", allowed, rule)
+	}
+
+	allowed, _, _ = e.CheckDomain("example.com")
+	if !allowed {
+		t.Error("expected example.com to be allowed")
+	}
+}
+
+func TestExprRuleRejectsUndefinedIdentifier(t *testing.T) {
+	e := NewEngine()
+	err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: bad-expr
+    expr: 'destination == "evil.com"'
+    action: block
+    enabled: true
+`))
+	if err == nil {
+		t.Fatal("expected an error for an expr referencing an undeclared identifier")
+	}
+}
+
+func TestExprRuleRejectsNonBoolOutput(t *testing.T) {
+	e := NewEngine()
+	err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: bad-expr
+    expr: 'domain'
+    action: block
+    enabled: true
+`))
+	if err == nil {
+		t.Fatal("expected an error for an expr that doesn't evaluate to bool")
+	}
+}
+
+func TestCheckCommandPatternRegex(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-rm-rf-root
+    pattern_regex: '^rm\s+-rf\s+/'
+    action: block
+    description: "Block rm -rf on root paths"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	allowed, rule, reason := e.CheckCommand("rm -rf /var")
+	if allowed || rule == nil || rule.ID != "block-rm-rf-root" {
+		t.Errorf("expected rm -rf /var to be blocked, got allowed=%v rule=%v", allowed, rule)
+	}
+	if !strings.Contains(reason, "pattern_regex") {
+		t.Errorf("expected reason to surface the matcher kind, got %q", reason)
+	}
+
+	if allowed, _, _ := e.CheckCommand("echo rm -rf /"); !allowed {
+		t.Error("expected non-anchored match to be allowed")
+	}
+}
+
+func TestCheckDomainSuffix(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-ru-tld
+    domain_suffix: ".ru"
+    action: block
+    description: "Block the entire .ru TLD"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	if allowed, _, _ := e.CheckDomain("evil.ru"); allowed {
+		t.Error("expected evil.ru to be blocked")
+	}
+	if allowed, _, _ := e.CheckDomain("example.com"); !allowed {
+		t.Error("expected example.com to be allowed")
+	}
+}
+
+func TestCheckHTTPCIDR(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-internal-metadata-source
+    cidr: "169.254.0.0/16"
+    action: block
+    description: "Block requests originating from the link-local metadata range"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	allowed, rule, reason := e.CheckHTTP(HTTPRequestInfo{Domain: "example.com", ClientIP: "169.254.169.254"})
+	if allowed || rule == nil || rule.ID != "block-internal-metadata-source" {
+		t.Errorf("expected a link-local client IP to be blocked, got allowed=%v rule=%v", allowed, rule)
+	}
+	if !strings.Contains(reason, "cidr") {
+		t.Errorf("expected reason to surface the matcher kind, got %q", reason)
+	}
+
+	if allowed, _, _ := e.CheckHTTP(HTTPRequestInfo{Domain: "example.com", ClientIP: "10.0.0.5"}); !allowed {
+		t.Error("expected a non-matching client IP to be allowed")
+	}
+}
+
+func TestCheckHTTPWhenOutOfHoursDeploy(t *testing.T) {
+	e := NewEngine()
+	yaml := `
+rules:
+  - id: block-deploy-outside-hours
+    path: "/deploy"
+    when: 'hour < 9 or hour >= 17'
+    action: block
+    description: "Block deploys outside business hours"
+    enabled: true
+`
+	if err := e.LoadRulesFromBytes([]byte(yaml)); err != nil {
+		t.Fatalf("LoadRulesFromBytes: %v", err)
+	}
+
+	// The rule always evaluates against the real wall clock, so just
+	// confirm the path still must match -- when.go's own logic is covered
+	// directly below without depending on the time of day this test runs.
+	if allowed, _, _ := e.CheckHTTP(HTTPRequestInfo{Domain: "example.com", Path: "/status"}); !allowed {
+		t.Error("expected an unrelated path to be allowed regardless of when")
+	}
+}
+
+func TestWhenCIDRAndTierMembership(t *testing.T) {
+	program, err := compileWhen(`client_ip cidr "10.0.0.0/8" and tier in ["captain", "crew"]`)
+	if err != nil {
+		t.Fatalf("compileWhen: %v", err)
+	}
+
+	vars := whenVarsFor("", "", "", "captain", "", "", "10.1.2.3", 0, 0)
+	if !program.eval(vars) {
+		t.Error("expected a captain-tier request from 10.1.2.3 to match")
+	}
+
+	vars = whenVarsFor("", "", "", "commodore", "", "", "10.1.2.3", 0, 0)
+	if program.eval(vars) {
+		t.Error("expected a commodore-tier request not to match (tier not in list)")
+	}
+
+	vars = whenVarsFor("", "", "", "captain", "", "", "172.16.0.1", 0, 0)
+	if program.eval(vars) {
+		t.Error("expected a request outside the CIDR block not to match")
+	}
+}
+
+func TestWhenRejectsInvalidCIDR(t *testing.T) {
+	if _, err := compileWhen(`client_ip cidr "not-a-cidr"`); err == nil {
+		t.Error("expected an invalid CIDR literal to fail to compile")
+	}
+}
+
+func TestWhenRuleRejectsBadSyntax(t *testing.T) {
+	e := NewEngine()
+	err := e.LoadRulesFromBytes([]byte(`rules:
+  - id: bad-when
+    when: 'hour >'
+    action: block
+    enabled: true
+`))
+	if err == nil {
+		t.Fatal("expected an error for a malformed when expression")
+	}
+}
@@ -0,0 +1,278 @@
+// Package rules provides the rule engine for filtering traffic.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// decodeJSONBody parses body as a JSON document for jsonPathLookup.
+// Re-decoded per BodyJSONPath rule rather than once per request: rulesets
+// rarely carry more than a handful of body_jsonpath rules, so the
+// simplicity wins over caching a decode that's already bounded by
+// body_jsonpath's own MaxBytes cap.
+func decodeJSONBody(body []byte) (interface{}, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// HTTPRequestInfo is the shape of an HTTP request that
+// Engine.CheckHTTPWithTier matches rules against. Domain mirrors what
+// CheckDomainWithTier already takes; Body/ContentType are only
+// populated when the caller has already decided a body_jsonpath rule is
+// loaded and worth buffering (see Engine.HasBodyJSONPathRules). ClientIP
+// and AgentID feed CIDR and When matching only -- Inspector.CheckRequest
+// populates both from the request it already has in hand.
+type HTTPRequestInfo struct {
+	Domain      string
+	Method      string
+	Path        string
+	Header      http.Header
+	ContentType string
+	Body        []byte
+	ClientIP    string
+	AgentID     string
+}
+
+// MatchMethod checks if method is among this rule's configured Methods
+// list (case-insensitive). Only meaningful when the rule actually sets
+// Methods -- an empty list isn't "matches nothing", it's "not in scope",
+// and MatchHTTP never calls this for such a rule.
+func (cr *CompiledRule) MatchMethod(method string) bool {
+	for _, m := range cr.Rule.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchHeader checks that every one of this rule's configured header
+// regexes matches the corresponding header's value.
+func (cr *CompiledRule) MatchHeader(header http.Header) bool {
+	for name, regex := range cr.HeaderMatchers {
+		if !regex.MatchString(header.Get(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchBodyJSONPath evaluates this rule's BodyJSONPath expression
+// against a decoded JSON document (an HTTP request body, or a
+// synthesized exec argv document -- see Engine.CheckCommandWithArgv).
+func (cr *CompiledRule) MatchBodyJSONPath(doc interface{}) bool {
+	bjp := cr.Rule.BodyJSONPath
+	if bjp == nil {
+		return false
+	}
+	results, err := jsonPathLookup(doc, bjp.Expression)
+	if err != nil || len(results) == 0 {
+		return false
+	}
+	if bjp.Equals == "" && cr.bodyJSONPathRegex == nil {
+		// No expected value configured -- the expression (typically a
+		// filter predicate) resolving to anything is itself the match.
+		return true
+	}
+	for _, v := range results {
+		s := jsonPathScalarString(v)
+		if bjp.Equals != "" && s == bjp.Equals {
+			return true
+		}
+		if cr.bodyJSONPathRegex != nil && cr.bodyJSONPathRegex.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpShaped reports whether r sets any of the fields
+// Engine.CheckHTTPWithTier matches on, i.e. whether it's in scope for
+// that check at all.
+func (r *Rule) httpShaped() bool {
+	return r.Domain != "" || r.DomainRegex != "" || r.DomainSuffix != "" || r.Path != "" || len(r.Methods) > 0 || len(r.Header) > 0 || r.BodyJSONPath != nil || r.Expr != "" || r.CIDR != "" || r.When != "" || r.RateLimit != nil
+}
+
+// MatchHTTP reports whether req satisfies every field this rule sets
+// among Domain/Methods/Path/Header/BodyJSONPath/Expr/CIDR/When (AND
+// semantics). A field left unset on the rule is simply not checked.
+func (cr *CompiledRule) MatchHTTP(req HTTPRequestInfo) bool {
+	if (cr.DomainMatcher != nil || cr.domainSuffix != "") && !cr.MatchDomain(req.Domain) {
+		return false
+	}
+	if len(cr.Rule.Methods) > 0 && !cr.MatchMethod(req.Method) {
+		return false
+	}
+	if cr.PathMatcher != nil && !cr.MatchPath(req.Path) {
+		return false
+	}
+	if len(cr.HeaderMatchers) > 0 && !cr.MatchHeader(req.Header) {
+		return false
+	}
+	if cr.Rule.BodyJSONPath != nil {
+		doc, err := decodeJSONBody(req.Body)
+		if err != nil || !cr.MatchBodyJSONPath(doc) {
+			return false
+		}
+	}
+	if cr.Rule.Expr != "" && !cr.MatchExpr("", nil, req.Domain, "", "") {
+		return false
+	}
+	if cr.cidr != nil && !cr.MatchCIDR(req.ClientIP) {
+		return false
+	}
+	if cr.whenProgram != nil {
+		now := time.Now()
+		vars := whenVarsFor(req.Domain, "", req.AgentID, "", req.Method, req.Path, req.ClientIP, now.Hour(), int(now.Weekday()))
+		if !cr.MatchWhen(vars) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHTTP evaluates req against the ruleset with no tier awareness,
+// mirroring CheckDomain's relationship to CheckDomainWithTier.
+func (e *Engine) CheckHTTP(req HTTPRequestInfo) (allowed bool, matchedRule *Rule, reason string) {
+	return e.CheckHTTPWithTier(req, "")
+}
+
+// CheckHTTPWithTier evaluates an HTTP request against the ruleset,
+// generalizing CheckDomainWithTier to also match on method, path,
+// header, and body_jsonpath. A rule that sets only Domain behaves
+// exactly as it would under CheckDomainWithTier; a rule that combines
+// Domain with the newer fields must satisfy all of them.
+func (e *Engine) CheckHTTPWithTier(req HTTPRequestInfo, tier string) (allowed bool, matchedRule *Rule, reason string) {
+	start := time.Now()
+	e.mu.RLock()
+	m := e.metrics
+	e.mu.RUnlock()
+	if m != nil {
+		defer e.recordRuleEval(m, start, tier, &allowed, &matchedRule)
+	}
+
+	for _, cr := range e.state.Load().compiled {
+		if !cr.Rule.Enabled {
+			continue
+		}
+		if !cr.Rule.httpShaped() {
+			continue
+		}
+		if tier != "" && !cr.Rule.appliesToTier(tier) {
+			continue
+		}
+		if !cr.MatchHTTP(req) {
+			continue
+		}
+		if cr.Rule.Action == "block" {
+			return false, cr.Rule, fmt.Sprintf("blocked by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
+		}
+		return true, cr.Rule, fmt.Sprintf("allowed by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
+	}
+
+	if e.defaultAction == "block" {
+		return false, nil, "blocked by default policy"
+	}
+	return true, nil, "allowed by default policy"
+}
+
+// HasBodyJSONPathRules reports whether the active ruleset has at least
+// one enabled rule with a BodyJSONPath matcher, so a caller like
+// Inspector.CheckRequest can skip buffering a request body entirely when
+// nothing would use it.
+func (e *Engine) HasBodyJSONPathRules() bool {
+	for _, cr := range e.state.Load().compiled {
+		if cr.Rule.Enabled && cr.Rule.BodyJSONPath != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxBodyJSONPathBytes returns the largest MaxBytes configured across all
+// enabled body_jsonpath rules, falling back to
+// DefaultBodyJSONPathMaxBytes for any that don't override it (and when no
+// such rule is loaded at all). This is the size
+// proxy.Inspector.CheckRequest buffers a JSON request body to before
+// evaluating them.
+func (e *Engine) MaxBodyJSONPathBytes() int {
+	maxBytes := 0
+	for _, cr := range e.state.Load().compiled {
+		if !cr.Rule.Enabled || cr.Rule.BodyJSONPath == nil {
+			continue
+		}
+		n := cr.Rule.BodyJSONPath.MaxBytes
+		if n == 0 {
+			n = DefaultBodyJSONPathMaxBytes
+		}
+		if n > maxBytes {
+			maxBytes = n
+		}
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultBodyJSONPathMaxBytes
+	}
+	return maxBytes
+}
+
+// CheckCommandWithArgv evaluates a command against the ruleset the same
+// way CheckCommandWithTier does, but also lets a rule's BodyJSONPath
+// match against argv's structured contents (e.g.
+// "$.args[?(@=='--dangerous')]") instead of just Pattern's command-string
+// glob, and lets its Expr and When see argv/tier too. A rule combining
+// Pattern/Regex/PatternRegex, BodyJSONPath, Expr, and When must satisfy
+// all of them.
+func (e *Engine) CheckCommandWithArgv(command string, argv []string, tier string) (allowed bool, matchedRule *Rule, reason string) {
+	var doc map[string]interface{}
+	if len(argv) > 0 {
+		args := make([]interface{}, len(argv))
+		for i, a := range argv {
+			args[i] = a
+		}
+		doc = map[string]interface{}{"command": command, "args": args}
+	}
+
+	for _, cr := range e.state.Load().compiled {
+		if !cr.Rule.Enabled {
+			continue
+		}
+		if cr.Rule.Pattern == "" && cr.Rule.Regex == "" && cr.Rule.PatternRegex == "" && cr.Rule.BodyJSONPath == nil && cr.Rule.Expr == "" && cr.Rule.When == "" {
+			continue
+		}
+		if tier != "" && !cr.Rule.appliesToTier(tier) {
+			continue
+		}
+		if (cr.Rule.Pattern != "" || cr.Rule.Regex != "" || cr.Rule.PatternRegex != "") && !cr.MatchCommand(command) {
+			continue
+		}
+		if cr.Rule.BodyJSONPath != nil && (doc == nil || !cr.MatchBodyJSONPath(doc)) {
+			continue
+		}
+		if cr.Rule.Expr != "" && !cr.MatchExpr(command, argv, "", "", "") {
+			continue
+		}
+		if cr.Rule.When != "" {
+			now := time.Now()
+			vars := whenVarsFor("", command, "", tier, "", "", "", now.Hour(), int(now.Weekday()))
+			if !cr.MatchWhen(vars) {
+				continue
+			}
+		}
+		if cr.Rule.Action == "block" {
+			return false, cr.Rule, fmt.Sprintf("blocked by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
+		}
+		return true, cr.Rule, fmt.Sprintf("allowed by rule %s%s: %s", cr.Rule.ID, matcherKindsLabel(cr.Rule), cr.Rule.Description)
+	}
+
+	if e.defaultAction == "block" {
+		return false, nil, "blocked by default policy"
+	}
+	return true, nil, "allowed by default policy"
+}
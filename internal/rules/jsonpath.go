@@ -0,0 +1,148 @@
+// Package rules provides the rule engine for filtering traffic.
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathLookup evaluates a constrained JSONPath-like expression against
+// doc (a tree of map[string]interface{}/[]interface{}/scalars, as
+// produced by encoding/json.Unmarshal into interface{}) and returns the
+// node(s) it resolves to. It supports only the subset a body_jsonpath
+// rule needs: dotted field access ($.field.sub), numeric array indices
+// ($.array[0]), and an equality filter predicate over an array's scalar
+// elements ($.args[?(@=='--dangerous')]). There's no wildcard, slice,
+// recursive-descent, or multi-field-union support -- if a rule needs
+// those, reach for a real JSONPath library instead of growing this by
+// hand, per the convention set in internal/tunnel and internal/metrics of
+// hand-rolling only what's actually used.
+func jsonPathLookup(doc interface{}, expr string) ([]interface{}, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath: expression must start with $: %q", expr)
+	}
+
+	nodes := []interface{}{doc}
+	rest := expr[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath: empty field name: %q", expr)
+			}
+			nodes = jsonPathField(nodes, name)
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("jsonpath: unterminated [: %q", expr)
+			}
+			nodes = jsonPathBracket(nodes, rest[1:end])
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("jsonpath: unexpected %q: %q", rest[0], expr)
+		}
+	}
+	return nodes, nil
+}
+
+// jsonPathField selects the named field out of every node that's a JSON
+// object, skipping nodes that aren't (or that don't have the field).
+func jsonPathField(nodes []interface{}, name string) []interface{} {
+	var out []interface{}
+	for _, n := range nodes {
+		m, ok := n.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := m[name]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// jsonPathBracket evaluates a "[...]" subscript: a numeric index or a
+// "?(@<op><value>)" filter predicate.
+func jsonPathBracket(nodes []interface{}, sub string) []interface{} {
+	sub = strings.TrimSpace(sub)
+	if strings.HasPrefix(sub, "?(") && strings.HasSuffix(sub, ")") {
+		return jsonPathFilter(nodes, strings.TrimSuffix(strings.TrimPrefix(sub, "?("), ")"))
+	}
+
+	idx, err := strconv.Atoi(sub)
+	if err != nil {
+		return nil
+	}
+	var out []interface{}
+	for _, n := range nodes {
+		arr, ok := n.([]interface{})
+		if !ok || idx < 0 || idx >= len(arr) {
+			continue
+		}
+		out = append(out, arr[idx])
+	}
+	return out
+}
+
+// jsonPathFilter evaluates a "@==value" or "@!=value" predicate against
+// every scalar element of each node that's an array, keeping the ones
+// that satisfy it. value may be quoted ('...' or "...") or bare.
+func jsonPathFilter(nodes []interface{}, predicate string) []interface{} {
+	predicate = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(predicate), "@"))
+
+	var op string
+	switch {
+	case strings.HasPrefix(predicate, "=="):
+		op = "=="
+	case strings.HasPrefix(predicate, "!="):
+		op = "!="
+	default:
+		return nil
+	}
+	want := jsonPathUnquote(strings.TrimSpace(strings.TrimPrefix(predicate, op)))
+
+	var out []interface{}
+	for _, n := range nodes {
+		arr, ok := n.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, el := range arr {
+			matches := jsonPathScalarString(el) == want
+			if op == "!=" {
+				matches = !matches
+			}
+			if matches {
+				out = append(out, el)
+			}
+		}
+	}
+	return out
+}
+
+func jsonPathUnquote(s string) string {
+	if len(s) >= 2 {
+		if (s[0] == '\'' && s[len(s)-1] == '\'') || (s[0] == '"' && s[len(s)-1] == '"') {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
+
+// jsonPathScalarString renders a decoded JSON scalar the way it would
+// appear in the expression it's being compared against.
+func jsonPathScalarString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
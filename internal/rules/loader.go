@@ -4,6 +4,9 @@ package rules
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -28,6 +31,54 @@ func LoadFromBytes(data []byte) (*RuleSet, error) {
 	return &rs, nil
 }
 
+// LoadFromDir loads and merges every *.yaml/*.yml fragment in a directory,
+// à la Traefik's file provider. Fragments are merged in deterministic
+// filename order. A fragment may scope its rules to a tenant by setting
+// `tenant:` at the top level; scoped rule IDs are prefixed "<tenant>/" so
+// rules from different tenants can't collide.
+func LoadFromDir(dir string) (*RuleSet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	merged := &RuleSet{}
+	for _, name := range names {
+		var fragment struct {
+			Tenant string `yaml:"tenant,omitempty"`
+			RuleSet
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules fragment %s: %w", name, err)
+		}
+		if err := yaml.Unmarshal(data, &fragment); err != nil {
+			return nil, fmt.Errorf("failed to parse rules fragment %s: %w", name, err)
+		}
+
+		for _, r := range fragment.Rules {
+			if fragment.Tenant != "" {
+				r.ID = fragment.Tenant + "/" + r.ID
+			}
+			merged.Rules = append(merged.Rules, r)
+		}
+	}
+
+	return merged, nil
+}
+
 // SaveToFile writes a RuleSet to a YAML file.
 func SaveToFile(rs *RuleSet, path string) error {
 	data, err := yaml.Marshal(rs)
@@ -2,39 +2,141 @@
 package rules
 
 import (
+	"fmt"
+	"net"
 	"regexp"
 	"strings"
+
+	"github.com/google/cel-go/cel"
 )
 
 // CompiledRule holds a rule with its pre-compiled matchers.
 type CompiledRule struct {
-	Rule           *Rule
-	CommandMatcher *regexp.Regexp // Compiled command pattern (nil if no pattern)
-	DomainMatcher  *regexp.Regexp // Compiled domain pattern (nil if no domain)
+	Rule              *Rule
+	CommandMatcher    *regexp.Regexp            // Compiled command pattern, Regex, or PatternRegex (nil if none set)
+	DomainMatcher     *regexp.Regexp            // Compiled domain pattern or DomainRegex (nil if neither set; mutually exclusive with domainSuffix)
+	domainSuffix      string                    // Lowercased DomainSuffix (nil/"" if unset, or if DomainMatcher took priority)
+	PathMatcher       *regexp.Regexp            // Compiled path pattern (nil if no path)
+	HeaderMatchers    map[string]*regexp.Regexp // Compiled header regexes, keyed by header name (nil if no header)
+	bodyJSONPathRegex *regexp.Regexp            // Compiled BodyJSONPath.Regex (nil if unset)
+	exprProgram       cel.Program               // Compiled Expr (nil if unset)
+	cidr              *net.IPNet                // Compiled CIDR (nil if unset)
+	whenProgram       whenProgram               // Compiled When (nil if unset)
 }
 
 // CompileRule compiles patterns in a rule for efficient matching.
-// Command patterns use glob syntax (* matches anything).
-// Domain patterns support exact match and wildcard prefix (*.example.com).
+// Command and path patterns use glob syntax (* matches anything), unless
+// prefixed "re2:" for a raw regex. Domain patterns support exact match,
+// wildcard prefix (*.example.com), and the same "re2:" escape hatch.
+// Header values and BodyJSONPath.Regex are plain regexes. Regex and
+// PatternRegex are dedicated raw-regex fields for a command pattern,
+// equivalent to setting Pattern to "re2:<same regex>"; if more than one
+// of Pattern/Regex/PatternRegex is set, Pattern wins, then Regex.
+// DomainRegex and DomainSuffix are the analogous alternatives for Domain.
+// Expr and When are compiled independent of all of the above -- see
+// cel.go and when.go. CIDR is compiled to a *net.IPNet, evaluated only by
+// MatchHTTP against the client IP Inspector.CheckRequest observed.
 func CompileRule(r *Rule) (*CompiledRule, error) {
 	cr := &CompiledRule{Rule: r}
 
-	// Compile command pattern if present
-	if r.Pattern != "" {
-		regex, err := globToRegex(r.Pattern)
+	// Compile command pattern if present: Pattern (glob, or "re2:" raw
+	// regex) takes priority over Regex and PatternRegex, both the same
+	// raw-regex form under their own key.
+	switch {
+	case r.Pattern != "":
+		regex, err := compileGlobOrRE2(r.Pattern, globToRegex)
 		if err != nil {
 			return nil, err
 		}
 		cr.CommandMatcher = regex
+	case r.Regex != "":
+		regex, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		cr.CommandMatcher = regex
+	case r.PatternRegex != "":
+		regex, err := regexp.Compile(r.PatternRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern_regex: %w", err)
+		}
+		cr.CommandMatcher = regex
 	}
 
-	// Compile domain pattern if present
-	if r.Domain != "" {
-		regex, err := domainToRegex(r.Domain)
+	// Compile domain pattern if present: Domain (glob, or "re2:" raw
+	// regex) takes priority over DomainRegex, then DomainSuffix.
+	switch {
+	case r.Domain != "":
+		regex, err := compileGlobOrRE2(r.Domain, domainToRegex)
 		if err != nil {
 			return nil, err
 		}
 		cr.DomainMatcher = regex
+	case r.DomainRegex != "":
+		regex, err := regexp.Compile(r.DomainRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain_regex: %w", err)
+		}
+		cr.DomainMatcher = regex
+	case r.DomainSuffix != "":
+		cr.domainSuffix = strings.ToLower(r.DomainSuffix)
+	}
+
+	// Compile Expr, a CEL predicate independent of Pattern/Regex/Domain.
+	if r.Expr != "" {
+		program, err := compileExprRule(r.Expr)
+		if err != nil {
+			return nil, err
+		}
+		cr.exprProgram = program
+	}
+
+	// Compile CIDR, checked only by MatchHTTP against the client IP.
+	if r.CIDR != "" {
+		_, ipnet, err := net.ParseCIDR(r.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr: %w", err)
+		}
+		cr.cidr = ipnet
+	}
+
+	// Compile When, the cross-cutting predicate language (see when.go).
+	if r.When != "" {
+		program, err := compileWhen(r.When)
+		if err != nil {
+			return nil, err
+		}
+		cr.whenProgram = program
+	}
+
+	// Compile path pattern if present
+	if r.Path != "" {
+		regex, err := compileGlobOrRE2(r.Path, globToRegex)
+		if err != nil {
+			return nil, err
+		}
+		cr.PathMatcher = regex
+	}
+
+	// Compile header regexes if present
+	if len(r.Header) > 0 {
+		cr.HeaderMatchers = make(map[string]*regexp.Regexp, len(r.Header))
+		for name, pattern := range r.Header {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, err
+			}
+			cr.HeaderMatchers[name] = regex
+		}
+	}
+
+	// Compile body_jsonpath's regex, if it uses one instead of Equals.
+	if r.BodyJSONPath != nil && r.BodyJSONPath.Regex != "" {
+		regex, err := regexp.Compile(r.BodyJSONPath.Regex)
+		if err != nil {
+			return nil, err
+		}
+		cr.bodyJSONPathRegex = regex
 	}
 
 	return cr, nil
@@ -48,13 +150,80 @@ func (cr *CompiledRule) MatchCommand(cmd string) bool {
 	return cr.CommandMatcher.MatchString(cmd)
 }
 
-// MatchDomain checks if a domain matches this rule's domain pattern.
+// MatchDomain checks if a domain matches this rule's domain pattern,
+// domain_regex, or domain_suffix (whichever CompileRule picked).
 func (cr *CompiledRule) MatchDomain(domain string) bool {
-	if cr.DomainMatcher == nil {
+	domain = strings.ToLower(domain)
+	if cr.DomainMatcher != nil {
+		return cr.DomainMatcher.MatchString(domain)
+	}
+	if cr.domainSuffix != "" {
+		return strings.HasSuffix(domain, cr.domainSuffix)
+	}
+	return false
+}
+
+// MatchCIDR checks if clientIP falls within this rule's CIDR block.
+// Returns false if CIDR isn't set, or clientIP doesn't parse.
+func (cr *CompiledRule) MatchCIDR(clientIP string) bool {
+	if cr.cidr == nil {
 		return false
 	}
-	// Normalize domain to lowercase for matching
-	return cr.DomainMatcher.MatchString(strings.ToLower(domain))
+	ip := net.ParseIP(clientIP)
+	return ip != nil && cr.cidr.Contains(ip)
+}
+
+// MatchWhen evaluates this rule's When predicate against vars (see
+// whenVarsFor). Returns false if When isn't set.
+func (cr *CompiledRule) MatchWhen(vars map[string]interface{}) bool {
+	if cr.whenProgram == nil {
+		return false
+	}
+	return cr.whenProgram.eval(vars)
+}
+
+// MatchPath checks if an HTTP path matches this rule's Path pattern.
+func (cr *CompiledRule) MatchPath(path string) bool {
+	if cr.PathMatcher == nil {
+		return false
+	}
+	return cr.PathMatcher.MatchString(path)
+}
+
+// MatchExpr evaluates this rule's Expr predicate against whichever of
+// cmd/argv/domain/principal/agentID the calling check has; a check that
+// doesn't have one (e.g. CheckDomainWithTier has no argv) passes its zero
+// value, which Expr sees as an empty string or list rather than an
+// evaluation error. Returns false if Expr isn't set, or if evaluation
+// itself errors, the same "treat the unexpected as no match" stance
+// globToRegex's compiled matchers take.
+func (cr *CompiledRule) MatchExpr(cmd string, argv []string, domain, principal, agentID string) bool {
+	if cr.exprProgram == nil {
+		return false
+	}
+	out, _, err := cr.exprProgram.Eval(exprVars{
+		Cmd:       cmd,
+		Argv:      argv,
+		Domain:    domain,
+		Principal: principal,
+		AgentID:   agentID,
+	}.toMap())
+	if err != nil {
+		return false
+	}
+	matched, ok := out.Value().(bool)
+	return ok && matched
+}
+
+// compileGlobOrRE2 compiles pattern with globCompiler, unless it's
+// prefixed "re2:", in which case the remainder is compiled as a raw
+// regexp instead -- the escape hatch Pattern, Domain, and Path all share
+// for matching glob can't express.
+func compileGlobOrRE2(pattern string, globCompiler func(string) (*regexp.Regexp, error)) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "re2:") {
+		return regexp.Compile(strings.TrimPrefix(pattern, "re2:"))
+	}
+	return globCompiler(pattern)
 }
 
 // globToRegex converts a glob pattern to a regex.
@@ -0,0 +1,103 @@
+// Package rules provides the rule engine for filtering traffic.
+package rules
+
+import (
+	"fmt"
+	"time"
+)
+
+// validRateLimitScopes enumerates the RateLimitSpec.Scope values
+// internal/ratelimit knows how to resolve a bucket key for.
+var validRateLimitScopes = map[string]bool{
+	"agent":  true,
+	"tier":   true,
+	"host":   true,
+	"global": true,
+}
+
+// validTiers enumerates the tier names rules are allowed to reference.
+// Keep in sync with the tiers used by internal/fleet (commodore, captain,
+// crew).
+var validTiers = map[string]bool{
+	"commodore": true,
+	"captain":   true,
+	"crew":      true,
+}
+
+// validActions enumerates the rule actions the engine understands.
+var validActions = map[string]bool{
+	"allow": true,
+	"block": true,
+}
+
+// ValidationError reports the index of the offending rule within the
+// RuleSet's Rules slice, alongside the underlying problem, so a caller like
+// POST /rules/reload can point an operator straight at the bad entry
+// instead of just failing the whole file.
+type ValidationError struct {
+	Index int
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("rule %d: %v", e.Index, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// Validate checks a RuleSet for problems that would make it unsafe to
+// serve: unknown actions, unknown tier names, duplicate rule IDs, and
+// patterns/domains that fail to compile. It is run on every reload so a
+// bad update never replaces a good, currently-serving ruleset.
+func Validate(rs *RuleSet) error {
+	if rs == nil {
+		return fmt.Errorf("nil ruleset")
+	}
+
+	seenIDs := make(map[string]bool, len(rs.Rules))
+	for i, r := range rs.Rules {
+		if r.ID == "" {
+			return &ValidationError{Index: i, Err: fmt.Errorf("id is required")}
+		}
+		if seenIDs[r.ID] {
+			return &ValidationError{Index: i, Err: fmt.Errorf("duplicate id %q", r.ID)}
+		}
+		seenIDs[r.ID] = true
+
+		if !validActions[r.Action] {
+			return &ValidationError{Index: i, Err: fmt.Errorf("unknown action %q", r.Action)}
+		}
+
+		for _, tier := range r.Tiers {
+			if !validTiers[tier] {
+				return &ValidationError{Index: i, Err: fmt.Errorf("unknown tier %q", tier)}
+			}
+		}
+
+		if r.Pattern == "" && r.Regex == "" && r.PatternRegex == "" && r.Domain == "" && r.DomainRegex == "" && r.DomainSuffix == "" && r.Expr == "" && r.CIDR == "" && r.When == "" && r.Path == "" && len(r.Methods) == 0 && len(r.Header) == 0 && r.BodyJSONPath == nil && r.RateLimit == nil {
+			return &ValidationError{Index: i, Err: fmt.Errorf("must set pattern, regex, pattern_regex, domain, domain_regex, domain_suffix, expr, cidr, when, method, path, header, body_jsonpath, or rate_limit")}
+		}
+
+		if r.BodyJSONPath != nil && r.BodyJSONPath.Expression == "" {
+			return &ValidationError{Index: i, Err: fmt.Errorf("body_jsonpath requires an expression")}
+		}
+
+		if r.RateLimit != nil {
+			if r.RateLimit.Requests <= 0 {
+				return &ValidationError{Index: i, Err: fmt.Errorf("rate_limit.requests must be positive")}
+			}
+			if _, err := time.ParseDuration(r.RateLimit.Per); err != nil {
+				return &ValidationError{Index: i, Err: fmt.Errorf("rate_limit.per: %w", err)}
+			}
+			if !validRateLimitScopes[r.RateLimit.Scope] {
+				return &ValidationError{Index: i, Err: fmt.Errorf("rate_limit.scope must be one of agent, tier, host, global, got %q", r.RateLimit.Scope)}
+			}
+		}
+
+		if _, err := CompileRule(&rs.Rules[i]); err != nil {
+			return &ValidationError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
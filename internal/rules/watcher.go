@@ -0,0 +1,195 @@
+package rules
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReloadEvent describes the outcome of a single reload attempt, delivered
+// to observers on Watcher.ReloadCh.
+type ReloadEvent struct {
+	Path      string
+	Err       error // nil on success
+	RuleCount int
+	Time      time.Time
+}
+
+// Watcher observes a rules file (or directory, for LoadFromDir mode) for
+// changes and atomically reloads the Engine's active ruleset on each
+// change. A bad update is logged and skipped; the engine keeps serving the
+// last good ruleset.
+type Watcher struct {
+	engine        *Engine
+	path          string
+	isDir         bool
+	watcher       *fsnotify.Watcher
+	ReloadCh      chan ReloadEvent
+	done          chan struct{}
+	onReload      func(oldCount, newCount int)
+	onReloadError func(err error)
+}
+
+// WatcherOption configures a Watcher at construction time.
+type WatcherOption func(*Watcher)
+
+// WithOnReload registers a callback fired after every successful reload
+// with the rule count before and after the swap, so a caller like the
+// proxy's main loop can log a one-line summary instead of consuming
+// ReloadCh itself.
+func WithOnReload(fn func(oldCount, newCount int)) WatcherOption {
+	return func(w *Watcher) {
+		w.onReload = fn
+	}
+}
+
+// WithOnReloadError registers a callback fired whenever a reload attempt
+// fails validation or compilation; the engine keeps serving its last good
+// ruleset regardless. Complements ReloadCh for a caller that wants a
+// direct hook rather than a channel to drain.
+func WithOnReloadError(fn func(err error)) WatcherOption {
+	return func(w *Watcher) {
+		w.onReloadError = fn
+	}
+}
+
+// NewWatcher creates a Watcher for a single rules file.
+func NewWatcher(engine *Engine, path string, opts ...WatcherOption) (*Watcher, error) {
+	return newWatcher(engine, path, false, opts)
+}
+
+// NewDirWatcher creates a Watcher that merges rule fragments from a
+// directory via LoadFromDir on every change.
+func NewDirWatcher(engine *Engine, dir string, opts ...WatcherOption) (*Watcher, error) {
+	return newWatcher(engine, dir, true, opts)
+}
+
+func newWatcher(engine *Engine, path string, isDir bool, opts []WatcherOption) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(path); err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		engine:   engine,
+		path:     path,
+		isDir:    isDir,
+		watcher:  fw,
+		ReloadCh: make(chan ReloadEvent, 8),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	go w.run()
+	go w.handleSIGHUP()
+	return w, nil
+}
+
+// run processes fsnotify events, debouncing bursts of writes (editors often
+// emit several events for a single save) into a single reload.
+func (w *Watcher) run() {
+	var pending *time.Timer
+	reload := func() {
+		w.reload()
+	}
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			if pending != nil {
+				pending.Stop()
+			}
+			pending = time.AfterFunc(100*time.Millisecond, reload)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("rules watcher error: %v", err)
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// handleSIGHUP triggers a reload whenever the process receives SIGHUP, the
+// conventional signal for "re-read your config".
+func (w *Watcher) handleSIGHUP() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	for {
+		select {
+		case <-sig:
+			w.reload()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// reload re-reads the watched path and swaps it into the engine, emitting
+// a ReloadEvent describing the outcome and invoking whichever of
+// onReload/onReloadError applies. The engine's previous ruleset is left
+// untouched on error (see Engine.swap), so oldCount == newCount on
+// failure.
+func (w *Watcher) reload() {
+	oldCount := w.engine.RuleCount()
+
+	var err error
+	if w.isDir {
+		var rs *RuleSet
+		rs, err = LoadFromDir(w.path)
+		if err == nil {
+			err = w.engine.swap(rs)
+		}
+	} else {
+		err = w.engine.LoadRules(w.path)
+	}
+
+	newCount := w.engine.RuleCount()
+
+	if err != nil {
+		log.Printf("rules reload failed for %s: %v", w.path, err)
+		if w.onReloadError != nil {
+			w.onReloadError(err)
+		}
+	} else if w.onReload != nil {
+		w.onReload(oldCount, newCount)
+	}
+
+	event := ReloadEvent{
+		Path:      w.path,
+		Err:       err,
+		RuleCount: newCount,
+		Time:      time.Now().UTC(),
+	}
+	select {
+	case w.ReloadCh <- event:
+	default:
+		// Slow/absent observer - don't block reloads on it.
+	}
+}
+
+// Close stops the watcher and releases its fsnotify resources.
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.watcher.Close()
+}
@@ -0,0 +1,603 @@
+// Package rules provides the rule engine for filtering traffic.
+package rules
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// This file implements "when", a small boolean expression language for a
+// rule's cross-cutting predicate over host/command/agent/tier/method/
+// path/client_ip/hour/weekday (see whenVars). It's the same hand-rolled
+// recursive-descent style internal/matcher's "expr" match type uses for
+// exec rules, but flatter -- whenVars has no nested or indexed
+// identifiers -- and with a cidr operator for matching client_ip against
+// a CIDR block. Expr (cel.go) already covers CEL-flavored predicates over
+// cmd/argv/domain/principal/agent_id; When is the tool for conditions
+// better read as and/or/not over this wider, request-shaped set.
+
+// whenProgram is a compiled When expression, evaluated each check against
+// a fresh whenVars map.
+type whenProgram interface {
+	eval(vars map[string]interface{}) bool
+}
+
+type whenValue interface {
+	value(vars map[string]interface{}) interface{}
+}
+
+// compileWhen parses a When expression once, at rule-compile time, so
+// CheckHTTPWithTier and friends never reparse it on the hot path. Any
+// syntax error -- an unknown operator, an unterminated string, a
+// malformed CIDR literal -- is returned here rather than deferred to
+// eval, per compileRuleSet's "fail loudly on parse errors" contract.
+func compileWhen(expr string) (whenProgram, error) {
+	toks, err := lexWhen(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid when expression: %w", err)
+	}
+	p := &whenParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid when expression: %w", err)
+	}
+	if p.peek().kind != whenTokEOF {
+		return nil, fmt.Errorf("invalid when expression: unexpected token %q", p.peek().text)
+	}
+	return root, nil
+}
+
+// whenVarsFor builds the identifier namespace a When expression evaluates
+// against. hour and weekday are the caller's wall-clock hour-of-day
+// (0-23) and day-of-week (0=Sunday, matching time.Weekday), passed in
+// rather than computed here so a test can pin them.
+func whenVarsFor(host, command, agent, tier, method, path, clientIP string, hour, weekday int) map[string]interface{} {
+	return map[string]interface{}{
+		"host":      host,
+		"command":   command,
+		"agent":     agent,
+		"tier":      tier,
+		"method":    method,
+		"path":      path,
+		"client_ip": clientIP,
+		"hour":      float64(hour),
+		"weekday":   float64(weekday),
+	}
+}
+
+// --- lexer ---
+
+type whenTokenKind int
+
+const (
+	whenTokEOF whenTokenKind = iota
+	whenTokIdent
+	whenTokString
+	whenTokNumber
+	whenTokLParen
+	whenTokRParen
+	whenTokLBracket
+	whenTokRBracket
+	whenTokComma
+	whenTokEQ
+	whenTokNEQ
+	whenTokLT
+	whenTokLE
+	whenTokGT
+	whenTokGE
+)
+
+type whenToken struct {
+	kind whenTokenKind
+	text string
+}
+
+func lexWhen(s string) ([]whenToken, error) {
+	runes := []rune(s)
+	var toks []whenToken
+	pos := 0
+
+	skipSpace := func() {
+		for pos < len(runes) && (runes[pos] == ' ' || runes[pos] == '\t' || runes[pos] == '\n' || runes[pos] == '\r') {
+			pos++
+		}
+	}
+	peekAt := func(offset int) rune {
+		if pos+offset >= len(runes) {
+			return 0
+		}
+		return runes[pos+offset]
+	}
+
+	for {
+		skipSpace()
+		if pos >= len(runes) {
+			toks = append(toks, whenToken{kind: whenTokEOF})
+			return toks, nil
+		}
+
+		c := runes[pos]
+		switch {
+		case c == '(':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokLParen, text: "("})
+		case c == ')':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokRParen, text: ")"})
+		case c == '[':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokLBracket, text: "["})
+		case c == ']':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokRBracket, text: "]"})
+		case c == ',':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokComma, text: ","})
+		case c == '=' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, whenToken{kind: whenTokEQ, text: "=="})
+		case c == '!' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, whenToken{kind: whenTokNEQ, text: "!="})
+		case c == '<' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, whenToken{kind: whenTokLE, text: "<="})
+		case c == '<':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokLT, text: "<"})
+		case c == '>' && peekAt(1) == '=':
+			pos += 2
+			toks = append(toks, whenToken{kind: whenTokGE, text: ">="})
+		case c == '>':
+			pos++
+			toks = append(toks, whenToken{kind: whenTokGT, text: ">"})
+		case c == '"':
+			start := pos + 1
+			pos++
+			var sb strings.Builder
+			closed := false
+			for pos < len(runes) {
+				if runes[pos] == '"' {
+					closed = true
+					pos++
+					break
+				}
+				if runes[pos] == '\\' && pos+1 < len(runes) {
+					pos++
+				}
+				sb.WriteRune(runes[pos])
+				pos++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string literal starting at position %d", start-1)
+			}
+			toks = append(toks, whenToken{kind: whenTokString, text: sb.String()})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := pos
+			if c == '-' {
+				pos++
+			}
+			for pos < len(runes) && runes[pos] >= '0' && runes[pos] <= '9' {
+				pos++
+			}
+			if pos < len(runes) && runes[pos] == '.' {
+				pos++
+				for pos < len(runes) && runes[pos] >= '0' && runes[pos] <= '9' {
+					pos++
+				}
+			}
+			toks = append(toks, whenToken{kind: whenTokNumber, text: string(runes[start:pos])})
+		case isWhenIdentStart(c):
+			start := pos
+			for pos < len(runes) && isWhenIdentPart(runes[pos]) {
+				pos++
+			}
+			toks = append(toks, whenToken{kind: whenTokIdent, text: string(runes[start:pos])})
+		default:
+			return nil, fmt.Errorf("unexpected character %q", string(c))
+		}
+	}
+}
+
+func isWhenIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWhenIdentPart(c rune) bool {
+	return isWhenIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// --- parser ---
+
+type whenParser struct {
+	toks []whenToken
+	pos  int
+}
+
+func (p *whenParser) peek() whenToken {
+	return p.toks[p.pos]
+}
+
+func (p *whenParser) advance() whenToken {
+	t := p.toks[p.pos]
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *whenParser) parseOr() (whenProgram, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == whenTokIdent && p.peek().text == "or" {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = whenOrNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseAnd() (whenProgram, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == whenTokIdent && p.peek().text == "and" {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = whenAndNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *whenParser) parseUnary() (whenProgram, error) {
+	if p.peek().kind == whenTokIdent && p.peek().text == "not" {
+		p.advance()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return whenNotNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *whenParser) parsePrimary() (whenProgram, error) {
+	if p.peek().kind == whenTokLParen {
+		p.advance()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != whenTokRParen {
+			return nil, fmt.Errorf("expected ')'")
+		}
+		p.advance()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *whenParser) parseComparison() (whenProgram, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch tok.kind {
+	case whenTokEQ, whenTokNEQ, whenTokLT, whenTokLE, whenTokGT, whenTokGE:
+		p.advance()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return whenComparisonNode{left: left, op: tok.text, right: right}, nil
+	case whenTokIdent:
+		switch tok.text {
+		case "contains":
+			p.advance()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return whenContainsNode{left: left, right: right}, nil
+		case "in":
+			p.advance()
+			list, err := p.parseListLiteral()
+			if err != nil {
+				return nil, err
+			}
+			return whenInNode{left: left, list: list}, nil
+		case "cidr":
+			p.advance()
+			pat := p.peek()
+			if pat.kind != whenTokString {
+				return nil, fmt.Errorf("cidr requires a string literal CIDR block")
+			}
+			p.advance()
+			_, ipnet, err := net.ParseCIDR(pat.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR block %q: %w", pat.text, err)
+			}
+			return whenCIDRNode{left: left, ipnet: ipnet}, nil
+		}
+	}
+	return whenTruthyNode{left}, nil
+}
+
+func (p *whenParser) parseOperand() (whenValue, error) {
+	tok := p.peek()
+	switch tok.kind {
+	case whenTokString:
+		p.advance()
+		return whenLiteral{tok.text}, nil
+	case whenTokNumber:
+		p.advance()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return whenLiteral{f}, nil
+	case whenTokIdent:
+		switch tok.text {
+		case "true":
+			p.advance()
+			return whenLiteral{true}, nil
+		case "false":
+			p.advance()
+			return whenLiteral{false}, nil
+		}
+		p.advance()
+		return whenIdent{name: tok.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected token %q", tok.text)
+}
+
+func (p *whenParser) parseListLiteral() ([]interface{}, error) {
+	if p.peek().kind != whenTokLBracket {
+		return nil, fmt.Errorf("expected '['")
+	}
+	p.advance()
+	var vals []interface{}
+	if p.peek().kind == whenTokRBracket {
+		p.advance()
+		return vals, nil
+	}
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case whenTokString:
+			vals = append(vals, tok.text)
+			p.advance()
+		case whenTokNumber:
+			f, err := strconv.ParseFloat(tok.text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", tok.text)
+			}
+			vals = append(vals, f)
+			p.advance()
+		default:
+			return nil, fmt.Errorf("expected a string or number in list literal")
+		}
+		if p.peek().kind == whenTokComma {
+			p.advance()
+			continue
+		}
+		break
+	}
+	if p.peek().kind != whenTokRBracket {
+		return nil, fmt.Errorf("expected ']'")
+	}
+	p.advance()
+	return vals, nil
+}
+
+// --- AST nodes ---
+
+type whenAndNode struct{ left, right whenProgram }
+
+func (n whenAndNode) eval(vars map[string]interface{}) bool {
+	return n.left.eval(vars) && n.right.eval(vars)
+}
+
+type whenOrNode struct{ left, right whenProgram }
+
+func (n whenOrNode) eval(vars map[string]interface{}) bool {
+	return n.left.eval(vars) || n.right.eval(vars)
+}
+
+type whenNotNode struct{ inner whenProgram }
+
+func (n whenNotNode) eval(vars map[string]interface{}) bool { return !n.inner.eval(vars) }
+
+// whenTruthyNode lets a bare identifier stand in for a boolean, e.g. a
+// when of just `agent` means "agent is set".
+type whenTruthyNode struct{ v whenValue }
+
+func (n whenTruthyNode) eval(vars map[string]interface{}) bool {
+	return whenIsTruthy(n.v.value(vars))
+}
+
+type whenComparisonNode struct {
+	left  whenValue
+	op    string
+	right whenValue
+}
+
+func (n whenComparisonNode) eval(vars map[string]interface{}) bool {
+	return whenCompare(n.op, n.left.value(vars), n.right.value(vars))
+}
+
+type whenContainsNode struct{ left, right whenValue }
+
+func (n whenContainsNode) eval(vars map[string]interface{}) bool {
+	return strings.Contains(whenAsString(n.left.value(vars)), whenAsString(n.right.value(vars)))
+}
+
+type whenInNode struct {
+	left whenValue
+	list []interface{}
+}
+
+func (n whenInNode) eval(vars map[string]interface{}) bool {
+	v := n.left.value(vars)
+	for _, item := range n.list {
+		if whenCompare("==", v, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// whenCIDRNode's ipnet is parsed once at compile time, not on every eval.
+type whenCIDRNode struct {
+	left  whenValue
+	ipnet *net.IPNet
+}
+
+func (n whenCIDRNode) eval(vars map[string]interface{}) bool {
+	ip := net.ParseIP(whenAsString(n.left.value(vars)))
+	return ip != nil && n.ipnet.Contains(ip)
+}
+
+type whenLiteral struct{ v interface{} }
+
+func (l whenLiteral) value(map[string]interface{}) interface{} { return l.v }
+
+// whenIdent resolves a flat identifier (host, tier, client_ip, ...)
+// against the map whenVarsFor builds. An identifier outside that fixed
+// set resolves to nil, which whenCompare/whenIsTruthy treat as the zero
+// value of whatever it's compared against, rather than erroring, so a
+// typo'd field name fails to match instead of failing to compile -- the
+// same stance internal/matcher's identNode takes for an unset field.
+type whenIdent struct{ name string }
+
+func (i whenIdent) value(vars map[string]interface{}) interface{} {
+	return vars[i.name]
+}
+
+// --- value helpers ---
+
+func whenIsTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case float64:
+		return t != 0
+	default:
+		return true
+	}
+}
+
+func whenAsString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func whenToFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func whenZeroLike(v interface{}) interface{} {
+	switch v.(type) {
+	case float64:
+		return float64(0)
+	case bool:
+		return false
+	default:
+		return ""
+	}
+}
+
+func whenCompare(op string, left, right interface{}) bool {
+	if left == nil {
+		left = whenZeroLike(right)
+	}
+	if right == nil {
+		right = whenZeroLike(left)
+	}
+	switch l := left.(type) {
+	case float64:
+		r, ok := whenToFloat(right)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		case "<":
+			return l < r
+		case "<=":
+			return l <= r
+		case ">":
+			return l > r
+		case ">=":
+			return l >= r
+		}
+		return false
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return l == r
+		case "!=":
+			return l != r
+		default:
+			return false
+		}
+	default:
+		ls, rs := whenAsString(left), whenAsString(right)
+		switch op {
+		case "==":
+			return ls == rs
+		case "!=":
+			return ls != rs
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		}
+		return false
+	}
+}
@@ -0,0 +1,367 @@
+//go:build !nobadger
+
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func init() {
+	register("badger", openBadger)
+}
+
+// Key prefixes badger uses to namespace entity types within its single flat
+// keyspace (badger has no notion of separate buckets).
+var (
+	prefixAgent          = []byte("agent:")
+	prefixRule           = []byte("rule:")
+	prefixLog            = []byte("log:")
+	prefixBastionSession = []byte("bsession:")
+	prefixBastionGrant   = []byte("bgrant:")
+	prefixRevocation     = []byte("revocation:")
+	prefixTenant         = []byte("tenant:")
+	prefixToken          = []byte("token:")
+	prefixAudit          = []byte("audit:")
+)
+
+// badgerStore is a Store backed by a badger key-value database directory.
+type badgerStore struct {
+	db *badger.DB
+}
+
+func openBadger(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("storage: badger requires a directory path")
+	}
+	opts := badger.DefaultOptions(path).WithLogger(nil)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open badger db: %w", err)
+	}
+	return &badgerStore{db: db}, nil
+}
+
+func (s *badgerStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *badgerStore) UpsertAgent(a Agent) error {
+	return badgerPutJSON(s.db, prefixAgent, a.ID, a)
+}
+
+func (s *badgerStore) GetAgent(id string) (Agent, error) {
+	var a Agent
+	err := badgerGetJSON(s.db, prefixAgent, id, &a)
+	return a, err
+}
+
+func (s *badgerStore) ListAgents() ([]Agent, error) {
+	var out []Agent
+	err := badgerForEachPrefix(s.db, prefixAgent, func(v []byte) error {
+		var a Agent
+		if err := json.Unmarshal(v, &a); err != nil {
+			return err
+		}
+		out = append(out, a)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) DeleteAgent(id string) error {
+	return badgerDelete(s.db, prefixAgent, id)
+}
+
+func (s *badgerStore) UpsertRule(r Rule) error {
+	return badgerPutJSON(s.db, prefixRule, r.ID, r)
+}
+
+func (s *badgerStore) ListRules() ([]Rule, error) {
+	var out []Rule
+	err := badgerForEachPrefix(s.db, prefixRule, func(v []byte) error {
+		var r Rule
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) DeleteRule(id string) error {
+	return badgerDelete(s.db, prefixRule, id)
+}
+
+func (s *badgerStore) AppendLog(entry LogEntry) error {
+	return badgerAppendSeq(s.db, prefixLog, entry)
+}
+
+func (s *badgerStore) ListLogs(limit int) ([]LogEntry, error) {
+	var out []LogEntry
+	err := badgerLastN(s.db, prefixLog, limit, func(v []byte) error {
+		var e LogEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) AppendBastionSession(ev BastionSession) error {
+	return badgerAppendSeq(s.db, prefixBastionSession, ev)
+}
+
+func (s *badgerStore) ListBastionSessions(limit int) ([]BastionSession, error) {
+	var out []BastionSession
+	err := badgerLastN(s.db, prefixBastionSession, limit, func(v []byte) error {
+		var e BastionSession
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) UpsertBastionGrant(g BastionGrant) error {
+	return badgerPutJSON(s.db, prefixBastionGrant, g.ID, g)
+}
+
+func (s *badgerStore) ListBastionGrants() ([]BastionGrant, error) {
+	var out []BastionGrant
+	err := badgerForEachPrefix(s.db, prefixBastionGrant, func(v []byte) error {
+		var g BastionGrant
+		if err := json.Unmarshal(v, &g); err != nil {
+			return err
+		}
+		out = append(out, g)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) DeleteBastionGrant(id string) error {
+	return badgerDelete(s.db, prefixBastionGrant, id)
+}
+
+func (s *badgerStore) Revoke(rev Revocation) error {
+	return badgerPutJSON(s.db, prefixRevocation, rev.Serial, rev)
+}
+
+func (s *badgerStore) IsRevoked(serial string) (bool, error) {
+	var rev Revocation
+	err := badgerGetJSON(s.db, prefixRevocation, serial, &rev)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *badgerStore) ListRevocations() ([]Revocation, error) {
+	var out []Revocation
+	err := badgerForEachPrefix(s.db, prefixRevocation, func(v []byte) error {
+		var rev Revocation
+		if err := json.Unmarshal(v, &rev); err != nil {
+			return err
+		}
+		out = append(out, rev)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) UpsertTenant(t Tenant) error {
+	return badgerPutJSON(s.db, prefixTenant, t.ID, t)
+}
+
+func (s *badgerStore) GetTenant(id string) (Tenant, error) {
+	var t Tenant
+	err := badgerGetJSON(s.db, prefixTenant, id, &t)
+	return t, err
+}
+
+func (s *badgerStore) ListTenants() ([]Tenant, error) {
+	var out []Tenant
+	err := badgerForEachPrefix(s.db, prefixTenant, func(v []byte) error {
+		var t Tenant
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		out = append(out, t)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) DeleteTenant(id string) error {
+	return badgerDelete(s.db, prefixTenant, id)
+}
+
+func (s *badgerStore) UpsertToken(t Token) error {
+	return badgerPutJSON(s.db, prefixToken, t.Token, t)
+}
+
+func (s *badgerStore) GetToken(token string) (Token, error) {
+	var t Token
+	err := badgerGetJSON(s.db, prefixToken, token, &t)
+	return t, err
+}
+
+func (s *badgerStore) ListTokens() ([]Token, error) {
+	var out []Token
+	err := badgerForEachPrefix(s.db, prefixToken, func(v []byte) error {
+		var t Token
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		out = append(out, t)
+		return nil
+	})
+	return out, err
+}
+
+func (s *badgerStore) DeleteToken(token string) error {
+	return badgerDelete(s.db, prefixToken, token)
+}
+
+func (s *badgerStore) AppendAudit(entry AuditEntry) error {
+	return badgerAppendSeq(s.db, prefixAudit, entry)
+}
+
+func (s *badgerStore) ListAudit(limit int) ([]AuditEntry, error) {
+	var out []AuditEntry
+	err := badgerLastN(s.db, prefixAudit, limit, func(v []byte) error {
+		var e AuditEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+func badgerPutJSON(db *badger.DB, prefix []byte, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("storage: marshal record: %w", err)
+	}
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set(append(append([]byte{}, prefix...), id...), data)
+	})
+}
+
+func badgerGetJSON(db *badger.DB, prefix []byte, id string, v interface{}) error {
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(append(append([]byte{}, prefix...), id...))
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(data []byte) error {
+			return json.Unmarshal(data, v)
+		})
+	})
+	return err
+}
+
+func badgerDelete(db *badger.DB, prefix []byte, id string) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(append(append([]byte{}, prefix...), id...))
+	})
+}
+
+func badgerForEachPrefix(db *badger.DB, prefix []byte, fn func(v []byte) error) error {
+	return db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			if err := it.Item().Value(fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// badgerAppendSeq appends v under prefix with a big-endian sequence suffix,
+// so append-only entities (logs, bastion sessions) iterate in insertion
+// order, mirroring appendSeq in bbolt.go.
+func badgerAppendSeq(db *badger.DB, prefix []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("storage: marshal record: %w", err)
+	}
+	return db.Update(func(txn *badger.Txn) error {
+		seq, err := nextBadgerSeq(txn, prefix)
+		if err != nil {
+			return err
+		}
+		key := append(append([]byte{}, prefix...), seqSuffix(seq)...)
+		return txn.Set(key, data)
+	})
+}
+
+// nextBadgerSeq scans for the highest existing sequence suffix under
+// prefix and returns one past it. Badger has no built-in auto-increment
+// comparable to bbolt's NextSequence, so the count is tracked this way.
+func nextBadgerSeq(txn *badger.Txn, prefix []byte) (uint64, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	opts.Reverse = true
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	seekKey := append(append([]byte{}, prefix...), 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	it.Seek(seekKey)
+	if !it.ValidForPrefix(prefix) {
+		return 0, nil
+	}
+	key := it.Item().KeyCopy(nil)
+	suffix := key[len(prefix):]
+	if len(suffix) != 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint64(suffix) + 1, nil
+}
+
+func badgerLastN(db *badger.DB, prefix []byte, limit int, fn func(v []byte) error) error {
+	return db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		opts.Reverse = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		seekKey := append(append([]byte{}, prefix...), 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+		n := 0
+		for it.Seek(seekKey); it.ValidForPrefix(prefix); it.Next() {
+			if limit > 0 && n >= limit {
+				break
+			}
+			if err := it.Item().Value(fn); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+func seqSuffix(seq uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, seq)
+	return b
+}
@@ -0,0 +1,342 @@
+//go:build !nobbolt
+
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+func init() {
+	register(defaultScheme, openBolt)
+}
+
+var (
+	bucketAgents          = []byte("agents")
+	bucketRules           = []byte("rules")
+	bucketLogs            = []byte("logs")
+	bucketBastionSessions = []byte("bastion_sessions")
+	bucketBastionGrants   = []byte("bastion_grants")
+	bucketRevocations     = []byte("revocations")
+	bucketTenants         = []byte("tenants")
+	bucketTokens          = []byte("tokens")
+	bucketAudit           = []byte("audit")
+	allBuckets            = [][]byte{
+		bucketAgents, bucketRules, bucketLogs,
+		bucketBastionSessions, bucketBastionGrants, bucketRevocations,
+		bucketTenants, bucketTokens, bucketAudit,
+	}
+)
+
+// boltStore is a Store backed by a single bbolt file, one bucket per
+// entity. Append-only entities (logs, bastion sessions) are keyed by a
+// big-endian sequence number so bucket iteration order is insertion order.
+type boltStore struct {
+	db *bbolt.DB
+}
+
+func openBolt(path string) (Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("storage: bbolt requires a file path")
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("storage: open bbolt db: %w", err)
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		for _, b := range allBuckets {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: init bbolt buckets: %w", err)
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *boltStore) UpsertAgent(a Agent) error {
+	return putJSON(s.db, bucketAgents, []byte(a.ID), a)
+}
+
+func (s *boltStore) GetAgent(id string) (Agent, error) {
+	var a Agent
+	err := getJSON(s.db, bucketAgents, []byte(id), &a)
+	return a, err
+}
+
+func (s *boltStore) ListAgents() ([]Agent, error) {
+	var out []Agent
+	err := forEach(s.db, bucketAgents, func(v []byte) error {
+		var a Agent
+		if err := json.Unmarshal(v, &a); err != nil {
+			return err
+		}
+		out = append(out, a)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) DeleteAgent(id string) error {
+	return deleteKey(s.db, bucketAgents, []byte(id))
+}
+
+func (s *boltStore) UpsertRule(r Rule) error {
+	return putJSON(s.db, bucketRules, []byte(r.ID), r)
+}
+
+func (s *boltStore) ListRules() ([]Rule, error) {
+	var out []Rule
+	err := forEach(s.db, bucketRules, func(v []byte) error {
+		var r Rule
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		out = append(out, r)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) DeleteRule(id string) error {
+	return deleteKey(s.db, bucketRules, []byte(id))
+}
+
+func (s *boltStore) AppendLog(entry LogEntry) error {
+	return appendSeq(s.db, bucketLogs, entry)
+}
+
+func (s *boltStore) ListLogs(limit int) ([]LogEntry, error) {
+	var out []LogEntry
+	err := lastN(s.db, bucketLogs, limit, func(v []byte) error {
+		var e LogEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) AppendBastionSession(ev BastionSession) error {
+	return appendSeq(s.db, bucketBastionSessions, ev)
+}
+
+func (s *boltStore) ListBastionSessions(limit int) ([]BastionSession, error) {
+	var out []BastionSession
+	err := lastN(s.db, bucketBastionSessions, limit, func(v []byte) error {
+		var e BastionSession
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) UpsertBastionGrant(g BastionGrant) error {
+	return putJSON(s.db, bucketBastionGrants, []byte(g.ID), g)
+}
+
+func (s *boltStore) ListBastionGrants() ([]BastionGrant, error) {
+	var out []BastionGrant
+	err := forEach(s.db, bucketBastionGrants, func(v []byte) error {
+		var g BastionGrant
+		if err := json.Unmarshal(v, &g); err != nil {
+			return err
+		}
+		out = append(out, g)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) DeleteBastionGrant(id string) error {
+	return deleteKey(s.db, bucketBastionGrants, []byte(id))
+}
+
+func (s *boltStore) Revoke(rev Revocation) error {
+	return putJSON(s.db, bucketRevocations, []byte(rev.Serial), rev)
+}
+
+func (s *boltStore) IsRevoked(serial string) (bool, error) {
+	var rev Revocation
+	err := getJSON(s.db, bucketRevocations, []byte(serial), &rev)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *boltStore) ListRevocations() ([]Revocation, error) {
+	var out []Revocation
+	err := forEach(s.db, bucketRevocations, func(v []byte) error {
+		var rev Revocation
+		if err := json.Unmarshal(v, &rev); err != nil {
+			return err
+		}
+		out = append(out, rev)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) UpsertTenant(t Tenant) error {
+	return putJSON(s.db, bucketTenants, []byte(t.ID), t)
+}
+
+func (s *boltStore) GetTenant(id string) (Tenant, error) {
+	var t Tenant
+	err := getJSON(s.db, bucketTenants, []byte(id), &t)
+	return t, err
+}
+
+func (s *boltStore) ListTenants() ([]Tenant, error) {
+	var out []Tenant
+	err := forEach(s.db, bucketTenants, func(v []byte) error {
+		var t Tenant
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		out = append(out, t)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) DeleteTenant(id string) error {
+	return deleteKey(s.db, bucketTenants, []byte(id))
+}
+
+func (s *boltStore) UpsertToken(t Token) error {
+	return putJSON(s.db, bucketTokens, []byte(t.Token), t)
+}
+
+func (s *boltStore) GetToken(token string) (Token, error) {
+	var t Token
+	err := getJSON(s.db, bucketTokens, []byte(token), &t)
+	return t, err
+}
+
+func (s *boltStore) ListTokens() ([]Token, error) {
+	var out []Token
+	err := forEach(s.db, bucketTokens, func(v []byte) error {
+		var t Token
+		if err := json.Unmarshal(v, &t); err != nil {
+			return err
+		}
+		out = append(out, t)
+		return nil
+	})
+	return out, err
+}
+
+func (s *boltStore) DeleteToken(token string) error {
+	return deleteKey(s.db, bucketTokens, []byte(token))
+}
+
+func (s *boltStore) AppendAudit(entry AuditEntry) error {
+	return appendSeq(s.db, bucketAudit, entry)
+}
+
+func (s *boltStore) ListAudit(limit int) ([]AuditEntry, error) {
+	var out []AuditEntry
+	err := lastN(s.db, bucketAudit, limit, func(v []byte) error {
+		var e AuditEntry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
+
+func putJSON(db *bbolt.DB, bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("storage: marshal record: %w", err)
+	}
+	return db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, data)
+	})
+}
+
+func getJSON(db *bbolt.DB, bucket, key []byte, v interface{}) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucket).Get(key)
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, v)
+	})
+}
+
+func deleteKey(db *bbolt.DB, bucket, key []byte) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Delete(key)
+	})
+}
+
+func forEach(db *bbolt.DB, bucket []byte, fn func(v []byte) error) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, v []byte) error {
+			return fn(v)
+		})
+	})
+}
+
+// appendSeq appends v to bucket under its next auto-incrementing
+// big-endian key, so later records sort after earlier ones.
+func appendSeq(db *bbolt.DB, bucket []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("storage: marshal record: %w", err)
+	}
+	return db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(seqKey(seq), data)
+	})
+}
+
+// lastN walks bucket from its newest entry backward, calling fn on up to
+// limit entries. limit <= 0 means no limit.
+func lastN(db *bbolt.DB, bucket []byte, limit int, fn func(v []byte) error) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		n := 0
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if limit > 0 && n >= limit {
+				break
+			}
+			if err := fn(v); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultScheme is used for a bare file path (no "scheme://" prefix), e.g.
+// "/var/lib/plasma-shield/shield.db".
+const defaultScheme = "bbolt"
+
+// drivers maps a DSN scheme to the constructor for that driver. Each
+// driver file registers itself in an init(), gated by its own build tag,
+// so a binary built with e.g. -tags nopostgres simply never populates the
+// "postgres" entry.
+var drivers = make(map[string]func(dsn string) (Store, error))
+
+func register(scheme string, open func(dsn string) (Store, error)) {
+	if _, exists := drivers[scheme]; exists {
+		panic("storage: driver already registered for scheme " + scheme)
+	}
+	drivers[scheme] = open
+}
+
+// Open opens a Store for the given DSN. The scheme selects the driver:
+//
+//   - a bare path, or a "bbolt://" prefix: single-file bbolt store (default)
+//   - "badger://path": badger key-value store directory
+//   - "postgres://...": PostgreSQL via pgx
+//
+// It returns an error naming the scheme if no driver is registered for it,
+// which happens either for a typo or because the binary was built with
+// that driver excluded (-tags noDRIVER).
+func Open(dsn string) (Store, error) {
+	scheme, rest := splitScheme(dsn)
+	open, ok := drivers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("storage: no driver registered for scheme %q (excluded at build time, or a typo in --storage-dsn)", scheme)
+	}
+	return open(rest)
+}
+
+// splitScheme splits a DSN into its scheme and the remainder. A DSN with
+// no "scheme://" prefix is treated as a bbolt file path.
+func splitScheme(dsn string) (scheme, rest string) {
+	if i := strings.Index(dsn, "://"); i >= 0 {
+		return dsn[:i], dsn[i+len("://"):]
+	}
+	return defaultScheme, dsn
+}
@@ -0,0 +1,455 @@
+//go:build !nopostgres
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func init() {
+	register("postgres", openPostgres)
+}
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS agents (
+	id TEXT PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL DEFAULT '',
+	ip TEXT NOT NULL DEFAULT '',
+	webhook_url TEXT NOT NULL DEFAULT '',
+	tier TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS rules (
+	id TEXT PRIMARY KEY,
+	pattern TEXT NOT NULL DEFAULT '',
+	domain TEXT NOT NULL DEFAULT '',
+	match_type TEXT NOT NULL DEFAULT '',
+	priority INTEGER NOT NULL DEFAULT 0,
+	action TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS logs (
+	seq BIGSERIAL PRIMARY KEY,
+	id TEXT NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL,
+	agent_id TEXT NOT NULL DEFAULT '',
+	type TEXT NOT NULL DEFAULT '',
+	request TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL DEFAULT '',
+	rule_id TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS bastion_sessions (
+	seq BIGSERIAL PRIMARY KEY,
+	session_id TEXT NOT NULL,
+	grant_id TEXT NOT NULL DEFAULT '',
+	principal TEXT NOT NULL DEFAULT '',
+	target TEXT NOT NULL DEFAULT '',
+	event TEXT NOT NULL DEFAULT '',
+	timestamp TIMESTAMPTZ NOT NULL,
+	data TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS bastion_grants (
+	id TEXT PRIMARY KEY,
+	principal TEXT NOT NULL DEFAULT '',
+	target TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS revocations (
+	serial TEXT PRIMARY KEY,
+	reason TEXT NOT NULL DEFAULT '',
+	revoked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS tenants (
+	id TEXT PRIMARY KEY,
+	mode TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS tokens (
+	token TEXT PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS audit_log (
+	seq BIGSERIAL PRIMARY KEY,
+	id TEXT NOT NULL,
+	timestamp TIMESTAMPTZ NOT NULL,
+	actor TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL DEFAULT '',
+	resource_type TEXT NOT NULL DEFAULT '',
+	resource_id TEXT NOT NULL DEFAULT '',
+	before TEXT NOT NULL DEFAULT '',
+	after TEXT NOT NULL DEFAULT '',
+	prev_hash TEXT NOT NULL DEFAULT '',
+	hash TEXT NOT NULL DEFAULT ''
+);
+`
+
+// postgresStore is a Store backed by PostgreSQL via pgx.
+type postgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func openPostgres(dsn string) (Store, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("storage: postgres requires a connection string")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("storage: connect to postgres: %w", err)
+	}
+	if _, err := pool.Exec(context.Background(), postgresSchema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("storage: migrate postgres schema: %w", err)
+	}
+	return &postgresStore{pool: pool}, nil
+}
+
+func (s *postgresStore) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+func (s *postgresStore) UpsertAgent(a Agent) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO agents (id, tenant_id, name, ip, webhook_url, tier, description, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			tenant_id = EXCLUDED.tenant_id, name = EXCLUDED.name, ip = EXCLUDED.ip,
+			webhook_url = EXCLUDED.webhook_url, tier = EXCLUDED.tier,
+			description = EXCLUDED.description, status = EXCLUDED.status,
+			updated_at = EXCLUDED.updated_at
+	`, a.ID, a.TenantID, a.Name, a.IP, a.WebhookURL, a.Tier, a.Description, a.Status, a.CreatedAt, a.UpdatedAt)
+	return err
+}
+
+func (s *postgresStore) GetAgent(id string) (Agent, error) {
+	var a Agent
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT id, tenant_id, name, ip, webhook_url, tier, description, status, created_at, updated_at
+		FROM agents WHERE id = $1
+	`, id).Scan(&a.ID, &a.TenantID, &a.Name, &a.IP, &a.WebhookURL, &a.Tier, &a.Description, &a.Status, &a.CreatedAt, &a.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Agent{}, ErrNotFound
+	}
+	return a, err
+}
+
+func (s *postgresStore) ListAgents() ([]Agent, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT id, tenant_id, name, ip, webhook_url, tier, description, status, created_at, updated_at
+		FROM agents
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Agent
+	for rows.Next() {
+		var a Agent
+		if err := rows.Scan(&a.ID, &a.TenantID, &a.Name, &a.IP, &a.WebhookURL, &a.Tier, &a.Description, &a.Status, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) DeleteAgent(id string) error {
+	_, err := s.pool.Exec(context.Background(), `DELETE FROM agents WHERE id = $1`, id)
+	return err
+}
+
+func (s *postgresStore) UpsertRule(r Rule) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO rules (id, pattern, domain, match_type, priority, action, description, enabled, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			pattern = EXCLUDED.pattern, domain = EXCLUDED.domain, match_type = EXCLUDED.match_type,
+			priority = EXCLUDED.priority, action = EXCLUDED.action,
+			description = EXCLUDED.description, enabled = EXCLUDED.enabled
+	`, r.ID, r.Pattern, r.Domain, r.MatchType, r.Priority, r.Action, r.Description, r.Enabled, r.CreatedAt)
+	return err
+}
+
+func (s *postgresStore) ListRules() ([]Rule, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT id, pattern, domain, match_type, priority, action, description, enabled, created_at FROM rules
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Rule
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(&r.ID, &r.Pattern, &r.Domain, &r.MatchType, &r.Priority, &r.Action, &r.Description, &r.Enabled, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) DeleteRule(id string) error {
+	_, err := s.pool.Exec(context.Background(), `DELETE FROM rules WHERE id = $1`, id)
+	return err
+}
+
+func (s *postgresStore) AppendLog(e LogEntry) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO logs (id, timestamp, agent_id, type, request, action, rule_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, e.ID, e.Timestamp, e.AgentID, e.Type, e.Request, e.Action, e.RuleID)
+	return err
+}
+
+func (s *postgresStore) ListLogs(limit int) ([]LogEntry, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT id, timestamp, agent_id, type, request, action, rule_id
+		FROM logs ORDER BY seq DESC LIMIT nullif($1, 0)
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LogEntry
+	for rows.Next() {
+		var e LogEntry
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.AgentID, &e.Type, &e.Request, &e.Action, &e.RuleID); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) AppendBastionSession(ev BastionSession) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO bastion_sessions (session_id, grant_id, principal, target, event, timestamp, data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, ev.SessionID, ev.GrantID, ev.Principal, ev.Target, ev.Event, ev.Timestamp, ev.Data)
+	return err
+}
+
+func (s *postgresStore) ListBastionSessions(limit int) ([]BastionSession, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT session_id, grant_id, principal, target, event, timestamp, data
+		FROM bastion_sessions ORDER BY seq DESC LIMIT nullif($1, 0)
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BastionSession
+	for rows.Next() {
+		var ev BastionSession
+		if err := rows.Scan(&ev.SessionID, &ev.GrantID, &ev.Principal, &ev.Target, &ev.Event, &ev.Timestamp, &ev.Data); err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) UpsertBastionGrant(g BastionGrant) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO bastion_grants (id, principal, target, expires_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			principal = EXCLUDED.principal, target = EXCLUDED.target, expires_at = EXCLUDED.expires_at
+	`, g.ID, g.Principal, g.Target, g.ExpiresAt, g.CreatedBy, g.CreatedAt)
+	return err
+}
+
+func (s *postgresStore) ListBastionGrants() ([]BastionGrant, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT id, principal, target, expires_at, created_by, created_at FROM bastion_grants
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BastionGrant
+	for rows.Next() {
+		var g BastionGrant
+		if err := rows.Scan(&g.ID, &g.Principal, &g.Target, &g.ExpiresAt, &g.CreatedBy, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) DeleteBastionGrant(id string) error {
+	_, err := s.pool.Exec(context.Background(), `DELETE FROM bastion_grants WHERE id = $1`, id)
+	return err
+}
+
+func (s *postgresStore) Revoke(rev Revocation) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO revocations (serial, reason, revoked_at) VALUES ($1, $2, $3)
+		ON CONFLICT (serial) DO NOTHING
+	`, rev.Serial, rev.Reason, rev.RevokedAt)
+	return err
+}
+
+func (s *postgresStore) IsRevoked(serial string) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT EXISTS(SELECT 1 FROM revocations WHERE serial = $1)
+	`, serial).Scan(&exists)
+	return exists, err
+}
+
+func (s *postgresStore) ListRevocations() ([]Revocation, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT serial, reason, revoked_at FROM revocations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Revocation
+	for rows.Next() {
+		var rev Revocation
+		if err := rows.Scan(&rev.Serial, &rev.Reason, &rev.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rev)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) UpsertTenant(t Tenant) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO tenants (id, mode, created_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET mode = EXCLUDED.mode, updated_at = EXCLUDED.updated_at
+	`, t.ID, t.Mode, t.CreatedAt, t.UpdatedAt)
+	return err
+}
+
+func (s *postgresStore) GetTenant(id string) (Tenant, error) {
+	var t Tenant
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT id, mode, created_at, updated_at FROM tenants WHERE id = $1
+	`, id).Scan(&t.ID, &t.Mode, &t.CreatedAt, &t.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Tenant{}, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *postgresStore) ListTenants() ([]Tenant, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT id, mode, created_at, updated_at FROM tenants`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Tenant
+	for rows.Next() {
+		var t Tenant
+		if err := rows.Scan(&t.ID, &t.Mode, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) DeleteTenant(id string) error {
+	_, err := s.pool.Exec(context.Background(), `DELETE FROM tenants WHERE id = $1`, id)
+	return err
+}
+
+func (s *postgresStore) UpsertToken(t Token) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO tokens (token, tenant_id, name, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (token) DO UPDATE SET tenant_id = EXCLUDED.tenant_id, name = EXCLUDED.name
+	`, t.Token, t.TenantID, t.Name, t.CreatedAt)
+	return err
+}
+
+func (s *postgresStore) GetToken(token string) (Token, error) {
+	var t Token
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT token, tenant_id, name, created_at FROM tokens WHERE token = $1
+	`, token).Scan(&t.Token, &t.TenantID, &t.Name, &t.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Token{}, ErrNotFound
+	}
+	return t, err
+}
+
+func (s *postgresStore) ListTokens() ([]Token, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT token, tenant_id, name, created_at FROM tokens`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Token
+	for rows.Next() {
+		var t Token
+		if err := rows.Scan(&t.Token, &t.TenantID, &t.Name, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (s *postgresStore) DeleteToken(token string) error {
+	_, err := s.pool.Exec(context.Background(), `DELETE FROM tokens WHERE token = $1`, token)
+	return err
+}
+
+func (s *postgresStore) AppendAudit(e AuditEntry) error {
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO audit_log (id, timestamp, actor, action, resource_type, resource_id, before, after, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, e.ID, e.Timestamp, e.Actor, e.Action, e.ResourceType, e.ResourceID, e.Before, e.After, e.PrevHash, e.Hash)
+	return err
+}
+
+func (s *postgresStore) ListAudit(limit int) ([]AuditEntry, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT id, timestamp, actor, action, resource_type, resource_id, before, after, prev_hash, hash
+		FROM audit_log ORDER BY seq DESC LIMIT nullif($1, 0)
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.Actor, &e.Action, &e.ResourceType, &e.ResourceID, &e.Before, &e.After, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
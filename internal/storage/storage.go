@@ -0,0 +1,195 @@
+// Package storage provides a pluggable persistence layer for the shield's
+// agents, rules, traffic logs, bastion sessions, bastion grants, and
+// certificate revocations. Everything upstream of this package (internal/api,
+// internal/fleet, internal/bastion, internal/agentca) previously kept this
+// state in memory plus a YAML file; Store lets it survive a restart without
+// those packages knowing which database backs it.
+//
+// Concrete drivers live in driver-specific files gated by build tags
+// (nobbolt, nobadger, nopostgres) so operators can exclude the drivers they
+// don't need from a binary. Each driver registers itself in an init(); Open
+// dispatches on the DSN's scheme to whichever drivers were compiled in.
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by single-record lookups when no record matches.
+var ErrNotFound = errors.New("storage: not found")
+
+// Agent is the persisted form of a fleet agent.
+type Agent struct {
+	ID          string
+	TenantID    string
+	Name        string
+	IP          string
+	WebhookURL  string
+	Tier        string
+	Description string
+	Status      string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Rule is the persisted form of a filtering rule.
+type Rule struct {
+	ID          string
+	Pattern     string
+	Domain      string
+	MatchType   string
+	Priority    int
+	Action      string
+	Description string
+	Enabled     bool
+	CreatedAt   time.Time
+}
+
+// LogEntry is the persisted form of a single traffic log entry.
+type LogEntry struct {
+	ID        string
+	Timestamp time.Time
+	AgentID   string
+	Type      string
+	Request   string
+	Action    string
+	RuleID    string
+}
+
+// BastionSession is the persisted form of a single bastion session event.
+type BastionSession struct {
+	SessionID string
+	GrantID   string
+	Principal string
+	Target    string
+	Event     string
+	Timestamp time.Time
+	Data      string
+}
+
+// BastionGrant is the persisted form of a bastion access grant.
+type BastionGrant struct {
+	ID        string
+	Principal string
+	Target    string
+	ExpiresAt time.Time
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+// Revocation is a single revoked certificate serial, shared by the bastion
+// CA's KRL and the agent CA's CRL.
+type Revocation struct {
+	Serial    string
+	Reason    string
+	RevokedAt time.Time
+}
+
+// Tenant is the persisted form of a tenant, managed via the admin CRUD API
+// independently of fleet.LoadAndApply's YAML-driven reconcile.
+type Tenant struct {
+	ID        string
+	Mode      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Token is the persisted form of an API/proxy auth token, managed via the
+// admin CRUD API.
+type Token struct {
+	Token     string
+	TenantID  string
+	Name      string
+	CreatedAt time.Time
+}
+
+// AuditEntry is the persisted form of a single admin audit log entry.
+// Before/After hold canonical JSON of the resource's state, and PrevHash/Hash
+// form a hash chain so tampering with or removing an entry breaks the chain
+// for every entry after it.
+type AuditEntry struct {
+	ID           string
+	Timestamp    time.Time
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Before       string
+	After        string
+	PrevHash     string
+	Hash         string
+}
+
+// Store is a pluggable persistence backend. Implementations must be safe
+// for concurrent use. Record not-found conditions are reported as
+// ErrNotFound rather than a zero value plus nil error, so callers can
+// distinguish "absent" from "failed to check".
+type Store interface {
+	// UpsertAgent creates or updates an agent record.
+	UpsertAgent(Agent) error
+	// GetAgent returns a single agent, or ErrNotFound.
+	GetAgent(id string) (Agent, error)
+	// ListAgents returns every agent, in no particular order.
+	ListAgents() ([]Agent, error)
+	// DeleteAgent removes an agent. It is not an error if id doesn't exist.
+	DeleteAgent(id string) error
+
+	// UpsertRule creates or updates a rule record.
+	UpsertRule(Rule) error
+	// ListRules returns every rule, in no particular order.
+	ListRules() ([]Rule, error)
+	// DeleteRule removes a rule. It is not an error if id doesn't exist.
+	DeleteRule(id string) error
+
+	// AppendLog appends a single traffic log entry.
+	AppendLog(LogEntry) error
+	// ListLogs returns up to limit log entries, most recent first.
+	ListLogs(limit int) ([]LogEntry, error)
+
+	// AppendBastionSession appends a single bastion session event.
+	AppendBastionSession(BastionSession) error
+	// ListBastionSessions returns up to limit session events, most recent first.
+	ListBastionSessions(limit int) ([]BastionSession, error)
+
+	// UpsertBastionGrant creates or updates a bastion grant.
+	UpsertBastionGrant(BastionGrant) error
+	// ListBastionGrants returns every bastion grant.
+	ListBastionGrants() ([]BastionGrant, error)
+	// DeleteBastionGrant removes a bastion grant. Not an error if id doesn't exist.
+	DeleteBastionGrant(id string) error
+
+	// Revoke records a revoked certificate serial.
+	Revoke(Revocation) error
+	// IsRevoked reports whether serial has been revoked.
+	IsRevoked(serial string) (bool, error)
+	// ListRevocations returns every revoked serial.
+	ListRevocations() ([]Revocation, error)
+
+	// UpsertTenant creates or updates a tenant record.
+	UpsertTenant(Tenant) error
+	// GetTenant returns a single tenant, or ErrNotFound.
+	GetTenant(id string) (Tenant, error)
+	// ListTenants returns every tenant, in no particular order.
+	ListTenants() ([]Tenant, error)
+	// DeleteTenant removes a tenant. It is not an error if id doesn't exist.
+	DeleteTenant(id string) error
+
+	// UpsertToken creates or updates a token record.
+	UpsertToken(Token) error
+	// GetToken returns a single token, or ErrNotFound.
+	GetToken(token string) (Token, error)
+	// ListTokens returns every token, in no particular order.
+	ListTokens() ([]Token, error)
+	// DeleteToken removes a token. It is not an error if token doesn't exist.
+	DeleteToken(token string) error
+
+	// AppendAudit appends a single audit log entry.
+	AppendAudit(AuditEntry) error
+	// ListAudit returns up to limit audit entries, most recent first.
+	// limit <= 0 means no limit.
+	ListAudit(limit int) ([]AuditEntry, error)
+
+	// Close releases any resources (file handles, connections) held by the store.
+	Close() error
+}
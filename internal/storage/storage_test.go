@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "shield.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestOpenDefaultsToBolt(t *testing.T) {
+	store := openTestStore(t)
+	if _, ok := store.(*boltStore); !ok {
+		t.Fatalf("expected a bare path to open a bbolt store, got %T", store)
+	}
+}
+
+func TestOpenUnknownScheme(t *testing.T) {
+	if _, err := Open("mongo://localhost/shield"); err == nil {
+		t.Fatal("expected an error for an unregistered scheme")
+	}
+}
+
+func TestStoreAgentRoundTrip(t *testing.T) {
+	store := openTestStore(t)
+
+	agent := Agent{ID: "agent-1", TenantID: "tenant-1", Name: "sarai", Status: "active", CreatedAt: time.Now().UTC()}
+	if err := store.UpsertAgent(agent); err != nil {
+		t.Fatalf("upsert agent: %v", err)
+	}
+
+	got, err := store.GetAgent("agent-1")
+	if err != nil {
+		t.Fatalf("get agent: %v", err)
+	}
+	if got.Name != "sarai" {
+		t.Errorf("expected name 'sarai', got %q", got.Name)
+	}
+
+	agent.Status = "paused"
+	if err := store.UpsertAgent(agent); err != nil {
+		t.Fatalf("update agent: %v", err)
+	}
+	if got, err = store.GetAgent("agent-1"); err != nil || got.Status != "paused" {
+		t.Fatalf("expected updated status 'paused', got %+v, err %v", got, err)
+	}
+
+	list, err := store.ListAgents()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected 1 listed agent, got %d, err %v", len(list), err)
+	}
+
+	if err := store.DeleteAgent("agent-1"); err != nil {
+		t.Fatalf("delete agent: %v", err)
+	}
+	if _, err := store.GetAgent("agent-1"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestStoreRules(t *testing.T) {
+	store := openTestStore(t)
+
+	if err := store.UpsertRule(Rule{ID: "rule-1", Domain: "evil.example", Action: "block", Enabled: true}); err != nil {
+		t.Fatalf("upsert rule: %v", err)
+	}
+	rules, err := store.ListRules()
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d, err %v", len(rules), err)
+	}
+	if err := store.DeleteRule("rule-1"); err != nil {
+		t.Fatalf("delete rule: %v", err)
+	}
+	if rules, err = store.ListRules(); err != nil || len(rules) != 0 {
+		t.Fatalf("expected 0 rules after delete, got %d, err %v", len(rules), err)
+	}
+}
+
+func TestStoreLogsOrderAndLimit(t *testing.T) {
+	store := openTestStore(t)
+
+	for i := 0; i < 5; i++ {
+		if err := store.AppendLog(LogEntry{ID: string(rune('a' + i)), Timestamp: time.Now().UTC()}); err != nil {
+			t.Fatalf("append log: %v", err)
+		}
+	}
+
+	logs, err := store.ListLogs(3)
+	if err != nil {
+		t.Fatalf("list logs: %v", err)
+	}
+	if len(logs) != 3 {
+		t.Fatalf("expected 3 logs, got %d", len(logs))
+	}
+	// Most recent first.
+	if logs[0].ID != "e" || logs[1].ID != "d" || logs[2].ID != "c" {
+		t.Errorf("expected logs in reverse insertion order, got %+v", logs)
+	}
+}
+
+func TestStoreBastionGrantsAndRevocations(t *testing.T) {
+	store := openTestStore(t)
+
+	grant := BastionGrant{ID: "grant-1", Principal: "alice", Target: "agent-1", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.UpsertBastionGrant(grant); err != nil {
+		t.Fatalf("upsert grant: %v", err)
+	}
+	grants, err := store.ListBastionGrants()
+	if err != nil || len(grants) != 1 {
+		t.Fatalf("expected 1 grant, got %d, err %v", len(grants), err)
+	}
+	if err := store.DeleteBastionGrant("grant-1"); err != nil {
+		t.Fatalf("delete grant: %v", err)
+	}
+
+	revoked, err := store.IsRevoked("123")
+	if err != nil || revoked {
+		t.Fatalf("expected serial 123 to not be revoked yet, got revoked=%v err=%v", revoked, err)
+	}
+	if err := store.Revoke(Revocation{Serial: "123", Reason: "compromised", RevokedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if revoked, err = store.IsRevoked("123"); err != nil || !revoked {
+		t.Fatalf("expected serial 123 to be revoked, got revoked=%v err=%v", revoked, err)
+	}
+}
+
+func TestStoreTenantsAndTokens(t *testing.T) {
+	store := openTestStore(t)
+
+	tenant := Tenant{ID: "tenant-1", Mode: "fleet", CreatedAt: time.Now().UTC()}
+	if err := store.UpsertTenant(tenant); err != nil {
+		t.Fatalf("upsert tenant: %v", err)
+	}
+	if got, err := store.GetTenant("tenant-1"); err != nil || got.Mode != "fleet" {
+		t.Fatalf("expected mode 'fleet', got %+v, err %v", got, err)
+	}
+	if err := store.DeleteTenant("tenant-1"); err != nil {
+		t.Fatalf("delete tenant: %v", err)
+	}
+	if _, err := store.GetTenant("tenant-1"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	token := Token{Token: "secret-token", TenantID: "tenant-1", Name: "ci", CreatedAt: time.Now().UTC()}
+	if err := store.UpsertToken(token); err != nil {
+		t.Fatalf("upsert token: %v", err)
+	}
+	tokens, err := store.ListTokens()
+	if err != nil || len(tokens) != 1 {
+		t.Fatalf("expected 1 token, got %d, err %v", len(tokens), err)
+	}
+	if err := store.DeleteToken("secret-token"); err != nil {
+		t.Fatalf("delete token: %v", err)
+	}
+	if _, err := store.GetToken("secret-token"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestStoreAuditAppendAndOrder(t *testing.T) {
+	store := openTestStore(t)
+
+	for i := 0; i < 3; i++ {
+		if err := store.AppendAudit(AuditEntry{ID: string(rune('a' + i)), Timestamp: time.Now().UTC()}); err != nil {
+			t.Fatalf("append audit: %v", err)
+		}
+	}
+
+	entries, err := store.ListAudit(0)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d, err %v", len(entries), err)
+	}
+	if entries[0].ID != "c" || entries[2].ID != "a" {
+		t.Errorf("expected audit entries in reverse insertion order, got %+v", entries)
+	}
+}
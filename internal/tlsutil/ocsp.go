@@ -0,0 +1,112 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspStapler caches a stapled OCSP response per leaf certificate, keyed by
+// its serial number, and refetches it once the cached response's NextUpdate
+// has passed. Like bcryptFileAuth's lazy mtime check (see
+// internal/api/auth.go), this reloads on access rather than running a
+// background refresh goroutine: a TLS handshake already calls
+// GetCertificate for every new connection, so there's no separate schedule
+// to maintain.
+type ocspStapler struct {
+	mu    sync.Mutex
+	cache map[string]*stapledResponse
+}
+
+type stapledResponse struct {
+	raw        []byte
+	nextUpdate time.Time
+}
+
+func newOCSPStapler() *ocspStapler {
+	return &ocspStapler{cache: make(map[string]*stapledResponse)}
+}
+
+// staple sets cert.OCSPStaple, fetching a fresh response from the issuing
+// CA's OCSP responder if the cached copy is missing, stale, or cert has no
+// responder. A fetch failure is logged and left unstapled rather than
+// failing the handshake -- stapling is a latency/privacy optimization, not
+// a trust requirement, since the client can still query the responder
+// itself.
+func (s *ocspStapler) staple(cert *tls.Certificate) {
+	if cert == nil || len(cert.Certificate) < 2 {
+		return
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return
+		}
+		leaf = parsed
+	}
+	if len(leaf.OCSPServer) == 0 {
+		return
+	}
+	key := leaf.SerialNumber.String()
+
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.nextUpdate) {
+		cert.OCSPStaple = cached.raw
+		return
+	}
+
+	issuer, err := x509.ParseCertificate(cert.Certificate[1])
+	if err != nil {
+		log.Printf("tlsutil: parse issuer certificate: %v", err)
+		return
+	}
+	raw, nextUpdate, err := fetchOCSP(leaf, issuer)
+	if err != nil {
+		log.Printf("tlsutil: fetch OCSP response: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.cache[key] = &stapledResponse{raw: raw, nextUpdate: nextUpdate}
+	s.mu.Unlock()
+	cert.OCSPStaple = raw
+}
+
+// fetchOCSP requests leaf's revocation status from issuer's OCSP responder.
+func fetchOCSP(leaf, issuer *x509.Certificate) (raw []byte, nextUpdate time.Time, err error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("create OCSP request: %w", err)
+	}
+
+	resp, err := http.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("POST to %s: %w", leaf.OCSPServer[0], err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("read OCSP response: %w", err)
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parse OCSP response: %w", err)
+	}
+	if parsed.Status != ocsp.Good {
+		return nil, time.Time{}, fmt.Errorf("responder reports status %d for serial %s", parsed.Status, leaf.SerialNumber)
+	}
+	return body, parsed.NextUpdate, nil
+}
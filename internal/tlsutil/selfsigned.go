@@ -0,0 +1,56 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// NewSelfSignedTLSConfig generates an ephemeral, in-memory self-signed
+// certificate for domains (defaulting to "localhost" if empty) and returns
+// a *tls.Config serving it, with the same secure MinVersion/CipherSuites as
+// NewAutocertTLSConfig. The certificate is regenerated on every process
+// restart -- there's nothing to persist or renew, unlike the autocert path
+// -- so this is meant for local development or an internal network that
+// already carries its own transport encryption, not a public deployment.
+func NewSelfSignedTLSConfig(domains []string) (*tls.Config, error) {
+	if len(domains) == 0 {
+		domains = []string{"localhost"}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("tlsutil: generate self-signed key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("tlsutil: generate serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: domains[0]},
+		DNSNames:     domains,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("tlsutil: create self-signed certificate: %w", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: secureCipherSuites,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+)
+
+func TestNewSelfSignedTLSConfig_DefaultsToLocalhost(t *testing.T) {
+	tlsConfig, err := NewSelfSignedTLSConfig(nil)
+	if err != nil {
+		t.Fatalf("NewSelfSignedTLSConfig: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse generated certificate: %v", err)
+	}
+	if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "localhost" {
+		t.Errorf("DNSNames = %v, want [localhost]", leaf.DNSNames)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %v, want %v", tlsConfig.MinVersion, tls.VersionTLS12)
+	}
+}
+
+func TestNewSelfSignedTLSConfig_UsesGivenDomains(t *testing.T) {
+	tlsConfig, err := NewSelfSignedTLSConfig([]string{"a.example", "b.example"})
+	if err != nil {
+		t.Fatalf("NewSelfSignedTLSConfig: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(tlsConfig.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("parse generated certificate: %v", err)
+	}
+	if len(leaf.DNSNames) != 2 || leaf.DNSNames[0] != "a.example" || leaf.DNSNames[1] != "b.example" {
+		t.Errorf("DNSNames = %v, want [a.example b.example]", leaf.DNSNames)
+	}
+}
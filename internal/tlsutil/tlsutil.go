@@ -0,0 +1,99 @@
+// Package tlsutil builds *tls.Config values for the gateway's inbound
+// listener and the management API, using ACME (e.g. Let's Encrypt, or an
+// internal CA like step-ca) to provision and renew certificates instead of
+// requiring an operator to hand the process a --tls-cert/--tls-key pair
+// maintained by an external cert-manager sidecar.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// secureCipherSuites mirrors the inbound listener's existing TLS config
+// (see cmd/gateway/main.go and cmd/gateway/gateway_test.go's TestTLSConfig):
+// TLS 1.2 minimum, ECDHE key exchange with AES-GCM only.
+var secureCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+}
+
+// AutocertConfig configures automatic certificate provisioning via ACME.
+type AutocertConfig struct {
+	// Domains are the hostnames autocert will request certificates for;
+	// any other SNI name is rejected before it reaches the CA. Required.
+	Domains []string
+	// CacheDir persists issued certificates and account keys across
+	// restarts, so the process doesn't re-request a certificate (and risk
+	// the CA's issuance rate limit) on every boot. Required.
+	CacheDir string
+	// Email is given to the ACME account registration, for the CA to
+	// contact about certificate problems. Optional.
+	Email string
+	// DirectoryURL is the ACME directory endpoint. Defaults to Let's
+	// Encrypt's production directory; set it to Let's Encrypt's staging
+	// directory (or an internal CA such as step-ca) for testing.
+	DirectoryURL string
+	// RenewBefore is how long before expiry autocert renews a
+	// certificate. Defaults to autocert's own default (30 days) if zero.
+	RenewBefore time.Duration
+}
+
+// NewAutocertTLSConfig builds an autocert.Manager from cfg and returns a
+// *tls.Config wired to it, with the inbound listener's existing secure
+// MinVersion/CipherSuites applied on top and OCSP responses stapled to each
+// certificate autocert hands out. The returned manager's HTTPHandler must be
+// mounted on :80 for ACME's HTTP-01 challenge to complete, since autocert
+// cannot be told to use DNS-01 or TLS-ALPN-01 alone without a custom
+// acme.Client challenge solver.
+func NewAutocertTLSConfig(cfg AutocertConfig) (*tls.Config, *autocert.Manager, error) {
+	if len(cfg.Domains) == 0 {
+		return nil, nil, fmt.Errorf("tlsutil: autocert requires at least one domain")
+	}
+	if cfg.CacheDir == "" {
+		return nil, nil, fmt.Errorf("tlsutil: autocert requires a cache directory")
+	}
+
+	m := &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		HostPolicy:  autocert.HostWhitelist(cfg.Domains...),
+		Cache:       autocert.DirCache(cfg.CacheDir),
+		Email:       cfg.Email,
+		RenewBefore: cfg.RenewBefore,
+	}
+	if cfg.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: cfg.DirectoryURL}
+	}
+
+	tlsConfig := m.TLSConfig()
+	tlsConfig.MinVersion = tls.VersionTLS12
+	tlsConfig.CipherSuites = secureCipherSuites
+
+	stapler := newOCSPStapler()
+	getCertificate := tlsConfig.GetCertificate
+	tlsConfig.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := getCertificate(hello)
+		if err != nil {
+			return nil, err
+		}
+		stapler.staple(cert)
+		return cert, nil
+	}
+
+	return tlsConfig, m, nil
+}
+
+// HTTPChallengeHandler returns m's ACME HTTP-01 challenge handler, falling
+// back to fallback for any request that isn't part of the challenge, for an
+// operator to mount on :80 alongside their normal redirect-to-HTTPS handler
+// when only HTTPS is bound. fallback may be nil.
+func HTTPChallengeHandler(m *autocert.Manager, fallback http.Handler) http.Handler {
+	return m.HTTPHandler(fallback)
+}
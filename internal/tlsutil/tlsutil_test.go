@@ -0,0 +1,42 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestNewAutocertTLSConfig_RequiresDomains(t *testing.T) {
+	if _, _, err := NewAutocertTLSConfig(AutocertConfig{CacheDir: t.TempDir()}); err == nil {
+		t.Fatal("expected an error with no domains configured")
+	}
+}
+
+func TestNewAutocertTLSConfig_RequiresCacheDir(t *testing.T) {
+	if _, _, err := NewAutocertTLSConfig(AutocertConfig{Domains: []string{"example.com"}}); err == nil {
+		t.Fatal("expected an error with no cache directory configured")
+	}
+}
+
+func TestNewAutocertTLSConfig_SecureDefaults(t *testing.T) {
+	tlsConfig, m, err := NewAutocertTLSConfig(AutocertConfig{
+		Domains:  []string{"example.com"},
+		CacheDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewAutocertTLSConfig: %v", err)
+	}
+	if m == nil {
+		t.Fatal("expected a non-nil autocert.Manager")
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("MinVersion = %v, want %v", tlsConfig.MinVersion, tls.VersionTLS12)
+	}
+	if len(tlsConfig.CipherSuites) != len(secureCipherSuites) {
+		t.Fatalf("CipherSuites = %v, want %v", tlsConfig.CipherSuites, secureCipherSuites)
+	}
+	for i, suite := range secureCipherSuites {
+		if tlsConfig.CipherSuites[i] != suite {
+			t.Errorf("CipherSuites[%d] = %v, want %v", i, tlsConfig.CipherSuites[i], suite)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build tools
+
+// Package tools pins build-time-only tool dependencies in go.mod/go.sum
+// so `go mod tidy` doesn't drop them, without the tools being imported by
+// (or shipped in) any binary. See pkg/apiclient/generate.go.
+package tools
+
+import (
+	_ "github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen"
+)
@@ -0,0 +1,277 @@
+// Package tunnel implements a minimal, hand-rolled WebSocket transport
+// (RFC 6455) used to multiplex HTTP requests to agents that have no
+// reachable WebhookURL/IP, e.g. agents behind NAT. It deliberately doesn't
+// pull in a WebSocket dependency: the rest of the shield hand-rolls
+// similarly-scoped protocol pieces (internal/mitmca's leaf cache,
+// internal/metrics's Prometheus exposition) rather than adding one, and a
+// tunnel only needs framed binary messages plus ping/pong, a small slice
+// of what RFC 6455 specifies.
+package tunnel
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// websocketGUID is the magic string RFC 6455 section 1.3 appends to
+// Sec-WebSocket-Key before hashing to produce Sec-WebSocket-Accept.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcode identifies a WebSocket frame's payload type.
+type Opcode byte
+
+const (
+	OpContinuation Opcode = 0x0
+	OpText         Opcode = 0x1
+	OpBinary       Opcode = 0x2
+	OpClose        Opcode = 0x8
+	OpPing         Opcode = 0x9
+	OpPong         Opcode = 0xA
+)
+
+// Conn is a hijacked connection speaking the WebSocket framing protocol.
+// It does not implement fragmentation (every frame it writes is final,
+// and ReadFrame refuses continuation frames) since every message the
+// tunnel protocol sends fits in a single frame.
+type Conn struct {
+	nc       net.Conn
+	br       *bufio.Reader
+	isClient bool // true when this side must mask outgoing frames (DialAgent)
+}
+
+// Upgrade performs the server side of the WebSocket handshake against r,
+// hijacking w's underlying connection, and returns a Conn ready for
+// ReadFrame/WriteFrame. The caller is responsible for closing the
+// returned Conn.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return nil, errors.New("tunnel: not a websocket upgrade request")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("tunnel: missing Sec-WebSocket-Key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("tunnel: hijacking not supported")
+	}
+	nc, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	accept := acceptKey(key)
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n"
+	if _, err := brw.WriteString(resp); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	return &Conn{nc: nc, br: brw.Reader}, nil
+}
+
+// DialAgent performs the client side of the WebSocket handshake against
+// addr (a bare "host:port", TLS not handled here - callers needing TLS
+// should dial and wrap the net.Conn themselves before constructing a
+// Conn, which this package doesn't currently expose a way to do since
+// every caller so far connects in-process). path and header let the
+// caller register as a specific agent (e.g. path "/agent/connect" with
+// an Authorization header and an "agent_id" query parameter).
+func DialAgent(addr, path string, header http.Header) (*Conn, error) {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	var req strings.Builder
+	fmt.Fprintf(&req, "GET %s HTTP/1.1\r\n", path)
+	fmt.Fprintf(&req, "Host: %s\r\n", addr)
+	req.WriteString("Upgrade: websocket\r\n")
+	req.WriteString("Connection: Upgrade\r\n")
+	fmt.Fprintf(&req, "Sec-WebSocket-Key: %s\r\n", key)
+	req.WriteString("Sec-WebSocket-Version: 13\r\n")
+	for name, values := range header {
+		for _, v := range values {
+			fmt.Fprintf(&req, "%s: %s\r\n", name, v)
+		}
+	}
+	req.WriteString("\r\n")
+
+	if _, err := io.WriteString(nc, req.String()); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(nc)
+	tp := textproto.NewReader(br)
+	statusLine, err := tp.ReadLine()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if !strings.Contains(statusLine, "101") {
+		nc.Close()
+		return nil, fmt.Errorf("tunnel: handshake failed: %s", statusLine)
+	}
+	respHeader, err := tp.ReadMIMEHeader()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	if respHeader.Get("Sec-Websocket-Accept") != acceptKey(key) {
+		nc.Close()
+		return nil, errors.New("tunnel: invalid Sec-WebSocket-Accept")
+	}
+
+	return &Conn{nc: nc, br: br, isClient: true}, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Close closes the underlying connection.
+func (c *Conn) Close() error {
+	return c.nc.Close()
+}
+
+// WriteFrame writes a single, unfragmented WebSocket frame.
+func (c *Conn) WriteFrame(op Opcode, payload []byte) error {
+	var header []byte
+	first := byte(0x80) | byte(op) // FIN=1
+
+	maskBit := byte(0)
+	if c.isClient {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		header = []byte{first, maskBit | byte(len(payload))}
+	case len(payload) <= 0xFFFF:
+		header = []byte{first, maskBit | 126, 0, 0}
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)))
+	default:
+		header = make([]byte, 10)
+		header[0] = first
+		header[1] = maskBit | 127
+		binary.BigEndian.PutUint64(header[2:], uint64(len(payload)))
+	}
+
+	if _, err := c.nc.Write(header); err != nil {
+		return err
+	}
+	if !c.isClient {
+		_, err := c.nc.Write(payload)
+		return err
+	}
+
+	var maskKey [4]byte
+	if _, err := rand.Read(maskKey[:]); err != nil {
+		return err
+	}
+	if _, err := c.nc.Write(maskKey[:]); err != nil {
+		return err
+	}
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ maskKey[i%4]
+	}
+	_, err := c.nc.Write(masked)
+	return err
+}
+
+// ReadFrame reads a single WebSocket frame. It handles ping frames
+// transparently by replying with a pong and reading the next frame, so
+// callers only ever see data-bearing or close frames.
+func (c *Conn) ReadFrame() (Opcode, []byte, error) {
+	for {
+		op, payload, err := c.readRawFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		if op == OpPing {
+			if err := c.WriteFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+		if op == OpPong {
+			continue
+		}
+		return op, payload, nil
+	}
+}
+
+func (c *Conn) readRawFrame() (Opcode, []byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(c.br, head); err != nil {
+		return 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	op := Opcode(head[0] & 0x0F)
+	if !fin {
+		return 0, nil, errors.New("tunnel: fragmented frames are not supported")
+	}
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(c.br, ext); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(c.br, ext); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, maskKey[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+	return op, payload, nil
+}
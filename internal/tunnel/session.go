@@ -0,0 +1,383 @@
+package tunnel
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// frameKind identifies the payload carried by a tunnel message. Every
+// WebSocket binary frame sent by Session is a tunnel frame: one byte of
+// frameKind, eight bytes of big-endian stream ID, then a kind-specific
+// body.
+type frameKind byte
+
+const (
+	frameRequestHeader  frameKind = 1
+	frameRequestBody    frameKind = 2
+	frameRequestEnd     frameKind = 3
+	frameResponseHeader frameKind = 4
+	frameResponseBody   frameKind = 5
+	frameResponseEnd    frameKind = 6
+	frameError          frameKind = 7
+)
+
+// requestHeader is the JSON body of a frameRequestHeader message.
+type requestHeader struct {
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Header map[string][]string `json:"header"`
+}
+
+// responseHeader is the JSON body of a frameResponseHeader message.
+type responseHeader struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header"`
+}
+
+// heartbeatInterval is how often Session pings an idle connection, and
+// heartbeatTimeout is how long it waits for a reply before declaring the
+// agent gone. Mirrors limiter.Breaker's own "a handful of seconds, not
+// configurable yet" stance on timing constants.
+const (
+	heartbeatInterval = 15 * time.Second
+	heartbeatTimeout  = 45 * time.Second
+)
+
+// stream tracks one in-flight request/response exchange multiplexed over
+// the session's single connection.
+type stream struct {
+	respHeader chan responseHeader
+	body       *io.PipeWriter
+	bodyReader *io.PipeReader
+	headerOnce sync.Once
+}
+
+// serveHandler is the function Serve dispatches incoming requests to.
+type serveHandler func(method, path string, header http.Header, body io.Reader) (status int, respHeader http.Header, respBody io.Reader)
+
+// pendingRequest tracks one in-flight request frame sequence on the
+// Serve side while its body is still streaming in.
+type pendingRequest struct {
+	header requestHeader
+	pw     *io.PipeWriter
+	pr     *io.PipeReader
+}
+
+// Session is one agent's persistent tunnel connection. A single
+// background readLoop goroutine demultiplexes frames to the pending
+// stream they belong to; writes are serialized with writeMu since
+// multiple requests can be in flight concurrently over the one Conn.
+type Session struct {
+	conn *Conn
+
+	writeMu sync.Mutex
+
+	streamsMu sync.Mutex
+	streams   map[uint64]*stream
+	nextID    uint64
+
+	// handlerMu guards handler and pending, which together let readLoop
+	// dispatch incoming request frames to the Serve side's handler. Only
+	// populated once Serve is called; RoundTrip-only sessions never touch
+	// these.
+	handlerMu sync.Mutex
+	handler   serveHandler
+	pending   map[uint64]*pendingRequest
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	closeErr  error
+
+	lastPong atomic.Int64 // unix nanos, updated by readLoop on any frame
+}
+
+// NewSession wraps an upgraded Conn and starts its read loop and
+// heartbeat. The caller should call Wait (or just block on Close) to
+// learn when the agent disconnects.
+func NewSession(conn *Conn) *Session {
+	s := &Session{
+		conn:    conn,
+		streams: make(map[uint64]*stream),
+		closed:  make(chan struct{}),
+	}
+	s.lastPong.Store(time.Now().UnixNano())
+	go s.readLoop()
+	go s.heartbeatLoop()
+	return s
+}
+
+// Wait blocks until the session's connection is closed (by either side,
+// or by a missed heartbeat) and returns the reason, if any.
+func (s *Session) Wait() error {
+	<-s.closed
+	return s.closeErr
+}
+
+// Close tears down the session's connection. Safe to call more than
+// once and from any goroutine.
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		s.conn.Close()
+		close(s.closed)
+	})
+	return nil
+}
+
+func (s *Session) fail(err error) {
+	s.closeOnce.Do(func() {
+		s.closeErr = err
+		s.conn.Close()
+		close(s.closed)
+	})
+}
+
+func (s *Session) heartbeatLoop() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, s.lastPong.Load())) > heartbeatTimeout {
+				s.fail(errors.New("tunnel: heartbeat timeout"))
+				return
+			}
+			s.writeMu.Lock()
+			err := s.conn.WriteFrame(OpPing, nil)
+			s.writeMu.Unlock()
+			if err != nil {
+				s.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// readLoop demultiplexes incoming frames to their stream until the
+// connection errors or closes.
+func (s *Session) readLoop() {
+	for {
+		op, payload, err := s.conn.ReadFrame()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		s.lastPong.Store(time.Now().UnixNano())
+		if op == OpClose {
+			s.fail(io.EOF)
+			return
+		}
+		if op != OpBinary || len(payload) < 9 {
+			continue
+		}
+		kind := frameKind(payload[0])
+		id := binary.BigEndian.Uint64(payload[1:9])
+		body := payload[9:]
+
+		if kind == frameRequestHeader || kind == frameRequestBody || kind == frameRequestEnd {
+			s.dispatchRequestFrame(kind, id, body)
+			continue
+		}
+
+		s.streamsMu.Lock()
+		st := s.streams[id]
+		s.streamsMu.Unlock()
+		if st == nil {
+			continue
+		}
+
+		switch kind {
+		case frameResponseHeader:
+			var h responseHeader
+			if err := json.Unmarshal(body, &h); err != nil {
+				st.headerOnce.Do(func() { close(st.respHeader) })
+				continue
+			}
+			st.headerOnce.Do(func() { st.respHeader <- h })
+		case frameResponseBody:
+			st.body.Write(body)
+		case frameResponseEnd:
+			st.body.Close()
+			s.streamsMu.Lock()
+			delete(s.streams, id)
+			s.streamsMu.Unlock()
+		case frameError:
+			st.body.CloseWithError(errors.New(string(body)))
+			st.headerOnce.Do(func() { close(st.respHeader) })
+			s.streamsMu.Lock()
+			delete(s.streams, id)
+			s.streamsMu.Unlock()
+		}
+	}
+}
+
+// dispatchRequestFrame handles a frameRequestHeader/Body/End frame read by
+// readLoop, routing it to the handler Serve registered. Frames for a
+// session with no registered handler (a RoundTrip-only session that never
+// called Serve) are dropped - there's nothing to dispatch them to.
+func (s *Session) dispatchRequestFrame(kind frameKind, id uint64, body []byte) {
+	s.handlerMu.Lock()
+	handler := s.handler
+	if handler == nil {
+		s.handlerMu.Unlock()
+		return
+	}
+
+	switch kind {
+	case frameRequestHeader:
+		var h requestHeader
+		if err := json.Unmarshal(body, &h); err != nil {
+			s.handlerMu.Unlock()
+			return
+		}
+		pr, pw := io.Pipe()
+		s.pending[id] = &pendingRequest{header: h, pw: pw, pr: pr}
+		s.handlerMu.Unlock()
+
+		go func(id uint64, h requestHeader, pr *io.PipeReader) {
+			status, respH, respBody := handler(h.Method, h.Path, http.Header(h.Header), pr)
+			hdr, _ := json.Marshal(responseHeader{Status: status, Header: respH})
+			if err := s.writeFrame(frameResponseHeader, id, hdr); err != nil {
+				return
+			}
+			if respBody != nil {
+				buf := make([]byte, 32*1024)
+				for {
+					n, rerr := respBody.Read(buf)
+					if n > 0 {
+						if err := s.writeFrame(frameResponseBody, id, buf[:n]); err != nil {
+							return
+						}
+					}
+					if rerr != nil {
+						break
+					}
+				}
+			}
+			s.writeFrame(frameResponseEnd, id, nil)
+		}(id, h, pr)
+
+	case frameRequestBody:
+		p := s.pending[id]
+		s.handlerMu.Unlock()
+		if p != nil {
+			p.pw.Write(body)
+		}
+
+	case frameRequestEnd:
+		p := s.pending[id]
+		delete(s.pending, id)
+		s.handlerMu.Unlock()
+		if p != nil {
+			p.pw.Close()
+		}
+	}
+}
+
+func (s *Session) writeFrame(kind frameKind, id uint64, body []byte) error {
+	payload := make([]byte, 9+len(body))
+	payload[0] = byte(kind)
+	binary.BigEndian.PutUint64(payload[1:9], id)
+	copy(payload[9:], body)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteFrame(OpBinary, payload)
+}
+
+// RoundTrip sends an HTTP request over the tunnel and returns the
+// response status/header plus a body reader the caller must close. It
+// streams the request body as it's read, and the returned response body
+// is filled in as frames arrive - neither side needs to buffer the
+// full message.
+func (s *Session) RoundTrip(method, path string, header http.Header, body io.Reader) (status int, respHeader http.Header, respBody io.ReadCloser, err error) {
+	id := atomic.AddUint64(&s.nextID, 1)
+
+	pr, pw := io.Pipe()
+	st := &stream{
+		respHeader: make(chan responseHeader, 1),
+		body:       pw,
+		bodyReader: pr,
+	}
+
+	s.streamsMu.Lock()
+	s.streams[id] = st
+	s.streamsMu.Unlock()
+	// On any path that returns before a response body reaches the
+	// caller, the stream is done and must be cleaned up here. Once
+	// st.bodyReader has been handed back successfully, though, readLoop
+	// still has body/end frames to deliver to it - it deletes the entry
+	// itself on frameResponseEnd/frameError once the body is fully
+	// drained, so this must NOT delete out from under it.
+	abandon := func() {
+		s.streamsMu.Lock()
+		delete(s.streams, id)
+		s.streamsMu.Unlock()
+	}
+
+	reqHeader, err := json.Marshal(requestHeader{Method: method, Path: path, Header: header})
+	if err != nil {
+		abandon()
+		return 0, nil, nil, err
+	}
+	if err := s.writeFrame(frameRequestHeader, id, reqHeader); err != nil {
+		abandon()
+		return 0, nil, nil, err
+	}
+
+	if body != nil {
+		buf := make([]byte, 32*1024)
+		for {
+			n, rerr := body.Read(buf)
+			if n > 0 {
+				if werr := s.writeFrame(frameRequestBody, id, buf[:n]); werr != nil {
+					abandon()
+					return 0, nil, nil, werr
+				}
+			}
+			if rerr != nil {
+				break
+			}
+		}
+	}
+	if err := s.writeFrame(frameRequestEnd, id, nil); err != nil {
+		abandon()
+		return 0, nil, nil, err
+	}
+
+	select {
+	case h, ok := <-st.respHeader:
+		if !ok {
+			abandon()
+			return 0, nil, nil, fmt.Errorf("tunnel: stream %d closed before response headers", id)
+		}
+		return h.Status, http.Header(h.Header), st.bodyReader, nil
+	case <-s.closed:
+		abandon()
+		return 0, nil, nil, fmt.Errorf("tunnel: session closed: %w", s.closeErr)
+	}
+}
+
+// Serve is the agent side's complement to RoundTrip: it registers handler
+// to receive request frames demultiplexed by the session's own readLoop
+// (started by NewSession) and dispatches each to handler, writing the
+// resulting response back as response frames. It blocks until the session
+// closes. Only exercised by tests in this package today - the shield's
+// in-repo agents are config data, not Go processes, so production
+// callers of Serve live outside this tree.
+func (s *Session) Serve(handler func(method, path string, header http.Header, body io.Reader) (respStatus int, respHeader http.Header, respBody io.Reader)) error {
+	s.handlerMu.Lock()
+	s.handler = handler
+	s.pending = make(map[uint64]*pendingRequest)
+	s.handlerMu.Unlock()
+
+	return s.Wait()
+}
@@ -0,0 +1,100 @@
+package tunnel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip_EchoesRequest(t *testing.T) {
+	serverConns := make(chan *Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Errorf("Upgrade: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	defer srv.Close()
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	clientConn, err := DialAgent(addr, "/agent/connect?agent_id=nat-agent", http.Header{
+		"Authorization": {"Bearer test-token"},
+	})
+	if err != nil {
+		t.Fatalf("DialAgent: %v", err)
+	}
+	defer clientConn.Close()
+
+	serverConn := <-serverConns
+	defer serverConn.Close()
+
+	session := NewSession(serverConn)
+	defer session.Close()
+
+	agentSession := NewSession(clientConn)
+	defer agentSession.Close()
+	go agentSession.Serve(func(method, path string, header http.Header, body io.Reader) (int, http.Header, io.Reader) {
+		got, _ := io.ReadAll(body)
+		return http.StatusOK, http.Header{"X-Echo-Path": {path}}, strings.NewReader(method + ":" + string(got))
+	})
+
+	status, header, body, err := session.RoundTrip(http.MethodPost, "/hello", http.Header{"X-Test": {"1"}}, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	defer body.Close()
+
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want 200", status)
+	}
+	if got := header.Get("X-Echo-Path"); got != "/hello" {
+		t.Errorf("X-Echo-Path = %q, want /hello", got)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if string(data) != "POST:payload" {
+		t.Errorf("body = %q, want %q", data, "POST:payload")
+	}
+}
+
+func TestSession_HeartbeatKeepsConnectionAlive(t *testing.T) {
+	serverConns := make(chan *Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Errorf("Upgrade: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	defer srv.Close()
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	clientConn, err := DialAgent(addr, "/agent/connect?agent_id=nat-agent", nil)
+	if err != nil {
+		t.Fatalf("DialAgent: %v", err)
+	}
+	agentSession := NewSession(clientConn)
+	defer agentSession.Close()
+	go agentSession.Serve(func(method, path string, header http.Header, body io.Reader) (int, http.Header, io.Reader) {
+		return http.StatusOK, nil, nil
+	})
+
+	serverConn := <-serverConns
+	session := NewSession(serverConn)
+	defer session.Close()
+
+	select {
+	case <-session.closed:
+		t.Fatal("session closed unexpectedly")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
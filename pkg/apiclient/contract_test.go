@@ -0,0 +1,130 @@
+package apiclient_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Extra-Chill/plasma-shield/internal/api"
+	"github.com/Extra-Chill/plasma-shield/pkg/apiclient"
+)
+
+// TestContractRoundTrip exercises every operation in api/openapi.yaml
+// against the generated apiclient, to catch drift between the schema,
+// the client, and the handlers before it reaches a real deployment.
+func TestContractRoundTrip(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "plasma.sock")
+
+	server, err := api.NewServer(api.ServerConfig{
+		Addr:           "unix://" + socketPath,
+		ManagementAuth: "mgmt-token",
+		AgentAuth:      "agent-token",
+		Version:        "1.0.0",
+	})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	server.RegisterAgent("agent-1", "sarai", "10.0.0.1")
+
+	go server.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+	waitForSocket(t, socketPath)
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", socketPath)
+			},
+		},
+	}
+	ctx := context.Background()
+	mgmt := apiclient.NewClient("http://unix", "mgmt-token", httpClient)
+
+	status, err := mgmt.GetStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if status.AgentCount != 1 {
+		t.Errorf("agent count = %d, want 1", status.AgentCount)
+	}
+
+	agents, err := mgmt.ListAgents(ctx)
+	if err != nil {
+		t.Fatalf("ListAgents: %v", err)
+	}
+	if len(agents.Agents) != 1 || agents.Agents[0].ID != "agent-1" {
+		t.Errorf("unexpected agents: %+v", agents.Agents)
+	}
+
+	if _, err := mgmt.PauseAgent(ctx, "agent-1"); err != nil {
+		t.Fatalf("PauseAgent: %v", err)
+	}
+	if action, err := mgmt.ResumeAgent(ctx, "agent-1"); err != nil {
+		t.Fatalf("ResumeAgent: %v", err)
+	} else if action.Status != "active" {
+		t.Errorf("resumed agent status = %q, want %q", action.Status, "active")
+	}
+
+	created, err := mgmt.CreateRule(ctx, apiclient.CreateRuleRequest{Domain: "evil.example", Action: "block"})
+	if err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if created.Rule.ID == "" {
+		t.Fatal("expected a generated rule id")
+	}
+
+	rules, err := mgmt.ListRules(ctx)
+	if err != nil {
+		t.Fatalf("ListRules: %v", err)
+	}
+	if rules.Total != 1 {
+		t.Errorf("rule count = %d, want 1", rules.Total)
+	}
+
+	if _, err := mgmt.DeleteRule(ctx, created.Rule.ID); err != nil {
+		t.Fatalf("DeleteRule: %v", err)
+	}
+
+	logs, err := mgmt.ListLogs(ctx, apiclient.ListLogsParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListLogs: %v", err)
+	}
+	if logs.Limit != 10 {
+		t.Errorf("limit = %d, want 10", logs.Limit)
+	}
+
+	agentClient := apiclient.NewClient("http://unix", "agent-token", httpClient)
+	exec, err := agentClient.CheckExec(ctx, apiclient.ExecCheckRequest{Command: "ls -la", AgentID: "agent-1"})
+	if err != nil {
+		t.Fatalf("CheckExec: %v", err)
+	}
+	if !exec.Allowed {
+		t.Errorf("expected the default-allow policy to allow %q, got %+v", "ls -la", exec)
+	}
+
+	if action, err := mgmt.KillAgent(ctx, "agent-1"); err != nil {
+		t.Fatalf("KillAgent: %v", err)
+	} else if action.Status != "killed" {
+		t.Errorf("killed agent status = %q, want %q", action.Status, "killed")
+	}
+}
+
+func waitForSocket(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("socket %s never came up", path)
+}
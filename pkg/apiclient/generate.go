@@ -0,0 +1,9 @@
+// Package apiclient is a typed client for the Plasma Shield management
+// API's stable surface (GET /status, /agents and its pause/resume/kill
+// actions, /rules and its mutations, /logs, and POST /exec/check),
+// generated from api/openapi.yaml.
+//
+// Regenerate with `go generate ./...` after editing the spec.
+package apiclient
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen --config=oapi-codegen.yaml ../../api/openapi.yaml